@@ -0,0 +1,40 @@
+package claudetool
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Kinds of AuditRecord.
+const (
+	AuditBash            = "bash"
+	AuditPatch           = "patch"
+	AuditBrowserNavigate = "browser_navigate"
+)
+
+// AuditRecord describes something a tool did on the machine, for the audit
+// log. Only the fields relevant to Kind are set.
+type AuditRecord struct {
+	Kind           string
+	ConversationID string
+	Cwd            string
+	Command        string
+	ExitCode       *int // nil if the command didn't run to completion
+	Duration       time.Duration
+	Path           string
+	BeforeHash     string // sha256 of the file before a patch; "" if it didn't exist
+	AfterHash      string
+	URL            string
+	Error          string
+}
+
+// AuditFunc receives audit records. Tools call it synchronously, after the
+// action and before returning their result.
+type AuditFunc func(AuditRecord)
+
+// contentHash returns the hex sha256 of b, as used in audit records.
+func contentHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
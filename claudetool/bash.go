@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -44,6 +45,20 @@ type BashTool struct {
 	// ConversationID is the ID of the conversation this tool belongs to.
 	// It is exposed to invoked commands via SHELLEY_CONVERSATION_ID.
 	ConversationID string
+	// Audit, if set, is told about every command that runs.
+	Audit AuditFunc
+	// Jobs runs commands with background set. If nil, background
+	// commands are refused.
+	Jobs *JobManager
+	// Persistent runs commands in one long-lived shell on a PTY, so that
+	// shell state carries over between calls.
+	Persistent bool
+	// OnWorkingDirChange, if set, is called when a command in the
+	// persistent shell changes its working directory.
+	OnWorkingDirChange func(newDir string)
+
+	shellMu sync.Mutex
+	shell   *persistentShell
 }
 
 const (
@@ -80,12 +95,20 @@ func (t *Timeouts) slow() time.Duration {
 func (b *BashTool) Tool() *llm.Tool {
 	return &llm.Tool{
 		Name:        bashName,
-		Description: strings.TrimSpace(bashDescription),
+		Description: strings.TrimSpace(b.description()),
 		InputSchema: llm.MustSchema(bashInputSchema),
 		Run:         b.Run,
 	}
 }
 
+// description returns the tool description for b's mode.
+func (b *BashTool) description() string {
+	if b.Persistent {
+		return strings.Replace(bashDescription, bashStatelessNote, bashPersistentNote, 1)
+	}
+	return bashDescription
+}
+
 // getWorkingDir returns the current working directory.
 func (b *BashTool) getWorkingDir() string {
 	return b.WorkingDir.Get()
@@ -101,11 +124,18 @@ func (b *BashTool) isNoTrailerSet() bool {
 }
 
 const (
-	bashName        = "bash"
+	bashName          = "bash"
+	bashStatelessNote = `Bash state changes (working dir, variables, aliases) don't persist between calls.
+`
+	bashPersistentNote = `Commands run in one persistent shell, on a terminal: the working dir, variables,
+aliases and activated virtualenvs persist between calls. Commands can't read stdin.
+If a command times out, the shell is restarted and its state is lost.
+`
 	bashDescription = `Executes shell commands via bash --login -c, returning combined stdout/stderr.
-Bash state changes (working dir, variables, aliases) don't persist between calls.
-
-For long-running processes (servers, watch modes), use tmux instead.
+` + bashStatelessNote + `
+For long-running processes (servers, watch modes), set background=true.
+The command then runs as a job that outlives the call, and the result is
+its job ID; use the jobs tool to read its output, send it input, or stop it.
 Do NOT use &, nohup, or disown — the bash tool kills its process group on exit.
 
 MUST set slow_ok=true for potentially slow commands: builds, downloads,
@@ -133,6 +163,10 @@ For complex scripts, write them to a file first and then execute the file.
     "slow_ok": {
       "type": "boolean",
       "description": "Use extended timeout"
+    },
+    "background": {
+      "type": "boolean",
+      "description": "Run as a background job, with no timeout"
     }
   }
 }
@@ -140,13 +174,15 @@ For complex scripts, write them to a file first and then execute the file.
 )
 
 type bashInput struct {
-	Command string `json:"command"`
-	SlowOK  bool   `json:"slow_ok,omitempty"`
+	Command    string `json:"command"`
+	SlowOK     bool   `json:"slow_ok,omitempty"`
+	Background bool   `json:"background,omitempty"`
 }
 
 // BashDisplayData is the display data sent to the UI for bash tool results.
 type BashDisplayData struct {
 	WorkingDir string `json:"workingDir"`
+	JobID      string `json:"jobId,omitempty"` // set for background commands
 }
 
 func (i *bashInput) timeout(t *Timeouts) time.Duration {
@@ -197,17 +233,50 @@ func (b *BashTool) Run(ctx context.Context, m json.RawMessage) llm.ToolOut {
 		req.Command = bashkit.AddCoauthorTrailer(req.Command, "Co-authored-by: Shelley <shelley@exe.dev>")
 	}
 
+	if req.Background {
+		return b.startJob(wd, req.Command)
+	}
+
 	timeout := req.timeout(b.Timeouts)
 
 	display := BashDisplayData{WorkingDir: wd}
 
-	out, execErr := b.executeBash(ctx, req, timeout)
+	start := time.Now()
+	var out string
+	var execErr error
+	if b.Persistent {
+		out, execErr = b.executeInShell(ctx, req, timeout)
+	} else {
+		out, execErr = b.executeBash(ctx, req, timeout)
+	}
+	if b.Audit != nil {
+		b.Audit(bashAuditRecord(wd, req.Command, time.Since(start), execErr))
+	}
 	if execErr != nil {
 		return llm.ErrorToolOut(execErr)
 	}
 	return llm.ToolOut{LLMContent: llm.TextContent(out), Display: display}
 }
 
+// bashAuditRecord describes a command that ran for the audit log. A command
+// that exited with a status has an exit code; one that timed out or failed
+// to start has none.
+func bashAuditRecord(cwd, command string, duration time.Duration, execErr error) AuditRecord {
+	rec := AuditRecord{Kind: AuditBash, Cwd: cwd, Command: command, Duration: duration}
+	var exitErr interface{ ExitCode() int } // *exec.ExitError or *shellExitError
+	switch {
+	case execErr == nil:
+		code := 0
+		rec.ExitCode = &code
+	case errors.As(execErr, &exitErr) && exitErr.ExitCode() >= 0:
+		code := exitErr.ExitCode()
+		rec.ExitCode = &code
+	default:
+		rec.Error = strings.SplitN(execErr.Error(), "\n", 2)[0]
+	}
+	return rec
+}
+
 const (
 	largeOutputThreshold = 50 * 1024 // 50KB - threshold for saving to file
 	firstLinesCount      = 2
@@ -233,6 +302,12 @@ func (b *BashTool) makeBashCommand(ctx context.Context, command string, out io.W
 		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL) // kill entire process group
 	}
 	cmd.WaitDelay = 15 * time.Second // prevent indefinite hangs when child processes keep pipes open
+	cmd.Env = b.bashEnv()
+	return cmd
+}
+
+// bashEnv returns the environment commands run with.
+func (b *BashTool) bashEnv() []string {
 	// Remove SHELLEY_CONVERSATION_ID so we control it explicitly below.
 	env := slices.DeleteFunc(os.Environ(), func(s string) bool {
 		return strings.HasPrefix(s, "SHELLEY_CONVERSATION_ID=")
@@ -242,8 +317,49 @@ func (b *BashTool) makeBashCommand(ctx context.Context, command string, out io.W
 	if b.ConversationID != "" {
 		env = append(env, "SHELLEY_CONVERSATION_ID="+b.ConversationID)
 	}
-	cmd.Env = env
-	return cmd
+	return env
+}
+
+// jobStartupWait is how long startJob waits to see whether a background
+// command fails straight away.
+var jobStartupWait = time.Second
+
+// startJob runs command as a background job.
+func (b *BashTool) startJob(wd, command string) llm.ToolOut {
+	if b.Jobs == nil {
+		return llm.ErrorfToolOut("background jobs are not available")
+	}
+	// The job must not end with the tool call, so it gets a context that
+	// is never cancelled; the job manager stops it instead.
+	cmd := b.makeBashCommand(context.Background(), command, nil)
+	// Keep collecting output from child processes after the shell exits.
+	cmd.WaitDelay = 0
+	j, err := b.Jobs.Start(b.ConversationID, command, cmd)
+	if b.Audit != nil {
+		rec := AuditRecord{Kind: AuditBash, Cwd: wd, Command: command}
+		if err != nil {
+			rec.Error = err.Error()
+		}
+		b.Audit(rec)
+	}
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
+	display := BashDisplayData{WorkingDir: wd, JobID: j.ID}
+	select {
+	case <-j.Done():
+		out, err := jobOutputLines(j, 20, "")
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent("the job ended straight away\n" + out), Display: display}
+	case <-time.After(jobStartupWait):
+	}
+	return llm.ToolOut{
+		LLMContent: llm.TextContent(fmt.Sprintf("started background job %s (pid %d)\nUse the jobs tool to see its output, send it input, or stop it.", j.ID, j.PID())),
+		Display:    display,
+	}
 }
 
 func cmdWait(cmd *exec.Cmd) error {
@@ -260,7 +376,13 @@ func (b *BashTool) executeBash(ctx context.Context, req bashInput, timeout time.
 	defer cancel()
 
 	output := new(bytes.Buffer)
-	cmd := b.makeBashCommand(execCtx, req.Command, output)
+	var out io.Writer = output
+	if report := Progress(ctx); report != nil {
+		progress := newProgressWriter(report)
+		defer progress.Close()
+		out = io.MultiWriter(output, progress)
+	}
+	cmd := b.makeBashCommand(execCtx, req.Command, out)
 	cmd.Env = append(cmd.Env, `GIT_SEQUENCE_EDITOR=echo "To do an interactive rebase, run it in a tmux session." && exit 1`)
 	if err := cmd.Start(); err != nil {
 		return "", fmt.Errorf("command failed: %w", err)
@@ -268,19 +390,89 @@ func (b *BashTool) executeBash(ctx context.Context, req bashInput, timeout time.
 
 	err := cmdWait(cmd)
 
-	out, formatErr := formatForegroundBashOutput(output.String())
+	result, formatErr := formatForegroundBashOutput(output.String())
 	if formatErr != nil {
 		return "", formatErr
 	}
 
 	if execCtx.Err() == context.DeadlineExceeded {
-		return "", fmt.Errorf("[command timed out after %s, showing output until timeout]\n%s", timeout, out)
+		return "", fmt.Errorf("[command timed out after %s, showing output until timeout]\n%s", timeout, result)
 	}
 	if err != nil {
+		return "", fmt.Errorf("[command failed: %w]\n%s", err, result)
+	}
+
+	return result, nil
+}
+
+// executeInShell runs req in the persistent shell, starting one if needed.
+// If the command changes directory, the shared working directory follows.
+func (b *BashTool) executeInShell(ctx context.Context, req bashInput, timeout time.Duration) (string, error) {
+	b.shellMu.Lock()
+	defer b.shellMu.Unlock()
+
+	wd := b.getWorkingDir()
+	if b.shell == nil || !b.shell.alive() {
+		if b.shell != nil {
+			b.shell.close()
+		}
+		env := append(b.bashEnv(), `GIT_SEQUENCE_EDITOR=echo "Interactive rebases aren't possible here." && exit 1`)
+		shell, err := startPersistentShell(wd, env)
+		if err != nil {
+			b.shell = nil
+			return "", err
+		}
+		b.shell = shell
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var progress io.Writer
+	if report := Progress(ctx); report != nil {
+		w := newProgressWriter(report)
+		defer w.Close()
+		progress = w
+	}
+	output, err := b.shell.run(execCtx, req.Command, wd, progress)
+	out, formatErr := formatForegroundBashOutput(output)
+	if formatErr != nil {
+		return "", formatErr
+	}
+
+	if pwd := b.shell.pwd; pwd != wd && b.shell.alive() {
+		b.WorkingDir.Set(pwd)
+		if b.OnWorkingDirChange != nil {
+			b.OnWorkingDirChange(pwd)
+		}
+	}
+
+	var exitErr *shellExitError
+	switch {
+	case err == nil:
+		return out, nil
+	case errors.As(err, &exitErr):
 		return "", fmt.Errorf("[command failed: %w]\n%s", err, out)
+	case errors.Is(err, errShellExited):
+		b.shell.close()
+		b.shell = nil
+		return "", fmt.Errorf("[%w]\n%s", err, out)
+	case execCtx.Err() == context.DeadlineExceeded:
+		b.shell = nil
+		return "", fmt.Errorf("[command timed out after %s, showing output until timeout; the shell was restarted, so its state was reset]\n%s", timeout, out)
+	default:
+		b.shell = nil
+		return "", fmt.Errorf("[command failed: %w; the shell was restarted, so its state was reset]\n%s", err, out)
 	}
+}
 
-	return out, nil
+// Close stops the persistent shell, if there is one.
+func (b *BashTool) Close() {
+	b.shellMu.Lock()
+	defer b.shellMu.Unlock()
+	if b.shell != nil {
+		b.shell.close()
+		b.shell = nil
+	}
 }
 
 // formatForegroundBashOutput formats the output of a foreground bash command for display to the agent.
@@ -3,6 +3,8 @@ package claudetool
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os/exec"
 	"strings"
 	"testing"
@@ -272,6 +274,30 @@ func TestExecuteBash(t *testing.T) {
 			t.Errorf("Expected timeout error, got: %v", err)
 		}
 	})
+	// Audit records carry the exit code of commands that exited, and none
+	// for commands that were killed or never ran.
+	t.Run("Audit Records", func(t *testing.T) {
+		exit7 := exec.Command("sh", "-c", "exit 7").Run()
+		for _, tc := range []struct {
+			err  error
+			want *int
+		}{
+			{nil, new(0)},
+			{fmt.Errorf("[command failed: %w]\n", exit7), new(7)},
+			{errors.New("[command timed out after 1s, showing output until timeout]\n"), nil},
+		} {
+			rec := bashAuditRecord("/tmp", "cmd", time.Second, tc.err)
+			if rec.Kind != AuditBash || rec.Command != "cmd" || rec.Cwd != "/tmp" || rec.Duration != time.Second {
+				t.Errorf("%v: record = %+v", tc.err, rec)
+			}
+			switch {
+			case tc.want == nil && (rec.ExitCode != nil || rec.Error == ""):
+				t.Errorf("%v: exit code %v, error %q; want no exit code and an error", tc.err, rec.ExitCode, rec.Error)
+			case tc.want != nil && (rec.ExitCode == nil || *rec.ExitCode != *tc.want):
+				t.Errorf("%v: exit code %v, want %d", tc.err, rec.ExitCode, *tc.want)
+			}
+		}
+	})
 }
 
 func TestBashTimeout(t *testing.T) {
@@ -52,6 +52,10 @@ type DownloadInfo struct {
 
 // BrowseTools contains all browser tools and manages a shared browser instance
 type BrowseTools struct {
+	// OnNavigate, if set, is called after every navigation with its URL
+	// and error, e.g. for an audit log.
+	OnNavigate func(url string, err error)
+
 	ctx              context.Context
 	allocCtx         context.Context
 	allocCancel      context.CancelFunc
@@ -326,6 +330,9 @@ func (b *BrowseTools) navigateRun(ctx context.Context, m json.RawMessage) llm.To
 		chromedp.Navigate(input.URL),
 		chromedp.WaitReady("body"),
 	)
+	if b.OnNavigate != nil {
+		b.OnNavigate(input.URL, err)
+	}
 	if err != nil {
 		// Navigation to download URLs fails with ERR_ABORTED, but the download may have succeeded.
 		// Wait briefly for download events to be processed, then check if we got any downloads.
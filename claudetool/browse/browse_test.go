@@ -593,7 +593,7 @@ func TestParseTimeout(t *testing.T) {
 func TestRegisterBrowserTools(t *testing.T) {
 	ctx := context.Background()
 
-	tools, cleanup := RegisterBrowserTools(ctx, 0)
+	tools, cleanup := RegisterBrowserTools(ctx, 0, nil)
 	t.Cleanup(cleanup)
 
 	if len(tools) != 6 {
@@ -10,8 +10,10 @@ import (
 // It also returns a cleanup function that should be called when done to properly close the browser.
 // The browser will be initialized lazily when a browser tool is first used.
 // maxImageDimension is the max pixel dimension for images (0 uses default of 2000).
-func RegisterBrowserTools(ctx context.Context, maxImageDimension int) ([]*llm.Tool, func()) {
+// onNavigate, if not nil, is called after every navigation with its URL and error.
+func RegisterBrowserTools(ctx context.Context, maxImageDimension int, onNavigate func(url string, err error)) ([]*llm.Tool, func()) {
 	browserTools := NewBrowseTools(ctx, 0, maxImageDimension)
+	browserTools.OnNavigate = onNavigate
 
 	return browserTools.GetTools(), func() {
 		browserTools.Close()
@@ -0,0 +1,598 @@
+// Package codeindex keeps a search index of a repository's source code:
+// the symbols declared in its Go, TypeScript, JavaScript and Python files,
+// the words each file uses and, optionally, an embedding of each file.
+//
+// An index updates incrementally, reading again only the files whose size
+// or modification time changed, and is saved between runs so that a
+// restart doesn't read the whole repository again.
+package codeindex
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Config configures code indexes. It is the "code_index" section of
+// shelley.json.
+type Config struct {
+	// Embeddings names the embeddings backend used to rank files by
+	// meaning as well as by words: "" for none, or "local" for
+	// LocalEmbedder.
+	Embeddings string `json:"embeddings,omitempty"`
+	// CacheDir is where indexes are saved between runs. It defaults to
+	// shelley/codeindex in the user's cache directory; "none" keeps
+	// indexes in memory only.
+	CacheDir string `json:"cache_dir,omitempty"`
+}
+
+// Indexes holds the index of each repository, shared by every
+// conversation working in it.
+type Indexes struct {
+	cacheDir string
+	embedder Embedder
+
+	mu     sync.Mutex
+	byRoot map[string]*Index
+}
+
+// New returns an empty set of indexes configured by cfg.
+func New(cfg Config) (*Indexes, error) {
+	x := &Indexes{byRoot: make(map[string]*Index)}
+	switch cfg.Embeddings {
+	case "":
+	case "local":
+		x.embedder = LocalEmbedder{}
+	default:
+		return nil, fmt.Errorf("unknown embeddings backend %q", cfg.Embeddings)
+	}
+	switch cfg.CacheDir {
+	case "none":
+	case "":
+		if dir, err := os.UserCacheDir(); err == nil {
+			x.cacheDir = filepath.Join(dir, "shelley", "codeindex")
+		}
+	default:
+		x.cacheDir = cfg.CacheDir
+	}
+	return x, nil
+}
+
+// For returns the index of the repository at root, which is read the
+// first time it is searched.
+func (x *Indexes) For(root string) *Index {
+	root = filepath.Clean(root)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if ix, ok := x.byRoot[root]; ok {
+		return ix
+	}
+	ix := &Index{root: root, embedder: x.embedder, files: make(map[string]*file), df: make(map[string]int)}
+	if x.cacheDir != "" {
+		sum := sha256.Sum256([]byte(root))
+		ix.cachePath = filepath.Join(x.cacheDir, hex.EncodeToString(sum[:8])+".gob")
+	}
+	x.byRoot[root] = ix
+	return ix
+}
+
+const (
+	// maxFiles caps the files an index reads, so that a huge repository
+	// doesn't take minutes to index.
+	maxFiles = 20000
+	// maxFileSize is the size above which files are left out: they are
+	// almost always generated or bundled.
+	maxFileSize = 512 << 10
+	// cacheVersion changes when the saved form of an index does.
+	cacheVersion = 1
+)
+
+// An Index is the search index of one repository.
+type Index struct {
+	root      string
+	cachePath string
+	embedder  Embedder
+
+	mu       sync.Mutex
+	loaded   bool
+	files    map[string]*file // by slash-separated path relative to root
+	df       map[string]int   // how many files use each word
+	totalLen int              // the number of words in all files
+	vectors  string           // the embedder the files' vectors are from
+}
+
+// file is what an index knows about a source file. Its fields are
+// exported for gob.
+type file struct {
+	ModTime   int64 // in nanoseconds
+	Size      int64
+	Lang      string
+	Generated bool // left out of searches
+	Symbols   []Symbol
+	Terms     map[string]int32 // how many times the file uses each word
+	Length    int32            // the number of words in the file
+	Vector    []float32
+}
+
+// Root returns the directory the index covers.
+func (ix *Index) Root() string { return ix.root }
+
+// Len returns the number of files in the index.
+func (ix *Index) Len() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return len(ix.files)
+}
+
+// Update brings the index up to date with the files on disk, reading only
+// those that changed. It reports how many files were added, changed or
+// removed.
+func (ix *Index) Update(ctx context.Context) (int, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.update(ctx)
+}
+
+func (ix *Index) update(ctx context.Context) (int, error) {
+	if !ix.loaded {
+		ix.loaded = true
+		if err := ix.load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			slog.WarnContext(ctx, "failed to load code index", "root", ix.root, "error", err)
+		}
+	}
+
+	paths, err := listFiles(ctx, ix.root)
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	seen := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return changed, err
+		}
+		lang := Language(p)
+		if lang == "" {
+			continue
+		}
+		if len(seen) == maxFiles {
+			break
+		}
+		info, err := os.Stat(filepath.Join(ix.root, filepath.FromSlash(p)))
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxFileSize {
+			continue
+		}
+		seen[p] = true
+		old := ix.files[p]
+		if old != nil && old.ModTime == info.ModTime().UnixNano() && old.Size == info.Size() {
+			continue
+		}
+		src, err := os.ReadFile(filepath.Join(ix.root, filepath.FromSlash(p)))
+		if err != nil {
+			continue
+		}
+		ix.replace(p, readFile(lang, src, info))
+		changed++
+	}
+	for p := range ix.files {
+		if !seen[p] {
+			ix.replace(p, nil)
+			changed++
+		}
+	}
+
+	embedded, err := ix.embed(ctx)
+	if err != nil {
+		// Searches still rank by words.
+		slog.WarnContext(ctx, "failed to embed code index files", "root", ix.root, "error", err)
+	}
+	if changed > 0 || embedded > 0 {
+		if err := ix.save(); err != nil {
+			slog.WarnContext(ctx, "failed to save code index", "root", ix.root, "error", err)
+		}
+	}
+	return changed, nil
+}
+
+// readFile reads a source file into the form the index keeps.
+func readFile(lang string, src []byte, info fs.FileInfo) *file {
+	f := &file{ModTime: info.ModTime().UnixNano(), Size: info.Size(), Lang: lang}
+	if IsGenerated(src) {
+		f.Generated = true
+		return f
+	}
+	f.Symbols = symbols(lang, src)
+	f.Terms = make(map[string]int32)
+	for _, w := range words(string(src)) {
+		f.Terms[w]++
+		f.Length++
+	}
+	return f
+}
+
+// IsGenerated reports whether a file says it is generated.
+func IsGenerated(src []byte) bool {
+	head := src[:min(len(src), 1024)]
+	return bytes.Contains(head, []byte("Code generated")) && bytes.Contains(head, []byte("DO NOT EDIT"))
+}
+
+// replace replaces the file at p, keeping the word counts right. A nil f
+// removes the file.
+func (ix *Index) replace(p string, f *file) {
+	if old := ix.files[p]; old != nil {
+		for w := range old.Terms {
+			if ix.df[w]--; ix.df[w] <= 0 {
+				delete(ix.df, w)
+			}
+		}
+		ix.totalLen -= int(old.Length)
+		delete(ix.files, p)
+	}
+	if f == nil {
+		return
+	}
+	for w := range f.Terms {
+		ix.df[w]++
+	}
+	ix.totalLen += int(f.Length)
+	ix.files[p] = f
+}
+
+// embedBatch is how many files are embedded in one call.
+const embedBatch = 64
+
+// embed computes the vectors the files lack.
+func (ix *Index) embed(ctx context.Context) (int, error) {
+	if ix.embedder == nil {
+		return 0, nil
+	}
+	if name := ix.embedder.Name(); ix.vectors != name {
+		for _, f := range ix.files {
+			f.Vector = nil
+		}
+		ix.vectors = name
+	}
+	var todo []string
+	for p, f := range ix.files {
+		if f.Vector == nil && !f.Generated {
+			todo = append(todo, p)
+		}
+	}
+	slices.Sort(todo)
+	done := 0
+	for batch := range slices.Chunk(todo, embedBatch) {
+		texts := make([]string, len(batch))
+		for i, p := range batch {
+			texts[i] = embeddingText(p, ix.files[p])
+		}
+		vecs, err := ix.embedder.Embed(ctx, texts)
+		if err != nil {
+			return done, err
+		}
+		if len(vecs) != len(batch) {
+			return done, fmt.Errorf("embedder returned %d vectors for %d texts", len(vecs), len(batch))
+		}
+		for i, p := range batch {
+			ix.files[p].Vector = vecs[i]
+		}
+		done += len(batch)
+	}
+	return done, nil
+}
+
+// embeddingText is the text a file's vector is computed from: its path
+// and what it declares.
+func embeddingText(p string, f *file) string {
+	var sb strings.Builder
+	sb.WriteString(p)
+	for _, s := range f.Symbols {
+		sb.WriteString("\n" + s.Kind + " " + s.QualifiedName())
+		if s.Doc != "" {
+			sb.WriteString(": " + s.Doc)
+		}
+		if sb.Len() > 8<<10 {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// listFiles returns the files under root, relative to it: those git knows
+// of or would add if root is in a git repository, and otherwise every file
+// outside hidden and dependency directories.
+func listFiles(ctx context.Context, root string) ([]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	cmd.Dir = root
+	var paths []string
+	if out, err := cmd.Output(); err == nil {
+		for p := range strings.SplitSeq(string(out), "\x00") {
+			if p != "" && !skipped(p) {
+				paths = append(paths, p)
+			}
+		}
+		// --cached and --others can list a file twice during a merge.
+		slices.Sort(paths)
+		return slices.Compact(paths), nil
+	}
+
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel != "." && skipped(rel+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if len(paths) >= 4*maxFiles {
+			return filepath.SkipAll
+		}
+		paths = append(paths, rel)
+		return ctx.Err()
+	})
+	return paths, err
+}
+
+// skipped reports whether the file at p is in a directory the index leaves
+// out: hidden directories and other people's code.
+func skipped(p string) bool {
+	dir, _ := path.Split(p)
+	for seg := range strings.SplitSeq(strings.TrimSuffix(dir, "/"), "/") {
+		switch {
+		case seg == "node_modules", seg == "vendor", seg == "__pycache__", seg == "site-packages":
+			return true
+		case len(seg) > 1 && seg[0] == '.':
+			return true
+		}
+	}
+	return false
+}
+
+// cached is the saved form of an index.
+type cached struct {
+	Version int
+	Root    string
+	Vectors string
+	Files   map[string]*file
+}
+
+func (ix *Index) load() error {
+	if ix.cachePath == "" {
+		return fs.ErrNotExist
+	}
+	f, err := os.Open(ix.cachePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var c cached
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&c); err != nil {
+		return err
+	}
+	if c.Version != cacheVersion || c.Root != ix.root {
+		return nil
+	}
+	for p, fi := range c.Files {
+		ix.replace(p, fi)
+	}
+	ix.vectors = c.Vectors
+	return nil
+}
+
+func (ix *Index) save() error {
+	if ix.cachePath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(ix.cachePath), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(ix.cachePath), "index-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	err = gob.NewEncoder(w).Encode(cached{Version: cacheVersion, Root: ix.root, Vectors: ix.vectors, Files: ix.files})
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), ix.cachePath)
+}
+
+// A Query is a search of an index.
+type Query struct {
+	// Text says what to find, in words or identifiers.
+	Text string
+	// Terms are more words or identifiers to look for.
+	Terms []string
+	// Kind, if set, limits the results to files that declare a matching
+	// symbol of this kind.
+	Kind string
+	// Path, if set, limits the results to files under this directory,
+	// relative to the index's root.
+	Path string
+	// Limit is the most results to return; it defaults to 10.
+	Limit int
+}
+
+// A Result is a file that matches a query.
+type Result struct {
+	// Path is relative to the index's root.
+	Path  string
+	Score float64
+	// Symbols are the file's best matching declarations, best first.
+	Symbols []Symbol
+}
+
+// maxResultSymbols is how many symbols a result lists.
+const maxResultSymbols = 3
+
+// BM25 parameters.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Search updates the index and returns the files that best match q, best
+// first.
+func (ix *Index) Search(ctx context.Context, q Query) ([]Result, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if _, err := ix.update(ctx); err != nil {
+		return nil, err
+	}
+
+	qw := queryWords(append([]string{q.Text}, q.Terms...)...)
+	if len(qw) == 0 {
+		return nil, errors.New("the query has no words to search for")
+	}
+	// Whole identifiers, such as "keywordRun" or "KeywordTool.Run", match
+	// a symbol's name exactly.
+	exact := map[string]bool{}
+	for _, text := range append([]string{q.Text}, q.Terms...) {
+		for field := range strings.FieldsSeq(text) {
+			field = strings.ToLower(strings.Trim(field, "().,:;`'\"*&"))
+			if len(field) >= 3 {
+				exact[field] = true
+			}
+		}
+	}
+
+	var qvec []float32
+	if ix.embedder != nil && ix.vectors == ix.embedder.Name() {
+		vecs, err := ix.embedder.Embed(ctx, []string{strings.Join(append([]string{q.Text}, q.Terms...), " ")})
+		if err == nil && len(vecs) == 1 {
+			qvec = vecs[0]
+		}
+	}
+
+	n := float64(len(ix.files))
+	avgLen := float64(ix.totalLen) / max(n, 1)
+	idf := func(w string) float64 {
+		df := float64(ix.df[w])
+		return math.Log(1 + (n-df+0.5)/(df+0.5))
+	}
+	// A word matching a symbol's name or a path counts for more the rarer
+	// it is, from 0.1 for words every file uses to 1.
+	query := make(map[string]float64, len(qw))
+	for _, w := range qw {
+		query[w] = min(max(idf(w)/math.Log(1+2*n+1), 0.1), 1)
+	}
+	prefix := strings.Trim(path.Clean(filepath.ToSlash(q.Path)), "/")
+	if prefix == "." {
+		prefix = ""
+	}
+	var results []Result
+	for p, f := range ix.files {
+		if f.Generated || prefix != "" && p != prefix && !strings.HasPrefix(p, prefix+"/") {
+			continue
+		}
+		var lexical float64
+		for _, w := range qw {
+			tf := float64(f.Terms[w])
+			if tf == 0 {
+				continue
+			}
+			lexical += idf(w) * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*float64(f.Length)/avgLen))
+		}
+		for _, w := range words(p) {
+			lexical += query[w]
+		}
+
+		type scored struct {
+			sym   Symbol
+			score float64
+		}
+		var syms []scored
+		for _, s := range f.Symbols {
+			if q.Kind != "" && s.Kind != q.Kind {
+				continue
+			}
+			if score := symbolScore(s, query, exact); score > 0 {
+				syms = append(syms, scored{s, score})
+			}
+		}
+		if q.Kind != "" && len(syms) == 0 {
+			continue
+		}
+		slices.SortStableFunc(syms, func(a, b scored) int { return cmp.Compare(b.score, a.score) })
+		if len(syms) > 0 {
+			lexical += syms[0].score
+		}
+
+		var similarity float64
+		if qvec != nil && f.Vector != nil {
+			similarity = cosine(qvec, f.Vector)
+		}
+		// Meaning alone is weak evidence, so it needs to be strong.
+		if lexical == 0 && similarity < 0.5 {
+			continue
+		}
+		r := Result{Path: p, Score: lexical + 4*max(similarity, 0)}
+		for _, s := range syms[:min(len(syms), maxResultSymbols)] {
+			r.Symbols = append(r.Symbols, s.sym)
+		}
+		results = append(results, r)
+	}
+
+	slices.SortFunc(results, func(a, b Result) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.Path, b.Path))
+	})
+	limit := cmp.Or(q.Limit, 10)
+	return results[:min(len(results), limit)], nil
+}
+
+// symbolScore scores how well a symbol matches the words of a query and
+// the identifiers in it. query holds the weight of each word.
+func symbolScore(s Symbol, query map[string]float64, exact map[string]bool) float64 {
+	var score float64
+	if exact[strings.ToLower(s.Name)] || exact[strings.ToLower(s.QualifiedName())] {
+		score += 6
+	}
+	parts := splitIdentifier(s.Name)
+	matched := 0
+	for _, p := range parts {
+		if weight, ok := query[p]; ok {
+			score += 3 * weight
+			matched++
+		}
+	}
+	if matched > 0 && matched == len(parts) {
+		score++
+	}
+	for _, p := range splitIdentifier(s.Container) {
+		score += query[p] / 2
+	}
+	for _, w := range words(s.Doc) {
+		score += query[w] / 4
+	}
+	if score > 0 && s.Exported {
+		score += 0.25
+	}
+	return score
+}
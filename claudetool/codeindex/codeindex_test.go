@@ -0,0 +1,151 @@
+package codeindex
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIndexSearch(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"auth/session.go":         "package auth\n\n// SessionStore keeps login sessions.\ntype SessionStore struct{}\n\nfunc (s *SessionStore) Expire(id string) {}\n",
+		"billing/invoice.go":      "package billing\n\n// Invoice is a bill.\ntype Invoice struct{ Total int }\n\nfunc renderInvoice(i Invoice) string { return \"\" }\n",
+		"web/src/LoginForm.tsx":   "export function LoginForm() {\n  return null;\n}\n",
+		"scripts/migrate.py":      "def migrate_sessions(db):\n    '''Move sessions to the new store.'''\n",
+		"node_modules/x/index.js": "export function SessionStore() {}\n",
+		"gen/gen.go":              "// Code generated by gen. DO NOT EDIT.\n\npackage gen\n\nfunc SessionStore() {}\n",
+		"README.md":               "sessions\n",
+	})
+	x, err := New(Config{CacheDir: "none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix := x.For(root)
+	ctx := context.Background()
+
+	results, err := ix.Search(ctx, Query{Text: "where do login sessions expire?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 || results[0].Path != "auth/session.go" {
+		t.Fatalf("results = %+v, want auth/session.go first", results)
+	}
+	if syms := results[0].Symbols; len(syms) == 0 || syms[0].QualifiedName() != "SessionStore.Expire" {
+		t.Errorf("symbols = %+v, want SessionStore.Expire first", syms)
+	}
+	for _, r := range results {
+		if r.Path == "node_modules/x/index.js" || r.Path == "gen/gen.go" || r.Path == "README.md" {
+			t.Errorf("%s is in the results", r.Path)
+		}
+	}
+	if ix.Len() != 5 { // the four sources and the generated file
+		t.Errorf("Len() = %d, want 5", ix.Len())
+	}
+
+	// An exact identifier finds its declaration.
+	results, _ = ix.Search(ctx, Query{Text: "renderInvoice"})
+	if len(results) == 0 || results[0].Path != "billing/invoice.go" || results[0].Symbols[0].Name != "renderInvoice" {
+		t.Errorf("renderInvoice: results = %+v", results)
+	}
+
+	// Kind and Path narrow the results.
+	results, _ = ix.Search(ctx, Query{Text: "sessions", Kind: "func"})
+	if len(results) != 1 || results[0].Path != "scripts/migrate.py" {
+		t.Errorf("kind func: results = %+v", results)
+	}
+	results, _ = ix.Search(ctx, Query{Text: "login", Path: "web"})
+	if len(results) != 1 || results[0].Path != "web/src/LoginForm.tsx" {
+		t.Errorf("path web: results = %+v", results)
+	}
+
+	if _, err := ix.Search(ctx, Query{Text: "the of and"}); err == nil {
+		t.Error("a query of stopwords succeeded")
+	}
+}
+
+func TestIndexUpdatesIncrementally(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"a.go": "package a\n\nfunc Alpha() {}\n",
+		"b.go": "package a\n\nfunc Beta() {}\n",
+	})
+	cache := t.TempDir()
+	x, _ := New(Config{CacheDir: cache, Embeddings: "local"})
+	ix := x.For(root)
+	ctx := context.Background()
+	if n, err := ix.Update(ctx); err != nil || n != 2 {
+		t.Fatalf("first Update = %d, %v; want 2 files", n, err)
+	}
+	if n, _ := ix.Update(ctx); n != 0 {
+		t.Errorf("Update with nothing changed = %d, want 0", n)
+	}
+
+	// Change one file, add one, remove one.
+	later := time.Now().Add(time.Second)
+	writeFiles(t, root, map[string]string{"a.go": "package a\n\nfunc Gamma() {}\n", "c.go": "package a\n\nfunc Delta() {}\n"})
+	os.Chtimes(filepath.Join(root, "a.go"), later, later)
+	os.Remove(filepath.Join(root, "b.go"))
+	if n, _ := ix.Update(ctx); n != 3 {
+		t.Errorf("Update after changes = %d, want 3", n)
+	}
+	results, _ := ix.Search(ctx, Query{Text: "Gamma"})
+	if len(results) != 1 || results[0].Path != "a.go" {
+		t.Errorf("Gamma: results = %+v", results)
+	}
+	if results, _ := ix.Search(ctx, Query{Text: "Alpha Beta"}); len(results) != 0 {
+		t.Errorf("Alpha Beta: results = %+v, want none", results)
+	}
+
+	// A new process reads the saved index and only the files that changed
+	// since.
+	x2, _ := New(Config{CacheDir: cache, Embeddings: "local"})
+	ix2 := x2.For(root)
+	if n, err := ix2.Update(ctx); err != nil || n != 0 {
+		t.Errorf("Update from the cache = %d, %v; want 0", n, err)
+	}
+	if ix2.Len() != 2 {
+		t.Errorf("Len() from the cache = %d, want 2", ix2.Len())
+	}
+	for _, f := range ix2.files {
+		if len(f.Vector) != localDims {
+			t.Errorf("cached vector has %d dimensions, want %d", len(f.Vector), localDims)
+		}
+	}
+}
+
+func TestLocalEmbedder(t *testing.T) {
+	vecs, err := LocalEmbedder{}.Embed(context.Background(), []string{
+		"func indexFiles walks the repository",
+		"indexing repository files",
+		"render the invoice total",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	related, unrelated := cosine(vecs[0], vecs[1]), cosine(vecs[0], vecs[2])
+	if related <= unrelated {
+		t.Errorf("similarity of related texts %.2f <= unrelated %.2f", related, unrelated)
+	}
+}
+
+func TestNewUnknownEmbeddings(t *testing.T) {
+	if _, err := New(Config{Embeddings: "magic"}); err == nil {
+		t.Error("New accepted an unknown embeddings backend")
+	}
+}
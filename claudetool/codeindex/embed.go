@@ -0,0 +1,86 @@
+package codeindex
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+)
+
+// An Embedder turns text into vectors whose cosine similarity measures how
+// related the texts are.
+type Embedder interface {
+	// Name identifies the embedder and its model; an index built with a
+	// different name has its vectors recomputed.
+	Name() string
+	// Embed returns a vector for each text.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// LocalEmbedder is a stand-in for an embeddings model that needs no
+// network or model weights. It hashes each word and its character
+// trigrams into a fixed number of dimensions, so related spellings
+// ("indexer", "indexing") land near each other. It knows nothing of
+// synonyms.
+type LocalEmbedder struct{}
+
+// localDims is the length of LocalEmbedder's vectors.
+const localDims = 256
+
+// Name implements Embedder.
+func (LocalEmbedder) Name() string { return "local-hash-v1" }
+
+// Embed implements Embedder.
+func (LocalEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, localDims)
+		for _, w := range words(text) {
+			addFeature(v, w, 1)
+			padded := "^" + w + "$"
+			for j := 0; j+3 <= len(padded); j++ {
+				addFeature(v, padded[j:j+3], 0.5)
+			}
+		}
+		normalize(v)
+		out[i] = v
+	}
+	return out, nil
+}
+
+// addFeature adds weight to the dimension feature hashes to, with a sign
+// from the hash so that collisions cancel out on average.
+func addFeature(v []float32, feature string, weight float32) {
+	h := fnv.New32a()
+	h.Write([]byte(feature))
+	sum := h.Sum32()
+	if sum&1 != 0 {
+		weight = -weight
+	}
+	v[(sum>>1)%uint32(len(v))] += weight
+}
+
+func normalize(v []float32) {
+	var sq float64
+	for _, x := range v {
+		sq += float64(x) * float64(x)
+	}
+	if sq == 0 {
+		return
+	}
+	n := float32(1 / math.Sqrt(sq))
+	for i := range v {
+		v[i] *= n
+	}
+}
+
+// cosine returns the cosine similarity of two normalized vectors.
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
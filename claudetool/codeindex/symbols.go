@@ -0,0 +1,345 @@
+package codeindex
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// A Symbol is a declaration in a source file.
+type Symbol struct {
+	Name string `json:"name"`
+	// Kind is one of func, method, type, class, interface, enum, const
+	// and var.
+	Kind string `json:"kind"`
+	// Container is the type or class a method belongs to.
+	Container string `json:"container,omitempty"`
+	Line      int    `json:"line"`
+	Exported  bool   `json:"exported,omitempty"`
+	// Doc is the first sentence of the symbol's doc comment, if any.
+	Doc string `json:"doc,omitempty"`
+}
+
+// QualifiedName returns the symbol's name with its container, if any.
+func (s Symbol) QualifiedName() string {
+	if s.Container == "" {
+		return s.Name
+	}
+	return s.Container + "." + s.Name
+}
+
+// Language returns the language of the file at name, or "" if the index
+// doesn't read it.
+func Language(name string) string {
+	switch path.Ext(name) {
+	case ".go":
+		return "go"
+	case ".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs":
+		if strings.HasSuffix(name, ".min.js") {
+			return ""
+		}
+		return "ts"
+	case ".py":
+		return "python"
+	}
+	return ""
+}
+
+// Symbols returns the declarations in the source of the file at name, or
+// nil if the index doesn't read files of its kind.
+func Symbols(name string, src []byte) []Symbol {
+	return symbols(Language(name), src)
+}
+
+// symbols returns the declarations in a file's source.
+func symbols(lang string, src []byte) []Symbol {
+	switch lang {
+	case "go":
+		return goSymbols(src)
+	case "ts":
+		return tsSymbols(src)
+	case "python":
+		return pythonSymbols(src)
+	}
+	return nil
+}
+
+func goSymbols(src []byte) []Symbol {
+	fset := token.NewFileSet()
+	// A file that doesn't parse still yields the declarations before the
+	// error.
+	f, _ := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if f == nil {
+		return nil
+	}
+	var syms []Symbol
+	add := func(name *ast.Ident, kind, container string, doc *ast.CommentGroup) {
+		if name == nil || name.Name == "_" {
+			return
+		}
+		syms = append(syms, Symbol{
+			Name:      name.Name,
+			Kind:      kind,
+			Container: container,
+			Line:      fset.Position(name.Pos()).Line,
+			Exported:  ast.IsExported(name.Name) && (container == "" || ast.IsExported(container)),
+			Doc:       firstSentence(doc.Text()),
+		})
+	}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil || len(d.Recv.List) == 0 {
+				add(d.Name, "func", "", d.Doc)
+				continue
+			}
+			add(d.Name, "method", receiverType(d.Recv.List[0].Type), d.Doc)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				doc := d.Doc
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Doc != nil {
+						doc = s.Doc
+					}
+					add(s.Name, "type", "", doc)
+					if it, ok := s.Type.(*ast.InterfaceType); ok {
+						for _, m := range it.Methods.List {
+							for _, name := range m.Names {
+								add(name, "method", s.Name.Name, m.Doc)
+							}
+						}
+					}
+				case *ast.ValueSpec:
+					if s.Doc != nil {
+						doc = s.Doc
+					}
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					for _, name := range s.Names {
+						add(name, kind, "", doc)
+					}
+				}
+			}
+		}
+	}
+	return syms
+}
+
+// receiverType returns the name of a method receiver's type.
+func receiverType(expr ast.Expr) string {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// firstSentence returns the first sentence of a doc comment.
+func firstSentence(doc string) string {
+	doc = strings.Join(strings.Fields(doc), " ")
+	if i := strings.Index(doc, ". "); i >= 0 {
+		doc = doc[:i+1]
+	}
+	const maxDoc = 200
+	if len(doc) > maxDoc {
+		doc = doc[:maxDoc] + "…"
+	}
+	return doc
+}
+
+var (
+	tsFunc      = regexp.MustCompile(`^(export\s+)?(default\s+)?(async\s+)?function\s*\*?\s*([A-Za-z_$][\w$]*)`)
+	tsClass     = regexp.MustCompile(`^(export\s+)?(default\s+)?(abstract\s+)?class\s+([A-Za-z_$][\w$]*)`)
+	tsInterface = regexp.MustCompile(`^(export\s+)?(declare\s+)?interface\s+([A-Za-z_$][\w$]*)`)
+	tsType      = regexp.MustCompile(`^(export\s+)?(declare\s+)?type\s+([A-Za-z_$][\w$]*)\s*(<.*>)?\s*=`)
+	tsEnum      = regexp.MustCompile(`^(export\s+)?(declare\s+)?(const\s+)?enum\s+([A-Za-z_$][\w$]*)`)
+	tsVar       = regexp.MustCompile(`^(export\s+)?(declare\s+)?(const|let|var)\s+([A-Za-z_$][\w$]*)`)
+	// tsArrow matches the rest of a variable declaration that holds a
+	// function: "= (a, b) =>", "= async x =>", "= function".
+	tsArrow = regexp.MustCompile(`^\s*(:[^=]+)?=\s*(async\s+)?(\([^)]*\)|[A-Za-z_$][\w$]*)\s*(:\s*[^=]+)?=>|^\s*(:[^=]+)?=\s*(async\s+)?function\b|^\s*(:[^=]+)?=\s*React\.(memo|forwardRef)\b`)
+	// tsMethod matches a method in a class body.
+	tsMethod = regexp.MustCompile(`^((?:public|private|protected|static|async|readonly|override|abstract|get|set)\s+)*\*?\s*([A-Za-z_$#][\w$]*)\s*(<[^>]*>)?\s*\(`)
+)
+
+// tsKeywords are words that look like method names at the start of a
+// line in a class body but aren't.
+var tsKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true,
+	"return": true, "function": true, "super": true, "await": true, "new": true,
+	"throw": true, "typeof": true, "do": true, "else": true, "this": true,
+}
+
+// tsSymbols finds the declarations in TypeScript or JavaScript source by
+// their shape, which is enough for the declarations that matter: those at
+// the top level and the methods of classes.
+func tsSymbols(src []byte) []Symbol {
+	var syms []Symbol
+	var class string // the class whose body is being read
+	classIndent, bodyIndent := -1, -1
+	for i, line := range strings.Split(string(src), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "*") || strings.HasPrefix(trimmed, "/*") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		if class != "" && indent <= classIndent {
+			class, classIndent, bodyIndent = "", -1, -1
+		}
+		lineNo := i + 1
+		exported := strings.HasPrefix(trimmed, "export ")
+		add := func(name, kind string) {
+			syms = append(syms, Symbol{Name: name, Kind: kind, Line: lineNo, Exported: exported})
+		}
+
+		if class != "" && indent > classIndent {
+			// Members are at the indentation of the body's first line;
+			// deeper lines are inside them.
+			if bodyIndent < 0 {
+				bodyIndent = indent
+			}
+			if indent != bodyIndent {
+				continue
+			}
+			if m := tsMethod.FindStringSubmatch(trimmed); m != nil && !tsKeywords[m[2]] && !strings.HasSuffix(trimmed, ";") {
+				syms = append(syms, Symbol{Name: m[2], Kind: "method", Container: class, Line: lineNo, Exported: !strings.HasPrefix(m[2], "#") && !strings.Contains(m[0], "private ")})
+			}
+			continue
+		}
+		if indent > 0 {
+			continue // inside a function or an object
+		}
+		switch {
+		case tsClass.MatchString(trimmed):
+			m := tsClass.FindStringSubmatch(trimmed)
+			add(m[4], "class")
+			if !strings.HasSuffix(trimmed, "}") {
+				class, classIndent = m[4], indent
+			}
+		case tsFunc.MatchString(trimmed):
+			add(tsFunc.FindStringSubmatch(trimmed)[4], "func")
+		case tsInterface.MatchString(trimmed):
+			add(tsInterface.FindStringSubmatch(trimmed)[3], "interface")
+		case tsType.MatchString(trimmed):
+			add(tsType.FindStringSubmatch(trimmed)[3], "type")
+		case tsEnum.MatchString(trimmed):
+			add(tsEnum.FindStringSubmatch(trimmed)[4], "enum")
+		case tsVar.MatchString(trimmed):
+			m := tsVar.FindStringSubmatchIndex(trimmed)
+			name := trimmed[m[8]:m[9]]
+			kind := "var"
+			if tsArrow.MatchString(trimmed[m[1]:]) {
+				kind = "func"
+			}
+			add(name, kind)
+		}
+	}
+	return syms
+}
+
+var (
+	pyDef      = regexp.MustCompile(`^(\s*)(?:async\s+)?def\s+(\w+)`)
+	pyClass    = regexp.MustCompile(`^(\s*)class\s+(\w+)`)
+	pyConstant = regexp.MustCompile(`^([A-Z][A-Z0-9_]*)\s*(?::[^=]+)?=`)
+	pyDocLine  = regexp.MustCompile(`^\s*[rRbBuU]?("""|''')(.*)`)
+)
+
+// pythonSymbols finds the classes, functions and module constants in
+// Python source. Functions nested in functions are left out.
+func pythonSymbols(src []byte) []Symbol {
+	type scope struct {
+		indent int
+		class  string // "" for a function
+	}
+	var syms []Symbol
+	var stack []scope
+	lines := strings.Split(string(src), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		for len(stack) > 0 && indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		var container string
+		inFunc := false
+		if len(stack) > 0 {
+			top := stack[len(stack)-1]
+			container, inFunc = top.class, top.class == ""
+		}
+
+		if m := pyClass.FindStringSubmatch(line); m != nil {
+			if !inFunc {
+				syms = append(syms, Symbol{Name: m[2], Kind: "class", Container: container, Line: i + 1, Exported: !strings.HasPrefix(m[2], "_"), Doc: pyDoc(lines[i+1:])})
+			}
+			stack = append(stack, scope{indent, m[2]})
+			continue
+		}
+		if m := pyDef.FindStringSubmatch(line); m != nil {
+			if !inFunc {
+				kind := "func"
+				if container != "" {
+					kind = "method"
+				}
+				name := m[2]
+				exported := !strings.HasPrefix(name, "_") || strings.HasPrefix(name, "__") && strings.HasSuffix(name, "__")
+				syms = append(syms, Symbol{Name: name, Kind: kind, Container: container, Line: i + 1, Exported: exported, Doc: pyDoc(lines[i+1:])})
+			}
+			stack = append(stack, scope{indent, ""})
+			continue
+		}
+		if indent == 0 {
+			if m := pyConstant.FindStringSubmatch(line); m != nil {
+				syms = append(syms, Symbol{Name: m[1], Kind: "const", Line: i + 1, Exported: true})
+			}
+		}
+	}
+	return syms
+}
+
+// pyDoc returns the first sentence of the docstring that starts the body
+// whose lines follow a def or class line.
+func pyDoc(body []string) string {
+	for j, line := range body {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		m := pyDocLine.FindStringSubmatch(line)
+		if m == nil {
+			return ""
+		}
+		var text strings.Builder
+		rest := m[2]
+		for k := j + 1; ; k++ {
+			if before, _, ok := strings.Cut(rest, m[1]); ok {
+				text.WriteString(before)
+				break
+			}
+			text.WriteString(rest + " ")
+			if k >= len(body) || k-j > 20 {
+				break
+			}
+			rest = body[k]
+		}
+		return firstSentence(text.String())
+	}
+	return ""
+}
@@ -0,0 +1,166 @@
+package codeindex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGoSymbols(t *testing.T) {
+	src := `package p
+
+// Server serves things. It is long-lived.
+type Server struct{}
+
+// Handler handles requests.
+type Handler interface {
+	Handle(req string) error
+}
+
+const (
+	// MaxSize is the largest size.
+	MaxSize = 10
+	minSize = 1
+)
+
+var errNope = 1
+
+// New returns a Server.
+func New() *Server { return nil }
+
+func (s *Server) Start() {}
+
+func (l list[T]) len() int { return 0 }
+`
+	want := []Symbol{
+		{Name: "Server", Kind: "type", Line: 4, Exported: true, Doc: "Server serves things."},
+		{Name: "Handler", Kind: "type", Line: 7, Exported: true, Doc: "Handler handles requests."},
+		{Name: "Handle", Kind: "method", Container: "Handler", Line: 8, Exported: true},
+		{Name: "MaxSize", Kind: "const", Line: 13, Exported: true, Doc: "MaxSize is the largest size."},
+		{Name: "minSize", Kind: "const", Line: 14},
+		{Name: "errNope", Kind: "var", Line: 17},
+		{Name: "New", Kind: "func", Line: 20, Exported: true, Doc: "New returns a Server."},
+		{Name: "Start", Kind: "method", Container: "Server", Line: 22, Exported: true},
+		{Name: "len", Kind: "method", Container: "list", Line: 24},
+	}
+	if got := symbols("go", []byte(src)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestTSSymbols(t *testing.T) {
+	src := `import React from "react";
+
+export interface Props {
+  name: string;
+}
+
+export type Mode = "a" | "b";
+
+export enum Color { Red, Green }
+
+const LIMIT = 64;
+
+export const handleClick = async (e: Event): Promise<void> => {
+  if (e) {
+    doThing();
+  }
+};
+
+export default function App({ name }: Props) {
+  return null;
+}
+
+export class Store<T> extends Base {
+  private items: T[] = [];
+
+  constructor(private api: Api) {
+    super();
+  }
+
+  async load(id: string): Promise<T> {
+    if (id) {
+      return this.items[0];
+    }
+    helper(id);
+  }
+
+  private reset() {}
+}
+
+function helper(x) {}
+`
+	want := []Symbol{
+		{Name: "Props", Kind: "interface", Line: 3, Exported: true},
+		{Name: "Mode", Kind: "type", Line: 7, Exported: true},
+		{Name: "Color", Kind: "enum", Line: 9, Exported: true},
+		{Name: "LIMIT", Kind: "var", Line: 11},
+		{Name: "handleClick", Kind: "func", Line: 13, Exported: true},
+		{Name: "App", Kind: "func", Line: 19, Exported: true},
+		{Name: "Store", Kind: "class", Line: 23, Exported: true},
+		{Name: "constructor", Kind: "method", Container: "Store", Line: 26, Exported: true},
+		{Name: "load", Kind: "method", Container: "Store", Line: 30, Exported: true},
+		{Name: "reset", Kind: "method", Container: "Store", Line: 37},
+		{Name: "helper", Kind: "func", Line: 40},
+	}
+	if got := symbols("ts", []byte(src)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestPythonSymbols(t *testing.T) {
+	src := `"""Module doc."""
+import os
+
+MAX_RETRIES = 3
+
+
+class Client(Base):
+    """A client for the API.
+
+    More detail.
+    """
+
+    def __init__(self):
+        def inner():
+            pass
+
+    async def fetch(self, url):
+        return None
+
+    def _private(self):
+        pass
+
+
+def main():
+    '''Run it.'''
+    class Local:
+        pass
+`
+	want := []Symbol{
+		{Name: "MAX_RETRIES", Kind: "const", Line: 4, Exported: true},
+		{Name: "Client", Kind: "class", Line: 7, Exported: true, Doc: "A client for the API."},
+		{Name: "__init__", Kind: "method", Container: "Client", Line: 13, Exported: true},
+		{Name: "fetch", Kind: "method", Container: "Client", Line: 17, Exported: true},
+		{Name: "_private", Kind: "method", Container: "Client", Line: 20},
+		{Name: "main", Kind: "func", Line: 24, Exported: true, Doc: "Run it."},
+	}
+	if got := symbols("python", []byte(src)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestSplitIdentifier(t *testing.T) {
+	tests := map[string][]string{
+		"parseHTTPHeader": {"parse", "http", "header"},
+		"keyword_search":  {"keyword", "search"},
+		"MaxSize":         {"max", "size"},
+		"utf8Valid":       {"utf", "8", "valid"},
+		"ID":              {"id"},
+		"__init__":        {"init"},
+	}
+	for in, want := range tests {
+		if got := splitIdentifier(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitIdentifier(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
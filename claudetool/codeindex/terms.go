@@ -0,0 +1,93 @@
+package codeindex
+
+import (
+	"strings"
+	"unicode"
+)
+
+// words splits text into lower-case search terms. Identifiers are split
+// at case changes, underscores and digits, so that "parseHTTPHeader"
+// yields "parse", "http" and "header"; an identifier made of several
+// words also yields itself, so that exact names match too.
+func words(text string) []string {
+	var out []string
+	for _, ident := range identifiers(text) {
+		parts := splitIdentifier(ident)
+		for _, p := range parts {
+			if len(p) > 1 {
+				out = append(out, p)
+			}
+		}
+		if len(parts) > 1 {
+			out = append(out, strings.ToLower(ident))
+		}
+	}
+	return out
+}
+
+// identifiers returns the runs of letters, digits and underscores in text.
+func identifiers(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// splitIdentifier splits an identifier into its lower-case words.
+func splitIdentifier(ident string) []string {
+	var parts []string
+	runes := []rune(ident)
+	start := 0
+	flush := func(end int) {
+		if end > start {
+			parts = append(parts, strings.ToLower(string(runes[start:end])))
+		}
+		start = end
+	}
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '_':
+			flush(i)
+			start = i + 1
+		case i == start:
+		case unicode.IsDigit(r) != unicode.IsDigit(runes[i-1]):
+			flush(i)
+		case unicode.IsUpper(r) && unicode.IsLower(runes[i-1]):
+			// parseHTTP: a word starts at the H.
+			flush(i)
+		case unicode.IsUpper(r) && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]):
+			// HTTPHeader: a word starts at the second H.
+			flush(i)
+		}
+	}
+	flush(len(runes))
+	return parts
+}
+
+// stopwords are words of a query that say nothing about the code.
+var stopwords = map[string]bool{
+	"a": true, "an": true, "and": true, "any": true, "are": true, "as": true,
+	"at": true, "be": true, "by": true, "code": true, "does": true, "find": true,
+	"for": true, "from": true, "how": true, "in": true, "is": true, "it": true,
+	"of": true, "on": true, "or": true, "that": true, "the": true, "this": true,
+	"to": true, "use": true, "used": true, "uses": true, "what": true,
+	"when": true, "where": true, "which": true, "with": true, "all": true,
+	"get": true, "set": true, "we": true, "do": true,
+}
+
+// queryWords returns the distinct search terms of a query, leaving out
+// stopwords.
+func queryWords(texts ...string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, text := range texts {
+		for _, w := range words(text) {
+			if stopwords[w] || seen[w] {
+				continue
+			}
+			seen[w] = true
+			out = append(out, w)
+		}
+	}
+	return out
+}
@@ -0,0 +1,124 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"shelley.exe.dev/claudetool/codeindex"
+	"shelley.exe.dev/llm"
+)
+
+// CodeSearchTool searches the code index of the repository the working
+// directory is in.
+type CodeSearchTool struct {
+	// Indexes holds the code indexes.
+	Indexes *codeindex.Indexes
+	// WorkingDir is the shared mutable working directory.
+	WorkingDir *MutableWorkingDir
+}
+
+const (
+	codeSearchName        = "code_search"
+	codeSearchDescription = `Searches an index of the repository's Go, TypeScript, JavaScript and Python code, and returns the best matching files with the functions, types and methods in them that match, with line numbers.
+
+Use it to find where something is implemented or declared when you know what it does but not what it is called. It is fast and can be called freely. Describe what you want in words and identifiers, e.g. "retry failed webhook deliveries" or "SessionStore expire".
+
+For exact strings such as error messages or log lines, use rg instead.
+`
+	codeSearchInputSchema = `
+{
+  "type": "object",
+  "required": ["query"],
+  "properties": {
+    "query": {
+      "type": "string",
+      "description": "What to find, in words and identifiers"
+    },
+    "kind": {
+      "type": "string",
+      "enum": ["func", "method", "type", "class", "interface", "enum", "const", "var"],
+      "description": "Only return files declaring a matching symbol of this kind"
+    },
+    "path": {
+      "type": "string",
+      "description": "Only search under this directory, relative to the repository root"
+    },
+    "limit": {
+      "type": "integer",
+      "description": "Most files to return (default 10)"
+    }
+  }
+}
+`
+	maxCodeSearchResults = 50
+)
+
+type codeSearchInput struct {
+	Query string `json:"query"`
+	Kind  string `json:"kind"`
+	Path  string `json:"path"`
+	Limit int    `json:"limit"`
+}
+
+// Tool returns an llm.Tool based on t.
+func (t *CodeSearchTool) Tool() *llm.Tool {
+	return &llm.Tool{
+		Name:        codeSearchName,
+		Description: strings.TrimSpace(codeSearchDescription),
+		InputSchema: llm.MustSchema(codeSearchInputSchema),
+		Run:         t.Run,
+	}
+}
+
+// Run runs the code_search tool.
+func (t *CodeSearchTool) Run(ctx context.Context, m json.RawMessage) llm.ToolOut {
+	var req codeSearchInput
+	if err := json.Unmarshal(m, &req); err != nil {
+		return llm.ErrorfToolOut("failed to parse code_search input: %w", err)
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		return llm.ErrorfToolOut("query is required")
+	}
+	ix := t.Indexes.For(repoRootOrDir(t.WorkingDir.Get()))
+	results, err := ix.Search(ctx, codeindex.Query{
+		Text:  req.Query,
+		Kind:  req.Kind,
+		Path:  req.Path,
+		Limit: min(req.Limit, maxCodeSearchResults),
+	})
+	if err != nil {
+		return llm.ErrorfToolOut("code search failed: %w", err)
+	}
+	if len(results) == 0 {
+		return llm.ToolOut{LLMContent: llm.TextContent(fmt.Sprintf("no matches in %s (%d files indexed)", ix.Root(), ix.Len()))}
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(formatCodeSearchResults(ix.Root(), results))}
+}
+
+// formatCodeSearchResults lists results with their matching symbols.
+func formatCodeSearchResults(root string, results []codeindex.Result) string {
+	sb := new(strings.Builder)
+	fmt.Fprintf(sb, "In %s, best match first:\n", root)
+	for _, r := range results {
+		fmt.Fprintf(sb, "\n%s\n", r.Path)
+		for _, s := range r.Symbols {
+			fmt.Fprintf(sb, "  %d: %s %s", s.Line, s.Kind, s.QualifiedName())
+			if s.Doc != "" {
+				fmt.Fprintf(sb, " — %s", s.Doc)
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// repoRootOrDir returns the root of the git repository dir is in, or dir
+// if it isn't in one.
+func repoRootOrDir(dir string) string {
+	if root, err := FindRepoRoot(dir); err == nil {
+		return root
+	}
+	return dir
+}
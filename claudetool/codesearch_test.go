@@ -0,0 +1,75 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/claudetool/codeindex"
+)
+
+func newTestIndexes(t *testing.T) (*codeindex.Indexes, string) {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"queue/retry.go": "package queue\n\n// RetryPolicy decides when to retry a failed delivery.\ntype RetryPolicy struct{}\n\n// NextDelay returns how long to wait before retrying.\nfunc (p RetryPolicy) NextDelay(attempt int) int { return attempt }\n",
+		"web/api.ts":     "export async function fetchWebhooks() {\n  return [];\n}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	indexes, err := codeindex.New(codeindex.Config{CacheDir: "none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return indexes, dir
+}
+
+func TestCodeSearchTool(t *testing.T) {
+	indexes, dir := newTestIndexes(t)
+	tool := &CodeSearchTool{Indexes: indexes, WorkingDir: NewMutableWorkingDir(dir)}
+
+	out := tool.Run(context.Background(), json.RawMessage(`{"query": "retry delay for failed deliveries"}`))
+	if out.Error != nil {
+		t.Fatal(out.Error)
+	}
+	text := out.LLMContent[0].Text
+	for _, want := range []string{"queue/retry.go", "7: method RetryPolicy.NextDelay — NextDelay returns how long to wait before retrying.", "4: type RetryPolicy"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output does not contain %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "web/api.ts") {
+		t.Errorf("output contains an unrelated file:\n%s", text)
+	}
+
+	out = tool.Run(context.Background(), json.RawMessage(`{"query": "invoices"}`))
+	if out.Error != nil || !strings.HasPrefix(out.LLMContent[0].Text, "no matches") {
+		t.Errorf("got %v %v, want no matches", out.Error, out.LLMContent)
+	}
+}
+
+func TestKeywordIndexResults(t *testing.T) {
+	indexes, dir := newTestIndexes(t)
+	k := NewKeywordToolWithWorkingDir(&mockLLMProvider{}, NewMutableWorkingDir(dir))
+	input := keywordInput{Query: "where are webhooks fetched", SearchTerms: []string{"fetchWebhooks"}}
+	if got := k.indexResults(context.Background(), dir, input); got != "" {
+		t.Errorf("without an index: %q", got)
+	}
+
+	k.index = indexes
+	got := k.indexResults(context.Background(), dir, input)
+	want := filepath.Join(dir, "web/api.ts") + ": func fetchWebhooks (line 1)\n"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
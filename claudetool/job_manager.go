@@ -0,0 +1,358 @@
+package claudetool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// jobOutputLimit is how much of a job's most recent output is kept.
+	jobOutputLimit = 1024 * 1024
+	// maxRunningJobs is how many jobs a conversation may have running at once.
+	maxRunningJobs = 16
+	// maxFinishedJobs is how many finished jobs a conversation keeps around
+	// for inspection; older ones are forgotten.
+	maxFinishedJobs = 16
+	// jobInputTimeout bounds how long sending input waits for a job that
+	// isn't reading it.
+	jobInputTimeout = 10 * time.Second
+)
+
+// jobStopGrace is how long a job has to exit after SIGTERM before its
+// process group is killed.
+var jobStopGrace = 5 * time.Second
+
+// JobManager runs background bash jobs and keeps track of them by
+// conversation. Jobs outlive the tool call that started them, and the
+// conversation's loop too; they end when they exit or are stopped.
+// It is safe for concurrent use.
+type JobManager struct {
+	mu   sync.Mutex
+	next int
+	jobs map[string][]*Job // conversation ID -> jobs, oldest first
+}
+
+// NewJobManager returns an empty JobManager.
+func NewJobManager() *JobManager {
+	return &JobManager{jobs: make(map[string][]*Job)}
+}
+
+// Job is a background command.
+type Job struct {
+	ID             string
+	ConversationID string
+	Command        string
+	Dir            string
+	StartedAt      time.Time
+
+	cmd   *exec.Cmd
+	stdin *os.File
+	out   *jobOutput
+	done  chan struct{}
+
+	mu       sync.Mutex
+	endedAt  time.Time
+	exitCode int // -1 if killed by a signal
+	waitErr  error
+	stopped  bool
+}
+
+// JobInfo describes a job for the UI.
+type JobInfo struct {
+	ID          string     `json:"id"`
+	Command     string     `json:"command"`
+	Dir         string     `json:"dir"`
+	PID         int        `json:"pid"`
+	StartedAt   time.Time  `json:"started_at"`
+	EndedAt     *time.Time `json:"ended_at,omitempty"`
+	Running     bool       `json:"running"`
+	ExitCode    *int       `json:"exit_code,omitempty"`
+	Stopped     bool       `json:"stopped,omitempty"`
+	OutputBytes int64      `json:"output_bytes"`
+}
+
+// Start starts cmd as a job of the given conversation. The job's output
+// and input replace cmd's; cmd must not have been started.
+func (m *JobManager) Start(conversationID, command string, cmd *exec.Cmd) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	running := 0
+	for _, j := range m.jobs[conversationID] {
+		if j.Running() {
+			running++
+		}
+	}
+	if running >= maxRunningJobs {
+		return nil, fmt.Errorf("too many running jobs (%d); stop some with the jobs tool first", running)
+	}
+
+	out := new(jobOutput)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	// Use an os.Pipe rather than cmd.StdinPipe, so that writes can time
+	// out when the job doesn't read its input.
+	stdinR, stdin, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdin = stdinR
+	err = cmd.Start()
+	stdinR.Close()
+	if err != nil {
+		stdin.Close()
+		return nil, fmt.Errorf("command failed: %w", err)
+	}
+
+	m.next++
+	j := &Job{
+		ID:             fmt.Sprintf("job%d", m.next),
+		ConversationID: conversationID,
+		Command:        command,
+		Dir:            cmd.Dir,
+		StartedAt:      time.Now(),
+		cmd:            cmd,
+		stdin:          stdin,
+		out:            out,
+		done:           make(chan struct{}),
+	}
+	go j.wait()
+	m.jobs[conversationID] = forgetFinishedJobs(append(m.jobs[conversationID], j))
+	return j, nil
+}
+
+// forgetFinishedJobs drops the oldest finished jobs beyond maxFinishedJobs.
+func forgetFinishedJobs(jobs []*Job) []*Job {
+	finished := 0
+	for _, j := range jobs {
+		if !j.Running() {
+			finished++
+		}
+	}
+	kept := jobs[:0]
+	for _, j := range jobs {
+		if finished > maxFinishedJobs && !j.Running() {
+			finished--
+			continue
+		}
+		kept = append(kept, j)
+	}
+	return kept
+}
+
+// Get returns the job of the conversation with the given ID.
+func (m *JobManager) Get(conversationID, id string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, j := range m.jobs[conversationID] {
+		if j.ID == id {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+// List returns the jobs of the conversation, oldest first.
+func (m *JobManager) List(conversationID string) []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Job(nil), m.jobs[conversationID]...)
+}
+
+// StopConversation stops every job of the conversation and forgets them.
+func (m *JobManager) StopConversation(conversationID string) {
+	m.mu.Lock()
+	jobs := m.jobs[conversationID]
+	delete(m.jobs, conversationID)
+	m.mu.Unlock()
+	stopJobs(jobs)
+}
+
+// StopAll stops every job and forgets them.
+func (m *JobManager) StopAll() {
+	m.mu.Lock()
+	var jobs []*Job
+	for _, js := range m.jobs {
+		jobs = append(jobs, js...)
+	}
+	clear(m.jobs)
+	m.mu.Unlock()
+	stopJobs(jobs)
+}
+
+func stopJobs(jobs []*Job) {
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Go(j.Stop)
+	}
+	wg.Wait()
+}
+
+// wait waits for the job to exit and records how it ended.
+func (j *Job) wait() {
+	err := j.cmd.Wait()
+	j.mu.Lock()
+	j.endedAt = time.Now()
+	j.exitCode = j.cmd.ProcessState.ExitCode()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		j.waitErr = err
+	}
+	j.mu.Unlock()
+	close(j.done)
+}
+
+// PID returns the process ID of the job's shell.
+func (j *Job) PID() int {
+	return j.cmd.Process.Pid
+}
+
+// Running reports whether the job is still running.
+func (j *Job) Running() bool {
+	select {
+	case <-j.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Done returns a channel that is closed when the job exits.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Status describes the job in one line, such as "running for 2m0s" or
+// "exited with status 1 after 3s".
+func (j *Job) Status() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.Running() {
+		return fmt.Sprintf("running for %s", time.Since(j.StartedAt).Round(time.Second))
+	}
+	took := j.endedAt.Sub(j.StartedAt).Round(time.Millisecond)
+	switch {
+	case j.waitErr != nil:
+		return fmt.Sprintf("failed after %s: %v", took, j.waitErr)
+	case j.stopped:
+		return fmt.Sprintf("stopped after %s", took)
+	case j.exitCode < 0:
+		return fmt.Sprintf("killed by a signal after %s", took)
+	default:
+		return fmt.Sprintf("exited with status %d after %s", j.exitCode, took)
+	}
+}
+
+// Info describes the job for the UI.
+func (j *Job) Info() JobInfo {
+	info := JobInfo{
+		ID:          j.ID,
+		Command:     j.Command,
+		Dir:         j.Dir,
+		PID:         j.PID(),
+		StartedAt:   j.StartedAt,
+		Running:     j.Running(),
+		OutputBytes: j.out.Total(),
+	}
+	if !info.Running {
+		j.mu.Lock()
+		info.EndedAt = new(j.endedAt)
+		if j.exitCode >= 0 {
+			info.ExitCode = new(j.exitCode)
+		}
+		info.Stopped = j.stopped
+		j.mu.Unlock()
+	}
+	return info
+}
+
+// Output returns the job's retained output, and whether earlier output
+// was discarded.
+func (j *Job) Output() (string, bool) {
+	return j.out.Contents()
+}
+
+// SendInput writes s to the job's standard input.
+func (j *Job) SendInput(s string) error {
+	if !j.Running() {
+		return fmt.Errorf("job %s is not running", j.ID)
+	}
+	j.stdin.SetWriteDeadline(time.Now().Add(jobInputTimeout))
+	if _, err := io.WriteString(j.stdin, s); err != nil {
+		return fmt.Errorf("failed to write to job %s: %w", j.ID, err)
+	}
+	return nil
+}
+
+// CloseInput closes the job's standard input.
+func (j *Job) CloseInput() error {
+	return j.stdin.Close()
+}
+
+// Stop stops the job: its process group gets SIGTERM, then SIGKILL if it
+// hasn't exited after jobStopGrace. Stop returns once the job has exited.
+func (j *Job) Stop() {
+	if !j.Running() {
+		return
+	}
+	j.mu.Lock()
+	j.stopped = true
+	j.mu.Unlock()
+	j.stdin.Close()
+	pgid := -j.PID()
+	syscall.Kill(pgid, syscall.SIGTERM)
+	select {
+	case <-j.done:
+	case <-time.After(jobStopGrace):
+		syscall.Kill(pgid, syscall.SIGKILL)
+		<-j.done
+	}
+}
+
+// jobOutput collects a job's output, keeping at least the most recent
+// jobOutputLimit bytes.
+type jobOutput struct {
+	mu    sync.Mutex
+	buf   []byte
+	total int64
+}
+
+func (o *jobOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.total += int64(len(p))
+	o.buf = append(o.buf, p...)
+	// Trim only once the buffer is twice the limit, so that a chatty job
+	// doesn't copy the whole buffer on every write.
+	if len(o.buf) > 2*jobOutputLimit {
+		over := len(o.buf) - jobOutputLimit
+		// Drop whole lines where possible, so that the output doesn't
+		// start partway through one.
+		if i := bytes.IndexByte(o.buf[over:], '\n'); i >= 0 && i < 4096 {
+			over += i + 1
+		}
+		o.buf = append(o.buf[:0], o.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// Contents returns the retained output, and whether any was discarded.
+func (o *jobOutput) Contents() (string, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return string(o.buf), o.total > int64(len(o.buf))
+}
+
+// Total returns how many bytes have been written in all.
+func (o *jobOutput) Total() int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.total
+}
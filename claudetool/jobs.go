@@ -0,0 +1,208 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"shelley.exe.dev/llm"
+)
+
+// JobsTool inspects and controls the background jobs started by the bash
+// tool.
+type JobsTool struct {
+	// Jobs holds the jobs.
+	Jobs *JobManager
+	// ConversationID is the conversation whose jobs the tool sees.
+	ConversationID string
+}
+
+const (
+	jobsName        = "jobs"
+	jobsDescription = `Inspects and controls background jobs started with bash background=true.
+
+Actions:
+- list: show every job with its status
+- output: show the last lines of a job's output (lines, default 50), or only
+  the lines matching a regular expression (grep)
+- send: write input to a job's stdin, verbatim; end it with a newline to
+  submit a line. Set close_input=true to close stdin afterwards.
+- stop: stop a job (SIGTERM to its process group, then SIGKILL)
+
+Jobs keep running after the call that started them, until they exit, are
+stopped, or the conversation is archived.
+`
+	jobsInputSchema = `
+{
+  "type": "object",
+  "required": ["action"],
+  "properties": {
+    "action": {
+      "type": "string",
+      "enum": ["list", "output", "send", "stop"]
+    },
+    "id": {
+      "type": "string",
+      "description": "Job ID, required except for list"
+    },
+    "lines": {
+      "type": "integer",
+      "description": "For output: how many lines to show, from the end"
+    },
+    "grep": {
+      "type": "string",
+      "description": "For output: only show lines matching this regular expression"
+    },
+    "input": {
+      "type": "string",
+      "description": "For send: text to write to the job's stdin"
+    },
+    "close_input": {
+      "type": "boolean",
+      "description": "For send: close stdin after writing"
+    }
+  }
+}
+`
+	defaultJobOutputLines = 50
+	maxJobOutputLines     = 1000
+)
+
+type jobsInput struct {
+	Action     string `json:"action"`
+	ID         string `json:"id"`
+	Lines      int    `json:"lines"`
+	Grep       string `json:"grep"`
+	Input      string `json:"input"`
+	CloseInput bool   `json:"close_input"`
+}
+
+// Tool returns an llm.Tool based on t.
+func (t *JobsTool) Tool() *llm.Tool {
+	return &llm.Tool{
+		Name:        jobsName,
+		Description: strings.TrimSpace(jobsDescription),
+		InputSchema: llm.MustSchema(jobsInputSchema),
+		Run:         t.Run,
+	}
+}
+
+// Run runs the jobs tool.
+func (t *JobsTool) Run(ctx context.Context, m json.RawMessage) llm.ToolOut {
+	var req jobsInput
+	if err := json.Unmarshal(m, &req); err != nil {
+		return llm.ErrorfToolOut("failed to parse jobs input: %w", err)
+	}
+
+	if req.Action == "list" {
+		jobs := t.Jobs.List(t.ConversationID)
+		if len(jobs) == 0 {
+			return llm.ToolOut{LLMContent: llm.TextContent("no jobs")}
+		}
+		sb := new(strings.Builder)
+		for _, j := range jobs {
+			fmt.Fprintf(sb, "%s (pid %d) %s: %s\n", j.ID, j.PID(), j.Status(), j.Command)
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent(sb.String())}
+	}
+
+	if req.ID == "" {
+		return llm.ErrorfToolOut("id is required for %s", req.Action)
+	}
+	j, ok := t.Jobs.Get(t.ConversationID, req.ID)
+	if !ok {
+		return llm.ErrorfToolOut("no job %q (use action=list to see jobs)", req.ID)
+	}
+
+	switch req.Action {
+	case "output":
+		out, err := jobOutputLines(j, req.Lines, req.Grep)
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent(out)}
+	case "send":
+		if req.Input == "" && !req.CloseInput {
+			return llm.ErrorfToolOut("input or close_input is required for send")
+		}
+		if req.Input != "" {
+			if err := j.SendInput(req.Input); err != nil {
+				return llm.ErrorToolOut(err)
+			}
+		}
+		if req.CloseInput {
+			if err := j.CloseInput(); err != nil {
+				return llm.ErrorfToolOut("failed to close input of job %s: %w", j.ID, err)
+			}
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent(fmt.Sprintf("sent %d bytes to %s", len(req.Input), j.ID))}
+	case "stop":
+		j.Stop()
+		out, err := jobOutputLines(j, 20, "")
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		return llm.ToolOut{LLMContent: llm.TextContent(out)}
+	default:
+		return llm.ErrorfToolOut("unknown action %q", req.Action)
+	}
+}
+
+// jobOutputLines describes j's status and shows the last n lines of its
+// output, or the last n lines that match the regular expression grep.
+func jobOutputLines(j *Job, n int, grep string) (string, error) {
+	if n <= 0 {
+		n = defaultJobOutputLines
+	}
+	n = min(n, maxJobOutputLines)
+	var re *regexp.Regexp
+	if grep != "" {
+		var err error
+		if re, err = regexp.Compile(grep); err != nil {
+			return "", fmt.Errorf("invalid grep pattern: %w", err)
+		}
+	}
+
+	out, discarded := j.Output()
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if out == "" {
+		lines = nil
+	}
+	type numbered struct {
+		n    int
+		text string
+	}
+	var shown []numbered
+	for i, line := range lines {
+		if re == nil || re.MatchString(line) {
+			shown = append(shown, numbered{i + 1, line})
+		}
+	}
+	omitted := max(0, len(shown)-n)
+	shown = shown[omitted:]
+
+	sb := new(strings.Builder)
+	fmt.Fprintf(sb, "%s (pid %d) %s: %s\n", j.ID, j.PID(), j.Status(), j.Command)
+	switch {
+	case len(lines) == 0:
+		sb.WriteString("(no output)\n")
+		return sb.String(), nil
+	case re != nil:
+		fmt.Fprintf(sb, "%d of %d lines match", len(shown)+omitted, len(lines))
+	default:
+		fmt.Fprintf(sb, "%d lines of output", len(lines))
+	}
+	if discarded {
+		sb.WriteString(" (earlier output was discarded)")
+	}
+	if omitted > 0 {
+		fmt.Fprintf(sb, ", showing the last %d", len(shown))
+	}
+	sb.WriteString(":\n")
+	for _, l := range shown {
+		fmt.Fprintf(sb, "%5d: %s\n", l.n, truncateLine(l.text))
+	}
+	return sb.String(), nil
+}
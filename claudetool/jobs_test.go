@@ -0,0 +1,174 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// waitForJobOutput waits until j's output contains want.
+func waitForJobOutput(t *testing.T, j *Job, want string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		out, _ := j.Output()
+		if strings.Contains(out, want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job output never contained %q; got %q", want, out)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func runJobsTool(t *testing.T, tool *JobsTool, input map[string]any) (string, error) {
+	t.Helper()
+	raw, _ := json.Marshal(input)
+	out := tool.Run(context.Background(), raw)
+	if out.Error != nil {
+		return "", out.Error
+	}
+	return out.LLMContent[0].Text, nil
+}
+
+func TestBackgroundJobs(t *testing.T) {
+	jobs := NewJobManager()
+	t.Cleanup(jobs.StopAll)
+	var audits []AuditRecord
+	bash := &BashTool{
+		WorkingDir:     NewMutableWorkingDir(t.TempDir()),
+		ConversationID: "c1",
+		Jobs:           jobs,
+		Audit:          func(rec AuditRecord) { audits = append(audits, rec) },
+	}
+	tool := &JobsTool{Jobs: jobs, ConversationID: "c1"}
+
+	out := bash.Run(context.Background(), json.RawMessage(`{"command":"echo ready; read line; echo got $line; sleep 60","background":true}`))
+	if out.Error != nil {
+		t.Fatalf("background bash failed: %v", out.Error)
+	}
+	if !strings.Contains(out.LLMContent[0].Text, "started background job job1") {
+		t.Fatalf("unexpected result: %q", out.LLMContent[0].Text)
+	}
+	if d := out.Display.(BashDisplayData); d.JobID != "job1" {
+		t.Errorf("display job ID = %q", d.JobID)
+	}
+	if len(audits) != 1 || audits[0].Command == "" || audits[0].ExitCode != nil {
+		t.Errorf("audit records = %+v", audits)
+	}
+	j, ok := jobs.Get("c1", "job1")
+	if !ok {
+		t.Fatal("job1 not found")
+	}
+	if _, ok := jobs.Get("other", "job1"); ok {
+		t.Error("job1 is visible to another conversation")
+	}
+
+	waitForJobOutput(t, j, "ready")
+	if _, err := runJobsTool(t, tool, map[string]any{"action": "send", "id": "job1", "input": "hello\n"}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	waitForJobOutput(t, j, "got hello")
+
+	got, err := runJobsTool(t, tool, map[string]any{"action": "output", "id": "job1", "grep": "^got "})
+	if err != nil {
+		t.Fatalf("output failed: %v", err)
+	}
+	if !strings.Contains(got, "running for") || !strings.Contains(got, ": got hello\n") || strings.Contains(got, ": ready") {
+		t.Errorf("grep output = %q", got)
+	}
+
+	got, err = runJobsTool(t, tool, map[string]any{"action": "list"})
+	if err != nil || !strings.Contains(got, "job1 (pid") {
+		t.Errorf("list = %q, %v", got, err)
+	}
+
+	got, err = runJobsTool(t, tool, map[string]any{"action": "stop", "id": "job1"})
+	if err != nil || !strings.Contains(got, "stopped after") {
+		t.Errorf("stop = %q, %v", got, err)
+	}
+	if info := j.Info(); info.Running || !info.Stopped || info.EndedAt == nil {
+		t.Errorf("info after stop = %+v", info)
+	}
+	if _, err := runJobsTool(t, tool, map[string]any{"action": "send", "id": "job1", "input": "x"}); err == nil {
+		t.Error("send to a stopped job succeeded")
+	}
+
+	// A job that fails straight away reports how it ended. Login shells
+	// can be slow to start, so give it plenty of time.
+	defer func(wait time.Duration) { jobStartupWait = wait }(jobStartupWait)
+	jobStartupWait = 30 * time.Second
+	out = bash.Run(context.Background(), json.RawMessage(`{"command":"echo oops; exit 3","background":true}`))
+	if out.Error != nil {
+		t.Fatalf("background bash failed: %v", out.Error)
+	}
+	if text := out.LLMContent[0].Text; !strings.Contains(text, "exited with status 3") || !strings.Contains(text, "oops") {
+		t.Errorf("result of a failing job = %q", text)
+	}
+
+	if _, err := runJobsTool(t, tool, map[string]any{"action": "output", "id": "job9"}); err == nil {
+		t.Error("output of a missing job succeeded")
+	}
+}
+
+func TestJobManagerStopConversation(t *testing.T) {
+	grace := jobStopGrace
+	t.Cleanup(func() { jobStopGrace = grace })
+	jobStopGrace = 200 * time.Millisecond
+	jobs := NewJobManager()
+	t.Cleanup(jobs.StopAll)
+	start := func(conversationID string) *Job {
+		cmd := exec.Command("sh", "-c", "trap '' TERM; echo started; sleep 60")
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		j, err := jobs.Start(conversationID, "sleep", cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitForJobOutput(t, j, "started")
+		return j
+	}
+	a, b := start("a"), start("b")
+
+	begin := time.Now()
+	jobs.StopConversation("a")
+	if a.Running() {
+		t.Error("job of the stopped conversation is still running")
+	}
+	// The job ignores SIGTERM, so it took SIGKILL.
+	if took := time.Since(begin); took < jobStopGrace {
+		t.Errorf("stop took %s, less than the grace period", took)
+	}
+	if !b.Running() {
+		t.Error("job of another conversation was stopped")
+	}
+	if len(jobs.List("a")) != 0 || len(jobs.List("b")) != 1 {
+		t.Errorf("jobs after stop: a=%d b=%d", len(jobs.List("a")), len(jobs.List("b")))
+	}
+}
+
+func TestJobOutputLimit(t *testing.T) {
+	o := new(jobOutput)
+	line := strings.Repeat("x", 1023) + "\n"
+	for range 3 * jobOutputLimit / len(line) {
+		o.Write([]byte(line))
+	}
+	o.Write([]byte("last\n"))
+	got, discarded := o.Contents()
+	if !discarded {
+		t.Error("output was not discarded")
+	}
+	if len(got) < jobOutputLimit || len(got) > 2*jobOutputLimit {
+		t.Errorf("kept %d bytes", len(got))
+	}
+	if !strings.HasPrefix(got, "x") || !strings.HasSuffix(got, "last\n") {
+		t.Errorf("kept output doesn't start at a line: %q...", got[:20])
+	}
+	if o.Total() != int64(3*jobOutputLimit/len(line)*len(line)+5) {
+		t.Errorf("total = %d", o.Total())
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"log/slog"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"shelley.exe.dev/claudetool/codeindex"
 	"shelley.exe.dev/llm"
 )
 
@@ -23,6 +25,9 @@ type LLMServiceProvider interface {
 type KeywordTool struct {
 	llmProvider LLMServiceProvider
 	workingDir  *MutableWorkingDir
+	// index, if set, adds the code index's best matches to the results
+	// the LLM ranks.
+	index *codeindex.Indexes
 }
 
 // NewKeywordTool creates a new keyword tool with the given LLM provider
@@ -132,13 +137,14 @@ func (k *KeywordTool) keywordRun(ctx context.Context, m json.RawMessage) llm.Too
 		keep = append(keep, term)
 	}
 
-	if len(keep) == 0 {
+	indexed := k.indexResults(ctx, wd, input)
+	if len(keep) == 0 && indexed == "" {
 		return llm.ToolOut{LLMContent: llm.TextContent("each of those search terms yielded too many results")}
 	}
 
 	// peel off keywords until we get a result that fits in the query window
 	var out string
-	for {
+	for len(keep) > 0 {
 		var err error
 		out, err = ripgrep(ctx, wd, keep)
 		if err != nil {
@@ -161,13 +167,17 @@ func (k *KeywordTool) keywordRun(ctx context.Context, m json.RawMessage) llm.Too
 		{Type: "text", Text: strings.TrimSpace(keywordSystemPrompt)},
 	}
 
+	content := []llm.Content{llm.StringContent("<pwd>\n" + wd + "\n</pwd>")}
+	if out != "" {
+		content = append(content, llm.StringContent("<ripgrep_results>\n"+out+"\n</ripgrep_results>"))
+	}
+	if indexed != "" {
+		content = append(content, llm.StringContent("<index_results>\n"+indexed+"</index_results>"))
+	}
+	content = append(content, llm.StringContent("<query>\n"+input.Query+"\n</query>"))
 	initialMessage := llm.Message{
-		Role: llm.MessageRoleUser,
-		Content: []llm.Content{
-			llm.StringContent("<pwd>\n" + wd + "\n</pwd>"),
-			llm.StringContent("<ripgrep_results>\n" + out + "\n</ripgrep_results>"),
-			llm.StringContent("<query>\n" + input.Query + "\n</query>"),
-		},
+		Role:    llm.MessageRoleUser,
+		Content: content,
 	}
 
 	req := &llm.Request{
@@ -196,6 +206,40 @@ func (k *KeywordTool) keywordRun(ctx context.Context, m json.RawMessage) llm.Too
 	return llm.ToolOut{LLMContent: llm.TextContent(resp.Content[0].Text)}
 }
 
+// keywordIndexResults is how many of the code index's matches are added
+// to the ripgrep results.
+const keywordIndexResults = 20
+
+// indexResults returns the code index's best matches for input, one file
+// per line with its matching symbols, or "" if there is no index.
+func (k *KeywordTool) indexResults(ctx context.Context, root string, input keywordInput) string {
+	if k.index == nil {
+		return ""
+	}
+	results, err := k.index.For(root).Search(ctx, codeindex.Query{
+		Text:  input.Query,
+		Terms: input.SearchTerms,
+		Limit: keywordIndexResults,
+	})
+	if err != nil {
+		slog.WarnContext(ctx, "keyword search: code index search failed", "root", root, "error", err)
+		return ""
+	}
+	sb := new(strings.Builder)
+	for _, r := range results {
+		sb.WriteString(filepath.Join(root, filepath.FromSlash(r.Path)))
+		for i, s := range r.Symbols {
+			sep := ", "
+			if i == 0 {
+				sep = ": "
+			}
+			fmt.Fprintf(sb, "%s%s %s (line %d)", sep, s.Kind, s.QualifiedName(), s.Line)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
 func ripgrep(ctx context.Context, wd string, terms []string) (string, error) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
@@ -0,0 +1,578 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"shelley.exe.dev/claudetool/lsp"
+	"shelley.exe.dev/llm"
+)
+
+// LSPTool answers questions about code using language servers such as
+// gopls, which it starts on demand for the conversation's repository.
+type LSPTool struct {
+	// WorkingDir is the shared mutable working directory.
+	WorkingDir *MutableWorkingDir
+	// Audit, if set, is told about every file a rename writes.
+	Audit AuditFunc
+
+	servers []lsp.Server
+	manager *lsp.Manager
+}
+
+// NewLSPTool returns an LSPTool using servers, typically the installed
+// subset of lsp.DefaultServers.
+func NewLSPTool(wd *MutableWorkingDir, servers []lsp.Server) *LSPTool {
+	return &LSPTool{
+		WorkingDir: wd,
+		servers:    servers,
+		manager:    lsp.NewManager(servers),
+	}
+}
+
+// Close shuts down the language servers the tool started.
+func (t *LSPTool) Close() {
+	t.manager.Close()
+}
+
+const (
+	lspName        = "lsp"
+	lspDescription = `Ask a language server about code: precise go-to-definition, find-references,
+hover (type and documentation), workspace symbol search, rename, and current diagnostics.
+
+Prefer this over grepping when you need exact answers about identifiers.
+Positions are given as a 1-based line plus the symbol on that line (or a 1-based column).
+Rename edits every affected file at once and reports the diff.
+The first request for a repository may be slow while the server loads it.
+
+Available for: %s
+`
+	lspInputSchema = `{
+  "type": "object",
+  "required": ["operation", "path"],
+  "properties": {
+    "operation": {
+      "type": "string",
+      "enum": ["definition", "references", "hover", "symbols", "rename", "diagnostics"]
+    },
+    "path": {
+      "type": "string",
+      "description": "File to ask about (absolute or relative). For symbols, any file in the language to search."
+    },
+    "line": {
+      "type": "integer",
+      "description": "1-based line of the symbol; required for definition, references, hover and rename"
+    },
+    "symbol": {
+      "type": "string",
+      "description": "The identifier on that line to ask about"
+    },
+    "column": {
+      "type": "integer",
+      "description": "1-based byte column, if symbol is ambiguous on the line"
+    },
+    "query": {
+      "type": "string",
+      "description": "For symbols: the name, or part of it, to search for"
+    },
+    "new_name": {
+      "type": "string",
+      "description": "For rename: the new name"
+    }
+  }
+}`
+	// lspTimeout bounds one operation, including starting the server.
+	lspTimeout = 3 * time.Minute
+	// lspDiagnosticsWait is how long to wait for fresh diagnostics.
+	lspDiagnosticsWait = 10 * time.Second
+	// maxLSPResults caps the locations and symbols listed.
+	maxLSPResults = 100
+)
+
+type lspInput struct {
+	Operation string `json:"operation"`
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+	Symbol    string `json:"symbol"`
+	Column    int    `json:"column"`
+	Query     string `json:"query"`
+	NewName   string `json:"new_name"`
+}
+
+// Tool returns an llm.Tool for the language servers.
+func (t *LSPTool) Tool() *llm.Tool {
+	var names []string
+	for _, s := range t.servers {
+		names = append(names, fmt.Sprintf("%s (%s)", s.Name, strings.Join(lsp.Extensions([]lsp.Server{s}), " ")))
+	}
+	return &llm.Tool{
+		Name:        lspName,
+		Description: fmt.Sprintf(lspDescription, strings.Join(names, ", ")),
+		InputSchema: llm.MustSchema(lspInputSchema),
+		Run:         t.Run,
+	}
+}
+
+// Run executes the lsp tool.
+func (t *LSPTool) Run(ctx context.Context, m json.RawMessage) llm.ToolOut {
+	var req lspInput
+	if err := json.Unmarshal(m, &req); err != nil {
+		return llm.ErrorfToolOut("failed to parse lsp input: %w", err)
+	}
+	if !slices.Contains([]string{"definition", "references", "hover", "symbols", "rename", "diagnostics"}, req.Operation) {
+		return llm.ErrorfToolOut("unknown operation %q", req.Operation)
+	}
+	if req.Path == "" {
+		return llm.ErrorfToolOut("path is required")
+	}
+	wd := t.WorkingDir.Get()
+	path := req.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(wd, path)
+	}
+	path = filepath.Clean(path)
+	if _, err := os.Stat(path); err != nil {
+		return llm.ErrorfToolOut("cannot read %s: %w", path, err)
+	}
+
+	server, lang, err := t.manager.ServerFor(path)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	root, err := FindRepoRoot(filepath.Dir(path))
+	if err != nil {
+		root = wd
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lspTimeout)
+	defer cancel()
+	client, err := t.manager.Client(ctx, root, server)
+	if err != nil {
+		return llm.ErrorfToolOut("%s: %w", server.Name, err)
+	}
+	// Sync the file asked about first, to learn whether it changed, and
+	// then any other files that were edited since the server saw them.
+	uri := lsp.FileURI(path)
+	gen := client.DiagnosticsGeneration(uri)
+	_, changed, err := client.SyncFile(path, lang)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	if err := client.SyncOpenFiles(); err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
+	var text string
+	switch req.Operation {
+	case "symbols":
+		if req.Query == "" {
+			return llm.ErrorfToolOut("query is required for symbols")
+		}
+		text, err = t.symbols(ctx, client, req.Query)
+	case "diagnostics":
+		if changed || gen == 0 {
+			waitCtx, cancel := context.WithTimeout(ctx, lspDiagnosticsWait)
+			client.WaitDiagnostics(waitCtx, uri, gen)
+			cancel()
+		}
+		text = formatDiagnostics(client, path)
+	case "definition", "references", "hover", "rename":
+		var pos lsp.Position
+		pos, err = lspPosition(path, req.Line, req.Symbol, req.Column)
+		if err != nil {
+			return llm.ErrorToolOut(err)
+		}
+		params := map[string]any{"textDocument": map[string]string{"uri": uri}, "position": pos}
+		switch req.Operation {
+		case "definition":
+			var raw json.RawMessage
+			if err = client.Call(ctx, "textDocument/definition", params, &raw); err == nil {
+				text = formatLocations(client.Root(), parseLocations(raw), "definition")
+			}
+		case "references":
+			params["context"] = map[string]bool{"includeDeclaration": true}
+			var locs []lsp.Location
+			if err = client.Call(ctx, "textDocument/references", params, &locs); err == nil {
+				text = formatLocations(client.Root(), locs, "reference")
+			}
+		case "hover":
+			var hover struct {
+				Contents json.RawMessage `json:"contents"`
+			}
+			if err = client.Call(ctx, "textDocument/hover", params, &hover); err == nil {
+				text = hoverText(hover.Contents)
+			}
+		case "rename":
+			if req.NewName == "" {
+				return llm.ErrorfToolOut("new_name is required for rename")
+			}
+			params["newName"] = req.NewName
+			var edit lsp.WorkspaceEdit
+			if err = client.Call(ctx, "textDocument/rename", params, &edit); err == nil {
+				text, err = t.applyRename(client, &edit, req.NewName)
+			}
+		}
+	}
+	if err != nil {
+		return llm.ErrorfToolOut("%s %s: %w", server.Name, req.Operation, err)
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(text)}
+}
+
+func (t *LSPTool) symbols(ctx context.Context, client *lsp.Client, query string) (string, error) {
+	var syms []lsp.SymbolInformation
+	if err := client.Call(ctx, "workspace/symbol", map[string]string{"query": query}, &syms); err != nil {
+		return "", err
+	}
+	if len(syms) == 0 {
+		return "No symbols found.", nil
+	}
+	sb := new(strings.Builder)
+	for i, s := range syms {
+		if i == maxLSPResults {
+			fmt.Fprintf(sb, "... and %d more\n", len(syms)-i)
+			break
+		}
+		name := s.Name
+		if s.ContainerName != "" {
+			name = s.ContainerName + "." + s.Name
+		}
+		fmt.Fprintf(sb, "%s %s %s:%d\n", symbolKind(s.Kind), name, relPath(client.Root(), lsp.URIPath(s.Location.URI)), s.Location.Range.Start.Line+1)
+	}
+	return sb.String(), nil
+}
+
+// applyRename writes a rename's edits to every file, or to none if any
+// can't be applied, and returns the resulting diffs.
+func (t *LSPTool) applyRename(client *lsp.Client, edit *lsp.WorkspaceEdit, newName string) (string, error) {
+	changes := make(map[string][]lsp.TextEdit)
+	for uri, edits := range edit.Changes {
+		changes[uri] = append(changes[uri], edits...)
+	}
+	for _, raw := range edit.DocumentChanges {
+		var dc struct {
+			Kind         string `json:"kind"`
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			Edits []lsp.TextEdit `json:"edits"`
+		}
+		if err := json.Unmarshal(raw, &dc); err != nil {
+			return "", err
+		}
+		if dc.Kind != "" {
+			return "", fmt.Errorf("the rename needs a file %s, which is not supported; do it by hand", dc.Kind)
+		}
+		changes[dc.TextDocument.URI] = append(changes[dc.TextDocument.URI], dc.Edits...)
+	}
+	if len(changes) == 0 {
+		return "", errors.New("the server found nothing to rename")
+	}
+
+	type result struct {
+		path          string
+		before, after []byte
+	}
+	var results []result
+	for uri, edits := range changes {
+		path := lsp.URIPath(uri)
+		before, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		after, err := applyTextEdits(string(before), edits)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", path, err)
+		}
+		results = append(results, result{path, before, []byte(after)})
+	}
+	slices.SortFunc(results, func(a, b result) int { return strings.Compare(a.path, b.path) })
+
+	sb := new(strings.Builder)
+	fmt.Fprintf(sb, "Renamed to %s in %d files.\n\n", newName, len(results))
+	for _, r := range results {
+		if err := os.WriteFile(r.path, r.after, 0o600); err != nil {
+			return "", fmt.Errorf("failed to write %s (earlier files were already renamed): %w", r.path, err)
+		}
+		if t.Audit != nil {
+			t.Audit(AuditRecord{Kind: AuditPatch, Cwd: t.WorkingDir.Get(), Path: r.path, BeforeHash: contentHash(r.before), AfterHash: contentHash(r.after)})
+		}
+		sb.WriteString(generateUnifiedDiff(r.path, string(r.before), string(r.after)))
+	}
+	return sb.String(), client.SyncOpenFiles()
+}
+
+// applyTextEdits applies non-overlapping LSP edits to text.
+func applyTextEdits(text string, edits []lsp.TextEdit) (string, error) {
+	lineStarts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	offset := func(p lsp.Position) (int, error) {
+		if p.Line >= len(lineStarts) {
+			if p.Line == len(lineStarts) && p.Character == 0 {
+				return len(text), nil
+			}
+			return 0, fmt.Errorf("edit at line %d is past the end of the file", p.Line+1)
+		}
+		start := lineStarts[p.Line]
+		end := len(text)
+		if p.Line+1 < len(lineStarts) {
+			end = lineStarts[p.Line+1]
+		}
+		return start + byteColumn(text[start:end], p.Character), nil
+	}
+	type span struct {
+		start, end int
+		text       string
+	}
+	spans := make([]span, 0, len(edits))
+	for _, e := range edits {
+		start, err := offset(e.Range.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := offset(e.Range.End)
+		if err != nil {
+			return "", err
+		}
+		spans = append(spans, span{start, end, e.NewText})
+	}
+	slices.SortStableFunc(spans, func(a, b span) int { return a.start - b.start })
+	var sb strings.Builder
+	last := 0
+	for _, s := range spans {
+		if s.start < last || s.end < s.start {
+			return "", errors.New("overlapping edits")
+		}
+		sb.WriteString(text[last:s.start])
+		sb.WriteString(s.text)
+		last = s.end
+	}
+	sb.WriteString(text[last:])
+	return sb.String(), nil
+}
+
+// lspPosition finds the position of symbol on the 1-based line of path, or
+// of the 1-based byte column if given.
+func lspPosition(path string, line int, symbol string, column int) (lsp.Position, error) {
+	if line < 1 {
+		return lsp.Position{}, errors.New("line is required")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return lsp.Position{}, err
+	}
+	lines := strings.Split(string(data), "\n")
+	if line > len(lines) {
+		return lsp.Position{}, fmt.Errorf("%s has only %d lines", path, len(lines))
+	}
+	text := lines[line-1]
+	col := 0
+	switch {
+	case column > 0:
+		col = min(column-1, len(text))
+	case symbol != "":
+		col = findIdentifier(text, symbol)
+		if col < 0 {
+			return lsp.Position{}, fmt.Errorf("%q not found on line %d: %s", symbol, line, strings.TrimSpace(text))
+		}
+	default:
+		return lsp.Position{}, errors.New("symbol or column is required")
+	}
+	return lsp.Position{Line: line - 1, Character: utf16Len(text[:col])}, nil
+}
+
+// findIdentifier returns the byte offset of the first whole-word occurrence
+// of ident in line, falling back to any occurrence, or -1.
+func findIdentifier(line, ident string) int {
+	isIdent := func(r rune) bool { return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+	for i := 0; ; {
+		j := strings.Index(line[i:], ident)
+		if j < 0 {
+			break
+		}
+		start, end := i+j, i+j+len(ident)
+		before, _ := utf8.DecodeLastRuneInString(line[:start])
+		after, _ := utf8.DecodeRuneInString(line[end:])
+		if (start == 0 || !isIdent(before)) && (end == len(line) || !isIdent(after)) {
+			return start
+		}
+		i = start + 1
+	}
+	return strings.Index(line, ident)
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// byteColumn converts a UTF-16 offset within line to a byte offset.
+func byteColumn(line string, units int) int {
+	n := 0
+	for i, r := range line {
+		if n >= units {
+			return i
+		}
+		n += utf16.RuneLen(r)
+	}
+	return len(strings.TrimSuffix(line, "\n"))
+}
+
+// parseLocations decodes a definition result: a Location, a list of them,
+// or a list of LocationLinks.
+func parseLocations(raw json.RawMessage) []lsp.Location {
+	var one lsp.Location
+	if json.Unmarshal(raw, &one) == nil && one.URI != "" {
+		return []lsp.Location{one}
+	}
+	var items []struct {
+		lsp.Location
+		TargetURI            string    `json:"targetUri"`
+		TargetSelectionRange lsp.Range `json:"targetSelectionRange"`
+	}
+	json.Unmarshal(raw, &items)
+	var locs []lsp.Location
+	for _, it := range items {
+		if it.TargetURI != "" {
+			locs = append(locs, lsp.Location{URI: it.TargetURI, Range: it.TargetSelectionRange})
+		} else {
+			locs = append(locs, it.Location)
+		}
+	}
+	return locs
+}
+
+// formatLocations lists locations as path:line:column with the line's text.
+func formatLocations(root string, locs []lsp.Location, what string) string {
+	if len(locs) == 0 {
+		return fmt.Sprintf("No %s found.", what)
+	}
+	files := make(map[string][]string)
+	sb := new(strings.Builder)
+	for i, loc := range locs {
+		if i == maxLSPResults {
+			fmt.Fprintf(sb, "... and %d more\n", len(locs)-i)
+			break
+		}
+		path := lsp.URIPath(loc.URI)
+		lines, ok := files[path]
+		if !ok {
+			data, _ := os.ReadFile(path)
+			lines = strings.Split(string(data), "\n")
+			files[path] = lines
+		}
+		line := ""
+		col := loc.Range.Start.Character
+		if l := loc.Range.Start.Line; l < len(lines) {
+			line = lines[l]
+			col = byteColumn(line, col)
+		}
+		fmt.Fprintf(sb, "%s:%d:%d: %s\n", relPath(root, path), loc.Range.Start.Line+1, col+1, strings.TrimSpace(line))
+	}
+	return sb.String()
+}
+
+// hoverText extracts the text of hover contents: MarkupContent, a
+// MarkedString, or a list of MarkedStrings.
+func hoverText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var markup struct {
+		Value string `json:"value"`
+	}
+	if json.Unmarshal(raw, &markup) == nil && markup.Value != "" {
+		return markup.Value
+	}
+	var list []json.RawMessage
+	if json.Unmarshal(raw, &list) == nil {
+		var parts []string
+		for _, item := range list {
+			if text := hoverText(item); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, "\n\n")
+	}
+	return "No hover information."
+}
+
+// formatDiagnostics lists the diagnostics for path, and counts those for
+// other files.
+func formatDiagnostics(client *lsp.Client, path string) string {
+	all := client.Diagnostics()
+	uri := lsp.FileURI(path)
+	sb := new(strings.Builder)
+	diags := all[uri]
+	if len(diags) == 0 {
+		fmt.Fprintf(sb, "No diagnostics for %s.\n", relPath(client.Root(), path))
+	}
+	for _, d := range diags {
+		source := ""
+		if d.Source != "" {
+			source = " (" + d.Source + ")"
+		}
+		fmt.Fprintf(sb, "%s:%d:%d: %s: %s%s\n", relPath(client.Root(), path), d.Range.Start.Line+1, d.Range.Start.Character+1, severityName(d.Severity), d.Message, source)
+	}
+	others, otherFiles := 0, 0
+	for u, d := range all {
+		if u != uri && len(d) > 0 {
+			others += len(d)
+			otherFiles++
+		}
+	}
+	if others > 0 {
+		fmt.Fprintf(sb, "\n%d diagnostics in %d other files.\n", others, otherFiles)
+	}
+	return sb.String()
+}
+
+func severityName(s int) string {
+	switch s {
+	case 1:
+		return "error"
+	case 2:
+		return "warning"
+	case 3:
+		return "info"
+	case 4:
+		return "hint"
+	}
+	return "diagnostic"
+}
+
+// symbolKind names an LSP SymbolKind.
+func symbolKind(k int) string {
+	kinds := []string{"", "file", "module", "namespace", "package", "class", "method", "property", "field",
+		"constructor", "enum", "interface", "function", "variable", "constant", "string", "number", "boolean",
+		"array", "object", "key", "null", "enum_member", "struct", "event", "operator", "type_parameter"}
+	if k > 0 && k < len(kinds) {
+		return kinds[k]
+	}
+	return "symbol"
+}
+
+func relPath(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
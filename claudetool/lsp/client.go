@@ -0,0 +1,487 @@
+// Package lsp is a minimal Language Server Protocol client, enough to ask
+// language servers such as gopls for definitions, references, hover text,
+// symbols, renames and diagnostics.
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Position is a zero-based line and UTF-16 character offset, as in LSP.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a span between two positions.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range in a document.
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// Diagnostic is an error, warning or hint reported by a server.
+type Diagnostic struct {
+	Range    Range  `json:"range"`
+	Severity int    `json:"severity,omitempty"` // 1 error, 2 warning, 3 information, 4 hint
+	Source   string `json:"source,omitempty"`
+	Message  string `json:"message"`
+}
+
+// TextEdit replaces a range of a document with new text.
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// WorkspaceEdit is a set of edits to several documents.
+type WorkspaceEdit struct {
+	Changes         map[string][]TextEdit `json:"changes,omitempty"`
+	DocumentChanges []json.RawMessage     `json:"documentChanges,omitempty"`
+}
+
+// SymbolInformation describes a symbol found by a workspace symbol search.
+type SymbolInformation struct {
+	Name          string   `json:"name"`
+	Kind          int      `json:"kind"`
+	Location      Location `json:"location"`
+	ContainerName string   `json:"containerName,omitempty"`
+}
+
+// ResponseError is an error returned by a server.
+type ResponseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("language server error %d: %s", e.Code, e.Message)
+}
+
+// message is any JSON-RPC message; which fields are set tells them apart.
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *ResponseError   `json:"error,omitempty"`
+}
+
+type openFile struct {
+	version    int
+	text       string
+	languageID string
+}
+
+// A Client talks to one language server process over stdio.
+type Client struct {
+	cmd  *exec.Cmd
+	root string
+
+	writeMu sync.Mutex
+	stdin   io.WriteCloser
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *message
+	open    map[string]*openFile    // URI -> last content sent
+	diags   map[string][]Diagnostic // URI -> latest published diagnostics
+	diagGen map[string]int          // URI -> number of publishes seen
+	diagCh  chan struct{}           // closed and replaced on every publish
+	done    chan struct{}           // closed when the server exits
+	err     error                   // why the server exited
+}
+
+// Start starts a language server with command in root and initializes it.
+func Start(ctx context.Context, root string, command []string) (*Client, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = root
+	cmd.Stderr = io.Discard
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", command[0], err)
+	}
+	c := &Client{
+		cmd:     cmd,
+		root:    root,
+		stdin:   stdin,
+		pending: make(map[int64]chan *message),
+		open:    make(map[string]*openFile),
+		diags:   make(map[string][]Diagnostic),
+		diagGen: make(map[string]int),
+		diagCh:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(stdout)
+
+	rootURI := FileURI(root)
+	params := map[string]any{
+		"processId": os.Getpid(),
+		"rootUri":   rootURI,
+		"workspaceFolders": []map[string]string{
+			{"uri": rootURI, "name": filepath.Base(root)},
+		},
+		"capabilities": map[string]any{
+			"workspace": map[string]any{
+				"workspaceEdit":    map[string]any{"documentChanges": true},
+				"workspaceFolders": true,
+				"configuration":    true,
+			},
+			"textDocument": map[string]any{
+				"synchronization":    map[string]any{"didSave": false},
+				"hover":              map[string]any{"contentFormat": []string{"plaintext", "markdown"}},
+				"definition":         map[string]any{"linkSupport": true},
+				"rename":             map[string]any{"prepareSupport": false},
+				"publishDiagnostics": map[string]any{"relatedInformation": false},
+			},
+		},
+	}
+	if err := c.Call(ctx, "initialize", params, nil); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("failed to initialize %s: %w", command[0], err)
+	}
+	if err := c.Notify("initialized", map[string]any{}); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Root returns the workspace root the server was started in.
+func (c *Client) Root() string {
+	return c.root
+}
+
+// Call sends a request and decodes its result into result, if non-nil.
+func (c *Client) Call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	rawID := json.RawMessage(strconv.FormatInt(id, 10))
+	if err := c.send(message{ID: &rawID, Method: method, Params: mustMarshal(params)}); err != nil {
+		return err
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil && len(resp.Result) > 0 {
+			return json.Unmarshal(resp.Result, result)
+		}
+		return nil
+	case <-c.done:
+		return c.exitErr()
+	case <-ctx.Done():
+		c.Notify("$/cancelRequest", map[string]any{"id": id})
+		return ctx.Err()
+	}
+}
+
+// Notify sends a notification.
+func (c *Client) Notify(method string, params any) error {
+	return c.send(message{Method: method, Params: mustMarshal(params)})
+}
+
+func (c *Client) send(msg message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.stdin, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		return fmt.Errorf("language server is not running: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) readLoop(stdout io.Reader) {
+	r := bufio.NewReader(stdout)
+	var err error
+	for {
+		var msg *message
+		if msg, err = readMessage(r); err != nil {
+			break
+		}
+		switch {
+		case msg.ID != nil && msg.Method != "":
+			c.handleServerRequest(msg)
+		case msg.ID != nil:
+			id, _ := strconv.ParseInt(string(*msg.ID), 10, 64)
+			c.mu.Lock()
+			ch := c.pending[id]
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- msg
+			}
+		case msg.Method == "textDocument/publishDiagnostics":
+			var p struct {
+				URI         string       `json:"uri"`
+				Diagnostics []Diagnostic `json:"diagnostics"`
+			}
+			if json.Unmarshal(msg.Params, &p) == nil {
+				c.mu.Lock()
+				c.diags[p.URI] = p.Diagnostics
+				c.diagGen[p.URI]++
+				close(c.diagCh)
+				c.diagCh = make(chan struct{})
+				c.mu.Unlock()
+			}
+		}
+	}
+	waitErr := c.cmd.Wait()
+	c.mu.Lock()
+	c.err = fmt.Errorf("language server exited: %w", errors.Join(err, waitErr))
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// handleServerRequest answers requests servers send to clients. Servers
+// commonly block until they get an answer, so every request gets one.
+func (c *Client) handleServerRequest(msg *message) {
+	var result any
+	if msg.Method == "workspace/configuration" {
+		var p struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		json.Unmarshal(msg.Params, &p)
+		result = make([]any, len(p.Items))
+	}
+	data := mustMarshal(result)
+	if data == nil {
+		data = json.RawMessage("null")
+	}
+	c.send(message{ID: msg.ID, Result: data})
+}
+
+func (c *Client) exitErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Done is closed when the server exits.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// SyncFile tells the server about the current contents of path, opening
+// it if needed. It returns the file's URI and whether the server was told
+// anything new.
+func (c *Client) SyncFile(path, languageID string) (uri string, changed bool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+	uri = FileURI(path)
+	text := string(data)
+	c.mu.Lock()
+	f := c.open[uri]
+	switch {
+	case f == nil:
+		f = &openFile{version: 1, text: text, languageID: languageID}
+		c.open[uri] = f
+	case f.text == text:
+		c.mu.Unlock()
+		return uri, false, nil
+	default:
+		f.version++
+		f.text = text
+	}
+	version := f.version
+	c.mu.Unlock()
+
+	if version == 1 {
+		err = c.Notify("textDocument/didOpen", map[string]any{
+			"textDocument": map[string]any{"uri": uri, "languageId": languageID, "version": version, "text": text},
+		})
+	} else {
+		err = c.Notify("textDocument/didChange", map[string]any{
+			"textDocument":   map[string]any{"uri": uri, "version": version},
+			"contentChanges": []map[string]string{{"text": text}},
+		})
+	}
+	return uri, true, err
+}
+
+// SyncOpenFiles re-sends open files that changed on disk since the server
+// last saw them, e.g. because they were patched.
+func (c *Client) SyncOpenFiles() error {
+	c.mu.Lock()
+	files := make(map[string]string, len(c.open))
+	for uri, f := range c.open {
+		files[uri] = f.languageID
+	}
+	c.mu.Unlock()
+	var errs []error
+	for uri, lang := range files {
+		if _, _, err := c.SyncFile(URIPath(uri), lang); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DiagnosticsGeneration returns how many times diagnostics were published
+// for uri, to pass to WaitDiagnostics.
+func (c *Client) DiagnosticsGeneration(uri string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.diagGen[uri]
+}
+
+// WaitDiagnostics waits until diagnostics newer than generation gen are
+// published for uri, or ctx is done, and returns the latest diagnostics.
+func (c *Client) WaitDiagnostics(ctx context.Context, uri string, gen int) []Diagnostic {
+	for {
+		c.mu.Lock()
+		diags, cur, ch := c.diags[uri], c.diagGen[uri], c.diagCh
+		c.mu.Unlock()
+		if cur > gen {
+			return diags
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return diags
+		case <-c.done:
+			return diags
+		}
+	}
+}
+
+// Diagnostics returns the latest diagnostics for every document that has any.
+func (c *Client) Diagnostics() map[string][]Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string][]Diagnostic)
+	for uri, d := range c.diags {
+		if len(d) > 0 {
+			out[uri] = d
+		}
+	}
+	return out
+}
+
+// Close asks the server to shut down, killing it if it doesn't.
+func (c *Client) Close() error {
+	select {
+	case <-c.done:
+		return nil
+	default:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if c.Call(ctx, "shutdown", nil, nil) == nil {
+		c.Notify("exit", nil)
+	}
+	c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-time.After(2 * time.Second):
+		syscall.Kill(-c.cmd.Process.Pid, syscall.SIGKILL)
+		<-c.done
+	}
+	return nil
+}
+
+func readMessage(r *bufio.Reader) (*message, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("bad Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, errors.New("message without Content-Length")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func mustMarshal(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// FileURI returns the file:// URI for an absolute path.
+func FileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// URIPath returns the path of a file:// URI, or the URI itself if it isn't one.
+func URIPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
@@ -0,0 +1,50 @@
+package lsp
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestFileURI(t *testing.T) {
+	for _, path := range []string{"/tmp/a.go", "/tmp/with space/b#c.go"} {
+		uri := FileURI(path)
+		if !strings.HasPrefix(uri, "file:///") {
+			t.Errorf("FileURI(%q) = %q", path, uri)
+		}
+		if got := URIPath(uri); got != path {
+			t.Errorf("URIPath(FileURI(%q)) = %q", path, got)
+		}
+	}
+	if got := URIPath("untitled:foo"); got != "untitled:foo" {
+		t.Errorf("URIPath of a non-file URI = %q", got)
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	body := `{"jsonrpc":"2.0","id":1,"result":null}`
+	input := "Content-Type: application/vscode-jsonrpc\r\ncontent-length: 38\r\n\r\n" + body
+	msg, err := readMessage(bufio.NewReader(strings.NewReader(input)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID == nil || string(*msg.ID) != "1" {
+		t.Errorf("id = %v", msg.ID)
+	}
+	if _, err := readMessage(bufio.NewReader(strings.NewReader("\r\n{}"))); err == nil {
+		t.Error("accepted a message without Content-Length")
+	}
+}
+
+func TestManagerServerFor(t *testing.T) {
+	m := NewManager([]Server{{Name: "fake", Command: []string{"fake"}, Languages: map[string]string{".go": "go"}}})
+	if s, lang, err := m.ServerFor("/x/main.GO"); err != nil || s.Name != "fake" || lang != "go" {
+		t.Errorf("ServerFor(main.GO) = %v, %q, %v", s.Name, lang, err)
+	}
+	if _, _, err := m.ServerFor("/x/main.py"); err == nil || !strings.Contains(err.Error(), "npm install -g pyright") {
+		t.Errorf("ServerFor(main.py) error = %v", err)
+	}
+	if _, _, err := m.ServerFor("/x/notes.txt"); err == nil {
+		t.Error("ServerFor(notes.txt) found a server")
+	}
+}
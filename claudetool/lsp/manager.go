@@ -0,0 +1,204 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server describes a language server and the files it handles.
+type Server struct {
+	// Name identifies the server in messages, e.g. "gopls".
+	Name string
+	// Command starts the server speaking LSP on stdio.
+	Command []string
+	// Languages maps the file extensions the server handles to their LSP
+	// language identifiers.
+	Languages map[string]string
+	// Install tells the user how to install the server.
+	Install string
+}
+
+// DefaultServers are the language servers used when they are installed.
+var DefaultServers = []Server{
+	{
+		Name:      "gopls",
+		Command:   []string{"gopls"},
+		Languages: map[string]string{".go": "go"},
+		Install:   "go install golang.org/x/tools/gopls@latest",
+	},
+	{
+		Name:    "typescript-language-server",
+		Command: []string{"typescript-language-server", "--stdio"},
+		Languages: map[string]string{
+			".ts": "typescript", ".tsx": "typescriptreact", ".mts": "typescript", ".cts": "typescript",
+			".js": "javascript", ".jsx": "javascriptreact", ".mjs": "javascript", ".cjs": "javascript",
+		},
+		Install: "npm install -g typescript typescript-language-server",
+	},
+	{
+		Name:      "pyright",
+		Command:   []string{"pyright-langserver", "--stdio"},
+		Languages: map[string]string{".py": "python", ".pyi": "python"},
+		Install:   "npm install -g pyright",
+	},
+	{
+		Name:      "rust-analyzer",
+		Command:   []string{"rust-analyzer"},
+		Languages: map[string]string{".rs": "rust"},
+		Install:   "rustup component add rust-analyzer",
+	},
+	{
+		Name:    "clangd",
+		Command: []string{"clangd"},
+		Languages: map[string]string{
+			".c": "c", ".h": "c", ".cc": "cpp", ".cpp": "cpp", ".cxx": "cpp", ".hpp": "cpp",
+		},
+		Install: "apt install clangd",
+	},
+}
+
+// Installed returns the servers whose commands are in PATH.
+func Installed(servers []Server) []Server {
+	var out []Server
+	for _, s := range servers {
+		if _, err := exec.LookPath(s.Command[0]); err == nil {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Extensions returns the file extensions servers handle, sorted.
+func Extensions(servers []Server) []string {
+	var exts []string
+	for _, s := range servers {
+		for ext := range s.Languages {
+			exts = append(exts, ext)
+		}
+	}
+	slices.Sort(exts)
+	return exts
+}
+
+// startTimeout bounds how long a server may take to initialize. Servers
+// like gopls load the whole workspace first, which can take a while.
+const startTimeout = 2 * time.Minute
+
+type clientKey struct {
+	root, server string
+}
+
+// A Manager starts language servers on demand, one per workspace root and
+// server, and keeps them running until Close.
+type Manager struct {
+	servers []Server
+
+	mu       sync.Mutex
+	clients  map[clientKey]*Client
+	starting map[clientKey]*startCall
+	closed   bool
+}
+
+type startCall struct {
+	done   chan struct{}
+	client *Client
+	err    error
+}
+
+// NewManager returns a Manager for servers.
+func NewManager(servers []Server) *Manager {
+	return &Manager{
+		servers:  servers,
+		clients:  make(map[clientKey]*Client),
+		starting: make(map[clientKey]*startCall),
+	}
+}
+
+// ServerFor returns the server for path and the file's language identifier.
+func (m *Manager) ServerFor(path string) (Server, string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, s := range m.servers {
+		if lang, ok := s.Languages[ext]; ok {
+			return s, lang, nil
+		}
+	}
+	for _, s := range DefaultServers {
+		if _, ok := s.Languages[ext]; ok {
+			return Server{}, "", fmt.Errorf("%s is not installed; install it with: %s", s.Name, s.Install)
+		}
+	}
+	return Server{}, "", fmt.Errorf("no language server for %q files", ext)
+}
+
+// Client returns a running client of server for root, starting one if needed.
+// A server that has exited is restarted.
+func (m *Manager) Client(ctx context.Context, root string, server Server) (*Client, error) {
+	key := clientKey{root, server.Name}
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("language servers are shut down")
+	}
+	if c := m.clients[key]; c != nil {
+		select {
+		case <-c.Done():
+			delete(m.clients, key)
+		default:
+			m.mu.Unlock()
+			return c, nil
+		}
+	}
+	// Concurrent callers wait for a single start.
+	call := m.starting[key]
+	if call == nil {
+		call = &startCall{done: make(chan struct{})}
+		m.starting[key] = call
+		go func() {
+			// The server outlives the request that started it.
+			startCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), startTimeout)
+			defer cancel()
+			call.client, call.err = Start(startCtx, root, server.Command)
+			m.mu.Lock()
+			delete(m.starting, key)
+			if call.err == nil {
+				if m.closed {
+					go call.client.Close()
+					call.client, call.err = nil, fmt.Errorf("language servers are shut down")
+				} else {
+					m.clients[key] = call.client
+				}
+			}
+			m.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	m.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.client, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close shuts down all servers.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	m.closed = true
+	clients := m.clients
+	m.clients = make(map[clientKey]*Client)
+	m.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, c := range clients {
+		wg.Go(func() { c.Close() })
+	}
+	wg.Wait()
+}
@@ -0,0 +1,258 @@
+package claudetool
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/claudetool/lsp"
+)
+
+// TestLSPHelperProcess is a fake language server, run by the tests below
+// as a child process. It knows about one identifier, "Foo".
+func TestLSPHelperProcess(t *testing.T) {
+	if os.Getenv("SHELLEY_LSP_HELPER") != "1" {
+		return
+	}
+	in := bufio.NewReader(os.Stdin)
+	texts := make(map[string]string)
+	var lastURI string
+	send := func(v map[string]any) {
+		v["jsonrpc"] = "2.0"
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(os.Stdout, "Content-Length: %d\r\n\r\n%s", len(data), data)
+	}
+	occurrences := func(uri string) []lsp.Location {
+		locs := []lsp.Location{}
+		for i, line := range strings.Split(texts[uri], "\n") {
+			for col := 0; ; col += 3 {
+				j := strings.Index(line[col:], "Foo")
+				if j < 0 {
+					break
+				}
+				col += j
+				locs = append(locs, lsp.Location{URI: uri, Range: lsp.Range{
+					Start: lsp.Position{Line: i, Character: col},
+					End:   lsp.Position{Line: i, Character: col + 3},
+				}})
+			}
+		}
+		return locs
+	}
+	for {
+		length := 0
+		for {
+			line, err := in.ReadString('\n')
+			if err != nil {
+				os.Exit(0)
+			}
+			line = strings.TrimSpace(line)
+			if line == "" {
+				break
+			}
+			if v, ok := strings.CutPrefix(line, "Content-Length: "); ok {
+				length, _ = strconv.Atoi(v)
+			}
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(in, body); err != nil {
+			os.Exit(0)
+		}
+		var msg struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params struct {
+				TextDocument struct {
+					URI  string `json:"uri"`
+					Text string `json:"text"`
+				} `json:"textDocument"`
+				ContentChanges []struct {
+					Text string `json:"text"`
+				} `json:"contentChanges"`
+				NewName string `json:"newName"`
+			} `json:"params"`
+		}
+		json.Unmarshal(body, &msg)
+		uri := msg.Params.TextDocument.URI
+		var result any
+		switch msg.Method {
+		case "textDocument/didOpen", "textDocument/didChange":
+			texts[uri] = msg.Params.TextDocument.Text
+			if len(msg.Params.ContentChanges) > 0 {
+				texts[uri] = msg.Params.ContentChanges[0].Text
+			}
+			lastURI = uri
+			diags := []lsp.Diagnostic{}
+			if strings.Contains(texts[uri], "undefined") {
+				diags = append(diags, lsp.Diagnostic{Severity: 1, Source: "fake", Message: "undefined: x"})
+			}
+			send(map[string]any{"method": "textDocument/publishDiagnostics", "params": map[string]any{"uri": uri, "diagnostics": diags}})
+			continue
+		case "exit":
+			os.Exit(0)
+		case "textDocument/definition":
+			result = occurrences(uri)[0]
+		case "textDocument/references":
+			result = occurrences(uri)
+		case "textDocument/hover":
+			result = map[string]any{"contents": map[string]string{"kind": "markdown", "value": "func Foo()"}}
+		case "workspace/symbol":
+			result = []lsp.SymbolInformation{{Name: "Foo", Kind: 12, Location: occurrences(lastURI)[0]}}
+		case "textDocument/rename":
+			var edits []lsp.TextEdit
+			for _, loc := range occurrences(uri) {
+				edits = append(edits, lsp.TextEdit{Range: loc.Range, NewText: msg.Params.NewName})
+			}
+			result = lsp.WorkspaceEdit{Changes: map[string][]lsp.TextEdit{uri: edits}}
+		}
+		if msg.ID != nil {
+			send(map[string]any{"id": msg.ID, "result": result})
+		}
+	}
+}
+
+func newFakeLSPTool(t *testing.T) (*LSPTool, string) {
+	t.Helper()
+	t.Setenv("SHELLEY_LSP_HELPER", "1")
+	dir := t.TempDir()
+	tool := NewLSPTool(NewMutableWorkingDir(dir), []lsp.Server{{
+		Name:      "fake",
+		Command:   []string{os.Args[0], "-test.run=^TestLSPHelperProcess$"},
+		Languages: map[string]string{".go": "go"},
+	}})
+	t.Cleanup(tool.Close)
+	return tool, dir
+}
+
+func runLSP(t *testing.T, tool *LSPTool, input map[string]any) (string, error) {
+	t.Helper()
+	data, err := json.Marshal(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := tool.Run(context.Background(), data)
+	if out.Error != nil {
+		return "", out.Error
+	}
+	return out.LLMContent[0].Text, nil
+}
+
+func TestLSPTool(t *testing.T) {
+	tool, dir := newFakeLSPTool(t)
+	path := filepath.Join(dir, "main.go")
+	src := "package main\n\nfunc Foo() {}\n\nfunc main() { Foo() }\n"
+	if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input map[string]any
+		want  []string
+	}{
+		{"definition", map[string]any{"operation": "definition", "path": "main.go", "line": 5, "symbol": "Foo"}, []string{"main.go:3:6: func Foo() {}"}},
+		{"references", map[string]any{"operation": "references", "path": "main.go", "line": 3, "symbol": "Foo"}, []string{"main.go:3:6:", "main.go:5:15: func main() { Foo() }"}},
+		{"hover", map[string]any{"operation": "hover", "path": "main.go", "line": 3, "column": 6}, []string{"func Foo()"}},
+		{"symbols", map[string]any{"operation": "symbols", "path": "main.go", "query": "Fo"}, []string{"function Foo main.go:3"}},
+		{"diagnostics", map[string]any{"operation": "diagnostics", "path": "main.go"}, []string{"No diagnostics for main.go."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := runLSP(t, tool, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("output %q does not contain %q", got, want)
+				}
+			}
+		})
+	}
+
+	t.Run("diagnostics after edit", func(t *testing.T) {
+		if err := os.WriteFile(path, []byte(src+"var _ = undefined\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := runLSP(t, tool, map[string]any{"operation": "diagnostics", "path": path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(got, "main.go:1:1: error: undefined: x (fake)") {
+			t.Errorf("diagnostics = %q", got)
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		var audited []AuditRecord
+		tool.Audit = func(r AuditRecord) { audited = append(audited, r) }
+		defer func() { tool.Audit = nil }()
+		got, err := runLSP(t, tool, map[string]any{"operation": "rename", "path": "main.go", "line": 3, "symbol": "Foo", "new_name": "Bar"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(got, "Renamed to Bar in 1 files.") || !strings.Contains(got, "+func Bar() {}") {
+			t.Errorf("rename output = %q", got)
+		}
+		data, _ := os.ReadFile(path)
+		if strings.Contains(string(data), "Foo") || strings.Count(string(data), "Bar") != 2 {
+			t.Errorf("renamed file = %q", data)
+		}
+		if len(audited) != 1 || audited[0].Path != path {
+			t.Errorf("audit records = %+v", audited)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, input := range []map[string]any{
+			{"operation": "definition", "path": "main.go", "line": 3, "symbol": "Missing"},
+			{"operation": "definition", "path": "main.go"},
+			{"operation": "bogus", "path": "main.go"},
+			{"operation": "hover", "path": "README.md", "line": 1, "column": 1},
+		} {
+			if _, err := runLSP(t, tool, input); err == nil {
+				t.Errorf("%v: expected an error", input)
+			}
+		}
+	})
+}
+
+func TestApplyTextEdits(t *testing.T) {
+	edit := func(line, start, end int, text string) lsp.TextEdit {
+		return lsp.TextEdit{Range: lsp.Range{Start: lsp.Position{Line: line, Character: start}, End: lsp.Position{Line: line, Character: end}}, NewText: text}
+	}
+	// "é" is one UTF-16 unit but two bytes; "😀" is two units and four bytes.
+	got, err := applyTextEdits("é x\n😀 x\n", []lsp.TextEdit{edit(1, 3, 4, "y"), edit(0, 2, 3, "z")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "é z\n😀 y\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := applyTextEdits("abc\n", []lsp.TextEdit{edit(0, 0, 2, "x"), edit(0, 1, 3, "y")}); err == nil {
+		t.Error("overlapping edits were applied")
+	}
+}
+
+func TestFindIdentifier(t *testing.T) {
+	tests := []struct {
+		line, ident string
+		want        int
+	}{
+		{"x := fooBar(foo)", "foo", 12},
+		{"fooBar", "foo", 0},
+		{"bar", "foo", -1},
+	}
+	for _, tt := range tests {
+		if got := findIdentifier(tt.line, tt.ident); got != tt.want {
+			t.Errorf("findIdentifier(%q, %q) = %d, want %d", tt.line, tt.ident, got, tt.want)
+		}
+	}
+}
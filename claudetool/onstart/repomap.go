@@ -0,0 +1,340 @@
+package onstart
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"shelley.exe.dev/claudetool/codeindex"
+)
+
+const (
+	// maxRepoMapFiles caps the source files read for a repository map.
+	maxRepoMapFiles = 5000
+	// maxRepoMapFileSize is the size above which source files are left
+	// out; they are almost always generated or bundled.
+	maxRepoMapFileSize = 512 << 10
+	maxMapPackages     = 30
+	maxMapSymbols      = 5 // per package
+	maxMapEntryPoints  = 10
+	maxMapScripts      = 8
+	maxMapDoc          = 80
+	// bytesPerToken is a rough average for code and identifiers.
+	bytesPerToken = 4
+)
+
+// mapKinds are the kinds of declaration a repository map lists.
+var mapKinds = map[string]bool{"func": true, "type": true, "class": true, "interface": true, "enum": true}
+
+var (
+	identPattern  = regexp.MustCompile(`[A-Za-z_$][\w$]*`)
+	goQualified   = regexp.MustCompile(`\b([A-Za-z_]\w*)\.([A-Z]\w*)`)
+	goPackage     = regexp.MustCompile(`(?m)^package (\w+)`)
+	goMainPackage = regexp.MustCompile(`(?m)^package main\b`)
+	pyMainGuard   = regexp.MustCompile(`if __name__ == ['"]__main__['"]`)
+)
+
+// mapSymbol is an exported declaration and how often it is referred to
+// outside the file that declares it.
+type mapSymbol struct {
+	codeindex.Symbol
+	file  string // relative to the repository root
+	goPkg string // the package name of a Go declaration
+	self  int    // occurrences of the name in the declaring file
+	refs  float64
+}
+
+// mapPackage is a directory of source files.
+type mapPackage struct {
+	dir     string
+	files   int
+	refs    float64
+	symbols []*mapSymbol
+}
+
+// RepoMap returns a compact map of the code in the repository at repoPath:
+// its entry points, and its packages with their most referenced exported
+// declarations, both ordered by how much the rest of the code refers to
+// them. The map is cut off to fit in about budget tokens. It is empty if
+// the repository has no Go, TypeScript, JavaScript or Python code.
+func RepoMap(ctx context.Context, repoPath string, budget int) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "ls-files", "-z")
+	cmd.Dir = repoPath
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	// Go code refers to another package's declarations by qualified
+	// name, so a Go declaration's references are its bare uses in its
+	// own package and its qualified uses elsewhere. Other languages import
+	// names, so their references are the uses of the name anywhere.
+	totals := map[string]int{}    // identifier occurrences outside Go files
+	goTotals := map[string]int{}  // by directory and identifier
+	qualified := map[string]int{} // "pkg.Name" occurrences in Go files
+	packages := map[string]*mapPackage{}
+	var syms []*mapSymbol
+	var entryPoints []string
+	read := 0
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Split(scanZero)
+	for scanner.Scan() && read < maxRepoMapFiles {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		file := scanner.Text()
+		if vendored(file) {
+			continue
+		}
+		if path.Base(file) == "package.json" {
+			if entry := packageJSONEntry(filepath.Join(repoPath, file)); entry != "" {
+				entryPoints = append(entryPoints, file+": "+entry)
+			}
+			continue
+		}
+		if codeindex.Language(file) == "" {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(repoPath, file))
+		if err != nil || !info.Mode().IsRegular() || info.Size() > maxRepoMapFileSize {
+			continue
+		}
+		src, err := os.ReadFile(filepath.Join(repoPath, file))
+		if err != nil || codeindex.IsGenerated(src) {
+			continue
+		}
+		read++
+
+		dir := path.Dir(file)
+		isGo := codeindex.Language(file) == "go"
+		counts := map[string]int{}
+		for _, ident := range identPattern.FindAll(src, -1) {
+			counts[string(ident)]++
+		}
+		for ident, n := range counts {
+			if isGo {
+				goTotals[dir+"\x00"+ident] += n
+			} else {
+				totals[ident] += n
+			}
+		}
+		if isGo {
+			for _, m := range goQualified.FindAllSubmatch(src, -1) {
+				qualified[string(m[1])+"."+string(m[2])]++
+			}
+		}
+		fileSyms := codeindex.Symbols(file, src)
+		if entry := entryPoint(file, src, fileSyms); entry != "" {
+			entryPoints = append(entryPoints, file+": "+entry)
+		}
+
+		pkg := packages[dir]
+		if pkg == nil {
+			pkg = &mapPackage{dir: dir}
+			packages[dir] = pkg
+		}
+		pkg.files++
+		if isTestFile(file) {
+			continue
+		}
+		for _, s := range fileSyms {
+			if !s.Exported || !mapKinds[s.Kind] || s.Container != "" {
+				continue
+			}
+			// Occurrences in the declaring file are the declaration
+			// itself and internal uses.
+			sym := &mapSymbol{Symbol: s, file: file, self: counts[s.Name]}
+			if isGo {
+				sym.goPkg = goPackageName(src, dir)
+			}
+			syms = append(syms, sym)
+			pkg.symbols = append(pkg.symbols, sym)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	// A name declared in several places shares its references among them.
+	decls := map[string]int{}
+	for _, s := range syms {
+		if s.goPkg == "" {
+			decls[s.Name]++
+		}
+	}
+	for _, s := range syms {
+		if s.goPkg != "" {
+			s.refs = float64(goTotals[path.Dir(s.file)+"\x00"+s.Name] - s.self + qualified[s.goPkg+"."+s.Name])
+			continue
+		}
+		s.refs = float64(totals[s.Name]-s.self) / float64(decls[s.Name])
+	}
+
+	var ranked []*mapPackage
+	for _, pkg := range packages {
+		pkg.symbols = slices.DeleteFunc(pkg.symbols, func(s *mapSymbol) bool { return s.refs <= 0 })
+		if len(pkg.symbols) == 0 {
+			continue
+		}
+		slices.SortFunc(pkg.symbols, func(a, b *mapSymbol) int {
+			return cmp.Or(cmp.Compare(b.refs, a.refs), cmp.Compare(a.file, b.file), cmp.Compare(a.Line, b.Line))
+		})
+		for _, s := range pkg.symbols {
+			pkg.refs += s.refs
+		}
+		ranked = append(ranked, pkg)
+	}
+	slices.SortFunc(ranked, func(a, b *mapPackage) int {
+		return cmp.Or(cmp.Compare(b.refs, a.refs), cmp.Compare(a.dir, b.dir))
+	})
+	slices.SortFunc(entryPoints, func(a, b string) int {
+		return cmp.Or(cmp.Compare(strings.Count(a, "/"), strings.Count(b, "/")), cmp.Compare(a, b))
+	})
+
+	if len(ranked) == 0 && len(entryPoints) == 0 {
+		return "", nil
+	}
+	m := &mapWriter{limit: budget * bytesPerToken}
+	if len(entryPoints) > 0 {
+		m.line("Entry points:")
+		for _, e := range entryPoints[:min(len(entryPoints), maxMapEntryPoints)] {
+			m.line("  " + e)
+		}
+	}
+	if len(ranked) > 0 {
+		m.line("Packages, most referenced first, with their most referenced exported declarations:")
+		for _, pkg := range ranked[:min(len(ranked), maxMapPackages)] {
+			m.line(fmt.Sprintf("  %s/ (%d files)", pkg.dir, pkg.files))
+			for _, s := range pkg.symbols[:min(len(pkg.symbols), maxMapSymbols)] {
+				line := fmt.Sprintf("    %s:%d %s %s", path.Base(s.file), s.Line, s.Kind, s.Name)
+				if s.Doc != "" {
+					line += " — " + truncateDoc(s.Doc)
+				}
+				m.line(line)
+			}
+		}
+	}
+	return m.String(), nil
+}
+
+// goPackageName returns the name in the package clause of Go source,
+// or the base of its directory if there isn't one.
+func goPackageName(src []byte, dir string) string {
+	if m := goPackage.FindSubmatch(src); m != nil {
+		return string(m[1])
+	}
+	return path.Base(dir)
+}
+
+// mapWriter collects lines until they would go over a size limit.
+type mapWriter struct {
+	strings.Builder
+	limit int
+	full  bool
+}
+
+func (m *mapWriter) line(s string) {
+	if m.full || m.Len()+len(s)+1 > m.limit {
+		m.full = true
+		return
+	}
+	m.WriteString(s)
+	m.WriteByte('\n')
+}
+
+// vendored reports whether file is third-party code checked into the
+// repository.
+func vendored(file string) bool {
+	for _, dir := range strings.Split(path.Dir(file), "/") {
+		switch dir {
+		case "vendor", "node_modules", "third_party", "testdata":
+			return true
+		}
+	}
+	return false
+}
+
+// isTestFile reports whether file holds tests, whose declarations the
+// rest of the code doesn't use.
+func isTestFile(file string) bool {
+	base := path.Base(file)
+	return strings.HasSuffix(base, "_test.go") ||
+		strings.HasPrefix(base, "test_") || strings.HasSuffix(base, "_test.py") ||
+		strings.Contains(base, ".test.") || strings.Contains(base, ".spec.")
+}
+
+// entryPoint describes how file is run, or returns "" if it isn't a
+// program's entry point.
+func entryPoint(file string, src []byte, syms []codeindex.Symbol) string {
+	switch path.Ext(file) {
+	case ".go":
+		if !goMainPackage.Match(src) {
+			return ""
+		}
+		for _, s := range syms {
+			if s.Kind == "func" && s.Name == "main" {
+				return "func main"
+			}
+		}
+	case ".py":
+		if pyMainGuard.Match(src) {
+			return `if __name__ == "__main__"`
+		}
+	}
+	return ""
+}
+
+// packageJSONEntry describes the main module, binaries and scripts
+// declared in a package.json file.
+func packageJSONEntry(file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	var pkg struct {
+		Main    string            `json:"main"`
+		Bin     json.RawMessage   `json:"bin"`
+		Scripts map[string]string `json:"scripts"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return ""
+	}
+	var parts []string
+	if pkg.Main != "" {
+		parts = append(parts, "main "+pkg.Main)
+	}
+	var bin string
+	var bins map[string]string
+	if json.Unmarshal(pkg.Bin, &bin) == nil && bin != "" {
+		parts = append(parts, "bin "+bin)
+	} else if json.Unmarshal(pkg.Bin, &bins) == nil && len(bins) > 0 {
+		parts = append(parts, "bin "+strings.Join(slices.Sorted(maps.Keys(bins)), ", "))
+	}
+	if len(pkg.Scripts) > 0 {
+		scripts := slices.Sorted(maps.Keys(pkg.Scripts))
+		list := strings.Join(scripts[:min(len(scripts), maxMapScripts)], ", ")
+		if len(scripts) > maxMapScripts {
+			list += fmt.Sprintf(" and %d more", len(scripts)-maxMapScripts)
+		}
+		parts = append(parts, "scripts "+list)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func truncateDoc(doc string) string {
+	if r := []rune(doc); len(r) > maxMapDoc {
+		return string(r[:maxMapDoc]) + "…"
+	}
+	return doc
+}
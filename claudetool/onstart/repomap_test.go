@@ -0,0 +1,108 @@
+package onstart
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepoMap(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"cmd/app/main.go": `package main
+
+import "example.com/app/store"
+
+func main() {
+	s := store.Open()
+	s.Put(store.Key("a"))
+	_ = store.Key("b")
+}
+`,
+		"store/store.go": `package store
+
+// Store holds values by key.
+type Store struct{}
+
+// Key names a value.
+type Key string
+
+// Open returns an empty store.
+func Open() *Store { return &Store{} }
+
+func (s *Store) Put(k Key) {}
+
+// Unused is exported but never referred to.
+func Unused() {}
+
+func helper() {}
+`,
+		"store/store_test.go": "package store\n\nfunc TestHelper() { helper(); Open() }\n",
+		"web/package.json":    `{"main": "index.js", "scripts": {"dev": "vite", "build": "vite build"}}`,
+		"web/api.ts":          "export function fetchItems() {}\nexport class Client {}\n",
+		"web/app.ts":          "import { fetchItems } from './api';\nfetchItems();\nfetchItems();\n",
+		"tools/gen.py":        "def run():\n    pass\n\nif __name__ == \"__main__\":\n    run()\n",
+		"vendor/dep/dep.go":   "package dep\n\nfunc Vendored() {}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command("sh", "-c", "git init -q && git add -A")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git: %v\n%s", err, out)
+	}
+
+	got, err := RepoMap(context.Background(), dir, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Entry points:
+  tools/gen.py: if __name__ == "__main__"
+  web/package.json: main index.js; scripts build, dev
+  cmd/app/main.go: func main
+Packages, most referenced first, with their most referenced exported declarations:
+  store/ (2 files)
+    store.go:7 type Key — Key names a value.
+    store.go:10 func Open — Open returns an empty store.
+  web/ (2 files)
+    api.ts:1 func fetchItems
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// The map is cut off at whole lines to fit the budget.
+	small, err := RepoMap(context.Background(), dir, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(small) > 20*bytesPerToken || !strings.HasPrefix(want, small) || !strings.HasSuffix(small, "\n") {
+		t.Errorf("map cut to 20 tokens:\n%s", small)
+	}
+}
+
+func TestRepoMapNoCode(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# hi\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("sh", "-c", "git init -q && git add -A")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git: %v\n%s", err, out)
+	}
+	got, err := RepoMap(context.Background(), dir, 1000)
+	if err != nil || got != "" {
+		t.Errorf("RepoMap = %q, %v; want empty", got, err)
+	}
+}
@@ -14,9 +14,9 @@ import (
 	"strings"
 
 	"github.com/pkg/diff"
+	"shelley.exe.dev/claudetool/patchkit"
 	"shelley.exe.dev/llm"
 	"sketch.dev/claudetool/editbuf"
-	"sketch.dev/claudetool/patchkit"
 )
 
 // PatchCallback defines the signature for patch tool callbacks.
@@ -39,6 +39,11 @@ type PatchTool struct {
 	// NB: The actual implementation of the patch tool is unchanged,
 	// this flag merely extends the description and input schema to include the clipboard operations.
 	ClipboardEnabled bool
+	// Audit, if set, is told about every file the tool writes.
+	Audit AuditFunc
+	// PostPatch configures formatters and checks to run on patched files.
+	// A repository's .shelley/post-patch.json takes precedence. May be nil.
+	PostPatch *PostPatchConfig
 	// clipboards stores clipboard name -> text
 	clipboards map[string]string
 }
@@ -50,13 +55,14 @@ func (p *PatchTool) getWorkingDir() string {
 
 // Tool returns an llm.Tool based on p.
 func (p *PatchTool) Tool() *llm.Tool {
-	description := PatchBaseDescription + PatchUsageNotes
+	description := PatchBaseDescription + PatchFilesDescription + PatchUsageNotes
 	schema := PatchStandardInputSchema
 	switch {
 	case p.Simplified:
+		description = PatchBaseDescription + PatchUsageNotes
 		schema = PatchStandardSimplifiedSchema
 	case p.ClipboardEnabled:
-		description = PatchBaseDescription + PatchClipboardDescription + PatchUsageNotes
+		description = PatchBaseDescription + PatchFilesDescription + PatchClipboardDescription + PatchUsageNotes
 		schema = PatchClipboardInputSchema
 	}
 	return &llm.Tool{
@@ -77,6 +83,14 @@ Operations:
 - append_eof: Append new text at the end of the file
 - prepend_bof: Insert new text at the beginning of the file
 - overwrite: Replace the entire file with new content (automatically creates the file)
+`
+
+	PatchFilesDescription = `
+Multiple files:
+- Instead of path and patches, pass files: a list of {path, patches, moveTo, delete}
+- All files are checked first; then either every change is made or none is
+- moveTo renames the file (after applying its patches, if any); delete removes it
+- Use this for changes that span files, such as renames and refactors
 `
 
 	PatchClipboardDescription = `
@@ -113,7 +127,6 @@ large overwrite. Prefer incremental replace operations over full file overwrites
 	PatchStandardInputSchema = `
 {
   "type": "object",
-  "required": ["path", "patches"],
   "properties": {
     "path": {
       "type": "string",
@@ -141,6 +154,48 @@ large overwrite. Prefer incremental replace operations over full file overwrites
           }
         }
       }
+    },
+    "files": {
+      "type": "array",
+      "description": "Instead of path and patches: changes to several files, made all together or not at all",
+      "items": {
+        "type": "object",
+        "required": ["path"],
+        "properties": {
+          "path": {
+            "type": "string",
+            "description": "Path to the file"
+          },
+          "patches": {
+            "type": "array",
+            "description": "Patch requests to apply to this file, as in the top-level patches",
+            "items": {
+              "type": "object",
+              "required": ["operation"],
+              "properties": {
+                "operation": {
+                  "type": "string",
+                  "enum": ["replace", "append_eof", "prepend_bof", "overwrite"]
+                },
+                "oldText": {
+                  "type": "string"
+                },
+                "newText": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "moveTo": {
+            "type": "string",
+            "description": "Rename the file to this path, after applying patches"
+          },
+          "delete": {
+            "type": "boolean",
+            "description": "Delete the file"
+          }
+        }
+      }
     }
   }
 }
@@ -179,7 +234,6 @@ large overwrite. Prefer incremental replace operations over full file overwrites
 	PatchClipboardInputSchema = `
 {
   "type": "object",
-  "required": ["path", "patches"],
   "properties": {
     "path": {
       "type": "string",
@@ -229,6 +283,48 @@ large overwrite. Prefer incremental replace operations over full file overwrites
           }
         }
       }
+    },
+    "files": {
+      "type": "array",
+      "description": "Instead of path and patches: changes to several files, made all together or not at all",
+      "items": {
+        "type": "object",
+        "required": ["path"],
+        "properties": {
+          "path": {
+            "type": "string",
+            "description": "Path to the file"
+          },
+          "patches": {
+            "type": "array",
+            "description": "Patch requests to apply to this file, as in the top-level patches",
+            "items": {
+              "type": "object",
+              "required": ["operation"],
+              "properties": {
+                "operation": {
+                  "type": "string",
+                  "enum": ["replace", "append_eof", "prepend_bof", "overwrite"]
+                },
+                "oldText": {
+                  "type": "string"
+                },
+                "newText": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "moveTo": {
+            "type": "string",
+            "description": "Rename the file to this path, after applying patches"
+          },
+          "delete": {
+            "type": "boolean",
+            "description": "Delete the file"
+          }
+        }
+      }
     }
   }
 }
@@ -241,6 +337,19 @@ large overwrite. Prefer incremental replace operations over full file overwrites
 type PatchInput struct {
 	Path    string         `json:"path"`
 	Patches []PatchRequest `json:"patches"`
+	// Files, if set instead of Path and Patches, changes several files
+	// at once: either all of the changes are made, or none are.
+	Files []PatchFile `json:"files,omitempty"`
+}
+
+// PatchFile is the change to one file in a multi-file patch.
+type PatchFile struct {
+	Path    string         `json:"path"`
+	Patches []PatchRequest `json:"patches,omitempty"`
+	// MoveTo, if set, renames the file after applying Patches.
+	MoveTo string `json:"moveTo,omitempty"`
+	// Delete removes the file.
+	Delete bool `json:"delete,omitempty"`
 }
 
 // PatchInputOne is a simplified version of PatchInput for single patch operations.
@@ -264,6 +373,9 @@ type PatchInputOneString struct {
 type PatchDisplayData struct {
 	Path string `json:"path"`
 	Diff string `json:"diff"`
+	// Files lists the files changed by a multi-file patch, for which Path
+	// is empty and Diff has a "diff --git" header before each file.
+	Files []string `json:"files,omitempty"`
 }
 
 // PatchRequest represents a single patch operation.
@@ -293,9 +405,12 @@ func (p *PatchTool) Run(ctx context.Context, m json.RawMessage) llm.ToolOut {
 	}
 	input, err := p.patchParse(m)
 	var output llm.ToolOut
-	if err != nil {
+	switch {
+	case err != nil:
 		output = llm.ErrorToolOut(err)
-	} else {
+	case len(input.Files) > 0:
+		output = p.patchFilesRun(ctx, &input)
+	default:
 		output = p.patchRun(ctx, &input)
 	}
 	if p.Callback != nil {
@@ -311,7 +426,7 @@ func (p *PatchTool) Run(ctx context.Context, m json.RawMessage) llm.ToolOut {
 func (p *PatchTool) patchParse(m json.RawMessage) (PatchInput, error) {
 	var input PatchInput
 	originalErr := json.Unmarshal(m, &input)
-	if originalErr == nil && len(input.Patches) > 0 {
+	if originalErr == nil && (len(input.Patches) > 0 || len(input.Files) > 0) {
 		return input, nil
 	}
 	var inputOne PatchInputOne
@@ -363,26 +478,84 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 	}
 	// TODO: check whether the file is autogenerated, and if so, require a "force" flag to modify it.
 
-	orig, err := os.ReadFile(input.Path)
-	// If the file doesn't exist, we can still apply patches
-	// that don't require finding existing text.
+	orig, err := readPatchTarget(input.Path, input.Patches)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
+	likelyGoFile := strings.HasSuffix(input.Path, ".go")
+
+	autogenerated := likelyGoFile && IsAutogeneratedGoFile(orig)
+
+	patched, clipboardsModified, err := p.applyPatches(ctx, input.Path, orig, input.Patches)
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(input.Path), 0o700); err != nil {
+		return llm.ErrorfToolOut("failed to create directory %q: %w", filepath.Dir(input.Path), err)
+	}
+	if err := os.WriteFile(input.Path, patched, 0o600); err != nil {
+		return llm.ErrorfToolOut("failed to write patched contents to file %q: %w", input.Path, err)
+	}
+	hookReport, patched := runPostPatchHooks(ctx, input.Path, patched, p.PostPatch)
+	if p.Audit != nil {
+		rec := AuditRecord{Kind: AuditPatch, Cwd: p.getWorkingDir(), Path: input.Path, AfterHash: contentHash(patched)}
+		if orig != nil {
+			rec.BeforeHash = contentHash(orig)
+		}
+		p.Audit(rec)
+	}
+
+	response := new(strings.Builder)
+	fmt.Fprintf(response, "<patches_applied>all</patches_applied>\n")
+	for _, msg := range clipboardsModified {
+		fmt.Fprintln(response, msg)
+	}
+
+	if autogenerated {
+		fmt.Fprintf(response, "<warning>%q appears to be autogenerated. Patches were applied anyway.</warning>\n", input.Path)
+	}
+	response.WriteString(hookReport)
+
+	diff := generateUnifiedDiff(input.Path, string(orig), string(patched))
+
+	// Display data for the UI includes the unified diff only.
+	displayData := PatchDisplayData{
+		Path: input.Path,
+		Diff: diff,
+	}
+
+	return llm.ToolOut{
+		LLMContent: llm.TextContent(response.String()),
+		Display:    displayData,
+	}
+}
+
+// readPatchTarget reads the file at path, to which patches will be applied.
+// If the file doesn't exist, it returns nil contents, provided the patches
+// don't require finding existing text.
+func readPatchTarget(path string, patches []PatchRequest) ([]byte, error) {
+	orig, err := os.ReadFile(path)
 	switch {
 	case errors.Is(err, os.ErrNotExist):
-		for _, patch := range input.Patches {
+		for _, patch := range patches {
 			switch patch.Operation {
 			case "prepend_bof", "append_eof", "overwrite":
 			default:
-				return llm.ErrorfToolOut("file %q does not exist", input.Path)
+				return nil, fmt.Errorf("file %q does not exist", path)
 			}
 		}
+		return nil, nil
 	case err != nil:
-		return llm.ErrorfToolOut("failed to read file %q: %w", input.Path, err)
+		return nil, fmt.Errorf("failed to read file %q: %w", path, err)
 	}
+	return orig, nil
+}
 
-	likelyGoFile := strings.HasSuffix(input.Path, ".go")
-
-	autogenerated := likelyGoFile && IsAutogeneratedGoFile(orig)
-
+// applyPatches applies patches to orig, the contents of the file at path
+// (nil if it doesn't exist). It returns the result and any notes about
+// clipboards whose contents had to be altered.
+func (p *PatchTool) applyPatches(ctx context.Context, path string, orig []byte, patches []PatchRequest) ([]byte, []string, error) {
 	origStr := string(orig)
 	// Process the patches "simultaneously", minimizing them along the way.
 	// Claude generates patches that interact with each other.
@@ -406,14 +579,14 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 		clipboardsModified = append(clipboardsModified, fmt.Sprintf(`<clipboard_modified name="%s"><message>clipboard contents altered in order to match uniquely</message><new_contents>%q</new_contents></clipboard_modified>`, patch.ToClipboard, matchedOldText))
 	}
 
-	for i, patch := range input.Patches {
+	for i, patch := range patches {
 		// Process toClipboard first, so that copy works
 		if patch.ToClipboard != "" {
 			if patch.Operation != "replace" {
-				return llm.ErrorfToolOut("toClipboard (%s): can only be used with replace operation", patch.ToClipboard)
+				return nil, nil, fmt.Errorf("toClipboard (%s): can only be used with replace operation", patch.ToClipboard)
 			}
 			if patch.OldText == "" {
-				return llm.ErrorfToolOut("toClipboard (%s): oldText cannot be empty when using toClipboard", patch.ToClipboard)
+				return nil, nil, fmt.Errorf("toClipboard (%s): oldText cannot be empty when using toClipboard", patch.ToClipboard)
 			}
 			p.clipboards[patch.ToClipboard] = patch.OldText
 		}
@@ -423,7 +596,7 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 		if patch.FromClipboard != "" {
 			clipboardText, ok := p.clipboards[patch.FromClipboard]
 			if !ok {
-				return llm.ErrorfToolOut("fromClipboard (%s): no clipboard with that name", patch.FromClipboard)
+				return nil, nil, fmt.Errorf("fromClipboard (%s): no clipboard with that name", patch.FromClipboard)
 			}
 			newText = clipboardText
 		}
@@ -432,7 +605,7 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 		if patch.Reindent != nil {
 			reindentedText, err := reindent(newText, patch.Reindent)
 			if err != nil {
-				return llm.ErrorfToolOut("reindent(%q -> %q): %w", patch.Reindent.Strip, patch.Reindent.Add, err)
+				return nil, nil, fmt.Errorf("reindent(%q -> %q): %w", patch.Reindent.Strip, patch.Reindent.Add, err)
 			}
 			newText = reindentedText
 		}
@@ -446,7 +619,7 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 			buf.Replace(0, len(orig), newText)
 		case "replace":
 			if patch.OldText == "" {
-				return llm.ErrorfToolOut("patch %d: oldText cannot be empty for %s operation", i, patch.Operation)
+				return nil, nil, fmt.Errorf("patch %d: oldText cannot be empty for %s operation", i, patch.Operation)
 			}
 
 			// Attempt to apply the patch.
@@ -482,22 +655,34 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 				continue
 			}
 
-			// Try ignoring leading/trailing whitespace in a semantically safe way.
-			spec, ok = patchkit.UniqueInValidGo(origStr, patch.OldText, newText)
-			if ok {
-				slog.DebugContext(ctx, "patch_applied", "method", "unique_in_valid_go")
-				spec.ApplyToEditBuf(buf)
-				updateToClipboard(patch, spec)
-				continue
-			}
+			if patchkit.HasTokenizer(path) {
+				// Try ignoring whitespace, except where this file's language says it matters.
+				// The Go-specific fallbacks below don't know those rules, so skip them.
+				spec, ok = patchkit.UniqueTokens(path, origStr, patch.OldText, newText)
+				if ok {
+					slog.DebugContext(ctx, "patch_applied", "method", "unique_tokens")
+					spec.ApplyToEditBuf(buf)
+					updateToClipboard(patch, spec)
+					continue
+				}
+			} else {
+				// Try ignoring leading/trailing whitespace in a semantically safe way.
+				spec, ok = patchkit.UniqueInValidGo(origStr, patch.OldText, newText)
+				if ok {
+					slog.DebugContext(ctx, "patch_applied", "method", "unique_in_valid_go")
+					spec.ApplyToEditBuf(buf)
+					updateToClipboard(patch, spec)
+					continue
+				}
 
-			// Try ignoring semantically insignificant whitespace.
-			spec, ok = patchkit.UniqueGoTokens(origStr, patch.OldText, newText)
-			if ok {
-				slog.DebugContext(ctx, "patch_applied", "method", "unique_go_tokens")
-				spec.ApplyToEditBuf(buf)
-				updateToClipboard(patch, spec)
-				continue
+				// Try ignoring semantically insignificant whitespace.
+				spec, ok = patchkit.UniqueGoTokens(origStr, patch.OldText, newText)
+				if ok {
+					slog.DebugContext(ctx, "patch_applied", "method", "unique_go_tokens")
+					spec.ApplyToEditBuf(buf)
+					updateToClipboard(patch, spec)
+					continue
+				}
 			}
 
 			// Try trimming the first line of the patch, if we can do so safely.
@@ -514,7 +699,7 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 			patchErr = errors.Join(patchErr, fmt.Errorf("old text not found:\n%s", patch.OldText))
 			continue
 		default:
-			return llm.ErrorfToolOut("unrecognized operation %q", patch.Operation)
+			return nil, nil, fmt.Errorf("unrecognized operation %q", patch.Operation)
 		}
 	}
 
@@ -523,42 +708,14 @@ func (p *PatchTool) patchRun(ctx context.Context, input *PatchInput) llm.ToolOut
 		for _, msg := range clipboardsModified {
 			errorMsg += "\n" + msg
 		}
-		return llm.ErrorToolOut(fmt.Errorf("%s", errorMsg))
+		return nil, nil, fmt.Errorf("%s", errorMsg)
 	}
 
 	patched, err := buf.Bytes()
 	if err != nil {
-		return llm.ErrorToolOut(err)
-	}
-	if err := os.MkdirAll(filepath.Dir(input.Path), 0o700); err != nil {
-		return llm.ErrorfToolOut("failed to create directory %q: %w", filepath.Dir(input.Path), err)
-	}
-	if err := os.WriteFile(input.Path, patched, 0o600); err != nil {
-		return llm.ErrorfToolOut("failed to write patched contents to file %q: %w", input.Path, err)
-	}
-
-	response := new(strings.Builder)
-	fmt.Fprintf(response, "<patches_applied>all</patches_applied>\n")
-	for _, msg := range clipboardsModified {
-		fmt.Fprintln(response, msg)
-	}
-
-	if autogenerated {
-		fmt.Fprintf(response, "<warning>%q appears to be autogenerated. Patches were applied anyway.</warning>\n", input.Path)
-	}
-
-	diff := generateUnifiedDiff(input.Path, string(orig), string(patched))
-
-	// Display data for the UI includes the unified diff only.
-	displayData := PatchDisplayData{
-		Path: input.Path,
-		Diff: diff,
-	}
-
-	return llm.ToolOut{
-		LLMContent: llm.TextContent(response.String()),
-		Display:    displayData,
+		return nil, nil, err
 	}
+	return patched, clipboardsModified, nil
 }
 
 // IsAutogeneratedGoFile reports whether a Go file has markers indicating it was autogenerated.
@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"shelley.exe.dev/llm"
 )
@@ -236,6 +238,38 @@ func TestPatchTool_FuzzyMatching(t *testing.T) {
 	}
 }
 
+func TestPatchTool_FuzzyMatchingTypeScript(t *testing.T) {
+	tempDir := t.TempDir()
+	patch := &PatchTool{WorkingDir: NewMutableWorkingDir(tempDir)}
+	ctx := context.Background()
+
+	testFile := filepath.Join(tempDir, "fuzzy.ts")
+	if err := os.WriteFile(testFile, []byte("export function test() {\n\tif (condition) {\n\t\tlog(\"hello\", 1);\n\n\t\tlog(\"world\");\n\t}\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Spaces instead of tabs, a missing blank line and different spacing
+	// in the arguments: only the token matcher can find this.
+	input := PatchInput{
+		Path: testFile,
+		Patches: []PatchRequest{{
+			Operation: "replace",
+			OldText:   "    log(\"hello\",1);\n    log(\"world\");",
+			NewText:   "    log(\"modified\");",
+		}},
+	}
+	msg, _ := json.Marshal(input)
+	result := patch.Run(ctx, msg)
+	if result.Error != nil {
+		t.Fatalf("fuzzy matching failed: %v", result.Error)
+	}
+
+	content, _ := os.ReadFile(testFile)
+	if want := "export function test() {\n\tif (condition) {\n\t\tlog(\"modified\");\n\t}\n}\n"; string(content) != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+}
+
 func TestPatchTool_ErrorCases(t *testing.T) {
 	tempDir := t.TempDir()
 	patch := &PatchTool{WorkingDir: NewMutableWorkingDir(tempDir)}
@@ -706,3 +740,273 @@ func TestPatchTool_DisplayDataContainsUnifiedDiffOnly(t *testing.T) {
 		t.Fatalf("display payload should not include newContent: %s", string(displayJSON))
 	}
 }
+
+func TestReplaceFileSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "real", "notes.txt")
+	os.Mkdir(filepath.Dir(target), 0o755)
+	if err := os.WriteFile(target, []byte("old\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "notes.txt")
+	if err := os.Symlink(filepath.Join("real", "notes.txt"), link); err != nil {
+		t.Fatal(err)
+	}
+
+	// The link's target is replaced, and the link is kept.
+	if err := replaceFile(link, []byte("new\n")); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("link: %v, %v; want it to stay a symlink", fi.Mode(), err)
+	}
+	if content, _ := os.ReadFile(target); string(content) != "new\n" {
+		t.Errorf("target = %q", content)
+	}
+	if fi, err := os.Stat(target); err != nil || fi.Mode().Perm() != 0o640 {
+		t.Errorf("target: %v, %v; want mode 0640", fi.Mode(), err)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(dir, ".shelley-patch-*")); len(temps) > 0 {
+		t.Errorf("temporary file written next to the link: %v", temps)
+	}
+}
+
+func TestPatchTool_PostPatchHooks(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "notes.txt")
+	run := func(p *PatchTool, text string) llm.ToolOut {
+		t.Helper()
+		msg, _ := json.Marshal(PatchInput{Path: file, Patches: []PatchRequest{{Operation: "overwrite", NewText: text}}})
+		out := p.Run(ctx, msg)
+		if out.Error != nil {
+			t.Fatalf("patch failed: %v", out.Error)
+		}
+		return out
+	}
+	resultText := func(out llm.ToolOut) string { return out.LLMContent[0].Text }
+
+	cfg := &PostPatchConfig{Hooks: []PostPatchHook{
+		{Extensions: []string{".txt"}, Command: `tr a-z A-Z < {file}`, Formatter: true},
+		{Extensions: []string{".txt"}, Command: `if grep -qi bad {file}; then echo "$(basename {file}): bad word" >&2; exit 1; fi`},
+		{Extensions: []string{".go"}, Command: `echo never runs; exit 1`},
+	}}
+	patch := &PatchTool{WorkingDir: NewMutableWorkingDir(dir), PostPatch: cfg}
+
+	// Check failures are reported; formatting is only suggested.
+	out := run(patch, "a bad line\n")
+	if got := resultText(out); !strings.Contains(got, "notes.txt: bad word") || !strings.Contains(got, "<post_patch_unformatted") || strings.Contains(got, "never runs") {
+		t.Errorf("result = %q", got)
+	}
+	if content, _ := os.ReadFile(file); string(content) != "a bad line\n" {
+		t.Errorf("file = %q, want it unformatted", content)
+	}
+
+	// With ApplyFormatting, formatter output is written, keeping the file's
+	// mode, and shows in the diff.
+	cfg.ApplyFormatting = true
+	os.Chmod(file, 0o750)
+	out = run(patch, "a good line\n")
+	if got := resultText(out); !strings.Contains(got, "<post_patch_formatted") || strings.Contains(got, "post_patch_diagnostics") {
+		t.Errorf("result = %q", got)
+	}
+	if content, _ := os.ReadFile(file); string(content) != "A GOOD LINE\n" {
+		t.Errorf("file = %q, want it formatted", content)
+	}
+	if fi, err := os.Stat(file); err != nil || fi.Mode().Perm() != 0o750 {
+		t.Errorf("formatted file: %v, %v; want mode 0750", fi.Mode(), err)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(dir, ".shelley-patch-*")); len(temps) > 0 {
+		t.Errorf("temporary files left behind: %v", temps)
+	}
+	if diff := out.Display.(PatchDisplayData).Diff; !strings.Contains(diff, "+A GOOD LINE") {
+		t.Errorf("diff = %q", diff)
+	}
+
+	// Hooks share a time budget.
+	patch.PostPatch = &PostPatchConfig{TimeoutSeconds: 0.2, Hooks: []PostPatchHook{{Command: "sleep 5"}, {Command: "true"}}}
+	start := time.Now()
+	out = run(patch, "x\n")
+	if got := resultText(out); strings.Count(got, "<post_patch_skipped") != 2 || time.Since(start) > 3*time.Second {
+		t.Errorf("result after %v = %q", time.Since(start), got)
+	}
+
+	// A repository's own config is ignored unless the server-wide one
+	// allows it, and then replaces it.
+	if err := exec.Command("git", "-C", dir, "init", "-q").Run(); err != nil {
+		t.Skipf("git unavailable: %v", err)
+	}
+	os.MkdirAll(filepath.Join(dir, ".shelley"), 0o755)
+	os.WriteFile(filepath.Join(dir, ".shelley", "post-patch.json"), []byte(`{"hooks":[{"command":"echo from repo; exit 1"}]}`), 0o644)
+	patch.PostPatch = cfg
+	if got := resultText(run(patch, "bad\n")); strings.Contains(got, "from repo") || !strings.Contains(got, "bad word") {
+		t.Errorf("result with repo config not allowed = %q", got)
+	}
+	patch.PostPatch = nil
+	if got := resultText(run(patch, "bad\n")); strings.Contains(got, "from repo") {
+		t.Errorf("result with repo config and no server config = %q", got)
+	}
+	cfg.AllowRepoConfig = true
+	patch.PostPatch = cfg
+	if got := resultText(run(patch, "bad\n")); !strings.Contains(got, "from repo") || strings.Contains(got, "bad word") {
+		t.Errorf("result with repo config = %q", got)
+	}
+	os.WriteFile(filepath.Join(dir, ".shelley", "post-patch.json"), []byte(`{`), 0o644)
+	if got := resultText(run(patch, "bad\n")); !strings.Contains(got, "invalid .shelley/post-patch.json") {
+		t.Errorf("result with invalid repo config = %q", got)
+	}
+}
+
+func TestPatchTool_MultiFile(t *testing.T) {
+	tempDir := t.TempDir()
+	var audited []AuditRecord
+	patch := &PatchTool{WorkingDir: NewMutableWorkingDir(tempDir), Audit: func(r AuditRecord) { audited = append(audited, r) }}
+	ctx := context.Background()
+
+	write := func(name, content string, mode os.FileMode) {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(tempDir, name))
+		if err != nil {
+			return "<missing>"
+		}
+		return string(data)
+	}
+	write("a.txt", "alpha\n", 0o644)
+	write("b.sh", "echo beta\n", 0o755)
+	write("c.txt", "gamma\n", 0o644)
+
+	run := func(files []PatchFile) llm.ToolOut {
+		msg, _ := json.Marshal(map[string]any{"files": files})
+		return patch.Run(ctx, msg)
+	}
+
+	t.Run("all or nothing", func(t *testing.T) {
+		result := run([]PatchFile{
+			{Path: "a.txt", Patches: []PatchRequest{{Operation: "replace", OldText: "alpha", NewText: "ALPHA"}}},
+			{Path: "b.sh", MoveTo: "bin/b.sh"},
+			{Path: "c.txt", Patches: []PatchRequest{{Operation: "replace", OldText: "missing", NewText: "x"}}},
+		})
+		if result.Error == nil {
+			t.Fatal("expected an error")
+		}
+		if !strings.Contains(result.Error.Error(), "old text not found") || !strings.Contains(result.Error.Error(), "no files were changed") {
+			t.Errorf("error = %v", result.Error)
+		}
+		if read("a.txt") != "alpha\n" || read("b.sh") != "echo beta\n" || read("bin/b.sh") != "<missing>" {
+			t.Error("files were changed by a failed patch")
+		}
+		if len(audited) != 0 {
+			t.Errorf("audit records = %+v", audited)
+		}
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		for name, files := range map[string][]PatchFile{
+			"listed twice":   {{Path: "a.txt", Delete: true}, {Path: "a.txt", MoveTo: "x.txt"}},
+			"target exists":  {{Path: "a.txt", MoveTo: "c.txt"}},
+			"same target":    {{Path: "a.txt", MoveTo: "x.txt"}, {Path: "c.txt", MoveTo: "x.txt"}},
+			"missing file":   {{Path: "nope.txt", Delete: true}},
+			"nothing to do":  {{Path: "a.txt"}},
+			"delete patched": {{Path: "a.txt", Delete: true, Patches: []PatchRequest{{Operation: "overwrite", NewText: "x"}}}},
+		} {
+			if result := run(files); result.Error == nil {
+				t.Errorf("%s: expected an error", name)
+			}
+		}
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		result := run([]PatchFile{
+			{Path: "a.txt", Patches: []PatchRequest{{Operation: "replace", OldText: "alpha", NewText: "ALPHA"}}},
+			{Path: "b.sh", MoveTo: "bin/b.sh", Patches: []PatchRequest{{Operation: "append_eof", NewText: "echo done\n"}}},
+			{Path: "c.txt", Delete: true},
+			{Path: "d.txt", Patches: []PatchRequest{{Operation: "overwrite", NewText: "delta\n"}}},
+		})
+		if result.Error != nil {
+			t.Fatalf("patch failed: %v", result.Error)
+		}
+		if got := read("a.txt"); got != "ALPHA\n" {
+			t.Errorf("a.txt = %q", got)
+		}
+		if got := read("bin/b.sh"); got != "echo beta\necho done\n" {
+			t.Errorf("bin/b.sh = %q", got)
+		}
+		if fi, err := os.Stat(filepath.Join(tempDir, "bin/b.sh")); err != nil || fi.Mode().Perm() != 0o755 {
+			t.Errorf("bin/b.sh mode = %v, %v", fi.Mode(), err)
+		}
+		if fi, err := os.Stat(filepath.Join(tempDir, "a.txt")); err != nil || fi.Mode().Perm() != 0o644 {
+			t.Errorf("a.txt mode = %v, %v", fi.Mode(), err)
+		}
+		if read("b.sh") != "<missing>" || read("c.txt") != "<missing>" {
+			t.Error("moved or deleted files still exist")
+		}
+		if got := read("d.txt"); got != "delta\n" {
+			t.Errorf("d.txt = %q", got)
+		}
+		if entries, _ := filepath.Glob(filepath.Join(tempDir, "*", ".shelley-patch-*")); len(entries) > 0 {
+			t.Errorf("temporary files left behind: %v", entries)
+		}
+
+		display := result.Display.(PatchDisplayData)
+		if len(display.Files) != 5 || display.Path != "" {
+			t.Errorf("display files = %v, path = %q", display.Files, display.Path)
+		}
+		for _, want := range []string{"+ALPHA", "rename from " + filepath.Join(tempDir, "b.sh"), "+echo done", "deleted file mode 100644", "-gamma", "new file mode 100600", "+delta"} {
+			if !strings.Contains(display.Diff, want) {
+				t.Errorf("diff does not contain %q:\n%s", want, display.Diff)
+			}
+		}
+		if n := strings.Count(display.Diff, "diff --git "); n != 4 {
+			t.Errorf("diff has %d file headers, want 4", n)
+		}
+		// One record per path: a, b (gone), bin/b, c (gone), d.
+		if len(audited) != 5 {
+			t.Errorf("got %d audit records, want 5: %+v", len(audited), audited)
+		}
+	})
+
+	t.Run("swap", func(t *testing.T) {
+		result := run([]PatchFile{
+			{Path: "a.txt", MoveTo: "d.txt"},
+			{Path: "d.txt", MoveTo: "a.txt"},
+		})
+		if result.Error != nil {
+			t.Fatalf("patch failed: %v", result.Error)
+		}
+		if read("a.txt") != "delta\n" || read("d.txt") != "ALPHA\n" {
+			t.Errorf("a.txt = %q, d.txt = %q", read("a.txt"), read("d.txt"))
+		}
+	})
+
+	t.Run("symlink", func(t *testing.T) {
+		os.Mkdir(filepath.Join(tempDir, "real"), 0o755)
+		write("real/e.txt", "epsilon\n", 0o640)
+		if err := os.Symlink(filepath.Join("real", "e.txt"), filepath.Join(tempDir, "e.txt")); err != nil {
+			t.Fatal(err)
+		}
+		result := run([]PatchFile{
+			{Path: "e.txt", Patches: []PatchRequest{{Operation: "replace", OldText: "epsilon", NewText: "EPSILON"}}},
+			{Path: "a.txt", Patches: []PatchRequest{{Operation: "append_eof", NewText: "more\n"}}},
+		})
+		if result.Error != nil {
+			t.Fatalf("patch failed: %v", result.Error)
+		}
+		// The file the link points to is patched, and the link is kept.
+		if fi, err := os.Lstat(filepath.Join(tempDir, "e.txt")); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("e.txt: %v, %v; want it to stay a symlink", fi.Mode(), err)
+		}
+		if got := read("real/e.txt"); got != "EPSILON\n" {
+			t.Errorf("real/e.txt = %q", got)
+		}
+		if fi, err := os.Stat(filepath.Join(tempDir, "real/e.txt")); err != nil || fi.Mode().Perm() != 0o640 {
+			t.Errorf("real/e.txt mode = %v, %v", fi.Mode(), err)
+		}
+		if entries, _ := filepath.Glob(filepath.Join(tempDir, "*", ".shelley-patch-*")); len(entries) > 0 {
+			t.Errorf("temporary files left behind: %v", entries)
+		}
+	})
+}
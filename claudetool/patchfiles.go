@@ -0,0 +1,368 @@
+package claudetool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/diff"
+	"shelley.exe.dev/llm"
+)
+
+// fileChange is one file's part of a multi-file patch, worked out before
+// anything is written.
+type fileChange struct {
+	path    string // absolute
+	moveTo  string // absolute; "" unless renamed
+	delete  bool
+	orig    []byte // nil if the file doesn't exist yet
+	patched []byte // the new contents, unless deleted
+	mode    os.FileMode
+	notes   []string // clipboard notes and warnings for the model
+	tmp     string   // temporary file holding patched, before commit
+	target  string   // where commit writes patched: dest, or the file it links to
+}
+
+// dest returns where the change leaves the file's contents.
+func (c *fileChange) dest() string {
+	if c.moveTo != "" {
+		return c.moveTo
+	}
+	return c.path
+}
+
+// patchFilesRun applies a multi-file patch. All files are patched in memory
+// and checked first. The new contents are then written to temporary files
+// and moved into place, and if any step fails, the files already changed
+// are restored.
+func (p *PatchTool) patchFilesRun(ctx context.Context, input *PatchInput) llm.ToolOut {
+	if input.Path != "" || len(input.Patches) > 0 {
+		return llm.ErrorfToolOut("use either path and patches, or files, not both")
+	}
+	changes, err := p.prepareFileChanges(ctx, input.Files)
+	if err != nil {
+		return llm.ErrorfToolOut("%w\nno files were changed", err)
+	}
+	if err := commitFileChanges(changes); err != nil {
+		return llm.ErrorToolOut(err)
+	}
+
+	response := new(strings.Builder)
+	fmt.Fprintf(response, "<patches_applied>all</patches_applied>\n")
+	combined := new(strings.Builder)
+	var files []string
+	for _, c := range changes {
+		var hookReport string
+		if !c.delete {
+			hookReport, c.patched = runPostPatchHooks(ctx, c.dest(), c.patched, p.PostPatch)
+		}
+		p.auditFileChange(c)
+
+		switch {
+		case c.delete:
+			fmt.Fprintf(response, "deleted %s\n", c.path)
+		case c.moveTo != "":
+			fmt.Fprintf(response, "moved %s to %s\n", c.path, c.moveTo)
+		case c.orig == nil:
+			fmt.Fprintf(response, "created %s\n", c.path)
+		default:
+			fmt.Fprintf(response, "patched %s\n", c.path)
+		}
+		for _, note := range c.notes {
+			fmt.Fprintln(response, note)
+		}
+		response.WriteString(hookReport)
+
+		combined.WriteString(fileChangeDiff(c))
+		files = append(files, c.path)
+		if c.moveTo != "" {
+			files = append(files, c.moveTo)
+		}
+	}
+
+	return llm.ToolOut{
+		LLMContent: llm.TextContent(response.String()),
+		Display: PatchDisplayData{
+			Diff:  combined.String(),
+			Files: files,
+		},
+	}
+}
+
+// prepareFileChanges reads and patches every file in memory, and checks
+// that the changes are consistent with each other and with the disk.
+// It reports every problem it finds, not just the first.
+func (p *PatchTool) prepareFileChanges(ctx context.Context, files []PatchFile) ([]*fileChange, error) {
+	abs := func(path string) string {
+		if path == "" || filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(p.getWorkingDir(), path)
+	}
+	// Paths whose current contents go away, which renames may reuse.
+	vacated := make(map[string]bool)
+	for _, f := range files {
+		if f.Delete || f.MoveTo != "" {
+			vacated[abs(f.Path)] = true
+		}
+	}
+
+	var errs []error
+	var changes []*fileChange
+	sources := make(map[string]bool)
+	targets := make(map[string]bool)
+	for i, f := range files {
+		c := &fileChange{path: abs(f.Path), moveTo: abs(f.MoveTo), delete: f.Delete, mode: 0o600}
+		fail := func(err error) {
+			errs = append(errs, fmt.Errorf("files[%d] %s: %w", i, f.Path, err))
+		}
+		switch {
+		case f.Path == "":
+			fail(errors.New("path is required"))
+			continue
+		case f.Delete && (f.MoveTo != "" || len(f.Patches) > 0):
+			fail(errors.New("delete can't be combined with patches or moveTo"))
+			continue
+		case !f.Delete && f.MoveTo == "" && len(f.Patches) == 0:
+			fail(errors.New("no patches, moveTo or delete given"))
+			continue
+		case c.moveTo == c.path:
+			fail(errors.New("moveTo is the same as path"))
+			continue
+		}
+		if sources[c.path] {
+			fail(errors.New("the file is listed more than once"))
+			continue
+		}
+		sources[c.path] = true
+		if c.moveTo != "" {
+			if targets[c.moveTo] {
+				fail(fmt.Errorf("another file is also moved to %q", c.moveTo))
+				continue
+			}
+			targets[c.moveTo] = true
+			if _, err := os.Lstat(c.moveTo); err == nil && !vacated[c.moveTo] {
+				fail(fmt.Errorf("moveTo %q already exists", c.moveTo))
+				continue
+			}
+		}
+
+		orig, err := readPatchTarget(c.path, f.Patches)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		if orig == nil && (f.Delete || f.MoveTo != "" || len(f.Patches) == 0) {
+			fail(fmt.Errorf("file %q does not exist", c.path))
+			continue
+		}
+		if fi, err := os.Stat(c.path); err == nil {
+			c.mode = fi.Mode().Perm()
+		}
+		c.orig = orig
+		c.patched = orig
+		if len(f.Patches) > 0 {
+			c.patched, c.notes, err = p.applyPatches(ctx, c.path, orig, f.Patches)
+			if err != nil {
+				fail(err)
+				continue
+			}
+			if strings.HasSuffix(c.path, ".go") && IsAutogeneratedGoFile(orig) {
+				c.notes = append(c.notes, fmt.Sprintf("<warning>%q appears to be autogenerated. Patches were applied anyway.</warning>", c.path))
+			}
+		}
+		changes = append(changes, c)
+	}
+	// A file can be moved to a path that is vacated, but not to one that
+	// is also patched in place.
+	for _, c := range changes {
+		if c.moveTo != "" && sources[c.moveTo] && !vacated[c.moveTo] {
+			errs = append(errs, fmt.Errorf("%s: can't move to %q, which is also patched", c.path, c.moveTo))
+		}
+	}
+	return changes, errors.Join(errs...)
+}
+
+// commitFileChanges writes changes to disk, all or nothing.
+func commitFileChanges(changes []*fileChange) error {
+	removeTemps := func() {
+		for _, c := range changes {
+			if c.tmp != "" {
+				os.Remove(c.tmp)
+				c.tmp = ""
+			}
+		}
+	}
+	// Stage the new contents next to their destinations, so that moving
+	// them into place is a rename within a directory.
+	for _, c := range changes {
+		if c.delete {
+			continue
+		}
+		// A file patched in place through a symlink is written where the
+		// link points, so that the link is kept and rolling back writes
+		// the same file.
+		c.target = c.dest()
+		if c.moveTo == "" && c.orig != nil {
+			target, err := filepath.EvalSymlinks(c.target)
+			if err != nil {
+				removeTemps()
+				return fmt.Errorf("failed to write %q: %w\nno files were changed", c.target, err)
+			}
+			c.target = target
+		}
+		dir := filepath.Dir(c.target)
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			removeTemps()
+			return fmt.Errorf("failed to create directory %q: %w\nno files were changed", dir, err)
+		}
+		tmp, err := writeTempFile(dir, c.patched, c.mode)
+		if err != nil {
+			removeTemps()
+			return fmt.Errorf("failed to write %q: %w\nno files were changed", c.dest(), err)
+		}
+		c.tmp = tmp
+	}
+
+	// Vacate paths first, so that renames can reuse them.
+	var undo []func() error
+	rollback := func(cause error) error {
+		var errs []error
+		for i := len(undo) - 1; i >= 0; i-- {
+			if err := undo[i](); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		removeTemps()
+		if err := errors.Join(errs...); err != nil {
+			return fmt.Errorf("%w\nrolling back also failed, so some files may have been changed: %w", cause, err)
+		}
+		return fmt.Errorf("%w\nno files were changed", cause)
+	}
+	for _, c := range changes {
+		if !c.delete && c.moveTo == "" {
+			continue
+		}
+		if err := os.Remove(c.path); err != nil {
+			return rollback(fmt.Errorf("failed to remove %q: %w", c.path, err))
+		}
+		undo = append(undo, func() error { return writeFileMode(c.path, c.orig, c.mode) })
+	}
+	for _, c := range changes {
+		if c.delete {
+			continue
+		}
+		dest := c.target
+		if err := os.Rename(c.tmp, dest); err != nil {
+			return rollback(fmt.Errorf("failed to write %q: %w", dest, err))
+		}
+		c.tmp = ""
+		if c.moveTo == "" && c.orig != nil {
+			undo = append(undo, func() error { return writeFileMode(dest, c.orig, c.mode) })
+		} else {
+			undo = append(undo, func() error { return os.Remove(dest) })
+		}
+	}
+	return nil
+}
+
+// writeTempFile writes data to a new temporary file in dir with mode.
+func writeTempFile(dir string, data []byte, mode os.FileMode) (string, error) {
+	f, err := os.CreateTemp(dir, ".shelley-patch-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// replaceFile replaces the contents of the existing file at path with data,
+// keeping its mode. The data is written to a temporary file that is moved
+// into place, so if anything fails the file keeps its old contents. If path
+// is a symlink, the file it points to is replaced and the link is kept.
+func replaceFile(path string, data []byte) error {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	tmp, err := writeTempFile(filepath.Dir(path), data, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeFileMode writes data to path and sets its mode.
+func writeFileMode(path string, data []byte, mode os.FileMode) error {
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+// auditFileChange records c in the audit log, as one record per path.
+func (p *PatchTool) auditFileChange(c *fileChange) {
+	if p.Audit == nil {
+		return
+	}
+	rec := AuditRecord{Kind: AuditPatch, Cwd: p.getWorkingDir(), Path: c.path}
+	if c.orig != nil {
+		rec.BeforeHash = contentHash(c.orig)
+	}
+	if c.delete || c.moveTo != "" {
+		// The file is gone from its old path.
+		p.Audit(rec)
+		if c.delete {
+			return
+		}
+		rec = AuditRecord{Kind: AuditPatch, Cwd: p.getWorkingDir(), Path: c.moveTo}
+	}
+	rec.AfterHash = contentHash(c.patched)
+	p.Audit(rec)
+}
+
+// fileChangeDiff returns the diff of c, with a git-style header that
+// shows creations, deletions and renames.
+func fileChangeDiff(c *fileChange) string {
+	sb := new(strings.Builder)
+	dest := c.dest()
+	fmt.Fprintf(sb, "diff --git a%s b%s\n", c.path, dest)
+	gitMode := 0o100000 | uint32(c.mode)
+	switch {
+	case c.delete:
+		fmt.Fprintf(sb, "deleted file mode %06o\n", gitMode)
+	case c.orig == nil:
+		fmt.Fprintf(sb, "new file mode %06o\n", gitMode)
+	case c.moveTo != "":
+		fmt.Fprintf(sb, "rename from %s\nrename to %s\n", c.path, c.moveTo)
+	}
+	var after string
+	if !c.delete {
+		after = string(c.patched)
+	}
+	if err := diff.Text(c.path, dest, string(c.orig), after, sb); err != nil {
+		fmt.Fprintf(sb, "(diff generation failed: %v)\n", err)
+	}
+	return sb.String()
+}
@@ -0,0 +1,692 @@
+package patchkit
+
+import (
+	"go/token"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Token kinds used by the non-Go tokenizers, in addition to go/token's
+// IDENT, INT, STRING, CHAR and COMMENT.
+const (
+	tokPunct   token.Token = -1 - iota // operator or delimiter
+	tokNewline                         // line break that ends a statement
+	tokSpace                           // whitespace that is significant, e.g. in JSX text
+	tokIndent                          // leading whitespace of a line; lit holds it
+)
+
+// A tokenizer splits code into tokens. For every token except tokNewline
+// and tokSpace, lit is exactly code[pos.Offset:pos.Offset+len(lit)].
+// It reports false if code can't be tokenized.
+type tokenizer func(code string) ([]tok, bool)
+
+// tokenizers maps file extensions to tokenizers for UniqueTokens.
+var tokenizers = map[string]tokenizer{
+	".js":    clikeTokenizer(jsLang),
+	".mjs":   clikeTokenizer(jsLang),
+	".cjs":   clikeTokenizer(jsLang),
+	".ts":    clikeTokenizer(jsLang),
+	".mts":   clikeTokenizer(jsLang),
+	".cts":   clikeTokenizer(jsLang),
+	".jsx":   clikeTokenizer(jsxLang),
+	".tsx":   clikeTokenizer(jsxLang),
+	".rs":    clikeTokenizer(rustLang),
+	".json":  clikeTokenizer(jsonLang),
+	".jsonc": clikeTokenizer(jsonLang),
+	".py":    tokenizePython,
+	".pyi":   tokenizePython,
+	".yaml":  tokenizeYAML,
+	".yml":   tokenizeYAML,
+}
+
+// UniqueTokens is UniqueGoTokens for other languages: TypeScript and
+// JavaScript, Python, Rust, JSON and YAML, chosen by the extension of path.
+// Each tokenizer keeps whitespace wherever it is significant in its
+// language: line breaks that end JavaScript statements, indentation in
+// Python and YAML, and everything inside strings, comments and JSX text.
+// The match must be unique both as tokens and as text.
+func UniqueTokens(path, haystack, needle, replace string) (*Spec, bool) {
+	tokenize, ok := tokenizers[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, false
+	}
+	nt, ok := tokenize(needle)
+	if !ok || len(nt) == 0 {
+		return nil, false
+	}
+	ht, ok := tokenize(haystack)
+	if !ok {
+		return nil, false
+	}
+	match := tokensUniqueMatch(ht, nt)
+	if match == -1 {
+		return nil, false
+	}
+	first, last := ht[match], ht[match+len(nt)-1]
+	// Whitespace around the needle's tokens isn't part of the match, so
+	// drop it from the replacement too when the two share it.
+	nFirst, nLast := nt[0], nt[len(nt)-1]
+	if lead := needle[:nFirst.pos.Offset]; lead != "" {
+		replace = strings.TrimPrefix(replace, lead)
+	}
+	if trail := needle[nLast.pos.Offset+len(nLast.lit):]; trail != "" {
+		replace = strings.TrimSuffix(replace, trail)
+	}
+	needle = haystack[first.pos.Offset : last.pos.Offset+len(last.lit)]
+	spec, count := Unique(haystack, needle, replace)
+	if count != 1 {
+		return nil, false
+	}
+	return spec, true
+}
+
+// HasTokenizer reports whether UniqueTokens knows the language of path.
+// For such files, UniqueTokens should be used instead of UniqueInValidGo
+// and UniqueGoTokens, whose rules about whitespace are Go's.
+func HasTokenizer(path string) bool {
+	_, ok := tokenizers[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// A clikeLang describes a language with C-like lexical structure.
+type clikeLang struct {
+	ops       []string // multi-character operators, longest first
+	templates bool     // `...${expr}...` template literals
+	regexps   bool     // /.../ regular expression literals
+	rust      bool     // raw strings, byte strings, lifetimes and nested block comments
+	// newlines reports whether line breaks end statements, outside of
+	// parentheses and brackets (JavaScript's automatic semicolons).
+	newlines bool
+	// gaps makes all whitespace between tokens significant, though not its
+	// amount (JSX text).
+	gaps bool
+}
+
+var (
+	jsOps = []string{
+		">>>=", "...", "===", "!==", "**=", "<<=", ">>=", ">>>", "&&=", "||=", "??=",
+		"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--", "+=", "-=",
+		"*=", "/=", "%=", "&=", "|=", "^=", "**", "<<", ">>",
+	}
+	jsLang   = clikeLang{ops: jsOps, templates: true, regexps: true, newlines: true}
+	jsxLang  = clikeLang{ops: jsOps, templates: true, regexps: true, gaps: true}
+	rustLang = clikeLang{
+		ops: []string{
+			"..=", "...", "<<=", ">>=", "::", "->", "=>", "==", "!=", "<=", ">=", "&&", "||",
+			"+=", "-=", "*=", "/=", "%=", "^=", "&=", "|=", "<<", ">>", "..",
+		},
+		rust: true,
+	}
+	jsonLang = clikeLang{}
+)
+
+// jsRegexpKeywords are the keywords after which a slash starts a regexp.
+var jsRegexpKeywords = []string{
+	"return", "typeof", "instanceof", "in", "of", "new", "delete", "void",
+	"throw", "case", "do", "else", "yield", "await",
+}
+
+func clikeTokenizer(lang clikeLang) tokenizer {
+	return func(code string) ([]tok, bool) {
+		return tokenizeClike(code, lang)
+	}
+}
+
+func tokenizeClike(code string, lang clikeLang) ([]tok, bool) {
+	var tokens []tok
+	var brackets []byte
+	sawSpace, sawNewline := false, false
+	emit := func(kind token.Token, start, end int) {
+		if len(tokens) > 0 {
+			switch {
+			case lang.gaps && sawNewline:
+				tokens = append(tokens, tok{tok: tokNewline, lit: "\n"})
+			case lang.gaps && sawSpace:
+				tokens = append(tokens, tok{tok: tokSpace, lit: " "})
+			case lang.newlines && sawNewline && (len(brackets) == 0 || brackets[len(brackets)-1] == '{'):
+				tokens = append(tokens, tok{tok: tokNewline, lit: "\n"})
+			}
+		}
+		sawSpace, sawNewline = false, false
+		tokens = append(tokens, tok{pos: token.Position{Offset: start}, tok: kind, lit: code[start:end]})
+	}
+	// regexpAllowed reports whether a slash here would start a regexp.
+	regexpAllowed := func() bool {
+		if len(tokens) == 0 {
+			return true
+		}
+		prev := tokens[len(tokens)-1]
+		switch prev.tok {
+		case tokPunct:
+			return prev.lit != ")" && prev.lit != "]"
+		case token.IDENT:
+			return slices.Contains(jsRegexpKeywords, prev.lit)
+		}
+		return false
+	}
+
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case c == '\n':
+			sawNewline = true
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			sawSpace = true
+			i++
+		case strings.HasPrefix(code[i:], "//"):
+			end := i + strings.IndexByte(code[i:]+"\n", '\n')
+			emit(token.COMMENT, i, i+len(strings.TrimRight(code[i:end], " \t\r")))
+			i = end
+		case strings.HasPrefix(code[i:], "/*"):
+			end, ok := blockCommentEnd(code, i, lang.rust)
+			if !ok {
+				return nil, false
+			}
+			emit(token.COMMENT, i, end)
+			i = end
+		case c == '"' || (c == '\'' && !lang.rust):
+			end, ok := quotedEnd(code, i, c, !lang.rust)
+			if !ok {
+				return nil, false
+			}
+			emit(token.STRING, i, end)
+			i = end
+		case c == '`' && lang.templates:
+			end, ok := templateEnd(code, i)
+			if !ok {
+				return nil, false
+			}
+			emit(token.STRING, i, end)
+			i = end
+		case c == '/' && lang.regexps && regexpAllowed() && regexpEnd(code, i) > 0:
+			end := regexpEnd(code, i)
+			emit(token.STRING, i, end)
+			i = end
+		case lang.rust && c == '\'':
+			end, kind, ok := rustQuoteEnd(code, i)
+			if !ok {
+				return nil, false
+			}
+			emit(kind, i, end)
+			i = end
+		case lang.rust && (c == 'r' || c == 'b') && rustStringEnd(code, i) > 0:
+			end := rustStringEnd(code, i)
+			emit(token.STRING, i, end)
+			i = end
+		case isDigit(c) || (c == '.' && i+1 < len(code) && isDigit(code[i+1])):
+			end := numberEnd(code, i)
+			emit(token.INT, i, end)
+			i = end
+		case isIdentByte(c):
+			end := i
+			for end < len(code) && (isIdentByte(code[end]) || isDigit(code[end])) {
+				end++
+			}
+			emit(token.IDENT, i, end)
+			i = end
+		default:
+			end := i + 1
+			for _, op := range lang.ops {
+				if strings.HasPrefix(code[i:], op) {
+					end = i + len(op)
+					break
+				}
+			}
+			switch c {
+			case '(', '[', '{':
+				brackets = append(brackets, c)
+			case ')', ']', '}':
+				// A needle may close brackets it didn't open.
+				if len(brackets) > 0 {
+					brackets = brackets[:len(brackets)-1]
+				}
+			}
+			emit(tokPunct, i, end)
+			i = end
+		}
+	}
+	return tokens, true
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// isIdentByte reports whether c can start an identifier. Non-ASCII bytes
+// are treated as identifier characters.
+func isIdentByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_' || c == '$' || c >= 0x80
+}
+
+// numberEnd returns the end of the number starting at i, including
+// suffixes, separators and exponent signs.
+func numberEnd(code string, i int) int {
+	hex := strings.HasPrefix(code[i:], "0x") || strings.HasPrefix(code[i:], "0X")
+	end := i
+	for end < len(code) {
+		c := code[end]
+		switch {
+		case isDigit(c) || isIdentByte(c) || c == '.':
+			end++
+		case (c == '+' || c == '-') && !hex && (code[end-1] == 'e' || code[end-1] == 'E'):
+			end++
+		default:
+			return end
+		}
+	}
+	return end
+}
+
+// quotedEnd returns the end of the string starting with quote at i.
+// If singleLine, the string may not contain an unescaped line break.
+func quotedEnd(code string, i int, quote byte, singleLine bool) (int, bool) {
+	for j := i + 1; j < len(code); j++ {
+		switch code[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1, true
+		case '\n':
+			if singleLine {
+				return 0, false
+			}
+		}
+	}
+	return 0, false
+}
+
+// blockCommentEnd returns the end of the block comment starting at i.
+func blockCommentEnd(code string, i int, nested bool) (int, bool) {
+	depth := 0
+	for j := i; j+1 < len(code); j++ {
+		switch {
+		case code[j] == '/' && code[j+1] == '*' && (nested || depth == 0):
+			depth++
+			j++
+		case code[j] == '*' && code[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// templateEnd returns the end of the template literal starting at i,
+// skipping over the strings and templates in its ${} expressions.
+func templateEnd(code string, i int) (int, bool) {
+	depth := 0 // of braces within a ${} expression
+	for j := i + 1; j < len(code); j++ {
+		c := code[j]
+		if depth == 0 {
+			switch {
+			case c == '\\':
+				j++
+			case c == '`':
+				return j + 1, true
+			case c == '$' && j+1 < len(code) && code[j+1] == '{':
+				depth = 1
+				j++
+			}
+			continue
+		}
+		switch c {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '"', '\'':
+			end, ok := quotedEnd(code, j, c, true)
+			if !ok {
+				return 0, false
+			}
+			j = end - 1
+		case '`':
+			end, ok := templateEnd(code, j)
+			if !ok {
+				return 0, false
+			}
+			j = end - 1
+		}
+	}
+	return 0, false
+}
+
+// regexpEnd returns the end of the regexp literal starting at i, or 0 if
+// there isn't one.
+func regexpEnd(code string, i int) int {
+	if i+1 < len(code) && (code[i+1] == '/' || code[i+1] == '*') {
+		return 0
+	}
+	inClass := false
+	for j := i + 1; j < len(code); j++ {
+		switch code[j] {
+		case '\\':
+			j++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			return 0
+		case '/':
+			if inClass {
+				continue
+			}
+			j++
+			for j < len(code) && isIdentByte(code[j]) {
+				j++
+			}
+			return j
+		}
+	}
+	return 0
+}
+
+// rustQuoteEnd returns the end of the character literal or lifetime
+// starting at i.
+func rustQuoteEnd(code string, i int) (int, token.Token, bool) {
+	if i+1 < len(code) && code[i+1] == '\\' {
+		end, ok := quotedEnd(code, i, '\'', true)
+		return end, token.CHAR, ok
+	}
+	// A character literal holds one character, which may be several bytes.
+	for j := i + 2; j < len(code) && j <= i+5; j++ {
+		if code[j] == '\'' {
+			return j + 1, token.CHAR, true
+		}
+		if code[j] < 0x80 {
+			break
+		}
+	}
+	end := i + 1
+	for end < len(code) && (isIdentByte(code[end]) || isDigit(code[end])) {
+		end++
+	}
+	if end == i+1 {
+		return 0, 0, false
+	}
+	return end, token.IDENT, true
+}
+
+// rustStringEnd returns the end of the raw or byte string starting at i,
+// or 0 if there isn't one.
+func rustStringEnd(code string, i int) int {
+	j := i
+	if code[j] == 'b' {
+		j++
+		if j < len(code) && (code[j] == '"' || code[j] == '\'') {
+			end, ok := quotedEnd(code, j, code[j], code[j] == '\'')
+			if !ok {
+				return 0
+			}
+			return end
+		}
+	}
+	if j >= len(code) || code[j] != 'r' {
+		return 0
+	}
+	j++
+	hashes := 0
+	for j < len(code) && code[j] == '#' {
+		hashes++
+		j++
+	}
+	if j >= len(code) || code[j] != '"' {
+		return 0
+	}
+	closing := "\"" + strings.Repeat("#", hashes)
+	end := strings.Index(code[j+1:], closing)
+	if end < 0 {
+		return 0
+	}
+	return j + 1 + end + len(closing)
+}
+
+var pythonOps = []string{
+	"**=", "//=", ">>=", "<<=", "...", "->", ":=", "**", "//", "==", "!=", "<=", ">=",
+	"<<", ">>", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "@=",
+}
+
+// tokenizePython tokenizes Python. Line breaks end statements except
+// inside brackets or after a backslash. Each line but the first starts
+// with a tokIndent holding its indentation, so indentation must match
+// exactly; UniqueDedent handles consistently shifted indentation.
+func tokenizePython(code string) ([]tok, bool) {
+	var tokens []tok
+	depth := 0
+	lineStart := true // at the start of a logical line
+	for i := 0; i < len(code); {
+		if lineStart && depth == 0 {
+			end := i
+			for end < len(code) && (code[end] == ' ' || code[end] == '\t') {
+				end++
+			}
+			// Blank and comment-only lines don't affect indentation.
+			if end < len(code) && code[end] != '\n' && code[end] != '\r' && code[end] != '#' {
+				if len(tokens) > 0 {
+					tokens = append(tokens, tok{pos: token.Position{Offset: i}, tok: tokIndent, lit: code[i:end]})
+				}
+				lineStart = false
+			}
+			i = end
+			if i >= len(code) {
+				break
+			}
+		}
+		c := code[i]
+		switch {
+		case c == '\n':
+			if depth == 0 && !lineStart {
+				tokens = append(tokens, tok{pos: token.Position{Offset: i}, tok: tokNewline, lit: "\n"})
+				lineStart = true
+			}
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+		case c == '\\' && strings.HasPrefix(strings.TrimPrefix(code[i+1:], "\r"), "\n"):
+			i += 1 + strings.IndexByte(code[i:], '\n')
+		case c == '#':
+			end := i + strings.IndexByte(code[i:]+"\n", '\n')
+			tokens = append(tokens, tok{pos: token.Position{Offset: i}, tok: token.COMMENT, lit: strings.TrimRight(code[i:end], " \t\r")})
+			i = end
+		case c == '"' || c == '\'' || (isIdentByte(c) && pythonStringEnd(code, i) > 0):
+			end := pythonStringEnd(code, i)
+			if end == 0 {
+				return nil, false
+			}
+			tokens = append(tokens, tok{pos: token.Position{Offset: i}, tok: token.STRING, lit: code[i:end]})
+			i = end
+		case isDigit(c) || (c == '.' && i+1 < len(code) && isDigit(code[i+1])):
+			end := numberEnd(code, i)
+			tokens = append(tokens, tok{pos: token.Position{Offset: i}, tok: token.INT, lit: code[i:end]})
+			i = end
+		case isIdentByte(c):
+			end := i
+			for end < len(code) && (isIdentByte(code[end]) || isDigit(code[end])) {
+				end++
+			}
+			tokens = append(tokens, tok{pos: token.Position{Offset: i}, tok: token.IDENT, lit: code[i:end]})
+			i = end
+		default:
+			end := i + 1
+			for _, op := range pythonOps {
+				if strings.HasPrefix(code[i:], op) {
+					end = i + len(op)
+					break
+				}
+			}
+			switch c {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				depth = max(0, depth-1)
+			}
+			tokens = append(tokens, tok{pos: token.Position{Offset: i}, tok: tokPunct, lit: code[i:end]})
+			i = end
+		}
+	}
+	return tokens, true
+}
+
+// pythonStringEnd returns the end of the string literal, with optional
+// prefix such as r or f, starting at i, or 0 if there isn't a valid one.
+func pythonStringEnd(code string, i int) int {
+	j := i
+	for j < len(code) && j-i < 2 && strings.IndexByte("rRbBuUfF", code[j]) >= 0 {
+		j++
+	}
+	if j >= len(code) || (code[j] != '"' && code[j] != '\'') {
+		return 0
+	}
+	if triple := strings.Repeat(code[j:j+1], 3); strings.HasPrefix(code[j:], triple) {
+		for k := j + 3; k < len(code); k++ {
+			if code[k] == '\\' {
+				k++
+			} else if strings.HasPrefix(code[k:], triple) {
+				return k + 3
+			}
+		}
+		return 0
+	}
+	end, ok := quotedEnd(code, j, code[j], true)
+	if !ok {
+		return 0
+	}
+	return end
+}
+
+// tokenizeYAML tokenizes YAML line by line. Each line but the first starts
+// with a tokIndent holding its indentation. The only whitespace that may
+// differ is blank lines, trailing whitespace, and spacing after "-",
+// after "key:" and before comments. Block scalars (| and >) are kept
+// verbatim.
+func tokenizeYAML(code string) ([]tok, bool) {
+	var tokens []tok
+	blockIndent := -1 // indentation of the line that started a block scalar
+	for off := 0; off < len(code); {
+		line := code[off:]
+		if n := strings.IndexByte(line, '\n'); n >= 0 {
+			line = line[:n]
+		}
+		start := off
+		off += len(line) + 1
+		content := strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimLeft(content, " \t")
+		indent := len(content) - len(trimmed)
+
+		if blockIndent >= 0 && (trimmed == "" || indent > blockIndent) {
+			// Everything in a block scalar is significant, even blank lines.
+			if len(tokens) > 0 {
+				tokens = append(tokens, tok{pos: token.Position{Offset: start}, tok: tokIndent})
+			}
+			tokens = append(tokens, tok{pos: token.Position{Offset: start}, tok: token.STRING, lit: strings.TrimSuffix(line, "\r")})
+			continue
+		}
+		blockIndent = -1
+		if trimmed == "" {
+			continue
+		}
+		pos := start + indent
+		if strings.HasPrefix(trimmed, "#") {
+			tokens = append(tokens, tok{pos: token.Position{Offset: pos}, tok: token.COMMENT, lit: trimmed})
+			continue
+		}
+		if len(tokens) > 0 {
+			tokens = append(tokens, tok{pos: token.Position{Offset: start}, tok: tokIndent, lit: line[:indent]})
+		}
+		add := func(kind token.Token, lit string) {
+			tokens = append(tokens, tok{pos: token.Position{Offset: pos}, tok: kind, lit: lit})
+			pos += len(lit)
+		}
+		// Spaces are skipped up to the end of content, which leaves out
+		// any trailing whitespace after "key:" or "-".
+		end := start + len(content)
+		skipSpace := func() {
+			for pos < end && (code[pos] == ' ' || code[pos] == '\t') {
+				pos++
+			}
+		}
+		for strings.HasPrefix(code[pos:end], "- ") || code[pos:end] == "-" {
+			add(tokPunct, "-")
+			skipSpace()
+		}
+		rest := code[pos:end]
+		if key := yamlKeyEnd(rest); key > 0 {
+			add(token.IDENT, rest[:key])
+			add(tokPunct, ":")
+			skipSpace()
+			rest = code[pos:end]
+		}
+		if rest == "" {
+			continue
+		}
+		value, comment := rest, ""
+		if n := yamlCommentStart(rest); n >= 0 {
+			value, comment = strings.TrimRight(rest[:n], " \t"), rest[n:]
+		}
+		if value != "" {
+			add(token.STRING, value)
+			if strings.Trim(value, "-+0123456789") == "|" || strings.Trim(value, "-+0123456789") == ">" {
+				blockIndent = indent
+			}
+		}
+		if comment != "" {
+			pos = end - len(comment)
+			add(token.COMMENT, comment)
+		}
+	}
+	return tokens, true
+}
+
+// yamlKeyEnd returns the length of the mapping key that s starts with, or
+// 0 if s doesn't start with one. The key is followed by a colon and then
+// whitespace or the end of the line.
+func yamlKeyEnd(s string) int {
+	i := 0
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		end, ok := quotedEnd(s, 0, s[0], true)
+		if !ok {
+			return 0
+		}
+		i = end
+		if i < len(s) && s[i] != ':' {
+			return 0
+		}
+	}
+	for ; i < len(s); i++ {
+		if s[i] == ':' && (i+1 == len(s) || s[i+1] == ' ' || s[i+1] == '\t') {
+			return i
+		}
+		if s[i] == '#' && i > 0 && (s[i-1] == ' ' || s[i-1] == '\t') {
+			return 0
+		}
+	}
+	return 0
+}
+
+// yamlCommentStart returns the index of the comment in the value s, or -1.
+// A comment starts with a # after whitespace, outside of quotes.
+func yamlCommentStart(s string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || s[i-1] == ' ' || s[i-1] == '[' || s[i-1] == '{' || s[i-1] == ','):
+			quote = c
+		case c == '#' && i > 0 && (s[i-1] == ' ' || s[i-1] == '\t'):
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,198 @@
+package patchkit
+
+import (
+	"testing"
+
+	"sketch.dev/claudetool/editbuf"
+)
+
+func TestUniqueTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		haystack string
+		needle   string
+		replace  string
+		want     string // result, or "" if no match is expected
+	}{
+		{
+			name:     "ts_reindented",
+			path:     "a.ts",
+			haystack: "function f() {\n\tif (x) {\n\t\treturn g(a, b);\n\t}\n}\n",
+			needle:   "if (x) {\n    return g(a,b);\n}",
+			replace:  "if (y) {\n    return g(a, b);\n}",
+			want:     "function f() {\n\tif (y) {\n    return g(a, b);\n}\n}\n",
+		},
+		{
+			name:     "ts_rewrapped_arguments",
+			path:     "a.tsx",
+			haystack: "const x = call(\n  first,\n  second,\n);\n",
+			needle:   "const x = call(\n    first,\n    second,\n);\n",
+			replace:  "const x = call(first);\n",
+			want:     "const x = call(first);\n",
+		},
+		{
+			name:     "js_newline_ends_statement",
+			path:     "a.js",
+			haystack: "function f() {\n  return\n  x;\n}\n",
+			needle:   "return x;",
+			replace:  "return y;",
+		},
+		{
+			name:     "js_operators_not_split",
+			path:     "a.js",
+			haystack: "a = b - -c;\n",
+			needle:   "a = b --c;",
+			replace:  "a = b;",
+		},
+		{
+			name:     "js_string_whitespace_matters",
+			path:     "a.mjs",
+			haystack: "log('a  b');\n",
+			needle:   "log( 'a b' );",
+			replace:  "log('c');",
+		},
+		{
+			name:     "js_regexp_whitespace_matters",
+			path:     "a.js",
+			haystack: "const re = /a b/;\n",
+			needle:   "const re = /a  b/;",
+			replace:  "const re = /c/;",
+		},
+		{
+			name:     "js_template_literal",
+			path:     "a.ts",
+			haystack: "const s = `${ a + `x` } y`;\nuse( s );\n",
+			needle:   "use(s);",
+			replace:  "use(t);",
+			want:     "const s = `${ a + `x` } y`;\nuse(t);\n",
+		},
+		{
+			name:     "jsx_text_spacing_matters",
+			path:     "a.jsx",
+			haystack: "<p>a</p>\n",
+			needle:   "<p> a </p>",
+			replace:  "<p>b</p>",
+		},
+		{
+			name:     "jsx_attribute_indentation",
+			path:     "a.jsx",
+			haystack: "<Button\n\t\tonClick={go}\n\t\tlabel=\"Go\"\n/>\n",
+			needle:   "<Button\n  onClick={go}\n  label=\"Go\"\n/>",
+			replace:  "<Button onClick={stop} />",
+			want:     "<Button onClick={stop} />\n",
+		},
+		{
+			name:     "python_line_spacing",
+			path:     "a.py",
+			haystack: "def f(a, b):\n    x = g(a,\n          b)  # note\n\n    return x\n",
+			needle:   "    x = g( a, b )  # note\n    return x\n",
+			replace:  "    return g(a, b)\n",
+			want:     "def f(a, b):\n    return g(a, b)\n",
+		},
+		{
+			name:     "python_indentation_matters",
+			path:     "a.py",
+			haystack: "if a:\n    b()\n    c()\n",
+			needle:   "if a:\n    b()\nc()",
+			replace:  "if a:\n    d()\nc()",
+		},
+		{
+			name:     "python_string_prefixes",
+			path:     "a.pyi",
+			haystack: "x = f'{a}'  +  rb\"\\d\"\n",
+			needle:   "x = f'{a}' + rb\"\\d\"",
+			replace:  "x = 1",
+			want:     "x = 1\n",
+		},
+		{
+			name:     "rust_lifetimes_and_raw_strings",
+			path:     "lib.rs",
+			haystack: "fn f<'a>(s: &'a str) -> &'a str {\n    let c = '\\'';\n    let r = r#\"x \"y\"\"#;\n    s\n}\n",
+			needle:   "fn f<'a>(s:&'a str)->&'a str {",
+			replace:  "fn f(s: &str) -> &str {",
+			want:     "fn f(s: &str) -> &str {\n    let c = '\\'';\n    let r = r#\"x \"y\"\"#;\n    s\n}\n",
+		},
+		{
+			name:     "json_whitespace",
+			path:     "package.json",
+			haystack: "{\n  \"name\": \"x\",\n  \"version\": \"1.0.0\"\n}\n",
+			needle:   "\"name\":\"x\",\n\t\"version\":\"1.0.0\"",
+			replace:  "\"name\": \"y\",\n  \"version\": \"1.0.0\"",
+			want:     "{\n  \"name\": \"y\",\n  \"version\": \"1.0.0\"\n}\n",
+		},
+		{
+			name:     "yaml_spacing",
+			path:     "ci.yml",
+			haystack: "jobs:\n  test:\n    runs-on:   ubuntu-latest   # fast\n\n    steps:\n      -   run: make\n",
+			needle:   "    runs-on: ubuntu-latest # fast\n    steps:\n      - run: make\n",
+			replace:  "    runs-on: ubuntu-latest\n    steps:\n      - run: make test\n",
+			want:     "jobs:\n  test:\n    runs-on: ubuntu-latest\n    steps:\n      - run: make test\n",
+		},
+		{
+			name:     "yaml_indentation_matters",
+			path:     "a.yaml",
+			haystack: "a:\n  b: 1\n  c: 2\n",
+			needle:   "a:\n  b: 1\nc: 2",
+			replace:  "a:\n  b: 3\nc: 2",
+		},
+		{
+			name:     "yaml_block_scalar_verbatim",
+			path:     "a.yaml",
+			haystack: "script: |\n  echo  a\nnext: 1\n",
+			needle:   "script: |\n  echo a\nnext: 1",
+			replace:  "next: 2",
+		},
+		{
+			name:     "yaml_trailing_space_after_key",
+			path:     "a.yaml",
+			haystack: "a: 1\nkey: \n  b: 2\n",
+			needle:   "key:\n  b:  2",
+			replace:  "key:\n  b: 3",
+			want:     "a: 1\nkey:\n  b: 3\n",
+		},
+		{
+			name:     "yaml_trailing_space_after_dash",
+			path:     "a.yaml",
+			haystack: "items:\n- \n  x: 1\n",
+			needle:   "-\n  x:  1",
+			replace:  "-\n  x: 2",
+			want:     "items:\n-\n  x: 2\n",
+		},
+		{
+			name:     "not_unique",
+			path:     "a.ts",
+			haystack: "f(a, b);\nf(a,b);\n",
+			needle:   "f( a, b );",
+			replace:  "g();",
+		},
+		{
+			name:     "unknown_language",
+			path:     "a.txt",
+			haystack: "a  b\n",
+			needle:   "a b",
+			replace:  "c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, ok := UniqueTokens(tt.path, tt.haystack, tt.needle, tt.replace)
+			if ok != (tt.want != "") {
+				t.Fatalf("UniqueTokens() ok = %v, want %v", ok, tt.want != "")
+			}
+			if !ok {
+				return
+			}
+			buf := editbuf.NewBuffer([]byte(tt.haystack))
+			spec.ApplyToEditBuf(buf)
+			result, err := buf.Bytes()
+			if err != nil {
+				t.Fatalf("failed to apply spec: %v", err)
+			}
+			if string(result) != tt.want {
+				t.Errorf("result = %q, want %q", result, tt.want)
+			}
+		})
+	}
+}
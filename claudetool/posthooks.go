@@ -0,0 +1,176 @@
+package claudetool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// PostPatchHook is a command run after the patch tool writes a file, such
+// as a formatter or a linter.
+type PostPatchHook struct {
+	// Extensions are the file extensions the hook runs for, like ".go".
+	Extensions []string `json:"extensions"`
+	// Command is run with bash -c in the file's directory. {file}, {dir}
+	// and {root} are replaced with the shell-quoted path of the file, its
+	// directory, and the root of its git repository (or its directory).
+	Command string `json:"command"`
+	// Formatter marks a hook that prints the formatted file to stdout,
+	// like "gofmt {file}". Other hooks are checks: their output is
+	// reported when they exit with a non-zero status.
+	Formatter bool `json:"formatter,omitempty"`
+}
+
+// PostPatchConfig configures the hooks run after each patch. Server-wide
+// configuration comes from shelley.json; if it sets AllowRepoConfig, a
+// repository can replace it with a .shelley/post-patch.json file at its root.
+type PostPatchConfig struct {
+	Hooks []PostPatchHook `json:"hooks"`
+	// TimeoutSeconds is the time budget for all hooks run after one patch.
+	// Zero means defaultPostPatchTimeout.
+	TimeoutSeconds float64 `json:"timeout_seconds,omitempty"`
+	// ApplyFormatting writes formatter output to the file. Otherwise the
+	// model is only told that the file is not formatted.
+	ApplyFormatting bool `json:"apply_formatting,omitempty"`
+	// AllowRepoConfig lets a repository's .shelley/post-patch.json replace
+	// this config. Its hooks run arbitrary commands, so only set it for
+	// repositories whose contents are trusted.
+	AllowRepoConfig bool `json:"allow_repo_config,omitempty"`
+}
+
+const (
+	// repoPostPatchConfig is the per-repository config file, relative to the repo root.
+	repoPostPatchConfig     = ".shelley/post-patch.json"
+	defaultPostPatchTimeout = 15 * time.Second
+	// maxHookOutput caps the diagnostics reported from one hook.
+	maxHookOutput = 8 * 1024
+)
+
+// postPatchConfigFor returns the config that applies to path and the
+// repository root. That is cfg, unless cfg allows repository configs and
+// path's repository has one.
+func postPatchConfigFor(path string, cfg *PostPatchConfig) (*PostPatchConfig, string, error) {
+	dir := filepath.Dir(path)
+	root, err := FindRepoRoot(dir)
+	if err != nil {
+		return cfg, dir, nil
+	}
+	if cfg == nil || !cfg.AllowRepoConfig {
+		return cfg, root, nil
+	}
+	data, err := os.ReadFile(filepath.Join(root, repoPostPatchConfig))
+	if err != nil {
+		return cfg, root, nil
+	}
+	var repoCfg PostPatchConfig
+	if err := json.Unmarshal(data, &repoCfg); err != nil {
+		return nil, root, fmt.Errorf("invalid %s: %w", repoPostPatchConfig, err)
+	}
+	return &repoCfg, root, nil
+}
+
+// runPostPatchHooks runs the hooks for path, which holds patched, and
+// returns what to tell the model about them. If formatting is applied,
+// the file is rewritten and the formatted contents are returned.
+func runPostPatchHooks(ctx context.Context, path string, patched []byte, cfg *PostPatchConfig) (report string, final []byte) {
+	final = patched
+	cfg, root, err := postPatchConfigFor(path, cfg)
+	if err != nil {
+		return fmt.Sprintf("<post_patch_diagnostics>%v</post_patch_diagnostics>\n", err), final
+	}
+	if cfg == nil {
+		return "", final
+	}
+	ext := filepath.Ext(path)
+	var hooks []PostPatchHook
+	for _, h := range cfg.Hooks {
+		if len(h.Extensions) == 0 || slices.Contains(h.Extensions, ext) {
+			hooks = append(hooks, h)
+		}
+	}
+	if len(hooks) == 0 {
+		return "", final
+	}
+
+	timeout := defaultPostPatchTimeout
+	if cfg.TimeoutSeconds > 0 {
+		timeout = time.Duration(cfg.TimeoutSeconds * float64(time.Second))
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	replacer := strings.NewReplacer(
+		"{file}", shellQuote(path),
+		"{dir}", shellQuote(filepath.Dir(path)),
+		"{root}", shellQuote(root),
+	)
+	sb := new(strings.Builder)
+	for _, h := range hooks {
+		if ctx.Err() != nil {
+			fmt.Fprintf(sb, "<post_patch_skipped hook=%q>time budget of %s used up</post_patch_skipped>\n", h.Command, timeout)
+			continue
+		}
+		stdout, output, err := runHook(ctx, replacer.Replace(h.Command), filepath.Dir(path))
+		switch {
+		case ctx.Err() != nil:
+			fmt.Fprintf(sb, "<post_patch_skipped hook=%q>timed out after %s</post_patch_skipped>\n", h.Command, timeout)
+		case err != nil:
+			fmt.Fprintf(sb, "<post_patch_diagnostics hook=%q>\n%s\n</post_patch_diagnostics>\n", h.Command, truncateHookOutput(output))
+		case h.Formatter && len(stdout) > 0 && !bytes.Equal(stdout, final):
+			if !cfg.ApplyFormatting {
+				fmt.Fprintf(sb, "<post_patch_unformatted hook=%q>the file is not formatted</post_patch_unformatted>\n", h.Command)
+				continue
+			}
+			if err := replaceFile(path, stdout); err != nil {
+				fmt.Fprintf(sb, "<post_patch_diagnostics hook=%q>\nfailed to write formatted file: %v\n</post_patch_diagnostics>\n", h.Command, err)
+				continue
+			}
+			final = stdout
+			fmt.Fprintf(sb, "<post_patch_formatted hook=%q>formatting was applied to the file</post_patch_formatted>\n", h.Command)
+		}
+	}
+	return sb.String(), final
+}
+
+// runHook runs command in dir, returning its stdout and its combined output.
+func runHook(ctx context.Context, command, dir string) (stdout, output []byte, err error) {
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.Dir = dir
+	var out, combined bytes.Buffer
+	cmd.Stdout = io.MultiWriter(&out, &combined)
+	cmd.Stderr = &combined
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = time.Second
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		combined.WriteString(err.Error())
+	}
+	return out.Bytes(), combined.Bytes(), err
+}
+
+func truncateHookOutput(out []byte) string {
+	s := strings.TrimSpace(string(out))
+	if len(s) > maxHookOutput {
+		s = s[:maxHookOutput] + "\n[output truncated]"
+	}
+	return s
+}
+
+// shellQuote quotes s for use as a single bash word.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,103 @@
+package claudetool
+
+import (
+	"bytes"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// progressInterval is how often a progressWriter reports output.
+var progressInterval = 250 * time.Millisecond
+
+// progressMaxPending is how long a partial line may grow before it is
+// reported anyway.
+const progressMaxPending = 4096
+
+// progressWriter reports what is written to it in chunks, at most every
+// progressInterval. It reports whole lines, and a partial line once it has
+// stopped growing, such as a prompt or a progress bar.
+type progressWriter struct {
+	report func(chunk string)
+	stop   chan struct{}
+	done   chan struct{}
+
+	mu      sync.Mutex
+	pending []byte
+	partial int // length of pending at the last tick, if it had no newline
+}
+
+func newProgressWriter(report func(chunk string)) *progressWriter {
+	w := &progressWriter{
+		report: report,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go w.loop()
+	return w
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.pending = append(w.pending, p...)
+	if len(w.pending) >= progressMaxPending && bytes.IndexByte(w.pending, '\n') < 0 {
+		w.flush(len(w.pending))
+	}
+	return len(p), nil
+}
+
+func (w *progressWriter) loop() {
+	defer close(w.done)
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.tick()
+		case <-w.stop:
+			w.mu.Lock()
+			w.flush(len(w.pending))
+			w.mu.Unlock()
+			return
+		}
+	}
+}
+
+// tick reports the complete lines written since the last tick, or a
+// partial line that hasn't changed since then.
+func (w *progressWriter) tick() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if i := bytes.LastIndexByte(w.pending, '\n'); i >= 0 {
+		w.flush(i + 1)
+	} else if len(w.pending) > 0 && len(w.pending) == w.partial {
+		w.flush(len(w.pending))
+	}
+	w.partial = len(w.pending)
+}
+
+// flush reports the first n pending bytes, holding back a rune that is cut
+// off at the end. The caller holds w.mu.
+func (w *progressWriter) flush(n int) {
+	for i := 1; i < utf8.UTFMax && i <= n; i++ {
+		if utf8.RuneStart(w.pending[n-i]) {
+			if !utf8.FullRune(w.pending[n-i : n]) {
+				n -= i
+			}
+			break
+		}
+	}
+	if n == 0 {
+		return
+	}
+	w.report(string(w.pending[:n]))
+	w.pending = append(w.pending[:0], w.pending[n:]...)
+	w.partial = 0
+}
+
+// Close reports whatever is still pending and stops the writer.
+func (w *progressWriter) Close() {
+	close(w.stop)
+	<-w.done
+}
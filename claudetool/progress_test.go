@@ -0,0 +1,88 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// progressRecorder collects reported chunks.
+type progressRecorder struct {
+	mu     sync.Mutex
+	chunks []string
+}
+
+func (r *progressRecorder) report(chunk string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.chunks = append(r.chunks, chunk)
+}
+
+func (r *progressRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.chunks...)
+}
+
+func TestProgressWriter(t *testing.T) {
+	interval := progressInterval
+	t.Cleanup(func() { progressInterval = interval })
+	progressInterval = 20 * time.Millisecond
+
+	var r progressRecorder
+	w := newProgressWriter(r.report)
+	w.Write([]byte("one\ntw"))
+	time.Sleep(5 * progressInterval)
+	// The complete line goes out first, then the partial one once it has
+	// stopped growing.
+	if got := r.get(); len(got) != 2 || got[0] != "one\n" || got[1] != "tw" {
+		t.Errorf("chunks = %q", got)
+	}
+
+	// A rune split across writes is never split across chunks.
+	w.Write([]byte("o\xe2\x82"))
+	time.Sleep(5 * progressInterval)
+	w.Write([]byte("\xac"))
+	w.Close()
+	if got := strings.Join(r.get(), ""); got != "one\ntwo€" {
+		t.Errorf("output = %q", got)
+	}
+	for _, c := range r.get() {
+		if !utf8.ValidString(c) {
+			t.Errorf("chunk %q isn't valid UTF-8", c)
+		}
+	}
+}
+
+func TestBashProgress(t *testing.T) {
+	for _, persistent := range []bool{false, true} {
+		bash := &BashTool{
+			WorkingDir: NewMutableWorkingDir(t.TempDir()),
+			Persistent: persistent,
+			Timeouts:   &Timeouts{Fast: 30 * time.Second, Slow: 30 * time.Second},
+		}
+		t.Cleanup(bash.Close)
+
+		var r progressRecorder
+		ctx := WithProgress(context.Background(), r.report)
+		input, _ := json.Marshal(bashInput{Command: "echo first; sleep 1; echo second"})
+		out := bash.Run(ctx, input)
+		if out.Error != nil {
+			t.Fatalf("persistent=%v: %v", persistent, out.Error)
+		}
+		// Login scripts may print something first.
+		result := out.LLMContent[0].Text
+		if !strings.HasSuffix(result, "first\nsecond\n") {
+			t.Errorf("persistent=%v: result = %q", persistent, result)
+		}
+		// The first line was reported before the command finished.
+		got := r.get()
+		if len(got) < 2 || got[len(got)-2] != "first\n" || got[len(got)-1] != "second\n" || strings.Join(got, "") != result {
+			t.Errorf("persistent=%v: chunks = %q", persistent, got)
+		}
+	}
+}
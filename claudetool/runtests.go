@@ -0,0 +1,285 @@
+package claudetool
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"shelley.exe.dev/claudetool/testkit"
+	"shelley.exe.dev/llm"
+)
+
+// RunTestsTool runs a project's tests and reports the results in a common
+// form: counts, and each failure with its file and line.
+type RunTestsTool struct {
+	// WorkingDir is the shared mutable working directory.
+	WorkingDir *MutableWorkingDir
+	// Bash runs the test commands, with its environment, permission
+	// check and audit log.
+	Bash *BashTool
+
+	mu   sync.Mutex
+	last map[string]lastTestRun // by framework and project root
+}
+
+// lastTestRun is what rerun_failed reruns.
+type lastTestRun struct {
+	args     []string
+	failures []testkit.Failure
+}
+
+const (
+	runTestsName        = "run_tests"
+	runTestsDescription = `Runs the project's tests and reports the results: how many passed, failed and were skipped, and each failure with its file:line and message.
+
+Prefer this over running test commands with bash. It knows go test, pytest, jest, vitest and cargo test, and detects which one the project in the working directory uses.
+
+Set rerun_failed=true to run only the tests that failed in the previous run_tests call for the same project, with the same args.
+`
+	runTestsInputSchema = `
+{
+  "type": "object",
+  "properties": {
+    "framework": {
+      "type": "string",
+      "enum": ["go", "pytest", "jest", "vitest", "cargo"],
+      "description": "Test framework; detected from the project files if omitted"
+    },
+    "args": {
+      "type": "array",
+      "items": {"type": "string"},
+      "description": "Extra arguments for the test runner, such as packages, paths or filters (e.g. [\"./server/...\", \"-run\", \"TestFoo\"])"
+    },
+    "rerun_failed": {
+      "type": "boolean",
+      "description": "Run only the tests that failed last time"
+    }
+  }
+}
+`
+	// maxReportedFailures caps the failures described to the model.
+	maxReportedFailures = 30
+	// testOutputTailLines is how much output is shown when the results
+	// can't be read.
+	testOutputTailLines = 20
+)
+
+type runTestsInput struct {
+	Framework   string   `json:"framework"`
+	Args        []string `json:"args"`
+	RerunFailed bool     `json:"rerun_failed"`
+}
+
+// RunTestsDisplayData is the display data sent to the UI for run_tests
+// results.
+type RunTestsDisplayData struct {
+	Framework string            `json:"framework"`
+	Command   string            `json:"command"`
+	Dir       string            `json:"dir"`
+	Passed    int               `json:"passed"`
+	Failed    int               `json:"failed"`
+	Skipped   int               `json:"skipped"`
+	Failures  []testkit.Failure `json:"failures,omitempty"`
+}
+
+// Tool returns an llm.Tool based on t.
+func (t *RunTestsTool) Tool() *llm.Tool {
+	return &llm.Tool{
+		Name:        runTestsName,
+		Description: strings.TrimSpace(runTestsDescription),
+		InputSchema: llm.MustSchema(runTestsInputSchema),
+		Run:         t.Run,
+	}
+}
+
+// Run runs the run_tests tool.
+func (t *RunTestsTool) Run(ctx context.Context, m json.RawMessage) llm.ToolOut {
+	var req runTestsInput
+	if err := json.Unmarshal(m, &req); err != nil {
+		return llm.ErrorfToolOut("failed to parse run_tests input: %w", err)
+	}
+
+	wd := t.WorkingDir.Get()
+	if _, err := os.Stat(wd); err != nil {
+		return llm.ErrorfToolOut("cannot access working directory %s: %w", wd, err)
+	}
+	var run testkit.Run
+	if req.Framework != "" {
+		run.Framework = testkit.Framework(req.Framework)
+		if !slices.Contains(testkit.Frameworks, run.Framework) {
+			return llm.ErrorfToolOut("unknown framework %q", req.Framework)
+		}
+		run.Dir = testkit.Root(run.Framework, wd)
+	} else {
+		var ok bool
+		run.Framework, run.Dir, ok = testkit.Detect(wd)
+		if !ok {
+			return llm.ErrorfToolOut("no test framework detected in %s or its parents; set framework", wd)
+		}
+	}
+	run.Args = req.Args
+
+	key := string(run.Framework) + "\x00" + run.Dir
+	if req.RerunFailed {
+		t.mu.Lock()
+		last, ok := t.last[key]
+		t.mu.Unlock()
+		if !ok || len(last.failures) == 0 {
+			return llm.ErrorfToolOut("no failures to rerun for %s in %s", run.Framework, run.Dir)
+		}
+		if run.Args == nil {
+			run.Args = last.args
+		}
+		run.Rerun = last.failures
+	}
+
+	if run.NeedsReport() {
+		tmp, err := os.MkdirTemp("", "shelley-tests-")
+		if err != nil {
+			return llm.ErrorfToolOut("failed to create a directory for the test report: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		run.Report = filepath.Join(tmp, "report")
+	}
+	argv, err := run.Command()
+	if err != nil {
+		return llm.ErrorToolOut(err)
+	}
+	words := make([]string, len(argv))
+	for i, a := range argv {
+		words[i] = shellWord(a)
+	}
+	command := strings.Join(words, " ")
+	if t.Bash.CheckPermission != nil {
+		if err := t.Bash.CheckPermission(command); err != nil {
+			return llm.ErrorToolOut(err)
+		}
+	}
+
+	output, runErr := t.execute(ctx, run.Dir, command)
+	if ctx.Err() != nil {
+		return llm.ErrorToolOut(ctx.Err())
+	}
+	var report []byte
+	if run.Report != "" {
+		report, _ = os.ReadFile(run.Report)
+	}
+	summary, err := run.Parse(output, report, runErr == nil)
+	if err != nil {
+		return llm.ErrorfToolOut("%w\n%s", err, outputTail(output))
+	}
+	if summary.Passed+summary.Failed+summary.Skipped == 0 && len(summary.Failures) == 0 {
+		return llm.ErrorfToolOut("no tests ran: %s\n%s", command, outputTail(output))
+	}
+
+	t.mu.Lock()
+	if t.last == nil {
+		t.last = make(map[string]lastTestRun)
+	}
+	t.last[key] = lastTestRun{args: run.Args, failures: summary.Failures}
+	t.mu.Unlock()
+
+	display := RunTestsDisplayData{
+		Framework: string(run.Framework),
+		Command:   command,
+		Dir:       run.Dir,
+		Passed:    summary.Passed,
+		Failed:    summary.Failed,
+		Skipped:   summary.Skipped,
+		Failures:  summary.Failures,
+	}
+	return llm.ToolOut{LLMContent: llm.TextContent(describeTestSummary(command, run.Dir, summary)), Display: display}
+}
+
+// execute runs command in dir the way the bash tool would, streaming its
+// output as progress.
+func (t *RunTestsTool) execute(ctx context.Context, dir, command string) ([]byte, error) {
+	execCtx, cancel := context.WithTimeout(ctx, t.Bash.Timeouts.slow())
+	defer cancel()
+
+	output := new(bytes.Buffer)
+	var out io.Writer = output
+	if report := Progress(ctx); report != nil {
+		progress := newProgressWriter(report)
+		defer progress.Close()
+		out = io.MultiWriter(output, progress)
+	}
+	cmd := t.Bash.makeBashCommand(execCtx, command, out)
+	cmd.Dir = dir
+	start := time.Now()
+	err := cmd.Start()
+	if err == nil {
+		err = cmdWait(cmd)
+	}
+	if execCtx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("tests timed out after %s", t.Bash.Timeouts.slow())
+	}
+	if t.Bash.Audit != nil {
+		t.Bash.Audit(bashAuditRecord(dir, command, time.Since(start), err))
+	}
+	return output.Bytes(), err
+}
+
+// describeTestSummary describes a run's results for the model.
+func describeTestSummary(command, dir string, s *testkit.Summary) string {
+	sb := new(strings.Builder)
+	fmt.Fprintf(sb, "%s\nin %s: %d passed, %d failed, %d skipped\n", command, dir, s.Passed, s.Failed, s.Skipped)
+	for i, f := range s.Failures {
+		if i == maxReportedFailures {
+			fmt.Fprintf(sb, "\n… and %d more failures\n", len(s.Failures)-i)
+			break
+		}
+		sb.WriteString("\nFAIL")
+		if f.Name != "" {
+			sb.WriteString(" " + f.Name)
+		}
+		if f.Suite != "" && f.Suite != f.File {
+			fmt.Fprintf(sb, " (%s)", f.Suite)
+		}
+		if loc := f.Location(); loc != "" {
+			sb.WriteString(" at " + loc)
+		}
+		sb.WriteString("\n")
+		for line := range strings.Lines(f.Message) {
+			sb.WriteString("    " + truncateLine(strings.TrimRight(line, "\n")) + "\n")
+		}
+	}
+	if len(s.Failures) > 0 {
+		sb.WriteString("\nAfter fixing, use rerun_failed=true to run only these tests again.\n")
+	}
+	return sb.String()
+}
+
+// plainWord matches words that need no quoting in bash.
+var plainWord = regexp.MustCompile(`^[\w@%+=:,./-]+$`)
+
+// shellWord quotes s for use as a single bash word if it needs it, so that
+// commands read as a person would type them.
+func shellWord(s string) string {
+	if plainWord.MatchString(s) {
+		return s
+	}
+	return shellQuote(s)
+}
+
+// outputTail returns the end of a command's output, for when its results
+// couldn't be read.
+func outputTail(output []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) > testOutputTailLines {
+		lines = lines[len(lines)-testOutputTailLines:]
+	}
+	for i, l := range lines {
+		lines[i] = truncateLine(l)
+	}
+	return strings.Join(lines, "\n")
+}
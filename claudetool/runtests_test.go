@@ -0,0 +1,103 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunTestsGo(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not found")
+	}
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module example.com/calc\n\ngo 1.22\n")
+	write("calc.go", "package calc\n\nfunc Add(a, b int) int { return a - b }\n")
+	write("calc_test.go", `package calc
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	if got := Add(2, 2); got != 4 {
+		t.Errorf("Add(2, 2) = %d, want 4", got)
+	}
+}
+
+func TestZero(t *testing.T) {
+	if Add(0, 0) != 0 {
+		t.Error("not zero")
+	}
+}
+
+func TestLater(t *testing.T) { t.Skip("later") }
+`)
+
+	var audits []AuditRecord
+	wd := NewMutableWorkingDir(dir)
+	tool := &RunTestsTool{
+		WorkingDir: wd,
+		Bash:       &BashTool{WorkingDir: wd, Audit: func(rec AuditRecord) { audits = append(audits, rec) }},
+	}
+	run := func(input string) (string, RunTestsDisplayData) {
+		t.Helper()
+		out := tool.Run(context.Background(), json.RawMessage(input))
+		if out.Error != nil {
+			t.Fatalf("run_tests %s failed: %v", input, out.Error)
+		}
+		return out.LLMContent[0].Text, out.Display.(RunTestsDisplayData)
+	}
+
+	text, d := run(`{}`)
+	if d.Framework != "go" || d.Dir != dir || d.Passed != 1 || d.Failed != 1 || d.Skipped != 1 {
+		t.Errorf("display = %+v", d)
+	}
+	if len(d.Failures) != 1 || d.Failures[0].Name != "TestAdd" || d.Failures[0].Location() != "calc_test.go:7" {
+		t.Fatalf("failures = %+v", d.Failures)
+	}
+	for _, want := range []string{"1 passed, 1 failed, 1 skipped", "FAIL TestAdd (example.com/calc) at calc_test.go:7", "Add(2, 2) = 0, want 4", "rerun_failed=true"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output does not contain %q:\n%s", want, text)
+		}
+	}
+	if len(audits) != 1 || audits[0].Cwd != dir || audits[0].ExitCode == nil || *audits[0].ExitCode != 1 {
+		t.Errorf("audits = %+v", audits)
+	}
+
+	// After the fix, only the failing test runs again.
+	write("calc.go", "package calc\n\nfunc Add(a, b int) int { return a + b }\n")
+	text, d = run(`{"rerun_failed": true}`)
+	if d.Passed != 1 || d.Failed != 0 || d.Skipped != 0 {
+		t.Errorf("rerun display = %+v", d)
+	}
+	if !strings.Contains(d.Command, "-run '^(TestAdd)$'") || strings.Contains(text, "rerun_failed") {
+		t.Errorf("rerun command %q, output:\n%s", d.Command, text)
+	}
+
+	out := tool.Run(context.Background(), json.RawMessage(`{"rerun_failed": true}`))
+	if out.Error == nil || !strings.Contains(out.Error.Error(), "no failures to rerun") {
+		t.Errorf("rerun with no failures: %v", out.Error)
+	}
+}
+
+func TestRunTestsNoFramework(t *testing.T) {
+	wd := NewMutableWorkingDir(t.TempDir())
+	tool := &RunTestsTool{WorkingDir: wd, Bash: &BashTool{WorkingDir: wd}}
+	out := tool.Run(context.Background(), json.RawMessage(`{}`))
+	if out.Error == nil || !strings.Contains(out.Error.Error(), "no test framework detected") {
+		t.Errorf("got %v, want no framework error", out.Error)
+	}
+	out = tool.Run(context.Background(), json.RawMessage(`{"framework": "mocha"}`))
+	if out.Error == nil || !strings.Contains(out.Error.Error(), "unknown framework") {
+		t.Errorf("got %v, want unknown framework error", out.Error)
+	}
+}
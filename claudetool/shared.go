@@ -36,3 +36,19 @@ func SessionID(ctx context.Context) string {
 	sessionID, _ := ctx.Value(sessionIDCtxKey).(string)
 	return sessionID
 }
+
+type progressCtxKeyType string
+
+const progressCtxKey progressCtxKeyType = "progress"
+
+// WithProgress returns a context in which tools that produce output over
+// time, such as bash, pass partial output to report while they run.
+func WithProgress(ctx context.Context, report func(chunk string)) context.Context {
+	return context.WithValue(ctx, progressCtxKey, report)
+}
+
+// Progress returns the function set by WithProgress, or nil.
+func Progress(ctx context.Context) func(chunk string) {
+	report, _ := ctx.Value(progressCtxKey).(func(string))
+	return report
+}
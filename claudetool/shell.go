@@ -0,0 +1,317 @@
+package claudetool
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/creack/pty"
+)
+
+// shellStartTimeout bounds how long a persistent shell may take to start,
+// including the login scripts.
+var shellStartTimeout = time.Minute
+
+// shellOutputLimit is how much of a command's output a persistent shell
+// keeps: the first and the most recent half of it.
+var shellOutputLimit = 1024 * 1024
+
+// persistentShell is a long-lived bash on a PTY. In persistent mode the
+// bash tool runs its commands in one, one at a time, so that the working
+// directory, variables and activated virtualenvs carry over between calls.
+type persistentShell struct {
+	cmd     *exec.Cmd
+	ptmx    *os.File
+	scripts string // directory holding the command scripts
+	nonce   string // marks the shell's own output
+	runs    int
+	pwd     string // working directory after the last command
+
+	mu     sync.Mutex
+	out    bytes.Buffer
+	notify chan struct{} // signalled when output arrives
+	exited chan struct{} // closed when the shell exits
+}
+
+// shellExitError reports a command's non-zero exit status.
+type shellExitError struct{ code int }
+
+func (e *shellExitError) Error() string { return fmt.Sprintf("exit status %d", e.code) }
+func (e *shellExitError) ExitCode() int { return e.code }
+
+// errShellExited means the shell itself exited, such as after exit.
+var errShellExited = errors.New("the shell exited; the next command starts a new one")
+
+// startPersistentShell starts a login shell in dir with env.
+func startPersistentShell(dir string, env []string) (*persistentShell, error) {
+	scripts, err := os.MkdirTemp("", "shelley-shell-")
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 8)
+	rand.Read(nonce)
+
+	cmd := exec.Command("bash", "--login")
+	cmd.Dir = dir
+	// Output goes to a terminal, so keep programs from paging or using
+	// terminal features the tool can't show.
+	cmd.Env = append(env, "TERM=dumb", "PAGER=cat", "GIT_PAGER=cat", "MANPAGER=cat", "SYSTEMD_PAGER=")
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{Rows: 50, Cols: 200})
+	if err != nil {
+		os.RemoveAll(scripts)
+		return nil, fmt.Errorf("failed to start shell: %w", err)
+	}
+	s := &persistentShell{
+		cmd:     cmd,
+		ptmx:    ptmx,
+		scripts: scripts,
+		nonce:   hex.EncodeToString(nonce),
+		pwd:     dir,
+		notify:  make(chan struct{}, 1),
+		exited:  make(chan struct{}),
+	}
+	go s.read()
+
+	// No echo, prompts, job control or history, and plain newlines.
+	setup := "stty -echo -onlcr; set +m; unset HISTFILE PROMPT_COMMAND; PS0=; PS1=; PS2=\n"
+	if _, err := s.ptmx.WriteString(setup); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to start shell: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shellStartTimeout)
+	defer cancel()
+	if _, err := s.run(ctx, "true", "", nil); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to start shell: %w", err)
+	}
+	return s, nil
+}
+
+// read copies the shell's output into s.out until the shell exits.
+func (s *persistentShell) read() {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := s.ptmx.Read(buf)
+		if n > 0 {
+			s.mu.Lock()
+			s.out.Write(buf[:n])
+			s.mu.Unlock()
+			select {
+			case s.notify <- struct{}{}:
+			default:
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	s.cmd.Wait()
+	close(s.exited)
+}
+
+// alive reports whether the shell is still running.
+func (s *persistentShell) alive() bool {
+	select {
+	case <-s.exited:
+		return false
+	default:
+		return true
+	}
+}
+
+// run runs command in the shell, first changing to dir if it is set and
+// differs from the shell's working directory. It returns the command's
+// output. A non-zero exit status is reported as a *shellExitError. If the
+// command doesn't finish before ctx is done, the shell is killed. If progress
+// is set, output is also written to it as it arrives.
+func (s *persistentShell) run(ctx context.Context, command, dir string, progress io.Writer) (string, error) {
+	s.runs++
+	script := filepath.Join(s.scripts, fmt.Sprintf("cmd-%d.sh", s.runs))
+	if err := os.WriteFile(script, []byte(command+"\n"), 0o600); err != nil {
+		return "", err
+	}
+	defer os.Remove(script)
+
+	// The script is sourced so that it can change the shell's state. Its
+	// stdin is /dev/null, since nothing can answer prompts. The markers
+	// are written with printf escapes, so that an echo of this line can't
+	// be mistaken for them.
+	var line strings.Builder
+	fmt.Fprintf(&line, `printf '\036%s-start\036\n'; `, s.nonce)
+	if dir != "" && dir != s.pwd {
+		fmt.Fprintf(&line, "cd -- %s && ", shellQuote(dir))
+	}
+	fmt.Fprintf(&line, `. %s </dev/null; printf '\036%s-end:%%d:%%s\036\n' "$?" "$PWD"`+"\n", shellQuote(script), s.nonce)
+
+	start := "\x1e" + s.nonce + "-start\x1e\n"
+	end := regexp.MustCompile("\x1e" + s.nonce + "-end:([0-9]+):(.*)\x1e\n")
+
+	// Discard anything printed since the last command, such as by jobs
+	// it left running.
+	s.mu.Lock()
+	s.out.Reset()
+	s.mu.Unlock()
+	if _, err := s.ptmx.WriteString(line.String()); err != nil {
+		return "", errShellExited
+	}
+
+	// The shell's output is moved out of s.out as it arrives, so each
+	// wakeup only scans the new bytes. Of the command's output, the first
+	// and the most recent shellOutputLimit/2 bytes are kept.
+	var (
+		pending []byte // output before the start marker
+		started bool
+		buf     []byte // the command's output, and perhaps the end marker
+		scan    int    // where in buf the end marker may start
+		sent    int    // how much of buf has been written to progress
+		omitAt  int    // where in buf output was left out
+		omitted int    // how much output was left out
+		done    []string
+		doneAt  int
+	)
+	endPrefix := []byte("\x1e" + s.nonce + "-end:")
+	take := func() {
+		s.mu.Lock()
+		if started {
+			buf = append(buf, s.out.Bytes()...)
+		} else {
+			pending = append(pending, s.out.Bytes()...)
+			if i := bytes.Index(pending, []byte(start)); i >= 0 {
+				started = true
+				buf = append(buf, pending[i+len(start):]...)
+				pending = nil
+			} else if keep := len(start) - 1; len(pending) > keep {
+				pending = append(pending[:0], pending[len(pending)-keep:]...)
+			}
+		}
+		s.out.Reset()
+		s.mu.Unlock()
+
+		for done == nil {
+			i := bytes.Index(buf[scan:], endPrefix)
+			if i < 0 {
+				scan = max(scan, len(buf)-len(endPrefix)+1)
+				break
+			}
+			scan += i
+			m := end.FindSubmatchIndex(buf[scan:])
+			if m == nil {
+				break // the rest of the marker hasn't arrived yet
+			}
+			done = []string{string(buf[scan+m[2] : scan+m[3]]), string(buf[scan+m[4] : scan+m[5]])}
+			doneAt = scan
+		}
+	}
+	// output returns the command's output up to n bytes into buf.
+	output := func(n int) string {
+		if omitted == 0 {
+			return normalizeShellOutput(buf[:n])
+		}
+		return normalizeShellOutput(buf[:omitAt]) +
+			fmt.Sprintf("\n[... %d bytes of output omitted ...]\n", omitted) +
+			normalizeShellOutput(buf[omitAt:n])
+	}
+	// trim drops output from the middle once buf holds twice the limit,
+	// as jobOutput does, never dropping what hasn't been scanned or
+	// written to progress yet.
+	trim := func() {
+		if len(buf) <= 2*shellOutputLimit {
+			return
+		}
+		from := omitAt
+		if omitted == 0 {
+			from = shellOutputLimit / 2
+		}
+		to := min(len(buf)-shellOutputLimit/2, scan)
+		if progress != nil {
+			to = min(to, sent)
+		}
+		// Drop whole lines where possible.
+		if i := bytes.LastIndexByte(buf[from:to], '\n'); i >= 0 && to-(from+i+1) < 4096 {
+			to = from + i + 1
+		}
+		if to <= from {
+			return
+		}
+		buf = append(buf[:from], buf[to:]...)
+		omitAt, omitted = from, omitted+to-from
+		scan -= to - from
+		if done != nil {
+			doneAt -= to - from
+		}
+		if progress != nil {
+			sent -= to - from
+		}
+	}
+
+	for {
+		take()
+		if progress != nil {
+			// Hold back what may be the start of the end marker, or of
+			// a line ending.
+			n := len(buf)
+			if done != nil {
+				n = doneAt
+			} else {
+				if i := bytes.IndexByte(buf[scan:], '\x1e'); i >= 0 {
+					n = scan + i
+				}
+				if n > sent && buf[n-1] == '\r' {
+					n--
+				}
+			}
+			if n > sent {
+				progress.Write([]byte(normalizeShellOutput(buf[sent:n])))
+				sent = n
+			}
+		}
+		trim()
+		if done != nil {
+			s.pwd = done[1]
+			out := output(doneAt)
+			code, _ := strconv.Atoi(done[0])
+			if code != 0 {
+				return out, &shellExitError{code}
+			}
+			return out, nil
+		}
+		select {
+		case <-s.notify:
+		case <-s.exited:
+			take()
+			return output(len(buf)), errShellExited
+		case <-ctx.Done():
+			take()
+			s.close()
+			return output(len(buf)), ctx.Err()
+		}
+	}
+}
+
+// close kills the shell and everything it started.
+func (s *persistentShell) close() {
+	if s.alive() {
+		syscall.Kill(-s.cmd.Process.Pid, syscall.SIGKILL)
+	}
+	s.ptmx.Close()
+	<-s.exited
+	os.RemoveAll(s.scripts)
+}
+
+// normalizeShellOutput turns terminal line endings into plain newlines.
+func normalizeShellOutput(b []byte) string {
+	return strings.ReplaceAll(string(b), "\r\n", "\n")
+}
@@ -0,0 +1,131 @@
+package claudetool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPersistentShell(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var dirChanges []string
+	bash := &BashTool{
+		WorkingDir:         NewMutableWorkingDir(dir),
+		Persistent:         true,
+		Timeouts:           &Timeouts{Fast: 20 * time.Second, Slow: 20 * time.Second},
+		OnWorkingDirChange: func(d string) { dirChanges = append(dirChanges, d) },
+	}
+	t.Cleanup(bash.Close)
+	if !strings.Contains(bash.Tool().Description, "persistent shell") {
+		t.Error("description doesn't mention the persistent shell")
+	}
+
+	run := func(command string) (string, error) {
+		t.Helper()
+		input, _ := json.Marshal(bashInput{Command: command})
+		out := bash.Run(context.Background(), input)
+		if out.Error != nil {
+			return "", out.Error
+		}
+		return out.LLMContent[0].Text, nil
+	}
+
+	// State carries over between calls.
+	if _, err := run("export GREETING=hello; cd sub"); err != nil {
+		t.Fatal(err)
+	}
+	out, err := run(`echo "$GREETING in $(basename "$PWD")"; test -t 1 && echo tty`)
+	if err != nil || out != "hello in sub\ntty\n" {
+		t.Errorf("output = %q, %v", out, err)
+	}
+	if got := bash.WorkingDir.Get(); got != sub {
+		t.Errorf("working dir = %q, want %q", got, sub)
+	}
+	if len(dirChanges) != 1 || dirChanges[0] != sub {
+		t.Errorf("working dir changes = %q", dirChanges)
+	}
+
+	// Commands can't read stdin, so they don't hang waiting for it.
+	if out, err := run("read x; echo read=$?"); err != nil || out != "read=1\n" {
+		t.Errorf("reading stdin: %q, %v", out, err)
+	}
+
+	// A failing command reports its status and leaves the shell alone.
+	if _, err := run("echo oops; false"); err == nil || !strings.Contains(err.Error(), "exit status 1") || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("failing command error = %v", err)
+	}
+	if out, err := run("echo $GREETING"); err != nil || out != "hello\n" {
+		t.Errorf("state after a failure: %q, %v", out, err)
+	}
+
+	// change_dir moves the shell too.
+	bash.WorkingDir.Set(dir)
+	if out, err := run("pwd"); err != nil || out != dir+"\n" {
+		t.Errorf("pwd after change_dir = %q, %v", out, err)
+	}
+
+	// Exiting starts a fresh shell on the next call.
+	if _, err := run("exit 3"); err == nil || !strings.Contains(err.Error(), "shell exited") {
+		t.Errorf("exit error = %v", err)
+	}
+	if out, err := run(`echo "[$GREETING]"`); err != nil || out != "[]\n" {
+		t.Errorf("state after exit: %q, %v", out, err)
+	}
+
+	// A command that times out restarts the shell.
+	bash.Timeouts = &Timeouts{Fast: time.Second, Slow: time.Second}
+	if _, err := run("export GREETING=again; echo started; sleep 30"); err == nil || !strings.Contains(err.Error(), "timed out") || !strings.Contains(err.Error(), "started") {
+		t.Errorf("timeout error = %v", err)
+	}
+	bash.Timeouts = &Timeouts{Fast: 20 * time.Second, Slow: 20 * time.Second}
+	if out, err := run(`echo "[$GREETING]"`); err != nil || out != "[]\n" {
+		t.Errorf("state after timeout: %q, %v", out, err)
+	}
+}
+
+func TestPersistentShellLargeOutput(t *testing.T) {
+	defer func(limit int) { shellOutputLimit = limit }(shellOutputLimit)
+	shellOutputLimit = 64 * 1024
+
+	s, err := startPersistentShell(t.TempDir(), os.Environ())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.close)
+
+	var want strings.Builder
+	for i := 1; i <= 200000; i++ {
+		fmt.Fprintf(&want, "%d\n", i)
+	}
+	var progress strings.Builder
+	out, err := s.run(context.Background(), "seq 1 200000", "", &progress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Progress sees everything, but only the start and end are kept.
+	if progress.String() != want.String() {
+		t.Errorf("progress has %d bytes, want %d", progress.Len(), want.Len())
+	}
+	if !strings.HasPrefix(out, "1\n2\n3\n") || !strings.HasSuffix(out, "\n199999\n200000\n") {
+		t.Errorf("output doesn't keep the start and end: %q ... %q", out[:20], out[len(out)-20:])
+	}
+	if !strings.Contains(out, "bytes of output omitted") || len(out) > 3*shellOutputLimit {
+		t.Errorf("output has %d bytes", len(out))
+	}
+	// The head and tail join at line boundaries.
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		if _, err := strconv.Atoi(line); err != nil && line != "" && !strings.HasPrefix(line, "[...") {
+			t.Errorf("partial line %q", line)
+		}
+	}
+}
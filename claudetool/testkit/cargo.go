@@ -0,0 +1,134 @@
+package testkit
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func (r *Run) cargoCommand() []string {
+	// Run every test target even when one fails.
+	cmd := append([]string{"cargo", "test", "--no-fail-fast"}, r.Args...)
+	if len(r.Rerun) > 0 {
+		var names []string
+		for _, f := range r.Rerun {
+			if f.Name != "" && !slices.Contains(names, f.Name) {
+				names = append(names, f.Name)
+			}
+		}
+		if len(names) > 0 {
+			// The test binaries' arguments follow "--".
+			if !slices.Contains(cmd, "--") {
+				cmd = append(cmd, "--")
+			}
+			cmd = append(cmd, "--exact")
+			cmd = append(cmd, names...)
+		}
+	}
+	return cmd
+}
+
+var (
+	// cargoTarget matches the line before each test binary's output:
+	// "     Running unittests src/lib.rs (target/debug/deps/x-1234)".
+	cargoTarget = regexp.MustCompile(`^\s+(?:Running (?:unittests )?(\S+)|Doc-tests (\S+))`)
+	// cargoResult matches "test tests::it_works ... ok".
+	cargoResult = regexp.MustCompile(`^test (.+?) \.\.\. (ok|FAILED|ignored)`)
+	// cargoOutput matches the header of a failed test's output.
+	cargoOutput = regexp.MustCompile(`^---- (.+?) stdout ----$`)
+	// cargoPanic matches where a test panicked, in the formats of both
+	// older and newer Rust versions.
+	cargoPanic = regexp.MustCompile(`panicked at (?:'(.*)', )?([^\s:']+):(\d+):\d+`)
+	// cargoCompileError matches where a compiler error is: " --> src/lib.rs:3:5".
+	cargoCompileError = regexp.MustCompile(`(?m)^\s*--> ([^\s:]+):(\d+):\d+`)
+)
+
+func (r *Run) parseCargo(output []byte) *Summary {
+	s := new(Summary)
+	var target string
+	failed := map[string]int{} // test name within the target to index in s.Failures
+	var current *Failure       // the failure whose output is being read
+	var message []string
+	var backtrace bool         // whether the rest of current's output is a backtrace
+	var compileErrors []string // compiler output, when the build fails
+	finish := func() {
+		if current != nil {
+			current.Message = head(strings.Join(message, "\n"), maxMessageLines)
+		}
+		current, message, backtrace = nil, nil, false
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(output))
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if m := cargoTarget.FindStringSubmatch(line); m != nil {
+			finish()
+			target = m[1] + m[2]
+			clear(failed)
+			continue
+		}
+		if m := cargoResult.FindStringSubmatch(line); m != nil {
+			switch m[2] {
+			case "ok":
+				s.Passed++
+			case "ignored":
+				s.Skipped++
+			case "FAILED":
+				s.Failed++
+				failed[m[1]] = len(s.Failures)
+				s.Failures = append(s.Failures, Failure{Suite: target, Name: m[1]})
+			}
+			continue
+		}
+		if m := cargoOutput.FindStringSubmatch(line); m != nil {
+			finish()
+			if i, ok := failed[m[1]]; ok {
+				current = &s.Failures[i]
+			}
+			continue
+		}
+		if current != nil {
+			if line == "failures:" {
+				finish()
+				continue
+			}
+			if m := cargoPanic.FindStringSubmatch(line); m != nil && current.File == "" {
+				current.File = m[2]
+				current.Line, _ = strconv.Atoi(m[3])
+				// The older format quotes the message; the newer one puts
+				// it on the following lines.
+				if m[1] != "" {
+					message = append(message, m[1])
+				}
+				continue
+			}
+			if line == "stack backtrace:" {
+				backtrace = true
+			}
+			if backtrace || strings.HasPrefix(line, "note: ") {
+				continue
+			}
+			message = append(message, line)
+			continue
+		}
+		if strings.HasPrefix(line, "error") || len(compileErrors) > 0 && !strings.HasPrefix(line, "test result:") {
+			compileErrors = append(compileErrors, line)
+		}
+	}
+	finish()
+
+	// A build that fails runs no tests.
+	if s.Passed+s.Failed+s.Skipped == 0 && len(compileErrors) > 0 {
+		f := Failure{Message: head(strings.Join(compileErrors, "\n"), maxMessageLines)}
+		if m := cargoCompileError.FindStringSubmatch(f.Message); m != nil {
+			f.File = m[1]
+			f.Line, _ = strconv.Atoi(m[2])
+		}
+		s.Failures = append(s.Failures, f)
+	}
+	return s
+}
@@ -0,0 +1,148 @@
+package testkit
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// cargoTestOutput is cargo test output with a passing, a failing and an
+// ignored test, run with RUST_BACKTRACE set.
+const cargoTestOutput = `   Compiling rp v0.1.0 (/tmp/tk/rp)
+    Finished ` + "`" + `test` + "`" + ` profile [unoptimized + debuginfo] target(s) in 2.95s
+     Running unittests src/lib.rs (target/debug/deps/rp-656d029fac1d6e28)
+
+running 3 tests
+test tests::it_fails ... FAILED
+test tests::it_works ... ok
+test tests::slow ... ignored
+
+failures:
+
+---- tests::it_fails stdout ----
+
+thread 'tests::it_fails' panicked at src/lib.rs:12:9:
+assertion ` + "`" + `left == right` + "`" + ` failed: math is hard
+  left: 4
+ right: 5
+stack backtrace:
+   0: __rustc::rust_begin_unwind
+             at /rustc/1159e78c4747b02ef996e55082b704c09b970588/library/std/src/panicking.rs:697:5
+   1: core::panicking::panic_fmt
+             at /rustc/1159e78c4747b02ef996e55082b704c09b970588/library/core/src/panicking.rs:75:14
+   2: core::panicking::assert_failed_inner
+             at /rustc/1159e78c4747b02ef996e55082b704c09b970588/library/core/src/panicking.rs:443:23
+   3: core::panicking::assert_failed
+             at /rustc/1159e78c4747b02ef996e55082b704c09b970588/library/core/src/panicking.rs:403:5
+   4: rp::tests::it_fails
+             at ./src/lib.rs:12:9
+   5: rp::tests::it_fails::{{closure}}
+             at ./src/lib.rs:11:18
+   6: core::ops::function::FnOnce::call_once
+             at /rustc/1159e78c4747b02ef996e55082b704c09b970588/library/core/src/ops/function.rs:253:5
+   7: core::ops::function::FnOnce::call_once
+             at /rustc/1159e78c4747b02ef996e55082b704c09b970588/library/core/src/ops/function.rs:253:5
+note: Some details are omitted, run with ` + "`" + `RUST_BACKTRACE=full` + "`" + ` for a verbose backtrace.
+
+
+failures:
+    tests::it_fails
+
+test result: FAILED. 1 passed; 1 failed; 1 ignored; 0 measured; 0 filtered out; finished in 0.02s
+
+error: test failed, to rerun pass ` + "`" + `--lib` + "`" + `
+   Doc-tests rp
+
+running 0 tests
+
+test result: ok. 0 passed; 0 failed; 0 ignored; 0 measured; 0 filtered out; finished in 0.00s
+
+error: 1 target failed:
+    ` + "`" + `--lib` + "`" + `
+`
+
+// cargoBuildOutput is cargo test output for tests that don't compile.
+const cargoBuildOutput = `   Compiling rp v0.1.0 (/tmp/tk/rp)
+error[E0425]: cannot find function ` + "`" + `nope` + "`" + ` in this scope
+ --> src/lib.rs:8:47
+  |
+8 |     fn it_works() { assert_eq!(add(2, 2), 4); nope(); }
+  |                                               ^^^^ not found in this scope
+
+For more information about this error, try ` + "`" + `rustc --explain E0425` + "`" + `.
+error: could not compile ` + "`" + `rp` + "`" + ` (lib test) due to 1 previous error
+`
+
+func TestParseCargo(t *testing.T) {
+	r := &Run{Framework: Cargo, Dir: "/tmp/tk/rp"}
+	s, err := r.Parse([]byte(cargoTestOutput), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Passed != 1 || s.Failed != 1 || s.Skipped != 1 {
+		t.Errorf("got %d passed, %d failed, %d skipped; want 1, 1, 1", s.Passed, s.Failed, s.Skipped)
+	}
+	want := []Failure{{
+		Suite:   "src/lib.rs",
+		Name:    "tests::it_fails",
+		File:    "src/lib.rs",
+		Line:    12,
+		Message: "assertion `left == right` failed: math is hard\n  left: 4\n right: 5",
+	}}
+	if !reflect.DeepEqual(s.Failures, want) {
+		t.Errorf("failures:\ngot  %+v\nwant %+v", s.Failures, want)
+	}
+}
+
+func TestParseCargoOldPanic(t *testing.T) {
+	output := `     Running unittests src/lib.rs (target/debug/deps/rp-1)
+
+running 1 test
+test tests::it_fails ... FAILED
+
+failures:
+
+---- tests::it_fails stdout ----
+thread 'tests::it_fails' panicked at 'assertion failed: false', src/lib.rs:7:9
+note: run with ` + "`RUST_BACKTRACE=1`" + ` environment variable to display a backtrace
+
+
+failures:
+    tests::it_fails
+
+test result: FAILED. 0 passed; 1 failed; 0 ignored; 0 measured; 0 filtered out
+`
+	s, err := (&Run{Framework: Cargo}).Parse([]byte(output), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Failures) != 1 || s.Failures[0].Location() != "src/lib.rs:7" {
+		t.Fatalf("failures = %+v, want one at src/lib.rs:7", s.Failures)
+	}
+	if !strings.Contains(s.Failures[0].Message, "assertion failed: false") {
+		t.Errorf("message = %q", s.Failures[0].Message)
+	}
+}
+
+func TestParseCargoBuildError(t *testing.T) {
+	s, err := (&Run{Framework: Cargo}).Parse([]byte(cargoBuildOutput), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Passed+s.Failed+s.Skipped != 0 || len(s.Failures) != 1 {
+		t.Fatalf("got %+v, want only a build failure", s)
+	}
+	f := s.Failures[0]
+	if f.Location() != "src/lib.rs:8" || !strings.HasPrefix(f.Message, "error[E0425]: cannot find function `nope`") {
+		t.Errorf("failure = %+v", f)
+	}
+}
+
+func TestCargoCommand(t *testing.T) {
+	r := &Run{Framework: Cargo, Args: []string{"-p", "core"}, Rerun: []Failure{{Name: "tests::a"}, {Name: "tests::b"}}}
+	got, _ := r.Command()
+	want := []string{"cargo", "test", "--no-fail-fast", "-p", "core", "--", "--exact", "tests::a", "tests::b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
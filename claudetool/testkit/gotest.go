@@ -0,0 +1,258 @@
+package testkit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func (r *Run) goCommand() []string {
+	cmd := []string{"go", "test", "-json"}
+	args := r.Args
+	if len(args) == 0 {
+		args = []string{"./..."}
+	}
+	cmd = append(cmd, args...)
+	if len(r.Rerun) > 0 {
+		// A later -run wins, and test flags may follow the packages.
+		var names []string
+		for _, f := range r.Rerun {
+			if name, _, _ := strings.Cut(f.Name, "/"); name != "" && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			cmd = append(cmd, "-run", alternation(names))
+		}
+	}
+	return cmd
+}
+
+// goEvent is a line of go test -json output.
+type goEvent struct {
+	Action      string
+	Package     string
+	Test        string
+	Output      string
+	FailedBuild string
+	ImportPath  string
+}
+
+type goTestKey struct{ pkg, test string }
+
+func (r *Run) parseGo(output []byte) *Summary {
+	var (
+		order      []goTestKey
+		status     = map[goTestKey]string{}
+		out        = map[goTestKey]*strings.Builder{}
+		buildOut   = map[string]*strings.Builder{}
+		failedPkgs []goEvent
+		other      strings.Builder // output that isn't JSON, such as old-style build errors
+	)
+	appendTo := func(m map[goTestKey]*strings.Builder, k goTestKey, s string) {
+		if m[k] == nil {
+			m[k] = new(strings.Builder)
+		}
+		m[k].WriteString(s)
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(output))
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		line := sc.Bytes()
+		var ev goEvent
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &ev) != nil {
+			other.Write(line)
+			other.WriteByte('\n')
+			continue
+		}
+		k := goTestKey{ev.Package, ev.Test}
+		switch ev.Action {
+		case "run":
+			if _, ok := status[k]; !ok {
+				order = append(order, k)
+			}
+			status[k] = "run"
+		case "output":
+			appendTo(out, k, ev.Output)
+		case "build-output":
+			if buildOut[ev.ImportPath] == nil {
+				buildOut[ev.ImportPath] = new(strings.Builder)
+			}
+			buildOut[ev.ImportPath].WriteString(ev.Output)
+		case "pass", "fail", "skip":
+			if ev.Test == "" {
+				if ev.Action == "fail" {
+					failedPkgs = append(failedPkgs, ev)
+				}
+				continue
+			}
+			if _, ok := status[k]; !ok {
+				order = append(order, k)
+			}
+			status[k] = ev.Action
+		}
+	}
+
+	// Count leaf tests only, so that a failing subtest isn't also
+	// reported through its parent.
+	isParent := func(k goTestKey) bool {
+		for _, o := range order {
+			if o.pkg == k.pkg && strings.HasPrefix(o.test, k.test+"/") {
+				return true
+			}
+		}
+		return false
+	}
+	s := new(Summary)
+	failedIn := map[string]bool{}
+	for _, k := range order {
+		if isParent(k) {
+			continue
+		}
+		switch status[k] {
+		case "pass":
+			s.Passed++
+		case "skip":
+			s.Skipped++
+		case "fail", "run": // a test still running when the binary died failed too
+			s.Failed++
+			failedIn[k.pkg] = true
+			f := Failure{Suite: k.pkg, Name: k.test}
+			var text string
+			if b := out[k]; b != nil {
+				text = b.String()
+			}
+			// A panic is reported in the output of the package.
+			if status[k] == "run" && out[goTestKey{k.pkg, ""}] != nil {
+				text += out[goTestKey{k.pkg, ""}].String()
+			}
+			f.File, f.Line = goLocation(text)
+			f.File = r.goFile(k.pkg, f.File)
+			f.Message = goMessage(text)
+			s.Failures = append(s.Failures, f)
+		}
+	}
+
+	// Packages that failed without a failing test: build errors, a
+	// failing TestMain, a timeout.
+	for _, ev := range failedPkgs {
+		if failedIn[ev.Package] {
+			continue
+		}
+		f := Failure{Suite: ev.Package}
+		var text string
+		if b := buildOut[ev.FailedBuild]; ev.FailedBuild != "" && b != nil {
+			text = b.String()
+		} else if b := out[goTestKey{ev.Package, ""}]; b != nil {
+			text = b.String()
+		}
+		if strings.TrimSpace(text) == "" {
+			text = other.String()
+		}
+		f.File, f.Line = goLocation(text)
+		f.File = r.goFile(ev.Package, f.File)
+		f.Message = goMessage(text)
+		s.Failures = append(s.Failures, f)
+	}
+	return s
+}
+
+var (
+	// goLogLocation matches t.Error output and compiler errors:
+	// "    foo_test.go:12: message" or "./foo.go:3:5: undefined: x".
+	goLogLocation = regexp.MustCompile(`(?m)^\s*([\w./\-]+\.go):(\d+)(?::\d+)?: `)
+	// goStackLocation matches a panic's stack frames.
+	goStackLocation = regexp.MustCompile(`(?m)^\s+(/\S+_test\.go):(\d+)`)
+)
+
+// goLocation finds where a failure happened in its output.
+func goLocation(text string) (string, int) {
+	m := goLogLocation.FindStringSubmatch(text)
+	if m == nil {
+		m = goStackLocation.FindStringSubmatch(text)
+	}
+	if m == nil {
+		return "", 0
+	}
+	line, _ := strconv.Atoi(m[2])
+	return m[1], line
+}
+
+// goMessage returns the interesting part of a failure's output.
+func goMessage(text string) string {
+	var kept []string
+	for line := range strings.Lines(text) {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- FAIL") ||
+			trimmed == "FAIL" || strings.HasPrefix(trimmed, "FAIL\t") || strings.HasPrefix(trimmed, "exit status ") {
+			continue
+		}
+		kept = append(kept, strings.TrimRight(line, "\n"))
+	}
+	return head(dedent(strings.Join(kept, "\n")), maxMessageLines)
+}
+
+// goFile turns a file name from the output of pkg's tests into a path
+// relative to the run's directory. Test output names files by their base
+// name, so it is found through the module's path.
+func (r *Run) goFile(pkg, file string) string {
+	switch {
+	case file == "":
+		return ""
+	case filepath.IsAbs(file):
+		return r.relPath(file)
+	case strings.Contains(file, "/"):
+		return path.Clean(file)
+	}
+	data, err := os.ReadFile(filepath.Join(r.Dir, "go.mod"))
+	if err != nil {
+		return file
+	}
+	mod := goModulePath(data)
+	if mod == "" || pkg != mod && !strings.HasPrefix(pkg, mod+"/") {
+		return file
+	}
+	return path.Join(strings.TrimPrefix(strings.TrimPrefix(pkg, mod), "/"), file)
+}
+
+// goModulePath returns the module path declared in a go.mod file.
+func goModulePath(gomod []byte) string {
+	for line := range strings.Lines(string(gomod)) {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+// dedent removes the indentation the lines of s share.
+func dedent(s string) string {
+	lines := strings.Split(s, "\n")
+	prefix := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		n := len(l) - len(strings.TrimLeft(l, " \t"))
+		if prefix < 0 || n < prefix {
+			prefix = n
+		}
+	}
+	if prefix <= 0 {
+		return s
+	}
+	for i, l := range lines {
+		if len(l) >= prefix {
+			lines[i] = l[prefix:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
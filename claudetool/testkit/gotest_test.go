@@ -0,0 +1,120 @@
+package testkit
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// goTestOutput is go test -json output for a package with passing, failing,
+// skipped and nested tests, and a package that doesn't build.
+const goTestOutput = `{"Action":"start","Package":"example.com/gop"}
+{"Action":"run","Package":"example.com/gop","Test":"TestPass"}
+{"Action":"output","Package":"example.com/gop","Test":"TestPass","Output":"=== RUN   TestPass\n","OutputType":"frame"}
+{"Action":"output","Package":"example.com/gop","Test":"TestPass","Output":"--- PASS: TestPass (0.00s)\n","OutputType":"frame"}
+{"Action":"pass","Package":"example.com/gop","Test":"TestPass"}
+{"Action":"run","Package":"example.com/gop","Test":"TestFail"}
+{"Action":"output","Package":"example.com/gop","Test":"TestFail","Output":"=== RUN   TestFail\n","OutputType":"frame"}
+{"Action":"output","Package":"example.com/gop","Test":"TestFail","Output":"    a_test.go:8: got 1, want 2\n","OutputType":"error"}
+{"Action":"output","Package":"example.com/gop","Test":"TestFail","Output":"--- FAIL: TestFail (0.00s)\n","OutputType":"frame"}
+{"Action":"fail","Package":"example.com/gop","Test":"TestFail"}
+{"Action":"run","Package":"example.com/gop","Test":"TestSkip"}
+{"Action":"output","Package":"example.com/gop","Test":"TestSkip","Output":"=== RUN   TestSkip\n","OutputType":"frame"}
+{"Action":"output","Package":"example.com/gop","Test":"TestSkip","Output":"    a_test.go:11: later\n"}
+{"Action":"output","Package":"example.com/gop","Test":"TestSkip","Output":"--- SKIP: TestSkip (0.00s)\n","OutputType":"frame"}
+{"Action":"skip","Package":"example.com/gop","Test":"TestSkip"}
+{"Action":"run","Package":"example.com/gop","Test":"TestSub"}
+{"Action":"output","Package":"example.com/gop","Test":"TestSub","Output":"=== RUN   TestSub\n","OutputType":"frame"}
+{"Action":"run","Package":"example.com/gop","Test":"TestSub/ok"}
+{"Action":"output","Package":"example.com/gop","Test":"TestSub/ok","Output":"=== RUN   TestSub/ok\n","OutputType":"frame"}
+{"Action":"output","Package":"example.com/gop","Test":"TestSub/ok","Output":"--- PASS: TestSub/ok (0.00s)\n","OutputType":"frame"}
+{"Action":"pass","Package":"example.com/gop","Test":"TestSub/ok"}
+{"Action":"run","Package":"example.com/gop","Test":"TestSub/bad"}
+{"Action":"output","Package":"example.com/gop","Test":"TestSub/bad","Output":"=== RUN   TestSub/bad\n","OutputType":"frame"}
+{"Action":"output","Package":"example.com/gop","Test":"TestSub/bad","Output":"    a_test.go:15: boom\n","OutputType":"error"}
+{"Action":"output","Package":"example.com/gop","Test":"TestSub/bad","Output":"--- FAIL: TestSub/bad (0.00s)\n","OutputType":"frame"}
+{"Action":"fail","Package":"example.com/gop","Test":"TestSub/bad"}
+{"Action":"output","Package":"example.com/gop","Test":"TestSub","Output":"--- FAIL: TestSub (0.00s)\n","OutputType":"frame"}
+{"Action":"fail","Package":"example.com/gop","Test":"TestSub"}
+{"Action":"output","Package":"example.com/gop","Output":"FAIL\n","OutputType":"frame"}
+{"Action":"output","Package":"example.com/gop","Output":"FAIL\texample.com/gop\t0.004s\n","OutputType":"frame"}
+{"Action":"fail","Package":"example.com/gop"}
+{"ImportPath":"example.com/gop/sub [example.com/gop/sub.test]","Action":"build-output","Output":"# example.com/gop/sub [example.com/gop/sub.test]\n"}
+{"ImportPath":"example.com/gop/sub [example.com/gop/sub.test]","Action":"build-output","Output":"sub/b_test.go:5:33: declared and not used: x\n"}
+{"ImportPath":"example.com/gop/sub [example.com/gop/sub.test]","Action":"build-fail"}
+{"Action":"start","Package":"example.com/gop/sub"}
+{"Action":"output","Package":"example.com/gop/sub","Output":"FAIL\texample.com/gop/sub [build failed]\n","OutputType":"frame"}
+{"Action":"fail","Package":"example.com/gop/sub","FailedBuild":"example.com/gop/sub [example.com/gop/sub.test]"}
+`
+
+func TestParseGo(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/gop\n\ngo 1.22\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r := &Run{Framework: Go, Dir: dir}
+	s, err := r.Parse([]byte(goTestOutput), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Passed != 2 || s.Failed != 2 || s.Skipped != 1 {
+		t.Errorf("got %d passed, %d failed, %d skipped; want 2, 2, 1", s.Passed, s.Failed, s.Skipped)
+	}
+	want := []Failure{
+		{Suite: "example.com/gop", Name: "TestFail", File: "a_test.go", Line: 8, Message: "a_test.go:8: got 1, want 2"},
+		{Suite: "example.com/gop", Name: "TestSub/bad", File: "a_test.go", Line: 15, Message: "a_test.go:15: boom"},
+		{Suite: "example.com/gop/sub", File: "sub/b_test.go", Line: 5, Message: "# example.com/gop/sub [example.com/gop/sub.test]\nsub/b_test.go:5:33: declared and not used: x"},
+	}
+	if !reflect.DeepEqual(s.Failures, want) {
+		t.Errorf("failures:\ngot  %+v\nwant %+v", s.Failures, want)
+	}
+}
+
+func TestParseGoPanic(t *testing.T) {
+	output := `{"Action":"run","Package":"example.com/m/pkg","Test":"TestPanics"}
+{"Action":"output","Package":"example.com/m/pkg","Test":"TestPanics","Output":"=== RUN   TestPanics\n"}
+{"Action":"output","Package":"example.com/m/pkg","Test":"TestPanics","Output":"--- FAIL: TestPanics (0.00s)\n"}
+{"Action":"output","Package":"example.com/m/pkg","Test":"TestPanics","Output":"panic: runtime error: index out of range [3] with length 0 [recovered]\n"}
+{"Action":"output","Package":"example.com/m/pkg","Test":"TestPanics","Output":"\tpanic: runtime error: index out of range [3] with length 0\n"}
+{"Action":"output","Package":"example.com/m/pkg","Test":"TestPanics","Output":"\n"}
+{"Action":"output","Package":"example.com/m/pkg","Test":"TestPanics","Output":"goroutine 7 [running]:\n"}
+{"Action":"output","Package":"example.com/m/pkg","Test":"TestPanics","Output":"example.com/m/pkg.TestPanics(0xc000003340)\n"}
+{"Action":"output","Package":"example.com/m/pkg","Test":"TestPanics","Output":"\t/home/u/m/pkg/p_test.go:9 +0x1d\n"}
+{"Action":"fail","Package":"example.com/m/pkg","Test":"TestPanics"}
+{"Action":"fail","Package":"example.com/m/pkg"}
+`
+	r := &Run{Framework: Go, Dir: "/home/u/m"}
+	s, err := r.Parse([]byte(output), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Failed != 1 || len(s.Failures) != 1 {
+		t.Fatalf("got %d failed, failures %+v; want 1", s.Failed, s.Failures)
+	}
+	f := s.Failures[0]
+	if f.Location() != "pkg/p_test.go:9" {
+		t.Errorf("location = %q, want pkg/p_test.go:9", f.Location())
+	}
+	if !strings.HasPrefix(f.Message, "panic: runtime error: index out of range") {
+		t.Errorf("message = %q", f.Message)
+	}
+}
+
+func TestGoCommand(t *testing.T) {
+	r := &Run{Framework: Go, Args: []string{"./server/..."}}
+	got, err := r.Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go", "test", "-json", "./server/..."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	r.Rerun = []Failure{{Name: "TestSub/bad"}, {Name: "TestSub/worse"}, {Name: "TestFail"}, {Suite: "example.com/x"}}
+	got, _ = r.Command()
+	if want := []string{"go", "test", "-json", "./server/...", "-run", "^(TestSub|TestFail)$"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rerun: got %q, want %q", got, want)
+	}
+}
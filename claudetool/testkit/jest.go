@@ -0,0 +1,128 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// jsCommand returns the command line for Jest or Vitest, whose JSON
+// reports share a format.
+func (r *Run) jsCommand() []string {
+	name := string(r.Framework)
+	cmd := []string{r.local("node_modules/.bin/"+name, "npx")}
+	if cmd[0] == "npx" {
+		cmd = append(cmd, name)
+	}
+	if r.Framework == Vitest {
+		cmd = append(cmd, "run", "--reporter=default", "--reporter=json", "--outputFile.json="+r.Report)
+	} else {
+		cmd = append(cmd, "--json", "--outputFile="+r.Report)
+	}
+	cmd = append(cmd, r.Args...)
+	if len(r.Rerun) > 0 {
+		var files, names []string
+		for _, f := range r.Rerun {
+			if f.Suite != "" && !slices.Contains(files, f.Suite) {
+				files = append(files, f.Suite)
+			}
+			if f.Name != "" && !slices.Contains(names, f.Name) {
+				names = append(names, f.Name)
+			}
+		}
+		cmd = append(cmd, files...)
+		if len(names) > 0 {
+			cmd = append(cmd, "--testNamePattern", alternation(names))
+		}
+	}
+	return cmd
+}
+
+type jsReport struct {
+	TestResults []struct {
+		Name             string `json:"name"`
+		Status           string `json:"status"`
+		Message          string `json:"message"`
+		AssertionResults []struct {
+			FullName        string   `json:"fullName"`
+			Status          string   `json:"status"`
+			FailureMessages []string `json:"failureMessages"`
+			Location        *struct {
+				Line int `json:"line"`
+			} `json:"location"`
+		} `json:"assertionResults"`
+	} `json:"testResults"`
+}
+
+// jsStackFrame matches a stack frame's location: "at fn (/a/b.test.js:4:15)"
+// or "at /a/b.test.js:4:15".
+var jsStackFrame = regexp.MustCompile(`at (?:.*? \()?((?:file://)?[^\s()]+):(\d+):\d+\)?`)
+
+func (r *Run) parseJS(report []byte) (*Summary, error) {
+	if len(report) == 0 {
+		return nil, fmt.Errorf("%s wrote no report", r.Framework)
+	}
+	var rep jsReport
+	if err := json.Unmarshal(report, &rep); err != nil {
+		return nil, fmt.Errorf("failed to parse the %s report: %w", r.Framework, err)
+	}
+	s := new(Summary)
+	for _, file := range rep.TestResults {
+		suite := r.relPath(file.Name)
+		for _, a := range file.AssertionResults {
+			switch a.Status {
+			case "passed":
+				s.Passed++
+			case "failed":
+				s.Failed++
+				f := Failure{Suite: suite, Name: a.FullName, File: suite}
+				text := stripANSI(strings.Join(a.FailureMessages, "\n"))
+				f.Line = jsLine(text, file.Name)
+				if f.Line == 0 && a.Location != nil {
+					f.Line = a.Location.Line
+				}
+				f.Message = jsMessage(text)
+				s.Failures = append(s.Failures, f)
+			default: // skipped, pending, todo, disabled
+				s.Skipped++
+			}
+		}
+		// A file that fails to load has no tests, only a message.
+		if file.Status == "failed" && len(file.AssertionResults) == 0 {
+			text := stripANSI(file.Message)
+			s.Failures = append(s.Failures, Failure{
+				Suite:   suite,
+				File:    suite,
+				Line:    jsLine(text, file.Name),
+				Message: jsMessage(text),
+			})
+		}
+	}
+	return s, nil
+}
+
+// jsLine returns the line of the first stack frame in file.
+func jsLine(text, file string) int {
+	for _, m := range jsStackFrame.FindAllStringSubmatch(text, -1) {
+		if strings.TrimPrefix(m[1], "file://") == file {
+			n, _ := strconv.Atoi(m[2])
+			return n
+		}
+	}
+	return 0
+}
+
+// jsMessage returns a failure message without its stack trace.
+func jsMessage(text string) string {
+	var kept []string
+	for line := range strings.Lines(text) {
+		if strings.HasPrefix(strings.TrimSpace(line), "at ") {
+			continue
+		}
+		kept = append(kept, strings.TrimRight(line, "\n"))
+	}
+	return head(strings.Join(kept, "\n"), maxMessageLines)
+}
@@ -0,0 +1,99 @@
+package testkit
+
+import (
+	"reflect"
+	"testing"
+)
+
+// jestReport is a trimmed jest --json report with a passing, a failing and a
+// skipped test, and a file that fails to load.
+const jestReport = `{
+  "numFailedTests": 1, "numPassedTests": 1, "numPendingTests": 1, "success": false,
+  "testResults": [
+    {
+      "name": "/src/app/math.test.js",
+      "status": "failed",
+      "message": "",
+      "assertionResults": [
+        {"fullName": "math adds", "status": "passed", "failureMessages": [], "location": null},
+        {
+          "fullName": "math subtracts",
+          "status": "failed",
+          "failureMessages": ["Error: \u001b[2mexpect(\u001b[22m\u001b[31mreceived\u001b[39m\u001b[2m).\u001b[22mtoBe\u001b[2m(\u001b[22m\u001b[32mexpected\u001b[39m\u001b[2m) // Object.is equality\u001b[22m\n\nExpected: \u001b[32m2\u001b[39m\nReceived: \u001b[31m1\u001b[39m\n    at Object.toBe (/src/app/math.test.js:9:23)\n    at Promise.then.completed (/src/app/node_modules/jest-circus/build/utils.js:298:28)"],
+          "location": null
+        },
+        {"fullName": "math divides", "status": "pending", "failureMessages": [], "location": null}
+      ]
+    },
+    {
+      "name": "/src/app/broken.test.js",
+      "status": "failed",
+      "message": "  \u001b[1m● \u001b[22mTest suite failed to run\n\n    Cannot find module './nope' from 'broken.test.js'\n\n      at Resolver._throwModNotFoundError (node_modules/jest-resolve/build/resolver.js:427:11)\n      at Object.<anonymous> (/src/app/broken.test.js:1:1)",
+      "assertionResults": []
+    }
+  ]
+}`
+
+func TestParseJest(t *testing.T) {
+	r := &Run{Framework: Jest, Dir: "/src/app"}
+	s, err := r.Parse(nil, []byte(jestReport), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Passed != 1 || s.Failed != 1 || s.Skipped != 1 {
+		t.Errorf("got %d passed, %d failed, %d skipped; want 1, 1, 1", s.Passed, s.Failed, s.Skipped)
+	}
+	want := []Failure{
+		{
+			Suite:   "math.test.js",
+			Name:    "math subtracts",
+			File:    "math.test.js",
+			Line:    9,
+			Message: "Error: expect(received).toBe(expected) // Object.is equality\n\nExpected: 2\nReceived: 1",
+		},
+		{
+			Suite:   "broken.test.js",
+			File:    "broken.test.js",
+			Line:    1,
+			Message: "● Test suite failed to run\n\n    Cannot find module './nope' from 'broken.test.js'",
+		},
+	}
+	if !reflect.DeepEqual(s.Failures, want) {
+		t.Errorf("failures:\ngot  %+v\nwant %+v", s.Failures, want)
+	}
+}
+
+func TestParseVitestLocation(t *testing.T) {
+	// Vitest reports where a test is rather than a stack trace.
+	report := `{"testResults": [{"name": "/src/app/src/sum.test.ts", "status": "failed", "message": "", "assertionResults": [
+	  {"fullName": "sum adds", "status": "failed", "failureMessages": ["expected 3 to be 4 // Object.is equality"], "location": {"line": 5, "column": 3}},
+	  {"fullName": "sum todo", "status": "todo", "failureMessages": []}
+	]}]}`
+	r := &Run{Framework: Vitest, Dir: "/src/app"}
+	s, err := r.Parse(nil, []byte(report), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Failure{{Suite: "src/sum.test.ts", Name: "sum adds", File: "src/sum.test.ts", Line: 5, Message: "expected 3 to be 4 // Object.is equality"}}
+	if s.Failed != 1 || s.Skipped != 1 || !reflect.DeepEqual(s.Failures, want) {
+		t.Errorf("got %+v, want failures %+v", s, want)
+	}
+}
+
+func TestJSCommand(t *testing.T) {
+	rerun := []Failure{{Suite: "src/sum.test.ts", Name: "sum adds"}, {Suite: "src/sum.test.ts", Name: "sum (big)"}}
+	tests := []struct {
+		framework Framework
+		want      []string
+	}{
+		{Vitest, []string{"npx", "vitest", "run", "--reporter=default", "--reporter=json", "--outputFile.json=/tmp/r.json", "src/sum.test.ts", "--testNamePattern", `^(sum adds|sum \(big\))$`}},
+		{Jest, []string{"npx", "jest", "--json", "--outputFile=/tmp/r.json", "src/sum.test.ts", "--testNamePattern", `^(sum adds|sum \(big\))$`}},
+	}
+	for _, tt := range tests {
+		r := &Run{Framework: tt.framework, Dir: t.TempDir(), Report: "/tmp/r.json", Rerun: rerun}
+		got, _ := r.Command()
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.framework, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package testkit
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func (r *Run) pytestCommand() []string {
+	python := r.local(".venv/bin/python", "python3")
+	// xunit1 reports record each test's file.
+	cmd := []string{python, "-m", "pytest", "-q", "--junitxml=" + r.Report, "-o", "junit_family=xunit1"}
+	cmd = append(cmd, r.Args...)
+	if len(r.Rerun) > 0 {
+		// pytest remembers which tests failed last time.
+		cmd = append(cmd, "--last-failed")
+	}
+	return cmd
+}
+
+type junitCase struct {
+	ClassName string       `xml:"classname,attr"`
+	Name      string       `xml:"name,attr"`
+	File      string       `xml:"file,attr"`
+	Line      string       `xml:"line,attr"`
+	Failure   *junitResult `xml:"failure"`
+	Error     *junitResult `xml:"error"`
+	Skipped   *junitResult `xml:"skipped"`
+}
+
+type junitResult struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSuite struct {
+	Cases  []junitCase  `xml:"testcase"`
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+// pyLocation matches the line pytest ends a traceback with:
+// "tests/test_x.py:7: AssertionError".
+var pyLocation = regexp.MustCompile(`(?m)^(\S+\.py):(\d+): `)
+
+func (r *Run) parsePytest(report []byte) (*Summary, error) {
+	if len(report) == 0 {
+		return nil, errors.New("pytest wrote no report")
+	}
+	var root junitSuite // <testsuites> or a lone <testsuite>
+	if err := xml.Unmarshal(report, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse the pytest report: %w", err)
+	}
+	s := new(Summary)
+	var walk func(junitSuite)
+	walk = func(suite junitSuite) {
+		for _, c := range suite.Cases {
+			res := c.Failure
+			if res == nil {
+				res = c.Error
+			}
+			switch {
+			case res != nil:
+				s.Failed++
+				s.Failures = append(s.Failures, pytestFailure(c, res))
+			case c.Skipped != nil:
+				s.Skipped++
+			default:
+				s.Passed++
+			}
+		}
+		for _, sub := range suite.Suites {
+			walk(sub)
+		}
+	}
+	walk(root)
+	return s, nil
+}
+
+func pytestFailure(c junitCase, res *junitResult) Failure {
+	f := Failure{Suite: c.File, Name: c.Name, File: c.File}
+	if f.Suite == "" {
+		f.Suite = c.ClassName
+	}
+	if c.ClassName != "" && c.File != "" {
+		// The class, if any, is what follows the module in classname.
+		module := strings.TrimSuffix(filepath.ToSlash(c.File), ".py")
+		module = strings.ReplaceAll(module, "/", ".")
+		if class, ok := strings.CutPrefix(c.ClassName, module+"."); ok {
+			f.Name = class + "::" + c.Name
+		}
+	}
+	// The traceback ends where the test failed; pytest's own line is
+	// where the test starts, counting from zero.
+	if m := pyLocation.FindAllStringSubmatch(res.Text, -1); m != nil {
+		last := m[len(m)-1]
+		f.File = last[1]
+		f.Line, _ = strconv.Atoi(last[2])
+	} else if n, err := strconv.Atoi(c.Line); err == nil {
+		f.Line = n + 1
+	}
+
+	// The "E" lines hold the assertion and its explanation.
+	var explain []string
+	for line := range strings.Lines(res.Text) {
+		if rest, ok := strings.CutPrefix(line, "E "); ok {
+			explain = append(explain, strings.TrimRight(rest, "\n"))
+		}
+	}
+	switch {
+	case len(explain) > 0:
+		f.Message = head(dedent(strings.Join(explain, "\n")), maxMessageLines)
+	case res.Message != "":
+		f.Message = head(res.Message, maxMessageLines)
+	default:
+		f.Message = tail(res.Text, maxMessageLines)
+	}
+	return f
+}
@@ -0,0 +1,55 @@
+package testkit
+
+import (
+	"reflect"
+	"testing"
+)
+
+// pytestReport is a junit XML report from pytest -o junit_family=xunit1,
+// with a passing, a failing, a skipped test, and a module that fails to
+// import.
+const pytestReport = `<?xml version="1.0" encoding="utf-8"?><testsuites><testsuite name="pytest" errors="1" failures="1" skipped="1" tests="4" time="0.031" timestamp="2026-10-18T18:20:00.000000" hostname="dev"><testcase classname="" name="tests.test_broken" time="0.000"><error message="collection failure">ImportError while importing test module '/src/proj/tests/test_broken.py'.
+tests/test_broken.py:1: in &lt;module&gt;
+    import nope
+E   ModuleNotFoundError: No module named 'nope'</error></testcase><testcase classname="tests.test_calc" name="test_add" file="tests/test_calc.py" line="3" time="0.001" /><testcase classname="tests.test_calc.TestCalc" name="test_sub" file="tests/test_calc.py" line="9" time="0.001"><failure message="assert 1 == 2&#10; +  where 1 = sub(3, 2)">self = &lt;tests.test_calc.TestCalc object at 0x7f3a2c1e5d50&gt;
+
+    def test_sub(self):
+&gt;       assert sub(3, 2) == 2
+E       assert 1 == 2
+E        +  where 1 = sub(3, 2)
+
+tests/test_calc.py:11: AssertionError</failure></testcase><testcase classname="tests.test_calc" name="test_later" file="tests/test_calc.py" line="13" time="0.000"><skipped type="pytest.skip" message="later">tests/test_calc.py:14: later</skipped></testcase></testsuite></testsuites>`
+
+func TestParsePytest(t *testing.T) {
+	r := &Run{Framework: Pytest, Dir: "/src/proj"}
+	s, err := r.Parse(nil, []byte(pytestReport), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Passed != 1 || s.Failed != 2 || s.Skipped != 1 {
+		t.Errorf("got %d passed, %d failed, %d skipped; want 1, 2, 1", s.Passed, s.Failed, s.Skipped)
+	}
+	want := []Failure{
+		{Name: "tests.test_broken", File: "tests/test_broken.py", Line: 1, Message: "ModuleNotFoundError: No module named 'nope'"},
+		{Suite: "tests/test_calc.py", Name: "TestCalc::test_sub", File: "tests/test_calc.py", Line: 11, Message: "assert 1 == 2\n +  where 1 = sub(3, 2)"},
+	}
+	if !reflect.DeepEqual(s.Failures, want) {
+		t.Errorf("failures:\ngot  %+v\nwant %+v", s.Failures, want)
+	}
+}
+
+func TestParsePytestNoReport(t *testing.T) {
+	r := &Run{Framework: Pytest}
+	if _, err := r.Parse([]byte("/usr/bin/python3: No module named pytest\n"), nil, false); err == nil {
+		t.Error("Parse succeeded without a report")
+	}
+}
+
+func TestPytestCommand(t *testing.T) {
+	r := &Run{Framework: Pytest, Dir: t.TempDir(), Args: []string{"tests/test_calc.py"}, Report: "/tmp/r.xml", Rerun: []Failure{{Name: "test_sub"}}}
+	got, _ := r.Command()
+	want := []string{"python3", "-m", "pytest", "-q", "--junitxml=/tmp/r.xml", "-o", "junit_family=xunit1", "tests/test_calc.py", "--last-failed"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
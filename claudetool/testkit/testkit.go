@@ -0,0 +1,268 @@
+// Package testkit runs test suites of several languages and parses their
+// results into a common summary: counts, and each failure with where it
+// happened.
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// A Framework is a test runner testkit knows how to drive.
+type Framework string
+
+const (
+	Go     Framework = "go"
+	Pytest Framework = "pytest"
+	Jest   Framework = "jest"
+	Vitest Framework = "vitest"
+	Cargo  Framework = "cargo"
+)
+
+// Frameworks lists the supported frameworks.
+var Frameworks = []Framework{Go, Pytest, Jest, Vitest, Cargo}
+
+// A Failure is a failed test, or a failure outside any test such as a
+// package that doesn't build.
+type Failure struct {
+	Suite   string `json:"suite,omitempty"` // Go package, test file, or Rust test target
+	Name    string `json:"name,omitempty"`  // empty for failures outside a test
+	File    string `json:"file,omitempty"`  // relative to the run's directory where possible
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// Location returns f's file:line, or "" if it is unknown.
+func (f Failure) Location() string {
+	switch {
+	case f.File == "":
+		return ""
+	case f.Line > 0:
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	default:
+		return f.File
+	}
+}
+
+// A Summary is the result of a run.
+type Summary struct {
+	Passed   int       `json:"passed"`
+	Failed   int       `json:"failed"`
+	Skipped  int       `json:"skipped"`
+	Failures []Failure `json:"failures,omitempty"`
+}
+
+// A Run is one run of a project's tests.
+type Run struct {
+	Framework Framework
+	// Dir is the project root, where the tests run.
+	Dir string
+	// Args are extra arguments for the runner, such as packages or paths.
+	Args []string
+	// Rerun, if set, limits the run to these earlier failures.
+	Rerun []Failure
+	// Report is a file for runners that write their results to one.
+	Report string
+}
+
+// Detect finds the test framework of the project dir belongs to, looking
+// in dir and then its parents. It returns the framework and the project
+// root.
+func Detect(dir string) (Framework, string, bool) {
+	for d := dir; ; d = filepath.Dir(d) {
+		if f, ok := detectIn(d); ok {
+			return f, d, true
+		}
+		if parent := filepath.Dir(d); parent == d {
+			return "", "", false
+		}
+	}
+}
+
+// Root finds the root of the nearest project using f, looking in dir and
+// then its parents. If there is none, it returns dir.
+func Root(f Framework, dir string) string {
+	for d := dir; ; d = filepath.Dir(d) {
+		if slices.Contains(markers(d), f) {
+			return d
+		}
+		if parent := filepath.Dir(d); parent == d {
+			return dir
+		}
+	}
+}
+
+func detectIn(dir string) (Framework, bool) {
+	if fs := markers(dir); len(fs) > 0 {
+		return fs[0], true
+	}
+	return "", false
+}
+
+// markers returns the frameworks whose project files are in dir, most
+// specific first.
+func markers(dir string) []Framework {
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(dir, name))
+		return err == nil
+	}
+	var fs []Framework
+	if exists("go.mod") {
+		fs = append(fs, Go)
+	}
+	if exists("Cargo.toml") {
+		fs = append(fs, Cargo)
+	}
+	if f, ok := packageJSONRunner(filepath.Join(dir, "package.json")); ok {
+		fs = append(fs, f)
+	}
+	for _, name := range []string{"pytest.ini", "conftest.py", "pyproject.toml", "setup.cfg", "setup.py", "tox.ini"} {
+		if exists(name) {
+			fs = append(fs, Pytest)
+			break
+		}
+	}
+	return fs
+}
+
+// packageJSONRunner reports whether the package.json at path uses vitest
+// or jest.
+func packageJSONRunner(path string) (Framework, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	var pkg struct {
+		Scripts         map[string]string `json:"scripts"`
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return "", false
+	}
+	uses := func(name string) bool {
+		_, dep := pkg.Dependencies[name]
+		_, dev := pkg.DevDependencies[name]
+		return dep || dev || strings.Contains(pkg.Scripts["test"], name)
+	}
+	switch {
+	case uses("vitest"):
+		return Vitest, true
+	case uses("jest"):
+		return Jest, true
+	}
+	return "", false
+}
+
+// NeedsReport reports whether the run's results are written to r.Report
+// rather than to the output.
+func (r *Run) NeedsReport() bool {
+	return r.Framework == Pytest || r.Framework == Jest || r.Framework == Vitest
+}
+
+// Command returns the command line for the run.
+func (r *Run) Command() ([]string, error) {
+	switch r.Framework {
+	case Go:
+		return r.goCommand(), nil
+	case Pytest:
+		return r.pytestCommand(), nil
+	case Jest, Vitest:
+		return r.jsCommand(), nil
+	case Cargo:
+		return r.cargoCommand(), nil
+	}
+	return nil, fmt.Errorf("unknown test framework %q", r.Framework)
+}
+
+// Parse parses the run's output, and the report for runners that write
+// one. exitOK is whether the runner exited successfully; a failed run with
+// no failing tests becomes a failure of its own, such as for a build
+// error.
+func (r *Run) Parse(output, report []byte, exitOK bool) (*Summary, error) {
+	var s *Summary
+	var err error
+	switch r.Framework {
+	case Go:
+		s = r.parseGo(output)
+	case Pytest:
+		s, err = r.parsePytest(report)
+	case Jest, Vitest:
+		s, err = r.parseJS(report)
+	case Cargo:
+		s = r.parseCargo(output)
+	default:
+		return nil, fmt.Errorf("unknown test framework %q", r.Framework)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !exitOK && len(s.Failures) == 0 {
+		s.Failures = append(s.Failures, Failure{Message: tail(string(output), maxMessageLines)})
+	}
+	return s, nil
+}
+
+// local returns the executable name from the project's own tools if it has
+// it, such as node_modules/.bin/jest, so that nothing is downloaded.
+func (r *Run) local(rel, name string) string {
+	path := filepath.Join(r.Dir, rel)
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	return name
+}
+
+// relPath returns path relative to the run's directory if it is inside it.
+func (r *Run) relPath(path string) string {
+	if !filepath.IsAbs(path) {
+		return path
+	}
+	rel, err := filepath.Rel(r.Dir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// maxMessageLines caps the lines kept of a failure message.
+const maxMessageLines = 40
+
+// tail returns the last n lines of s, trimmed.
+func tail(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = append([]string{"…"}, lines[len(lines)-n:]...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// head returns the first n lines of s, trimmed.
+func head(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = append(lines[:n], "…")
+	}
+	return strings.Join(lines, "\n")
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// stripANSI removes terminal color codes.
+func stripANSI(s string) string {
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+// alternation returns a regexp matching exactly one of names.
+func alternation(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = regexp.QuoteMeta(n)
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
@@ -0,0 +1,67 @@
+package testkit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module example.com/m\n")
+	write("web/package.json", `{"devDependencies": {"vitest": "^2.0.0"}}`)
+	write("web/src/app.ts", "")
+	write("legacy/package.json", `{"scripts": {"test": "jest --ci"}}`)
+	write("tools/package.json", `{"scripts": {"build": "tsc"}}`)
+	write("py/pyproject.toml", "[project]\n")
+	write("rs/Cargo.toml", "[package]\n")
+
+	tests := []struct {
+		dir      string
+		want     Framework
+		wantRoot string
+	}{
+		{".", Go, "."},
+		{"web/src", Vitest, "web"},
+		{"legacy", Jest, "legacy"},
+		{"tools", Go, "."}, // a package.json without a test runner
+		{"py", Pytest, "py"},
+		{"rs", Cargo, "rs"},
+	}
+	for _, tt := range tests {
+		f, dir, ok := Detect(filepath.Join(root, tt.dir))
+		if !ok || f != tt.want || dir != filepath.Join(root, tt.wantRoot) {
+			t.Errorf("Detect(%s) = %s, %s, %v; want %s, %s", tt.dir, f, dir, ok, tt.want, tt.wantRoot)
+		}
+	}
+
+	if got := Root(Go, filepath.Join(root, "web/src")); got != root {
+		t.Errorf("Root(go, web/src) = %s, want %s", got, root)
+	}
+	if got, want := Root(Cargo, filepath.Join(root, "py")), filepath.Join(root, "py"); got != want {
+		t.Errorf("Root(cargo, py) = %s, want %s", got, want)
+	}
+}
+
+func TestParseFailedRunWithoutFailures(t *testing.T) {
+	// A failed run that reports no failing test, such as go finding no
+	// module, still reports a failure with the output.
+	r := &Run{Framework: Go}
+	s, err := r.Parse([]byte("go: cannot find main module\n"), nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Failures) != 1 || s.Failures[0].Message != "go: cannot find main module" {
+		t.Errorf("failures = %+v", s.Failures)
+	}
+}
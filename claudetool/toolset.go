@@ -7,6 +7,8 @@ import (
 	"sync"
 
 	"shelley.exe.dev/claudetool/browse"
+	"shelley.exe.dev/claudetool/codeindex"
+	"shelley.exe.dev/claudetool/lsp"
 	"shelley.exe.dev/llm"
 )
 
@@ -72,14 +74,30 @@ type ToolSetConfig struct {
 	// AvailableModels is the list of models the subagent can choose from.
 	// If nil, the list is built from LLMProvider.GetAvailableModels().
 	AvailableModels []AvailableModel
+	// Audit, if set, is told about every bash command, file patch and
+	// browser navigation.
+	Audit AuditFunc
+	// PostPatch configures the formatters and checks the patch tool runs
+	// on the files it writes. May be nil.
+	PostPatch *PostPatchConfig
+	// PersistentShell makes the bash tool run commands in one long-lived
+	// shell, so that shell state carries over between calls.
+	PersistentShell bool
+	// CodeIndex, if set, holds the code search indexes: the code_search
+	// tool is added, and keyword_search uses them too.
+	CodeIndex *codeindex.Indexes
+	// Jobs runs the conversation's background bash jobs. Jobs outlive the
+	// ToolSet, so the owner stops them. If nil, the ToolSet makes its own
+	// and stops its jobs in Cleanup.
+	Jobs *JobManager
 }
 
 // ToolSet holds a set of tools for a single conversation.
 // Each conversation should have its own ToolSet.
 type ToolSet struct {
-	tools   []*llm.Tool
-	cleanup func()
-	wd      *MutableWorkingDir
+	tools    []*llm.Tool
+	cleanups []func()
+	wd       *MutableWorkingDir
 }
 
 // Tools returns the tools in this set.
@@ -87,10 +105,10 @@ func (ts *ToolSet) Tools() []*llm.Tool {
 	return ts.tools
 }
 
-// Cleanup releases resources held by the tools (e.g., browser).
+// Cleanup releases resources held by the tools (e.g., browser, language servers).
 func (ts *ToolSet) Cleanup() {
-	if ts.cleanup != nil {
-		ts.cleanup()
+	for _, cleanup := range ts.cleanups {
+		cleanup()
 	}
 }
 
@@ -117,12 +135,29 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 	}
 	wd := NewMutableWorkingDir(workingDir)
 
+	var cleanups []func()
+
+	jobs := cfg.Jobs
+	if jobs == nil {
+		jobs = NewJobManager()
+		cleanups = append(cleanups, jobs.StopAll)
+	}
+
 	bashTool := &BashTool{
-		WorkingDir:       wd,
-		LLMProvider:      cfg.LLMProvider,
-		EnableJITInstall: cfg.EnableJITInstall,
-		ConversationID:   cfg.ConversationID,
+		WorkingDir:         wd,
+		LLMProvider:        cfg.LLMProvider,
+		EnableJITInstall:   cfg.EnableJITInstall,
+		ConversationID:     cfg.ConversationID,
+		Audit:              cfg.Audit,
+		Jobs:               jobs,
+		Persistent:         cfg.PersistentShell,
+		OnWorkingDirChange: cfg.OnWorkingDirChange,
+	}
+	if bashTool.Persistent {
+		cleanups = append(cleanups, bashTool.Close)
 	}
+	jobsTool := &JobsTool{Jobs: jobs, ConversationID: cfg.ConversationID}
+	runTestsTool := &RunTestsTool{WorkingDir: wd, Bash: bashTool}
 
 	// Use simplified patch schema for weaker models, full schema for sonnet/opus
 	simplified := !isStrongModel(cfg.ModelID)
@@ -130,9 +165,12 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 		Simplified:       simplified,
 		WorkingDir:       wd,
 		ClipboardEnabled: true,
+		Audit:            cfg.Audit,
+		PostPatch:        cfg.PostPatch,
 	}
 
 	keywordTool := NewKeywordToolWithWorkingDir(cfg.LLMProvider, wd)
+	keywordTool.index = cfg.CodeIndex
 
 	changeDirTool := &ChangeDirTool{
 		WorkingDir: wd,
@@ -143,11 +181,17 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 
 	tools := []*llm.Tool{
 		bashTool.Tool(),
+		jobsTool.Tool(),
+		runTestsTool.Tool(),
 		patchTool.Tool(),
 		keywordTool.Tool(),
 		changeDirTool.Tool(),
 		outputIframeTool.Tool(),
 	}
+	if cfg.CodeIndex != nil {
+		codeSearchTool := &CodeSearchTool{Indexes: cfg.CodeIndex, WorkingDir: wd}
+		tools = append(tools, codeSearchTool.Tool())
+	}
 
 	// Build the available models list (shared by subagent and llm_one_shot tools).
 	availableModels := cfg.AvailableModels
@@ -183,7 +227,15 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 		tools = append(tools, llmOneShotTool.Tool())
 	}
 
-	var cleanup func()
+	// Add the lsp tool if any language server is installed. Servers start
+	// on first use.
+	if servers := lsp.Installed(lsp.DefaultServers); len(servers) > 0 {
+		lspTool := NewLSPTool(wd, servers)
+		lspTool.Audit = cfg.Audit
+		tools = append(tools, lspTool.Tool())
+		cleanups = append(cleanups, lspTool.Close)
+	}
+
 	if cfg.EnableBrowser {
 		// Get max image dimension from the LLM service
 		maxImageDimension := 0
@@ -192,16 +244,28 @@ func NewToolSet(ctx context.Context, cfg ToolSetConfig) *ToolSet {
 				maxImageDimension = svc.MaxImageDimension()
 			}
 		}
-		browserTools, browserCleanup := browse.RegisterBrowserTools(ctx, maxImageDimension)
+		var onNavigate func(url string, err error)
+		if cfg.Audit != nil {
+			onNavigate = func(url string, err error) {
+				rec := AuditRecord{Kind: AuditBrowserNavigate, URL: url}
+				if err != nil {
+					rec.Error = err.Error()
+				}
+				cfg.Audit(rec)
+			}
+		}
+		browserTools, browserCleanup := browse.RegisterBrowserTools(ctx, maxImageDimension, onNavigate)
 		if len(browserTools) > 0 {
 			tools = append(tools, browserTools...)
 		}
-		cleanup = browserCleanup
+		if browserCleanup != nil {
+			cleanups = append(cleanups, browserCleanup)
+		}
 	}
 
 	return &ToolSet{
-		tools:   tools,
-		cleanup: cleanup,
-		wd:      wd,
+		tools:    tools,
+		cleanups: cleanups,
+		wd:       wd,
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
@@ -59,6 +60,7 @@ func (f *multiFlag) Set(value string) error {
 type clientConfig struct {
 	serverURL string
 	headers   map[string]string
+	token     string // API token for servers running with -auth
 }
 
 func (cc *clientConfig) newHTTPClient() (*http.Client, string, error) {
@@ -82,20 +84,17 @@ func (cc *clientConfig) newHTTPClient() (*http.Client, string, error) {
 	}
 }
 
-func (cc *clientConfig) newRequest(method, url string, body *strings.Reader) (*http.Request, error) {
-	var req *http.Request
-	var err error
-	if body != nil {
-		req, err = http.NewRequest(method, url, body)
-	} else {
-		req, err = http.NewRequest(method, url, nil)
-	}
+func (cc *clientConfig) newRequest(method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, url, body)
 	if err != nil {
 		return nil, err
 	}
 	if method == http.MethodPost {
 		req.Header.Set("X-Shelley-Request", "1")
 	}
+	if cc.token != "" {
+		req.Header.Set("Authorization", "Bearer "+cc.token)
+	}
 	for k, v := range cc.headers {
 		req.Header.Set(k, v)
 	}
@@ -108,6 +107,7 @@ func Run(args []string) {
 	urlFlag := fs.String("url", defaultClientURL(), "Server URL (unix:///path, http://host:port, https://host:port)")
 	var headerFlags multiFlag
 	fs.Var(&headerFlags, "H", `Extra HTTP header ("Name: Value", can be repeated)`)
+	token := fs.String("token", os.Getenv("SHELLEY_TOKEN"), "API token for servers running with -auth (default $SHELLEY_TOKEN)")
 	fs.Usage = func() {
 		fmt.Fprintf(fs.Output(), "EXPERIMENTAL: Shelley CLI client\n\n")
 		fmt.Fprintf(fs.Output(), "Usage: shelley client [flags] <subcommand> [args...]\n\n")
@@ -118,6 +118,7 @@ func Run(args []string) {
 		fmt.Fprintf(fs.Output(), "  read     Read conversation messages\n")
 		fmt.Fprintf(fs.Output(), "  list     List conversations\n")
 		fmt.Fprintf(fs.Output(), "  archive  Archive a conversation\n")
+		fmt.Fprintf(fs.Output(), "  repl     Interactive session\n")
 		fmt.Fprintf(fs.Output(), "  help     Print detailed help\n")
 	}
 	fs.Parse(args)
@@ -132,7 +133,7 @@ func Run(args []string) {
 		headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
 	}
 
-	cc := &clientConfig{serverURL: *urlFlag, headers: headers}
+	cc := &clientConfig{serverURL: *urlFlag, headers: headers, token: *token}
 
 	subArgs := fs.Args()
 	if len(subArgs) == 0 {
@@ -149,6 +150,8 @@ func Run(args []string) {
 		cmdList(cc, subArgs[1:])
 	case "archive":
 		cmdArchive(cc, subArgs[1:])
+	case "repl":
+		cmdRepl(cc, subArgs[1:])
 	case "help":
 		cmdHelp()
 	default:
@@ -371,6 +374,15 @@ func cmdList(cc *clientConfig, args []string) {
 	archived := fs.Bool("archived", false, "List archived conversations instead")
 	limit := fs.Int("limit", 50, "Maximum number of conversations to return")
 	query := fs.String("q", "", "Search query")
+	tag := fs.String("tag", "", "Only conversations with this tag")
+	cwd := fs.String("cwd", "", "Only conversations in this directory or below it")
+	repo := fs.String("repo", "", "Only conversations in the git repository rooted at this directory")
+	branch := fs.String("branch", "", "Only conversations that have been on this git branch")
+	model := fs.String("model", "", "Only conversations using this model")
+	since := fs.String("since", "", "Only conversations updated at or after this time (RFC 3339 or YYYY-MM-DD)")
+	until := fs.String("until", "", "Only conversations updated before this time (RFC 3339 or YYYY-MM-DD)")
+	pinned := fs.Bool("pinned", false, "Only pinned conversations")
+	hasSubagents := fs.Bool("has-subagents", false, "Only conversations that spawned subagents")
 	fs.Parse(args)
 
 	client, baseURL, err := cc.newHTTPClient()
@@ -384,12 +396,30 @@ func cmdList(cc *clientConfig, args []string) {
 		endpoint = "/api/conversations/archived"
 	}
 
-	params := fmt.Sprintf("?limit=%d", *limit)
-	if *query != "" {
-		params += "&q=" + url.QueryEscape(*query)
+	params := url.Values{}
+	params.Set("limit", fmt.Sprint(*limit))
+	for name, value := range map[string]string{
+		"q":      *query,
+		"tag":    *tag,
+		"cwd":    *cwd,
+		"repo":   *repo,
+		"branch": *branch,
+		"model":  *model,
+		"since":  *since,
+		"until":  *until,
+	} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	if *pinned {
+		params.Set("pinned", "true")
+	}
+	if *hasSubagents {
+		params.Set("has_subagents", "true")
 	}
 
-	req, err := cc.newRequest("GET", baseURL+endpoint+params, nil)
+	req, err := cc.newRequest("GET", baseURL+endpoint+"?"+params.Encode(), nil)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating request: %v\n", err)
 		os.Exit(1)
@@ -403,7 +433,8 @@ func cmdList(cc *clientConfig, args []string) {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		fmt.Fprintf(os.Stderr, "Error: HTTP %d\n", resp.StatusCode)
+		body, _ := io.ReadAll(resp.Body)
+		fmt.Fprintf(os.Stderr, "Error: HTTP %d: %s\n", resp.StatusCode, strings.TrimSpace(string(body)))
 		os.Exit(1)
 	}
 
@@ -415,12 +446,17 @@ func cmdList(cc *clientConfig, args []string) {
 
 	for _, conv := range conversations {
 		var c struct {
-			ConversationID string  `json:"conversation_id"`
-			Slug           *string `json:"slug"`
-			CreatedAt      string  `json:"created_at"`
-			UpdatedAt      string  `json:"updated_at"`
-			Working        bool    `json:"working"`
-			Model          *string `json:"model"`
+			ConversationID string   `json:"conversation_id"`
+			Slug           *string  `json:"slug"`
+			CreatedAt      string   `json:"created_at"`
+			UpdatedAt      string   `json:"updated_at"`
+			Working        bool     `json:"working"`
+			Model          *string  `json:"model"`
+			Cwd            *string  `json:"cwd"`
+			Pinned         bool     `json:"pinned"`
+			RepoRoot       *string  `json:"repo_root,omitempty"`
+			Tags           []string `json:"tags,omitempty"`
+			Branches       []string `json:"branches,omitempty"`
 		}
 		if json.Unmarshal(conv, &c) == nil {
 			json.NewEncoder(os.Stdout).Encode(c)
@@ -468,8 +504,31 @@ func cmdArchive(cc *clientConfig, args []string) {
 // --- Wire types for JSON parsing ---
 
 type streamResponseWire struct {
-	Messages  []messageWire `json:"messages"`
-	Heartbeat bool          `json:"heartbeat"`
+	Messages          []messageWire          `json:"messages"`
+	Conversation      *conversationWire      `json:"conversation,omitempty"`
+	ConversationState *conversationStateWire `json:"conversation_state,omitempty"`
+	Heartbeat         bool                   `json:"heartbeat"`
+	ToolProgress      *toolProgressWire      `json:"tool_progress,omitempty"`
+}
+
+type toolProgressWire struct {
+	ToolUseID string `json:"tool_use_id"`
+	ToolName  string `json:"tool_name"`
+	Output    string `json:"output"`
+	Offset    int64  `json:"offset"`
+}
+
+type conversationWire struct {
+	ConversationID string  `json:"conversation_id"`
+	Slug           *string `json:"slug"`
+	Cwd            *string `json:"cwd"`
+	Model          *string `json:"model"`
+}
+
+type conversationStateWire struct {
+	ConversationID string `json:"conversation_id"`
+	Working        bool   `json:"working"`
+	Model          string `json:"model,omitempty"`
 }
 
 type messageWire struct {
@@ -484,9 +543,14 @@ type llmMessageWire struct {
 }
 
 type llmContentWire struct {
-	Type     int    `json:"Type"`
-	Text     string `json:"Text,omitempty"`
-	ToolName string `json:"ToolName,omitempty"`
+	Type       int              `json:"Type"`
+	Text       string           `json:"Text,omitempty"`
+	ToolName   string           `json:"ToolName,omitempty"`
+	ToolInput  json.RawMessage  `json:"ToolInput,omitempty"`
+	ToolUseID  string           `json:"ToolUseID,omitempty"`
+	ToolError  bool             `json:"ToolError,omitempty"`
+	ToolResult []llmContentWire `json:"ToolResult,omitempty"`
+	Display    json.RawMessage  `json:"Display,omitempty"`
 }
 
 // Content type constants matching llm.ContentType iota values from llm/llm.go.
@@ -556,18 +620,31 @@ Subcommands:
       Read all messages in a conversation as JSON lines.
       With -wait, streams via SSE until the agent turn ends.
 
-  list [-archived] [-limit N] [-q QUERY]
-      List conversations as JSON lines.
+  list [-archived] [-limit N] [-q QUERY] [-tag TAG] [-cwd DIR] [-model MODEL]
+       [-repo DIR] [-branch BRANCH] [-since DATE] [-until DATE] [-pinned]
+       [-has-subagents]
+      List conversations as JSON lines. Pinned conversations come first.
+      DATE is RFC 3339 or YYYY-MM-DD. -cwd matches DIR and its subdirectories.
+      -repo matches the main repository root, including its worktrees.
 
   archive CONVERSATION_ID
       Archive a conversation.
 
+  repl [-model MODEL] [-cwd DIR] [-history N] [SLUG_OR_ID]
+      Interactive session. Streams the conversation live, rendering tool
+      calls and diffs. Resumes SLUG_OR_ID if given. Type /help inside the
+      REPL for commands; Ctrl-C cancels the agent while it is working.
+
   help
       Print this help text.
 
 Connecting over HTTP with auth headers:
   shelley client -url http://localhost:9999 -H "X-Exedev-Userid: user" list
 
+Connecting to a server running with -auth (create a token with
+"shelley users token" or POST /api/auth/tokens):
+  SHELLEY_TOKEN=shelley_... shelley client -url https://shelley.example.com list
+
 Examples:
   # Start a conversation and wait for the agent
   ID=$(shelley client chat -p "list files" | jq -r .conversation_id)
@@ -579,6 +656,15 @@ Examples:
   # Read current state
   shelley client read "$ID"
 
+  # Work interactively (e.g. over SSH), resuming a conversation by slug
+  shelley client repl my-conversation-slug
+
+  # Conversations tagged "release" in a repo since the start of the month
+  shelley client list -tag release -cwd ~/src/myrepo -since 2025-06-01
+
+  # All agent work on a feature branch
+  shelley client list -branch feature/login
+
 NOTE: This feature is EXPERIMENTAL and may change without notice.
 `, DefaultSocketPath())
 }
@@ -0,0 +1,964 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const replHelp = `Commands:
+  /help               Show this help
+  /new                Start a new conversation with the next message
+  /resume SLUG_OR_ID  Switch to an existing conversation
+  /cancel             Cancel the agent's current turn (also Ctrl-C)
+  /stop               Stop the running tool call; the agent carries on
+  /model [MODEL]      Show models, or switch model. Switching in an existing
+                      conversation continues in a new, distilled conversation.
+  /cwd [DIR]          Show or set the working directory for new conversations
+  /attach PATH        Upload a file and reference it in the next message
+  /info               Show the current conversation
+  /quit               Exit (also Ctrl-D)
+
+End a line with \ to continue the message on the next line.
+Start a message with // to send a line that begins with /.
+`
+
+// maxResultLines bounds how many lines of a tool result the REPL prints.
+const maxResultLines = 12
+
+// ANSI styles used when stdout is a terminal.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiDim    = "\x1b[2m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// repl is an interactive terminal session against a Shelley server.
+type repl struct {
+	cc           *clientConfig
+	client       *http.Client
+	baseURL      string
+	color        bool
+	historyLimit int
+
+	outMu sync.Mutex // serializes writes to out
+	out   io.Writer
+
+	mu            sync.Mutex
+	convID        string
+	slug          string
+	model         string
+	cwd           string
+	working       bool
+	attachments   []string
+	pendingEcho   []string // messages we sent, skipped when they come back on the stream
+	lastSeqID     int64
+	stopStream    context.CancelFunc
+	lastInterrupt time.Time
+
+	// Output of running tool calls is printed as it arrives. toolOutput
+	// holds how much of each call's output has been printed, runningTool
+	// the call that last printed some, and midLine whether it stopped
+	// partway through a line.
+	toolOutput  map[string]int64
+	runningTool string
+	midLine     bool
+}
+
+func cmdRepl(cc *clientConfig, args []string) {
+	fs := flag.NewFlagSet("client repl", flag.ExitOnError)
+	model := fs.String("model", "", "Model for new conversations (server default if empty)")
+	cwd := fs.String("cwd", "", "Working directory for new conversations")
+	history := fs.Int("history", 20, "Number of earlier messages to show when resuming")
+	fs.Parse(args)
+
+	client, baseURL, err := cc.newHTTPClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	r := &repl{
+		cc:           cc,
+		client:       client,
+		baseURL:      baseURL,
+		color:        isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "",
+		historyLimit: *history,
+		out:          os.Stdout,
+		model:        *model,
+		cwd:          *cwd,
+		lastSeqID:    -1,
+	}
+
+	r.printf("%s\n", r.style(ansiBold, "Shelley REPL")+r.style(ansiDim, " — "+cc.serverURL+" — /help for commands"))
+	if fs.NArg() > 0 {
+		if err := r.resume(fs.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	defer signal.Stop(sigCh)
+	go func() {
+		for range sigCh {
+			r.interrupt()
+		}
+	}()
+
+	r.run(os.Stdin)
+	r.stopStreaming()
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// run reads input lines until EOF or /quit.
+func (r *repl) run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var pending []string
+	r.prompt()
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasSuffix(line, `\`) {
+			pending = append(pending, strings.TrimSuffix(line, `\`))
+			continue
+		}
+		pending = append(pending, line)
+		input := strings.Join(pending, "\n")
+		pending = nil
+		if !r.handleInput(input) {
+			return
+		}
+		r.prompt()
+	}
+}
+
+// handleInput runs a command or sends a message. It returns false to exit.
+func (r *repl) handleInput(input string) bool {
+	trimmed := strings.TrimSpace(input)
+	if trimmed == "" {
+		return true
+	}
+	if strings.HasPrefix(trimmed, "//") {
+		r.send(strings.TrimPrefix(trimmed, "/"))
+		return true
+	}
+	if !strings.HasPrefix(trimmed, "/") {
+		r.send(input)
+		return true
+	}
+
+	cmd, arg, _ := strings.Cut(trimmed, " ")
+	arg = strings.TrimSpace(arg)
+	switch cmd {
+	case "/quit", "/exit":
+		return false
+	case "/help":
+		r.printf("%s", replHelp)
+	case "/new":
+		r.stopStreaming()
+		r.mu.Lock()
+		r.convID, r.slug, r.working = "", "", false
+		r.mu.Unlock()
+		r.notef("next message starts a new conversation")
+	case "/resume":
+		if arg == "" {
+			r.errorf("usage: /resume SLUG_OR_ID")
+			break
+		}
+		if err := r.resume(arg); err != nil {
+			r.errorf("%v", err)
+		}
+	case "/cancel":
+		r.cancel()
+	case "/stop":
+		r.stopTool()
+	case "/model":
+		r.switchModel(arg)
+	case "/cwd":
+		r.setCwd(arg)
+	case "/attach":
+		if arg == "" {
+			r.errorf("usage: /attach PATH")
+			break
+		}
+		r.attach(arg)
+	case "/info":
+		r.info()
+	default:
+		r.errorf("unknown command %s (try /help)", cmd)
+	}
+	return true
+}
+
+// interrupt handles Ctrl-C: cancel a working agent, otherwise exit on a
+// second press.
+func (r *repl) interrupt() {
+	r.mu.Lock()
+	working := r.working && r.convID != ""
+	again := time.Since(r.lastInterrupt) < 2*time.Second
+	r.lastInterrupt = time.Now()
+	r.mu.Unlock()
+
+	if working {
+		r.printf("\n")
+		r.cancel()
+		return
+	}
+	if again {
+		r.printf("\n")
+		os.Exit(130)
+	}
+	r.notef("press Ctrl-C again or type /quit to exit")
+}
+
+func (r *repl) send(text string) {
+	r.mu.Lock()
+	convID, model, cwd := r.convID, r.model, r.cwd
+	for _, path := range r.attachments {
+		text += "\n[" + path + "]"
+	}
+	r.attachments = nil
+	r.pendingEcho = append(r.pendingEcho, text)
+	r.working = true
+	r.mu.Unlock()
+
+	body := map[string]string{"message": text}
+	if model != "" {
+		body["model"] = model
+	}
+
+	if convID != "" {
+		if err := r.postJSON("/api/conversation/"+convID+"/chat", body, nil); err != nil {
+			r.setWorking(false)
+			r.errorf("%v", err)
+		}
+		return
+	}
+
+	if cwd != "" {
+		body["cwd"] = cwd
+	}
+	var resp struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	if err := r.postJSON("/api/conversations/new", body, &resp); err != nil {
+		r.setWorking(false)
+		r.errorf("%v", err)
+		return
+	}
+	r.mu.Lock()
+	r.convID = resp.ConversationID
+	r.mu.Unlock()
+	r.notef("conversation %s", resp.ConversationID)
+	r.startStreaming()
+}
+
+func (r *repl) cancel() {
+	r.mu.Lock()
+	convID := r.convID
+	r.mu.Unlock()
+	if convID == "" {
+		r.notef("nothing to cancel")
+		return
+	}
+	if err := r.postJSON("/api/conversation/"+convID+"/cancel", nil, nil); err != nil {
+		r.errorf("cancel: %v", err)
+		return
+	}
+	r.notef("cancelled")
+}
+
+// stopTool cancels the running tool call, leaving the rest of the turn
+// alone.
+func (r *repl) stopTool() {
+	r.mu.Lock()
+	convID, toolUseID := r.convID, r.runningTool
+	r.mu.Unlock()
+	if convID == "" || toolUseID == "" {
+		r.notef("no tool call is running")
+		return
+	}
+	if err := r.postJSON("/api/conversation/"+convID+"/tools/"+url.PathEscape(toolUseID)+"/cancel", nil, nil); err != nil {
+		r.errorf("stop: %v", err)
+		return
+	}
+	r.notef("stopped the tool call")
+}
+
+// resume switches to the conversation with the given slug or ID.
+func (r *repl) resume(ref string) error {
+	var conv conversationWire
+	err := r.getJSON("/api/conversation-by-slug/"+url.PathEscape(ref), &conv)
+	if errors.Is(err, errNotFound) {
+		var sr streamResponseWire
+		err = r.getJSON("/api/conversation/"+url.PathEscape(ref), &sr)
+		if errors.Is(err, errNotFound) || (err == nil && sr.Conversation == nil) {
+			return fmt.Errorf("no conversation with slug or ID %q", ref)
+		}
+		if err == nil {
+			conv = *sr.Conversation
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	r.stopStreaming()
+	r.mu.Lock()
+	r.convID = conv.ConversationID
+	r.slug = deref(conv.Slug)
+	if conv.Model != nil {
+		r.model = *conv.Model
+	}
+	if conv.Cwd != nil {
+		r.cwd = *conv.Cwd
+	}
+	r.working = false
+	r.mu.Unlock()
+
+	r.notef("resumed %s", describeConversation(conv.ConversationID, deref(conv.Slug)))
+	r.startStreaming()
+	return nil
+}
+
+// switchModel lists models, sets the model for a new conversation, or
+// continues the current conversation in a distilled copy using the model,
+// since a conversation's model is fixed once it has started.
+func (r *repl) switchModel(model string) {
+	r.mu.Lock()
+	convID, current, cwd := r.convID, r.model, r.cwd
+	r.mu.Unlock()
+
+	if model == "" {
+		var models []struct {
+			ID          string `json:"id"`
+			DisplayName string `json:"display_name"`
+			Ready       bool   `json:"ready"`
+		}
+		if err := r.getJSON("/api/models", &models); err != nil {
+			r.errorf("%v", err)
+			return
+		}
+		for _, m := range models {
+			marker := "  "
+			if m.ID == current {
+				marker = "* "
+			}
+			line := marker + m.ID
+			if m.DisplayName != "" && m.DisplayName != m.ID {
+				line += " (" + m.DisplayName + ")"
+			}
+			if !m.Ready {
+				line += r.style(ansiDim, " [not ready]")
+			}
+			r.printf("%s\n", line)
+		}
+		return
+	}
+
+	if model == current {
+		r.notef("already using %s", model)
+		return
+	}
+	if convID == "" {
+		r.mu.Lock()
+		r.model = model
+		r.mu.Unlock()
+		r.notef("new conversations will use %s", model)
+		return
+	}
+
+	body := map[string]string{"source_conversation_id": convID, "model": model}
+	if cwd != "" {
+		body["cwd"] = cwd
+	}
+	var resp struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	if err := r.postJSON("/api/conversations/distill", body, &resp); err != nil {
+		r.errorf("switch model: %v", err)
+		return
+	}
+
+	r.stopStreaming()
+	r.mu.Lock()
+	r.convID, r.slug, r.model, r.working = resp.ConversationID, "", model, false
+	r.mu.Unlock()
+	r.notef("continuing in %s with %s (distilling context from %s)", resp.ConversationID, model, convID)
+	r.startStreaming()
+}
+
+func (r *repl) setCwd(dir string) {
+	if dir == "" {
+		r.mu.Lock()
+		cwd := r.cwd
+		r.mu.Unlock()
+		if cwd == "" {
+			cwd = "(server default)"
+		}
+		r.notef("cwd: %s", cwd)
+		return
+	}
+	if abs, err := filepath.Abs(dir); err == nil && !strings.HasPrefix(dir, "~") {
+		dir = abs
+	}
+	r.mu.Lock()
+	r.cwd = dir
+	inConversation := r.convID != ""
+	r.mu.Unlock()
+	if inConversation {
+		r.notef("cwd %s applies to new conversations (/new)", dir)
+	} else {
+		r.notef("cwd: %s", dir)
+	}
+}
+
+// attach uploads a local file and queues its server path for the next message.
+func (r *repl) attach(path string) {
+	path = expandHome(path)
+	f, err := os.Open(path)
+	if err != nil {
+		r.errorf("%v", err)
+		return
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	part, err := mw.CreateFormFile("file", filepath.Base(path))
+	if err == nil {
+		_, err = io.Copy(part, f)
+	}
+	if err == nil {
+		err = mw.Close()
+	}
+	if err != nil {
+		r.errorf("attach: %v", err)
+		return
+	}
+
+	req, err := r.cc.newRequest("POST", r.baseURL+"/api/upload", &buf)
+	if err != nil {
+		r.errorf("attach: %v", err)
+		return
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	var resp struct {
+		Path string `json:"path"`
+	}
+	if err := r.do(req, &resp); err != nil {
+		r.errorf("attach: %v", err)
+		return
+	}
+
+	r.mu.Lock()
+	r.attachments = append(r.attachments, resp.Path)
+	r.mu.Unlock()
+	r.notef("attached %s (sent with your next message)", path)
+}
+
+func (r *repl) info() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.convID == "" {
+		r.notef("no conversation yet; model %s, cwd %s", orDefault(r.model), orDefault(r.cwd))
+		return
+	}
+	state := "idle"
+	if r.working {
+		state = "working"
+	}
+	r.notef("%s; model %s, cwd %s, %s", describeConversation(r.convID, r.slug), orDefault(r.model), orDefault(r.cwd), state)
+}
+
+func (r *repl) setWorking(working bool) {
+	r.mu.Lock()
+	r.working = working
+	r.mu.Unlock()
+}
+
+// --- Streaming ---
+
+func (r *repl) startStreaming() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.mu.Lock()
+	if r.stopStream != nil {
+		r.stopStream()
+	}
+	r.stopStream = cancel
+	r.lastSeqID = -1
+	convID := r.convID
+	r.mu.Unlock()
+	go r.stream(ctx, convID)
+}
+
+func (r *repl) stopStreaming() {
+	r.mu.Lock()
+	cancel := r.stopStream
+	r.stopStream = nil
+	r.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// stream follows the conversation's SSE stream, reconnecting (and resuming
+// from the last seen message) until ctx is cancelled.
+func (r *repl) stream(ctx context.Context, convID string) {
+	for {
+		err := r.streamOnce(ctx, convID)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			r.notef("stream disconnected (%v); reconnecting", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+func (r *repl) streamOnce(ctx context.Context, convID string) error {
+	r.mu.Lock()
+	lastSeqID := r.lastSeqID
+	r.mu.Unlock()
+
+	endpoint := r.baseURL + "/api/conversation/" + convID + "/stream"
+	if lastSeqID >= 0 {
+		endpoint += fmt.Sprintf("?last_sequence_id=%d", lastSeqID)
+	}
+	req, err := r.cc.newRequest("GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	first := lastSeqID < 0
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var sr streamResponseWire
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &sr); err != nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		r.handleStream(convID, sr, first)
+		first = false
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func (r *repl) handleStream(convID string, sr streamResponseWire, first bool) {
+	if sr.Conversation != nil && sr.Conversation.ConversationID == convID && sr.Conversation.Slug != nil {
+		r.mu.Lock()
+		r.slug = *sr.Conversation.Slug
+		r.mu.Unlock()
+	}
+	if st := sr.ConversationState; st != nil && st.ConversationID == convID {
+		r.setWorking(st.Working)
+	}
+	if p := sr.ToolProgress; p != nil {
+		if out := r.renderToolProgress(p); out != "" {
+			r.printf("%s", out)
+		}
+	}
+
+	messages := sr.Messages
+	if first && r.historyLimit >= 0 && len(messages) > r.historyLimit {
+		skipped := len(messages) - r.historyLimit
+		messages = messages[skipped:]
+		r.notef("… %d earlier messages", skipped)
+	}
+
+	for _, msg := range messages {
+		r.mu.Lock()
+		if msg.SequenceID <= r.lastSeqID {
+			r.mu.Unlock()
+			continue
+		}
+		r.lastSeqID = msg.SequenceID
+		r.mu.Unlock()
+
+		if out := r.renderMessage(msg); out != "" {
+			r.printf("%s", out)
+		}
+		if (msg.Type == "agent" || msg.Type == "error") && msg.EndOfTurn != nil && *msg.EndOfTurn {
+			r.setWorking(false)
+			r.prompt()
+		}
+	}
+	if n := len(sr.Messages); n > 0 {
+		r.mu.Lock()
+		if last := sr.Messages[n-1].SequenceID; last > r.lastSeqID {
+			r.lastSeqID = last
+		}
+		r.mu.Unlock()
+	}
+}
+
+// --- Rendering ---
+
+// renderMessage renders a conversation message for the terminal.
+func (r *repl) renderMessage(msg messageWire) string {
+	if msg.Type == "system" || msg.LlmData == nil {
+		return ""
+	}
+	var llmMsg llmMessageWire
+	if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, c := range llmMsg.Content {
+		switch c.Type {
+		case contentTypeText:
+			if c.Text == "" {
+				continue
+			}
+			switch msg.Type {
+			case "user":
+				if r.consumeEcho(c.Text) {
+					continue
+				}
+				b.WriteString(r.style(ansiBold, "you› ") + c.Text + "\n")
+			case "error":
+				b.WriteString(r.style(ansiRed, "error: "+c.Text) + "\n")
+			case "gitinfo":
+				b.WriteString(r.style(ansiDim, "git: "+c.Text) + "\n")
+			default:
+				b.WriteString(c.Text + "\n")
+			}
+		case contentTypeToolUse:
+			b.WriteString(r.style(ansiCyan, "▶ "+c.ToolName) + " " + summarizeToolInput(c.ToolInput) + "\n")
+		case contentTypeToolResult:
+			b.WriteString(r.renderToolResult(c))
+		}
+	}
+	return b.String()
+}
+
+// consumeEcho reports whether text is a message this REPL sent, so it is not
+// printed a second time when it comes back on the stream.
+func (r *repl) consumeEcho(text string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, sent := range r.pendingEcho {
+		if sent == text {
+			r.pendingEcho = append(r.pendingEcho[:i], r.pendingEcho[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// renderToolProgress renders output of a running tool call that hasn't
+// been printed yet. A chunk may repeat output already seen, when the stream
+// reconnects and catches up.
+func (r *repl) renderToolProgress(p *toolProgressWire) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.toolOutput == nil {
+		r.toolOutput = make(map[string]int64)
+	}
+	printed, seen := r.toolOutput[p.ToolUseID]
+	end := p.Offset + int64(len(p.Output))
+	if end <= printed {
+		return ""
+	}
+	var b strings.Builder
+	chunk := p.Output
+	switch {
+	case p.Offset < printed:
+		chunk = chunk[printed-p.Offset:]
+	case p.Offset > printed:
+		if r.midLine {
+			b.WriteString("\n")
+			r.midLine = false
+		}
+		b.WriteString(r.style(ansiDim, "  │ …") + "\n")
+	}
+	if r.midLine && (!seen || r.runningTool != p.ToolUseID) {
+		b.WriteString("\n")
+		r.midLine = false
+	}
+	r.toolOutput[p.ToolUseID] = end
+	r.runningTool = p.ToolUseID
+
+	for line := range strings.Lines(chunk) {
+		text, complete := strings.CutSuffix(line, "\n")
+		if !r.midLine {
+			text = "  │ " + text
+		}
+		b.WriteString(r.style(ansiDim, text))
+		if complete {
+			b.WriteString("\n")
+		}
+		r.midLine = !complete
+	}
+	return b.String()
+}
+
+func (r *repl) renderToolResult(c llmContentWire) string {
+	var b strings.Builder
+
+	// If the output was printed as it arrived, only the outcome is left.
+	r.mu.Lock()
+	_, streamed := r.toolOutput[c.ToolUseID]
+	delete(r.toolOutput, c.ToolUseID)
+	if streamed && r.midLine {
+		b.WriteString("\n")
+		r.midLine = false
+	}
+	if r.runningTool == c.ToolUseID {
+		r.runningTool = ""
+	}
+	r.mu.Unlock()
+
+	var display struct {
+		Path string `json:"path"`
+		Diff string `json:"diff"`
+	}
+	if len(c.Display) > 0 && json.Unmarshal(c.Display, &display) == nil && display.Diff != "" {
+		b.WriteString(r.renderDiff(display.Diff))
+	}
+
+	var texts []string
+	for _, rc := range c.ToolResult {
+		if rc.Type == contentTypeText && rc.Text != "" {
+			texts = append(texts, rc.Text)
+		}
+	}
+	text := strings.TrimRight(strings.Join(texts, "\n"), "\n")
+	if streamed {
+		if c.ToolError {
+			first, _, _ := strings.Cut(text, "\n")
+			b.WriteString(r.style(ansiRed, "✗ "+first) + "\n")
+		}
+		return b.String()
+	}
+	if c.ToolError {
+		b.WriteString(r.style(ansiRed, indent(truncateLines(text, maxResultLines), "✗ ")) + "\n")
+	} else if display.Diff == "" && text != "" {
+		b.WriteString(r.style(ansiDim, indent(truncateLines(text, maxResultLines), "  ")) + "\n")
+	}
+	return b.String()
+}
+
+// renderDiff colors a unified diff.
+func (r *repl) renderDiff(diff string) string {
+	var b strings.Builder
+	for line := range strings.Lines(diff) {
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			line = r.style(ansiBold, line)
+		case strings.HasPrefix(line, "+"):
+			line = r.style(ansiGreen, line)
+		case strings.HasPrefix(line, "-"):
+			line = r.style(ansiRed, line)
+		case strings.HasPrefix(line, "@@"):
+			line = r.style(ansiCyan, line)
+		}
+		b.WriteString("  " + line + "\n")
+	}
+	return b.String()
+}
+
+// summarizeToolInput returns a one-line description of a tool call's input,
+// preferring the fields that identify what the tool is acting on.
+func summarizeToolInput(input json.RawMessage) string {
+	var fields map[string]any
+	if err := json.Unmarshal(input, &fields); err != nil {
+		return truncate(string(input), 120)
+	}
+	for _, key := range []string{"command", "path", "url", "query", "pattern", "task", "prompt"} {
+		if s, ok := fields[key].(string); ok && s != "" {
+			first, rest, multiline := strings.Cut(strings.TrimSpace(s), "\n")
+			if multiline && rest != "" {
+				first += " …"
+			}
+			return truncate(first, 120)
+		}
+	}
+	compact, _ := json.Marshal(fields)
+	return truncate(string(compact), 120)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-1] + "…"
+}
+
+func truncateLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) <= n {
+		return s
+	}
+	return strings.Join(lines[:n], "\n") + fmt.Sprintf("\n… (%d more lines)", len(lines)-n)
+}
+
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+strings.Repeat(" ", len([]rune(prefix))))
+}
+
+func (r *repl) style(code, s string) string {
+	if !r.color {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func (r *repl) printf(format string, args ...any) {
+	r.outMu.Lock()
+	defer r.outMu.Unlock()
+	fmt.Fprintf(r.out, format, args...)
+}
+
+func (r *repl) notef(format string, args ...any) {
+	r.printf("%s\n", r.style(ansiDim, "· "+fmt.Sprintf(format, args...)))
+}
+
+func (r *repl) errorf(format string, args ...any) {
+	r.printf("%s\n", r.style(ansiRed, "! "+fmt.Sprintf(format, args...)))
+}
+
+func (r *repl) prompt() {
+	r.mu.Lock()
+	working := r.working
+	r.mu.Unlock()
+	if working {
+		return
+	}
+	r.printf("%s", r.style(ansiYellow, "› "))
+}
+
+func describeConversation(id, slug string) string {
+	if slug == "" {
+		return id
+	}
+	return slug + " (" + id + ")"
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func orDefault(s string) string {
+	if s == "" {
+		return "(server default)"
+	}
+	return s
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// --- HTTP helpers ---
+
+var errNotFound = errors.New("not found")
+
+func (r *repl) getJSON(path string, out any) error {
+	req, err := r.cc.newRequest("GET", r.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	return r.do(req, out)
+}
+
+func (r *repl) postJSON(path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := r.cc.newRequest("POST", r.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return r.do(req, out)
+}
+
+func (r *repl) do(req *http.Request, out any) error {
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
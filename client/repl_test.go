@@ -0,0 +1,104 @@
+package client
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func wireMessage(t *testing.T, typ string, content ...map[string]any) messageWire {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"Content": content})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(data)
+	return messageWire{Type: typ, LlmData: &s}
+}
+
+func TestReplRenderMessage(t *testing.T) {
+	r := &repl{}
+
+	got := r.renderMessage(wireMessage(t, "agent",
+		map[string]any{"Type": contentTypeText, "Text": "Let me look."},
+		map[string]any{"Type": contentTypeToolUse, "ToolName": "bash", "ToolInput": map[string]any{"command": "ls -la\npwd"}},
+	))
+	if want := "Let me look.\n▶ bash ls -la …\n"; got != want {
+		t.Errorf("agent message: got %q, want %q", got, want)
+	}
+
+	got = r.renderMessage(wireMessage(t, "tool", map[string]any{
+		"Type":       contentTypeToolResult,
+		"ToolResult": []map[string]any{{"Type": contentTypeText, "Text": "<patches_applied>all</patches_applied>"}},
+		"Display":    map[string]any{"path": "a.go", "diff": "--- a.go\n+++ a.go\n@@ -1 +1 @@\n-old\n+new\n"},
+	}))
+	if want := "  --- a.go\n  +++ a.go\n  @@ -1 +1 @@\n  -old\n  +new\n"; got != want {
+		t.Errorf("patch result: got %q, want %q", got, want)
+	}
+
+	got = r.renderMessage(wireMessage(t, "tool", map[string]any{
+		"Type":       contentTypeToolResult,
+		"ToolError":  true,
+		"ToolResult": []map[string]any{{"Type": contentTypeText, "Text": "exit status 1"}},
+	}))
+	if want := "✗ exit status 1\n"; got != want {
+		t.Errorf("tool error: got %q, want %q", got, want)
+	}
+
+	// Messages sent from this REPL are not echoed back, but others are shown.
+	r.pendingEcho = []string{"hello"}
+	got = r.renderMessage(wireMessage(t, "user", map[string]any{"Type": contentTypeText, "Text": "hello"}))
+	if got != "" {
+		t.Errorf("expected own message to be suppressed, got %q", got)
+	}
+	got = r.renderMessage(wireMessage(t, "user", map[string]any{"Type": contentTypeText, "Text": "hello"}))
+	if want := "you› hello\n"; got != want {
+		t.Errorf("user message: got %q, want %q", got, want)
+	}
+}
+
+func TestTruncateLines(t *testing.T) {
+	s := strings.Repeat("line\n", 20)
+	got := truncateLines(strings.TrimSuffix(s, "\n"), 3)
+	if want := "line\nline\nline\n… (17 more lines)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestReplToolProgress(t *testing.T) {
+	r := &repl{}
+	progress := func(offset int64, output string) string {
+		return r.renderToolProgress(&toolProgressWire{ToolUseID: "t1", ToolName: "bash", Output: output, Offset: offset})
+	}
+
+	if got, want := progress(0, "one\ntw"), "  │ one\n  │ tw"; got != want {
+		t.Errorf("first chunk: got %q, want %q", got, want)
+	}
+	if got, want := progress(6, "o\n"), "o\n"; got != want {
+		t.Errorf("rest of the line: got %q, want %q", got, want)
+	}
+	// A catch-up snapshot after reconnecting only adds what is new.
+	if got, want := progress(4, "two\nthree\n"), "  │ three\n"; got != want {
+		t.Errorf("overlapping chunk: got %q, want %q", got, want)
+	}
+	if got := progress(0, "one\n"); got != "" {
+		t.Errorf("repeated chunk: got %q", got)
+	}
+	if r.runningTool != "t1" {
+		t.Errorf("running tool = %q", r.runningTool)
+	}
+
+	// The streamed output isn't printed again with the result.
+	got := r.renderMessage(wireMessage(t, "tool", map[string]any{
+		"Type":       contentTypeToolResult,
+		"ToolUseID":  "t1",
+		"ToolError":  true,
+		"ToolResult": []map[string]any{{"Type": contentTypeText, "Text": "the user cancelled this tool call\none\ntwo\nthree\n"}},
+	}))
+	if want := "✗ the user cancelled this tool call\n"; got != want {
+		t.Errorf("result: got %q, want %q", got, want)
+	}
+	if r.runningTool != "" || len(r.toolOutput) != 0 {
+		t.Errorf("tool call still tracked: %q, %v", r.runningTool, r.toolOutput)
+	}
+}
@@ -97,21 +97,26 @@ type conversationStateForTS struct {
 }
 
 type conversationWithStateForTS struct {
-	ConversationID       string  `json:"conversation_id"`
-	Slug                 *string `json:"slug"`
-	UserInitiated        bool    `json:"user_initiated"`
-	CreatedAt            string  `json:"created_at"`
-	UpdatedAt            string  `json:"updated_at"`
-	Cwd                  *string `json:"cwd"`
-	Archived             bool    `json:"archived"`
-	ParentConversationID *string `json:"parent_conversation_id"`
-	Model                *string `json:"model"`
-	Working              bool    `json:"working"`
-	GitRepoRoot          string  `json:"git_repo_root,omitempty"`
-	GitWorktreeRoot      string  `json:"git_worktree_root,omitempty"`
-	GitCommit            string  `json:"git_commit,omitempty"`
-	GitSubject           string  `json:"git_subject,omitempty"`
-	SubagentCount        int64   `json:"subagent_count"`
+	ConversationID       string   `json:"conversation_id"`
+	Slug                 *string  `json:"slug"`
+	UserInitiated        bool     `json:"user_initiated"`
+	CreatedAt            string   `json:"created_at"`
+	UpdatedAt            string   `json:"updated_at"`
+	Cwd                  *string  `json:"cwd"`
+	Archived             bool     `json:"archived"`
+	ParentConversationID *string  `json:"parent_conversation_id"`
+	Model                *string  `json:"model"`
+	Pinned               bool     `json:"pinned"`
+	RepoRoot             *string  `json:"repo_root"`
+	RepoWorktree         *string  `json:"repo_worktree"`
+	Working              bool     `json:"working"`
+	GitRepoRoot          string   `json:"git_repo_root,omitempty"`
+	GitWorktreeRoot      string   `json:"git_worktree_root,omitempty"`
+	GitCommit            string   `json:"git_commit,omitempty"`
+	GitSubject           string   `json:"git_subject,omitempty"`
+	SubagentCount        int64    `json:"subagent_count"`
+	Tags                 []string `json:"tags,omitempty"`
+	Branches             []string `json:"branches,omitempty"`
 }
 
 type streamResponseForTS struct {
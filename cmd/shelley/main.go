@@ -12,9 +12,11 @@ import (
 	"strings"
 
 	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/claudetool/codeindex"
 	"shelley.exe.dev/client"
 	"shelley.exe.dev/db"
 	"shelley.exe.dev/models"
+	"shelley.exe.dev/redact"
 	"shelley.exe.dev/server"
 	_ "shelley.exe.dev/server/notifications/channels" // register channel types
 	"shelley.exe.dev/templates"
@@ -49,7 +51,9 @@ func main() {
 		flag.PrintDefaults()
 		fmt.Fprintf(flag.CommandLine.Output(), "\nCommands:\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  serve [flags]                 Start the web server\n")
-		fmt.Fprintf(flag.CommandLine.Output(), "  client [flags] <subcommand>   CLI client (chat, read, list, archive) (experimental)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  run -p PROMPT [flags]         Run one prompt headlessly and print a JSON summary\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  client [flags] <subcommand>   CLI client (chat, read, list, archive, repl) (experimental)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  users <subcommand>            Manage users and API tokens for -auth\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  unpack-template <name> <dir>  Unpack a project template to a directory\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "  version                       Print version information as JSON\n")
 		fmt.Fprintf(flag.CommandLine.Output(), "\nUse '%s <command> -h' for command-specific help\n", os.Args[0])
@@ -68,8 +72,12 @@ func main() {
 	switch command {
 	case "serve":
 		runServe(global, args[1:])
+	case "run":
+		runRun(global, args[1:])
 	case "client":
 		client.Run(args[1:])
+	case "users":
+		runUsers(global, args[1:])
 	case "unpack-template":
 		runUnpackTemplate(args[1:])
 	case "version":
@@ -88,6 +96,9 @@ func runServe(global GlobalConfig, args []string) {
 	systemdActivation := fs.Bool("systemd-activation", false, "Use systemd socket activation (listen on fd from systemd)")
 	requireHeader := fs.String("require-header", "", "Require this header on all API requests (e.g., X-Exedev-Userid)")
 	socketPath := fs.String("socket", client.DefaultSocketPath(), "Path to Unix socket for local CLI client access (set to 'none' to disable)")
+	auth := fs.Bool("auth", false, "Require users to sign in (see 'shelley users'); OIDC is configured under \"auth\" in shelley.json")
+	multiUser := fs.Bool("multi-user", false, "Give conversations owners and limit users to their own and shared ones (needs -auth or -require-header)")
+	admins := fs.String("admins", "", "Comma-separated emails of multi-user admins, who manage server-wide settings and budgets")
 	fs.Parse(args)
 
 	logger := setupLogging(global.Debug)
@@ -108,10 +119,39 @@ func runServe(global GlobalConfig, args []string) {
 	availableModels := llmManager.GetAvailableModels()
 	logger.Info("Available models", "models", strings.Join(availableModels, ", "))
 
-	toolSetConfig := setupToolSetConfig(llmManager, llmManager)
+	svr := setupServer(global, logger, database, llmConfig, llmManager, "", *requireHeader)
 
-	// Create server
-	svr := server.NewServer(database, llmManager, toolSetConfig, logger, global.PredictableOnly, llmConfig.TerminalURL, llmConfig.DefaultModel, *requireHeader, llmConfig.Links)
+	if *auth {
+		if oidc := llmConfig.Auth.OIDC; oidc != nil {
+			if err := oidc.Validate(); err != nil {
+				logger.Error("Invalid auth configuration", "error", err)
+				os.Exit(1)
+			}
+		}
+		users, err := database.ListUsers(context.Background())
+		if err != nil {
+			logger.Error("Failed to list users", "error", err)
+			os.Exit(1)
+		}
+		if len(users) == 0 && llmConfig.Auth.OIDC == nil {
+			logger.Warn("Authentication is enabled but there are no users; add one with 'shelley users add'")
+		}
+		svr.EnableAuth(llmConfig.Auth)
+		logger.Info("Authentication enabled", "users", len(users), "oidc", llmConfig.Auth.OIDC != nil)
+	} else if llmConfig.Auth.OIDC != nil {
+		logger.Warn("OIDC is configured but -auth is not set; authentication is disabled")
+	}
+
+	if *multiUser {
+		if !*auth && *requireHeader == "" {
+			logger.Error("-multi-user needs -auth or -require-header to know who each user is")
+			os.Exit(1)
+		}
+		svr.EnableMultiUser(strings.Split(*admins, ","))
+		logger.Info("Multi-user mode enabled", "admins", *admins)
+	} else if *admins != "" {
+		logger.Warn("-admins has no effect without -multi-user")
+	}
 
 	// Seed notification channels from config file if DB is empty (one-time migration)
 	svr.SeedNotificationChannelsFromConfig(llmConfig.NotificationChannels)
@@ -155,6 +195,37 @@ func runServe(global GlobalConfig, args []string) {
 	}
 }
 
+// setupServer creates the server that serve and run share, with the tools
+// and secret redaction configured in llmConfig. The agent works in
+// workingDir, or in the current directory if it is empty.
+func setupServer(global GlobalConfig, logger *slog.Logger, database *db.DB, llmConfig *server.LLMConfig, llmManager server.LLMProvider, workingDir, requireHeader string) *server.Server {
+	toolSetConfig := setupToolSetConfig(llmManager, llmManager)
+	if workingDir != "" {
+		toolSetConfig.WorkingDir = workingDir
+	}
+	toolSetConfig.PostPatch = llmConfig.PostPatch
+	toolSetConfig.PersistentShell = llmConfig.PersistentShell
+	if llmConfig.CodeIndex != nil {
+		indexes, err := codeindex.New(*llmConfig.CodeIndex)
+		if err != nil {
+			logger.Error("Invalid code index configuration", "error", err)
+			os.Exit(1)
+		}
+		toolSetConfig.CodeIndex = indexes
+	}
+
+	svr := server.NewServer(database, llmManager, toolSetConfig, logger, global.PredictableOnly, llmConfig.TerminalURL, llmConfig.DefaultModel, requireHeader, llmConfig.Links)
+
+	if err := svr.EnableRedaction(llmConfig.Redaction); err != nil {
+		logger.Error("Invalid redaction configuration", "error", err)
+		os.Exit(1)
+	}
+	if llmConfig.Redaction.Disabled {
+		logger.Warn("Secret redaction is disabled; tool output reaches the LLM and the database as is")
+	}
+	return svr
+}
+
 func setupLogging(debug bool) *slog.Logger {
 	logLevel := slog.LevelInfo
 	if debug {
@@ -305,11 +376,16 @@ func buildLLMConfig(logger *slog.Logger, configPath, terminalURL, defaultModel s
 		}
 
 		var cfg struct {
-			LLMGateway           string           `json:"llm_gateway"`
-			TerminalURL          string           `json:"terminal_url"`
-			DefaultModel         string           `json:"default_model"`
-			Links                []server.Link    `json:"links"`
-			NotificationChannels []map[string]any `json:"notification_channels"`
+			LLMGateway           string                      `json:"llm_gateway"`
+			TerminalURL          string                      `json:"terminal_url"`
+			DefaultModel         string                      `json:"default_model"`
+			Links                []server.Link               `json:"links"`
+			NotificationChannels []map[string]any            `json:"notification_channels"`
+			Auth                 server.AuthConfig           `json:"auth"`
+			Redaction            redact.Config               `json:"redaction"`
+			PostPatch            *claudetool.PostPatchConfig `json:"post_patch"`
+			PersistentShell      bool                        `json:"persistent_shell"`
+			CodeIndex            *codeindex.Config           `json:"code_index"`
 		}
 		if err := json.Unmarshal(data, &cfg); err != nil {
 			logger.Warn("Failed to parse config file", "path", configPath, "error", err)
@@ -358,6 +434,18 @@ func buildLLMConfig(logger *slog.Logger, configPath, terminalURL, defaultModel s
 			llmCfg.NotificationChannels = cfg.NotificationChannels
 			logger.Info("Notification channels configured", "count", len(cfg.NotificationChannels))
 		}
+
+		llmCfg.Auth = cfg.Auth
+		llmCfg.Redaction = cfg.Redaction
+		if cfg.PostPatch != nil {
+			llmCfg.PostPatch = cfg.PostPatch
+			logger.Info("Post-patch hooks configured", "count", len(cfg.PostPatch.Hooks))
+		}
+		llmCfg.PersistentShell = cfg.PersistentShell
+		if cfg.CodeIndex != nil {
+			llmCfg.CodeIndex = cfg.CodeIndex
+			logger.Info("Code index enabled", "embeddings", cfg.CodeIndex.Embeddings)
+		}
 	}
 
 	return llmCfg
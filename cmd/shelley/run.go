@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"shelley.exe.dev/server"
+)
+
+// Exit codes for `shelley run`.
+const (
+	runExitSuccess        = 0
+	runExitError          = 1
+	runExitTimeout        = 3
+	runExitBudgetExceeded = 4
+)
+
+// runExitCode maps a headless run status to the process exit code.
+func runExitCode(status server.HeadlessStatus) int {
+	switch status {
+	case server.HeadlessStatusSuccess:
+		return runExitSuccess
+	case server.HeadlessStatusTimeout:
+		return runExitTimeout
+	case server.HeadlessStatusBudgetExceeded:
+		return runExitBudgetExceeded
+	default:
+		return runExitError
+	}
+}
+
+// runRun runs a single prompt to completion without a UI and prints a JSON summary.
+func runRun(global GlobalConfig, args []string) {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	prompt := fs.String("p", "", "Prompt to send (use '-' to read it from stdin)")
+	cwd := fs.String("cwd", "", "Working directory for the agent (default: current directory)")
+	model := fs.String("model", "", "Model to use (default: the server default model)")
+	timeout := fs.Duration("timeout", 0, "Cancel the run after this long (e.g. 30m; 0 means no timeout)")
+	maxCost := fs.Float64("max-cost", 0, "Cancel the run once it has cost more than this many USD (0 means no budget)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: shelley [global-flags] run -p PROMPT [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Runs one agent turn headlessly and prints a JSON summary to stdout.\n")
+		fmt.Fprintf(fs.Output(), "Logs go to stderr.\n\n")
+		fmt.Fprintf(fs.Output(), "Flags:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nExit status:\n")
+		fmt.Fprintf(fs.Output(), "  %d  the agent finished its turn\n", runExitSuccess)
+		fmt.Fprintf(fs.Output(), "  %d  the run failed (LLM error, bad arguments, interrupted)\n", runExitError)
+		fmt.Fprintf(fs.Output(), "  %d  -timeout was reached\n", runExitTimeout)
+		fmt.Fprintf(fs.Output(), "  %d  -max-cost was exceeded\n", runExitBudgetExceeded)
+	}
+	fs.Parse(args)
+
+	text := *prompt
+	if text == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading prompt from stdin: %v\n", err)
+			os.Exit(runExitError)
+		}
+		text = string(data)
+	}
+	if text == "" {
+		fs.Usage()
+		os.Exit(runExitError)
+	}
+
+	dir := *cwd
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting working directory: %v\n", err)
+			os.Exit(runExitError)
+		}
+		dir = wd
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving -cwd: %v\n", err)
+		os.Exit(runExitError)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		fmt.Fprintf(os.Stderr, "Error: -cwd %q is not a directory\n", dir)
+		os.Exit(runExitError)
+	}
+
+	// Stdout is reserved for the JSON summary.
+	logLevel := slog.LevelWarn
+	if global.Debug {
+		logLevel = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+	slog.SetDefault(logger)
+
+	database := setupDatabase(global.DBPath, logger)
+	defer database.Close()
+	server.DBPath = global.DBPath
+
+	llmConfig := buildLLMConfig(logger, global.ConfigPath, global.TerminalURL, global.DefaultModel, database)
+	llmManager := server.NewLLMServiceManager(llmConfig)
+	svr := setupServer(global, logger, database, llmConfig, llmManager, dir, "")
+
+	// SIGINT/SIGTERM cancel the run; the summary is still printed.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := svr.RunHeadless(ctx, server.HeadlessRunRequest{
+		Prompt:     text,
+		Model:      *model,
+		Cwd:        dir,
+		Timeout:    *timeout,
+		MaxCostUSD: *maxCost,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(runExitError)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding result: %v\n", err)
+		os.Exit(runExitError)
+	}
+	// os.Exit skips deferred calls, so close the database explicitly.
+	database.Close()
+	os.Exit(runExitCode(result.Status))
+}
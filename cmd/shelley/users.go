@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/server"
+)
+
+// runUsers manages the users and API tokens used by `shelley serve -auth`.
+// It works on the database directly, so it can create the first user.
+func runUsers(global GlobalConfig, args []string) {
+	fs := flag.NewFlagSet("users", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: shelley [global-flags] users <subcommand> [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Manages the users who can sign in when the server runs with -auth.\n\n")
+		fmt.Fprintf(fs.Output(), "Subcommands:\n")
+		fmt.Fprintf(fs.Output(), "  list                           List users\n")
+		fmt.Fprintf(fs.Output(), "  add -email EMAIL [-oidc-only]  Add a user; reads the password from stdin\n")
+		fmt.Fprintf(fs.Output(), "  passwd -email EMAIL            Set a user's password (from stdin) and sign out their sessions\n")
+		fmt.Fprintf(fs.Output(), "  delete -email EMAIL            Delete a user with their sessions and API tokens\n")
+		fmt.Fprintf(fs.Output(), "  token -email EMAIL -name NAME  Create an API token and print it\n")
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	logger := setupLogging(global.Debug)
+	database := setupDatabase(global.DBPath, logger)
+	defer database.Close()
+	ctx := context.Background()
+
+	sub, subArgs := fs.Arg(0), fs.Args()[1:]
+	subFS := flag.NewFlagSet("users "+sub, flag.ExitOnError)
+	email := subFS.String("email", "", "User email")
+	var err error
+	switch sub {
+	case "list":
+		subFS.Parse(subArgs)
+		err = listUsers(ctx, database)
+	case "add":
+		oidcOnly := subFS.Bool("oidc-only", false, "Create the user without a password; they sign in through OIDC")
+		subFS.Parse(subArgs)
+		password := ""
+		if !*oidcOnly {
+			password, err = readPassword()
+		}
+		if err == nil {
+			var user *generated.User
+			user, err = server.CreateUser(ctx, database, *email, password)
+			if err == nil {
+				fmt.Printf("Added %s (%s)\n", user.Email, user.UserID)
+			}
+		}
+	case "passwd":
+		subFS.Parse(subArgs)
+		var user *generated.User
+		if user, err = lookupUser(ctx, database, *email); err == nil {
+			var password, hash string
+			if password, err = readPassword(); err == nil {
+				if hash, err = server.HashPassword(password); err == nil {
+					err = database.SetUserPassword(ctx, user.UserID, &hash)
+				}
+			}
+		}
+	case "delete":
+		subFS.Parse(subArgs)
+		var user *generated.User
+		if user, err = lookupUser(ctx, database, *email); err == nil {
+			err = database.DeleteUser(ctx, user.UserID)
+		}
+	case "token":
+		name := subFS.String("name", "cli", "Token name, to recognize it later")
+		subFS.Parse(subArgs)
+		var user *generated.User
+		if user, err = lookupUser(ctx, database, *email); err == nil {
+			var secret string
+			if secret, _, err = server.CreateAPIToken(ctx, database, user.UserID, *name); err == nil {
+				fmt.Println(secret)
+			}
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown subcommand: %s\n", sub)
+		fs.Usage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func lookupUser(ctx context.Context, database *db.DB, email string) (*generated.User, error) {
+	if email == "" {
+		return nil, errors.New("-email is required")
+	}
+	user, err := database.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no user %s", email)
+	}
+	return user, err
+}
+
+func listUsers(ctx context.Context, database *db.DB) error {
+	users, err := database.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "EMAIL\tID\tSIGN-IN\tLAST LOGIN")
+	for _, u := range users {
+		signIn := "password"
+		if u.PasswordHash == nil {
+			signIn = "oidc"
+		}
+		lastLogin := "never"
+		if u.LastLoginAt != nil {
+			lastLogin = u.LastLoginAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", u.Email, u.UserID, signIn, lastLogin)
+	}
+	return tw.Flush()
+}
+
+// readPassword reads a password from the first line of stdin, prompting if
+// stdin is a terminal. Note that the password is echoed.
+func readPassword() (string, error) {
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("no password on stdin")
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
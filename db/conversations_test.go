@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -827,3 +828,277 @@ func TestUnarchivePreservesSortOrder(t *testing.T) {
 		}
 	}
 }
+
+func TestConversationTags(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conv, err := db.CreateConversation(ctx, stringPtr("tagged"), true, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	other, err := db.CreateConversation(ctx, stringPtr("other"), true, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	if err := db.SetConversationTags(ctx, conv.ConversationID, []string{"bug", "clients/acme"}); err != nil {
+		t.Fatalf("SetConversationTags() error = %v", err)
+	}
+	if err := db.SetConversationTags(ctx, other.ConversationID, []string{"bug"}); err != nil {
+		t.Fatalf("SetConversationTags() error = %v", err)
+	}
+
+	tags, err := db.GetConversationTags(ctx, conv.ConversationID)
+	if err != nil {
+		t.Fatalf("GetConversationTags() error = %v", err)
+	}
+	if strings.Join(tags, ",") != "bug,clients/acme" {
+		t.Errorf("Expected tags [bug clients/acme], got %v", tags)
+	}
+
+	// Replacing the tag set drops tags that are no longer present
+	if err := db.SetConversationTags(ctx, conv.ConversationID, []string{"clients/acme"}); err != nil {
+		t.Fatalf("SetConversationTags() error = %v", err)
+	}
+	all, err := db.GetAllConversationTags(ctx)
+	if err != nil {
+		t.Fatalf("GetAllConversationTags() error = %v", err)
+	}
+	if strings.Join(all[conv.ConversationID], ",") != "clients/acme" {
+		t.Errorf("Expected tags [clients/acme], got %v", all[conv.ConversationID])
+	}
+	if strings.Join(all[other.ConversationID], ",") != "bug" {
+		t.Errorf("Expected tags [bug], got %v", all[other.ConversationID])
+	}
+
+	counts, err := db.ListTagCounts(ctx)
+	if err != nil {
+		t.Fatalf("ListTagCounts() error = %v", err)
+	}
+	if len(counts) != 2 {
+		t.Fatalf("Expected 2 tags, got %d", len(counts))
+	}
+
+	// Deleting a conversation removes its tags
+	if err := db.DeleteConversation(ctx, conv.ConversationID); err != nil {
+		t.Fatalf("DeleteConversation() error = %v", err)
+	}
+	all, err = db.GetAllConversationTags(ctx)
+	if err != nil {
+		t.Fatalf("GetAllConversationTags() error = %v", err)
+	}
+	if _, ok := all[conv.ConversationID]; ok {
+		t.Error("Expected tags of deleted conversation to be removed")
+	}
+}
+
+func TestConversationPinned_SortedFirst(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	older, err := db.CreateConversation(ctx, stringPtr("older"), true, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	newer, err := db.CreateConversation(ctx, stringPtr("newer"), true, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	err = db.Pool().Exec(ctx, "UPDATE conversations SET updated_at = '2024-01-01 10:00:00' WHERE conversation_id = ?", older.ConversationID)
+	if err != nil {
+		t.Fatalf("Failed to set updated_at: %v", err)
+	}
+
+	pinned, err := db.SetConversationPinned(ctx, older.ConversationID, true)
+	if err != nil {
+		t.Fatalf("SetConversationPinned() error = %v", err)
+	}
+	if !pinned.Pinned {
+		t.Error("Expected conversation to be pinned")
+	}
+
+	conversations, err := db.ListConversations(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("ListConversations() error = %v", err)
+	}
+	if len(conversations) != 2 || conversations[0].ConversationID != older.ConversationID || conversations[1].ConversationID != newer.ConversationID {
+		t.Errorf("Expected pinned conversation first, got %v", conversations)
+	}
+
+	unpinned, err := db.SetConversationPinned(ctx, older.ConversationID, false)
+	if err != nil {
+		t.Fatalf("SetConversationPinned() error = %v", err)
+	}
+	if unpinned.Pinned {
+		t.Error("Expected conversation to be unpinned")
+	}
+}
+
+func TestListConversationsFiltered(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	repoA, err := db.CreateConversation(ctx, stringPtr("repo-a"), true, stringPtr("/src/a"), stringPtr("model-1"))
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	repoASub, err := db.CreateConversation(ctx, stringPtr("repo-a-sub"), true, stringPtr("/src/a/pkg"), stringPtr("model-2"))
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	repoAB, err := db.CreateConversation(ctx, stringPtr("repo-ab"), true, stringPtr("/src/ab"), stringPtr("model-1"))
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateSubagentConversation(ctx, "child", repoAB.ConversationID, stringPtr("/src/ab")); err != nil {
+		t.Fatalf("Failed to create subagent conversation: %v", err)
+	}
+	if err := db.SetConversationTags(ctx, repoASub.ConversationID, []string{"release"}); err != nil {
+		t.Fatalf("SetConversationTags() error = %v", err)
+	}
+	if _, err := db.CreateMessage(ctx, CreateMessageParams{
+		ConversationID: repoAB.ConversationID,
+		Type:           MessageTypeUser,
+		LLMData:        map[string]string{"text": "please deploy the widget"},
+	}); err != nil {
+		t.Fatalf("Failed to create message: %v", err)
+	}
+	err = db.Pool().Exec(ctx, "UPDATE conversations SET updated_at = '2024-01-01 10:00:00' WHERE conversation_id = ?", repoA.ConversationID)
+	if err != nil {
+		t.Fatalf("Failed to set updated_at: %v", err)
+	}
+
+	yes := true
+	tests := []struct {
+		name   string
+		filter ConversationFilter
+		want   []string
+	}{
+		{"cwd includes subdirectories", ConversationFilter{Cwd: "/src/a/"}, []string{repoASub.ConversationID, repoA.ConversationID}},
+		{"tag", ConversationFilter{Tag: "release"}, []string{repoASub.ConversationID}},
+		{"model", ConversationFilter{Model: "model-1"}, []string{repoAB.ConversationID, repoA.ConversationID}},
+		{"has subagents", ConversationFilter{HasSubagents: &yes}, []string{repoAB.ConversationID}},
+		{"updated before", ConversationFilter{UpdatedBefore: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}, []string{repoA.ConversationID}},
+		{"updated after", ConversationFilter{Model: "model-1", UpdatedAfter: time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)}, []string{repoAB.ConversationID}},
+		{"query", ConversationFilter{Query: "sub", Cwd: "/src"}, []string{repoASub.ConversationID}},
+		{"content query", ConversationFilter{ContentQuery: "deploy the widget", Model: "model-1"}, []string{repoAB.ConversationID}},
+		{"content query matches the slug", ConversationFilter{ContentQuery: "a-sub"}, []string{repoASub.ConversationID}},
+		{"content query with other filters", ConversationFilter{ContentQuery: "widget", Cwd: "/src/a"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conversations, err := db.ListConversationsFiltered(ctx, tt.filter, 10, 0)
+			if err != nil {
+				t.Fatalf("ListConversationsFiltered() error = %v", err)
+			}
+			var got []string
+			for _, c := range conversations {
+				got = append(got, c.ConversationID)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRecordConversationGitState(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	main, err := db.CreateConversation(ctx, stringPtr("main"), true, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	feature, err := db.CreateConversation(ctx, stringPtr("feature"), true, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	if _, err := db.CreateConversation(ctx, stringPtr("no-repo"), true, nil, nil); err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+
+	if err := db.RecordConversationGitState(ctx, main.ConversationID, "/src/repo", "/src/repo", "main"); err != nil {
+		t.Fatalf("RecordConversationGitState() error = %v", err)
+	}
+	if err := db.RecordConversationGitState(ctx, feature.ConversationID, "/src/repo", "/src/repo-wt", "main"); err != nil {
+		t.Fatalf("RecordConversationGitState() error = %v", err)
+	}
+	if err := db.RecordConversationGitState(ctx, feature.ConversationID, "/src/repo", "/src/repo-wt", "feature/x"); err != nil {
+		t.Fatalf("RecordConversationGitState() error = %v", err)
+	}
+	// Seeing a branch again does not duplicate it; detached HEAD adds nothing
+	if err := db.RecordConversationGitState(ctx, feature.ConversationID, "/src/repo", "/src/repo-wt", "main"); err != nil {
+		t.Fatalf("RecordConversationGitState() error = %v", err)
+	}
+	if err := db.RecordConversationGitState(ctx, feature.ConversationID, "/src/repo", "/src/repo-wt", ""); err != nil {
+		t.Fatalf("RecordConversationGitState() error = %v", err)
+	}
+
+	got, err := db.GetConversationByID(ctx, feature.ConversationID)
+	if err != nil {
+		t.Fatalf("GetConversationByID() error = %v", err)
+	}
+	if got.RepoRoot == nil || *got.RepoRoot != "/src/repo" || got.RepoWorktree == nil || *got.RepoWorktree != "/src/repo-wt" {
+		t.Errorf("Expected repo /src/repo in worktree /src/repo-wt, got %v %v", got.RepoRoot, got.RepoWorktree)
+	}
+
+	branches, err := db.GetConversationBranches(ctx, feature.ConversationID)
+	if err != nil {
+		t.Fatalf("GetConversationBranches() error = %v", err)
+	}
+	// first_seen_at has one-second precision, so compare without order
+	slices.Sort(branches)
+	if strings.Join(branches, ",") != "feature/x,main" {
+		t.Errorf("Expected branches [feature/x main], got %v", branches)
+	}
+
+	repoConvs, err := db.ListRepoConversations(ctx, "/src/repo/")
+	if err != nil {
+		t.Fatalf("ListRepoConversations() error = %v", err)
+	}
+	if len(repoConvs) != 2 {
+		t.Errorf("Expected 2 conversations in repo, got %d", len(repoConvs))
+	}
+
+	onFeature, err := db.ListConversationsFiltered(ctx, ConversationFilter{Branch: "feature/x"}, 10, 0)
+	if err != nil {
+		t.Fatalf("ListConversationsFiltered() error = %v", err)
+	}
+	if len(onFeature) != 1 || onFeature[0].ConversationID != feature.ConversationID {
+		t.Errorf("Expected only the feature conversation, got %v", onFeature)
+	}
+
+	inRepo, err := db.ListConversationsFiltered(ctx, ConversationFilter{RepoRoot: "/src/repo"}, 10, 0)
+	if err != nil {
+		t.Fatalf("ListConversationsFiltered() error = %v", err)
+	}
+	if len(inRepo) != 2 {
+		t.Errorf("Expected 2 conversations in repo, got %d", len(inRepo))
+	}
+
+	if err := db.DeleteConversation(ctx, feature.ConversationID); err != nil {
+		t.Fatalf("DeleteConversation() error = %v", err)
+	}
+	all, err := db.GetAllConversationBranches(ctx)
+	if err != nil {
+		t.Fatalf("GetAllConversationBranches() error = %v", err)
+	}
+	if _, ok := all[feature.ConversationID]; ok {
+		t.Error("Expected branches of deleted conversation to be removed")
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"shelley.exe.dev/db/generated"
@@ -236,6 +237,16 @@ func WithTxRes[T any](db *DB, ctx context.Context, fn func(*generated.Queries) (
 
 // CreateConversation creates a new conversation with an optional slug
 func (db *DB) CreateConversation(ctx context.Context, slug *string, userInitiated bool, cwd, model *string) (*generated.Conversation, error) {
+	return db.CreateOwnedConversation(ctx, "", slug, userInitiated, cwd, model)
+}
+
+// CreateOwnedConversation creates a new conversation owned by ownerEmail.
+// An empty ownerEmail creates a conversation without an owner.
+func (db *DB) CreateOwnedConversation(ctx context.Context, ownerEmail string, slug *string, userInitiated bool, cwd, model *string) (*generated.Conversation, error) {
+	var owner *string
+	if ownerEmail != "" {
+		owner = &ownerEmail
+	}
 	conversationID, err := generateConversationID()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate conversation ID: %w", err)
@@ -249,6 +260,7 @@ func (db *DB) CreateConversation(ctx context.Context, slug *string, userInitiate
 			UserInitiated:  userInitiated,
 			Cwd:            cwd,
 			Model:          model,
+			OwnerEmail:     owner,
 		})
 		return err
 	})
@@ -336,6 +348,94 @@ func (db *DB) SearchConversationsWithMessages(ctx context.Context, query string,
 	return conversations, err
 }
 
+// SearchVisibleConversationsWithMessages is SearchConversationsWithMessages
+// limited to the conversations viewer may see in multi-user mode.
+func (db *DB) SearchVisibleConversationsWithMessages(ctx context.Context, viewer, query string, limit, offset int64) ([]generated.Conversation, error) {
+	var conversations []generated.Conversation
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		conversations, err = q.SearchVisibleConversationsWithMessages(ctx, generated.SearchVisibleConversationsWithMessagesParams{
+			Query:  query,
+			Viewer: &viewer,
+			Limit:  limit,
+			Offset: offset,
+		})
+		return err
+	})
+	return conversations, err
+}
+
+// ConversationFilter narrows the results of ListConversationsFiltered.
+// Zero-valued fields are ignored.
+type ConversationFilter struct {
+	Query         string    // substring of the slug
+	ContentQuery  string    // substring of the slug or of a user or agent message
+	Tag           string    // exact tag
+	Cwd           string    // cwd equal to or below this directory
+	RepoRoot      string    // exact main repository root
+	Branch        string    // branch the conversation has been on
+	Model         string    // exact model ID
+	UpdatedAfter  time.Time // inclusive
+	UpdatedBefore time.Time // exclusive
+	Pinned        *bool
+	HasSubagents  *bool
+	Viewer        string // only conversations this user may see (multi-user mode)
+}
+
+// IsZero reports whether the filter has no constraints set.
+func (f ConversationFilter) IsZero() bool {
+	return f.Query == "" && f.ContentQuery == "" && f.Tag == "" && f.Cwd == "" && f.Model == "" &&
+		f.RepoRoot == "" && f.Branch == "" &&
+		f.UpdatedAfter.IsZero() && f.UpdatedBefore.IsZero() &&
+		f.Pinned == nil && f.HasSubagents == nil && f.Viewer == ""
+}
+
+// sqliteTimeFormat matches the format SQLite uses for CURRENT_TIMESTAMP.
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// ListConversationsFiltered retrieves top-level, non-archived conversations
+// matching the filter, pinned conversations first.
+func (db *DB) ListConversationsFiltered(ctx context.Context, filter ConversationFilter, limit, offset int64) ([]generated.Conversation, error) {
+	optString := func(s string) *string {
+		if s == "" {
+			return nil
+		}
+		return &s
+	}
+	optTime := func(t time.Time) *string {
+		if t.IsZero() {
+			return nil
+		}
+		s := t.UTC().Format(sqliteTimeFormat)
+		return &s
+	}
+	params := generated.ListConversationsFilteredParams{
+		Query:         optString(filter.Query),
+		Tag:           optString(filter.Tag),
+		Cwd:           optString(strings.TrimSuffix(filter.Cwd, "/")),
+		RepoRoot:      optString(strings.TrimSuffix(filter.RepoRoot, "/")),
+		Branch:        optString(filter.Branch),
+		Model:         optString(filter.Model),
+		UpdatedAfter:  optTime(filter.UpdatedAfter),
+		UpdatedBefore: optTime(filter.UpdatedBefore),
+		Pinned:        filter.Pinned,
+		HasSubagents:  filter.HasSubagents,
+		Viewer:        optString(filter.Viewer),
+		ContentQuery:  optString(filter.ContentQuery),
+		Limit:         limit,
+		Offset:        offset,
+	}
+	var conversations []generated.Conversation
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		conversations, err = q.ListConversationsFiltered(ctx, params)
+		return err
+	})
+	return conversations, err
+}
+
 // UpdateConversationSlug updates the slug of a conversation
 func (db *DB) UpdateConversationSlug(ctx context.Context, conversationID, slug string) (*generated.Conversation, error) {
 	var conversation generated.Conversation
@@ -569,6 +669,18 @@ func (db *DB) CountMessagesByType(ctx context.Context, conversationID string, me
 	return count, err
 }
 
+// GetConversationCost returns the total cost in USD recorded on a conversation's messages.
+func (db *DB) GetConversationCost(ctx context.Context, conversationID string) (float64, error) {
+	var cost float64
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		cost, err = q.GetConversationCost(ctx, conversationID)
+		return err
+	})
+	return cost, err
+}
+
 // UpdateMessageUserData updates the user_data JSON field of a message
 func (db *DB) UpdateMessageUserData(ctx context.Context, messageID string, userData *string) error {
 	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
@@ -628,6 +740,28 @@ func (db *DB) SearchArchivedConversations(ctx context.Context, query string, lim
 	return conversations, err
 }
 
+// ListVisibleArchivedConversations retrieves the archived conversations
+// viewer may see in multi-user mode, optionally searched by slug.
+func (db *DB) ListVisibleArchivedConversations(ctx context.Context, viewer, query string, limit, offset int64) ([]generated.Conversation, error) {
+	var queryPtr *string
+	if query != "" {
+		queryPtr = &query
+	}
+	var conversations []generated.Conversation
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		conversations, err = q.ListVisibleArchivedConversations(ctx, generated.ListVisibleArchivedConversationsParams{
+			Query:  queryPtr,
+			Viewer: &viewer,
+			Limit:  limit,
+			Offset: offset,
+		})
+		return err
+	})
+	return conversations, err
+}
+
 // ArchiveConversation archives a conversation
 func (db *DB) ArchiveConversation(ctx context.Context, conversationID string) (*generated.Conversation, error) {
 	var conversation generated.Conversation
@@ -652,6 +786,84 @@ func (db *DB) UnarchiveConversation(ctx context.Context, conversationID string)
 	return &conversation, err
 }
 
+// SetConversationPinned pins or unpins a conversation
+func (db *DB) SetConversationPinned(ctx context.Context, conversationID string, pinned bool) (*generated.Conversation, error) {
+	var conversation generated.Conversation
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		conversation, err = q.SetConversationPinned(ctx, generated.SetConversationPinnedParams{
+			Pinned:         pinned,
+			ConversationID: conversationID,
+		})
+		return err
+	})
+	return &conversation, err
+}
+
+// GetConversationTags returns the tags of a conversation in sorted order
+func (db *DB) GetConversationTags(ctx context.Context, conversationID string) ([]string, error) {
+	var tags []string
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		tags, err = q.ListConversationTags(ctx, conversationID)
+		return err
+	})
+	return tags, err
+}
+
+// SetConversationTags replaces the tags of a conversation
+func (db *DB) SetConversationTags(ctx context.Context, conversationID string, tags []string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		if err := q.DeleteConversationTags(ctx, conversationID); err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			if err := q.AddConversationTag(ctx, generated.AddConversationTagParams{
+				ConversationID: conversationID,
+				Tag:            tag,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetAllConversationTags returns a map of conversation_id -> sorted tags.
+func (db *DB) GetAllConversationTags(ctx context.Context) (map[string][]string, error) {
+	var rows []generated.ListAllConversationTagsRow
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		rows, err = q.ListAllConversationTags(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string][]string)
+	for _, r := range rows {
+		tags[r.ConversationID] = append(tags[r.ConversationID], r.Tag)
+	}
+	return tags, nil
+}
+
+// ListTagCounts returns every tag in use with the number of non-archived
+// conversations carrying it.
+func (db *DB) ListTagCounts(ctx context.Context) ([]generated.ListTagCountsRow, error) {
+	var rows []generated.ListTagCountsRow
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		rows, err = q.ListTagCounts(ctx)
+		return err
+	})
+	return rows, err
+}
+
 // DeleteConversation deletes a conversation and all its messages
 func (db *DB) DeleteConversation(ctx context.Context, conversationID string) error {
 	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
@@ -660,10 +872,96 @@ func (db *DB) DeleteConversation(ctx context.Context, conversationID string) err
 		if err := q.DeleteConversationMessages(ctx, conversationID); err != nil {
 			return fmt.Errorf("failed to delete messages: %w", err)
 		}
+		if err := q.DeleteConversationTags(ctx, conversationID); err != nil {
+			return fmt.Errorf("failed to delete tags: %w", err)
+		}
+		if err := q.DeleteConversationBranches(ctx, conversationID); err != nil {
+			return fmt.Errorf("failed to delete branches: %w", err)
+		}
+		if err := q.DeleteConversationReplyTokens(ctx, conversationID); err != nil {
+			return fmt.Errorf("failed to delete reply tokens: %w", err)
+		}
+		if err := q.DeleteConversationShares(ctx, conversationID); err != nil {
+			return fmt.Errorf("failed to delete shares: %w", err)
+		}
 		return q.DeleteConversation(ctx, conversationID)
 	})
 }
 
+// RecordConversationGitState stores the repository a conversation works in and
+// adds branch (if non-empty) to the branches it has been on. It does not
+// change updated_at.
+func (db *DB) RecordConversationGitState(ctx context.Context, conversationID, repoRoot, worktree, branch string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		if err := q.UpdateConversationGitRepo(ctx, generated.UpdateConversationGitRepoParams{
+			RepoRoot:       &repoRoot,
+			RepoWorktree:   &worktree,
+			ConversationID: conversationID,
+		}); err != nil {
+			return err
+		}
+		if branch == "" {
+			return nil
+		}
+		return q.AddConversationBranch(ctx, generated.AddConversationBranchParams{
+			ConversationID: conversationID,
+			Branch:         branch,
+		})
+	})
+}
+
+// GetConversationBranches returns the branches a conversation has been on,
+// in the order they were first seen.
+func (db *DB) GetConversationBranches(ctx context.Context, conversationID string) ([]string, error) {
+	var branches []string
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		branches, err = q.ListConversationBranches(ctx, conversationID)
+		return err
+	})
+	return branches, err
+}
+
+// GetAllConversationBranches returns a map of conversation ID to the branches
+// it has been on.
+func (db *DB) GetAllConversationBranches(ctx context.Context) (map[string][]string, error) {
+	var rows []generated.ListAllConversationBranchesRow
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		rows, err = q.ListAllConversationBranches(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	branches := make(map[string][]string)
+	for _, r := range rows {
+		branches[r.ConversationID] = append(branches[r.ConversationID], r.Branch)
+	}
+	return branches, nil
+}
+
+// ListRepoConversations returns top-level, non-archived conversations with a
+// known repository, most recently updated first. If repoRoot is non-empty only
+// conversations in that repository are returned.
+func (db *DB) ListRepoConversations(ctx context.Context, repoRoot string) ([]generated.Conversation, error) {
+	var repo *string
+	if repoRoot = strings.TrimSuffix(repoRoot, "/"); repoRoot != "" {
+		repo = &repoRoot
+	}
+	var conversations []generated.Conversation
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		conversations, err = q.ListRepoConversations(ctx, repo)
+		return err
+	})
+	return conversations, err
+}
+
 // CreateSubagentConversation creates a new subagent conversation with a parent
 func (db *DB) CreateSubagentConversation(ctx context.Context, slug, parentID string, cwd *string) (*generated.Conversation, error) {
 	conversationID, err := generateConversationID()
@@ -1074,49 +1372,1010 @@ func (db *DB) DeleteNotificationChannel(ctx context.Context, channelID string) e
 	})
 }
 
-// GetSetting retrieves a setting value by key
-// Returns empty string and nil error if the setting doesn't exist
-func (db *DB) GetSetting(ctx context.Context, key string) (string, error) {
-	var value string
+// CreateNotificationDelivery queues an event for delivery to a channel.
+func (db *DB) CreateNotificationDelivery(ctx context.Context, params generated.CreateNotificationDeliveryParams) (*generated.NotificationDelivery, error) {
+	var delivery generated.NotificationDelivery
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		delivery, err = q.CreateNotificationDelivery(ctx, params)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetNotificationDelivery returns a delivery, or sql.ErrNoRows.
+func (db *DB) GetNotificationDelivery(ctx context.Context, deliveryID string) (*generated.NotificationDelivery, error) {
+	var delivery generated.NotificationDelivery
 	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
 		q := generated.New(rx.Conn())
 		var err error
-		value, err = q.GetSetting(ctx, key)
-		if errors.Is(err, sql.ErrNoRows) {
+		delivery, err = q.GetNotificationDelivery(ctx, deliveryID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ListPendingNotificationDeliveries returns up to limit pending deliveries, oldest first.
+func (db *DB) ListPendingNotificationDeliveries(ctx context.Context, limit int64) ([]generated.NotificationDelivery, error) {
+	var deliveries []generated.NotificationDelivery
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		deliveries, err = q.ListPendingNotificationDeliveries(ctx, limit)
+		return err
+	})
+	return deliveries, err
+}
+
+// ListNotificationDeliveries returns the newest deliveries, optionally
+// restricted to a status and channel (empty strings match everything).
+func (db *DB) ListNotificationDeliveries(ctx context.Context, status, channelID string, limit int64) ([]generated.NotificationDelivery, error) {
+	optString := func(s string) *string {
+		if s == "" {
 			return nil
 		}
+		return &s
+	}
+	var deliveries []generated.NotificationDelivery
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		deliveries, err = q.ListNotificationDeliveries(ctx, generated.ListNotificationDeliveriesParams{
+			Status:    optString(status),
+			ChannelID: optString(channelID),
+			Limit:     limit,
+		})
 		return err
 	})
-	return value, err
+	return deliveries, err
 }
 
-// SetSetting sets a setting value by key
-func (db *DB) SetSetting(ctx context.Context, key, value string) error {
+// MarkNotificationDelivered records a successful delivery.
+func (db *DB) MarkNotificationDelivered(ctx context.Context, deliveryID string, at time.Time) error {
 	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
 		q := generated.New(tx.Conn())
-		return q.SetSetting(ctx, generated.SetSettingParams{
-			Key:   key,
-			Value: value,
+		return q.MarkNotificationDelivered(ctx, generated.MarkNotificationDeliveredParams{
+			DeliveredAt: &at,
+			DeliveryID:  deliveryID,
 		})
 	})
 }
 
-// GetAllSettings retrieves all settings
-func (db *DB) GetAllSettings(ctx context.Context) (map[string]string, error) {
-	var rows []generated.GetAllSettingsRow
+// MarkNotificationDeliveryFailed records a failed attempt. A zero retryAt
+// gives up on the delivery; otherwise it is retried at retryAt.
+func (db *DB) MarkNotificationDeliveryFailed(ctx context.Context, deliveryID, lastError string, retryAt time.Time) error {
+	status := "pending"
+	if retryAt.IsZero() {
+		status = "failed"
+		retryAt = time.Now()
+	}
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		return q.MarkNotificationDeliveryFailed(ctx, generated.MarkNotificationDeliveryFailedParams{
+			Status:        status,
+			LastError:     &lastError,
+			NextAttemptAt: retryAt,
+			DeliveryID:    deliveryID,
+		})
+	})
+}
+
+// RetryNotificationDelivery makes a delivery pending again, due immediately.
+// It returns sql.ErrNoRows if the delivery does not exist.
+func (db *DB) RetryNotificationDelivery(ctx context.Context, deliveryID string) (*generated.NotificationDelivery, error) {
+	var delivery generated.NotificationDelivery
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		delivery, err = q.RetryNotificationDelivery(ctx, generated.RetryNotificationDeliveryParams{
+			NextAttemptAt: time.Now(),
+			DeliveryID:    deliveryID,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// PruneNotificationDeliveries deletes all but the newest keep finished deliveries.
+func (db *DB) PruneNotificationDeliveries(ctx context.Context, keep int64) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		return q.PruneNotificationDeliveries(ctx, keep)
+	})
+}
+
+// ListSchedules returns all schedules, oldest first.
+func (db *DB) ListSchedules(ctx context.Context) ([]generated.Schedule, error) {
+	var schedules []generated.Schedule
 	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
 		q := generated.New(rx.Conn())
 		var err error
-		rows, err = q.GetAllSettings(ctx)
+		schedules, err = q.ListSchedules(ctx)
+		return err
+	})
+	return schedules, err
+}
+
+// ListEnabledSchedules returns all enabled schedules, oldest first.
+func (db *DB) ListEnabledSchedules(ctx context.Context) ([]generated.Schedule, error) {
+	var schedules []generated.Schedule
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		schedules, err = q.ListEnabledSchedules(ctx)
+		return err
+	})
+	return schedules, err
+}
+
+// GetSchedule retrieves a schedule by ID. It returns sql.ErrNoRows if there is none.
+func (db *DB) GetSchedule(ctx context.Context, scheduleID string) (*generated.Schedule, error) {
+	var schedule generated.Schedule
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		schedule, err = q.GetSchedule(ctx, scheduleID)
 		return err
 	})
 	if err != nil {
 		return nil, err
 	}
+	return &schedule, nil
+}
 
-	settings := make(map[string]string)
-	for _, row := range rows {
-		settings[row.Key] = row.Value
+func (db *DB) CreateSchedule(ctx context.Context, params generated.CreateScheduleParams) (*generated.Schedule, error) {
+	var schedule generated.Schedule
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		schedule, err = q.CreateSchedule(ctx, params)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	return settings, nil
+	return &schedule, nil
+}
+
+func (db *DB) UpdateSchedule(ctx context.Context, params generated.UpdateScheduleParams) (*generated.Schedule, error) {
+	var schedule generated.Schedule
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		schedule, err = q.UpdateSchedule(ctx, params)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// UpdateScheduleNextRun sets when a schedule fires next and how many runs are queued.
+func (db *DB) UpdateScheduleNextRun(ctx context.Context, scheduleID string, nextRunAt *time.Time, pendingRuns int64) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		return q.UpdateScheduleNextRun(ctx, generated.UpdateScheduleNextRunParams{
+			NextRunAt:   nextRunAt,
+			PendingRuns: pendingRuns,
+			ScheduleID:  scheduleID,
+		})
+	})
+}
+
+// DeleteSchedule deletes a schedule. Its past runs are kept as ordinary conversations.
+func (db *DB) DeleteSchedule(ctx context.Context, scheduleID string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		if err := q.ClearConversationSchedule(ctx, &scheduleID); err != nil {
+			return err
+		}
+		return q.DeleteSchedule(ctx, scheduleID)
+	})
+}
+
+// CreateScheduleRun creates the conversation for a schedule run and records it
+// as the schedule's latest run.
+func (db *DB) CreateScheduleRun(ctx context.Context, scheduleID string, cwd *string, model string, runAt time.Time, nextRunAt *time.Time, pendingRuns int64) (*generated.Conversation, error) {
+	conversationID, err := generateConversationID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate conversation ID: %w", err)
+	}
+	var conversation generated.Conversation
+	err = db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		conversation, err = q.CreateConversation(ctx, generated.CreateConversationParams{
+			ConversationID: conversationID,
+			UserInitiated:  true,
+			Cwd:            cwd,
+			Model:          &model,
+		})
+		if err != nil {
+			return err
+		}
+		if err := q.SetConversationSchedule(ctx, generated.SetConversationScheduleParams{
+			ScheduleID:     &scheduleID,
+			ConversationID: conversationID,
+		}); err != nil {
+			return err
+		}
+		conversation.ScheduleID = &scheduleID
+		return q.RecordScheduleRun(ctx, generated.RecordScheduleRunParams{
+			LastRunAt:          &runAt,
+			LastConversationID: &conversationID,
+			NextRunAt:          nextRunAt,
+			PendingRuns:        pendingRuns,
+			ScheduleID:         scheduleID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// ListScheduleConversations returns the most recent runs of a schedule, newest first.
+func (db *DB) ListScheduleConversations(ctx context.Context, scheduleID string, limit int64) ([]generated.Conversation, error) {
+	var conversations []generated.Conversation
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		conversations, err = q.ListScheduleConversations(ctx, generated.ListScheduleConversationsParams{
+			ScheduleID: &scheduleID,
+			Limit:      limit,
+		})
+		return err
+	})
+	return conversations, err
+}
+
+// CreateNotificationReplyToken stores the hash of a token that accepts
+// replies to a conversation until expiresAt.
+func (db *DB) CreateNotificationReplyToken(ctx context.Context, tokenHash, conversationID string, expiresAt time.Time) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		return q.CreateNotificationReplyToken(ctx, generated.CreateNotificationReplyTokenParams{
+			TokenHash:      tokenHash,
+			ConversationID: conversationID,
+			ExpiresAt:      expiresAt,
+		})
+	})
+}
+
+// GetNotificationReplyToken retrieves a reply token by its hash. It returns
+// sql.ErrNoRows if there is none; expiry is left to the caller.
+func (db *DB) GetNotificationReplyToken(ctx context.Context, tokenHash string) (*generated.NotificationReplyToken, error) {
+	var replyToken generated.NotificationReplyToken
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		replyToken, err = q.GetNotificationReplyToken(ctx, tokenHash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &replyToken, nil
+}
+
+// UseNotificationReplyToken deletes a reply token once its reply is
+// accepted, so that it can't be used again. It returns sql.ErrNoRows if the
+// token was already used.
+func (db *DB) UseNotificationReplyToken(ctx context.Context, tokenHash string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		n, err := q.DeleteNotificationReplyToken(ctx, tokenHash)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// PruneNotificationReplyTokens deletes reply tokens that expired before now.
+func (db *DB) PruneNotificationReplyTokens(ctx context.Context, now time.Time) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		tokens, err := q.ListNotificationReplyTokenExpiries(ctx)
+		if err != nil {
+			return err
+		}
+		for _, t := range tokens {
+			if t.ExpiresAt.Before(now) {
+				if _, err := q.DeleteNotificationReplyToken(ctx, t.TokenHash); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ListTriggers returns all triggers, oldest first.
+func (db *DB) ListTriggers(ctx context.Context) ([]generated.Trigger, error) {
+	var triggers []generated.Trigger
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		triggers, err = q.ListTriggers(ctx)
+		return err
+	})
+	return triggers, err
+}
+
+// GetTrigger retrieves a trigger by ID. It returns sql.ErrNoRows if there is none.
+func (db *DB) GetTrigger(ctx context.Context, triggerID string) (*generated.Trigger, error) {
+	var trigger generated.Trigger
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		trigger, err = q.GetTrigger(ctx, triggerID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &trigger, nil
+}
+
+func (db *DB) CreateTrigger(ctx context.Context, params generated.CreateTriggerParams) (*generated.Trigger, error) {
+	var trigger generated.Trigger
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		trigger, err = q.CreateTrigger(ctx, params)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &trigger, nil
+}
+
+func (db *DB) UpdateTrigger(ctx context.Context, params generated.UpdateTriggerParams) (*generated.Trigger, error) {
+	var trigger generated.Trigger
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		trigger, err = q.UpdateTrigger(ctx, params)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &trigger, nil
+}
+
+// SetTriggerSecret replaces a trigger's secret.
+func (db *DB) SetTriggerSecret(ctx context.Context, triggerID, secret string) (*generated.Trigger, error) {
+	var trigger generated.Trigger
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		trigger, err = q.SetTriggerSecret(ctx, generated.SetTriggerSecretParams{
+			Secret:    secret,
+			TriggerID: triggerID,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &trigger, nil
+}
+
+// DeleteTrigger deletes a trigger. Its conversations are kept as ordinary conversations.
+func (db *DB) DeleteTrigger(ctx context.Context, triggerID string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		if err := q.ClearConversationTrigger(ctx, &triggerID); err != nil {
+			return err
+		}
+		return q.DeleteTrigger(ctx, triggerID)
+	})
+}
+
+// CreateTriggerConversation creates a conversation for a trigger and records
+// it as the trigger's latest conversation.
+func (db *DB) CreateTriggerConversation(ctx context.Context, triggerID string, cwd *string, model string, at time.Time) (*generated.Conversation, error) {
+	conversationID, err := generateConversationID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate conversation ID: %w", err)
+	}
+	var conversation generated.Conversation
+	err = db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		conversation, err = q.CreateConversation(ctx, generated.CreateConversationParams{
+			ConversationID: conversationID,
+			UserInitiated:  true,
+			Cwd:            cwd,
+			Model:          &model,
+		})
+		if err != nil {
+			return err
+		}
+		if err := q.SetConversationTrigger(ctx, generated.SetConversationTriggerParams{
+			TriggerID:      &triggerID,
+			ConversationID: conversationID,
+		}); err != nil {
+			return err
+		}
+		conversation.TriggerID = &triggerID
+		return q.RecordTriggerRun(ctx, generated.RecordTriggerRunParams{
+			LastTriggeredAt:    &at,
+			LastConversationID: &conversationID,
+			TriggerID:          triggerID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+// RecordTriggerRun records that a trigger fired for an existing conversation.
+func (db *DB) RecordTriggerRun(ctx context.Context, triggerID, conversationID string, at time.Time) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		return q.RecordTriggerRun(ctx, generated.RecordTriggerRunParams{
+			LastTriggeredAt:    &at,
+			LastConversationID: &conversationID,
+			TriggerID:          triggerID,
+		})
+	})
+}
+
+// ListTriggerConversations returns a trigger's most recent conversations, newest first.
+func (db *DB) ListTriggerConversations(ctx context.Context, triggerID string, limit int64) ([]generated.Conversation, error) {
+	var conversations []generated.Conversation
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		conversations, err = q.ListTriggerConversations(ctx, generated.ListTriggerConversationsParams{
+			TriggerID: &triggerID,
+			Limit:     limit,
+		})
+		return err
+	})
+	return conversations, err
+}
+
+// ListUsers returns all users, oldest first.
+func (db *DB) ListUsers(ctx context.Context) ([]generated.User, error) {
+	var users []generated.User
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		users, err = q.ListUsers(ctx)
+		return err
+	})
+	return users, err
+}
+
+// GetUser retrieves a user by ID. It returns sql.ErrNoRows if there is none.
+func (db *DB) GetUser(ctx context.Context, userID string) (*generated.User, error) {
+	var user generated.User
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		user, err = q.GetUser(ctx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserByEmail retrieves a user by email, ignoring case. It returns
+// sql.ErrNoRows if there is none.
+func (db *DB) GetUserByEmail(ctx context.Context, email string) (*generated.User, error) {
+	var user generated.User
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		user, err = q.GetUserByEmail(ctx, email)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreateUser creates a user. passwordHash is nil for users who can only sign
+// in through OIDC.
+func (db *DB) CreateUser(ctx context.Context, userID, email string, passwordHash *string) (*generated.User, error) {
+	var user generated.User
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		user, err = q.CreateUser(ctx, generated.CreateUserParams{
+			UserID:       userID,
+			Email:        email,
+			PasswordHash: passwordHash,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetUserPassword replaces a user's password hash and signs out all of the
+// user's sessions. API tokens are kept.
+func (db *DB) SetUserPassword(ctx context.Context, userID string, passwordHash *string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		if err := q.SetUserPassword(ctx, generated.SetUserPasswordParams{
+			PasswordHash: passwordHash,
+			UserID:       userID,
+		}); err != nil {
+			return err
+		}
+		return q.DeleteUserSessions(ctx, userID)
+	})
+}
+
+// RecordUserLogin sets when a user last signed in.
+func (db *DB) RecordUserLogin(ctx context.Context, userID string, at time.Time) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		return q.RecordUserLogin(ctx, generated.RecordUserLoginParams{
+			LastLoginAt: &at,
+			UserID:      userID,
+		})
+	})
+}
+
+// DeleteUser deletes a user along with their sessions and API tokens.
+func (db *DB) DeleteUser(ctx context.Context, userID string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		if err := q.DeleteUserSessions(ctx, userID); err != nil {
+			return fmt.Errorf("failed to delete sessions: %w", err)
+		}
+		if err := q.DeleteUserAPITokens(ctx, userID); err != nil {
+			return fmt.Errorf("failed to delete API tokens: %w", err)
+		}
+		return q.DeleteUser(ctx, userID)
+	})
+}
+
+// CreateUserSession stores a session, identified by the hash of its cookie
+// value, that is valid until expiresAt.
+func (db *DB) CreateUserSession(ctx context.Context, sessionHash, userID string, expiresAt time.Time) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		return q.CreateUserSession(ctx, generated.CreateUserSessionParams{
+			SessionHash: sessionHash,
+			UserID:      userID,
+			ExpiresAt:   expiresAt,
+		})
+	})
+}
+
+// GetUserSession retrieves a session by hash. It returns sql.ErrNoRows if
+// there is none; expiry is left to the caller.
+func (db *DB) GetUserSession(ctx context.Context, sessionHash string) (*generated.UserSession, error) {
+	var session generated.UserSession
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		session, err = q.GetUserSession(ctx, sessionHash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// DeleteUserSession deletes a session.
+func (db *DB) DeleteUserSession(ctx context.Context, sessionHash string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		return q.DeleteUserSession(ctx, sessionHash)
+	})
+}
+
+// PruneUserSessions deletes sessions that expired before now.
+func (db *DB) PruneUserSessions(ctx context.Context, now time.Time) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		sessions, err := q.ListUserSessionExpiries(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range sessions {
+			if s.ExpiresAt.Before(now) {
+				if err := q.DeleteUserSession(ctx, s.SessionHash); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// ListAPITokens returns a user's API tokens, oldest first.
+func (db *DB) ListAPITokens(ctx context.Context, userID string) ([]generated.ApiToken, error) {
+	var tokens []generated.ApiToken
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		tokens, err = q.ListAPITokens(ctx, userID)
+		return err
+	})
+	return tokens, err
+}
+
+// GetAPITokenByHash retrieves an API token by the hash of its secret. It
+// returns sql.ErrNoRows if there is none.
+func (db *DB) GetAPITokenByHash(ctx context.Context, tokenHash string) (*generated.ApiToken, error) {
+	var token generated.ApiToken
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		token, err = q.GetAPITokenByHash(ctx, tokenHash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// CreateAPIToken stores an API token for a user by the hash of its secret.
+func (db *DB) CreateAPIToken(ctx context.Context, tokenID, userID, name, tokenHash string) (*generated.ApiToken, error) {
+	var token generated.ApiToken
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		token, err = q.CreateAPIToken(ctx, generated.CreateAPITokenParams{
+			TokenID:   tokenID,
+			UserID:    userID,
+			Name:      name,
+			TokenHash: tokenHash,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// TouchAPIToken sets when an API token was last used.
+func (db *DB) TouchAPIToken(ctx context.Context, tokenID string, at time.Time) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		return q.TouchAPIToken(ctx, generated.TouchAPITokenParams{
+			LastUsedAt: &at,
+			TokenID:    tokenID,
+		})
+	})
+}
+
+// DeleteAPIToken deletes one of a user's API tokens. It returns
+// sql.ErrNoRows if the user has no such token.
+func (db *DB) DeleteAPIToken(ctx context.Context, tokenID, userID string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		n, err := q.DeleteAPIToken(ctx, generated.DeleteAPITokenParams{
+			TokenID: tokenID,
+			UserID:  userID,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// ListConversationShares returns who a conversation is shared with.
+func (db *DB) ListConversationShares(ctx context.Context, conversationID string) ([]generated.ConversationShare, error) {
+	var shares []generated.ConversationShare
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		shares, err = q.ListConversationShares(ctx, conversationID)
+		return err
+	})
+	return shares, err
+}
+
+// ListSharesForEmail returns the conversations shared with a user.
+func (db *DB) ListSharesForEmail(ctx context.Context, email string) ([]generated.ConversationShare, error) {
+	var shares []generated.ConversationShare
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		shares, err = q.ListSharesForEmail(ctx, email)
+		return err
+	})
+	return shares, err
+}
+
+// GetConversationShare returns a conversation's share with a user, or
+// sql.ErrNoRows.
+func (db *DB) GetConversationShare(ctx context.Context, conversationID, email string) (*generated.ConversationShare, error) {
+	var share generated.ConversationShare
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		share, err = q.GetConversationShare(ctx, generated.GetConversationShareParams{
+			ConversationID: conversationID,
+			Email:          email,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// ShareConversation shares a conversation with a user, or changes the role
+// of an existing share.
+func (db *DB) ShareConversation(ctx context.Context, conversationID, email, role string) (*generated.ConversationShare, error) {
+	var share generated.ConversationShare
+	err := db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		var err error
+		share, err = q.UpsertConversationShare(ctx, generated.UpsertConversationShareParams{
+			ConversationID: conversationID,
+			Email:          email,
+			Role:           role,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+// UnshareConversation removes a conversation's share with a user. It returns
+// sql.ErrNoRows if there was none.
+func (db *DB) UnshareConversation(ctx context.Context, conversationID, email string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		n, err := q.DeleteConversationShare(ctx, generated.DeleteConversationShareParams{
+			ConversationID: conversationID,
+			Email:          email,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// GetUserSetting retrieves a user's override of a setting.
+// Returns empty string and nil error if the user has none.
+func (db *DB) GetUserSetting(ctx context.Context, email, key string) (string, error) {
+	var value string
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		value, err = q.GetUserSetting(ctx, generated.GetUserSettingParams{Email: email, Key: key})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	})
+	return value, err
+}
+
+// ListUserSettings returns a user's setting overrides by key.
+func (db *DB) ListUserSettings(ctx context.Context, email string) (map[string]string, error) {
+	settings := make(map[string]string)
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		rows, err := q.ListUserSettings(ctx, email)
+		for _, r := range rows {
+			settings[r.Key] = r.Value
+		}
+		return err
+	})
+	return settings, err
+}
+
+// ListUserSettingsByKey returns every user's value of one setting, by email.
+func (db *DB) ListUserSettingsByKey(ctx context.Context, key string) (map[string]string, error) {
+	settings := make(map[string]string)
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		rows, err := q.ListUserSettingsByKey(ctx, key)
+		for _, r := range rows {
+			settings[r.Email] = r.Value
+		}
+		return err
+	})
+	return settings, err
+}
+
+// SetUserSetting sets a user's override of a setting. An empty value
+// removes the override.
+func (db *DB) SetUserSetting(ctx context.Context, email, key, value string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		if value == "" {
+			return q.DeleteUserSetting(ctx, generated.DeleteUserSettingParams{Email: email, Key: key})
+		}
+		return q.SetUserSetting(ctx, generated.SetUserSettingParams{Email: email, Key: key, Value: value})
+	})
+}
+
+// OwnerSpendSince sums the cost of the messages in conversations owned by
+// email that were created at or after since.
+func (db *DB) OwnerSpendSince(ctx context.Context, email string, since time.Time) (float64, error) {
+	var total float64
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		rows, err := q.ListOwnerMessageCosts(ctx, &email)
+		for _, r := range rows {
+			if !r.CreatedAt.Before(since) {
+				total += r.CostUsd
+			}
+		}
+		return err
+	})
+	return total, err
+}
+
+// InsertAuditEntry appends an entry to the audit log.
+func (db *DB) InsertAuditEntry(ctx context.Context, params generated.InsertAuditEntryParams) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		return q.InsertAuditEntry(ctx, params)
+	})
+}
+
+// AuditFilter narrows the results of ListAuditEntries. Zero-valued fields
+// are ignored.
+type AuditFilter struct {
+	Kind           string
+	ConversationID string
+	Since          time.Time // inclusive
+	Until          time.Time // exclusive
+	BeforeID       int64     // only entries older than this one
+}
+
+// ListAuditEntries returns up to limit audit log entries matching the
+// filter, newest first.
+func (db *DB) ListAuditEntries(ctx context.Context, filter AuditFilter, limit int64) ([]generated.AuditLog, error) {
+	optString := func(s string) *string {
+		if s == "" {
+			return nil
+		}
+		return &s
+	}
+	optTime := func(t time.Time) *string {
+		if t.IsZero() {
+			return nil
+		}
+		s := t.UTC().Format(sqliteTimeFormat)
+		return &s
+	}
+	params := generated.ListAuditEntriesParams{
+		Kind:           optString(filter.Kind),
+		ConversationID: optString(filter.ConversationID),
+		Since:          optTime(filter.Since),
+		Until:          optTime(filter.Until),
+		Limit:          limit,
+	}
+	if filter.BeforeID > 0 {
+		params.BeforeID = &filter.BeforeID
+	}
+	var entries []generated.AuditLog
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		entries, err = q.ListAuditEntries(ctx, params)
+		return err
+	})
+	return entries, err
+}
+
+// GetSetting retrieves a setting value by key
+// Returns empty string and nil error if the setting doesn't exist
+func (db *DB) GetSetting(ctx context.Context, key string) (string, error) {
+	var value string
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		value, err = q.GetSetting(ctx, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	})
+	return value, err
+}
+
+// SetSetting sets a setting value by key
+func (db *DB) SetSetting(ctx context.Context, key, value string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		return q.SetSetting(ctx, generated.SetSettingParams{
+			Key:   key,
+			Value: value,
+		})
+	})
+}
+
+// GetAllSettings retrieves all settings
+func (db *DB) GetAllSettings(ctx context.Context) (map[string]string, error) {
+	var rows []generated.GetAllSettingsRow
+	err := db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		rows, err = q.GetAllSettings(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make(map[string]string)
+	for _, row := range rows {
+		settings[row.Key] = row.Value
+	}
+	return settings, nil
+}
+
+// repoMapsKept is how many of a worktree's repository maps are kept, so
+// that switching back to a recent branch doesn't rebuild its map.
+const repoMapsKept = 10
+
+// GetRepoMap returns the cached repository map of a worktree at a commit.
+// ok is false if there is none.
+func (db *DB) GetRepoMap(ctx context.Context, worktree, commit string) (content string, ok bool, err error) {
+	err = db.pool.Rx(ctx, func(ctx context.Context, rx *Rx) error {
+		q := generated.New(rx.Conn())
+		var err error
+		content, err = q.GetRepoMap(ctx, generated.GetRepoMapParams{Worktree: worktree, CommitHash: commit})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		ok = err == nil
+		return err
+	})
+	return content, ok, err
+}
+
+// SaveRepoMap caches the repository map of a worktree at a commit,
+// dropping the worktree's oldest maps.
+func (db *DB) SaveRepoMap(ctx context.Context, worktree, commit, content string) error {
+	return db.pool.Tx(ctx, func(ctx context.Context, tx *Tx) error {
+		q := generated.New(tx.Conn())
+		if err := q.UpsertRepoMap(ctx, generated.UpsertRepoMapParams{Worktree: worktree, CommitHash: commit, Content: content}); err != nil {
+			return err
+		}
+		return q.PruneRepoMaps(ctx, generated.PruneRepoMapsParams{Worktree: worktree, Keep: repoMapsKept})
+	})
 }
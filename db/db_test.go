@@ -631,3 +631,39 @@ func TestLLMRequestOpenAIStyle(t *testing.T) {
 		req2FullLen-req2StoredLen,
 		100.0*float64(req2FullLen-req2StoredLen)/float64(req2FullLen))
 }
+
+func TestRepoMaps(t *testing.T) {
+	db := setupTestDB(t)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, ok, err := db.GetRepoMap(ctx, "/repo", "abc1234"); err != nil || ok {
+		t.Fatalf("GetRepoMap of a missing map = %v, %v", ok, err)
+	}
+	if err := db.SaveRepoMap(ctx, "/repo", "abc1234", "old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveRepoMap(ctx, "/repo", "abc1234", "Entry points:\n"); err != nil {
+		t.Fatal(err)
+	}
+	content, ok, err := db.GetRepoMap(ctx, "/repo", "abc1234")
+	if err != nil || !ok || content != "Entry points:\n" {
+		t.Fatalf("GetRepoMap = %q, %v, %v", content, ok, err)
+	}
+	if _, ok, _ := db.GetRepoMap(ctx, "/other", "abc1234"); ok {
+		t.Error("found a map of another worktree")
+	}
+
+	// Only the newest maps of a worktree are kept.
+	for i := range repoMapsKept {
+		if err := db.SaveRepoMap(ctx, "/repo", fmt.Sprintf("c%d", i), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok, _ := db.GetRepoMap(ctx, "/repo", "abc1234"); ok {
+		t.Error("the oldest map was kept")
+	}
+	if _, ok, _ := db.GetRepoMap(ctx, "/repo", "c0"); !ok {
+		t.Error("a recent map was dropped")
+	}
+}
@@ -13,7 +13,7 @@ const archiveConversation = `-- name: ArchiveConversation :one
 UPDATE conversations
 SET archived = TRUE
 WHERE conversation_id = ?
-RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email
 `
 
 func (q *Queries) ArchiveConversation(ctx context.Context, conversationID string) (Conversation, error) {
@@ -29,6 +29,12 @@ func (q *Queries) ArchiveConversation(ctx context.Context, conversationID string
 		&i.Archived,
 		&i.ParentConversationID,
 		&i.Model,
+		&i.Pinned,
+		&i.RepoRoot,
+		&i.RepoWorktree,
+		&i.ScheduleID,
+		&i.TriggerID,
+		&i.OwnerEmail,
 	)
 	return i, err
 }
@@ -56,9 +62,9 @@ func (q *Queries) CountConversations(ctx context.Context) (int64, error) {
 }
 
 const createConversation = `-- name: CreateConversation :one
-INSERT INTO conversations (conversation_id, slug, user_initiated, cwd, model)
-VALUES (?, ?, ?, ?, ?)
-RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model
+INSERT INTO conversations (conversation_id, slug, user_initiated, cwd, model, owner_email)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email
 `
 
 type CreateConversationParams struct {
@@ -67,6 +73,7 @@ type CreateConversationParams struct {
 	UserInitiated  bool    `json:"user_initiated"`
 	Cwd            *string `json:"cwd"`
 	Model          *string `json:"model"`
+	OwnerEmail     *string `json:"owner_email"`
 }
 
 func (q *Queries) CreateConversation(ctx context.Context, arg CreateConversationParams) (Conversation, error) {
@@ -76,6 +83,7 @@ func (q *Queries) CreateConversation(ctx context.Context, arg CreateConversation
 		arg.UserInitiated,
 		arg.Cwd,
 		arg.Model,
+		arg.OwnerEmail,
 	)
 	var i Conversation
 	err := row.Scan(
@@ -88,14 +96,23 @@ func (q *Queries) CreateConversation(ctx context.Context, arg CreateConversation
 		&i.Archived,
 		&i.ParentConversationID,
 		&i.Model,
+		&i.Pinned,
+		&i.RepoRoot,
+		&i.RepoWorktree,
+		&i.ScheduleID,
+		&i.TriggerID,
+		&i.OwnerEmail,
 	)
 	return i, err
 }
 
 const createSubagentConversation = `-- name: CreateSubagentConversation :one
-INSERT INTO conversations (conversation_id, slug, user_initiated, cwd, parent_conversation_id)
-VALUES (?, ?, FALSE, ?, ?)
-RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model
+INSERT INTO conversations (conversation_id, slug, user_initiated, cwd, parent_conversation_id, owner_email)
+VALUES (
+  ?1, ?2, FALSE, ?3, ?4,
+  (SELECT p.owner_email FROM conversations p WHERE p.conversation_id = ?4)
+)
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email
 `
 
 type CreateSubagentConversationParams struct {
@@ -105,6 +122,7 @@ type CreateSubagentConversationParams struct {
 	ParentConversationID *string `json:"parent_conversation_id"`
 }
 
+// Subagent conversations belong to the owner of their parent.
 func (q *Queries) CreateSubagentConversation(ctx context.Context, arg CreateSubagentConversationParams) (Conversation, error) {
 	row := q.db.QueryRowContext(ctx, createSubagentConversation,
 		arg.ConversationID,
@@ -123,6 +141,12 @@ func (q *Queries) CreateSubagentConversation(ctx context.Context, arg CreateSuba
 		&i.Archived,
 		&i.ParentConversationID,
 		&i.Model,
+		&i.Pinned,
+		&i.RepoRoot,
+		&i.RepoWorktree,
+		&i.ScheduleID,
+		&i.TriggerID,
+		&i.OwnerEmail,
 	)
 	return i, err
 }
@@ -138,7 +162,7 @@ func (q *Queries) DeleteConversation(ctx context.Context, conversationID string)
 }
 
 const getConversation = `-- name: GetConversation :one
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email FROM conversations
 WHERE conversation_id = ?
 `
 
@@ -155,12 +179,18 @@ func (q *Queries) GetConversation(ctx context.Context, conversationID string) (C
 		&i.Archived,
 		&i.ParentConversationID,
 		&i.Model,
+		&i.Pinned,
+		&i.RepoRoot,
+		&i.RepoWorktree,
+		&i.ScheduleID,
+		&i.TriggerID,
+		&i.OwnerEmail,
 	)
 	return i, err
 }
 
 const getConversationBySlug = `-- name: GetConversationBySlug :one
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email FROM conversations
 WHERE slug = ?
 `
 
@@ -177,12 +207,18 @@ func (q *Queries) GetConversationBySlug(ctx context.Context, slug *string) (Conv
 		&i.Archived,
 		&i.ParentConversationID,
 		&i.Model,
+		&i.Pinned,
+		&i.RepoRoot,
+		&i.RepoWorktree,
+		&i.ScheduleID,
+		&i.TriggerID,
+		&i.OwnerEmail,
 	)
 	return i, err
 }
 
 const getConversationBySlugAndParent = `-- name: GetConversationBySlugAndParent :one
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email FROM conversations
 WHERE slug = ? AND parent_conversation_id = ?
 `
 
@@ -204,6 +240,12 @@ func (q *Queries) GetConversationBySlugAndParent(ctx context.Context, arg GetCon
 		&i.Archived,
 		&i.ParentConversationID,
 		&i.Model,
+		&i.Pinned,
+		&i.RepoRoot,
+		&i.RepoWorktree,
+		&i.ScheduleID,
+		&i.TriggerID,
+		&i.OwnerEmail,
 	)
 	return i, err
 }
@@ -244,7 +286,7 @@ func (q *Queries) GetSubagentCounts(ctx context.Context) ([]GetSubagentCountsRow
 }
 
 const getSubagents = `-- name: GetSubagents :many
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email FROM conversations
 WHERE parent_conversation_id = ?
 ORDER BY created_at ASC
 `
@@ -268,6 +310,12 @@ func (q *Queries) GetSubagents(ctx context.Context, parentConversationID *string
 			&i.Archived,
 			&i.ParentConversationID,
 			&i.Model,
+			&i.Pinned,
+			&i.RepoRoot,
+			&i.RepoWorktree,
+			&i.ScheduleID,
+			&i.TriggerID,
+			&i.OwnerEmail,
 		); err != nil {
 			return nil, err
 		}
@@ -283,7 +331,7 @@ func (q *Queries) GetSubagents(ctx context.Context, parentConversationID *string
 }
 
 const listArchivedConversations = `-- name: ListArchivedConversations :many
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email FROM conversations
 WHERE archived = TRUE
 ORDER BY updated_at DESC
 LIMIT ? OFFSET ?
@@ -313,6 +361,12 @@ func (q *Queries) ListArchivedConversations(ctx context.Context, arg ListArchive
 			&i.Archived,
 			&i.ParentConversationID,
 			&i.Model,
+			&i.Pinned,
+			&i.RepoRoot,
+			&i.RepoWorktree,
+			&i.ScheduleID,
+			&i.TriggerID,
+			&i.OwnerEmail,
 		); err != nil {
 			return nil, err
 		}
@@ -328,9 +382,9 @@ func (q *Queries) ListArchivedConversations(ctx context.Context, arg ListArchive
 }
 
 const listConversations = `-- name: ListConversations :many
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email FROM conversations
 WHERE archived = FALSE AND parent_conversation_id IS NULL
-ORDER BY updated_at DESC
+ORDER BY pinned DESC, updated_at DESC
 LIMIT ? OFFSET ?
 `
 
@@ -358,6 +412,184 @@ func (q *Queries) ListConversations(ctx context.Context, arg ListConversationsPa
 			&i.Archived,
 			&i.ParentConversationID,
 			&i.Model,
+			&i.Pinned,
+			&i.RepoRoot,
+			&i.RepoWorktree,
+			&i.ScheduleID,
+			&i.TriggerID,
+			&i.OwnerEmail,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConversationsFiltered = `-- name: ListConversationsFiltered :many
+SELECT c.conversation_id, c.slug, c.user_initiated, c.created_at, c.updated_at, c.cwd, c.archived, c.parent_conversation_id, c.model, c.pinned, c.repo_root, c.repo_worktree, c.schedule_id, c.trigger_id, c.owner_email FROM conversations c
+WHERE c.archived = FALSE AND c.parent_conversation_id IS NULL
+  AND (CAST(?1 AS TEXT) IS NULL OR c.slug LIKE '%' || CAST(?1 AS TEXT) || '%')
+  AND (CAST(?2 AS TEXT) IS NULL OR EXISTS (
+    SELECT 1 FROM conversation_tags t
+    WHERE t.conversation_id = c.conversation_id AND t.tag = CAST(?2 AS TEXT)
+  ))
+  AND (CAST(?3 AS TEXT) IS NULL OR c.cwd = CAST(?3 AS TEXT) OR c.cwd LIKE CAST(?3 AS TEXT) || '/%')
+  AND (CAST(?4 AS TEXT) IS NULL OR c.repo_root = CAST(?4 AS TEXT))
+  AND (CAST(?5 AS TEXT) IS NULL OR EXISTS (
+    SELECT 1 FROM conversation_branches b
+    WHERE b.conversation_id = c.conversation_id AND b.branch = CAST(?5 AS TEXT)
+  ))
+  AND (CAST(?6 AS TEXT) IS NULL OR c.model = CAST(?6 AS TEXT))
+  AND (CAST(?7 AS TEXT) IS NULL OR datetime(c.updated_at) >= datetime(CAST(?7 AS TEXT)))
+  AND (CAST(?8 AS TEXT) IS NULL OR datetime(c.updated_at) < datetime(CAST(?8 AS TEXT)))
+  AND (CAST(?9 AS BOOLEAN) IS NULL OR c.pinned = CAST(?9 AS BOOLEAN))
+  AND (CAST(?10 AS BOOLEAN) IS NULL OR EXISTS (
+    SELECT 1 FROM conversations s WHERE s.parent_conversation_id = c.conversation_id
+  ) = CAST(?10 AS BOOLEAN))
+  AND (CAST(?11 AS TEXT) IS NULL OR c.owner_email IS NULL OR c.owner_email = CAST(?11 AS TEXT) OR EXISTS (
+    SELECT 1 FROM conversation_shares sh
+    WHERE sh.conversation_id = COALESCE(c.parent_conversation_id, c.conversation_id) AND sh.email = CAST(?11 AS TEXT)
+  ))
+  AND (CAST(?12 AS TEXT) IS NULL OR c.slug LIKE '%' || CAST(?12 AS TEXT) || '%' OR EXISTS (
+    SELECT 1 FROM messages m
+    WHERE m.conversation_id = c.conversation_id AND m.type IN ('user', 'agent')
+      AND (json_extract(m.user_data, '$.text') LIKE '%' || CAST(?12 AS TEXT) || '%'
+        OR m.llm_data LIKE '%' || CAST(?12 AS TEXT) || '%')
+  ))
+ORDER BY c.pinned DESC, c.updated_at DESC
+LIMIT ?14 OFFSET ?13
+`
+
+type ListConversationsFilteredParams struct {
+	Query         *string `json:"query"`
+	Tag           *string `json:"tag"`
+	Cwd           *string `json:"cwd"`
+	RepoRoot      *string `json:"repo_root"`
+	Branch        *string `json:"branch"`
+	Model         *string `json:"model"`
+	UpdatedAfter  *string `json:"updated_after"`
+	UpdatedBefore *string `json:"updated_before"`
+	Pinned        *bool   `json:"pinned"`
+	HasSubagents  *bool   `json:"has_subagents"`
+	Viewer        *string `json:"viewer"`
+	ContentQuery  *string `json:"content_query"`
+	Offset        int64   `json:"offset"`
+	Limit         int64   `json:"limit"`
+}
+
+// Every filter is optional; a NULL argument disables that filter.
+func (q *Queries) ListConversationsFiltered(ctx context.Context, arg ListConversationsFilteredParams) ([]Conversation, error) {
+	rows, err := q.db.QueryContext(ctx, listConversationsFiltered,
+		arg.Query,
+		arg.Tag,
+		arg.Cwd,
+		arg.RepoRoot,
+		arg.Branch,
+		arg.Model,
+		arg.UpdatedAfter,
+		arg.UpdatedBefore,
+		arg.Pinned,
+		arg.HasSubagents,
+		arg.Viewer,
+		arg.ContentQuery,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Conversation{}
+	for rows.Next() {
+		var i Conversation
+		if err := rows.Scan(
+			&i.ConversationID,
+			&i.Slug,
+			&i.UserInitiated,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Cwd,
+			&i.Archived,
+			&i.ParentConversationID,
+			&i.Model,
+			&i.Pinned,
+			&i.RepoRoot,
+			&i.RepoWorktree,
+			&i.ScheduleID,
+			&i.TriggerID,
+			&i.OwnerEmail,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listVisibleArchivedConversations = `-- name: ListVisibleArchivedConversations :many
+SELECT c.conversation_id, c.slug, c.user_initiated, c.created_at, c.updated_at, c.cwd, c.archived, c.parent_conversation_id, c.model, c.pinned, c.repo_root, c.repo_worktree, c.schedule_id, c.trigger_id, c.owner_email FROM conversations c
+WHERE c.archived = TRUE
+  AND (CAST(?1 AS TEXT) IS NULL OR c.slug LIKE '%' || CAST(?1 AS TEXT) || '%')
+  AND (CAST(?2 AS TEXT) IS NULL OR c.owner_email IS NULL OR c.owner_email = CAST(?2 AS TEXT) OR EXISTS (
+    SELECT 1 FROM conversation_shares sh
+    WHERE sh.conversation_id = COALESCE(c.parent_conversation_id, c.conversation_id) AND sh.email = CAST(?2 AS TEXT)
+  ))
+ORDER BY c.updated_at DESC
+LIMIT ?4 OFFSET ?3
+`
+
+type ListVisibleArchivedConversationsParams struct {
+	Query  *string `json:"query"`
+	Viewer *string `json:"viewer"`
+	Offset int64   `json:"offset"`
+	Limit  int64   `json:"limit"`
+}
+
+// Archived conversations viewer may see in multi-user mode, optionally
+// searched by slug.
+func (q *Queries) ListVisibleArchivedConversations(ctx context.Context, arg ListVisibleArchivedConversationsParams) ([]Conversation, error) {
+	rows, err := q.db.QueryContext(ctx, listVisibleArchivedConversations,
+		arg.Query,
+		arg.Viewer,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Conversation{}
+	for rows.Next() {
+		var i Conversation
+		if err := rows.Scan(
+			&i.ConversationID,
+			&i.Slug,
+			&i.UserInitiated,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Cwd,
+			&i.Archived,
+			&i.ParentConversationID,
+			&i.Model,
+			&i.Pinned,
+			&i.RepoRoot,
+			&i.RepoWorktree,
+			&i.ScheduleID,
+			&i.TriggerID,
+			&i.OwnerEmail,
 		); err != nil {
 			return nil, err
 		}
@@ -373,7 +605,7 @@ func (q *Queries) ListConversations(ctx context.Context, arg ListConversationsPa
 }
 
 const searchArchivedConversations = `-- name: SearchArchivedConversations :many
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email FROM conversations
 WHERE slug LIKE '%' || ? || '%' AND archived = TRUE
 ORDER BY updated_at DESC
 LIMIT ? OFFSET ?
@@ -404,6 +636,12 @@ func (q *Queries) SearchArchivedConversations(ctx context.Context, arg SearchArc
 			&i.Archived,
 			&i.ParentConversationID,
 			&i.Model,
+			&i.Pinned,
+			&i.RepoRoot,
+			&i.RepoWorktree,
+			&i.ScheduleID,
+			&i.TriggerID,
+			&i.OwnerEmail,
 		); err != nil {
 			return nil, err
 		}
@@ -419,7 +657,7 @@ func (q *Queries) SearchArchivedConversations(ctx context.Context, arg SearchArc
 }
 
 const searchConversations = `-- name: SearchConversations :many
-SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model FROM conversations
+SELECT conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email FROM conversations
 WHERE slug LIKE '%' || ? || '%' AND archived = FALSE AND parent_conversation_id IS NULL
 ORDER BY updated_at DESC
 LIMIT ? OFFSET ?
@@ -450,6 +688,12 @@ func (q *Queries) SearchConversations(ctx context.Context, arg SearchConversatio
 			&i.Archived,
 			&i.ParentConversationID,
 			&i.Model,
+			&i.Pinned,
+			&i.RepoRoot,
+			&i.RepoWorktree,
+			&i.ScheduleID,
+			&i.TriggerID,
+			&i.OwnerEmail,
 		); err != nil {
 			return nil, err
 		}
@@ -465,7 +709,7 @@ func (q *Queries) SearchConversations(ctx context.Context, arg SearchConversatio
 }
 
 const searchConversationsWithMessages = `-- name: SearchConversationsWithMessages :many
-SELECT DISTINCT c.conversation_id, c.slug, c.user_initiated, c.created_at, c.updated_at, c.cwd, c.archived, c.parent_conversation_id, c.model FROM conversations c
+SELECT DISTINCT c.conversation_id, c.slug, c.user_initiated, c.created_at, c.updated_at, c.cwd, c.archived, c.parent_conversation_id, c.model, c.pinned, c.repo_root, c.repo_worktree, c.schedule_id, c.trigger_id, c.owner_email FROM conversations c
 LEFT JOIN messages m ON c.conversation_id = m.conversation_id AND m.type IN ('user', 'agent')
 WHERE c.archived = FALSE
   AND (
@@ -512,6 +756,12 @@ func (q *Queries) SearchConversationsWithMessages(ctx context.Context, arg Searc
 			&i.Archived,
 			&i.ParentConversationID,
 			&i.Model,
+			&i.Pinned,
+			&i.RepoRoot,
+			&i.RepoWorktree,
+			&i.ScheduleID,
+			&i.TriggerID,
+			&i.OwnerEmail,
 		); err != nil {
 			return nil, err
 		}
@@ -526,11 +776,116 @@ func (q *Queries) SearchConversationsWithMessages(ctx context.Context, arg Searc
 	return items, nil
 }
 
+const searchVisibleConversationsWithMessages = `-- name: SearchVisibleConversationsWithMessages :many
+SELECT DISTINCT c.conversation_id, c.slug, c.user_initiated, c.created_at, c.updated_at, c.cwd, c.archived, c.parent_conversation_id, c.model, c.pinned, c.repo_root, c.repo_worktree, c.schedule_id, c.trigger_id, c.owner_email FROM conversations c
+LEFT JOIN messages m ON c.conversation_id = m.conversation_id AND m.type IN ('user', 'agent')
+WHERE c.archived = FALSE
+  AND (
+    c.slug LIKE '%' || CAST(?1 AS TEXT) || '%'
+    OR json_extract(m.user_data, '$.text') LIKE '%' || CAST(?1 AS TEXT) || '%'
+    OR m.llm_data LIKE '%' || CAST(?1 AS TEXT) || '%'
+  )
+  AND (CAST(?2 AS TEXT) IS NULL OR c.owner_email IS NULL OR c.owner_email = CAST(?2 AS TEXT) OR EXISTS (
+    SELECT 1 FROM conversation_shares sh
+    WHERE sh.conversation_id = COALESCE(c.parent_conversation_id, c.conversation_id) AND sh.email = CAST(?2 AS TEXT)
+  ))
+ORDER BY c.updated_at DESC
+LIMIT ?4 OFFSET ?3
+`
+
+type SearchVisibleConversationsWithMessagesParams struct {
+	Query  string  `json:"query"`
+	Viewer *string `json:"viewer"`
+	Offset int64   `json:"offset"`
+	Limit  int64   `json:"limit"`
+}
+
+// Like SearchConversationsWithMessages, limited to what viewer may see in
+// multi-user mode.
+func (q *Queries) SearchVisibleConversationsWithMessages(ctx context.Context, arg SearchVisibleConversationsWithMessagesParams) ([]Conversation, error) {
+	rows, err := q.db.QueryContext(ctx, searchVisibleConversationsWithMessages,
+		arg.Query,
+		arg.Viewer,
+		arg.Offset,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Conversation{}
+	for rows.Next() {
+		var i Conversation
+		if err := rows.Scan(
+			&i.ConversationID,
+			&i.Slug,
+			&i.UserInitiated,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Cwd,
+			&i.Archived,
+			&i.ParentConversationID,
+			&i.Model,
+			&i.Pinned,
+			&i.RepoRoot,
+			&i.RepoWorktree,
+			&i.ScheduleID,
+			&i.TriggerID,
+			&i.OwnerEmail,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setConversationPinned = `-- name: SetConversationPinned :one
+UPDATE conversations
+SET pinned = ?
+WHERE conversation_id = ?
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email
+`
+
+type SetConversationPinnedParams struct {
+	Pinned         bool   `json:"pinned"`
+	ConversationID string `json:"conversation_id"`
+}
+
+func (q *Queries) SetConversationPinned(ctx context.Context, arg SetConversationPinnedParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, setConversationPinned, arg.Pinned, arg.ConversationID)
+	var i Conversation
+	err := row.Scan(
+		&i.ConversationID,
+		&i.Slug,
+		&i.UserInitiated,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Cwd,
+		&i.Archived,
+		&i.ParentConversationID,
+		&i.Model,
+		&i.Pinned,
+		&i.RepoRoot,
+		&i.RepoWorktree,
+		&i.ScheduleID,
+		&i.TriggerID,
+		&i.OwnerEmail,
+	)
+	return i, err
+}
+
 const unarchiveConversation = `-- name: UnarchiveConversation :one
 UPDATE conversations
 SET archived = FALSE
 WHERE conversation_id = ?
-RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email
 `
 
 func (q *Queries) UnarchiveConversation(ctx context.Context, conversationID string) (Conversation, error) {
@@ -546,6 +901,12 @@ func (q *Queries) UnarchiveConversation(ctx context.Context, conversationID stri
 		&i.Archived,
 		&i.ParentConversationID,
 		&i.Model,
+		&i.Pinned,
+		&i.RepoRoot,
+		&i.RepoWorktree,
+		&i.ScheduleID,
+		&i.TriggerID,
+		&i.OwnerEmail,
 	)
 	return i, err
 }
@@ -554,7 +915,7 @@ const updateConversationCwd = `-- name: UpdateConversationCwd :one
 UPDATE conversations
 SET cwd = ?, updated_at = CURRENT_TIMESTAMP
 WHERE conversation_id = ?
-RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email
 `
 
 type UpdateConversationCwdParams struct {
@@ -575,6 +936,12 @@ func (q *Queries) UpdateConversationCwd(ctx context.Context, arg UpdateConversat
 		&i.Archived,
 		&i.ParentConversationID,
 		&i.Model,
+		&i.Pinned,
+		&i.RepoRoot,
+		&i.RepoWorktree,
+		&i.ScheduleID,
+		&i.TriggerID,
+		&i.OwnerEmail,
 	)
 	return i, err
 }
@@ -599,7 +966,7 @@ const updateConversationSlug = `-- name: UpdateConversationSlug :one
 UPDATE conversations
 SET slug = ?, updated_at = CURRENT_TIMESTAMP
 WHERE conversation_id = ?
-RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model
+RETURNING conversation_id, slug, user_initiated, created_at, updated_at, cwd, archived, parent_conversation_id, model, pinned, repo_root, repo_worktree, schedule_id, trigger_id, owner_email
 `
 
 type UpdateConversationSlugParams struct {
@@ -620,6 +987,12 @@ func (q *Queries) UpdateConversationSlug(ctx context.Context, arg UpdateConversa
 		&i.Archived,
 		&i.ParentConversationID,
 		&i.Model,
+		&i.Pinned,
+		&i.RepoRoot,
+		&i.RepoWorktree,
+		&i.ScheduleID,
+		&i.TriggerID,
+		&i.OwnerEmail,
 	)
 	return i, err
 }
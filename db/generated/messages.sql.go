@@ -104,6 +104,19 @@ func (q *Queries) DeleteMessage(ctx context.Context, messageID string) error {
 	return err
 }
 
+const getConversationCost = `-- name: GetConversationCost :one
+SELECT CAST(COALESCE(SUM(json_extract(usage_data, '$.cost_usd')), 0) AS REAL) AS cost_usd
+FROM messages
+WHERE conversation_id = ?
+`
+
+func (q *Queries) GetConversationCost(ctx context.Context, conversationID string) (float64, error) {
+	row := q.db.QueryRowContext(ctx, getConversationCost, conversationID)
+	var cost_usd float64
+	err := row.Scan(&cost_usd)
+	return cost_usd, err
+}
+
 const getLatestMessage = `-- name: GetLatestMessage :one
 SELECT message_id, conversation_id, sequence_id, type, llm_data, user_data, usage_data, created_at, display_data, excluded_from_context FROM messages
 WHERE conversation_id = ?
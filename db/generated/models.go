@@ -8,6 +8,32 @@ import (
 	"time"
 )
 
+type ApiToken struct {
+	TokenID    string     `json:"token_id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	TokenHash  string     `json:"token_hash"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type AuditLog struct {
+	AuditID        int64     `json:"audit_id"`
+	Kind           string    `json:"kind"`
+	ConversationID *string   `json:"conversation_id"`
+	Actor          *string   `json:"actor"`
+	Cwd            *string   `json:"cwd"`
+	Command        *string   `json:"command"`
+	ExitCode       *int64    `json:"exit_code"`
+	DurationMs     *int64    `json:"duration_ms"`
+	Path           *string   `json:"path"`
+	BeforeHash     *string   `json:"before_hash"`
+	AfterHash      *string   `json:"after_hash"`
+	Url            *string   `json:"url"`
+	Error          *string   `json:"error"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
 type Conversation struct {
 	ConversationID       string    `json:"conversation_id"`
 	Slug                 *string   `json:"slug"`
@@ -18,6 +44,32 @@ type Conversation struct {
 	Archived             bool      `json:"archived"`
 	ParentConversationID *string   `json:"parent_conversation_id"`
 	Model                *string   `json:"model"`
+	Pinned               bool      `json:"pinned"`
+	RepoRoot             *string   `json:"repo_root"`
+	RepoWorktree         *string   `json:"repo_worktree"`
+	ScheduleID           *string   `json:"schedule_id"`
+	TriggerID            *string   `json:"trigger_id"`
+	OwnerEmail           *string   `json:"owner_email"`
+}
+
+type ConversationBranch struct {
+	ConversationID string    `json:"conversation_id"`
+	Branch         string    `json:"branch"`
+	FirstSeenAt    time.Time `json:"first_seen_at"`
+	LastSeenAt     time.Time `json:"last_seen_at"`
+}
+
+type ConversationShare struct {
+	ConversationID string    `json:"conversation_id"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type ConversationTag struct {
+	ConversationID string    `json:"conversation_id"`
+	Tag            string    `json:"tag"`
+	CreatedAt      time.Time `json:"created_at"`
 }
 
 type LlmRequest struct {
@@ -78,8 +130,93 @@ type NotificationChannel struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+type NotificationDelivery struct {
+	DeliveryID     string     `json:"delivery_id"`
+	ChannelID      string     `json:"channel_id"`
+	EventType      string     `json:"event_type"`
+	ConversationID *string    `json:"conversation_id"`
+	Event          string     `json:"event"`
+	Status         string     `json:"status"`
+	Attempts       int64      `json:"attempts"`
+	LastError      *string    `json:"last_error"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type NotificationReplyToken struct {
+	TokenHash      string    `json:"token_hash"`
+	ConversationID string    `json:"conversation_id"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type RepoMap struct {
+	Worktree   string    `json:"worktree"`
+	CommitHash string    `json:"commit_hash"`
+	Content    string    `json:"content"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type Schedule struct {
+	ScheduleID         string     `json:"schedule_id"`
+	Name               string     `json:"name"`
+	Cron               string     `json:"cron"`
+	Timezone           string     `json:"timezone"`
+	Prompt             string     `json:"prompt"`
+	Cwd                *string    `json:"cwd"`
+	Model              *string    `json:"model"`
+	Enabled            bool       `json:"enabled"`
+	OverlapPolicy      string     `json:"overlap_policy"`
+	NextRunAt          *time.Time `json:"next_run_at"`
+	LastRunAt          *time.Time `json:"last_run_at"`
+	LastConversationID *string    `json:"last_conversation_id"`
+	PendingRuns        int64      `json:"pending_runs"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
 type Setting struct {
 	Key       string    `json:"key"`
 	Value     string    `json:"value"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+type Trigger struct {
+	TriggerID          string     `json:"trigger_id"`
+	Name               string     `json:"name"`
+	Secret             string     `json:"secret"`
+	PromptTemplate     string     `json:"prompt_template"`
+	Cwd                *string    `json:"cwd"`
+	Model              *string    `json:"model"`
+	Mode               string     `json:"mode"`
+	CallbackUrl        *string    `json:"callback_url"`
+	Enabled            bool       `json:"enabled"`
+	LastTriggeredAt    *time.Time `json:"last_triggered_at"`
+	LastConversationID *string    `json:"last_conversation_id"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+type User struct {
+	UserID       string     `json:"user_id"`
+	Email        string     `json:"email"`
+	PasswordHash *string    `json:"password_hash"`
+	LastLoginAt  *time.Time `json:"last_login_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type UserSession struct {
+	SessionHash string    `json:"session_hash"`
+	UserID      string    `json:"user_id"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type UserSetting struct {
+	Email     string    `json:"email"`
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
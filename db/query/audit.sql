@@ -0,0 +1,15 @@
+-- name: InsertAuditEntry :exec
+INSERT INTO audit_log (kind, conversation_id, actor, cwd, command, exit_code, duration_ms, path, before_hash, after_hash, url, error)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+
+-- name: ListAuditEntries :many
+-- Newest first. Every filter is optional; a NULL argument disables it.
+-- before_id pages backwards through the log.
+SELECT * FROM audit_log
+WHERE (CAST(sqlc.narg('kind') AS TEXT) IS NULL OR kind = CAST(sqlc.narg('kind') AS TEXT))
+  AND (CAST(sqlc.narg('conversation_id') AS TEXT) IS NULL OR conversation_id = CAST(sqlc.narg('conversation_id') AS TEXT))
+  AND (CAST(sqlc.narg('since') AS TEXT) IS NULL OR datetime(created_at) >= datetime(CAST(sqlc.narg('since') AS TEXT)))
+  AND (CAST(sqlc.narg('until') AS TEXT) IS NULL OR datetime(created_at) < datetime(CAST(sqlc.narg('until') AS TEXT)))
+  AND (CAST(sqlc.narg('before_id') AS INTEGER) IS NULL OR audit_id < CAST(sqlc.narg('before_id') AS INTEGER))
+ORDER BY audit_id DESC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,58 @@
+-- name: ListUsers :many
+SELECT * FROM users ORDER BY created_at ASC;
+
+-- name: GetUser :one
+SELECT * FROM users WHERE user_id = ?;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users WHERE email = ?;
+
+-- name: CreateUser :one
+INSERT INTO users (user_id, email, password_hash)
+VALUES (?, ?, ?)
+RETURNING *;
+
+-- name: SetUserPassword :exec
+UPDATE users SET password_hash = ? WHERE user_id = ?;
+
+-- name: RecordUserLogin :exec
+UPDATE users SET last_login_at = ? WHERE user_id = ?;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE user_id = ?;
+
+-- name: CreateUserSession :exec
+INSERT INTO user_sessions (session_hash, user_id, expires_at)
+VALUES (?, ?, ?);
+
+-- name: GetUserSession :one
+SELECT * FROM user_sessions WHERE session_hash = ?;
+
+-- name: ListUserSessionExpiries :many
+SELECT session_hash, expires_at FROM user_sessions;
+
+-- name: DeleteUserSession :exec
+DELETE FROM user_sessions WHERE session_hash = ?;
+
+-- name: DeleteUserSessions :exec
+DELETE FROM user_sessions WHERE user_id = ?;
+
+-- name: ListAPITokens :many
+SELECT * FROM api_tokens WHERE user_id = ? ORDER BY created_at ASC;
+
+-- name: GetAPITokenByHash :one
+SELECT * FROM api_tokens WHERE token_hash = ?;
+
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (token_id, user_id, name, token_hash)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: TouchAPIToken :exec
+UPDATE api_tokens SET last_used_at = ? WHERE token_id = ?;
+
+-- name: DeleteAPIToken :execrows
+DELETE FROM api_tokens WHERE token_id = ? AND user_id = ?;
+
+-- name: DeleteUserAPITokens :exec
+DELETE FROM api_tokens WHERE user_id = ?;
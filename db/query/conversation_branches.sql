@@ -0,0 +1,30 @@
+-- name: UpdateConversationGitRepo :exec
+UPDATE conversations
+SET repo_root = ?, repo_worktree = ?
+WHERE conversation_id = ?;
+
+-- name: AddConversationBranch :exec
+INSERT INTO conversation_branches (conversation_id, branch)
+VALUES (?, ?)
+ON CONFLICT(conversation_id, branch) DO UPDATE SET last_seen_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteConversationBranches :exec
+DELETE FROM conversation_branches
+WHERE conversation_id = ?;
+
+-- name: ListConversationBranches :many
+SELECT branch FROM conversation_branches
+WHERE conversation_id = ?
+ORDER BY first_seen_at ASC, branch ASC;
+
+-- name: ListAllConversationBranches :many
+SELECT conversation_id, branch FROM conversation_branches
+ORDER BY conversation_id, first_seen_at ASC, branch ASC;
+
+-- name: ListRepoConversations :many
+-- Top-level, non-archived conversations that have a known repository,
+-- optionally restricted to one repository.
+SELECT * FROM conversations
+WHERE repo_root IS NOT NULL AND archived = FALSE AND parent_conversation_id IS NULL
+  AND (CAST(sqlc.narg('repo_root') AS TEXT) IS NULL OR repo_root = CAST(sqlc.narg('repo_root') AS TEXT))
+ORDER BY updated_at DESC;
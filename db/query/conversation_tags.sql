@@ -0,0 +1,29 @@
+-- name: AddConversationTag :exec
+INSERT INTO conversation_tags (conversation_id, tag)
+VALUES (?, ?)
+ON CONFLICT(conversation_id, tag) DO NOTHING;
+
+-- name: RemoveConversationTag :exec
+DELETE FROM conversation_tags
+WHERE conversation_id = ? AND tag = ?;
+
+-- name: DeleteConversationTags :exec
+DELETE FROM conversation_tags
+WHERE conversation_id = ?;
+
+-- name: ListConversationTags :many
+SELECT tag FROM conversation_tags
+WHERE conversation_id = ?
+ORDER BY tag ASC;
+
+-- name: ListAllConversationTags :many
+SELECT conversation_id, tag FROM conversation_tags
+ORDER BY conversation_id, tag ASC;
+
+-- name: ListTagCounts :many
+SELECT t.tag, COUNT(*) AS count
+FROM conversation_tags t
+JOIN conversations c ON c.conversation_id = t.conversation_id
+WHERE c.archived = FALSE
+GROUP BY t.tag
+ORDER BY t.tag ASC;
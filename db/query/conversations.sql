@@ -1,6 +1,6 @@
 -- name: CreateConversation :one
-INSERT INTO conversations (conversation_id, slug, user_initiated, cwd, model)
-VALUES (?, ?, ?, ?, ?)
+INSERT INTO conversations (conversation_id, slug, user_initiated, cwd, model, owner_email)
+VALUES (?, ?, ?, ?, ?, ?)
 RETURNING *;
 
 -- name: GetConversation :one
@@ -14,9 +14,44 @@ WHERE slug = ?;
 -- name: ListConversations :many
 SELECT * FROM conversations
 WHERE archived = FALSE AND parent_conversation_id IS NULL
-ORDER BY updated_at DESC
+ORDER BY pinned DESC, updated_at DESC
 LIMIT ? OFFSET ?;
 
+-- name: ListConversationsFiltered :many
+-- Every filter is optional; a NULL argument disables that filter.
+SELECT c.* FROM conversations c
+WHERE c.archived = FALSE AND c.parent_conversation_id IS NULL
+  AND (CAST(sqlc.narg('query') AS TEXT) IS NULL OR c.slug LIKE '%' || CAST(sqlc.narg('query') AS TEXT) || '%')
+  AND (CAST(sqlc.narg('tag') AS TEXT) IS NULL OR EXISTS (
+    SELECT 1 FROM conversation_tags t
+    WHERE t.conversation_id = c.conversation_id AND t.tag = CAST(sqlc.narg('tag') AS TEXT)
+  ))
+  AND (CAST(sqlc.narg('cwd') AS TEXT) IS NULL OR c.cwd = CAST(sqlc.narg('cwd') AS TEXT) OR c.cwd LIKE CAST(sqlc.narg('cwd') AS TEXT) || '/%')
+  AND (CAST(sqlc.narg('repo_root') AS TEXT) IS NULL OR c.repo_root = CAST(sqlc.narg('repo_root') AS TEXT))
+  AND (CAST(sqlc.narg('branch') AS TEXT) IS NULL OR EXISTS (
+    SELECT 1 FROM conversation_branches b
+    WHERE b.conversation_id = c.conversation_id AND b.branch = CAST(sqlc.narg('branch') AS TEXT)
+  ))
+  AND (CAST(sqlc.narg('model') AS TEXT) IS NULL OR c.model = CAST(sqlc.narg('model') AS TEXT))
+  AND (CAST(sqlc.narg('updated_after') AS TEXT) IS NULL OR datetime(c.updated_at) >= datetime(CAST(sqlc.narg('updated_after') AS TEXT)))
+  AND (CAST(sqlc.narg('updated_before') AS TEXT) IS NULL OR datetime(c.updated_at) < datetime(CAST(sqlc.narg('updated_before') AS TEXT)))
+  AND (CAST(sqlc.narg('pinned') AS BOOLEAN) IS NULL OR c.pinned = CAST(sqlc.narg('pinned') AS BOOLEAN))
+  AND (CAST(sqlc.narg('has_subagents') AS BOOLEAN) IS NULL OR EXISTS (
+    SELECT 1 FROM conversations s WHERE s.parent_conversation_id = c.conversation_id
+  ) = CAST(sqlc.narg('has_subagents') AS BOOLEAN))
+  AND (CAST(sqlc.narg('viewer') AS TEXT) IS NULL OR c.owner_email IS NULL OR c.owner_email = CAST(sqlc.narg('viewer') AS TEXT) OR EXISTS (
+    SELECT 1 FROM conversation_shares sh
+    WHERE sh.conversation_id = COALESCE(c.parent_conversation_id, c.conversation_id) AND sh.email = CAST(sqlc.narg('viewer') AS TEXT)
+  ))
+  AND (CAST(sqlc.narg('content_query') AS TEXT) IS NULL OR c.slug LIKE '%' || CAST(sqlc.narg('content_query') AS TEXT) || '%' OR EXISTS (
+    SELECT 1 FROM messages m
+    WHERE m.conversation_id = c.conversation_id AND m.type IN ('user', 'agent')
+      AND (json_extract(m.user_data, '$.text') LIKE '%' || CAST(sqlc.narg('content_query') AS TEXT) || '%'
+        OR m.llm_data LIKE '%' || CAST(sqlc.narg('content_query') AS TEXT) || '%')
+  ))
+ORDER BY c.pinned DESC, c.updated_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
 -- name: ListArchivedConversations :many
 SELECT * FROM conversations
 WHERE archived = TRUE
@@ -43,6 +78,37 @@ WHERE c.archived = FALSE
 ORDER BY c.updated_at DESC
 LIMIT ? OFFSET ?;
 
+-- name: SearchVisibleConversationsWithMessages :many
+-- Like SearchConversationsWithMessages, limited to what viewer may see in
+-- multi-user mode.
+SELECT DISTINCT c.* FROM conversations c
+LEFT JOIN messages m ON c.conversation_id = m.conversation_id AND m.type IN ('user', 'agent')
+WHERE c.archived = FALSE
+  AND (
+    c.slug LIKE '%' || CAST(sqlc.arg('query') AS TEXT) || '%'
+    OR json_extract(m.user_data, '$.text') LIKE '%' || CAST(sqlc.arg('query') AS TEXT) || '%'
+    OR m.llm_data LIKE '%' || CAST(sqlc.arg('query') AS TEXT) || '%'
+  )
+  AND (CAST(sqlc.narg('viewer') AS TEXT) IS NULL OR c.owner_email IS NULL OR c.owner_email = CAST(sqlc.narg('viewer') AS TEXT) OR EXISTS (
+    SELECT 1 FROM conversation_shares sh
+    WHERE sh.conversation_id = COALESCE(c.parent_conversation_id, c.conversation_id) AND sh.email = CAST(sqlc.narg('viewer') AS TEXT)
+  ))
+ORDER BY c.updated_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListVisibleArchivedConversations :many
+-- Archived conversations viewer may see in multi-user mode, optionally
+-- searched by slug.
+SELECT c.* FROM conversations c
+WHERE c.archived = TRUE
+  AND (CAST(sqlc.narg('query') AS TEXT) IS NULL OR c.slug LIKE '%' || CAST(sqlc.narg('query') AS TEXT) || '%')
+  AND (CAST(sqlc.narg('viewer') AS TEXT) IS NULL OR c.owner_email IS NULL OR c.owner_email = CAST(sqlc.narg('viewer') AS TEXT) OR EXISTS (
+    SELECT 1 FROM conversation_shares sh
+    WHERE sh.conversation_id = COALESCE(c.parent_conversation_id, c.conversation_id) AND sh.email = CAST(sqlc.narg('viewer') AS TEXT)
+  ))
+ORDER BY c.updated_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
 -- name: SearchArchivedConversations :many
 SELECT * FROM conversations
 WHERE slug LIKE '%' || ? || '%' AND archived = TRUE
@@ -76,6 +142,12 @@ SET archived = TRUE
 WHERE conversation_id = ?
 RETURNING *;
 
+-- name: SetConversationPinned :one
+UPDATE conversations
+SET pinned = ?
+WHERE conversation_id = ?
+RETURNING *;
+
 -- name: UnarchiveConversation :one
 UPDATE conversations
 SET archived = FALSE
@@ -90,8 +162,12 @@ RETURNING *;
 
 
 -- name: CreateSubagentConversation :one
-INSERT INTO conversations (conversation_id, slug, user_initiated, cwd, parent_conversation_id)
-VALUES (?, ?, FALSE, ?, ?)
+-- Subagent conversations belong to the owner of their parent.
+INSERT INTO conversations (conversation_id, slug, user_initiated, cwd, parent_conversation_id, owner_email)
+VALUES (
+  sqlc.arg('conversation_id'), sqlc.arg('slug'), FALSE, sqlc.arg('cwd'), sqlc.arg('parent_conversation_id'),
+  (SELECT p.owner_email FROM conversations p WHERE p.conversation_id = sqlc.arg('parent_conversation_id'))
+)
 RETURNING *;
 
 -- name: GetSubagents :many
@@ -62,3 +62,8 @@ ORDER BY sequence_id ASC;
 
 -- name: UpdateMessageUserData :exec
 UPDATE messages SET user_data = ? WHERE message_id = ?;
+
+-- name: GetConversationCost :one
+SELECT CAST(COALESCE(SUM(json_extract(usage_data, '$.cost_usd')), 0) AS REAL) AS cost_usd
+FROM messages
+WHERE conversation_id = ?;
@@ -0,0 +1,45 @@
+-- name: ListConversationShares :many
+SELECT * FROM conversation_shares WHERE conversation_id = ? ORDER BY created_at ASC;
+
+-- name: ListSharesForEmail :many
+SELECT * FROM conversation_shares WHERE email = ?;
+
+-- name: GetConversationShare :one
+SELECT * FROM conversation_shares WHERE conversation_id = ? AND email = ?;
+
+-- name: UpsertConversationShare :one
+INSERT INTO conversation_shares (conversation_id, email, role)
+VALUES (?, ?, ?)
+ON CONFLICT (conversation_id, email) DO UPDATE SET role = excluded.role
+RETURNING *;
+
+-- name: DeleteConversationShare :execrows
+DELETE FROM conversation_shares WHERE conversation_id = ? AND email = ?;
+
+-- name: DeleteConversationShares :exec
+DELETE FROM conversation_shares WHERE conversation_id = ?;
+
+-- name: GetUserSetting :one
+SELECT value FROM user_settings WHERE email = ? AND key = ?;
+
+-- name: ListUserSettings :many
+SELECT key, value FROM user_settings WHERE email = ?;
+
+-- name: ListUserSettingsByKey :many
+SELECT email, value FROM user_settings WHERE key = ? ORDER BY email ASC;
+
+-- name: SetUserSetting :exec
+INSERT INTO user_settings (email, key, value)
+VALUES (?, ?, ?)
+ON CONFLICT (email, key) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteUserSetting :exec
+DELETE FROM user_settings WHERE email = ? AND key = ?;
+
+-- name: ListOwnerMessageCosts :many
+-- Costs of the messages in a user's conversations, for budget checks. The
+-- caller filters by created_at.
+SELECT m.created_at, CAST(COALESCE(json_extract(m.usage_data, '$.cost_usd'), 0) AS REAL) AS cost_usd
+FROM messages m
+JOIN conversations c ON c.conversation_id = m.conversation_id
+WHERE c.owner_email = ? AND m.usage_data IS NOT NULL;
@@ -0,0 +1,60 @@
+-- name: CreateNotificationDelivery :one
+INSERT INTO notification_deliveries (delivery_id, channel_id, event_type, conversation_id, event, next_attempt_at)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetNotificationDelivery :one
+SELECT * FROM notification_deliveries WHERE delivery_id = ?;
+
+-- name: ListPendingNotificationDeliveries :many
+SELECT * FROM notification_deliveries
+WHERE status = 'pending'
+ORDER BY created_at ASC, rowid ASC
+LIMIT ?;
+
+-- name: ListNotificationDeliveries :many
+-- Every filter is optional; a NULL argument disables that filter.
+SELECT * FROM notification_deliveries
+WHERE (sqlc.narg('status') IS NULL OR status = sqlc.narg('status'))
+  AND (sqlc.narg('channel_id') IS NULL OR channel_id = sqlc.narg('channel_id'))
+ORDER BY created_at DESC, rowid DESC
+LIMIT sqlc.arg('limit');
+
+-- name: MarkNotificationDelivered :exec
+UPDATE notification_deliveries
+SET status = 'delivered',
+    attempts = attempts + 1,
+    last_error = NULL,
+    delivered_at = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE delivery_id = ?;
+
+-- name: MarkNotificationDeliveryFailed :exec
+-- Records a failed attempt. status is 'pending' to retry at next_attempt_at
+-- or 'failed' to give up.
+UPDATE notification_deliveries
+SET status = ?,
+    attempts = attempts + 1,
+    last_error = ?,
+    next_attempt_at = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE delivery_id = ?;
+
+-- name: RetryNotificationDelivery :one
+UPDATE notification_deliveries
+SET status = 'pending',
+    next_attempt_at = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE delivery_id = ?
+RETURNING *;
+
+-- name: PruneNotificationDeliveries :exec
+-- Keeps the newest finished deliveries; pending ones are never pruned.
+DELETE FROM notification_deliveries
+WHERE status != 'pending'
+  AND delivery_id NOT IN (
+    SELECT delivery_id FROM notification_deliveries
+    WHERE status != 'pending'
+    ORDER BY created_at DESC, rowid DESC
+    LIMIT ?
+  );
@@ -0,0 +1,15 @@
+-- name: CreateNotificationReplyToken :exec
+INSERT INTO notification_reply_tokens (token_hash, conversation_id, expires_at)
+VALUES (?, ?, ?);
+
+-- name: GetNotificationReplyToken :one
+SELECT * FROM notification_reply_tokens WHERE token_hash = ?;
+
+-- name: ListNotificationReplyTokenExpiries :many
+SELECT token_hash, expires_at FROM notification_reply_tokens;
+
+-- name: DeleteNotificationReplyToken :execrows
+DELETE FROM notification_reply_tokens WHERE token_hash = ?;
+
+-- name: DeleteConversationReplyTokens :exec
+DELETE FROM notification_reply_tokens WHERE conversation_id = ?;
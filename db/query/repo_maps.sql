@@ -0,0 +1,21 @@
+-- name: GetRepoMap :one
+SELECT content FROM repo_maps
+WHERE worktree = ? AND commit_hash = ?;
+
+-- name: UpsertRepoMap :exec
+INSERT INTO repo_maps (worktree, commit_hash, content)
+VALUES (?, ?, ?)
+ON CONFLICT(worktree, commit_hash) DO UPDATE SET
+    content = excluded.content,
+    created_at = CURRENT_TIMESTAMP;
+
+-- name: PruneRepoMaps :exec
+-- Keeps the newest maps of a worktree.
+DELETE FROM repo_maps
+WHERE worktree = sqlc.arg('worktree')
+  AND commit_hash NOT IN (
+    SELECT commit_hash FROM repo_maps
+    WHERE worktree = sqlc.arg('worktree')
+    ORDER BY created_at DESC, rowid DESC
+    LIMIT sqlc.arg('keep')
+  );
@@ -0,0 +1,54 @@
+-- name: ListSchedules :many
+SELECT * FROM schedules ORDER BY created_at ASC;
+
+-- name: GetSchedule :one
+SELECT * FROM schedules WHERE schedule_id = ?;
+
+-- name: ListEnabledSchedules :many
+SELECT * FROM schedules WHERE enabled = TRUE ORDER BY created_at ASC;
+
+-- name: CreateSchedule :one
+INSERT INTO schedules (schedule_id, name, cron, timezone, prompt, cwd, model, enabled, overlap_policy, next_run_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: UpdateSchedule :one
+UPDATE schedules
+SET name = ?,
+    cron = ?,
+    timezone = ?,
+    prompt = ?,
+    cwd = ?,
+    model = ?,
+    enabled = ?,
+    overlap_policy = ?,
+    next_run_at = ?,
+    pending_runs = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE schedule_id = ?
+RETURNING *;
+
+-- name: UpdateScheduleNextRun :exec
+UPDATE schedules
+SET next_run_at = ?, pending_runs = ?
+WHERE schedule_id = ?;
+
+-- name: RecordScheduleRun :exec
+UPDATE schedules
+SET last_run_at = ?, last_conversation_id = ?, next_run_at = ?, pending_runs = ?
+WHERE schedule_id = ?;
+
+-- name: DeleteSchedule :exec
+DELETE FROM schedules WHERE schedule_id = ?;
+
+-- name: ClearConversationSchedule :exec
+UPDATE conversations SET schedule_id = NULL WHERE schedule_id = ?;
+
+-- name: SetConversationSchedule :exec
+UPDATE conversations SET schedule_id = ? WHERE conversation_id = ?;
+
+-- name: ListScheduleConversations :many
+SELECT * FROM conversations
+WHERE schedule_id = ?
+ORDER BY created_at DESC
+LIMIT ?;
@@ -0,0 +1,49 @@
+-- name: ListTriggers :many
+SELECT * FROM triggers ORDER BY created_at ASC;
+
+-- name: GetTrigger :one
+SELECT * FROM triggers WHERE trigger_id = ?;
+
+-- name: CreateTrigger :one
+INSERT INTO triggers (trigger_id, name, secret, prompt_template, cwd, model, mode, callback_url, enabled)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: UpdateTrigger :one
+UPDATE triggers
+SET name = ?,
+    prompt_template = ?,
+    cwd = ?,
+    model = ?,
+    mode = ?,
+    callback_url = ?,
+    enabled = ?,
+    updated_at = CURRENT_TIMESTAMP
+WHERE trigger_id = ?
+RETURNING *;
+
+-- name: SetTriggerSecret :one
+UPDATE triggers
+SET secret = ?, updated_at = CURRENT_TIMESTAMP
+WHERE trigger_id = ?
+RETURNING *;
+
+-- name: RecordTriggerRun :exec
+UPDATE triggers
+SET last_triggered_at = ?, last_conversation_id = ?
+WHERE trigger_id = ?;
+
+-- name: DeleteTrigger :exec
+DELETE FROM triggers WHERE trigger_id = ?;
+
+-- name: ClearConversationTrigger :exec
+UPDATE conversations SET trigger_id = NULL WHERE trigger_id = ?;
+
+-- name: SetConversationTrigger :exec
+UPDATE conversations SET trigger_id = ? WHERE conversation_id = ?;
+
+-- name: ListTriggerConversations :many
+SELECT * FROM conversations
+WHERE trigger_id = ?
+ORDER BY created_at DESC
+LIMIT ?;
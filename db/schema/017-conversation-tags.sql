@@ -0,0 +1,15 @@
+-- Conversation tags and pinning
+-- Tags let users group conversations; a tag containing "/" (e.g. "clients/acme")
+-- can be treated as a folder path by clients. Pinned conversations sort first.
+
+ALTER TABLE conversations ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE conversation_tags (
+    conversation_id TEXT NOT NULL REFERENCES conversations(conversation_id),
+    tag TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (conversation_id, tag)
+);
+
+-- Index on tag for filtering conversations by tag
+CREATE INDEX idx_conversation_tags_tag ON conversation_tags(tag);
@@ -0,0 +1,21 @@
+-- Git repository tracking for conversations
+-- repo_root is the main repository root (shared by all of its worktrees);
+-- repo_worktree is the checkout the conversation works in. Both are updated
+-- from the git state observed by the agent loop.
+
+ALTER TABLE conversations ADD COLUMN repo_root TEXT;
+ALTER TABLE conversations ADD COLUMN repo_worktree TEXT;
+
+CREATE INDEX idx_conversations_repo_root ON conversations(repo_root);
+
+-- Branches a conversation has been on, in the order they were first seen
+CREATE TABLE conversation_branches (
+    conversation_id TEXT NOT NULL REFERENCES conversations(conversation_id),
+    branch TEXT NOT NULL,
+    first_seen_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (conversation_id, branch)
+);
+
+-- Index on branch for filtering conversations by branch
+CREATE INDEX idx_conversation_branches_branch ON conversation_branches(branch);
@@ -0,0 +1,28 @@
+-- Scheduled conversations
+-- A schedule starts a new conversation with a fixed prompt whenever its cron
+-- expression fires. Runs are grouped under the schedule through
+-- conversations.schedule_id. When the previous run is still working, the
+-- overlap policy decides whether the new run is skipped or queued
+-- (pending_runs counts queued runs).
+
+CREATE TABLE schedules (
+    schedule_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    cron TEXT NOT NULL,
+    timezone TEXT NOT NULL DEFAULT '',
+    prompt TEXT NOT NULL,
+    cwd TEXT,
+    model TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    overlap_policy TEXT NOT NULL DEFAULT 'skip' CHECK (overlap_policy IN ('skip', 'queue')),
+    next_run_at DATETIME,
+    last_run_at DATETIME,
+    last_conversation_id TEXT,
+    pending_runs INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE conversations ADD COLUMN schedule_id TEXT;
+
+CREATE INDEX idx_conversations_schedule_id ON conversations(schedule_id);
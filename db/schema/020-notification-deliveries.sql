@@ -0,0 +1,24 @@
+-- Notification delivery outbox
+-- Every event sent to a stored notification channel is written here first
+-- and delivered by a background worker, which retries failures with
+-- exponential backoff. Finished rows stay behind as the delivery log.
+-- status is 'pending' until the event is delivered ('delivered') or the
+-- worker gives up ('failed').
+
+CREATE TABLE notification_deliveries (
+    delivery_id TEXT PRIMARY KEY,
+    channel_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    conversation_id TEXT,
+    event TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_notification_deliveries_status ON notification_deliveries(status, created_at);
+CREATE INDEX idx_notification_deliveries_channel ON notification_deliveries(channel_id, created_at);
@@ -0,0 +1,27 @@
+-- Incoming webhook triggers
+-- A trigger turns an authenticated POST to /api/triggers/<id> into a prompt
+-- (rendered from prompt_template) and starts a new conversation or, in
+-- 'continue' mode, continues the trigger's last one. Conversations are
+-- grouped under the trigger through conversations.trigger_id. secret is
+-- accepted as a bearer token or used to check an HMAC-SHA256 signature of
+-- the body, and signs the optional callback to callback_url.
+
+CREATE TABLE triggers (
+    trigger_id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    prompt_template TEXT NOT NULL,
+    cwd TEXT,
+    model TEXT,
+    mode TEXT NOT NULL DEFAULT 'new' CHECK (mode IN ('new', 'continue')),
+    callback_url TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_triggered_at DATETIME,
+    last_conversation_id TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE conversations ADD COLUMN trigger_id TEXT;
+
+CREATE INDEX idx_conversations_trigger_id ON conversations(trigger_id);
@@ -0,0 +1,16 @@
+-- Notification reply tokens
+-- agent_done and agent_error notifications carry a reply URL,
+-- /api/notification-replies/<token>. A message POSTed there is queued as the
+-- next user message of the conversation the token belongs to. Only the
+-- token's SHA-256 hash is stored, like API tokens and sessions. A token is
+-- deleted once its reply is accepted, and expires so that old notifications
+-- stop accepting replies.
+
+CREATE TABLE notification_reply_tokens (
+    token_hash TEXT PRIMARY KEY,
+    conversation_id TEXT NOT NULL REFERENCES conversations(conversation_id),
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_notification_reply_tokens_expires ON notification_reply_tokens(expires_at);
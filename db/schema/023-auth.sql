@@ -0,0 +1,34 @@
+-- Built-in authentication
+-- Optional; enabled with `shelley serve -auth`. Users sign in to the web UI
+-- with a password or through an OIDC provider (password_hash is NULL for
+-- users who only use OIDC), which creates a session. Scripts and the CLI
+-- client use personal API tokens instead. Session and API token secrets are
+-- never stored, only their SHA-256 hashes.
+
+CREATE TABLE users (
+    user_id TEXT PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE COLLATE NOCASE,
+    password_hash TEXT,
+    last_login_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_sessions (
+    session_hash TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(user_id),
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_user_sessions_user_id ON user_sessions(user_id);
+
+CREATE TABLE api_tokens (
+    token_id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(user_id),
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    last_used_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_api_tokens_user_id ON api_tokens(user_id);
@@ -0,0 +1,33 @@
+-- Multi-user mode
+-- Conversations record the email of the user who started them (subagent
+-- conversations copy their parent's). With `shelley serve -multi-user`, users
+-- only see conversations they own, conversations shared with them, and
+-- conversations without an owner (those started over the Unix socket, by
+-- schedules or by triggers). Sharing grants 'read' or 'collaborate' (send
+-- messages, rename, archive) rights on a top-level conversation and its
+-- subagents.
+--
+-- user_settings holds per-user overrides of settings, keyed by email, and
+-- each user's monthly_budget_usd.
+
+ALTER TABLE conversations ADD COLUMN owner_email TEXT;
+
+CREATE INDEX idx_conversations_owner_email ON conversations(owner_email);
+
+CREATE TABLE conversation_shares (
+    conversation_id TEXT NOT NULL REFERENCES conversations(conversation_id),
+    email TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('read', 'collaborate')),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (conversation_id, email)
+);
+
+CREATE INDEX idx_conversation_shares_email ON conversation_shares(email);
+
+CREATE TABLE user_settings (
+    email TEXT NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (email, key)
+);
@@ -0,0 +1,35 @@
+-- Audit log
+-- An append-only record of what was done on the machine: bash commands,
+-- file patches, browser navigations and web terminal sessions. It has no
+-- foreign keys, so entries outlive the conversations they came from, and
+-- triggers refuse updates and deletes.
+
+CREATE TABLE audit_log (
+    audit_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL, -- 'bash', 'patch', 'browser_navigate' or 'terminal'
+    conversation_id TEXT, -- NULL for terminal sessions
+    actor TEXT, -- email of the user who opened a terminal session, if known
+    cwd TEXT,
+    command TEXT,
+    exit_code INTEGER, -- NULL if the command didn't run to completion
+    duration_ms INTEGER,
+    path TEXT,
+    before_hash TEXT, -- sha256 of the file before a patch; NULL if it didn't exist
+    after_hash TEXT,
+    url TEXT,
+    error TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+CREATE INDEX idx_audit_log_conversation_id ON audit_log(conversation_id);
+
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
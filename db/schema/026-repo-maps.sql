@@ -0,0 +1,11 @@
+-- Repository maps
+-- The map of a repository's code included in the system prompt, cached
+-- per worktree and commit since building one reads the whole tree.
+
+CREATE TABLE repo_maps (
+    worktree TEXT NOT NULL,
+    commit_hash TEXT NOT NULL,
+    content TEXT NOT NULL, -- empty if the repository has no code to map
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (worktree, commit_hash)
+);
@@ -175,6 +175,10 @@ type Content struct {
 	// Display is content to be displayed to the user, copied from ToolOut
 	Display any
 
+	// Redactions is how many secrets were redacted from a tool_result;
+	// added externally; not sent to the LLM
+	Redactions int `json:",omitempty"`
+
 	Cache bool
 }
 
@@ -1,7 +1,10 @@
 package loop
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -35,6 +38,13 @@ type Config struct {
 	// If set, this is called at end of turn to check for git state changes.
 	// If nil, Config.WorkingDir is used as a static value.
 	GetWorkingDir func() string
+	// Redact, if set, replaces secrets in tool results before they are
+	// recorded or sent to the LLM, returning how many it replaced.
+	Redact func(string) (string, int)
+	// OnToolProgress, if set, is passed partial output from tools while
+	// they run. It is not redacted; chunks may split a secret, so the
+	// receiver must put lines back together before redacting them.
+	OnToolProgress func(toolUseID, toolName, chunk string)
 }
 
 // Loop manages a conversation turn with an LLM including tool execution and message recording.
@@ -53,6 +63,9 @@ type Loop struct {
 	onGitStateChange GitStateChangeFunc
 	getWorkingDir    func() string
 	lastGitState     *gitstate.GitState
+	redact           func(string) (string, int)
+	onToolProgress   func(toolUseID, toolName, chunk string)
+	toolCancels      map[string]context.CancelFunc // running tool calls, by ID
 }
 
 // NewLoop creates a new Loop instance with the provided configuration
@@ -81,6 +94,9 @@ func NewLoop(config Config) *Loop {
 		onGitStateChange: config.OnGitStateChange,
 		getWorkingDir:    config.GetWorkingDir,
 		lastGitState:     initialGitState,
+		redact:           config.Redact,
+		onToolProgress:   config.OnToolProgress,
+		toolCancels:      make(map[string]context.CancelFunc),
 	}
 }
 
@@ -452,13 +468,34 @@ func (l *Loop) executeToolCalls(ctx context.Context, content []llm.Content) erro
 		}
 
 		// Execute the tool with working directory set in context
-		toolCtx := ctx
+		toolCtx, cancel := context.WithCancel(ctx)
 		if l.workingDir != "" {
-			toolCtx = claudetool.WithWorkingDir(ctx, l.workingDir)
+			toolCtx = claudetool.WithWorkingDir(toolCtx, l.workingDir)
 		}
+		if l.onToolProgress != nil {
+			toolCtx = claudetool.WithProgress(toolCtx, func(chunk string) {
+				l.onToolProgress(c.ID, c.ToolName, chunk)
+			})
+		}
+		l.mu.Lock()
+		l.toolCancels[c.ID] = cancel
+		l.mu.Unlock()
 		startTime := time.Now()
 		result := tool.Run(toolCtx, c.ToolInput)
 		endTime := time.Now()
+		l.mu.Lock()
+		delete(l.toolCancels, c.ID)
+		l.mu.Unlock()
+		cancelled := toolCtx.Err() != nil && ctx.Err() == nil
+		cancel()
+
+		if cancelled {
+			msg := "the user cancelled this tool call"
+			if result.Error != nil {
+				msg += ": " + result.Error.Error()
+			}
+			result = llm.ToolOut{Error: errors.New(msg), Display: result.Display}
+		}
 
 		var toolResultContent []llm.Content
 		if result.Error != nil {
@@ -471,6 +508,15 @@ func (l *Loop) executeToolCalls(ctx context.Context, content []llm.Content) erro
 			l.logger.Debug("tool executed successfully", "name", c.ToolName, "duration", endTime.Sub(startTime))
 		}
 
+		display := result.Display
+		var redactions int
+		if l.redact != nil {
+			toolResultContent, display, redactions = l.redactToolResult(toolResultContent, display)
+			if redactions > 0 {
+				l.logger.Info("redacted secrets from tool result", "name", c.ToolName, "count", redactions)
+			}
+		}
+
 		toolResults = append(toolResults, llm.Content{
 			Type:             llm.ContentTypeToolResult,
 			ToolUseID:        c.ID,
@@ -478,7 +524,8 @@ func (l *Loop) executeToolCalls(ctx context.Context, content []llm.Content) erro
 			ToolResult:       toolResultContent,
 			ToolUseStartTime: &startTime,
 			ToolUseEndTime:   &endTime,
-			Display:          result.Display,
+			Display:          display,
+			Redactions:       redactions,
 		})
 	}
 
@@ -511,6 +558,82 @@ func (l *Loop) executeToolCalls(ctx context.Context, content []llm.Content) erro
 	return nil
 }
 
+// CancelToolCall cancels the running tool call with the given ID, leaving
+// the rest of the turn alone: the tool's result says that it was cancelled
+// and the agent carries on. It reports whether such a call was running.
+func (l *Loop) CancelToolCall(toolUseID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cancel, ok := l.toolCancels[toolUseID]
+	if ok {
+		cancel()
+	}
+	return ok
+}
+
+// redactToolResult returns copies of a tool's output and display data with
+// secrets redacted, and how many secrets either contained. Display data is
+// redacted string by string after a JSON round trip; if that fails, it is
+// dropped rather than risk storing a secret.
+func (l *Loop) redactToolResult(content []llm.Content, display any) ([]llm.Content, any, int) {
+	total := 0
+	redacted := make([]llm.Content, len(content))
+	for i, c := range content {
+		if c.Type == llm.ContentTypeText {
+			var n int
+			c.Text, n = l.redact(c.Text)
+			total += n
+		}
+		redacted[i] = c
+	}
+	if display == nil {
+		return redacted, nil, total
+	}
+	data, err := json.Marshal(display)
+	if err != nil {
+		return redacted, nil, total
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return redacted, nil, total
+	}
+	value, n := l.redactValue(value)
+	if n == 0 {
+		return redacted, display, total
+	}
+	total = max(total, n)
+	if data, err = json.Marshal(value); err != nil {
+		return redacted, nil, total
+	}
+	return redacted, json.RawMessage(data), total
+}
+
+// redactValue redacts the strings in a decoded JSON value, returning the
+// value and how many secrets it contained. Maps and slices are changed in
+// place.
+func (l *Loop) redactValue(value any) (any, int) {
+	total := 0
+	switch v := value.(type) {
+	case string:
+		return l.redact(v)
+	case map[string]any:
+		for key, elem := range v {
+			var n int
+			v[key], n = l.redactValue(elem)
+			total += n
+		}
+	case []any:
+		for i, elem := range v {
+			var n int
+			v[i], n = l.redactValue(elem)
+			total += n
+		}
+	}
+	return value, total
+}
+
 // insertMissingToolResults fixes tool_result issues in the conversation history:
 //  1. Adds error results for tool_uses that were requested but not included in the next message.
 //     This can happen when a request is cancelled or fails after the LLM responds with tool_use
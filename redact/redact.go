@@ -0,0 +1,205 @@
+// Package redact finds secrets in text, such as tool output, and replaces
+// them with placeholders before the text is sent to an LLM or stored.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Config configures redaction. It is the "redaction" object in shelley.json.
+type Config struct {
+	// Disabled turns redaction off entirely.
+	Disabled bool `json:"disabled"`
+	// DisableEntropy turns off the high-entropy string detector, which is
+	// the one most prone to false positives.
+	DisableEntropy bool `json:"disable_entropy"`
+	// Patterns are extra regular expressions to redact. If a pattern has
+	// a capture group, only the first group is redacted; otherwise the
+	// whole match is.
+	Patterns []string `json:"patterns"`
+	// Key is the key placeholder ids are derived from. Reusing it keeps
+	// placeholders the same across restarts; if it is empty, New picks a
+	// random one.
+	Key []byte `json:"-"`
+}
+
+type detector struct {
+	kind string
+	re   *regexp.Regexp
+}
+
+// builtinDetectors match well-known secret formats. Detectors with a capture
+// group redact only the group, leaving e.g. the variable name in place.
+var builtinDetectors = []detector{
+	{"private_key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY( BLOCK)?-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY( BLOCK)?-----`)},
+	{"aws_access_key", regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`)},
+	{"aws_secret_key", regexp.MustCompile(`(?i)aws_secret_access_key["']?\s*[=:]\s*["']?([A-Za-z0-9/+]{40})\b`)},
+	{"github_token", regexp.MustCompile(`\b((?:gh[pousr]_[A-Za-z0-9]{36,255})|(?:github_pat_[A-Za-z0-9_]{22,255}))\b`)},
+	{"api_key", regexp.MustCompile(`\b(sk-(?:ant-|proj-)?[A-Za-z0-9_-]{32,})`)},
+	{"slack_token", regexp.MustCompile(`\b(xox[abprs]-[A-Za-z0-9-]{10,})`)},
+}
+
+// entropyCandidate matches runs of characters that random tokens are made of.
+var entropyCandidate = regexp.MustCompile(`[A-Za-z0-9+/=_-]{32,}`)
+
+// minEntropy is the Shannon entropy, in bits per character, above which a
+// candidate counts as random. Hex strings such as git hashes top out at 4,
+// and long identifiers made of words stay below it too.
+const minEntropy = 4.5
+
+// hashPrefixes precede content hashes, such as those in go.sum and
+// package-lock.json, which look random but are not secrets.
+var hashPrefixes = []string{"h1:", "sha1-", "sha256-", "sha384-", "sha512-"}
+
+// A Redactor replaces secrets with placeholders of the form
+// [REDACTED:<kind>:<id>]. The id is derived from the secret with the
+// Config's Key, so the same secret gets the same placeholder for as long as
+// the key is kept without the placeholder revealing anything about it.
+type Redactor struct {
+	key       []byte
+	detectors []detector
+	entropy   bool
+}
+
+// New returns a Redactor for cfg, or nil if redaction is disabled.
+// A nil Redactor redacts nothing.
+func New(cfg Config) (*Redactor, error) {
+	if cfg.Disabled {
+		return nil, nil
+	}
+	r := &Redactor{
+		key:       cfg.Key,
+		detectors: builtinDetectors,
+		entropy:   !cfg.DisableEntropy,
+	}
+	if len(r.key) == 0 {
+		r.key = NewKey()
+	}
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		r.detectors = append(r.detectors, detector{"custom", re})
+	}
+	return r, nil
+}
+
+// NewKey returns a random key for Config.Key.
+func NewKey() []byte {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return key
+}
+
+// Redact returns s with secrets replaced, and how many were replaced.
+func (r *Redactor) Redact(s string) (string, int) {
+	if r == nil || s == "" {
+		return s, 0
+	}
+	total := 0
+	for _, d := range r.detectors {
+		var n int
+		s, n = r.replace(s, d.kind, spans(d.re, s))
+		total += n
+	}
+	if r.entropy {
+		var n int
+		s, n = r.replace(s, "high_entropy", entropySpans(s))
+		total += n
+	}
+	return s, total
+}
+
+// placeholder returns the placeholder for secret.
+func (r *Redactor) placeholder(kind, secret string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(secret))
+	return "[REDACTED:" + kind + ":" + hex.EncodeToString(mac.Sum(nil)[:4]) + "]"
+}
+
+// replace replaces the non-overlapping, ordered spans of s with placeholders.
+func (r *Redactor) replace(s, kind string, spans [][2]int) (string, int) {
+	if len(spans) == 0 {
+		return s, 0
+	}
+	var b strings.Builder
+	last := 0
+	for _, sp := range spans {
+		b.WriteString(s[last:sp[0]])
+		b.WriteString(r.placeholder(kind, s[sp[0]:sp[1]]))
+		last = sp[1]
+	}
+	b.WriteString(s[last:])
+	return b.String(), len(spans)
+}
+
+// spans returns the parts of s that re says to redact: the first capture
+// group of each match if re has one, or the whole match.
+func spans(re *regexp.Regexp, s string) [][2]int {
+	var out [][2]int
+	for _, m := range re.FindAllStringSubmatchIndex(s, -1) {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if start < end {
+			out = append(out, [2]int{start, end})
+		}
+	}
+	return out
+}
+
+// entropySpans returns the tokens in s that look like random secrets: long,
+// mixing upper case, lower case and digits, and with high entropy.
+func entropySpans(s string) [][2]int {
+	var out [][2]int
+	for _, m := range entropyCandidate.FindAllStringIndex(s, -1) {
+		token := s[m[0]:m[1]]
+		if isHash(s[:m[0]], token) {
+			continue
+		}
+		if !strings.ContainsAny(token, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") ||
+			!strings.ContainsAny(token, "abcdefghijklmnopqrstuvwxyz") ||
+			!strings.ContainsAny(token, "0123456789") {
+			continue
+		}
+		if shannonEntropy(token) >= minEntropy {
+			out = append(out, [2]int{m[0], m[1]})
+		}
+	}
+	return out
+}
+
+// isHash reports whether token, or the text before it, starts a content hash.
+func isHash(before, token string) bool {
+	for _, p := range hashPrefixes {
+		if strings.HasSuffix(before, p) || strings.HasPrefix(token, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// shannonEntropy returns the entropy of s in bits per byte.
+func shannonEntropy(s string) float64 {
+	var counts [256]int
+	for i := 0; i < len(s); i++ {
+		counts[s[i]]++
+	}
+	var h float64
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / float64(len(s))
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
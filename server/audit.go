@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+)
+
+// auditTerminal is the audit log kind of web terminal (exec-ws) sessions,
+// which the server runs itself rather than through a tool.
+const auditTerminal = "terminal"
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// recordAudit appends rec to the audit log. actor is the email of the user
+// behind the action, if known. Failures are logged, not returned: the
+// action has already happened.
+func (s *Server) recordAudit(rec claudetool.AuditRecord, actor string) {
+	optString := func(s string) *string {
+		if s == "" {
+			return nil
+		}
+		return &s
+	}
+	params := generated.InsertAuditEntryParams{
+		Kind:           rec.Kind,
+		ConversationID: optString(rec.ConversationID),
+		Actor:          optString(actor),
+		Cwd:            optString(rec.Cwd),
+		Command:        optString(rec.Command),
+		Path:           optString(rec.Path),
+		BeforeHash:     optString(rec.BeforeHash),
+		AfterHash:      optString(rec.AfterHash),
+		Url:            optString(rec.URL),
+		Error:          optString(rec.Error),
+	}
+	if rec.ExitCode != nil {
+		code := int64(*rec.ExitCode)
+		params.ExitCode = &code
+	}
+	if rec.Duration > 0 {
+		ms := rec.Duration.Milliseconds()
+		params.DurationMs = &ms
+	}
+	if err := s.db.InsertAuditEntry(context.Background(), params); err != nil {
+		s.logger.Error("Failed to record audit entry", "kind", rec.Kind, "conversationID", rec.ConversationID, "error", err)
+	}
+}
+
+// handleAudit handles GET /api/audit, which lists audit log entries newest
+// first, or with format=jsonl exports them as one JSON object per line.
+//
+//	kind=KIND            bash, patch, browser_navigate or terminal
+//	conversation_id=ID   only entries from one conversation
+//	since=TIME, until=T  RFC 3339 time range
+//	before_id=N          entries older than entry N, for paging
+//	limit=N              at most N entries (default 100, up to 1000); unlimited for jsonl
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.isAdmin(r) {
+		http.Error(w, "Only admins can read the audit log", http.StatusForbidden)
+		return
+	}
+	q := r.URL.Query()
+	filter := db.AuditFilter{
+		Kind:           q.Get("kind"),
+		ConversationID: q.Get("conversation_id"),
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "invalid "+p.name+": want an RFC 3339 time", http.StatusBadRequest)
+				return
+			}
+			*p.dst = t
+		}
+	}
+	if v := q.Get("before_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			http.Error(w, "invalid before_id", http.StatusBadRequest)
+			return
+		}
+		filter.BeforeID = id
+	}
+	jsonl := q.Get("format") == "jsonl"
+	limit := int64(defaultAuditLimit)
+	if jsonl {
+		limit = 0
+	}
+	if v := q.Get("limit"); v != "" {
+		l, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || l <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = l
+		if !jsonl && limit > maxAuditLimit {
+			limit = maxAuditLimit
+		}
+	}
+
+	if !jsonl {
+		entries, err := s.db.ListAuditEntries(r.Context(), filter, limit)
+		if err != nil {
+			s.logger.Error("Failed to list audit entries", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if entries == nil {
+			entries = []generated.AuditLog{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+		return
+	}
+
+	// Export page by page, so a large log isn't held in memory.
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="shelley-audit.jsonl"`)
+	enc := json.NewEncoder(w)
+	var written int64
+	for limit == 0 || written < limit {
+		page := int64(maxAuditLimit)
+		if limit > 0 && limit-written < page {
+			page = limit - written
+		}
+		entries, err := s.db.ListAuditEntries(r.Context(), filter, page)
+		if err != nil {
+			// Headers are gone; all we can do is stop early.
+			s.logger.Error("Failed to export audit entries", "error", err)
+			return
+		}
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return
+			}
+		}
+		written += int64(len(entries))
+		if int64(len(entries)) < page {
+			break
+		}
+		filter.BeforeID = entries[len(entries)-1].AuditID
+	}
+}
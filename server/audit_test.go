@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/db/generated"
+)
+
+func TestAuditLog(t *testing.T) {
+	h := NewTestHarness(t)
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(file, []byte("an example\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	h.NewConversation("bash: exit 3", dir)
+	h.WaitResponse()
+	waitForIdle(t, h.server, h.convID)
+	h.Chat("patch: " + file)
+	h.WaitResponse()
+	waitForIdle(t, h.server, h.convID)
+	conversationID := h.convID
+
+	get := func(path string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", path, w.Code, w.Body.String())
+		}
+		return w
+	}
+	list := func(path string) []generated.AuditLog {
+		t.Helper()
+		var entries []generated.AuditLog
+		if err := json.Unmarshal(get(path).Body.Bytes(), &entries); err != nil {
+			t.Fatal(err)
+		}
+		return entries
+	}
+
+	bash := list("/api/audit?kind=bash&conversation_id=" + conversationID)
+	if len(bash) != 1 || bash[0].Command == nil || !strings.HasPrefix(*bash[0].Command, "exit 3") ||
+		bash[0].ExitCode == nil || *bash[0].ExitCode != 3 || bash[0].Cwd == nil || *bash[0].Cwd != dir || bash[0].DurationMs == nil {
+		t.Errorf("bash entries = %+v", bash)
+	}
+	patch := list("/api/audit?kind=patch")
+	if len(patch) != 1 || patch[0].Path == nil || *patch[0].Path != file ||
+		patch[0].BeforeHash == nil || patch[0].AfterHash == nil || *patch[0].BeforeHash == *patch[0].AfterHash {
+		t.Errorf("patch entries = %+v", patch)
+	}
+
+	// The log outlives the conversation and can't be changed.
+	if err := h.db.DeleteConversation(t.Context(), conversationID); err != nil {
+		t.Fatal(err)
+	}
+	all := list("/api/audit")
+	if len(all) != 2 || all[0].AuditID <= all[1].AuditID {
+		t.Errorf("after deleting the conversation: %d entries, want 2, newest first", len(all))
+	}
+	if err := h.db.Pool().Exec(t.Context(), "DELETE FROM audit_log"); err == nil {
+		t.Error("deleted from the append-only audit log")
+	}
+
+	if page := list("/api/audit?limit=1&before_id=" + strconv.FormatInt(all[0].AuditID, 10)); len(page) != 1 || page[0].AuditID != all[1].AuditID {
+		t.Errorf("second page = %+v", page)
+	}
+
+	w := get("/api/audit?format=jsonl")
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("export Content-Type = %q", ct)
+	}
+	var lines int
+	for sc := bufio.NewScanner(w.Body); sc.Scan(); lines++ {
+		var e generated.AuditLog
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("line %d: %v", lines+1, err)
+		}
+	}
+	if lines != 2 {
+		t.Errorf("export has %d lines, want 2", lines)
+	}
+}
@@ -0,0 +1,605 @@
+package server
+
+import (
+	"context"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+)
+
+// Built-in authentication is optional. When it is enabled (see EnableAuth),
+// every request on the TCP listener needs a signed-in user except the login
+// endpoints and the requests that carry their own credentials (trigger
+// invocations and notification replies). Browsers sign in with a password
+// or through OIDC and get a session cookie; the CLI client and scripts send
+// a personal API token as a bearer token. The Unix socket stays trusted.
+
+const (
+	sessionCookieName = "shelley_session"
+	sessionTTL        = 30 * 24 * time.Hour
+
+	// apiTokenPrefix marks Shelley API tokens so they are recognizable in
+	// configuration files and secret scanners.
+	apiTokenPrefix = "shelley_"
+
+	// apiTokenTouchInterval limits how often last_used_at is written.
+	apiTokenTouchInterval = time.Minute
+
+	passwordIterations = 600_000
+	minPasswordLength  = 8
+)
+
+// AuthConfig configures built-in authentication.
+type AuthConfig struct {
+	// OIDC, if set, lets users sign in through an OpenID Connect provider
+	// in addition to passwords.
+	OIDC *OIDCConfig `json:"oidc"`
+}
+
+// EnableAuth turns on built-in authentication for the TCP listener. It must
+// be called before StartWithListeners.
+func (s *Server) EnableAuth(config AuthConfig) {
+	s.auth = &config
+}
+
+// authUser is the signed-in user of a request.
+type authUser struct {
+	*generated.User
+	// TokenID is the API token the request was made with, or "" for a
+	// browser session.
+	TokenID string
+}
+
+type authUserKey struct{}
+
+// requestUser returns the signed-in user of a request, or nil if
+// authentication is disabled or the request came in over the Unix socket.
+func requestUser(ctx context.Context) *authUser {
+	user, _ := ctx.Value(authUserKey{}).(*authUser)
+	return user
+}
+
+// hashSecret returns the hex SHA-256 of a session or API token secret.
+// Both are random, so an unsalted fast hash is enough.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// HashPassword hashes a password with PBKDF2-SHA256 for storage.
+func HashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	key, err := pbkdf2.Key(sha256.New, password, salt, passwordIterations, 32)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("pbkdf2-sha256$%d$%s$%s", passwordIterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// checkPassword reports whether password matches a hash from HashPassword.
+func checkPassword(hash, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != "pbkdf2-sha256" {
+		return false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(want))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(got, want) == 1
+}
+
+// dummyPasswordHash is checked against when a login names an unknown user,
+// so that the response time does not reveal which emails have accounts.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("not-a-real-password")
+	return hash
+})
+
+// CreateUser creates a user who signs in with email and password. An empty
+// password creates a user who can only sign in through OIDC.
+func CreateUser(ctx context.Context, database *db.DB, email, password string) (*generated.User, error) {
+	email = strings.TrimSpace(email)
+	if !strings.Contains(email, "@") {
+		return nil, fmt.Errorf("invalid email %q", email)
+	}
+	var passwordHash *string
+	if password != "" {
+		hash, err := HashPassword(password)
+		if err != nil {
+			return nil, err
+		}
+		passwordHash = &hash
+	}
+	return database.CreateUser(ctx, "user-"+uuid.New().String()[:8], email, passwordHash)
+}
+
+// CreateAPIToken creates a named API token for a user and returns the
+// token's secret, which is not stored and cannot be shown again.
+func CreateAPIToken(ctx context.Context, database *db.DB, userID, name string) (string, *generated.ApiToken, error) {
+	secret := apiTokenPrefix + randomSecret()
+	token, err := database.CreateAPIToken(ctx, "token-"+uuid.New().String()[:8], userID, name, hashSecret(secret))
+	if err != nil {
+		return "", nil, err
+	}
+	return secret, token, nil
+}
+
+// authenticate returns the user a request is signed in as, from its bearer
+// API token or its session cookie, or nil.
+func (s *Server) authenticate(r *http.Request) (*authUser, error) {
+	ctx := r.Context()
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token, err := s.db.GetAPITokenByHash(ctx, hashSecret(strings.TrimSpace(bearer)))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		user, err := s.db.GetUser(ctx, token.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if token.LastUsedAt == nil || time.Since(*token.LastUsedAt) > apiTokenTouchInterval {
+			if err := s.db.TouchAPIToken(ctx, token.TokenID, time.Now()); err != nil {
+				s.logger.Warn("Failed to record API token use", "tokenID", token.TokenID, "error", err)
+			}
+		}
+		return &authUser{User: user, TokenID: token.TokenID}, nil
+	}
+
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, nil
+	}
+	session, err := s.db.GetUserSession(ctx, hashSecret(cookie.Value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, nil
+	}
+	user, err := s.db.GetUser(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &authUser{User: user}, nil
+}
+
+// authExempt reports whether r may be served without a signed-in user.
+func authExempt(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/auth/login", "/auth/oidc/login", "/auth/oidc/callback", "/version":
+		return true
+	}
+	return hasOwnCredentials(r)
+}
+
+// requiresSession reports whether r is for an endpoint that API tokens may
+// not use: the terminal and the file editor act directly on the machine, so
+// they are only available to a user signed in to the web UI.
+func requiresSession(r *http.Request) bool {
+	return r.URL.Path == "/api/exec-ws" || r.URL.Path == "/api/write-file"
+}
+
+// AuthMiddleware requires a signed-in user on every request that is not
+// exempt. Unauthenticated page loads are redirected to the login page. The
+// X-ExeDev-Email header is replaced with the signed-in user's email, so
+// handlers see the authenticated identity and clients cannot claim another.
+func (s *Server) AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authExempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, err := s.authenticate(r)
+		if err != nil {
+			s.logger.Error("Failed to authenticate request", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			if r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/api/") {
+				http.Redirect(w, r, "/auth/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusSeeOther)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="shelley"`)
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		if user.TokenID != "" && requiresSession(r) {
+			http.Error(w, "This endpoint requires signing in to the web UI", http.StatusForbidden)
+			return
+		}
+		r.Header.Set("X-ExeDev-Email", user.Email)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), authUserKey{}, user)))
+	})
+}
+
+// isSecureRequest reports whether the client reached us over HTTPS,
+// directly or through a proxy.
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// startSession signs the user in by creating a session and setting its cookie.
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, user *generated.User) error {
+	ctx := r.Context()
+	secret := randomSecret()
+	expiresAt := time.Now().Add(sessionTTL)
+	if err := s.db.CreateUserSession(ctx, hashSecret(secret), user.UserID, expiresAt); err != nil {
+		return err
+	}
+	if err := s.db.RecordUserLogin(ctx, user.UserID, time.Now()); err != nil {
+		s.logger.Warn("Failed to record login", "userID", user.UserID, "error", err)
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    secret,
+		Path:     "/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	s.logger.Info("User signed in", "email", user.Email)
+	return nil
+}
+
+// safeRedirect returns next if it is a path on this server, and "/" otherwise.
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign in - Shelley</title>
+<style>
+body { font-family: system-ui, sans-serif; background: #f5f5f5; display: flex; justify-content: center; padding-top: 10vh; margin: 0; }
+form, .box { background: #fff; padding: 24px; border-radius: 8px; box-shadow: 0 1px 4px rgba(0,0,0,.1); width: 300px; }
+h1 { font-size: 20px; margin: 0 0 16px; }
+label { display: block; font-size: 14px; margin-bottom: 12px; }
+input { display: block; width: 100%; box-sizing: border-box; padding: 8px; margin-top: 4px; font-size: 14px; }
+button, .sso { display: block; width: 100%; box-sizing: border-box; padding: 8px; font-size: 14px; text-align: center; cursor: pointer; }
+.sso { margin-top: 12px; color: inherit; border: 1px solid #ccc; border-radius: 4px; text-decoration: none; }
+.error { color: #b00020; font-size: 14px; margin-bottom: 12px; }
+</style>
+</head>
+<body>
+<form method="post" action="/auth/login">
+<h1>Sign in to Shelley</h1>
+{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+<input type="hidden" name="next" value="{{.Next}}">
+<label>Email <input type="email" name="email" autocomplete="username" required autofocus></label>
+<label>Password <input type="password" name="password" autocomplete="current-password" required></label>
+<button type="submit">Sign in</button>
+{{if .OIDCName}}<a class="sso" href="/auth/oidc/login?next={{.Next}}">Sign in with {{.OIDCName}}</a>{{end}}
+</form>
+</body>
+</html>
+`))
+
+// renderLoginPage writes the login page with an optional error.
+func (s *Server) renderLoginPage(w http.ResponseWriter, status int, next, errMsg string) {
+	data := struct {
+		Next     string
+		Error    string
+		OIDCName string
+	}{Next: safeRedirect(next), Error: errMsg}
+	if s.auth != nil && s.auth.OIDC != nil {
+		data.OIDCName = s.auth.OIDC.displayName()
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	loginPage.Execute(w, data)
+}
+
+// handleLogin handles /auth/login. GET shows the login page; POST signs in
+// with an email and password sent as a form (from the login page) or as
+// JSON (from scripts), and answers with a redirect or JSON respectively.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if s.auth == nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		next := safeRedirect(r.URL.Query().Get("next"))
+		if user, _ := s.authenticate(r); user != nil {
+			http.Redirect(w, r, next, http.StatusSeeOther)
+			return
+		}
+		s.renderLoginPage(w, http.StatusOK, next, "")
+	case http.MethodPost:
+		s.handlePasswordLogin(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handlePasswordLogin(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var email, password, next string
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	isJSON := mediaType == "application/json"
+	if isJSON {
+		var req struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		email, password = req.Email, req.Password
+	} else {
+		r.Body = http.MaxBytesReader(w, r.Body, 64<<10)
+		email, password, next = r.PostFormValue("email"), r.PostFormValue("password"), r.PostFormValue("next")
+	}
+
+	user, err := s.db.GetUserByEmail(ctx, strings.TrimSpace(email))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		s.logger.Error("Failed to look up user", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	hash := dummyPasswordHash()
+	if user != nil && user.PasswordHash != nil {
+		hash = *user.PasswordHash
+	}
+	if !checkPassword(hash, password) || user == nil || user.PasswordHash == nil {
+		s.logger.Info("Failed sign-in", "email", email)
+		if isJSON {
+			http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+		} else {
+			s.renderLoginPage(w, http.StatusUnauthorized, next, "Invalid email or password")
+		}
+		return
+	}
+
+	if err := s.startSession(w, r, user); err != nil {
+		s.logger.Error("Failed to create session", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if isJSON {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(toUserAPI(user))
+		return
+	}
+	http.Redirect(w, r, safeRedirect(next), http.StatusSeeOther)
+}
+
+// handleLogout handles POST /auth/logout, which ends the current session.
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if cookie, err := r.Cookie(sessionCookieName); err == nil && cookie.Value != "" {
+		if err := s.db.DeleteUserSession(r.Context(), hashSecret(cookie.Value)); err != nil {
+			s.logger.Warn("Failed to delete session", "error", err)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UserAPI is a user as returned by the API.
+type UserAPI struct {
+	UserID      string     `json:"user_id"`
+	Email       string     `json:"email"`
+	HasPassword bool       `json:"has_password"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func toUserAPI(u *generated.User) UserAPI {
+	return UserAPI{
+		UserID:      u.UserID,
+		Email:       u.Email,
+		HasPassword: u.PasswordHash != nil,
+		LastLoginAt: u.LastLoginAt,
+		CreatedAt:   u.CreatedAt,
+	}
+}
+
+// APITokenAPI is an API token as returned by the API. Token is only set in
+// the response that creates it.
+type APITokenAPI struct {
+	TokenID    string     `json:"token_id"`
+	Name       string     `json:"name"`
+	Token      string     `json:"token,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func toAPITokenAPI(t *generated.ApiToken) APITokenAPI {
+	return APITokenAPI{
+		TokenID:    t.TokenID,
+		Name:       t.Name,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+// handleAuthMe handles GET /api/auth/me, which describes the signed-in user.
+func (s *Server) handleAuthMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	resp := struct {
+		AuthEnabled bool     `json:"auth_enabled"`
+		User        *UserAPI `json:"user"`
+		Via         string   `json:"via,omitempty"`
+	}{AuthEnabled: s.auth != nil}
+	if user := requestUser(r.Context()); user != nil {
+		u := toUserAPI(user.User)
+		resp.User = &u
+		resp.Via = "session"
+		if user.TokenID != "" {
+			resp.Via = "token"
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// signedInUser returns the request's user, writing an error response if
+// authentication is disabled or nobody is signed in.
+func (s *Server) signedInUser(w http.ResponseWriter, r *http.Request) *authUser {
+	if s.auth == nil {
+		http.Error(w, "Authentication is not enabled", http.StatusNotFound)
+		return nil
+	}
+	user := requestUser(r.Context())
+	if user == nil {
+		http.Error(w, "Not signed in", http.StatusUnauthorized)
+		return nil
+	}
+	return user
+}
+
+// handleAPITokens handles GET and POST /api/auth/tokens, which list and
+// create the signed-in user's API tokens.
+func (s *Server) handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	switch r.Method {
+	case http.MethodGet:
+		user := s.signedInUser(w, r)
+		if user == nil {
+			return
+		}
+		tokens, err := s.db.ListAPITokens(ctx, user.UserID)
+		if err != nil {
+			s.logger.Error("Failed to list API tokens", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		result := make([]APITokenAPI, len(tokens))
+		for i := range tokens {
+			result[i] = toAPITokenAPI(&tokens[i])
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	case http.MethodPost:
+		user := s.signedInUser(w, r)
+		if user == nil {
+			return
+		}
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		req.Name = strings.TrimSpace(req.Name)
+		if req.Name == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		secret, token, err := CreateAPIToken(ctx, s.db, user.UserID, req.Name)
+		if err != nil {
+			s.logger.Error("Failed to create API token", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		s.logger.Info("Created API token", "email", user.Email, "tokenID", token.TokenID, "name", token.Name)
+		result := toAPITokenAPI(token)
+		result.Token = secret
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(result)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAPIToken handles DELETE /api/auth/tokens/<id>, which revokes one of
+// the signed-in user's API tokens.
+func (s *Server) handleAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	user := s.signedInUser(w, r)
+	if user == nil {
+		return
+	}
+	tokenID := strings.TrimPrefix(r.URL.Path, "/api/auth/tokens/")
+	err := s.db.DeleteAPIToken(r.Context(), tokenID, user.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Token not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to delete API token", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Info("Revoked API token", "email", user.Email, "tokenID", tokenID)
+	w.WriteHeader(http.StatusNoContent)
+}
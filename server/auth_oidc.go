@@ -0,0 +1,376 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	oidcCookieName = "shelley_oidc"
+	oidcFlowTTL    = 10 * time.Minute
+	oidcTimeout    = 15 * time.Second
+)
+
+// OIDCConfig configures sign-in through an OpenID Connect provider using the
+// authorization code flow with PKCE.
+type OIDCConfig struct {
+	// Name labels the sign-in button, e.g. "Google". Defaults to "SSO".
+	Name         string `json:"name"`
+	Issuer       string `json:"issuer"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	// RedirectURL is the callback registered with the provider. Defaults to
+	// <public URL>/auth/oidc/callback, using the notify_public_url setting
+	// if set and the request's host otherwise.
+	RedirectURL string `json:"redirect_url"`
+	// AllowedEmails and AllowedDomains list who may sign in for the first
+	// time, which creates a user for them. Existing users may always sign in.
+	AllowedEmails  []string `json:"allowed_emails"`
+	AllowedDomains []string `json:"allowed_domains"`
+}
+
+func (c *OIDCConfig) displayName() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return "SSO"
+}
+
+// Validate checks that the required fields are set.
+func (c *OIDCConfig) Validate() error {
+	if c.Issuer == "" || c.ClientID == "" {
+		return errors.New("oidc: issuer and client_id are required")
+	}
+	u, err := url.Parse(c.Issuer)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("oidc: invalid issuer %q", c.Issuer)
+	}
+	return nil
+}
+
+// mayCreateUser reports whether a first-time sign-in by email creates a user.
+func (c *OIDCConfig) mayCreateUser(email string) bool {
+	email = strings.ToLower(email)
+	for _, allowed := range c.AllowedEmails {
+		if strings.ToLower(allowed) == email {
+			return true
+		}
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	for _, allowed := range c.AllowedDomains {
+		if strings.ToLower(strings.TrimPrefix(allowed, "@")) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// oidcProvider is the part of the provider's discovery document we use.
+type oidcProvider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// oidcDiscovery caches the provider's discovery document.
+type oidcDiscovery struct {
+	mu       sync.Mutex
+	provider *oidcProvider
+}
+
+// oidcProvider fetches the provider's discovery document the first time it
+// is needed.
+func (s *Server) oidcProvider(ctx context.Context) (*oidcProvider, error) {
+	s.oidc.mu.Lock()
+	defer s.oidc.mu.Unlock()
+	if s.oidc.provider != nil {
+		return s.oidc.provider, nil
+	}
+
+	issuer := strings.TrimSuffix(s.auth.OIDC.Issuer, "/")
+	ctx, cancel := context.WithTimeout(ctx, oidcTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc discovery: %s", resp.Status)
+	}
+	var provider oidcProvider
+	if err := json.NewDecoder(resp.Body).Decode(&provider); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", provider.Issuer, issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" {
+		return nil, errors.New("oidc discovery: missing authorization or token endpoint")
+	}
+	s.oidc.provider = &provider
+	return &provider, nil
+}
+
+// oidcRedirectURL returns the callback URL sent to the provider.
+func (s *Server) oidcRedirectURL(r *http.Request) string {
+	if s.auth.OIDC.RedirectURL != "" {
+		return s.auth.OIDC.RedirectURL
+	}
+	if base := s.publicURL(r.Context()); base != "" {
+		return base + "/auth/oidc/callback"
+	}
+	scheme := "http"
+	if isSecureRequest(r) {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/auth/oidc/callback"
+}
+
+// oidcFlow is what the browser carries, in a short-lived cookie, from the
+// redirect to the provider to the callback.
+type oidcFlow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	Next     string `json:"next"`
+}
+
+// handleOIDCLogin handles GET /auth/oidc/login, which sends the browser to
+// the provider.
+func (s *Server) handleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if s.auth == nil || s.auth.OIDC == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	provider, err := s.oidcProvider(r.Context())
+	if err != nil {
+		s.logger.Error("OIDC discovery failed", "error", err)
+		s.renderLoginPage(w, http.StatusBadGateway, r.URL.Query().Get("next"), "Single sign-on is unavailable")
+		return
+	}
+
+	flow := oidcFlow{
+		State:    randomSecret(),
+		Nonce:    randomSecret(),
+		Verifier: randomSecret(),
+		Next:     safeRedirect(r.URL.Query().Get("next")),
+	}
+	flowJSON, _ := json.Marshal(flow)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(flowJSON),
+		Path:     "/auth/oidc/",
+		MaxAge:   int(oidcFlowTTL / time.Second),
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	challenge := sha256.Sum256([]byte(flow.Verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {s.auth.OIDC.ClientID},
+		"redirect_uri":          {s.oidcRedirectURL(r)},
+		"scope":                 {"openid email"},
+		"state":                 {flow.State},
+		"nonce":                 {flow.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	http.Redirect(w, r, provider.AuthorizationEndpoint+sep+params.Encode(), http.StatusSeeOther)
+}
+
+// handleOIDCCallback handles GET /auth/oidc/callback, where the provider
+// sends the browser back with an authorization code.
+func (s *Server) handleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if s.auth == nil || s.auth.OIDC == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	var flow oidcFlow
+	cookie, err := r.Cookie(oidcCookieName)
+	if err == nil {
+		var flowJSON []byte
+		flowJSON, err = base64.RawURLEncoding.DecodeString(cookie.Value)
+		if err == nil {
+			err = json.Unmarshal(flowJSON, &flow)
+		}
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcCookieName, Path: "/auth/oidc/", MaxAge: -1})
+	if err != nil || flow.State == "" || subtle.ConstantTimeCompare([]byte(flow.State), []byte(query.Get("state"))) != 1 {
+		s.renderLoginPage(w, http.StatusBadRequest, "/", "Sign-in expired, please try again")
+		return
+	}
+	if errCode := query.Get("error"); errCode != "" {
+		s.logger.Info("OIDC provider returned an error", "error", errCode, "description", query.Get("error_description"))
+		s.renderLoginPage(w, http.StatusUnauthorized, flow.Next, "Sign-in was not completed")
+		return
+	}
+
+	email, err := s.exchangeOIDCCode(r, query.Get("code"), &flow)
+	if err != nil {
+		s.logger.Warn("OIDC sign-in failed", "error", err)
+		s.renderLoginPage(w, http.StatusUnauthorized, flow.Next, "Single sign-on failed")
+		return
+	}
+
+	// Providers don't promise to keep an address's casing from one
+	// sign-in to the next, so users are keyed on the lower-case form.
+	email = strings.ToLower(email)
+	user, err := s.db.GetUserByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		if !s.auth.OIDC.mayCreateUser(email) {
+			s.logger.Info("OIDC sign-in refused", "email", email)
+			s.renderLoginPage(w, http.StatusForbidden, flow.Next, email+" is not allowed to use this server")
+			return
+		}
+		user, err = CreateUser(ctx, s.db, email, "")
+		if err == nil {
+			s.logger.Info("Created user from OIDC sign-in", "email", email)
+		}
+	}
+	if err != nil {
+		s.logger.Error("Failed to look up OIDC user", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := s.startSession(w, r, user); err != nil {
+		s.logger.Error("Failed to create session", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, flow.Next, http.StatusSeeOther)
+}
+
+// idTokenClaims are the ID token claims we check.
+type idTokenClaims struct {
+	Issuer        string          `json:"iss"`
+	Audience      json.RawMessage `json:"aud"`
+	Expiry        int64           `json:"exp"`
+	Nonce         string          `json:"nonce"`
+	Email         string          `json:"email"`
+	EmailVerified *bool           `json:"email_verified"`
+}
+
+func (c *idTokenClaims) audiences() []string {
+	var one string
+	if json.Unmarshal(c.Audience, &one) == nil {
+		return []string{one}
+	}
+	var many []string
+	json.Unmarshal(c.Audience, &many)
+	return many
+}
+
+// exchangeOIDCCode redeems an authorization code at the provider's token
+// endpoint and returns the verified email from the ID token.
+//
+// The ID token's signature is not checked: it comes straight from the token
+// endpoint over TLS, which OpenID Connect Core (section 3.1.3.7) allows in
+// place of signature validation. Its issuer, audience, expiry and nonce are.
+func (s *Server) exchangeOIDCCode(r *http.Request, code string, flow *oidcFlow) (string, error) {
+	if code == "" {
+		return "", errors.New("no authorization code")
+	}
+	provider, err := s.oidcProvider(r.Context())
+	if err != nil {
+		return "", err
+	}
+	config := s.auth.OIDC
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.oidcRedirectURL(r)},
+		"code_verifier": {flow.Verifier},
+		"client_id":     {config.ClientID},
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), oidcTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(config.ClientID), url.QueryEscape(config.ClientSecret))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil || tokens.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+
+	parts := strings.Split(tokens.IDToken, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed id_token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.New("malformed id_token")
+	}
+	var claims idTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", errors.New("malformed id_token")
+	}
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != strings.TrimSuffix(provider.Issuer, "/"):
+		return "", fmt.Errorf("id_token issuer %q", claims.Issuer)
+	case !slices.Contains(claims.audiences(), config.ClientID):
+		return "", errors.New("id_token is for another client")
+	case time.Now().After(time.Unix(claims.Expiry, 0)):
+		return "", errors.New("id_token has expired")
+	case subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(flow.Nonce)) != 1:
+		return "", errors.New("id_token nonce mismatch")
+	case claims.Email == "":
+		return "", errors.New("id_token has no email; is the email scope allowed?")
+	case claims.EmailVerified != nil && !*claims.EmailVerified:
+		return "", fmt.Errorf("email %s is not verified", claims.Email)
+	}
+	return claims.Email, nil
+}
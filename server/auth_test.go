@@ -0,0 +1,243 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAuth(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := t.Context()
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+	h.server.EnableAuth(AuthConfig{})
+	handler := h.server.AuthMiddleware(mux)
+
+	if _, err := CreateUser(ctx, h.db, "ada@example.com", "short"); err == nil {
+		t.Error("created a user with a short password")
+	}
+	if _, err := CreateUser(ctx, h.db, "ada@example.com", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(method, path, body string, header ...string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// Without credentials, API calls are refused and pages go to the login page.
+	if w := do("GET", "/api/conversations", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("API without credentials: got %d, want 401", w.Code)
+	}
+	if w := do("GET", "/c/some-slug", ""); w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/auth/login?next=%2Fc%2Fsome-slug" {
+		t.Errorf("page without credentials: got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := do("GET", "/auth/login?next=/c/some-slug", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `value="/c/some-slug"`) {
+		t.Errorf("login page: got %d", w.Code)
+	}
+
+	if w := do("POST", "/auth/login", `{"email":"ada@example.com","password":"wrong password"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: got %d, want 401", w.Code)
+	}
+	if w := do("POST", "/auth/login", `{"email":"bob@example.com","password":"correct horse"}`); w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown user: got %d, want 401", w.Code)
+	}
+
+	// The login form redirects to where the user was going.
+	req := httptest.NewRequest("POST", "/auth/login", strings.NewReader(url.Values{
+		"email": {"ADA@example.com"}, "password": {"correct horse"}, "next": {"/c/some-slug"},
+	}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/c/some-slug" {
+		t.Fatalf("form login: got %d to %q", w.Code, w.Header().Get("Location"))
+	}
+	var session *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == sessionCookieName {
+			session = c
+		}
+	}
+	if session == nil || !session.HttpOnly {
+		t.Fatalf("session cookie = %+v", session)
+	}
+	cookie := "shelley_session=" + session.Value
+
+	// The session identifies the user, overriding any claimed identity.
+	w = do("GET", "/api/auth/me", "", "Cookie", cookie, "X-ExeDev-Email", "mallory@example.com")
+	var me struct {
+		AuthEnabled bool     `json:"auth_enabled"`
+		User        *UserAPI `json:"user"`
+		Via         string   `json:"via"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &me)
+	if !me.AuthEnabled || me.User == nil || me.User.Email != "ada@example.com" || me.Via != "session" {
+		t.Fatalf("me = %+v", me)
+	}
+	if w := do("GET", "/api/conversations", "", "Cookie", cookie); w.Code != http.StatusOK {
+		t.Errorf("API with session: got %d", w.Code)
+	}
+
+	// API tokens work as bearer tokens, except for the terminal and the file editor.
+	w = do("POST", "/api/auth/tokens", `{"name":"laptop"}`, "Cookie", cookie)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create token: %d %s", w.Code, w.Body.String())
+	}
+	var token APITokenAPI
+	json.Unmarshal(w.Body.Bytes(), &token)
+	if !strings.HasPrefix(token.Token, apiTokenPrefix) {
+		t.Fatalf("token = %+v", token)
+	}
+	bearer := "Bearer " + token.Token
+	if w := do("GET", "/api/conversations", "", "Authorization", bearer); w.Code != http.StatusOK {
+		t.Errorf("API with token: got %d", w.Code)
+	}
+	if w := do("GET", "/api/conversations", "", "Authorization", "Bearer shelley_wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("API with wrong token: got %d, want 401", w.Code)
+	}
+	if w := do("POST", "/api/write-file", `{}`, "Authorization", bearer); w.Code != http.StatusForbidden {
+		t.Errorf("write-file with token: got %d, want 403", w.Code)
+	}
+	if w := do("GET", "/api/exec-ws?cmd=sh", "", "Authorization", bearer); w.Code != http.StatusForbidden {
+		t.Errorf("exec-ws with token: got %d, want 403", w.Code)
+	}
+	if w := do("POST", "/api/write-file", `{}`, "Cookie", cookie); w.Code == http.StatusForbidden || w.Code == http.StatusUnauthorized {
+		t.Errorf("write-file with session: got %d", w.Code)
+	}
+
+	w = do("GET", "/api/auth/tokens", "", "Authorization", bearer)
+	var tokens []APITokenAPI
+	json.Unmarshal(w.Body.Bytes(), &tokens)
+	if len(tokens) != 1 || tokens[0].Token != "" || tokens[0].LastUsedAt == nil {
+		t.Errorf("tokens = %+v, want one used token without its secret", tokens)
+	}
+	if w := do("DELETE", "/api/auth/tokens/"+token.TokenID, "", "Cookie", cookie); w.Code != http.StatusNoContent {
+		t.Fatalf("revoke: %d", w.Code)
+	}
+	if w := do("GET", "/api/conversations", "", "Authorization", bearer); w.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token: got %d, want 401", w.Code)
+	}
+
+	// Requests that carry their own credentials skip sign-in.
+	if w := do("POST", "/api/notification-replies/unknown", "hi"); w.Code != http.StatusNotFound {
+		t.Errorf("notification reply: got %d, want 404", w.Code)
+	}
+
+	// Signing out ends the session.
+	if w := do("POST", "/auth/logout", "", "Cookie", cookie); w.Code != http.StatusNoContent {
+		t.Fatalf("logout: %d", w.Code)
+	}
+	if w := do("GET", "/api/conversations", "", "Cookie", cookie); w.Code != http.StatusUnauthorized {
+		t.Errorf("after logout: got %d, want 401", w.Code)
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	h := NewTestHarness(t)
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+
+	// A minimal provider: discovery and a token endpoint that issues an
+	// unsigned ID token for whatever email and nonce the test sets.
+	var email, nonce string
+	var provider *httptest.Server
+	provider = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{
+				"issuer":                 provider.URL,
+				"authorization_endpoint": provider.URL + "/authorize",
+				"token_endpoint":         provider.URL + "/token",
+			})
+		case "/token":
+			if id, secret, _ := r.BasicAuth(); id != "shelley" || secret != "s3cret" || r.PostFormValue("code") != "the-code" || r.PostFormValue("code_verifier") == "" {
+				http.Error(w, "invalid_grant", http.StatusBadRequest)
+				return
+			}
+			claims, _ := json.Marshal(map[string]any{
+				"iss": provider.URL, "aud": "shelley", "exp": 4102444800,
+				"nonce": nonce, "email": email, "email_verified": true,
+			})
+			json.NewEncoder(w).Encode(map[string]string{
+				"id_token": "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(claims) + ".",
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer provider.Close()
+
+	h.server.EnableAuth(AuthConfig{OIDC: &OIDCConfig{
+		Issuer:         provider.URL,
+		ClientID:       "shelley",
+		ClientSecret:   "s3cret",
+		AllowedDomains: []string{"example.com"},
+	}})
+	handler := h.server.AuthMiddleware(mux)
+
+	signIn := func(as string) *httptest.ResponseRecorder {
+		t.Helper()
+		email = as
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/auth/oidc/login?next=/c/x", nil))
+		if w.Code != http.StatusSeeOther {
+			t.Fatalf("oidc login: %d %s", w.Code, w.Body.String())
+		}
+		authorize, err := url.Parse(w.Header().Get("Location"))
+		if err != nil || !strings.HasPrefix(authorize.String(), provider.URL+"/authorize?") {
+			t.Fatalf("redirected to %q", w.Header().Get("Location"))
+		}
+		q := authorize.Query()
+		if q.Get("code_challenge_method") != "S256" || q.Get("redirect_uri") != "http://example.com/auth/oidc/callback" {
+			t.Errorf("authorize params = %v", q)
+		}
+		nonce = q.Get("nonce")
+
+		req := httptest.NewRequest("GET", "/auth/oidc/callback?code=the-code&state="+url.QueryEscape(q.Get("state")), nil)
+		req.AddCookie(w.Result().Cookies()[0])
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := signIn("Grace@Example.com")
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/c/x" {
+		t.Fatalf("callback: %d to %q: %s", w.Code, w.Header().Get("Location"), w.Body.String())
+	}
+	user, err := h.db.GetUserByEmail(t.Context(), "grace@example.com")
+	if err != nil || user.Email != "grace@example.com" || user.PasswordHash != nil || user.LastLoginAt == nil {
+		t.Errorf("user = %+v, %v; want a signed-in OIDC-only user with a lower-case email", user, err)
+	}
+	// The provider changing the address's casing signs in the same user.
+	if w := signIn("GRACE@example.com"); w.Code != http.StatusSeeOther {
+		t.Fatalf("sign-in with different casing: %d %s", w.Code, w.Body.String())
+	}
+	if users, err := h.db.ListUsers(t.Context()); err != nil || len(users) != 1 {
+		t.Errorf("users = %+v, %v; want one", users, err)
+	}
+
+	if w := signIn("eve@elsewhere.test"); w.Code != http.StatusForbidden {
+		t.Errorf("disallowed domain: got %d, want 403", w.Code)
+	}
+
+	// A callback whose state does not match the browser's is refused.
+	req := httptest.NewRequest("GET", "/auth/oidc/callback?code=the-code&state=forged", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("forged state: got %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/db"
+)
+
+// maxTagLength bounds the length of a single tag.
+const maxTagLength = 64
+
+// maxTagsPerConversation bounds how many tags a conversation may carry.
+const maxTagsPerConversation = 32
+
+// normalizeTag lowercases a tag, replaces whitespace runs with "-", and strips
+// leading/trailing "/" so that "Clients / Acme" and "clients/acme" are the same
+// folder-style tag. It returns "" for tags that are empty after normalization.
+func normalizeTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	parts := strings.Split(tag, "/")
+	clean := parts[:0]
+	for _, p := range parts {
+		p = strings.Join(strings.Fields(p), "-")
+		if p != "" {
+			clean = append(clean, p)
+		}
+	}
+	return strings.Join(clean, "/")
+}
+
+// normalizeTags normalizes, deduplicates and sorts a list of tags.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, raw := range tags {
+		tag := normalizeTag(raw)
+		if tag == "" {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, maxTagLength)
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	if len(result) > maxTagsPerConversation {
+		return nil, fmt.Errorf("at most %d tags are allowed per conversation", maxTagsPerConversation)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// parseConversationFilter builds a db.ConversationFilter from the query
+// parameters of GET /api/conversations.
+//
+//	tag=NAME            conversations carrying this tag
+//	cwd=DIR             conversations whose cwd is DIR or below it
+//	repo=DIR            conversations in the git repository rooted at DIR
+//	branch=NAME         conversations that have been on branch NAME
+//	model=ID            conversations using this model
+//	since=DATE          updated at or after DATE (RFC 3339 or YYYY-MM-DD)
+//	until=DATE          updated before DATE (RFC 3339 or YYYY-MM-DD)
+//	pinned=BOOL         only pinned (true) or unpinned (false) conversations
+//	has_subagents=BOOL  only conversations with (true) or without (false) subagents
+func parseConversationFilter(query url.Values) (db.ConversationFilter, error) {
+	var filter db.ConversationFilter
+	filter.Tag = normalizeTag(query.Get("tag"))
+	filter.Cwd = query.Get("cwd")
+	filter.RepoRoot = query.Get("repo")
+	filter.Branch = query.Get("branch")
+	filter.Model = query.Get("model")
+
+	var err error
+	if v := query.Get("since"); v != "" {
+		if filter.UpdatedAfter, err = parseFilterDate(v); err != nil {
+			return filter, fmt.Errorf("invalid since: %w", err)
+		}
+	}
+	if v := query.Get("until"); v != "" {
+		if filter.UpdatedBefore, err = parseFilterDate(v); err != nil {
+			return filter, fmt.Errorf("invalid until: %w", err)
+		}
+	}
+	if v := query.Get("pinned"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid pinned: %q", v)
+		}
+		filter.Pinned = &b
+	}
+	if v := query.Get("has_subagents"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid has_subagents: %q", v)
+		}
+		filter.HasSubagents = &b
+	}
+	return filter, nil
+}
+
+// parseFilterDate accepts either an RFC 3339 timestamp or a bare date.
+func parseFilterDate(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected RFC 3339 or YYYY-MM-DD, got %q", v)
+	}
+	return t, nil
+}
+
+// TagsRequest is the body of POST /api/conversation/<id>/tags.
+type TagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// TagCount is a tag with the number of non-archived conversations carrying it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// handleTags handles GET /api/tags
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rows, err := s.db.ListTagCounts(r.Context())
+	if err != nil {
+		s.logger.Error("Failed to list tags", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]TagCount, len(rows))
+	for i, row := range rows {
+		result[i] = TagCount{Tag: row.Tag, Count: row.Count}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleGetConversationTags handles GET /conversation/<id>/tags
+func (s *Server) handleGetConversationTags(w http.ResponseWriter, r *http.Request, conversationID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tags, err := s.db.GetConversationTags(r.Context(), conversationID)
+	if err != nil {
+		s.logger.Error("Failed to get conversation tags", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TagsRequest{Tags: tags})
+}
+
+// handleSetConversationTags handles POST /conversation/<id>/tags.
+// The request body replaces the full tag set of the conversation.
+func (s *Server) handleSetConversationTags(w http.ResponseWriter, r *http.Request, conversationID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+
+	var req TagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conversation, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+
+	if err := s.db.SetConversationTags(ctx, conversationID, tags); err != nil {
+		s.logger.Error("Failed to set conversation tags", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Notify conversation list subscribers
+	go s.publishConversationListUpdate(ConversationListUpdate{
+		Type:         "update",
+		Conversation: conversation,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TagsRequest{Tags: tags})
+}
+
+// handlePinConversation handles POST /conversation/<id>/pin and /unpin
+func (s *Server) handlePinConversation(w http.ResponseWriter, r *http.Request, conversationID string, pinned bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	conversation, err := s.db.SetConversationPinned(ctx, conversationID, pinned)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to update conversation pin", "conversationID", conversationID, "pinned", pinned, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Notify conversation list subscribers
+	go s.publishConversationListUpdate(ConversationListUpdate{
+		Type:         "update",
+		Conversation: conversation,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conversation)
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := normalizeTags([]string{" Bug ", "Clients / Acme", "bug", "", "needs review"})
+	if err != nil {
+		t.Fatalf("normalizeTags() error = %v", err)
+	}
+	if got, want := strings.Join(tags, ","), "bug,clients/acme,needs-review"; got != want {
+		t.Errorf("normalizeTags() = %q, want %q", got, want)
+	}
+
+	if _, err := normalizeTags([]string{strings.Repeat("x", maxTagLength+1)}); err == nil {
+		t.Error("Expected error for overlong tag")
+	}
+}
+
+func TestConversationTagsAndPinAPI(t *testing.T) {
+	h := NewTestHarness(t)
+
+	cwd := "/src/project"
+	conv, err := h.db.CreateConversation(t.Context(), nil, true, &cwd, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	other, err := h.db.CreateConversation(t.Context(), nil, true, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	for _, id := range []string{conv.ConversationID, other.ConversationID} {
+		if _, err := h.db.CreateMessage(t.Context(), db.CreateMessageParams{
+			ConversationID: id,
+			Type:           db.MessageTypeUser,
+			LLMData:        map[string]string{"text": "hello there"},
+		}); err != nil {
+			t.Fatalf("Failed to create message: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Set tags
+	rec := do("POST", "/api/conversation/"+conv.ConversationID+"/tags", `{"tags":["Release","release","Clients/Acme"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var tagsResp TagsRequest
+	if err := json.NewDecoder(rec.Body).Decode(&tagsResp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if got := strings.Join(tagsResp.Tags, ","); got != "clients/acme,release" {
+		t.Errorf("Expected normalized tags, got %q", got)
+	}
+
+	// Tagging a missing conversation fails
+	rec = do("POST", "/api/conversation/missing/tags", `{"tags":["x"]}`)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Pin
+	rec = do("POST", "/api/conversation/"+conv.ConversationID+"/pin", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var pinned generated.Conversation
+	if err := json.NewDecoder(rec.Body).Decode(&pinned); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !pinned.Pinned {
+		t.Error("Expected conversation to be pinned")
+	}
+
+	// Pinning a missing conversation fails
+	rec = do("POST", "/api/conversation/missing/pin", "")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Filter the list by tag; the result carries tags and pin state
+	rec = do("GET", "/api/conversations?tag=release", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var list []ConversationWithState
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(list) != 1 || list[0].ConversationID != conv.ConversationID {
+		t.Fatalf("Expected only the tagged conversation, got %+v", list)
+	}
+	if !list[0].Pinned || strings.Join(list[0].Tags, ",") != "clients/acme,release" {
+		t.Errorf("Expected pinned conversation with tags, got %+v", list[0])
+	}
+
+	// Filter by cwd
+	rec = do("GET", "/api/conversations?cwd=/src", "")
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(list) != 1 || list[0].ConversationID != conv.ConversationID {
+		t.Errorf("Expected only the conversation under /src, got %+v", list)
+	}
+
+	// Content search honors the other filters
+	rec = do("GET", "/api/conversations?q=hello&search_content=true&tag=release", "")
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(list) != 1 || list[0].ConversationID != conv.ConversationID {
+		t.Errorf("Expected only the tagged conversation mentioning hello, got %+v", list)
+	}
+	rec = do("GET", "/api/conversations?q=hello&search_content=true&tag=missing", "")
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("Expected no conversations with a missing tag, got %+v", list)
+	}
+
+	// Invalid filters are rejected
+	rec = do("GET", "/api/conversations?since=yesterday", "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Tag counts
+	rec = do("GET", "/api/tags", "")
+	var counts []TagCount
+	if err := json.NewDecoder(rec.Body).Decode(&counts); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(counts) != 2 || counts[0].Count != 1 {
+		t.Errorf("Expected two tags with one conversation each, got %+v", counts)
+	}
+}
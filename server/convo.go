@@ -49,6 +49,18 @@ type ConversationManager struct {
 	// onStateChange is called when the conversation state changes.
 	// This allows the server to broadcast state changes to all subscribers.
 	onStateChange func(state ConversationState)
+
+	// gitState is the last git state seen for the working directory, and
+	// onGitCommit, if set, is called when it moves to a new commit.
+	gitState    *gitstate.GitState
+	onGitCommit func(ctx context.Context, prev, cur *gitstate.GitState)
+
+	// redact, if set, redacts secrets from tool results.
+	redact func(string) (string, int)
+
+	// toolOutput is the output of the running tool calls so far, by
+	// tool use ID.
+	toolOutput map[string]*runningToolOutput
 }
 
 // NewConversationManager constructs a manager with dependencies but defers hydration until needed.
@@ -108,6 +120,17 @@ func (cm *ConversationManager) GetModel() string {
 	return cm.modelID
 }
 
+// GetUsage returns the usage accumulated by the conversation's current loop.
+func (cm *ConversationManager) GetUsage() llm.Usage {
+	cm.mu.Lock()
+	loopInstance := cm.loop
+	cm.mu.Unlock()
+	if loopInstance == nil {
+		return llm.Usage{}
+	}
+	return loopInstance.GetUsage()
+}
+
 // Hydrate loads conversation metadata from the database and generates a system
 // prompt if one doesn't exist yet. It does NOT cache the message history;
 // ensureLoop reads messages fresh from the DB when creating a loop so that
@@ -256,6 +279,9 @@ func (cm *ConversationManager) createSystemPrompt(ctx context.Context) (*generat
 	if cm.userEmail != "" {
 		opts = append(opts, WithUserEmail(cm.userEmail))
 	}
+	if repoMap := cm.repoMap(ctx, gitstate.GetGitState(cm.cwd)); repoMap != "" {
+		opts = append(opts, WithRepoMap(repoMap))
+	}
 	systemPrompt, err := GenerateSystemPrompt(cm.cwd, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate system prompt: %w", err)
@@ -432,6 +458,12 @@ func (cm *ConversationManager) ensureLoop(service llm.Service, modelID string) e
 	toolSetConfig.ModelID = modelID
 	toolSetConfig.ConversationID = conversationID
 	toolSetConfig.ParentConversationID = conversationID // For subagent tool
+	if audit := toolSetConfig.Audit; audit != nil {
+		toolSetConfig.Audit = func(rec claudetool.AuditRecord) {
+			rec.ConversationID = conversationID
+			audit(rec)
+		}
+	}
 	toolSetConfig.OnWorkingDirChange = func(newDir string) {
 		// Persist working directory change to database
 		if err := db.UpdateConversationCwd(context.Background(), conversationID, newDir); err != nil {
@@ -465,15 +497,23 @@ func (cm *ConversationManager) ensureLoop(service llm.Service, modelID string) e
 	processCtx, cancel := context.WithTimeout(baseCtx, 12*time.Hour)
 	toolSet := claudetool.NewToolSet(processCtx, toolSetConfig)
 
+	// Output kept for catching up on running tool calls is dropped once
+	// their results are in.
+	record := func(ctx context.Context, message llm.Message, usage llm.Usage) error {
+		cm.forgetToolOutput(message)
+		return recordMessage(ctx, message, usage)
+	}
 	loopInstance := loop.NewLoop(loop.Config{
-		LLM:           service,
-		History:       history,
-		Tools:         toolSet.Tools(),
-		RecordMessage: recordMessage,
-		Logger:        logger,
-		System:        system,
-		WorkingDir:    cwd,
-		GetWorkingDir: toolSet.WorkingDir().Get,
+		LLM:            service,
+		History:        history,
+		Tools:          toolSet.Tools(),
+		RecordMessage:  record,
+		Logger:         logger,
+		System:         system,
+		WorkingDir:     cwd,
+		GetWorkingDir:  toolSet.WorkingDir().Get,
+		Redact:         cm.redact,
+		OnToolProgress: cm.reportToolProgress,
 		OnGitStateChange: func(ctx context.Context, state *gitstate.GitState) {
 			cm.recordGitStateChange(ctx, state)
 		},
@@ -506,6 +546,20 @@ func (cm *ConversationManager) ensureLoop(service llm.Service, modelID string) e
 		}
 	}
 
+	// Record the repository the conversation starts in; later changes
+	// arrive through OnGitStateChange.
+	if cwd != "" {
+		go func() {
+			state := gitstate.GetGitState(cwd)
+			cm.mu.Lock()
+			if cm.gitState == nil {
+				cm.gitState = state
+			}
+			cm.mu.Unlock()
+			cm.recordConversationGitState(context.Background(), state)
+		}()
+	}
+
 	go func() {
 		if err := loopInstance.Go(processCtx); err != nil && err != context.DeadlineExceeded && err != context.Canceled {
 			if logger != nil {
@@ -528,6 +582,7 @@ func (cm *ConversationManager) stopLoop() {
 	cm.loop = nil
 	cm.modelID = ""
 	cm.toolSet = nil
+	cm.toolOutput = nil
 	cm.mu.Unlock()
 
 	if cancel != nil {
@@ -669,6 +724,7 @@ func (cm *ConversationManager) CancelConversation(ctx context.Context) error {
 	cm.loopCtx = nil
 	cm.loop = nil
 	cm.modelID = ""
+	cm.toolOutput = nil
 	// Reset hydrated so that the next AcceptUserMessage will reload history from the database
 	cm.hydrated = false
 	cm.mu.Unlock()
@@ -719,11 +775,43 @@ func (cm *ConversationManager) recordGitStateChange(ctx context.Context, state *
 	}
 
 	cm.logger.Debug("Recorded git state change", "state", state.String())
+	cm.recordConversationGitState(ctx, state)
+
+	cm.mu.Lock()
+	prev := cm.gitState
+	cm.gitState = state
+	onGitCommit := cm.onGitCommit
+	cm.mu.Unlock()
+	// Build the map of the new commit now, so that the next conversation
+	// here has it.
+	if prev == nil || prev.Worktree != state.Worktree || prev.Commit != state.Commit {
+		go cm.refreshRepoMap(state)
+	}
+	if onGitCommit != nil {
+		go onGitCommit(context.WithoutCancel(ctx), prev, state)
+	}
 
 	// Notify subscribers so the UI updates
 	go cm.notifyGitStateChange(context.WithoutCancel(ctx), createdMsg)
 }
 
+// recordConversationGitState stores the repository root, worktree and branch
+// from state on the conversation row so conversations can be grouped by
+// repository and filtered by branch.
+func (cm *ConversationManager) recordConversationGitState(ctx context.Context, state *gitstate.GitState) {
+	if state == nil || !state.IsRepo {
+		return
+	}
+	// For a linked worktree, group under the main repository.
+	repoRoot := getGitWorktreeRoot(state.Worktree)
+	if repoRoot == "" {
+		repoRoot = state.Worktree
+	}
+	if err := cm.db.RecordConversationGitState(ctx, cm.conversationID, repoRoot, state.Worktree, state.Branch); err != nil {
+		cm.logger.Error("Failed to record conversation git state", "error", err)
+	}
+}
+
 // notifyGitStateChange publishes a gitinfo message to subscribers.
 func (cm *ConversationManager) notifyGitStateChange(ctx context.Context, msg *generated.Message) {
 	var conversation generated.Conversation
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five-field cron expression
+// (minute hour day-of-month month day-of-week).
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64 // bit i set means value i matches
+	domStar, dowStar              bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var cronMonthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronDayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// parseCron parses a standard five-field cron expression. Fields accept "*",
+// numbers, ranges ("1-5"), steps ("*/15", "0-30/10"), comma-separated lists,
+// and three-letter month and weekday names. Day-of-week 7 is Sunday. The
+// macros @yearly, @monthly, @weekly, @daily and @hourly are also accepted.
+//
+// As in Vixie cron, when both day-of-month and day-of-week are restricted a
+// day matches if either field matches.
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	var c cronSchedule
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12, cronMonthNames); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7, cronDayNames); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = strings.HasPrefix(fields[2], "*")
+	c.dowStar = strings.HasPrefix(fields[4], "*")
+	return &c, nil
+}
+
+func parseCronField(field string, lo, hi int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		var start, end int
+		switch {
+		case rangePart == "*":
+			start, end = lo, hi
+		case strings.Contains(rangePart, "-"):
+			a, b, _ := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = parseCronValue(a, names); err != nil {
+				return 0, err
+			}
+			if end, err = parseCronValue(b, names); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := parseCronValue(rangePart, names)
+			if err != nil {
+				return 0, err
+			}
+			start, end = v, v
+			if hasStep {
+				end = hi
+			}
+		}
+		if start < lo || end > hi || start > end {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, lo, hi)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// dayMatches reports whether t's day satisfies the day-of-month and
+// day-of-week fields.
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Next returns the first time strictly after t that matches the schedule, in
+// t's location. Local times skipped by a DST transition do not match, and
+// local times repeated by one match only the first time. It returns the zero
+// time if nothing matches within five years (e.g. "0 0 30 2 *").
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = cronAdvance(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = cronAdvance(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 || cronRepeatedWallTime(t) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// cronAdvance returns next, or t plus an hour if a DST transition made the
+// local midnight next resolve to a time that is not after t.
+func cronAdvance(t, next time.Time) time.Time {
+	if !next.After(t) {
+		return t.Add(time.Hour)
+	}
+	return next
+}
+
+// cronRepeatedWallTime reports whether t's local wall-clock time already
+// occurred an hour earlier, i.e. t is in the second pass of a DST fall-back.
+func cronRepeatedWallTime(t time.Time) bool {
+	prev := t.Add(-time.Hour)
+	return prev.Day() == t.Day() && prev.Hour() == t.Hour() && prev.Minute() == t.Minute()
+}
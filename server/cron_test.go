@@ -0,0 +1,81 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no tzdata: %v", err)
+	}
+
+	// Friday 2026-10-16 10:30 UTC
+	from := time.Date(2026, 10, 16, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 * * * *", from, time.Date(2026, 10, 16, 10, 45, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", from, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * mon-fri", from, time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"30 10 * * *", from, time.Date(2026, 10, 17, 10, 30, 0, 0, time.UTC)},
+		{"@hourly", from, time.Date(2026, 10, 16, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", from, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", from, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 jan *", from, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either may match (the 20th, or a Sunday).
+		{"0 0 20 * sun", from, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// 9am in New York is 13:00 UTC during daylight saving time.
+		{"0 9 * * *", from.In(ny), time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC)},
+		// Spring forward: 2:30 does not exist on 2027-03-14, so that day is skipped.
+		{"30 2 * * *", time.Date(2027, 3, 14, 0, 0, 0, 0, ny), time.Date(2027, 3, 15, 2, 30, 0, 0, ny)},
+		// Fall back: 1:30 happens twice on 2026-11-01; only the first one runs.
+		{"30 1 * * *", time.Date(2026, 11, 1, 0, 0, 0, 0, ny), time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC)},
+		{"30 1 * * *", time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC).In(ny), time.Date(2026, 11, 2, 1, 30, 0, 0, ny)},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := c.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%v) = %v, want %v", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestCronNever(t *testing.T) {
+	c, err := parseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Next(time.Now()); !got.IsZero() {
+		t.Errorf("Next = %v, want zero time", got)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"x * * * *",
+		"@often",
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) succeeded, want error", expr)
+		}
+	}
+}
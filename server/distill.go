@@ -93,6 +93,10 @@ func (s *Server) handleDistillConversation(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if !s.authorizeConversation(w, r, req.SourceConversationID, accessRead) {
+		return
+	}
+
 	// Get messages from source conversation
 	messages, err := s.db.ListMessages(ctx, req.SourceConversationID)
 	if err != nil {
@@ -117,7 +121,7 @@ func (s *Server) handleDistillConversation(w http.ResponseWriter, r *http.Reques
 	} else if sourceConv.Cwd != nil {
 		cwdPtr = sourceConv.Cwd
 	}
-	conversation, err := s.db.CreateConversation(ctx, nil, true, cwdPtr, &modelID)
+	conversation, err := s.db.CreateOwnedConversation(ctx, strings.ToLower(r.Header.Get("X-ExeDev-Email")), nil, true, cwdPtr, &modelID)
 	if err != nil {
 		s.logger.Error("Failed to create conversation", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -9,11 +9,14 @@ import (
 	"os"
 	"os/exec"
 	"syscall"
+	"time"
 	"unsafe"
 
 	"github.com/coder/websocket"
 	"github.com/coder/websocket/wsjson"
 	"github.com/creack/pty"
+
+	"shelley.exe.dev/claudetool"
 )
 
 // ExecMessage is the message format for terminal websocket communication
@@ -83,12 +86,21 @@ func (s *Server) handleExecWS(w http.ResponseWriter, r *http.Request) {
 	shellCmd.Dir = cwd
 	shellCmd.Env = append(os.Environ(), "TERM=xterm-256color")
 
+	// Record the session in the audit log when it ends.
+	started := time.Now()
+	audit := claudetool.AuditRecord{Kind: auditTerminal, Cwd: cwd, Command: cmd}
+	defer func() {
+		audit.Duration = time.Since(started)
+		s.recordAudit(audit, r.Header.Get("X-ExeDev-Email"))
+	}()
+
 	// Start with pty
 	ptmx, err := pty.StartWithSize(shellCmd, &pty.Winsize{
 		Cols: cols,
 		Rows: rows,
 	})
 	if err != nil {
+		audit.Error = err.Error()
 		s.logger.Error("Failed to start command with pty", "error", err, "cmd", cmd)
 		errMsg := ExecMessage{
 			Type: "error",
@@ -162,6 +174,7 @@ func (s *Server) handleExecWS(w http.ResponseWriter, r *http.Request) {
 					exitCode = exitError.ExitCode()
 				}
 			}
+			audit.ExitCode = &exitCode
 			exitMsg := ExecMessage{
 				Type: "exit",
 				Data: fmt.Sprintf("%d", exitCode),
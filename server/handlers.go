@@ -15,6 +15,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -420,6 +421,10 @@ func (s *Server) serveIndexWithInit(w http.ResponseWriter, r *http.Request, fs h
 		initData["links"] = s.links
 	}
 
+	if user := requestUser(r.Context()); user != nil {
+		initData["auth_email"] = user.Email
+	}
+
 	// Inject notification channel type metadata for the settings modal
 	initData["notification_channel_types"] = s.getNotificationChannelTypes()
 
@@ -473,20 +478,38 @@ func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
 	}
 	query = r.URL.Query().Get("q")
 	searchContent := r.URL.Query().Get("search_content") == "true"
+	filter, err := parseConversationFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// Get conversations from database
 	var conversations []generated.Conversation
-	var err error
 
-	if query != "" {
-		if searchContent {
-			// Search in both slug and message content
+	viewer := s.viewer(r)
+	filtered := !filter.IsZero()
+	filter.Viewer = viewer
+
+	switch {
+	case searchContent && query != "" && !filtered:
+		// Search in both slug and message content, including subagents
+		if viewer != "" {
+			conversations, err = s.db.SearchVisibleConversationsWithMessages(ctx, viewer, query, int64(limit), int64(offset))
+		} else {
 			conversations, err = s.db.SearchConversationsWithMessages(ctx, query, int64(limit), int64(offset))
+		}
+	case filtered || viewer != "":
+		if searchContent {
+			filter.ContentQuery = query
 		} else {
-			// Search only in slug
-			conversations, err = s.db.SearchConversations(ctx, query, int64(limit), int64(offset))
+			filter.Query = query
 		}
-	} else {
+		conversations, err = s.db.ListConversationsFiltered(ctx, filter, int64(limit), int64(offset))
+	case query != "":
+		// Search only in slug
+		conversations, err = s.db.SearchConversations(ctx, query, int64(limit), int64(offset))
+	default:
 		conversations, err = s.db.ListConversations(ctx, int64(limit), int64(offset))
 	}
 
@@ -507,6 +530,22 @@ func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
 		subagentCounts = make(map[string]int64)
 	}
 
+	// Get tags for all conversations
+	tags, err := s.db.GetAllConversationTags(ctx)
+	if err != nil {
+		s.logger.Error("Failed to get conversation tags", "error", err)
+		// Non-fatal, continue without tags
+		tags = make(map[string][]string)
+	}
+
+	// Get branches for all conversations
+	branches, err := s.db.GetAllConversationBranches(ctx)
+	if err != nil {
+		s.logger.Error("Failed to get conversation branches", "error", err)
+		// Non-fatal, continue without branches
+		branches = make(map[string][]string)
+	}
+
 	// Build response with working state included
 	// Cache git info by cwd to avoid redundant git subprocess calls
 	gitStates := make(map[string]*gitstate.GitState)
@@ -516,6 +555,8 @@ func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
 			Conversation:  conv,
 			Working:       workingStates[conv.ConversationID],
 			SubagentCount: subagentCounts[conv.ConversationID],
+			Tags:          tags[conv.ConversationID],
+			Branches:      branches[conv.ConversationID],
 		}
 		if conv.Cwd != nil {
 			gs, ok := gitStates[*conv.Cwd]
@@ -541,37 +582,62 @@ func (s *Server) handleConversations(w http.ResponseWriter, r *http.Request) {
 func (s *Server) conversationMux() *http.ServeMux {
 	mux := http.NewServeMux()
 	// GET /api/conversation/<id> - returns all messages (can be large, compress)
-	mux.Handle("GET /{id}", gzipHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("GET /{id}", gzipHandler(s.withAccess(accessRead, func(w http.ResponseWriter, r *http.Request) {
 		s.handleGetConversation(w, r, r.PathValue("id"))
 	})))
 	// GET /api/conversation/<id>/stream - SSE stream (do NOT compress)
 	// TODO: Consider gzip for SSE in the future. Would reduce bandwidth
 	// for large tool outputs, but needs flush after each event.
-	mux.HandleFunc("GET /{id}/stream", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("GET /{id}/stream", s.withAccess(accessRead, func(w http.ResponseWriter, r *http.Request) {
 		s.handleStreamConversation(w, r, r.PathValue("id"))
-	})
+	}))
 	// POST endpoints - small responses, no compression needed
-	mux.HandleFunc("POST /{id}/chat", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("POST /{id}/chat", s.withAccess(accessCollaborate, func(w http.ResponseWriter, r *http.Request) {
 		s.handleChatConversation(w, r, r.PathValue("id"))
-	})
-	mux.HandleFunc("POST /{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+	}))
+	mux.HandleFunc("POST /{id}/cancel", s.withAccess(accessCollaborate, func(w http.ResponseWriter, r *http.Request) {
 		s.handleCancelConversation(w, r, r.PathValue("id"))
-	})
-	mux.HandleFunc("POST /{id}/archive", func(w http.ResponseWriter, r *http.Request) {
+	}))
+	mux.HandleFunc("POST /{id}/archive", s.withAccess(accessCollaborate, func(w http.ResponseWriter, r *http.Request) {
 		s.handleArchiveConversation(w, r, r.PathValue("id"))
-	})
-	mux.HandleFunc("POST /{id}/unarchive", func(w http.ResponseWriter, r *http.Request) {
+	}))
+	mux.HandleFunc("POST /{id}/unarchive", s.withAccess(accessCollaborate, func(w http.ResponseWriter, r *http.Request) {
 		s.handleUnarchiveConversation(w, r, r.PathValue("id"))
-	})
-	mux.HandleFunc("POST /{id}/delete", func(w http.ResponseWriter, r *http.Request) {
+	}))
+	mux.HandleFunc("POST /{id}/delete", s.withAccess(accessOwner, func(w http.ResponseWriter, r *http.Request) {
 		s.handleDeleteConversation(w, r, r.PathValue("id"))
-	})
-	mux.HandleFunc("POST /{id}/rename", func(w http.ResponseWriter, r *http.Request) {
+	}))
+	mux.HandleFunc("POST /{id}/rename", s.withAccess(accessCollaborate, func(w http.ResponseWriter, r *http.Request) {
 		s.handleRenameConversation(w, r, r.PathValue("id"))
-	})
-	mux.HandleFunc("GET /{id}/subagents", func(w http.ResponseWriter, r *http.Request) {
+	}))
+	mux.HandleFunc("GET /{id}/subagents", s.withAccess(accessRead, func(w http.ResponseWriter, r *http.Request) {
 		s.handleGetSubagents(w, r, r.PathValue("id"))
-	})
+	}))
+	mux.HandleFunc("GET /{id}/jobs", s.withAccess(accessRead, func(w http.ResponseWriter, r *http.Request) {
+		s.handleGetJobs(w, r, r.PathValue("id"))
+	}))
+	mux.HandleFunc("POST /{id}/jobs/{job}/stop", s.withAccess(accessCollaborate, func(w http.ResponseWriter, r *http.Request) {
+		s.handleStopJob(w, r, r.PathValue("id"), r.PathValue("job"))
+	}))
+	mux.HandleFunc("POST /{id}/tools/{tool}/cancel", s.withAccess(accessCollaborate, func(w http.ResponseWriter, r *http.Request) {
+		s.handleCancelToolCall(w, r, r.PathValue("id"), r.PathValue("tool"))
+	}))
+	mux.HandleFunc("POST /{id}/pin", s.withAccess(accessCollaborate, func(w http.ResponseWriter, r *http.Request) {
+		s.handlePinConversation(w, r, r.PathValue("id"), true)
+	}))
+	mux.HandleFunc("POST /{id}/unpin", s.withAccess(accessCollaborate, func(w http.ResponseWriter, r *http.Request) {
+		s.handlePinConversation(w, r, r.PathValue("id"), false)
+	}))
+	mux.HandleFunc("GET /{id}/tags", s.withAccess(accessRead, func(w http.ResponseWriter, r *http.Request) {
+		s.handleGetConversationTags(w, r, r.PathValue("id"))
+	}))
+	mux.HandleFunc("POST /{id}/tags", s.withAccess(accessCollaborate, func(w http.ResponseWriter, r *http.Request) {
+		s.handleSetConversationTags(w, r, r.PathValue("id"))
+	}))
+	// Sharing, in multi-user mode
+	mux.HandleFunc("GET /{id}/shares", s.withAccess(accessRead, s.handleConversationShares))
+	mux.HandleFunc("POST /{id}/shares", s.withAccess(accessOwner, s.handleConversationShares))
+	mux.HandleFunc("DELETE /{id}/shares/{email}", s.withAccess(accessOwner, s.handleConversationShare))
 	return mux
 }
 
@@ -658,36 +724,55 @@ func (s *Server) handleChatConversation(w http.ResponseWriter, r *http.Request,
 	}
 
 	userEmail := r.Header.Get("X-ExeDev-Email")
-
-	// Get or create conversation manager
-	manager, err := s.getOrCreateConversationManager(ctx, conversationID, userEmail)
+	err = s.sendUserMessage(ctx, conversationID, userEmail, llmService, modelID, req.Message)
 	if errors.Is(err, errConversationModelMismatch) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if errors.Is(err, errBudgetExceeded) {
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+		return
+	}
 	if err != nil {
-		s.logger.Error("Failed to get conversation manager", "conversationID", conversationID, "error", err)
+		s.logger.Error("Failed to send user message", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	// Create user message
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "accepted"})
+}
+
+// sendUserMessage queues text as the next user message of a conversation.
+// If it is the conversation's first message, the slug is generated from it.
+// In multi-user mode it fails with errBudgetExceeded once the conversation's
+// owner has spent their monthly budget.
+func (s *Server) sendUserMessage(ctx context.Context, conversationID, userEmail string, llmService llm.Service, modelID, text string) error {
+	if s.multiUser {
+		conv, err := s.db.GetConversationByID(ctx, conversationID)
+		if err != nil {
+			return fmt.Errorf("get conversation: %w", err)
+		}
+		if conv.OwnerEmail != nil {
+			if err := s.checkBudget(ctx, *conv.OwnerEmail); err != nil {
+				return err
+			}
+		}
+	}
+	manager, err := s.getOrCreateConversationManager(ctx, conversationID, userEmail)
+	if err != nil {
+		return fmt.Errorf("get conversation manager: %w", err)
+	}
+
 	userMessage := llm.Message{
 		Role: llm.MessageRoleUser,
 		Content: []llm.Content{
-			{Type: llm.ContentTypeText, Text: req.Message},
+			{Type: llm.ContentTypeText, Text: text},
 		},
 	}
-
 	firstMessage, err := manager.AcceptUserMessage(ctx, llmService, modelID, userMessage)
-	if errors.Is(err, errConversationModelMismatch) {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
 	if err != nil {
-		s.logger.Error("Failed to accept user message", "conversationID", conversationID, "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		return fmt.Errorf("accept user message: %w", err)
 	}
 
 	if firstMessage {
@@ -695,7 +780,7 @@ func (s *Server) handleChatConversation(w http.ResponseWriter, r *http.Request,
 		go func() {
 			slugCtx, cancel := context.WithTimeout(ctxNoCancel, 15*time.Second)
 			defer cancel()
-			_, err := slug.GenerateSlug(slugCtx, s.llmManager, s.db, s.logger, conversationID, req.Message, modelID)
+			_, err := slug.GenerateSlug(slugCtx, s.llmManager, s.db, s.logger, conversationID, text, modelID)
 			if err != nil {
 				s.logger.Warn("Failed to generate slug for conversation", "conversationID", conversationID, "error", err)
 			} else {
@@ -703,9 +788,7 @@ func (s *Server) handleChatConversation(w http.ResponseWriter, r *http.Request,
 			}
 		}()
 	}
-
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]string{"status": "accepted"})
+	return nil
 }
 
 // handleNewConversation handles POST /api/conversations/new - creates conversation implicitly on first message
@@ -748,7 +831,18 @@ func (s *Server) handleNewConversation(w http.ResponseWriter, r *http.Request) {
 	if req.Cwd != "" {
 		cwdPtr = &req.Cwd
 	}
-	conversation, err := s.db.CreateConversation(ctx, nil, true, cwdPtr, &modelID)
+	userEmail := r.Header.Get("X-ExeDev-Email")
+	owner := strings.ToLower(userEmail)
+	if err := s.checkBudget(ctx, owner); err != nil {
+		if errors.Is(err, errBudgetExceeded) {
+			http.Error(w, err.Error(), http.StatusPaymentRequired)
+			return
+		}
+		s.logger.Error("Failed to check budget", "email", owner, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	conversation, err := s.db.CreateOwnedConversation(ctx, owner, nil, true, cwdPtr, &modelID)
 	if err != nil {
 		s.logger.Error("Failed to create conversation", "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -762,53 +856,21 @@ func (s *Server) handleNewConversation(w http.ResponseWriter, r *http.Request) {
 		Conversation: conversation,
 	})
 
-	userEmail := r.Header.Get("X-ExeDev-Email")
-
-	// Get or create conversation manager
-	manager, err := s.getOrCreateConversationManager(ctx, conversationID, userEmail)
+	err = s.sendUserMessage(ctx, conversationID, userEmail, llmService, modelID, req.Message)
 	if errors.Is(err, errConversationModelMismatch) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	if err != nil {
-		s.logger.Error("Failed to get conversation manager", "conversationID", conversationID, "error", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Create user message
-	userMessage := llm.Message{
-		Role: llm.MessageRoleUser,
-		Content: []llm.Content{
-			{Type: llm.ContentTypeText, Text: req.Message},
-		},
-	}
-
-	firstMessage, err := manager.AcceptUserMessage(ctx, llmService, modelID, userMessage)
-	if errors.Is(err, errConversationModelMismatch) {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if errors.Is(err, errBudgetExceeded) {
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
 		return
 	}
 	if err != nil {
-		s.logger.Error("Failed to accept user message", "conversationID", conversationID, "error", err)
+		s.logger.Error("Failed to send user message", "conversationID", conversationID, "error", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	if firstMessage {
-		ctxNoCancel := context.WithoutCancel(ctx)
-		go func() {
-			slugCtx, cancel := context.WithTimeout(ctxNoCancel, 15*time.Second)
-			defer cancel()
-			_, err := slug.GenerateSlug(slugCtx, s.llmManager, s.db, s.logger, conversationID, req.Message, modelID)
-			if err != nil {
-				s.logger.Warn("Failed to generate slug for conversation", "conversationID", conversationID, "error", err)
-			} else {
-				go s.notifySubscribers(ctxNoCancel, conversationID)
-			}
-		}()
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -975,6 +1037,16 @@ func (s *Server) handleStreamConversation(w http.ResponseWriter, r *http.Request
 	// Subscribe to new messages after the last one we sent
 	next := manager.subpub.Subscribe(ctx, lastSeqID)
 
+	// Catch up on the output of running tool calls. This comes after
+	// subscribing so that no output is missed; clients use the offsets
+	// to drop what they see twice.
+	for _, progress := range manager.RunningToolOutput() {
+		data, _ := json.Marshal(StreamResponse{ToolProgress: &progress})
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		w.(http.Flusher).Flush()
+	}
+	visible := s.conversationVisibility(ctx, s.viewer(r))
+
 	// Start heartbeat goroutine - sends state every 30 seconds if no other messages
 	heartbeatDone := make(chan struct{})
 	go func() {
@@ -1018,6 +1090,10 @@ func (s *Server) handleStreamConversation(w http.ResponseWriter, r *http.Request
 		if !cont {
 			break
 		}
+		// Updates about other conversations only go to users who may see them.
+		if !streamVisible(streamData, visible) {
+			continue
+		}
 		// Always forward updates, even if only the conversation changed (e.g., slug added)
 		data, _ := json.Marshal(streamData)
 		fmt.Fprintf(w, "data: %s\n\n", data)
@@ -1112,7 +1188,9 @@ func (s *Server) handleArchivedConversations(w http.ResponseWriter, r *http.Requ
 	var conversations []generated.Conversation
 	var err error
 
-	if query != "" {
+	if viewer := s.viewer(r); viewer != "" {
+		conversations, err = s.db.ListVisibleArchivedConversations(ctx, viewer, query, int64(limit), int64(offset))
+	} else if query != "" {
 		conversations, err = s.db.SearchArchivedConversations(ctx, query, int64(limit), int64(offset))
 	} else {
 		conversations, err = s.db.ListArchivedConversations(ctx, int64(limit), int64(offset))
@@ -1142,6 +1220,7 @@ func (s *Server) handleArchiveConversation(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	go s.jobs.StopConversation(conversationID)
 
 	// Notify conversation list subscribers
 	go s.publishConversationListUpdate(ConversationListUpdate{
@@ -1191,6 +1270,7 @@ func (s *Server) handleDeleteConversation(w http.ResponseWriter, r *http.Request
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	go s.jobs.StopConversation(conversationID)
 
 	// Notify conversation list subscribers about the deletion
 	go s.publishConversationListUpdate(ConversationListUpdate{
@@ -1226,6 +1306,9 @@ func (s *Server) handleConversationBySlug(w http.ResponseWriter, r *http.Request
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	if !s.authorizeConversation(w, r, conversation.ConversationID, accessRead) {
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(conversation)
@@ -1363,7 +1446,8 @@ func (s *Server) handleExit(w http.ResponseWriter, r *http.Request) {
 	}()
 }
 
-// handleGetSettings retrieves all settings
+// handleGetSettings retrieves all settings, with the user's overrides in
+// multi-user mode
 func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
 	settings, err := s.db.GetAllSettings(r.Context())
 	if err != nil {
@@ -1371,16 +1455,34 @@ func (s *Server) handleGetSettings(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("Failed to get settings: %v", err), http.StatusInternalServerError)
 		return
 	}
+	delete(settings, redactionKeySetting)
+	// In multi-user mode, the user's own overrides replace server-wide values.
+	if viewer := s.viewer(r); viewer != "" {
+		overrides, err := s.db.ListUserSettings(r.Context(), viewer)
+		if err != nil {
+			s.logger.Error("Failed to get user settings", "error", err)
+			http.Error(w, fmt.Sprintf("Failed to get settings: %v", err), http.StatusInternalServerError)
+			return
+		}
+		for _, key := range userSettingKeys {
+			if value, ok := overrides[key]; ok {
+				settings[key] = value
+			}
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(settings)
 }
 
-// handleSetSetting sets a single setting
+// handleSetSetting sets a single setting. In multi-user mode, settings in
+// userSettingKeys are set for the user making the request unless scope is
+// "server"; server-wide settings can only be changed by admins.
 func (s *Server) handleSetSetting(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Key   string `json:"key"`
 		Value string `json:"value"`
+		Scope string `json:"scope,omitempty"` // "user" or "server"
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -1397,12 +1499,44 @@ func (s *Server) handleSetSetting(w http.ResponseWriter, r *http.Request) {
 	allowedKeys := map[string]bool{
 		"auto_upgrade": true,
 	}
+	for _, key := range notificationSettingKeys {
+		allowedKeys[key] = true
+	}
 	if !allowedKeys[req.Key] {
 		http.Error(w, fmt.Sprintf("Invalid setting key: %s", req.Key), http.StatusBadRequest)
 		return
 	}
 
-	if err := s.db.SetSetting(r.Context(), req.Key, req.Value); err != nil {
+	viewer := s.viewer(r)
+	if req.Scope == "" {
+		req.Scope = "server"
+		if viewer != "" && slices.Contains(userSettingKeys, req.Key) {
+			req.Scope = "user"
+		}
+	}
+	var err error
+	switch req.Scope {
+	case "user":
+		if viewer == "" {
+			http.Error(w, "Per-user settings need a signed-in user in multi-user mode", http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(userSettingKeys, req.Key) {
+			http.Error(w, fmt.Sprintf("Setting %s can't be set per user", req.Key), http.StatusBadRequest)
+			return
+		}
+		err = s.db.SetUserSetting(r.Context(), viewer, req.Key, req.Value)
+	case "server":
+		if !s.isAdmin(r) {
+			http.Error(w, "Only admins can change server-wide settings", http.StatusForbidden)
+			return
+		}
+		err = s.db.SetSetting(r.Context(), req.Key, req.Value)
+	default:
+		http.Error(w, fmt.Sprintf("Invalid scope: %s", req.Scope), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
 		s.logger.Error("Failed to set setting", "error", err, "key", req.Key)
 		http.Error(w, fmt.Sprintf("Failed to set setting: %v", err), http.StatusInternalServerError)
 		return
@@ -0,0 +1,325 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/llm"
+)
+
+// HeadlessStatus is the outcome of a headless run.
+type HeadlessStatus string
+
+const (
+	HeadlessStatusSuccess        HeadlessStatus = "success"
+	HeadlessStatusError          HeadlessStatus = "error"
+	HeadlessStatusTimeout        HeadlessStatus = "timeout"
+	HeadlessStatusBudgetExceeded HeadlessStatus = "budget_exceeded"
+)
+
+// HeadlessRunRequest describes a single non-interactive agent turn, as used by
+// `shelley run` from CI and cron jobs.
+type HeadlessRunRequest struct {
+	Prompt string
+	Model  string
+	Cwd    string
+	// Timeout bounds the whole run. Zero means no timeout.
+	Timeout time.Duration
+	// MaxCostUSD cancels the run once the accumulated cost exceeds it. Zero means no budget.
+	MaxCostUSD float64
+}
+
+// HeadlessCommit is a git commit made during a headless run.
+type HeadlessCommit struct {
+	Hash    string `json:"hash"`
+	Subject string `json:"subject"`
+}
+
+// HeadlessRunResult summarizes a headless run.
+type HeadlessRunResult struct {
+	Status         HeadlessStatus   `json:"status"`
+	ConversationID string           `json:"conversation_id"`
+	Slug           string           `json:"slug,omitempty"`
+	Model          string           `json:"model"`
+	Cwd            string           `json:"cwd,omitempty"`
+	Response       string           `json:"response"`
+	Error          string           `json:"error,omitempty"`
+	Usage          llm.Usage        `json:"usage"`
+	Commits        []HeadlessCommit `json:"commits"`
+	FilesChanged   []string         `json:"files_changed"`
+	DurationMS     int64            `json:"duration_ms"`
+}
+
+// headlessPollInterval is how often RunHeadless checks whether the agent is done.
+const headlessPollInterval = 500 * time.Millisecond
+
+// RunHeadless creates a conversation, sends the prompt, and waits for the agent
+// to finish its turn, time out, or exceed its budget. Like
+// SubagentRunner.waitForResponse it polls the conversation manager's working
+// state rather than subscribing to the stream.
+//
+// The returned error is only set when the run could not be started; failures
+// of the agent itself are reported through HeadlessRunResult.Status.
+func (s *Server) RunHeadless(ctx context.Context, req HeadlessRunRequest) (*HeadlessRunResult, error) {
+	start := time.Now()
+
+	if strings.TrimSpace(req.Prompt) == "" {
+		return nil, errors.New("prompt is required")
+	}
+
+	modelID := req.Model
+	if modelID == "" {
+		modelID = s.defaultModel
+	}
+	if modelID == "" && s.predictableOnly {
+		modelID = "predictable"
+	}
+	llmService, err := s.llmManager.GetService(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported model %s: %w", modelID, err)
+	}
+
+	var cwdPtr *string
+	if req.Cwd != "" {
+		cwdPtr = &req.Cwd
+	}
+	conversation, err := s.db.CreateConversation(ctx, nil, true, cwdPtr, &modelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create conversation: %w", err)
+	}
+	conversationID := conversation.ConversationID
+
+	manager, err := s.getOrCreateConversationManager(ctx, conversationID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation manager: %w", err)
+	}
+
+	// Files already changed before the run are only reported if the run
+	// changes them again.
+	startHead := gitHead(req.Cwd)
+	var startDirty map[string]string
+	if startHead != "" {
+		_, startDirty = gitDirtyFiles(req.Cwd, startHead)
+	}
+
+	userMessage := llm.Message{
+		Role:    llm.MessageRoleUser,
+		Content: []llm.Content{{Type: llm.ContentTypeText, Text: req.Prompt}},
+	}
+	if _, err := manager.AcceptUserMessage(ctx, llmService, modelID, userMessage); err != nil {
+		return nil, fmt.Errorf("failed to accept user message: %w", err)
+	}
+
+	result := &HeadlessRunResult{
+		Status:         HeadlessStatusSuccess,
+		ConversationID: conversationID,
+		Model:          modelID,
+		Cwd:            req.Cwd,
+		Commits:        []HeadlessCommit{},
+		FilesChanged:   []string{},
+	}
+
+	var deadline <-chan time.Time
+	if req.Timeout > 0 {
+		timer := time.NewTimer(req.Timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	ticker := time.NewTicker(headlessPollInterval)
+	defer ticker.Stop()
+
+wait:
+	for {
+		if !manager.IsAgentWorking() {
+			break
+		}
+		if req.MaxCostUSD > 0 {
+			if usage := manager.GetUsage(); usage.CostUSD > req.MaxCostUSD {
+				result.Status = HeadlessStatusBudgetExceeded
+				result.Error = fmt.Sprintf("cost $%.4f exceeded budget of $%.4f", usage.CostUSD, req.MaxCostUSD)
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			result.Status = HeadlessStatusError
+			result.Error = ctx.Err().Error()
+			break wait
+		case <-deadline:
+			result.Status = HeadlessStatusTimeout
+			result.Error = fmt.Sprintf("agent did not finish within %s", req.Timeout)
+			break wait
+		case <-ticker.C:
+		}
+	}
+
+	if result.Status != HeadlessStatusSuccess {
+		// Use a fresh context: ctx may already be done.
+		if err := manager.CancelConversation(context.WithoutCancel(ctx)); err != nil {
+			s.logger.Warn("Failed to cancel headless conversation", "conversationID", conversationID, "error", err)
+		}
+	}
+
+	result.Usage = manager.GetUsage()
+	result.Usage.Model = modelID
+
+	response, errorType, err := s.lastAgentResponse(context.WithoutCancel(ctx), conversationID)
+	if err != nil {
+		s.logger.Warn("Failed to read headless response", "conversationID", conversationID, "error", err)
+	}
+	result.Response = response
+	if errorType != llm.ErrorTypeNone && result.Status == HeadlessStatusSuccess {
+		result.Status = HeadlessStatusError
+		result.Error = response
+	}
+
+	if conv, err := s.db.GetConversationByID(context.WithoutCancel(ctx), conversationID); err == nil && conv.Slug != nil {
+		result.Slug = *conv.Slug
+	}
+
+	if startHead != "" {
+		result.Commits = gitCommitsSince(req.Cwd, startHead)
+		result.FilesChanged = gitFilesChangedSince(req.Cwd, startHead, startDirty)
+	}
+
+	result.DurationMS = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// lastAgentResponse returns the text of the most recent agent or error message
+// in a conversation, along with its error type.
+func (s *Server) lastAgentResponse(ctx context.Context, conversationID string) (string, llm.ErrorType, error) {
+	messages, err := s.db.ListMessages(ctx, conversationID)
+	if err != nil {
+		return "", llm.ErrorTypeNone, err
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Type != string(db.MessageTypeAgent) && msg.Type != string(db.MessageTypeError) {
+			continue
+		}
+		if msg.LlmData == nil {
+			continue
+		}
+		var llmMsg llm.Message
+		if err := json.Unmarshal([]byte(*msg.LlmData), &llmMsg); err != nil {
+			return "", llm.ErrorTypeNone, fmt.Errorf("failed to parse message: %w", err)
+		}
+		var texts []string
+		for _, content := range llmMsg.Content {
+			if content.Type == llm.ContentTypeText && content.Text != "" {
+				texts = append(texts, content.Text)
+			}
+		}
+		if len(texts) == 0 && llmMsg.ErrorType == llm.ErrorTypeNone {
+			// Tool-use-only message; keep looking for the last prose.
+			continue
+		}
+		return strings.Join(texts, "\n"), llmMsg.ErrorType, nil
+	}
+	return "", llm.ErrorTypeNone, nil
+}
+
+// gitHead returns the full commit hash of HEAD in dir, or "" if dir is not a
+// git repository or has no commits.
+func gitHead(dir string) string {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "HEAD")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// gitCommitsSince lists commits reachable from HEAD but not from base, oldest first.
+func gitCommitsSince(dir, base string) []HeadlessCommit {
+	commits := []HeadlessCommit{}
+	cmd := exec.Command("git", "log", "--reverse", "--pretty=format:%H%x00%s", base+"..HEAD")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return commits
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		hash, subject, ok := strings.Cut(line, "\x00")
+		if !ok {
+			continue
+		}
+		commits = append(commits, HeadlessCommit{Hash: hash, Subject: subject})
+	}
+	return commits
+}
+
+// gitFilesChangedSince lists files that differ between base and the working
+// tree, including new untracked files, leaving out those whose contents are
+// as recorded in before by gitDirtyFiles. Files in before that no longer
+// differ from base were restored, and are listed too. Paths are relative to
+// the repository root.
+func gitFilesChangedSince(dir, base string, before map[string]string) []string {
+	files, hashes := gitDirtyFiles(dir, base)
+	changed := []string{}
+	for _, file := range files {
+		if hash, ok := before[file]; !ok || hash != hashes[file] {
+			changed = append(changed, file)
+		}
+	}
+	for _, file := range slices.Sorted(maps.Keys(before)) {
+		if _, ok := hashes[file]; !ok {
+			changed = append(changed, file)
+		}
+	}
+	return changed
+}
+
+// gitDirtyFiles lists files that differ between base and the working tree,
+// including untracked files, with a hash of the contents of each ("" if it
+// was deleted). Paths are relative to the repository root.
+func gitDirtyFiles(dir, base string) ([]string, map[string]string) {
+	var files []string
+	hashes := make(map[string]string)
+	cmd := exec.Command("git", "rev-parse", "--show-toplevel")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return files, hashes
+	}
+	root := strings.TrimSpace(string(output))
+	add := func(output []byte) {
+		for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			if _, seen := hashes[line]; line == "" || seen {
+				continue
+			}
+			hashes[line] = ""
+			if data, err := os.ReadFile(filepath.Join(root, line)); err == nil {
+				sum := sha256.Sum256(data)
+				hashes[line] = hex.EncodeToString(sum[:])
+			}
+			files = append(files, line)
+		}
+	}
+
+	cmd = exec.Command("git", "diff", "--name-only", base)
+	cmd.Dir = dir
+	if output, err := cmd.Output(); err == nil {
+		add(output)
+	}
+	cmd = exec.Command("git", "ls-files", "--others", "--exclude-standard", "--full-name")
+	cmd.Dir = dir
+	if output, err := cmd.Output(); err == nil {
+		add(output)
+	}
+	return files, hashes
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunHeadless(t *testing.T) {
+	for _, k := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(k, "Test")
+	}
+	for _, k := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(k, "test@example.com")
+	}
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"commit", "-q", "--allow-empty", "-m", "initial"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	h := NewTestHarness(t)
+
+	t.Run("success", func(t *testing.T) {
+		result, err := h.server.RunHeadless(context.Background(), HeadlessRunRequest{
+			Prompt:  "bash: echo a > a.txt && git add a.txt && git commit -q -m 'add a' && echo b > b.txt",
+			Model:   "predictable",
+			Cwd:     dir,
+			Timeout: 10 * time.Second,
+		})
+		if err != nil {
+			t.Fatalf("RunHeadless: %v", err)
+		}
+		if result.Status != HeadlessStatusSuccess {
+			t.Fatalf("status = %q (%s), want success", result.Status, result.Error)
+		}
+		if result.Response == "" {
+			t.Error("expected a final response")
+		}
+		if result.Usage.OutputTokens == 0 {
+			t.Error("expected usage to be reported")
+		}
+		if len(result.Commits) != 1 || result.Commits[0].Subject != "add a" {
+			t.Errorf("commits = %+v, want one commit %q", result.Commits, "add a")
+		}
+		if want := []string{"a.txt", "b.txt"}; !reflect.DeepEqual(result.FilesChanged, want) {
+			t.Errorf("files_changed = %v, want %v", result.FilesChanged, want)
+		}
+	})
+
+	t.Run("dirty worktree", func(t *testing.T) {
+		// b.txt is left over from the last run; c.txt is untracked and
+		// changed again, d.txt untracked and left alone.
+		os.WriteFile(filepath.Join(dir, "c.txt"), []byte("c\n"), 0o644)
+		os.WriteFile(filepath.Join(dir, "d.txt"), []byte("d\n"), 0o644)
+		result, err := h.server.RunHeadless(context.Background(), HeadlessRunRequest{
+			Prompt:  "bash: echo more >> c.txt && echo e > e.txt",
+			Model:   "predictable",
+			Cwd:     dir,
+			Timeout: 10 * time.Second,
+		})
+		if err != nil {
+			t.Fatalf("RunHeadless: %v", err)
+		}
+		if want := []string{"c.txt", "e.txt"}; !reflect.DeepEqual(result.FilesChanged, want) {
+			t.Errorf("files_changed = %v, want %v", result.FilesChanged, want)
+		}
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		result, err := h.server.RunHeadless(context.Background(), HeadlessRunRequest{
+			Prompt:  "bash: sleep 10",
+			Model:   "predictable",
+			Cwd:     dir,
+			Timeout: 500 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("RunHeadless: %v", err)
+		}
+		if result.Status != HeadlessStatusTimeout {
+			t.Fatalf("status = %q, want timeout", result.Status)
+		}
+		if h.server.IsAgentWorking(result.ConversationID) {
+			t.Error("agent still working after timeout")
+		}
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		result, err := h.server.RunHeadless(context.Background(), HeadlessRunRequest{
+			Prompt:     "bash: sleep 10",
+			Model:      "predictable",
+			Cwd:        dir,
+			Timeout:    10 * time.Second,
+			MaxCostUSD: 1e-9,
+		})
+		if err != nil {
+			t.Fatalf("RunHeadless: %v", err)
+		}
+		if result.Status != HeadlessStatusBudgetExceeded {
+			t.Fatalf("status = %q, want budget_exceeded", result.Status)
+		}
+	})
+
+	t.Run("empty prompt", func(t *testing.T) {
+		if _, err := h.server.RunHeadless(context.Background(), HeadlessRunRequest{Prompt: "  "}); err == nil {
+			t.Fatal("expected error for empty prompt")
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"shelley.exe.dev/claudetool"
+)
+
+// handleGetJobs handles GET /api/conversation/<id>/jobs, listing the
+// conversation's background bash jobs, oldest first.
+func (s *Server) handleGetJobs(w http.ResponseWriter, r *http.Request, conversationID string) {
+	infos := []claudetool.JobInfo{}
+	for _, j := range s.jobs.List(conversationID) {
+		infos = append(infos, j.Info())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(infos)
+}
+
+// handleStopJob handles POST /api/conversation/<id>/jobs/<job>/stop. It
+// returns once the job has exited.
+func (s *Server) handleStopJob(w http.ResponseWriter, r *http.Request, conversationID, jobID string) {
+	j, ok := s.jobs.Get(conversationID, jobID)
+	if !ok {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	j.Stop()
+	s.logger.Info("Stopped background job", "conversationID", conversationID, "job", jobID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(j.Info())
+}
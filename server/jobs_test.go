@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/claudetool"
+)
+
+func TestJobsEndpoints(t *testing.T) {
+	h := NewTestHarness(t)
+	t.Cleanup(h.server.jobs.StopAll)
+	ctx := context.Background()
+	conv, err := h.db.CreateConversation(ctx, nil, true, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	id := conv.ConversationID
+
+	start := func() *claudetool.Job {
+		cmd := exec.Command("sleep", "60")
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		j, err := h.server.jobs.Start(id, "sleep 60", cmd)
+		if err != nil {
+			t.Fatalf("Failed to start job: %v", err)
+		}
+		return j
+	}
+	first, second := start(), start()
+
+	w := httptest.NewRecorder()
+	h.server.handleGetJobs(w, httptest.NewRequest(http.MethodGet, "/api/conversation/"+id+"/jobs", nil), id)
+	var infos []claudetool.JobInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &infos); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if len(infos) != 2 || infos[0].ID != first.ID || !infos[0].Running || infos[0].Command != "sleep 60" {
+		t.Fatalf("Unexpected jobs: %+v", infos)
+	}
+
+	w = httptest.NewRecorder()
+	h.server.handleStopJob(w, httptest.NewRequest(http.MethodPost, "/", nil), id, first.ID)
+	if w.Code != http.StatusOK || first.Running() {
+		t.Errorf("Stop: status %d, running %v", w.Code, first.Running())
+	}
+	w = httptest.NewRecorder()
+	h.server.handleStopJob(w, httptest.NewRequest(http.MethodPost, "/", nil), "other", second.ID)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Stopping another conversation's job: status %d", w.Code)
+	}
+
+	// Archiving the conversation stops its jobs.
+	w = httptest.NewRecorder()
+	h.server.handleArchiveConversation(w, httptest.NewRequest(http.MethodPost, "/", nil), id)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Archive: status %d", w.Code)
+	}
+	select {
+	case <-second.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("Job still running after archive")
+	}
+
+	w = httptest.NewRecorder()
+	h.server.handleGetJobs(w, httptest.NewRequest(http.MethodGet, "/", nil), id)
+	if got := w.Body.String(); got != "[]\n" {
+		t.Errorf("Jobs after archive = %q", got)
+	}
+}
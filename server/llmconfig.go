@@ -3,7 +3,10 @@ package server
 import (
 	"log/slog"
 
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/claudetool/codeindex"
 	"shelley.exe.dev/db"
+	"shelley.exe.dev/redact"
 )
 
 // Link represents a custom link to be displayed in the UI
@@ -37,6 +40,26 @@ type LLMConfig struct {
 	// Each entry is a map with at least a "type" key, plus channel-specific fields.
 	NotificationChannels []map[string]any
 
+	// Auth is the built-in authentication config from shelley.json. It only
+	// takes effect when the server is started with -auth.
+	Auth AuthConfig
+
+	// Redaction configures secret redaction from shelley.json. The built-in
+	// detectors are on unless it sets "disabled".
+	Redaction redact.Config
+
+	// PostPatch configures the formatters and checks run after each patch,
+	// from shelley.json (optional).
+	PostPatch *claudetool.PostPatchConfig
+
+	// PersistentShell makes the bash tool keep one shell per conversation,
+	// from shelley.json (optional).
+	PersistentShell bool
+
+	// CodeIndex configures the code search index, from shelley.json
+	// (optional). If nil, there is no index.
+	CodeIndex *codeindex.Config
+
 	// DB is the database for recording LLM requests (optional)
 	DB *db.DB
 
@@ -26,8 +26,9 @@ func LoggerMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
 func RequireHeaderMiddleware(headerName string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Only check API routes
-			if strings.HasPrefix(r.URL.Path, "/api/") {
+			// Only check API routes. Trigger invocations and notification
+			// replies come from outside and carry their own credentials.
+			if strings.HasPrefix(r.URL.Path, "/api/") && !hasOwnCredentials(r) {
 				if r.Header.Get(headerName) == "" {
 					http.Error(w, "missing required header: "+headerName, http.StatusForbidden)
 					return
@@ -38,6 +39,20 @@ func RequireHeaderMiddleware(headerName string) func(http.Handler) http.Handler
 	}
 }
 
+// hasOwnCredentials reports whether r is a POST /api/triggers/<id> or
+// /api/notification-replies/<token>, which authenticate themselves.
+func hasOwnCredentials(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	for _, prefix := range []string{"/api/triggers/", "/api/notification-replies/"} {
+		if id, ok := strings.CutPrefix(r.URL.Path, prefix); ok && id != "" && !strings.Contains(id, "/") {
+			return true
+		}
+	}
+	return false
+}
+
 // gzipResponseWriter wraps http.ResponseWriter to compress responses
 type gzipResponseWriter struct {
 	http.ResponseWriter
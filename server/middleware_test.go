@@ -70,6 +70,31 @@ func TestRequireHeaderMiddleware_AllowsVersionEndpointWithoutHeader(t *testing.T
 	}
 }
 
+func TestRequireHeaderMiddleware_AllowsSelfAuthenticatedWithoutHeader(t *testing.T) {
+	handler := RequireHeaderMiddleware("X-Exedev-Userid")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{"POST", "/api/triggers/trigger-1", http.StatusOK},
+		{"GET", "/api/triggers/trigger-1", http.StatusForbidden},
+		{"POST", "/api/triggers/trigger-1/rotate-secret", http.StatusForbidden},
+		{"POST", "/api/notification-replies/abc123", http.StatusOK},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if w.Code != tc.want {
+			t.Errorf("%s %s without required header: got %d, want %d", tc.method, tc.path, w.Code, tc.want)
+		}
+	}
+}
+
 func TestGzipHandler_CompressesResponse(t *testing.T) {
 	handler := gzipHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,506 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/db/generated"
+)
+
+// Multi-user mode (shelley serve -multi-user) gives each conversation an
+// owner, the X-ExeDev-Email of whoever started it, and limits every user to
+// their own conversations, the ones shared with them, and ownerless ones
+// (started over the Unix socket, by schedules or by triggers). Only admins
+// may configure schedules, triggers and notification channels, use the debug
+// pages, or upgrade and stop the server. Requests over
+// the Unix socket carry no identity and are unrestricted.
+
+// accessLevel is what a user may do with a conversation.
+type accessLevel int
+
+const (
+	accessNone        accessLevel = iota
+	accessRead                    // view messages and stream updates
+	accessCollaborate             // also chat, cancel, rename, archive, pin and tag
+	accessOwner                   // also delete and manage shares
+)
+
+// Roles a conversation can be shared with.
+const (
+	shareRoleRead        = "read"
+	shareRoleCollaborate = "collaborate"
+)
+
+// monthlyBudgetSetting is the per-user setting holding how many USD the
+// conversations a user owns may spend per calendar month (UTC).
+const monthlyBudgetSetting = "monthly_budget_usd"
+
+// userSettingKeys are the settings each user may override for the
+// conversations they own.
+var userSettingKeys = []string{notifyBudgetSetting, notifyLongBashSetting, notifyIdleSetting}
+
+// errBudgetExceeded is returned when a conversation's owner has spent their
+// monthly budget.
+var errBudgetExceeded = errors.New("monthly budget exceeded")
+
+// EnableMultiUser turns on per-user conversation ownership. The emails in
+// admins may change server-wide settings and everyone's budgets.
+func (s *Server) EnableMultiUser(admins []string) {
+	s.multiUser = true
+	s.admins = make(map[string]bool)
+	for _, email := range admins {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			s.admins[email] = true
+		}
+	}
+}
+
+// viewer returns the email of the user making r in multi-user mode, or ""
+// if the request is unrestricted.
+func (s *Server) viewer(r *http.Request) string {
+	if !s.multiUser {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(r.Header.Get("X-ExeDev-Email")))
+}
+
+// isAdmin reports whether r may change server-wide settings and budgets.
+func (s *Server) isAdmin(r *http.Request) bool {
+	viewer := s.viewer(r)
+	return viewer == "" || s.admins[viewer]
+}
+
+// adminOnly wraps a handler that acts on the whole server, such as
+// notification channels, schedules, triggers, the debug pages and
+// upgrading, so that only admins may use it. Conversations started by
+// schedules and triggers have no owner, and the debug pages show every
+// conversation's LLM requests, so letting any user at them would let users
+// read each other's work and get around their budgets.
+func (s *Server) adminOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAdmin(r) {
+			http.Error(w, "Only admins can do that", http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// RequireIdentityMiddleware refuses API requests without an
+// X-ExeDev-Email, so that a TCP client can't act as the unrestricted local
+// user. It runs after AuthMiddleware, which sets the header.
+func (s *Server) RequireIdentityMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") && !authExempt(r) && s.viewer(r) == "" {
+			http.Error(w, "multi-user mode requires a signed-in user", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// conversationAccess returns what viewer may do with conv. Shares apply to
+// a top-level conversation and its subagents.
+func (s *Server) conversationAccess(ctx context.Context, viewer string, conv *generated.Conversation) (accessLevel, error) {
+	if viewer == "" {
+		return accessOwner, nil
+	}
+	if conv.OwnerEmail == nil {
+		if s.admins[viewer] {
+			return accessOwner, nil
+		}
+		return accessCollaborate, nil
+	}
+	if strings.EqualFold(*conv.OwnerEmail, viewer) {
+		return accessOwner, nil
+	}
+	root := conv.ConversationID
+	if conv.ParentConversationID != nil {
+		root = *conv.ParentConversationID
+	}
+	share, err := s.db.GetConversationShare(ctx, root, viewer)
+	if errors.Is(err, sql.ErrNoRows) {
+		return accessNone, nil
+	}
+	if err != nil {
+		return accessNone, err
+	}
+	if share.Role == shareRoleCollaborate {
+		return accessCollaborate, nil
+	}
+	return accessRead, nil
+}
+
+// authorizeConversation writes an error and returns false unless the user
+// making r has at least the need access level to the conversation.
+// Conversations the user can't see at all are reported as not found.
+func (s *Server) authorizeConversation(w http.ResponseWriter, r *http.Request, conversationID string, need accessLevel) bool {
+	viewer := s.viewer(r)
+	if viewer == "" {
+		return true
+	}
+	conv, err := s.db.GetConversationByID(r.Context(), conversationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return true // let the handler report it
+	}
+	if err != nil {
+		s.logger.Error("Failed to get conversation", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	access, err := s.conversationAccess(r.Context(), viewer, conv)
+	if err != nil {
+		s.logger.Error("Failed to check conversation access", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return false
+	}
+	if access == accessNone {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return false
+	}
+	if access < need {
+		http.Error(w, "You don't have permission to do that in this conversation", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// withAccess wraps a conversationMux handler so it only runs for users with
+// the need access level to the conversation in the {id} path segment.
+func (s *Server) withAccess(need accessLevel, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.authorizeConversation(w, r, r.PathValue("id"), need) {
+			handler(w, r)
+		}
+	}
+}
+
+// conversationVisibility returns a function reporting whether viewer may
+// see a conversation, remembering the answers. It is used by SSE streams,
+// which carry updates about every conversation.
+func (s *Server) conversationVisibility(ctx context.Context, viewer string) func(conversationID string, conv *generated.Conversation) bool {
+	visible := make(map[string]bool)
+	return func(conversationID string, conv *generated.Conversation) bool {
+		if viewer == "" || conversationID == "" {
+			return true
+		}
+		if v, ok := visible[conversationID]; ok {
+			return v
+		}
+		if conv == nil {
+			var err error
+			if conv, err = s.db.GetConversationByID(ctx, conversationID); err != nil {
+				return false
+			}
+		}
+		access, err := s.conversationAccess(ctx, viewer, conv)
+		if err != nil {
+			return false
+		}
+		visible[conversationID] = access > accessNone
+		return visible[conversationID]
+	}
+}
+
+// streamVisible reports whether a stream update that may concern other
+// conversations should go to a viewer.
+func streamVisible(data StreamResponse, visible func(string, *generated.Conversation) bool) bool {
+	if u := data.ConversationListUpdate; u != nil && u.Conversation != nil && !visible(u.Conversation.ConversationID, u.Conversation) {
+		return false
+	}
+	if st := data.ConversationState; st != nil && !visible(st.ConversationID, nil) {
+		return false
+	}
+	if ev := data.NotificationEvent; ev != nil && !visible(ev.ConversationID, nil) {
+		return false
+	}
+	return true
+}
+
+// ShareRequest is the body of POST /api/conversation/<id>/shares.
+type ShareRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"` // "read" or "collaborate"
+}
+
+// handleConversationShares handles GET and POST /api/conversation/<id>/shares.
+func (s *Server) handleConversationShares(w http.ResponseWriter, r *http.Request) {
+	if !s.multiUser {
+		http.Error(w, "Multi-user mode is not enabled", http.StatusNotFound)
+		return
+	}
+	ctx := r.Context()
+	conversationID := r.PathValue("id")
+	conv, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		shares, err := s.db.ListConversationShares(ctx, conversationID)
+		if err != nil {
+			s.logger.Error("Failed to list shares", "conversationID", conversationID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if shares == nil {
+			shares = []generated.ConversationShare{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(shares)
+	case http.MethodPost:
+		if conv.ParentConversationID != nil {
+			http.Error(w, "Subagent conversations are shared with their parent", http.StatusBadRequest)
+			return
+		}
+		if conv.OwnerEmail == nil {
+			http.Error(w, "Conversations without an owner are visible to everyone", http.StatusBadRequest)
+			return
+		}
+		var req ShareRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		email := strings.ToLower(strings.TrimSpace(req.Email))
+		if !strings.Contains(email, "@") {
+			http.Error(w, "A valid email is required", http.StatusBadRequest)
+			return
+		}
+		if strings.EqualFold(email, *conv.OwnerEmail) {
+			http.Error(w, "The owner already has access", http.StatusBadRequest)
+			return
+		}
+		if req.Role != shareRoleRead && req.Role != shareRoleCollaborate {
+			http.Error(w, `role must be "read" or "collaborate"`, http.StatusBadRequest)
+			return
+		}
+		share, err := s.db.ShareConversation(ctx, conversationID, email, req.Role)
+		if err != nil {
+			s.logger.Error("Failed to share conversation", "conversationID", conversationID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(share)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleConversationShare handles DELETE /api/conversation/<id>/shares/<email>.
+func (s *Server) handleConversationShare(w http.ResponseWriter, r *http.Request) {
+	if !s.multiUser {
+		http.Error(w, "Multi-user mode is not enabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	email := strings.ToLower(r.PathValue("email"))
+	err := s.db.UnshareConversation(r.Context(), r.PathValue("id"), email)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Share not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to unshare conversation", "conversationID", r.PathValue("id"), "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ownerSettingFloat is settingFloat for a conversation, preferring its
+// owner's override of the setting.
+func (s *Server) ownerSettingFloat(ctx context.Context, owner *string, key string, def float64) float64 {
+	def = s.settingFloat(ctx, key, def)
+	if owner == nil {
+		return def
+	}
+	value, err := s.db.GetUserSetting(ctx, *owner, key)
+	if err != nil || value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		s.logger.Warn("Ignoring invalid user setting", "email", *owner, "key", key, "value", value)
+		return def
+	}
+	return f
+}
+
+// conversationSettingFloat is ownerSettingFloat for a conversation ID.
+func (s *Server) conversationSettingFloat(ctx context.Context, conversationID, key string, def float64) float64 {
+	var owner *string
+	if conv, err := s.db.GetConversationByID(ctx, conversationID); err == nil {
+		owner = conv.OwnerEmail
+	}
+	return s.ownerSettingFloat(ctx, owner, key, def)
+}
+
+// monthStart returns the start of the UTC calendar month containing t.
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// userBudget returns a user's monthly budget in USD, or 0 if they have none.
+func (s *Server) userBudget(ctx context.Context, email string) (float64, error) {
+	value, err := s.db.GetUserSetting(ctx, email, monthlyBudgetSetting)
+	if err != nil || value == "" {
+		return 0, err
+	}
+	budget, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s for %s: %q", monthlyBudgetSetting, email, value)
+	}
+	return budget, nil
+}
+
+// checkBudget returns errBudgetExceeded if owner has a monthly budget and
+// their conversations have spent it this month. It does nothing outside
+// multi-user mode or for ownerless conversations.
+func (s *Server) checkBudget(ctx context.Context, owner string) error {
+	if !s.multiUser || owner == "" {
+		return nil
+	}
+	budget, err := s.userBudget(ctx, owner)
+	if err != nil || budget <= 0 {
+		return err
+	}
+	spent, err := s.db.OwnerSpendSince(ctx, owner, monthStart(time.Now()))
+	if err != nil {
+		return err
+	}
+	if spent >= budget {
+		return fmt.Errorf("%w: %s has spent $%.2f of $%.2f this month", errBudgetExceeded, owner, spent, budget)
+	}
+	return nil
+}
+
+// BudgetAPI is a user's monthly budget and what they've spent of it.
+type BudgetAPI struct {
+	Email            string   `json:"email"`
+	MonthlyBudgetUSD *float64 `json:"monthly_budget_usd"`
+	SpentUSD         float64  `json:"spent_usd"`
+}
+
+func (s *Server) budgetFor(ctx context.Context, email, value string) (BudgetAPI, error) {
+	b := BudgetAPI{Email: email}
+	if value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			b.MonthlyBudgetUSD = &f
+		}
+	}
+	var err error
+	b.SpentUSD, err = s.db.OwnerSpendSince(ctx, email, monthStart(time.Now()))
+	return b, err
+}
+
+// handleBudgets handles GET /api/budgets. Admins see every user with a
+// budget; other users see only their own.
+func (s *Server) handleBudgets(w http.ResponseWriter, r *http.Request) {
+	if !s.multiUser {
+		http.Error(w, "Multi-user mode is not enabled", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	budgets, err := s.db.ListUserSettingsByKey(ctx, monthlyBudgetSetting)
+	if err != nil {
+		s.logger.Error("Failed to list budgets", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	emails := []string{s.viewer(r)}
+	if s.isAdmin(r) {
+		emails = emails[:0]
+		for email := range budgets {
+			emails = append(emails, email)
+		}
+		slices.Sort(emails)
+	}
+	result := []BudgetAPI{}
+	for _, email := range emails {
+		b, err := s.budgetFor(ctx, email, budgets[email])
+		if err != nil {
+			s.logger.Error("Failed to get spend", "email", email, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		result = append(result, b)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleBudget handles PUT and DELETE /api/budgets/<email>, for admins.
+func (s *Server) handleBudget(w http.ResponseWriter, r *http.Request) {
+	if !s.multiUser {
+		http.Error(w, "Multi-user mode is not enabled", http.StatusNotFound)
+		return
+	}
+	if !s.isAdmin(r) {
+		http.Error(w, "Only admins can set budgets", http.StatusForbidden)
+		return
+	}
+	ctx := r.Context()
+	email := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/api/budgets/"))
+	if !strings.Contains(email, "@") {
+		http.Error(w, "A valid email is required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var req struct {
+			MonthlyBudgetUSD float64 `json:"monthly_budget_usd"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		if req.MonthlyBudgetUSD <= 0 {
+			http.Error(w, "monthly_budget_usd must be positive; DELETE removes a budget", http.StatusBadRequest)
+			return
+		}
+		value := strconv.FormatFloat(req.MonthlyBudgetUSD, 'f', -1, 64)
+		if err := s.db.SetUserSetting(ctx, email, monthlyBudgetSetting, value); err != nil {
+			s.logger.Error("Failed to set budget", "email", email, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		b, err := s.budgetFor(ctx, email, value)
+		if err != nil {
+			s.logger.Error("Failed to get spend", "email", email, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(b)
+	case http.MethodDelete:
+		if err := s.db.SetUserSetting(ctx, email, monthlyBudgetSetting, ""); err != nil {
+			s.logger.Error("Failed to delete budget", "email", email, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+)
+
+func TestMultiUser(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := t.Context()
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+	h.server.EnableMultiUser([]string{"Admin@example.com"})
+	handler := h.server.RequireIdentityMiddleware(mux)
+
+	do := func(method, path, body, email string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if email != "" {
+			req.Header.Set("X-ExeDev-Email", email)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+	list := func(email, path string) []string {
+		t.Helper()
+		w := do("GET", path, "", email)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s as %s: %d %s", path, email, w.Code, w.Body.String())
+		}
+		var convs []generated.Conversation
+		json.Unmarshal(w.Body.Bytes(), &convs)
+		var ids []string
+		for _, c := range convs {
+			ids = append(ids, c.ConversationID)
+		}
+		return ids
+	}
+	newConversation := func(email string) string {
+		t.Helper()
+		w := do("POST", "/api/conversations/new", `{"message":"echo: hi","model":"predictable"}`, email)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("new conversation as %s: %d %s", email, w.Code, w.Body.String())
+		}
+		var resp struct {
+			ConversationID string `json:"conversation_id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		waitForIdle(t, h.server, resp.ConversationID)
+		return resp.ConversationID
+	}
+
+	if w := do("GET", "/api/conversations", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("without an identity: got %d, want 401", w.Code)
+	}
+
+	adas := newConversation("Ada@example.com")
+	bobs := newConversation("bob@example.com")
+	conv, err := h.db.GetConversationByID(ctx, adas)
+	if err != nil || conv.OwnerEmail == nil || *conv.OwnerEmail != "ada@example.com" {
+		t.Fatalf("conversation = %+v, %v; want it owned by ada", conv, err)
+	}
+	// Conversations without an owner, e.g. from the Unix socket, are shared by everyone.
+	h.NewConversation("echo: local", "")
+	h.WaitResponse()
+	waitForIdle(t, h.server, h.convID)
+	local := h.convID
+
+	contains := func(ids []string, id string) bool {
+		for _, x := range ids {
+			if x == id {
+				return true
+			}
+		}
+		return false
+	}
+	ids := list("ada@example.com", "/api/conversations")
+	if !contains(ids, adas) || contains(ids, bobs) || !contains(ids, local) {
+		t.Errorf("ada's list = %v; want %s and %s but not %s", ids, adas, local, bobs)
+	}
+	if ids := list("ada@example.com", "/api/conversations?q=echo&search_content=true"); contains(ids, bobs) || !contains(ids, adas) {
+		t.Errorf("ada's search = %v", ids)
+	}
+	if w := do("GET", "/api/conversation/"+bobs, "", "ada@example.com"); w.Code != http.StatusNotFound {
+		t.Errorf("reading someone else's conversation: got %d, want 404", w.Code)
+	}
+
+	// Read-only sharing lets ada read but not chat; collaborate lets her chat.
+	if w := do("POST", "/api/conversation/"+bobs+"/shares", `{"email":"bob@example.com","role":"read"}`, "ada@example.com"); w.Code != http.StatusNotFound {
+		t.Errorf("sharing someone else's conversation: got %d, want 404", w.Code)
+	}
+	if w := do("POST", "/api/conversation/"+bobs+"/shares", `{"email":"ADA@example.com","role":"read"}`, "bob@example.com"); w.Code != http.StatusOK {
+		t.Fatalf("share: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", "/api/conversation/"+bobs, "", "ada@example.com"); w.Code != http.StatusOK {
+		t.Errorf("reading a shared conversation: got %d", w.Code)
+	}
+	if ids := list("ada@example.com", "/api/conversations"); !contains(ids, bobs) {
+		t.Errorf("ada's list = %v; want the shared %s", ids, bobs)
+	}
+	chat := `{"message":"echo: from ada","model":"predictable"}`
+	if w := do("POST", "/api/conversation/"+bobs+"/chat", chat, "ada@example.com"); w.Code != http.StatusForbidden {
+		t.Errorf("chat with read access: got %d, want 403", w.Code)
+	}
+	do("POST", "/api/conversation/"+bobs+"/shares", `{"email":"ada@example.com","role":"collaborate"}`, "bob@example.com")
+	if w := do("POST", "/api/conversation/"+bobs+"/chat", chat, "ada@example.com"); w.Code != http.StatusAccepted {
+		t.Errorf("chat with collaborate access: got %d", w.Code)
+	}
+	waitForIdle(t, h.server, bobs)
+	if w := do("POST", "/api/conversation/"+bobs+"/delete", "", "ada@example.com"); w.Code != http.StatusForbidden {
+		t.Errorf("delete as collaborator: got %d, want 403", w.Code)
+	}
+
+	// Stream updates about conversations ada can't see are dropped.
+	visible := h.server.conversationVisibility(ctx, "ada@example.com")
+	carol := newConversation("carol@example.com")
+	if streamVisible(StreamResponse{ConversationState: &ConversationState{ConversationID: carol}}, visible) {
+		t.Error("ada's stream would show carol's conversation state")
+	}
+	if !streamVisible(StreamResponse{ConversationState: &ConversationState{ConversationID: bobs}}, visible) {
+		t.Error("ada's stream would hide the conversation shared with her")
+	}
+
+	if w := do("DELETE", "/api/conversation/"+bobs+"/shares/ada@example.com", "", "bob@example.com"); w.Code != http.StatusNoContent {
+		t.Fatalf("unshare: %d", w.Code)
+	}
+	if w := do("GET", "/api/conversation/"+bobs, "", "ada@example.com"); w.Code != http.StatusNotFound {
+		t.Errorf("after unsharing: got %d, want 404", w.Code)
+	}
+
+	// Per-user settings override server-wide ones; only admins change the latter.
+	if w := do("POST", "/settings", `{"key":"notify_idle_minutes","value":"5"}`, "ada@example.com"); w.Code != http.StatusOK {
+		t.Fatalf("user setting: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("POST", "/settings", `{"key":"notify_idle_minutes","value":"60","scope":"server"}`, "ada@example.com"); w.Code != http.StatusForbidden {
+		t.Errorf("server setting as a user: got %d, want 403", w.Code)
+	}
+	if w := do("POST", "/settings", `{"key":"notify_idle_minutes","value":"60","scope":"server"}`, "admin@example.com"); w.Code != http.StatusOK {
+		t.Errorf("server setting as an admin: got %d", w.Code)
+	}
+	settings := func(email string) map[string]string {
+		var m map[string]string
+		json.Unmarshal(do("GET", "/settings", "", email).Body.Bytes(), &m)
+		return m
+	}
+	if got := settings("ada@example.com")["notify_idle_minutes"]; got != "5" {
+		t.Errorf("ada's notify_idle_minutes = %q, want 5", got)
+	}
+	if got := settings("bob@example.com")["notify_idle_minutes"]; got != "60" {
+		t.Errorf("bob's notify_idle_minutes = %q, want 60", got)
+	}
+	if got := h.server.conversationSettingFloat(ctx, adas, notifyIdleSetting, defaultIdleMinutes); got != 5 {
+		t.Errorf("idle minutes for ada's conversation = %v, want 5", got)
+	}
+
+	// Schedules, triggers and notification channels are server-wide, so only
+	// admins configure them; anyone with a trigger's secret may invoke it.
+	for _, path := range []string{"/api/schedules", "/api/triggers", "/api/notification-channels", "/api/notification-deliveries", "/debug/llm_requests/api"} {
+		if w := do("GET", path, "", "ada@example.com"); w.Code != http.StatusForbidden {
+			t.Errorf("GET %s as a user: got %d, want 403", path, w.Code)
+		}
+		if w := do("GET", path, "", "admin@example.com"); w.Code != http.StatusOK {
+			t.Errorf("GET %s as an admin: got %d", path, w.Code)
+		}
+	}
+	for _, path := range []string{"/exit", "/upgrade"} {
+		if w := do("POST", path, "", "ada@example.com"); w.Code != http.StatusForbidden {
+			t.Errorf("POST %s as a user: got %d, want 403", path, w.Code)
+		}
+	}
+	trigger := `{"name":"ci","prompt_template":"echo: {{.Body}}","mode":"new"}`
+	if w := do("POST", "/api/triggers", trigger, "ada@example.com"); w.Code != http.StatusForbidden {
+		t.Errorf("creating a trigger as a user: got %d, want 403", w.Code)
+	}
+	w := do("POST", "/api/triggers", trigger, "admin@example.com")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create trigger: %d %s", w.Code, w.Body.String())
+	}
+	var created generated.Trigger
+	json.Unmarshal(w.Body.Bytes(), &created)
+	for _, method := range []string{"GET", "PUT", "DELETE"} {
+		if w := do(method, "/api/triggers/"+created.TriggerID, trigger, "ada@example.com"); w.Code != http.StatusForbidden {
+			t.Errorf("%s trigger as a user: got %d, want 403", method, w.Code)
+		}
+	}
+	if w := do("POST", "/api/triggers/"+created.TriggerID+"/rotate-secret", "", "ada@example.com"); w.Code != http.StatusForbidden {
+		t.Errorf("rotating a trigger secret as a user: got %d, want 403", w.Code)
+	}
+	req := httptest.NewRequest("POST", "/api/triggers/"+created.TriggerID, strings.NewReader("hi"))
+	req.Header.Set("Authorization", "Bearer "+created.Secret)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("invoking a trigger: got %d %s", w.Code, w.Body.String())
+	}
+	var run struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &run)
+	waitForIdle(t, h.server, run.ConversationID)
+
+	// Budgets are set by admins and stop the owner's conversations once spent.
+	if w := do("PUT", "/api/budgets/ada@example.com", `{"monthly_budget_usd":1}`, "ada@example.com"); w.Code != http.StatusForbidden {
+		t.Errorf("setting a budget as a user: got %d, want 403", w.Code)
+	}
+	if w := do("PUT", "/api/budgets/ada@example.com", `{"monthly_budget_usd":1}`, "admin@example.com"); w.Code != http.StatusOK {
+		t.Fatalf("set budget: %d %s", w.Code, w.Body.String())
+	}
+	reply := llm.Message{Role: llm.MessageRoleAssistant, Content: []llm.Content{llm.StringContent("ok")}}
+	if err := h.server.recordMessage(ctx, adas, reply, llm.Usage{CostUSD: 1.5}); err != nil {
+		t.Fatal(err)
+	}
+	var budgets []BudgetAPI
+	json.Unmarshal(do("GET", "/api/budgets", "", "ada@example.com").Body.Bytes(), &budgets)
+	if len(budgets) != 1 || budgets[0].MonthlyBudgetUSD == nil || *budgets[0].MonthlyBudgetUSD != 1 || budgets[0].SpentUSD < 1.5 {
+		t.Errorf("ada's budgets = %+v", budgets)
+	}
+	if w := do("POST", "/api/conversation/"+adas+"/chat", chat, "ada@example.com"); w.Code != http.StatusPaymentRequired {
+		t.Errorf("chat over budget: got %d, want 402", w.Code)
+	}
+	if w := do("POST", "/api/conversations/new", chat, "ada@example.com"); w.Code != http.StatusPaymentRequired {
+		t.Errorf("new conversation over budget: got %d, want 402", w.Code)
+	}
+	if w := do("POST", "/api/conversation/"+bobs+"/chat", `{"message":"echo: bob","model":"predictable"}`, "bob@example.com"); w.Code != http.StatusAccepted {
+		t.Errorf("chat within budget: got %d", w.Code)
+	}
+	waitForIdle(t, h.server, bobs)
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -77,6 +78,32 @@ var channelTypeInfo = map[string]ChannelTypeInfo{
 			{Name: "password", Label: "Password", Type: "password", Description: "Optional. For private topics, use with username."},
 			{Name: "done_priority", Label: "Done Priority", Type: "string", Required: true, Default: "default", Options: []string{"min", "low", "default", "high", "max"}},
 			{Name: "error_priority", Label: "Error Priority", Type: "string", Required: true, Default: "high", Options: []string{"min", "low", "default", "high", "max"}},
+			{Name: "reply_actions", Label: "Reply Buttons", Type: "text", Placeholder: "Continue\nCommit: Yes, go ahead and commit", Description: "Optional. Up to two buttons on agent_done and agent_error, one per line as \"Label\" or \"Label: message\"; tapping one sends the message to the conversation. Requires Allow Replies and the notify_public_url setting."},
+		},
+	},
+	"smtp": {
+		Type:  "smtp",
+		Label: "Email (SMTP)",
+		ConfigFields: []ConfigField{
+			{Name: "host", Label: "SMTP Host", Type: "string", Required: true, Placeholder: "smtp.example.com"},
+			{Name: "port", Label: "Port", Type: "string", Placeholder: "587", Description: "Optional. Defaults to 587 for starttls, 465 for tls and 25 for none."},
+			{Name: "security", Label: "Security", Type: "string", Required: true, Default: "starttls", Options: []string{"starttls", "tls", "none"}},
+			{Name: "username", Label: "Username", Type: "string", Description: "Optional. Leave empty if the server does not require authentication."},
+			{Name: "password", Label: "Password", Type: "password"},
+			{Name: "from", Label: "From", Type: "string", Required: true, Placeholder: "Shelley <shelley@example.com>"},
+			{Name: "to", Label: "To", Type: "string", Required: true, Placeholder: "you@example.com, team@example.com", Description: "Comma-separated list of recipients."},
+		},
+	},
+	"webhook": {
+		Type:  "webhook",
+		Label: "Webhook",
+		ConfigFields: []ConfigField{
+			{Name: "url", Label: "URL", Type: "string", Required: true, Placeholder: "https://example.com/hooks/shelley"},
+			{Name: "secret", Label: "Signing Secret", Type: "password", Description: "Optional. When set, the body is signed with HMAC-SHA256 and sent as \"sha256=<hex>\" in the signature header."},
+			{Name: "signature_header", Label: "Signature Header", Type: "string", Default: "X-Shelley-Signature-256"},
+			{Name: "headers", Label: "Headers", Type: "text", Placeholder: "Authorization: Bearer ...", Description: "Optional. One \"Name: value\" per line."},
+			{Name: "content_type", Label: "Content Type", Type: "string", Default: "application/json"},
+			{Name: "template", Label: "Body Template", Type: "text", Placeholder: `{"text": {{json .Title}}}`, Description: "Optional Go text/template. Fields: .Type, .ConversationID, .Timestamp, .Payload, .Title, .Message, .URL, .ReplyURL; the json function quotes a value. Defaults to the event as JSON."},
 		},
 	},
 }
@@ -162,7 +189,7 @@ func (s *Server) handleCreateNotificationChannel(w http.ResponseWriter, r *http.
 			validationConfig[k] = v
 		}
 	}
-	if _, err := notifications.CreateFromConfig(validationConfig, s.logger); err != nil {
+	if _, err := s.newNotificationChannel("", validationConfig); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -199,6 +226,15 @@ func (s *Server) handleNotificationChannel(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	if path == "test" {
+		if r.Method == http.MethodPost {
+			s.handleTestNotificationChannelConfig(w, r)
+		} else {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
 	if strings.HasSuffix(path, "/test") {
 		channelID := strings.TrimSuffix(path, "/test")
 		if r.Method == http.MethodPost {
@@ -265,7 +301,7 @@ func (s *Server) handleUpdateNotificationChannel(w http.ResponseWriter, r *http.
 			validationConfig[k] = v
 		}
 	}
-	if _, err := notifications.CreateFromConfig(validationConfig, s.logger); err != nil {
+	if _, err := s.newNotificationChannel("", validationConfig); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -319,6 +355,37 @@ func (s *Server) handleTestNotificationChannel(w http.ResponseWriter, r *http.Re
 		}
 	}
 
+	s.sendTestNotification(w, r, config)
+}
+
+// handleTestNotificationChannelConfig handles POST /api/notification-channels/test.
+// It sends a test notification through an unsaved channel configuration so the
+// settings can be checked before they are stored.
+func (s *Server) handleTestNotificationChannelConfig(w http.ResponseWriter, r *http.Request) {
+	var req CreateNotificationChannelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.ChannelType == "" {
+		http.Error(w, "channel_type is required", http.StatusBadRequest)
+		return
+	}
+
+	config := map[string]any{}
+	if m, ok := req.Config.(map[string]any); ok {
+		for k, v := range m {
+			config[k] = v
+		}
+	}
+	config["type"] = req.ChannelType
+
+	s.sendTestNotification(w, r, config)
+}
+
+// sendTestNotification creates a channel from config, sends it a sample
+// event, and reports the outcome as {"success", "message"}.
+func (s *Server) sendTestNotification(w http.ResponseWriter, r *http.Request, config map[string]any) {
 	ch, err := notifications.CreateFromConfig(config, s.logger)
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
@@ -368,20 +435,67 @@ func (s *Server) handleNotificationChannelTypes(w http.ResponseWriter, r *http.R
 	json.NewEncoder(w).Encode(s.getNotificationChannelTypes())
 }
 
+// notificationFilterFields describes the event filter keys every channel
+// type accepts (see notifications.ParseFilter).
+func notificationFilterFields() []ConfigField {
+	events := make([]string, len(notifications.AllEventTypes))
+	for i, e := range notifications.AllEventTypes {
+		events[i] = string(e)
+	}
+	defaults := make([]string, len(notifications.DefaultEventTypes))
+	for i, e := range notifications.DefaultEventTypes {
+		defaults[i] = string(e)
+	}
+	return []ConfigField{
+		{
+			Name: "events", Label: "Events", Type: "string", Placeholder: strings.Join(defaults, ", "),
+			Description: "Optional. Comma-separated events to send, or \"all\". Available: " + strings.Join(events, ", ") + ".",
+		},
+		{
+			Name: "cwd_globs", Label: "Working Directories", Type: "string", Placeholder: "/home/me/src/*",
+			Description: "Optional. Comma-separated globs; only conversations in a matching directory (or below it) notify.",
+		},
+		{
+			Name: "tags", Label: "Tags", Type: "string", Placeholder: "prod, nightly",
+			Description: "Optional. Comma-separated tags; only conversations with one of them notify.",
+		},
+		{
+			Name: "allow_replies", Label: "Allow Replies", Type: "string", Default: "no", Options: []string{"no", "yes"},
+			Description: "Whether agent_done and agent_error carry a link that replies to the conversation. Anyone who can read this channel can use it to message the agent, so only allow it on private channels. Requires the notify_public_url setting.",
+		},
+	}
+}
+
 // getNotificationChannelTypes returns channel type metadata for the frontend.
 func (s *Server) getNotificationChannelTypes() []ChannelTypeInfo {
 	types := notifications.RegisteredTypes()
 	result := make([]ChannelTypeInfo, 0, len(types))
 	for _, t := range types {
-		if info, ok := channelTypeInfo[t]; ok {
-			result = append(result, info)
-		} else {
-			result = append(result, ChannelTypeInfo{Type: t, Label: t})
+		info, ok := channelTypeInfo[t]
+		if !ok {
+			info = ChannelTypeInfo{Type: t, Label: t}
 		}
+		info.ConfigFields = append(slices.Clip(info.ConfigFields), notificationFilterFields()...)
+		result = append(result, info)
 	}
 	return result
 }
 
+// newNotificationChannel creates the stored channel with the given ID (empty
+// when only validating) from its config, wrapped with the event filter the
+// config describes.
+func (s *Server) newNotificationChannel(channelID string, config map[string]any) (notifications.Channel, error) {
+	ch, err := notifications.CreateFromConfig(config, s.logger)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := notifications.ParseFilter(config)
+	if err != nil {
+		return nil, err
+	}
+	return notifications.Configured(channelID, ch, filter), nil
+}
+
 // ReloadNotificationChannels reads enabled channels from DB and replaces the dispatcher's channel set.
 func (s *Server) ReloadNotificationChannels() {
 	channels, err := s.db.GetEnabledNotificationChannels(context.Background())
@@ -399,7 +513,7 @@ func (s *Server) ReloadNotificationChannels() {
 				config[k] = v
 			}
 		}
-		ch, err := notifications.CreateFromConfig(config, s.logger)
+		ch, err := s.newNotificationChannel(dbCh.ChannelID, config)
 		if err != nil {
 			s.logger.Warn("Failed to create notification channel", "id", dbCh.ChannelID, "error", err)
 			continue
@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	_ "shelley.exe.dev/server/notifications/channels" // register channel types
+)
+
+func TestTestNotificationChannelConfig(t *testing.T) {
+	h := NewTestHarness(t)
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+
+	received := make(chan struct{}, 1)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+	}))
+	defer hook.Close()
+
+	test := func(body string) (success bool, message string) {
+		t.Helper()
+		req := httptest.NewRequest("POST", "/api/notification-channels/test", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("test: %d %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Success bool   `json:"success"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Success, resp.Message
+	}
+
+	if ok, msg := test(`{"channel_type":"webhook","display_name":"hook","config":{"url":"` + hook.URL + `"}}`); !ok {
+		t.Fatalf("webhook test failed: %s", msg)
+	}
+	<-received
+
+	if ok, _ := test(`{"channel_type":"smtp","display_name":"mail","config":{"host":"mail.example.com"}}`); ok {
+		t.Error("smtp test with incomplete config succeeded")
+	}
+
+	// Nothing was saved.
+	if channels, err := h.db.GetNotificationChannels(t.Context()); err != nil || len(channels) != 0 {
+		t.Errorf("channels = %v, %v; want none", channels, err)
+	}
+}
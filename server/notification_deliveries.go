@@ -0,0 +1,341 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/server/notifications"
+)
+
+const (
+	// deliveryPollInterval is how often the outbox is checked for due
+	// retries; new deliveries wake the worker immediately.
+	deliveryPollInterval = 5 * time.Second
+	// deliveryTimeout bounds a single delivery attempt.
+	deliveryTimeout = 30 * time.Second
+	// deliveryMaxAttempts is how many times a delivery is tried before it is marked failed.
+	deliveryMaxAttempts = 8
+	// Retries back off exponentially from deliveryBaseBackoff up to deliveryMaxBackoff.
+	deliveryBaseBackoff = 30 * time.Second
+	deliveryMaxBackoff  = time.Hour
+	// deliveryRateLimit is the most deliveries sent to one channel per minute.
+	// Anything over the limit waits in the outbox.
+	deliveryRateLimit = 20
+	// deliveryLogSize is how many finished deliveries are kept for the log.
+	deliveryLogSize = 1000
+	// deliveryBatchSize is how many pending deliveries are read per pass.
+	deliveryBatchSize = 500
+)
+
+// deliveryWorker tracks the state of the background delivery of queued
+// notifications (see processDeliveries).
+type deliveryWorker struct {
+	wake chan struct{} // nudges deliveryRoutine when a delivery is queued
+
+	mu     sync.Mutex
+	busy   map[string]bool        // channel ID -> a goroutine is delivering to it
+	recent map[string][]time.Time // channel ID -> send times within the last minute
+}
+
+func newDeliveryWorker() *deliveryWorker {
+	return &deliveryWorker{
+		wake:   make(chan struct{}, 1),
+		busy:   make(map[string]bool),
+		recent: make(map[string][]time.Time),
+	}
+}
+
+// notificationOutbox stores dispatched events in the notification_deliveries
+// table for the delivery worker.
+type notificationOutbox struct {
+	s *Server
+}
+
+func (o notificationOutbox) Enqueue(ctx context.Context, channelID string, event notifications.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+	var conversationID *string
+	if event.ConversationID != "" {
+		conversationID = &event.ConversationID
+	}
+	_, err = o.s.db.CreateNotificationDelivery(context.WithoutCancel(ctx), generated.CreateNotificationDeliveryParams{
+		DeliveryID:     "delivery-" + uuid.New().String()[:8],
+		ChannelID:      channelID,
+		EventType:      string(event.Type),
+		ConversationID: conversationID,
+		Event:          string(data),
+		NextAttemptAt:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	select {
+	case o.s.deliveries.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// deliveryBackoff returns how long to wait before retrying after the given
+// number of failed attempts.
+func deliveryBackoff(attempts int64) time.Duration {
+	backoff := deliveryBaseBackoff
+	for i := int64(1); i < attempts; i++ {
+		backoff *= 2
+		if backoff >= deliveryMaxBackoff {
+			return deliveryMaxBackoff
+		}
+	}
+	return backoff
+}
+
+// deliveryRoutine delivers queued notifications until the server shuts down.
+func (s *Server) deliveryRoutine() {
+	ticker := time.NewTicker(deliveryPollInterval)
+	defer ticker.Stop()
+	lastPrune := time.Now()
+	for {
+		go s.processDeliveries(context.Background(), time.Now())
+		if time.Since(lastPrune) > time.Hour {
+			if err := s.db.PruneNotificationDeliveries(context.Background(), deliveryLogSize); err != nil {
+				s.logger.Warn("Failed to prune notification deliveries", "error", err)
+			}
+			if err := s.db.PruneNotificationReplyTokens(context.Background(), time.Now()); err != nil {
+				s.logger.Warn("Failed to prune notification reply tokens", "error", err)
+			}
+			lastPrune = time.Now()
+		}
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+		case <-s.deliveries.wake:
+		}
+	}
+}
+
+// processDeliveries sends every pending delivery that is due at now. Each
+// channel is served by its own goroutine, one delivery at a time and at most
+// deliveryRateLimit per minute, so a slow or failing channel only delays
+// itself. It returns once this pass's deliveries have been attempted;
+// channels already being served by an earlier pass are skipped.
+func (s *Server) processDeliveries(ctx context.Context, now time.Time) {
+	pending, err := s.db.ListPendingNotificationDeliveries(ctx, deliveryBatchSize)
+	if err != nil {
+		s.logger.Error("Failed to list pending notification deliveries", "error", err)
+		return
+	}
+
+	due := map[string][]generated.NotificationDelivery{}
+	var order []string
+	for _, d := range pending {
+		if d.NextAttemptAt.After(now) {
+			continue
+		}
+		if _, ok := due[d.ChannelID]; !ok {
+			order = append(order, d.ChannelID)
+		}
+		due[d.ChannelID] = append(due[d.ChannelID], d)
+	}
+
+	var wg sync.WaitGroup
+	for _, channelID := range order {
+		s.deliveries.mu.Lock()
+		busy := s.deliveries.busy[channelID]
+		s.deliveries.busy[channelID] = true
+		s.deliveries.mu.Unlock()
+		if busy {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				s.deliveries.mu.Lock()
+				delete(s.deliveries.busy, channelID)
+				s.deliveries.mu.Unlock()
+			}()
+			s.deliverToChannel(ctx, channelID, due[channelID])
+		}()
+	}
+	wg.Wait()
+}
+
+// deliverToChannel attempts the given deliveries to one channel in order.
+func (s *Server) deliverToChannel(ctx context.Context, channelID string, deliveries []generated.NotificationDelivery) {
+	ch := s.notifDispatcher.Channel(channelID)
+	for _, d := range deliveries {
+		if ch == nil {
+			s.recordDeliveryResult(ctx, d, errors.New("channel was deleted or disabled"), false)
+			continue
+		}
+		if !s.takeDeliverySlot(channelID) {
+			return // over the rate limit; the rest stay queued
+		}
+
+		var event notifications.Event
+		if err := json.Unmarshal([]byte(d.Event), &event); err != nil {
+			s.recordDeliveryResult(ctx, d, fmt.Errorf("invalid queued event: %w", err), false)
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+		err := ch.Send(sendCtx, event)
+		cancel()
+		s.recordDeliveryResult(ctx, d, err, true)
+	}
+}
+
+// takeDeliverySlot reports whether another delivery may be sent to the
+// channel within the rate limit, and if so counts it.
+func (s *Server) takeDeliverySlot(channelID string) bool {
+	s.deliveries.mu.Lock()
+	defer s.deliveries.mu.Unlock()
+	cutoff := time.Now().Add(-time.Minute)
+	recent := s.deliveries.recent[channelID]
+	for len(recent) > 0 && recent[0].Before(cutoff) {
+		recent = recent[1:]
+	}
+	if len(recent) >= deliveryRateLimit {
+		s.deliveries.recent[channelID] = recent
+		return false
+	}
+	s.deliveries.recent[channelID] = append(recent, time.Now())
+	return true
+}
+
+// recordDeliveryResult stores the outcome of a delivery attempt. Failures
+// are retried with backoff if retry is set and attempts remain.
+func (s *Server) recordDeliveryResult(ctx context.Context, d generated.NotificationDelivery, sendErr error, retry bool) {
+	var err error
+	if sendErr == nil {
+		err = s.db.MarkNotificationDelivered(ctx, d.DeliveryID, time.Now())
+	} else {
+		attempts := d.Attempts + 1
+		var retryAt time.Time
+		if retry && attempts < deliveryMaxAttempts {
+			retryAt = time.Now().Add(deliveryBackoff(attempts))
+		}
+		s.logger.Warn("Notification delivery failed",
+			"deliveryID", d.DeliveryID,
+			"channelID", d.ChannelID,
+			"event", d.EventType,
+			"attempts", attempts,
+			"willRetry", !retryAt.IsZero(),
+			"error", sendErr)
+		err = s.db.MarkNotificationDeliveryFailed(ctx, d.DeliveryID, sendErr.Error(), retryAt)
+	}
+	if err != nil {
+		s.logger.Error("Failed to record notification delivery", "deliveryID", d.DeliveryID, "error", err)
+	}
+}
+
+// NotificationDeliveryAPI is a delivery as returned by /api/notification-deliveries.
+type NotificationDeliveryAPI struct {
+	generated.NotificationDelivery
+	// Event is the queued event as JSON rather than a string.
+	Event json.RawMessage `json:"event"`
+	// ChannelName is the channel's display name, if it still exists.
+	ChannelName string `json:"channel_name,omitempty"`
+}
+
+// handleNotificationDeliveries handles GET /api/notification-deliveries,
+// the delivery log, newest first. Query parameters: status (pending,
+// delivered or failed), channel_id, and limit (default 100, at most 1000).
+func (s *Server) handleNotificationDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	status := q.Get("status")
+	switch status {
+	case "", "pending", "delivered", "failed":
+	default:
+		http.Error(w, "status must be pending, delivered or failed", http.StatusBadRequest)
+		return
+	}
+	limit := int64(100)
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+		if limit > deliveryLogSize {
+			limit = deliveryLogSize
+		}
+	}
+
+	deliveries, err := s.db.ListNotificationDeliveries(r.Context(), status, q.Get("channel_id"), limit)
+	if err != nil {
+		s.logger.Error("Failed to list notification deliveries", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	names := map[string]string{}
+	if channels, err := s.db.GetNotificationChannels(r.Context()); err == nil {
+		for _, ch := range channels {
+			names[ch.ChannelID] = ch.DisplayName
+		}
+	}
+
+	result := make([]NotificationDeliveryAPI, len(deliveries))
+	for i, d := range deliveries {
+		result[i] = NotificationDeliveryAPI{
+			NotificationDelivery: d,
+			Event:                json.RawMessage(d.Event),
+			ChannelName:          names[d.ChannelID],
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleNotificationDelivery handles POST /api/notification-deliveries/<id>/retry,
+// which queues a delivery again right away.
+func (s *Server) handleNotificationDelivery(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/notification-deliveries/")
+	deliveryID, action, _ := strings.Cut(path, "/")
+	if deliveryID == "" || action != "retry" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	delivery, err := s.db.RetryNotificationDelivery(r.Context(), deliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Delivery not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to retry notification delivery", "deliveryID", deliveryID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	select {
+	case s.deliveries.wake <- struct{}{}:
+	default:
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(NotificationDeliveryAPI{
+		NotificationDelivery: *delivery,
+		Event:                json.RawMessage(delivery.Event),
+	})
+}
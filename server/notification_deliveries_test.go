@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/server/notifications"
+)
+
+// flakyChannel fails while fail is set and records what it delivered.
+type flakyChannel struct {
+	mu        sync.Mutex
+	fail      bool
+	delivered []notifications.Event
+}
+
+func (c *flakyChannel) Name() string { return "flaky" }
+
+func (c *flakyChannel) Send(ctx context.Context, event notifications.Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail {
+		return errors.New("connection refused")
+	}
+	c.delivered = append(c.delivered, event)
+	return nil
+}
+
+func (c *flakyChannel) setFail(fail bool) {
+	c.mu.Lock()
+	c.fail = fail
+	c.mu.Unlock()
+}
+
+func (c *flakyChannel) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.delivered)
+}
+
+func TestNotificationDeliveries(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+
+	ch := &flakyChannel{fail: true}
+	h.server.notifDispatcher.Register(notifications.Configured("channel-1", ch, notifications.Filter{}))
+
+	h.server.notifDispatcher.Dispatch(ctx, notifications.Event{
+		Type:           notifications.EventAgentDone,
+		ConversationID: "conv-1",
+		Timestamp:      time.Now(),
+		Payload:        notifications.AgentDonePayload{ConversationTitle: "fix-tests", FinalResponse: "All green"},
+	})
+	pending, err := h.db.ListPendingNotificationDeliveries(ctx, 10)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("pending = %v, %v; want one delivery", pending, err)
+	}
+	id := pending[0].DeliveryID
+
+	// A failed attempt is retried later with backoff.
+	h.server.processDeliveries(ctx, time.Now())
+	d, err := h.db.GetNotificationDelivery(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Status != "pending" || d.Attempts != 1 || d.LastError == nil || *d.LastError != "connection refused" {
+		t.Errorf("after failure: status=%s attempts=%d last_error=%v", d.Status, d.Attempts, d.LastError)
+	}
+	if wait := time.Until(d.NextAttemptAt); wait < deliveryBaseBackoff-time.Second {
+		t.Errorf("next attempt in %v, want about %v", wait, deliveryBaseBackoff)
+	}
+
+	// Nothing is sent before the retry is due.
+	ch.setFail(false)
+	h.server.processDeliveries(ctx, time.Now())
+	if ch.count() != 0 {
+		t.Fatal("delivery retried before it was due")
+	}
+	h.server.processDeliveries(ctx, d.NextAttemptAt.Add(time.Second))
+	if ch.count() != 1 {
+		t.Fatalf("delivered %d events, want 1", ch.count())
+	}
+	got := ch.delivered[0]
+	if p, ok := got.Payload.(notifications.AgentDonePayload); !ok || p.FinalResponse != "All green" {
+		t.Errorf("delivered payload = %#v", got.Payload)
+	}
+	if d, _ = h.db.GetNotificationDelivery(ctx, id); d.Status != "delivered" || d.DeliveredAt == nil {
+		t.Errorf("after success: status=%s delivered_at=%v", d.Status, d.DeliveredAt)
+	}
+
+	// Deliveries to a channel that no longer exists fail without retrying.
+	h.server.notifDispatcher.ReplaceChannels([]notifications.Channel{
+		notifications.Configured("channel-2", &flakyChannel{}, notifications.Filter{}),
+	})
+	h.server.notifDispatcher.Dispatch(ctx, notifications.Event{Type: notifications.EventAgentDone, Timestamp: time.Now()})
+	h.server.notifDispatcher.ReplaceChannels(nil)
+	h.server.processDeliveries(ctx, time.Now())
+
+	list := func(query string) []NotificationDeliveryAPI {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/notification-deliveries"+query, nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("list%s: %d %s", query, w.Code, w.Body.String())
+		}
+		var result []NotificationDeliveryAPI
+		if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	if all := list(""); len(all) != 2 {
+		t.Fatalf("log has %d deliveries, want 2", len(all))
+	}
+	failed := list("?status=failed")
+	if len(failed) != 1 || failed[0].ChannelID != "channel-2" || failed[0].Attempts != 1 {
+		t.Fatalf("failed deliveries = %+v", failed)
+	}
+	if n := len(list("?channel_id=channel-1&status=delivered")); n != 1 {
+		t.Errorf("channel-1 delivered = %d, want 1", n)
+	}
+
+	// Retrying a failed delivery queues it again.
+	retry := func(id string) int {
+		req := httptest.NewRequest("POST", "/api/notification-deliveries/"+id+"/retry", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := retry("delivery-missing"); code != http.StatusNotFound {
+		t.Errorf("retry missing delivery: %d, want 404", code)
+	}
+	if code := retry(failed[0].DeliveryID); code != http.StatusOK {
+		t.Fatalf("retry: %d", code)
+	}
+	if d, _ := h.db.GetNotificationDelivery(ctx, failed[0].DeliveryID); d.Status != "pending" {
+		t.Errorf("retried delivery status = %s, want pending", d.Status)
+	}
+}
+
+func TestNotificationDeliveryLimits(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+
+	ch := &flakyChannel{fail: true}
+	h.server.notifDispatcher.Register(notifications.Configured("channel-1", ch, notifications.Filter{}))
+	h.server.notifDispatcher.Dispatch(ctx, notifications.Event{Type: notifications.EventAgentDone, Timestamp: time.Now()})
+
+	// A delivery that keeps failing is given up after deliveryMaxAttempts.
+	now := time.Now()
+	for range deliveryMaxAttempts {
+		h.server.processDeliveries(ctx, now)
+		now = now.Add(deliveryMaxBackoff + time.Second)
+	}
+	failed, err := h.db.ListNotificationDeliveries(ctx, "failed", "", 10)
+	if err != nil || len(failed) != 1 || failed[0].Attempts != deliveryMaxAttempts {
+		t.Fatalf("failed = %+v, %v; want one delivery after %d attempts", failed, err, deliveryMaxAttempts)
+	}
+
+	// Sends over the rate limit stay queued.
+	ch.setFail(false)
+	h.server.deliveries.recent = map[string][]time.Time{}
+	for range deliveryRateLimit + 5 {
+		h.server.notifDispatcher.Dispatch(ctx, notifications.Event{Type: notifications.EventAgentDone, Timestamp: time.Now()})
+	}
+	h.server.processDeliveries(ctx, time.Now())
+	if ch.count() != deliveryRateLimit {
+		t.Errorf("delivered %d, want %d", ch.count(), deliveryRateLimit)
+	}
+	if pending, _ := h.db.ListPendingNotificationDeliveries(ctx, 100); len(pending) != 5 {
+		t.Errorf("%d deliveries still pending, want 5", len(pending))
+	}
+
+	if got, want := deliveryBackoff(1), deliveryBaseBackoff; got != want {
+		t.Errorf("deliveryBackoff(1) = %v, want %v", got, want)
+	}
+	if got := deliveryBackoff(20); got != deliveryMaxBackoff {
+		t.Errorf("deliveryBackoff(20) = %v, want %v", got, deliveryMaxBackoff)
+	}
+}
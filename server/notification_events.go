@@ -0,0 +1,385 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"shelley.exe.dev/db"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/gitstate"
+	"shelley.exe.dev/llm"
+	"shelley.exe.dev/server/notifications"
+)
+
+// Settings that control when threshold-based notification events fire.
+const (
+	// notifyBudgetSetting is the conversation cost in USD that triggers
+	// budget_threshold. Empty or zero disables the event.
+	notifyBudgetSetting = "notify_budget_usd"
+	// notifyLongBashSetting is how many seconds a bash command must run
+	// before its completion triggers bash_finished.
+	notifyLongBashSetting = "notify_long_bash_seconds"
+	// notifyIdleSetting is how many minutes a finished conversation waits
+	// for the user before conversation_idle fires. Zero disables the event.
+	notifyIdleSetting = "notify_idle_minutes"
+	// notifyPublicURLSetting is the base URL the web UI is reachable at
+	// from wherever notifications are read, e.g. "https://shelley.example.com".
+	// Notifications link to conversations and accept replies only when it is set.
+	notifyPublicURLSetting = "notify_public_url"
+)
+
+const (
+	defaultLongBashSeconds = 300
+	defaultIdleMinutes     = 30
+
+	// idleCheckInterval is how often conversations are checked for idleness.
+	idleCheckInterval = time.Minute
+	// idleLookback bounds how long after the idle deadline a conversation is
+	// still notified, so a restart doesn't notify about every old conversation.
+	idleLookback = 10 * time.Minute
+	// idleNotifiedTTL is how long a conversation_idle is remembered, to not
+	// repeat it. It must exceed the idle setting plus idleLookback.
+	idleNotifiedTTL = 7 * 24 * time.Hour
+)
+
+// notificationSettingKeys are the settings users may change through /api/settings.
+var notificationSettingKeys = []string{notifyBudgetSetting, notifyLongBashSetting, notifyIdleSetting, notifyPublicURLSetting}
+
+// settingFloat returns a numeric setting, or def if it is unset or invalid.
+func (s *Server) settingFloat(ctx context.Context, key string, def float64) float64 {
+	value, err := s.db.GetSetting(ctx, key)
+	if err != nil || value == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		s.logger.Warn("Ignoring invalid setting", "key", key, "value", value)
+		return def
+	}
+	return f
+}
+
+// dispatchNotification fills in the conversation's cwd and tags, which
+// channel filters match on, and sends the event to the notification channels.
+func (s *Server) dispatchNotification(ctx context.Context, event notifications.Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if event.ConversationID != "" {
+		tagsConversationID := event.ConversationID
+		conv, err := s.db.GetConversationByID(ctx, event.ConversationID)
+		if err == nil {
+			if conv.Cwd != nil {
+				event.Cwd = *conv.Cwd
+			}
+			// Subagents inherit their parent's tags.
+			if conv.ParentConversationID != nil {
+				tagsConversationID = *conv.ParentConversationID
+			}
+		}
+		if tags, err := s.db.GetConversationTags(ctx, tagsConversationID); err == nil {
+			event.Tags = tags
+		}
+		// Links come last: whether to make a reply URL depends on which
+		// channels accept the event.
+		if err == nil {
+			s.addNotificationLinks(ctx, &event, conv)
+		}
+	}
+	s.notifDispatcher.Dispatch(ctx, event)
+}
+
+// conversationTitle returns the conversation's slug, or "" if it has none.
+func (s *Server) conversationTitle(ctx context.Context, conversationID string) string {
+	conv, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil || conv.Slug == nil {
+		return ""
+	}
+	return *conv.Slug
+}
+
+// turnEndEvent builds the event for a conversation that just stopped
+// working: agent_done, agent_error when the turn ended with an error, or
+// subagent_done for subagent conversations.
+func (s *Server) turnEndEvent(ctx context.Context, state ConversationState) notifications.Event {
+	event := notifications.Event{
+		Type:           notifications.EventAgentDone,
+		ConversationID: state.ConversationID,
+		Timestamp:      time.Now(),
+	}
+	conv, convErr := s.db.GetConversationByID(ctx, state.ConversationID)
+	var title string
+	if convErr == nil && conv.Slug != nil {
+		title = *conv.Slug
+	}
+
+	var text string
+	var isError bool
+	if msg, err := s.db.GetLatestMessage(ctx, state.ConversationID); err == nil && msg.LlmData != nil &&
+		(msg.Type == string(db.MessageTypeAgent) || msg.Type == string(db.MessageTypeError)) {
+		var llmMsg llm.Message
+		if json.Unmarshal([]byte(*msg.LlmData), &llmMsg) == nil {
+			for _, c := range llmMsg.Content {
+				if c.Type == llm.ContentTypeText && c.Text != "" {
+					text = c.Text
+				}
+			}
+			if len(text) > 255 {
+				text = text[:255] + "..."
+			}
+			isError = msg.Type == string(db.MessageTypeError)
+		}
+	}
+
+	switch {
+	case convErr == nil && conv.ParentConversationID != nil:
+		event.Type = notifications.EventSubagentDone
+		event.Payload = notifications.SubagentDonePayload{
+			ParentConversationID: *conv.ParentConversationID,
+			SubagentName:         title,
+			FinalResponse:        text,
+		}
+	case isError:
+		event.Type = notifications.EventAgentError
+		event.Payload = notifications.AgentErrorPayload{
+			ConversationTitle: title,
+			ErrorMessage:      text,
+		}
+	default:
+		payload := notifications.AgentDonePayload{
+			Model:             state.Model,
+			ConversationTitle: title,
+			FinalResponse:     text,
+		}
+		if convErr == nil && conv.ScheduleID != nil {
+			if schedule, err := s.db.GetSchedule(ctx, *conv.ScheduleID); err == nil {
+				payload.ScheduleName = schedule.Name
+			}
+		}
+		event.Payload = payload
+	}
+	return event
+}
+
+// notifyLongBashCommands sends bash_finished for every bash result in message
+// that ran longer than the configured threshold. It must be called before
+// message is recorded, while the latest message is still the agent message
+// holding the matching tool calls.
+func (s *Server) notifyLongBashCommands(ctx context.Context, conversationID string, message llm.Message) {
+	type longResult struct {
+		toolUseID string
+		duration  time.Duration
+		failed    bool
+	}
+	var results []longResult
+	for _, c := range message.Content {
+		if c.Type != llm.ContentTypeToolResult || c.ToolUseStartTime == nil || c.ToolUseEndTime == nil {
+			continue
+		}
+		results = append(results, longResult{c.ToolUseID, c.ToolUseEndTime.Sub(*c.ToolUseStartTime), c.ToolError})
+	}
+	if len(results) == 0 {
+		return
+	}
+	threshold := time.Duration(s.conversationSettingFloat(ctx, conversationID, notifyLongBashSetting, defaultLongBashSeconds) * float64(time.Second))
+	var long []longResult
+	for _, r := range results {
+		if r.duration >= threshold {
+			long = append(long, r)
+		}
+	}
+	if len(long) == 0 {
+		return
+	}
+
+	// Find the commands in the agent message that requested them.
+	commands := map[string]string{}
+	if msg, err := s.db.GetLatestMessage(ctx, conversationID); err == nil && msg.LlmData != nil {
+		var agentMsg llm.Message
+		if json.Unmarshal([]byte(*msg.LlmData), &agentMsg) == nil {
+			for _, c := range agentMsg.Content {
+				if c.Type != llm.ContentTypeToolUse || c.ToolName != "bash" {
+					continue
+				}
+				var input struct {
+					Command string `json:"command"`
+				}
+				if json.Unmarshal(c.ToolInput, &input) == nil {
+					commands[c.ID] = input.Command
+				}
+			}
+		}
+	}
+
+	title := s.conversationTitle(ctx, conversationID)
+	for _, r := range long {
+		command, ok := commands[r.toolUseID]
+		if !ok {
+			continue // not a bash call
+		}
+		go s.dispatchNotification(context.WithoutCancel(ctx), notifications.Event{
+			Type:           notifications.EventBashFinished,
+			ConversationID: conversationID,
+			Payload: notifications.BashFinishedPayload{
+				ConversationTitle: title,
+				Command:           command,
+				DurationSeconds:   r.duration.Seconds(),
+				Failed:            r.failed,
+			},
+		})
+	}
+}
+
+// checkBudgetThreshold sends budget_threshold when the message that just
+// cost added pushed the conversation's total over the configured threshold.
+// It must be called right after that message is recorded, before the next.
+func (s *Server) checkBudgetThreshold(ctx context.Context, conversationID string, added float64) {
+	threshold := s.conversationSettingFloat(ctx, conversationID, notifyBudgetSetting, 0)
+	if threshold <= 0 || added <= 0 {
+		return
+	}
+	total, err := s.db.GetConversationCost(ctx, conversationID)
+	if err != nil {
+		s.logger.Warn("Failed to get conversation cost", "conversationID", conversationID, "error", err)
+		return
+	}
+	if total < threshold || total-added >= threshold {
+		return
+	}
+	go s.dispatchNotification(context.WithoutCancel(ctx), notifications.Event{
+		Type:           notifications.EventBudgetThreshold,
+		ConversationID: conversationID,
+		Payload: notifications.BudgetThresholdPayload{
+			ConversationTitle: s.conversationTitle(ctx, conversationID),
+			CostUSD:           total,
+			ThresholdUSD:      threshold,
+		},
+	})
+}
+
+// notifyGitCommit sends git_commit when the conversation's repository moved
+// to a new commit on the same branch.
+func (s *Server) notifyGitCommit(ctx context.Context, conversationID string, prev, cur *gitstate.GitState) {
+	if prev == nil || cur == nil || !prev.IsRepo || !cur.IsRepo {
+		return
+	}
+	if prev.Worktree != cur.Worktree || prev.Branch != cur.Branch || prev.Commit == cur.Commit {
+		return
+	}
+	s.dispatchNotification(ctx, notifications.Event{
+		Type:           notifications.EventGitCommit,
+		ConversationID: conversationID,
+		Payload: notifications.GitCommitPayload{
+			ConversationTitle: s.conversationTitle(ctx, conversationID),
+			Worktree:          cur.Worktree,
+			Branch:            cur.Branch,
+			Commit:            cur.Commit,
+			Subject:           cur.Subject,
+		},
+	})
+}
+
+// notificationRoutine periodically sends conversation_idle notifications
+// until the server shuts down.
+func (s *Server) notificationRoutine() {
+	ticker := time.NewTicker(idleCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+			s.notifyIdleConversations(context.Background(), time.Now())
+		}
+	}
+}
+
+// notifyIdleConversations sends conversation_idle for conversations whose
+// agent finished more than the configured number of minutes before now and
+// that have not heard from the user since.
+func (s *Server) notifyIdleConversations(ctx context.Context, now time.Time) {
+	conversations, err := s.db.ListConversations(ctx, 200, 0)
+	if err != nil {
+		s.logger.Error("Failed to list conversations for idle check", "error", err)
+		return
+	}
+
+	s.idleMu.Lock()
+	defer s.idleMu.Unlock()
+	for id, updatedAt := range s.idleNotified {
+		if now.Sub(updatedAt) > idleNotifiedTTL {
+			delete(s.idleNotified, id)
+		}
+	}
+
+	defaultMinutes := s.settingFloat(ctx, notifyIdleSetting, defaultIdleMinutes)
+	for _, conv := range conversations {
+		// In multi-user mode the owner may have their own idle setting.
+		minutes := defaultMinutes
+		if conv.OwnerEmail != nil {
+			minutes = s.ownerSettingFloat(ctx, conv.OwnerEmail, notifyIdleSetting, defaultIdleMinutes)
+		}
+		if int(minutes) <= 0 {
+			continue
+		}
+		idleAfter := time.Duration(int(minutes)) * time.Minute
+		idle := now.Sub(conv.UpdatedAt)
+		if idle < idleAfter || idle > idleAfter+idleLookback {
+			continue
+		}
+		if notified, ok := s.idleNotified[conv.ConversationID]; ok && notified.Equal(conv.UpdatedAt) {
+			continue
+		}
+		if s.IsAgentWorking(conv.ConversationID) || !s.waitingForUser(ctx, conv) {
+			continue
+		}
+		s.idleNotified[conv.ConversationID] = conv.UpdatedAt
+		var title string
+		if conv.Slug != nil {
+			title = *conv.Slug
+		}
+		s.dispatchNotification(ctx, notifications.Event{
+			Type:           notifications.EventConversationIdle,
+			ConversationID: conv.ConversationID,
+			Payload: notifications.ConversationIdlePayload{
+				ConversationTitle: title,
+				IdleMinutes:       int(idle / time.Minute),
+			},
+		})
+	}
+}
+
+// waitingForUser reports whether the conversation's last message is the
+// agent's (or an error), i.e. the next move is the user's.
+func (s *Server) waitingForUser(ctx context.Context, conv generated.Conversation) bool {
+	msg, err := s.db.GetLatestMessage(ctx, conv.ConversationID)
+	if err != nil {
+		return false
+	}
+	return msg.Type == string(db.MessageTypeAgent) || msg.Type == string(db.MessageTypeError)
+}
+
+// notifyUpgradeAvailable sends upgrade_available once per release, when the
+// UI would start showing its upgrade dot.
+func (s *Server) notifyUpgradeAvailable(ctx context.Context) {
+	info, err := s.versionChecker.Check(ctx, false)
+	if err != nil || !info.ShouldNotify || info.LatestTag == "" {
+		return
+	}
+	const key = "notified_upgrade_tag"
+	if last, _ := s.db.GetSetting(ctx, key); last == info.LatestTag {
+		return
+	}
+	if err := s.db.SetSetting(ctx, key, info.LatestTag); err != nil {
+		s.logger.Warn("Failed to record upgrade notification", "error", err)
+	}
+	s.dispatchNotification(ctx, notifications.Event{
+		Type: notifications.EventUpgradeAvailable,
+		Payload: notifications.UpgradeAvailablePayload{
+			CurrentVersion: info.CurrentTag,
+			LatestVersion:  info.LatestTag,
+		},
+	})
+}
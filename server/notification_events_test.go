@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/gitstate"
+	"shelley.exe.dev/llm"
+	"shelley.exe.dev/server/notifications"
+)
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []notifications.Event
+}
+
+func (r *eventRecorder) Name() string { return "recorder" }
+
+func (r *eventRecorder) Send(ctx context.Context, event notifications.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *eventRecorder) count(typ notifications.EventType) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, e := range r.events {
+		if e.Type == typ {
+			n++
+		}
+	}
+	return n
+}
+
+// wait returns the first event of the given type, waiting up to 5 seconds for it.
+func (r *eventRecorder) wait(t *testing.T, typ notifications.EventType) notifications.Event {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		for _, e := range r.events {
+			if e.Type == typ {
+				r.mu.Unlock()
+				return e
+			}
+		}
+		r.mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("no %s event", typ)
+	return notifications.Event{}
+}
+
+func registerRecorder(t *testing.T, s *Server, config map[string]any) *eventRecorder {
+	t.Helper()
+	filter, err := notifications.ParseFilter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := &eventRecorder{}
+	s.notifDispatcher.Register(notifications.WithFilter(rec, filter))
+	return rec
+}
+
+func TestNotificationEvents(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+	all := registerRecorder(t, h.server, map[string]any{"events": "all"})
+	prod := registerRecorder(t, h.server, map[string]any{"events": "all", "tags": "prod"})
+
+	if err := h.db.SetSetting(ctx, notifyLongBashSetting, "0.5"); err != nil {
+		t.Fatal(err)
+	}
+	cwd := t.TempDir()
+	h.NewConversation("bash: sleep 1", cwd)
+	h.WaitResponse()
+	waitForIdle(t, h.server, h.convID)
+
+	bash := all.wait(t, notifications.EventBashFinished)
+	if p, ok := bash.Payload.(notifications.BashFinishedPayload); !ok || p.Command != "sleep 1" || p.DurationSeconds < 1 {
+		t.Errorf("bash_finished payload = %+v", bash.Payload)
+	}
+	if done := all.wait(t, notifications.EventAgentDone); done.Cwd != cwd {
+		t.Errorf("agent_done cwd = %q, want %q", done.Cwd, cwd)
+	}
+
+	// Crossing the budget threshold notifies once.
+	if err := h.db.SetSetting(ctx, notifyBudgetSetting, "1"); err != nil {
+		t.Fatal(err)
+	}
+	reply := llm.Message{Role: llm.MessageRoleAssistant, Content: []llm.Content{llm.StringContent("ok")}}
+	for range 3 {
+		if err := h.server.recordMessage(ctx, h.convID, reply, llm.Usage{CostUSD: 0.6}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	budget := all.wait(t, notifications.EventBudgetThreshold)
+	if p := budget.Payload.(notifications.BudgetThresholdPayload); p.ThresholdUSD != 1 || p.CostUSD < 1 {
+		t.Errorf("budget_threshold payload = %+v", p)
+	}
+
+	// A new commit on the same branch notifies; a branch switch does not.
+	prev := &gitstate.GitState{IsRepo: true, Worktree: cwd, Branch: "main", Commit: "aaa"}
+	h.server.notifyGitCommit(ctx, h.convID, prev, &gitstate.GitState{IsRepo: true, Worktree: cwd, Branch: "dev", Commit: "bbb"})
+	h.server.notifyGitCommit(ctx, h.convID, prev, &gitstate.GitState{IsRepo: true, Worktree: cwd, Branch: "main", Commit: "ccc", Subject: "Fix it"})
+	all.wait(t, notifications.EventGitCommit)
+
+	// The conversation is idle once it has waited for the user long enough,
+	// and is only reported once.
+	if err := h.db.SetConversationTags(ctx, h.convID, []string{"prod"}); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(defaultIdleMinutes*time.Minute + time.Minute)
+	h.server.notifyIdleConversations(ctx, time.Now())
+	h.server.notifyIdleConversations(ctx, later)
+	h.server.notifyIdleConversations(ctx, later.Add(time.Minute))
+	all.wait(t, notifications.EventConversationIdle)
+	prod.wait(t, notifications.EventConversationIdle)
+
+	time.Sleep(100 * time.Millisecond) // let any extra sends arrive
+	if n := all.count(notifications.EventGitCommit); n != 1 {
+		t.Errorf("git_commit events = %d, want 1", n)
+	}
+	if n := all.count(notifications.EventConversationIdle); n != 1 {
+		t.Errorf("conversation_idle events = %d, want 1", n)
+	}
+	if n := all.count(notifications.EventBudgetThreshold); n != 1 {
+		t.Errorf("budget_threshold events = %d, want 1", n)
+	}
+
+	// The tag filter only let events through after the tag was added.
+	if n := prod.count(notifications.EventConversationIdle); n != 1 {
+		t.Errorf("tag-filtered channel got %d conversation_idle events, want 1", n)
+	}
+	if n := prod.count(notifications.EventAgentDone); n != 0 {
+		t.Errorf("tag-filtered channel got %d agent_done events before the tag was set", n)
+	}
+}
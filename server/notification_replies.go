@@ -0,0 +1,194 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/server/notifications"
+)
+
+// replyTokenTTL is how long a notification accepts replies. A reply link
+// is a credential for driving the agent, so it doesn't outlive the day.
+const replyTokenTTL = 24 * time.Hour
+
+// maxReplySize bounds the body of a notification reply.
+const maxReplySize = 64 << 10
+
+// publicURL returns the configured public base URL without a trailing slash,
+// or "" if it is unset or invalid.
+func (s *Server) publicURL(ctx context.Context) string {
+	value, err := s.db.GetSetting(ctx, notifyPublicURLSetting)
+	if err != nil || value == "" {
+		return ""
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		s.logger.Warn("Ignoring invalid setting", "key", notifyPublicURLSetting, "value", value)
+		return ""
+	}
+	return strings.TrimSuffix(value, "/")
+}
+
+// addNotificationLinks sets the event's link to the conversation and, for
+// agent_done and agent_error of top-level conversations going to a channel
+// that allows replies, a reply URL backed by a new reply token. Only the
+// token's hash is stored.
+func (s *Server) addNotificationLinks(ctx context.Context, event *notifications.Event, conv *generated.Conversation) {
+	base := s.publicURL(ctx)
+	if base == "" {
+		return
+	}
+	if conv.Slug != nil {
+		event.URL = base + "/c/" + url.PathEscape(*conv.Slug)
+	}
+	if conv.ParentConversationID != nil ||
+		(event.Type != notifications.EventAgentDone && event.Type != notifications.EventAgentError) ||
+		!s.notifDispatcher.WantsReplies(*event) {
+		return
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+	if err := s.db.CreateNotificationReplyToken(ctx, hashSecret(token), conv.ConversationID, time.Now().Add(replyTokenTTL)); err != nil {
+		s.logger.Warn("Failed to create notification reply token", "conversationID", conv.ConversationID, "error", err)
+		return
+	}
+	event.ReplyURL = base + "/api/notification-replies/" + token
+}
+
+// readReplyMessage reads a reply from a JSON body ({"message": ...}), a form
+// (message=...), or plain text.
+func readReplyMessage(r *http.Request, body []byte) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		var req struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			return "", errors.New("invalid JSON")
+		}
+		return strings.TrimSpace(req.Message), nil
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "", errors.New("invalid form")
+		}
+		return strings.TrimSpace(values.Get("message")), nil
+	default:
+		return strings.TrimSpace(string(body)), nil
+	}
+}
+
+// handleNotificationReply handles POST /api/notification-replies/<token>,
+// which queues the reply as the next user message of the conversation the
+// notification was about. The token in the URL is the only credential, and
+// works for one reply.
+func (s *Server) handleNotificationReply(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	token := strings.TrimPrefix(r.URL.Path, "/api/notification-replies/")
+	if token == "" || strings.Contains(token, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	tokenHash := hashSecret(token)
+	replyToken, err := s.db.GetNotificationReplyToken(ctx, tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Reply link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get notification reply token", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if time.Now().After(replyToken.ExpiresAt) {
+		http.Error(w, "Reply link has expired", http.StatusGone)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxReplySize))
+	if err != nil {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	message, err := readReplyMessage(r, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if message == "" {
+		http.Error(w, "Message is required", http.StatusBadRequest)
+		return
+	}
+
+	conversationID := replyToken.ConversationID
+	conv, err := s.db.GetConversationByID(ctx, conversationID)
+	if err != nil {
+		http.Error(w, "Conversation not found", http.StatusNotFound)
+		return
+	}
+	modelID := s.defaultModel
+	if conv.Model != nil {
+		modelID = *conv.Model
+	}
+	llmService, err := s.llmManager.GetService(modelID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unsupported model: %s", modelID), http.StatusBadRequest)
+		return
+	}
+
+	// Use up the token before queueing the reply, so that concurrent
+	// requests can't both get through.
+	err = s.db.UseNotificationReplyToken(ctx, tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Reply link not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to use notification reply token", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	err = s.sendUserMessage(ctx, conversationID, "", llmService, modelID, message)
+	if errors.Is(err, errConversationModelMismatch) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, errBudgetExceeded) {
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to send notification reply", "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	s.logger.Info("Notification reply queued", "conversationID", conversationID)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":          "accepted",
+		"conversation_id": conversationID,
+	})
+}
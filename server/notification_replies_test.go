@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/server/notifications"
+)
+
+func TestNotificationReplies(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+	rec := registerRecorder(t, h.server, map[string]any{"allow_replies": "yes"})
+	// A channel that doesn't allow replies gets no reply link.
+	noReplies := registerRecorder(t, h.server, nil)
+
+	if err := h.db.SetSetting(ctx, notifyPublicURLSetting, "https://shelley.example.com/"); err != nil {
+		t.Fatal(err)
+	}
+	h.NewConversation("echo: first", "")
+	h.WaitResponse()
+	waitForIdle(t, h.server, h.convID)
+
+	done := rec.wait(t, notifications.EventAgentDone)
+	replyPath, ok := strings.CutPrefix(done.ReplyURL, "https://shelley.example.com")
+	if !ok || !strings.HasPrefix(replyPath, "/api/notification-replies/") {
+		t.Fatalf("reply_url = %q", done.ReplyURL)
+	}
+	if !strings.HasPrefix(done.URL, "https://shelley.example.com/c/") {
+		t.Errorf("url = %q", done.URL)
+	}
+	if event := noReplies.wait(t, notifications.EventAgentDone); event.ReplyURL != "" || event.URL != done.URL {
+		t.Errorf("channel without replies got url %q, reply_url %q", event.URL, event.ReplyURL)
+	}
+	// Only the token's hash is stored.
+	token := strings.TrimPrefix(replyPath, "/api/notification-replies/")
+	if _, err := h.db.GetNotificationReplyToken(ctx, token); err == nil {
+		t.Error("reply token is stored in the clear")
+	}
+
+	reply := func(path, contentType, body string) int {
+		t.Helper()
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := reply(replyPath, "application/json", `{"message":""}`); code != http.StatusBadRequest {
+		t.Errorf("empty reply: got %d, want 400", code)
+	}
+	if code := reply(replyPath, "application/json", `{"message":"echo: second"}`); code != http.StatusAccepted {
+		t.Fatalf("reply: got %d", code)
+	}
+	waitForIdle(t, h.server, h.convID)
+	// A reply link works once; the next notification brings a new one.
+	if code := reply(replyPath, "text/plain", "echo: again"); code != http.StatusNotFound {
+		t.Errorf("reusing a reply link: got %d, want 404", code)
+	}
+	nextReplyPath := func(n int) string {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			rec.mu.Lock()
+			var done []notifications.Event
+			for _, e := range rec.events {
+				if e.Type == notifications.EventAgentDone {
+					done = append(done, e)
+				}
+			}
+			rec.mu.Unlock()
+			if len(done) >= n {
+				return strings.TrimPrefix(done[n-1].ReplyURL, "https://shelley.example.com")
+			}
+		}
+		t.Fatalf("no agent_done event %d", n)
+		return ""
+	}
+	// Plain text works too, so `curl -d` is enough.
+	if code := reply(nextReplyPath(2), "text/plain", "echo: third"); code != http.StatusAccepted {
+		t.Fatalf("plain text reply: got %d", code)
+	}
+	waitForIdle(t, h.server, h.convID)
+
+	messages, err := h.db.ListMessages(ctx, h.convID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var replies int
+	for _, m := range messages {
+		if m.Type == "user" && m.LlmData != nil &&
+			(strings.Contains(*m.LlmData, "echo: second") || strings.Contains(*m.LlmData, "echo: third")) {
+			replies++
+		}
+	}
+	if replies != 2 {
+		t.Errorf("found %d replies in the conversation, want 2", replies)
+	}
+
+	if code := reply("/api/notification-replies/unknown", "text/plain", "hi"); code != http.StatusNotFound {
+		t.Errorf("unknown token: got %d, want 404", code)
+	}
+	if err := h.db.CreateNotificationReplyToken(ctx, hashSecret("expired"), h.convID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if code := reply("/api/notification-replies/expired", "text/plain", "hi"); code != http.StatusGone {
+		t.Errorf("expired token: got %d, want 410", code)
+	}
+	if err := h.db.PruneNotificationReplyTokens(ctx, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if code := reply("/api/notification-replies/expired", "text/plain", "hi"); code != http.StatusNotFound {
+		t.Errorf("pruned token: got %d, want 404", code)
+	}
+}
+
+func TestNotificationRepliesNeedOptIn(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := context.Background()
+	rec := registerRecorder(t, h.server, nil)
+
+	if err := h.db.SetSetting(ctx, notifyPublicURLSetting, "https://shelley.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	h.NewConversation("echo: first", "")
+	h.WaitResponse()
+	waitForIdle(t, h.server, h.convID)
+
+	done := rec.wait(t, notifications.EventAgentDone)
+	if done.ReplyURL != "" {
+		t.Errorf("reply_url = %q without a channel that allows replies", done.ReplyURL)
+	}
+	if !strings.HasPrefix(done.URL, "https://shelley.example.com/c/") {
+		t.Errorf("url = %q", done.URL)
+	}
+}
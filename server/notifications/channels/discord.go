@@ -44,6 +44,9 @@ func (d *discord) Send(ctx context.Context, event notifications.Event) error {
 	if msg == nil {
 		return nil
 	}
+	for i := range msg.Embeds {
+		msg.Embeds[i].URL = event.URL
+	}
 
 	body, err := json.Marshal(msg)
 	if err != nil {
@@ -75,6 +78,7 @@ type discordMessage struct {
 
 type discordEmbed struct {
 	Title       string `json:"title"`
+	URL         string `json:"url,omitempty"` // makes the title a link
 	Description string `json:"description,omitempty"`
 	Color       int    `json:"color"`
 	Timestamp   string `json:"timestamp,omitempty"`
@@ -88,11 +92,7 @@ func formatDiscordMessage(event notifications.Event) *discordMessage {
 			Timestamp: event.Timestamp.Format(time.RFC3339),
 		}
 		if p, ok := event.Payload.(notifications.AgentDonePayload); ok {
-			if p.ConversationTitle != "" {
-				embed.Title = fmt.Sprintf("Agent finished: %s", p.ConversationTitle)
-			} else {
-				embed.Title = "Agent finished"
-			}
+			embed.Title = p.Title()
 			if p.Model != "" {
 				embed.Description = fmt.Sprintf("Model: `%s`", p.Model)
 			}
@@ -108,17 +108,23 @@ func formatDiscordMessage(event notifications.Event) *discordMessage {
 		return &discordMessage{Embeds: []discordEmbed{embed}}
 
 	case notifications.EventAgentError:
+		title, message := event.Summary()
 		embed := discordEmbed{
-			Title:     "Agent error",
-			Color:     0xef4444, // red
-			Timestamp: event.Timestamp.Format(time.RFC3339),
-		}
-		if p, ok := event.Payload.(notifications.AgentErrorPayload); ok && p.ErrorMessage != "" {
-			embed.Description = p.ErrorMessage
+			Title:       title,
+			Description: message,
+			Color:       0xef4444, // red
+			Timestamp:   event.Timestamp.Format(time.RFC3339),
 		}
 		return &discordMessage{Embeds: []discordEmbed{embed}}
 
 	default:
-		return nil
+		title, message := event.Summary()
+		embed := discordEmbed{
+			Title:       title,
+			Description: message,
+			Color:       0x3b82f6, // blue
+			Timestamp:   event.Timestamp.Format(time.RFC3339),
+		}
+		return &discordMessage{Embeds: []discordEmbed{embed}}
 	}
 }
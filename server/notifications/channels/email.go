@@ -92,9 +92,7 @@ func formatEmailMessage(event notifications.Event) (subject, body string) {
 	case notifications.EventAgentDone:
 		subject = "Agent finished"
 		if p, ok := event.Payload.(notifications.AgentDonePayload); ok {
-			if p.ConversationTitle != "" {
-				subject = fmt.Sprintf("Agent finished: %s", p.ConversationTitle)
-			}
+			subject = p.Title()
 			if p.Model != "" {
 				body = fmt.Sprintf("Model: %s\nTime: %s", p.Model, event.Timestamp.Format(time.RFC822))
 			} else {
@@ -106,14 +104,7 @@ func formatEmailMessage(event notifications.Event) (subject, body string) {
 		}
 		return subject, body
 
-	case notifications.EventAgentError:
-		subject = "Agent error"
-		if p, ok := event.Payload.(notifications.AgentErrorPayload); ok && p.ErrorMessage != "" {
-			body = p.ErrorMessage
-		}
-		return subject, body
-
 	default:
-		return "", ""
+		return event.Summary()
 	}
 }
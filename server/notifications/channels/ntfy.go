@@ -8,6 +8,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"shelley.exe.dev/server/notifications"
@@ -55,6 +56,11 @@ func init() {
 			return nil, fmt.Errorf("ntfy channel: invalid error_priority %q", errorPriorityStr)
 		}
 
+		replyActions, err := parseNtfyReplyActions(config["reply_actions"])
+		if err != nil {
+			return nil, err
+		}
+
 		return &ntfy{
 			server:        server,
 			topic:         topic,
@@ -63,6 +69,7 @@ func init() {
 			password:      password,
 			donePriority:  donePriority,
 			errorPriority: errorPriority,
+			replyActions:  replyActions,
 			client: &http.Client{
 				Timeout: 10 * time.Second,
 			},
@@ -78,17 +85,88 @@ type ntfy struct {
 	password      string
 	donePriority  int
 	errorPriority int
+	replyActions  []ntfyReplyAction
 	client        *http.Client
 }
 
+// ntfyMaxActions is the most action buttons ntfy shows on a notification.
+const ntfyMaxActions = 3
+
+// ntfyReplyAction is a quick-reply button that sends message as the next user
+// message of the conversation.
+type ntfyReplyAction struct {
+	label   string
+	message string
+}
+
+// parseNtfyReplyActions parses the reply_actions config: one button per line,
+// either "Label" (which also sends the label) or "Label: message".
+func parseNtfyReplyActions(v any) ([]ntfyReplyAction, error) {
+	text, _ := v.(string)
+	var actions []ntfyReplyAction
+	for line := range strings.Lines(text) {
+		label, message, found := strings.Cut(strings.TrimSpace(line), ":")
+		label, message = strings.TrimSpace(label), strings.TrimSpace(message)
+		if label == "" {
+			continue
+		}
+		if !found || message == "" {
+			message = label
+		}
+		actions = append(actions, ntfyReplyAction{label: label, message: message})
+	}
+	if len(actions) > ntfyMaxActions-1 {
+		return nil, fmt.Errorf("ntfy channel: at most %d reply_actions", ntfyMaxActions-1)
+	}
+	return actions, nil
+}
+
 func (n *ntfy) Name() string { return "ntfy" }
 
 type ntfyMessage struct {
-	Topic    string   `json:"topic"`
-	Title    string   `json:"title"`
-	Message  string   `json:"message"`
-	Priority int      `json:"priority"`
-	Tags     []string `json:"tags"`
+	Topic    string       `json:"topic"`
+	Title    string       `json:"title"`
+	Message  string       `json:"message"`
+	Priority int          `json:"priority"`
+	Tags     []string     `json:"tags"`
+	Click    string       `json:"click,omitempty"`
+	Actions  []ntfyAction `json:"actions,omitempty"`
+}
+
+// ntfyAction is an action button; see https://docs.ntfy.sh/publish/#action-buttons.
+type ntfyAction struct {
+	Action  string            `json:"action"` // "view" or "http"
+	Label   string            `json:"label"`
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+	Clear   bool              `json:"clear,omitempty"`
+}
+
+// actions returns the buttons for an event: one that opens the conversation
+// and, if the event can be replied to, the configured quick replies.
+func (n *ntfy) actions(event notifications.Event) []ntfyAction {
+	var actions []ntfyAction
+	if event.URL != "" {
+		actions = append(actions, ntfyAction{Action: "view", Label: "Open", URL: event.URL})
+	}
+	if event.ReplyURL == "" {
+		return actions
+	}
+	for _, a := range n.replyActions {
+		body, _ := json.Marshal(map[string]string{"message": a.message})
+		actions = append(actions, ntfyAction{
+			Action:  "http",
+			Label:   a.label,
+			URL:     event.ReplyURL,
+			Method:  http.MethodPost,
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    string(body),
+			Clear:   true,
+		})
+	}
+	return actions
 }
 
 func (n *ntfy) Send(ctx context.Context, event notifications.Event) error {
@@ -96,6 +174,8 @@ func (n *ntfy) Send(ctx context.Context, event notifications.Event) error {
 	if msg == nil {
 		return nil
 	}
+	msg.Click = event.URL
+	msg.Actions = n.actions(event)
 
 	body, err := json.Marshal(msg)
 	if err != nil {
@@ -141,11 +221,7 @@ func (n *ntfy) formatMessage(event notifications.Event) *ntfyMessage {
 			Tags:     []string{"white_check_mark"},
 		}
 		if p, ok := event.Payload.(notifications.AgentDonePayload); ok {
-			if p.ConversationTitle != "" {
-				msg.Title = fmt.Sprintf("Agent finished: %s", p.ConversationTitle)
-			} else {
-				msg.Title = "Agent finished"
-			}
+			msg.Title = p.Title()
 			var body string
 			if p.Model != "" {
 				body = fmt.Sprintf("Model: %s", p.Model)
@@ -163,18 +239,23 @@ func (n *ntfy) formatMessage(event notifications.Event) *ntfyMessage {
 		return msg
 
 	case notifications.EventAgentError:
-		msg := &ntfyMessage{
+		title, message := event.Summary()
+		return &ntfyMessage{
 			Topic:    n.topic,
-			Title:    "Agent error",
+			Title:    title,
+			Message:  message,
 			Priority: n.errorPriority,
 			Tags:     []string{"x"},
 		}
-		if p, ok := event.Payload.(notifications.AgentErrorPayload); ok && p.ErrorMessage != "" {
-			msg.Message = p.ErrorMessage
-		}
-		return msg
 
 	default:
-		return nil
+		title, message := event.Summary()
+		return &ntfyMessage{
+			Topic:    n.topic,
+			Title:    title,
+			Message:  message,
+			Priority: ntfyPriorities["default"],
+			Tags:     []string{"bell"},
+		}
 	}
 }
@@ -0,0 +1,71 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"shelley.exe.dev/server/notifications"
+)
+
+func TestNtfyReplyActions(t *testing.T) {
+	srv, requests := newCaptureServer(t, http.StatusOK)
+	ch, err := notifications.CreateFromConfig(map[string]any{
+		"type":          "ntfy",
+		"server":        srv.URL,
+		"topic":         "shelley",
+		"reply_actions": "Continue\nCommit: Yes, go ahead and commit\n",
+	}, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := testEvent
+	event.URL = "https://shelley.example.com/c/fix-tests"
+	event.ReplyURL = "https://shelley.example.com/api/notification-replies/tok"
+	if err := ch.Send(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	var msg ntfyMessage
+	if err := json.Unmarshal((<-requests).body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Click != event.URL {
+		t.Errorf("click = %q, want %q", msg.Click, event.URL)
+	}
+	if len(msg.Actions) != 3 {
+		t.Fatalf("actions = %+v, want open and two replies", msg.Actions)
+	}
+	if a := msg.Actions[0]; a.Action != "view" || a.URL != event.URL {
+		t.Errorf("first action = %+v, want view of the conversation", a)
+	}
+	commit := msg.Actions[2]
+	if commit.Action != "http" || commit.Label != "Commit" || commit.URL != event.ReplyURL || commit.Method != "POST" {
+		t.Errorf("reply action = %+v", commit)
+	}
+	if commit.Body != `{"message":"Yes, go ahead and commit"}` {
+		t.Errorf("reply body = %s", commit.Body)
+	}
+
+	// Without a reply URL only the link remains.
+	event.ReplyURL = ""
+	if err := ch.Send(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	msg = ntfyMessage{}
+	json.Unmarshal((<-requests).body, &msg)
+	if len(msg.Actions) != 1 {
+		t.Errorf("actions without reply URL = %+v, want only open", msg.Actions)
+	}
+
+	if _, err := notifications.CreateFromConfig(map[string]any{
+		"type":          "ntfy",
+		"server":        srv.URL,
+		"topic":         "shelley",
+		"reply_actions": "a\nb\nc",
+	}, slog.Default()); err == nil {
+		t.Error("three reply_actions accepted, want error")
+	}
+}
@@ -0,0 +1,288 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"shelley.exe.dev/server/notifications"
+)
+
+// SMTP connection security modes.
+const (
+	smtpSecurityStartTLS = "starttls" // plain connection upgraded with STARTTLS (usually port 587)
+	smtpSecurityTLS      = "tls"      // implicit TLS (usually port 465)
+	smtpSecurityNone     = "none"     // unencrypted; only sensible for a local relay
+)
+
+func init() {
+	notifications.Register("smtp", func(config map[string]any, logger *slog.Logger) (notifications.Channel, error) {
+		host, _ := config["host"].(string)
+		if host == "" {
+			return nil, fmt.Errorf("smtp channel requires \"host\"")
+		}
+
+		security, _ := config["security"].(string)
+		if security == "" {
+			security = smtpSecurityStartTLS
+		}
+		port := 587
+		switch security {
+		case smtpSecurityStartTLS:
+		case smtpSecurityTLS:
+			port = 465
+		case smtpSecurityNone:
+			port = 25
+		default:
+			return nil, fmt.Errorf("smtp channel: invalid security %q (want starttls, tls or none)", security)
+		}
+		switch p := config["port"].(type) {
+		case float64:
+			port = int(p)
+		case string:
+			if p != "" {
+				n, err := strconv.Atoi(p)
+				if err != nil || n <= 0 || n > 65535 {
+					return nil, fmt.Errorf("smtp channel: invalid port %q", p)
+				}
+				port = n
+			}
+		}
+
+		fromStr, _ := config["from"].(string)
+		if fromStr == "" {
+			return nil, fmt.Errorf("smtp channel requires \"from\"")
+		}
+		from, err := mail.ParseAddress(fromStr)
+		if err != nil {
+			return nil, fmt.Errorf("smtp channel: invalid from address: %w", err)
+		}
+
+		to, err := parseSMTPRecipients(config["to"])
+		if err != nil {
+			return nil, fmt.Errorf("smtp channel: %w", err)
+		}
+
+		username, _ := config["username"].(string)
+		password, _ := config["password"].(string)
+
+		return &smtpChannel{
+			host:     host,
+			port:     port,
+			security: security,
+			username: username,
+			password: password,
+			from:     from,
+			to:       to,
+		}, nil
+	})
+}
+
+// parseSMTPRecipients accepts a comma-separated string (from the settings UI)
+// or a JSON array of strings (from the config file).
+func parseSMTPRecipients(v any) ([]*mail.Address, error) {
+	var list string
+	switch v := v.(type) {
+	case string:
+		list = v
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("\"to\" entries must be strings")
+			}
+			parts = append(parts, s)
+		}
+		list = strings.Join(parts, ",")
+	}
+	if strings.TrimSpace(list) == "" {
+		return nil, fmt.Errorf("requires \"to\"")
+	}
+	addrs, err := mail.ParseAddressList(list)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to address: %w", err)
+	}
+	return addrs, nil
+}
+
+type smtpChannel struct {
+	host     string
+	port     int
+	security string
+	username string
+	password string
+	from     *mail.Address
+	to       []*mail.Address
+}
+
+func (s *smtpChannel) Name() string { return "smtp" }
+
+func (s *smtpChannel) Send(ctx context.Context, event notifications.Event) error {
+	subject, text := formatEmailMessage(event)
+	if subject == "" {
+		return nil
+	}
+	msg, err := s.buildMessage(subject, text, formatEmailHTML(event, subject), event.Timestamp)
+	if err != nil {
+		return fmt.Errorf("build smtp message: %w", err)
+	}
+
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	if s.security == smtpSecurityTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: s.host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connect to smtp server: %w", err)
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		return fmt.Errorf("smtp handshake: %w", err)
+	}
+	defer c.Close()
+
+	if s.security == smtpSecurityStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("smtp server does not support STARTTLS")
+		}
+		if err := c.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return fmt.Errorf("smtp starttls: %w", err)
+		}
+	}
+	if s.username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted
+		// connection to anything but localhost.
+		if err := c.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(s.from.Address); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	for _, rcpt := range s.to {
+		if err := c.Rcpt(rcpt.Address); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s: %w", rcpt.Address, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("write smtp message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	return c.Quit()
+}
+
+// buildMessage returns an RFC 5322 message with text and HTML alternatives.
+func (s *smtpChannel) buildMessage(subject, text, html string, date time.Time) ([]byte, error) {
+	if date.IsZero() {
+		date = time.Now()
+	}
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	to := make([]string, len(s.to))
+	for i, a := range s.to {
+		to[i] = a.String()
+	}
+	id := make([]byte, 12)
+	rand.Read(id)
+	domain := s.from.Address[strings.LastIndex(s.from.Address, "@")+1:]
+
+	header := []string{
+		"From: " + s.from.String(),
+		"To: " + strings.Join(to, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", subject),
+		"Date: " + date.Format(time.RFC1123Z),
+		"Message-ID: <" + hex.EncodeToString(id) + "@" + domain + ">",
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + mw.Boundary(),
+	}
+	var msg bytes.Buffer
+	msg.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	msg.Write(buf.Bytes())
+	return msg.Bytes(), nil
+}
+
+var emailHTMLTemplate = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html><body style="font-family: sans-serif">
+<h2>{{.Subject}}</h2>
+{{if .Model}}<p><strong>Model:</strong> <code>{{.Model}}</code></p>{{end}}
+{{if not .Time.IsZero}}<p><strong>Time:</strong> {{.Time.Format "Mon, 02 Jan 2006 15:04 MST"}}</p>{{end}}
+{{if .Body}}<pre style="white-space: pre-wrap">{{.Body}}</pre>{{end}}
+</body></html>
+`))
+
+// formatEmailHTML renders the HTML alternative for an event.
+func formatEmailHTML(event notifications.Event, subject string) string {
+	data := struct {
+		Subject string
+		Model   string
+		Time    time.Time
+		Body    string
+	}{Subject: subject, Time: event.Timestamp}
+	_, data.Body = event.Summary()
+	if p, ok := event.Payload.(notifications.AgentDonePayload); ok {
+		data.Model = p.Model
+	}
+	var buf bytes.Buffer
+	if err := emailHTMLTemplate.Execute(&buf, data); err != nil {
+		return ""
+	}
+	return buf.String()
+}
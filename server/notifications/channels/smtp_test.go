@@ -0,0 +1,165 @@
+package channels
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"shelley.exe.dev/server/notifications"
+)
+
+type capturedMail struct {
+	from string
+	rcpt []string
+	data string
+}
+
+// newFakeSMTPServer accepts a single unencrypted, unauthenticated SMTP session.
+func newFakeSMTPServer(t *testing.T) (net.Addr, <-chan capturedMail) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	ch := make(chan capturedMail, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		var m capturedMail
+		tp.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "MAIL":
+				m.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+				tp.PrintfLine("250 OK")
+			case "RCPT":
+				m.rcpt = append(m.rcpt, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				data, err := io.ReadAll(tp.DotReader())
+				if err != nil {
+					return
+				}
+				m.data = string(data)
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				ch <- m
+				return
+			default:
+				tp.PrintfLine("502 unrecognized")
+			}
+		}
+	}()
+	return ln.Addr(), ch
+}
+
+func TestSMTPSend(t *testing.T) {
+	addr, mails := newFakeSMTPServer(t)
+	host, port, _ := net.SplitHostPort(addr.String())
+	ch, err := notifications.CreateFromConfig(map[string]any{
+		"type":     "smtp",
+		"host":     host,
+		"port":     port,
+		"security": "none",
+		"from":     "Shelley <shelley@example.com>",
+		"to":       "a@example.com, Bob <b@example.com>",
+	}, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ch.Send(context.Background(), testEvent); err != nil {
+		t.Fatal(err)
+	}
+	m := <-mails
+
+	if m.from != "shelley@example.com" {
+		t.Errorf("MAIL FROM = %q", m.from)
+	}
+	if strings.Join(m.rcpt, ",") != "a@example.com,b@example.com" {
+		t.Errorf("RCPT TO = %v", m.rcpt)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(m.data))
+	if err != nil {
+		t.Fatalf("parse message: %v\n%s", err, m.data)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if !strings.Contains(subject, "fix-tests") {
+		t.Errorf("Subject = %q", subject)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q", msg.Header.Get("Content-Type"))
+	}
+
+	parts := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		b, _ := io.ReadAll(p) // multipart.Reader decodes quoted-printable
+		parts[ct] = string(b)
+	}
+	if !strings.Contains(parts["text/plain"], `All "green"`) {
+		t.Errorf("text part = %q", parts["text/plain"])
+	}
+	if !strings.Contains(parts["text/html"], "All &#34;green&#34;") {
+		t.Errorf("html part = %q", parts["text/html"])
+	}
+}
+
+func TestSMTPErrors(t *testing.T) {
+	for _, config := range []map[string]any{
+		{"type": "smtp", "from": "a@example.com", "to": "b@example.com"},
+		{"type": "smtp", "host": "mail.example.com", "to": "b@example.com"},
+		{"type": "smtp", "host": "mail.example.com", "from": "a@example.com"},
+		{"type": "smtp", "host": "mail.example.com", "from": "not an address", "to": "b@example.com"},
+		{"type": "smtp", "host": "mail.example.com", "from": "a@example.com", "to": "b@example.com", "security": "ssl3"},
+		{"type": "smtp", "host": "mail.example.com", "from": "a@example.com", "to": "b@example.com", "port": "99999"},
+	} {
+		if _, err := notifications.CreateFromConfig(config, slog.Default()); err == nil {
+			t.Errorf("CreateFromConfig(%v) succeeded, want error", config)
+		}
+	}
+
+	ch, err := notifications.CreateFromConfig(map[string]any{
+		"type": "smtp",
+		"host": "mail.example.com",
+		"from": "a@example.com",
+		"to":   []any{"b@example.com", "c@example.com"},
+	}, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := ch.(*smtpChannel); s.port != 587 || len(s.to) != 2 {
+		t.Errorf("defaults: port=%d to=%v", s.port, s.to)
+	}
+}
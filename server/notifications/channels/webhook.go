@@ -0,0 +1,196 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"shelley.exe.dev/server/notifications"
+)
+
+// defaultSignatureHeader carries the HMAC-SHA256 of the request body, in the
+// same "sha256=<hex>" form GitHub uses for X-Hub-Signature-256.
+const defaultSignatureHeader = "X-Shelley-Signature-256"
+
+func init() {
+	notifications.Register("webhook", func(config map[string]any, logger *slog.Logger) (notifications.Channel, error) {
+		rawURL, _ := config["url"].(string)
+		if rawURL == "" {
+			return nil, fmt.Errorf("webhook channel requires \"url\"")
+		}
+		u, err := url.Parse(rawURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("webhook channel: invalid url %q", rawURL)
+		}
+
+		headers, err := parseWebhookHeaders(config["headers"])
+		if err != nil {
+			return nil, fmt.Errorf("webhook channel: %w", err)
+		}
+
+		w := &webhook{
+			url:             rawURL,
+			headers:         headers,
+			signatureHeader: defaultSignatureHeader,
+			contentType:     "application/json",
+			client: &http.Client{
+				Timeout: 10 * time.Second,
+			},
+		}
+		w.secret, _ = config["secret"].(string)
+		if h, _ := config["signature_header"].(string); h != "" {
+			w.signatureHeader = h
+		}
+		if ct, _ := config["content_type"].(string); ct != "" {
+			w.contentType = ct
+		}
+		if text, _ := config["template"].(string); strings.TrimSpace(text) != "" {
+			tmpl, err := template.New("webhook").Funcs(webhookTemplateFuncs).Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("webhook channel: invalid template: %w", err)
+			}
+			w.template = tmpl
+		}
+		return w, nil
+	})
+}
+
+// parseWebhookHeaders accepts either a JSON object of header names to values
+// (from the config file) or "Name: value" lines (from the settings UI).
+func parseWebhookHeaders(v any) (http.Header, error) {
+	headers := http.Header{}
+	switch v := v.(type) {
+	case nil:
+	case map[string]any:
+		for name, value := range v {
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("header %q must be a string", name)
+			}
+			headers.Set(name, s)
+		}
+	case string:
+		for _, line := range strings.Split(v, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			name, value, ok := strings.Cut(line, ":")
+			if !ok || strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("invalid header line %q (want \"Name: value\")", line)
+			}
+			headers.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+		}
+	default:
+		return nil, fmt.Errorf("headers must be an object or \"Name: value\" lines")
+	}
+	return headers, nil
+}
+
+type webhook struct {
+	url             string
+	headers         http.Header
+	secret          string
+	signatureHeader string
+	contentType     string
+	template        *template.Template
+	client          *http.Client
+}
+
+func (w *webhook) Name() string { return "webhook" }
+
+// webhookTemplateData is the data passed to a webhook body template.
+type webhookTemplateData struct {
+	notifications.Event
+	// Title is a one-line summary such as "Agent finished: fix-tests".
+	Title string
+	// Message is the longer description, such as the final response, if any.
+	Message string
+}
+
+var webhookTemplateFuncs = template.FuncMap{
+	// json encodes a value as JSON, so a template can embed strings safely:
+	// {"text": {{json .Title}}}
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func newWebhookTemplateData(event notifications.Event) webhookTemplateData {
+	data := webhookTemplateData{Event: event}
+	data.Title, data.Message = event.Summary()
+	return data
+}
+
+// body renders the request body: the template if configured, otherwise the event as JSON.
+func (w *webhook) body(event notifications.Event) ([]byte, error) {
+	if w.template == nil {
+		b, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("marshal webhook payload: %w", err)
+		}
+		return b, nil
+	}
+	var buf bytes.Buffer
+	if err := w.template.Execute(&buf, newWebhookTemplateData(event)); err != nil {
+		return nil, fmt.Errorf("render webhook template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// signWebhookBody returns the signature header value for body.
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *webhook) Send(ctx context.Context, event notifications.Event) error {
+	body, err := w.body(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.contentType)
+	req.Header.Set("User-Agent", "Shelley-Webhook")
+	req.Header.Set("X-Shelley-Event", string(event.Type))
+	for name, values := range w.headers {
+		req.Header[name] = values
+	}
+	if w.secret != "" {
+		req.Header.Set(w.signatureHeader, signWebhookBody(w.secret, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if len(b) > 0 {
+			return fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(b))
+		}
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -0,0 +1,136 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/server/notifications"
+)
+
+type capturedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newCaptureServer(t *testing.T, status int) (*httptest.Server, <-chan capturedRequest) {
+	t.Helper()
+	ch := make(chan capturedRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ch <- capturedRequest{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, ch
+}
+
+var testEvent = notifications.Event{
+	Type:           notifications.EventAgentDone,
+	ConversationID: "c123",
+	Timestamp:      time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	Payload: notifications.AgentDonePayload{
+		Model:             "predictable",
+		ConversationTitle: "fix-tests",
+		FinalResponse:     `All "green"`,
+	},
+}
+
+func TestWebhookDefaultBodyAndSignature(t *testing.T) {
+	srv, requests := newCaptureServer(t, http.StatusOK)
+	ch, err := notifications.CreateFromConfig(map[string]any{
+		"type":    "webhook",
+		"url":     srv.URL,
+		"secret":  "s3cret",
+		"headers": "Authorization: Bearer abc\nX-Extra: 1",
+	}, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ch.Send(context.Background(), testEvent); err != nil {
+		t.Fatal(err)
+	}
+	req := <-requests
+
+	var got notifications.Event
+	if err := json.Unmarshal(req.body, &got); err != nil {
+		t.Fatalf("body is not JSON: %v\n%s", err, req.body)
+	}
+	if got.Type != notifications.EventAgentDone || got.ConversationID != "c123" {
+		t.Errorf("unexpected event: %+v", got)
+	}
+	if sig, want := req.header.Get(defaultSignatureHeader), signWebhookBody("s3cret", req.body); sig != want {
+		t.Errorf("signature = %q, want %q", sig, want)
+	}
+	if req.header.Get("Authorization") != "Bearer abc" || req.header.Get("X-Extra") != "1" {
+		t.Errorf("custom headers missing: %v", req.header)
+	}
+	if req.header.Get("X-Shelley-Event") != "agent_done" {
+		t.Errorf("X-Shelley-Event = %q", req.header.Get("X-Shelley-Event"))
+	}
+}
+
+func TestWebhookTemplate(t *testing.T) {
+	srv, requests := newCaptureServer(t, http.StatusOK)
+	ch, err := notifications.CreateFromConfig(map[string]any{
+		"type":             "webhook",
+		"url":              srv.URL,
+		"secret":           "k",
+		"signature_header": "X-Sig",
+		"headers":          map[string]any{"X-Token": "t"},
+		"template":         `{"text": {{json (printf "%s: %s" .Title .Message)}}, "id": {{json .ConversationID}}}`,
+	}, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ch.Send(context.Background(), testEvent); err != nil {
+		t.Fatal(err)
+	}
+	req := <-requests
+
+	var got map[string]string
+	if err := json.Unmarshal(req.body, &got); err != nil {
+		t.Fatalf("rendered body is not JSON: %v\n%s", err, req.body)
+	}
+	if want := `Agent finished: fix-tests: All "green"`; got["text"] != want {
+		t.Errorf("text = %q, want %q", got["text"], want)
+	}
+	if got["id"] != "c123" {
+		t.Errorf("id = %q", got["id"])
+	}
+	if req.header.Get("X-Sig") != signWebhookBody("k", req.body) {
+		t.Errorf("custom signature header missing or wrong")
+	}
+	if req.header.Get("X-Token") != "t" {
+		t.Errorf("X-Token header missing")
+	}
+}
+
+func TestWebhookErrors(t *testing.T) {
+	for _, config := range []map[string]any{
+		{"type": "webhook"},
+		{"type": "webhook", "url": "ftp://example.com"},
+		{"type": "webhook", "url": "https://example.com", "headers": "no colon"},
+		{"type": "webhook", "url": "https://example.com", "template": "{{.Nope"},
+	} {
+		if _, err := notifications.CreateFromConfig(config, slog.Default()); err == nil {
+			t.Errorf("CreateFromConfig(%v) succeeded, want error", config)
+		}
+	}
+
+	srv, _ := newCaptureServer(t, http.StatusBadGateway)
+	ch, err := notifications.CreateFromConfig(map[string]any{"type": "webhook", "url": srv.URL}, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Send(context.Background(), testEvent); err == nil {
+		t.Error("expected error for 502 response")
+	}
+}
@@ -4,12 +4,23 @@ import (
 	"context"
 	"log/slog"
 	"sync"
+	"time"
 )
 
+// sendTimeout bounds a direct Send from Dispatch.
+const sendTimeout = 30 * time.Second
+
+// Outbox stores events for reliable, retried delivery to stored channels.
+type Outbox interface {
+	// Enqueue records that event must be delivered to the channel with channelID.
+	Enqueue(ctx context.Context, channelID string, event Event) error
+}
+
 // Dispatcher routes notification events to registered backend channels.
 type Dispatcher struct {
 	mu       sync.RWMutex
 	channels []Channel
+	outbox   Outbox
 	logger   *slog.Logger
 }
 
@@ -18,6 +29,14 @@ func NewDispatcher(logger *slog.Logger) *Dispatcher {
 	return &Dispatcher{logger: logger}
 }
 
+// SetOutbox makes Dispatch queue events for channels with an ID (see
+// Configured) in outbox instead of sending them directly.
+func (d *Dispatcher) SetOutbox(outbox Outbox) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.outbox = outbox
+}
+
 // Register adds a backend channel to the dispatcher.
 func (d *Dispatcher) Register(ch Channel) {
 	d.mu.Lock()
@@ -41,20 +60,97 @@ func (d *Dispatcher) Channels() []Channel {
 	return result
 }
 
-// Dispatch sends an event to all registered backend channels.
-// It does not block on individual channel failures.
+// Channel returns the registered channel with the given ID, or nil.
+func (d *Dispatcher) Channel(id string) Channel {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, ch := range d.channels {
+		if ChannelID(ch) == id {
+			return ch
+		}
+	}
+	return nil
+}
+
+// ChannelID returns the ID of a channel created with Configured, or "".
+func ChannelID(ch Channel) string {
+	if c, ok := ch.(interface{ ID() string }); ok {
+		return c.ID()
+	}
+	return ""
+}
+
+// accepts reports whether ch's filter accepts event. Channels registered
+// without a filter (see WithFilter) get the DefaultEventTypes.
+func accepts(ch Channel, event Event) bool {
+	if f, ok := ch.(interface{ Accepts(Event) bool }); ok {
+		return f.Accepts(event)
+	}
+	return Filter{}.Match(event)
+}
+
+// allowsReplies reports whether ch's filter lets its events carry a
+// ReplyURL.
+func allowsReplies(ch Channel) bool {
+	f, ok := ch.(interface{ AllowsReplies() bool })
+	return ok && f.AllowsReplies()
+}
+
+// WantsReplies reports whether a channel that allows replies would get
+// event, so that a reply URL is only made when someone can use it.
+func (d *Dispatcher) WantsReplies(event Event) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, ch := range d.channels {
+		if allowsReplies(ch) && accepts(ch, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatch hands an event to every registered backend channel whose filter
+// accepts it, leaving out its ReplyURL for channels that don't allow
+// replies. Channels with an ID are queued in the outbox, if there is one;
+// the rest are sent in the background. Dispatch never waits for a channel
+// to respond.
 func (d *Dispatcher) Dispatch(ctx context.Context, event Event) {
 	d.mu.RLock()
 	channels := d.channels
+	outbox := d.outbox
 	d.mu.RUnlock()
 
 	for _, ch := range channels {
-		if err := ch.Send(ctx, event); err != nil {
-			d.logger.Warn("notification channel failed",
+		if !accepts(ch, event) {
+			continue
+		}
+		event := event
+		if !allowsReplies(ch) {
+			event.ReplyURL = ""
+		}
+		if id := ChannelID(ch); outbox != nil && id != "" {
+			err := outbox.Enqueue(ctx, id, event)
+			if err == nil {
+				continue
+			}
+			d.logger.Warn("failed to queue notification, sending directly",
 				"channel", ch.Name(),
 				"event", string(event.Type),
 				"error", err,
 			)
 		}
+		go d.send(context.WithoutCancel(ctx), ch, event)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, ch Channel, event Event) {
+	ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	if err := ch.Send(ctx, event); err != nil {
+		d.logger.Warn("notification channel failed",
+			"channel", ch.Name(),
+			"event", string(event.Type),
+			"error", err,
+		)
 	}
 }
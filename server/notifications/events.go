@@ -1,6 +1,10 @@
 package notifications
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // EventType identifies the kind of notification event.
 type EventType string
@@ -8,14 +12,106 @@ type EventType string
 const (
 	EventAgentDone  EventType = "agent_done"
 	EventAgentError EventType = "agent_error"
+	// EventToolApprovalNeeded is sent when a tool call is waiting for the user to approve it.
+	EventToolApprovalNeeded EventType = "tool_approval_needed"
+	// EventBudgetThreshold is sent once when a conversation's cost crosses the configured threshold.
+	EventBudgetThreshold EventType = "budget_threshold"
+	// EventSubagentDone is sent when a subagent conversation finishes its turn.
+	EventSubagentDone EventType = "subagent_done"
+	// EventGitCommit is sent when the agent creates a commit in the conversation's repository.
+	EventGitCommit EventType = "git_commit"
+	// EventBashFinished is sent when a bash command that ran longer than the configured threshold finishes.
+	EventBashFinished EventType = "bash_finished"
+	// EventConversationIdle is sent once when a conversation has been waiting for the user for a while.
+	EventConversationIdle EventType = "conversation_idle"
+	// EventUpgradeAvailable is sent once per release when a newer Shelley version is available.
+	EventUpgradeAvailable EventType = "upgrade_available"
 )
 
+// AllEventTypes lists every event type, in the order shown to users.
+var AllEventTypes = []EventType{
+	EventAgentDone,
+	EventAgentError,
+	EventToolApprovalNeeded,
+	EventBudgetThreshold,
+	EventSubagentDone,
+	EventGitCommit,
+	EventBashFinished,
+	EventConversationIdle,
+	EventUpgradeAvailable,
+}
+
+// DefaultEventTypes are the events a channel receives when its config does
+// not list any, which keeps channels created before the other events existed
+// behaving as they did.
+var DefaultEventTypes = []EventType{EventAgentDone, EventAgentError}
+
 // Event is a notification event generated by the system.
 type Event struct {
 	Type           EventType `json:"type"`
 	ConversationID string    `json:"conversation_id"`
 	Timestamp      time.Time `json:"timestamp"`
 	Payload        any       `json:"payload,omitempty"`
+	// Cwd and Tags describe the conversation the event belongs to, so
+	// channels can filter on them. Both are empty for server-wide events.
+	Cwd  string   `json:"cwd,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	// URL links to the conversation in the web UI. ReplyURL, set on
+	// agent_done and agent_error for channels that allow replies, accepts
+	// a POSTed reply ({"message": ...} or plain text) that continues the
+	// conversation. Both need the server's public URL to be configured.
+	URL      string `json:"url,omitempty"`
+	ReplyURL string `json:"reply_url,omitempty"`
+}
+
+// UnmarshalJSON decodes an event, restoring Payload to the payload type for
+// its event type so that queued events look like freshly dispatched ones.
+func (e *Event) UnmarshalJSON(data []byte) error {
+	type plain Event
+	var raw struct {
+		plain
+		Payload json.RawMessage `json:"payload,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*e = Event(raw.plain)
+	if len(raw.Payload) == 0 || string(raw.Payload) == "null" {
+		e.Payload = nil
+		return nil
+	}
+	var err error
+	switch e.Type {
+	case EventAgentDone:
+		e.Payload, err = unmarshalPayload[AgentDonePayload](raw.Payload)
+	case EventAgentError:
+		e.Payload, err = unmarshalPayload[AgentErrorPayload](raw.Payload)
+	case EventToolApprovalNeeded:
+		e.Payload, err = unmarshalPayload[ToolApprovalPayload](raw.Payload)
+	case EventBudgetThreshold:
+		e.Payload, err = unmarshalPayload[BudgetThresholdPayload](raw.Payload)
+	case EventSubagentDone:
+		e.Payload, err = unmarshalPayload[SubagentDonePayload](raw.Payload)
+	case EventGitCommit:
+		e.Payload, err = unmarshalPayload[GitCommitPayload](raw.Payload)
+	case EventBashFinished:
+		e.Payload, err = unmarshalPayload[BashFinishedPayload](raw.Payload)
+	case EventConversationIdle:
+		e.Payload, err = unmarshalPayload[ConversationIdlePayload](raw.Payload)
+	case EventUpgradeAvailable:
+		e.Payload, err = unmarshalPayload[UpgradeAvailablePayload](raw.Payload)
+	default:
+		var v any
+		err = json.Unmarshal(raw.Payload, &v)
+		e.Payload = v
+	}
+	return err
+}
+
+func unmarshalPayload[T any](data json.RawMessage) (any, error) {
+	var p T
+	err := json.Unmarshal(data, &p)
+	return p, err
 }
 
 // AgentDonePayload is the payload for EventAgentDone.
@@ -23,9 +119,129 @@ type AgentDonePayload struct {
 	Model             string `json:"model,omitempty"`
 	ConversationTitle string `json:"conversation_title,omitempty"`
 	FinalResponse     string `json:"final_response,omitempty"`
+	// ScheduleName is set when the conversation was started by a schedule.
+	ScheduleName string `json:"schedule_name,omitempty"`
 }
 
 // AgentErrorPayload is the payload for EventAgentError.
 type AgentErrorPayload struct {
-	ErrorMessage string `json:"error_message"`
+	ConversationTitle string `json:"conversation_title,omitempty"`
+	ErrorMessage      string `json:"error_message"`
+}
+
+// ToolApprovalPayload is the payload for EventToolApprovalNeeded.
+type ToolApprovalPayload struct {
+	ConversationTitle string `json:"conversation_title,omitempty"`
+	ToolName          string `json:"tool_name"`
+	Input             string `json:"input,omitempty"`
+}
+
+// BudgetThresholdPayload is the payload for EventBudgetThreshold.
+type BudgetThresholdPayload struct {
+	ConversationTitle string  `json:"conversation_title,omitempty"`
+	CostUSD           float64 `json:"cost_usd"`
+	ThresholdUSD      float64 `json:"threshold_usd"`
+}
+
+// SubagentDonePayload is the payload for EventSubagentDone. The event's
+// ConversationID is the subagent's; the parent is in ParentConversationID.
+type SubagentDonePayload struct {
+	ParentConversationID string `json:"parent_conversation_id"`
+	SubagentName         string `json:"subagent_name,omitempty"`
+	FinalResponse        string `json:"final_response,omitempty"`
+}
+
+// GitCommitPayload is the payload for EventGitCommit.
+type GitCommitPayload struct {
+	ConversationTitle string `json:"conversation_title,omitempty"`
+	Worktree          string `json:"worktree"`
+	Branch            string `json:"branch,omitempty"`
+	Commit            string `json:"commit"`
+	Subject           string `json:"subject,omitempty"`
+}
+
+// BashFinishedPayload is the payload for EventBashFinished.
+type BashFinishedPayload struct {
+	ConversationTitle string  `json:"conversation_title,omitempty"`
+	Command           string  `json:"command"`
+	DurationSeconds   float64 `json:"duration_seconds"`
+	Failed            bool    `json:"failed"`
+}
+
+// ConversationIdlePayload is the payload for EventConversationIdle.
+type ConversationIdlePayload struct {
+	ConversationTitle string `json:"conversation_title,omitempty"`
+	IdleMinutes       int    `json:"idle_minutes"`
+}
+
+// UpgradeAvailablePayload is the payload for EventUpgradeAvailable.
+type UpgradeAvailablePayload struct {
+	CurrentVersion string `json:"current_version"`
+	LatestVersion  string `json:"latest_version"`
+}
+
+// Title returns a one-line summary of the event, e.g. "Agent finished: fix-tests"
+// or "Scheduled run finished: nightly-triage".
+func (p AgentDonePayload) Title() string {
+	switch {
+	case p.ScheduleName != "":
+		return "Scheduled run finished: " + p.ScheduleName
+	case p.ConversationTitle != "":
+		return "Agent finished: " + p.ConversationTitle
+	default:
+		return "Agent finished"
+	}
+}
+
+// Summary returns a one-line title and an optional longer message for any
+// event. Channels use it to render events they have no special layout for.
+func (e Event) Summary() (title, message string) {
+	withConversation := func(title, conversation string) string {
+		if conversation == "" {
+			return title
+		}
+		return title + ": " + conversation
+	}
+	switch p := e.Payload.(type) {
+	case AgentDonePayload:
+		return p.Title(), p.FinalResponse
+	case AgentErrorPayload:
+		return withConversation("Agent error", p.ConversationTitle), p.ErrorMessage
+	case ToolApprovalPayload:
+		return withConversation("Approval needed", p.ConversationTitle), fmt.Sprintf("%s: %s", p.ToolName, p.Input)
+	case BudgetThresholdPayload:
+		return withConversation("Budget threshold reached", p.ConversationTitle),
+			fmt.Sprintf("Cost $%.2f has reached the $%.2f threshold.", p.CostUSD, p.ThresholdUSD)
+	case SubagentDonePayload:
+		return withConversation("Subagent finished", p.SubagentName), p.FinalResponse
+	case GitCommitPayload:
+		commit := p.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		msg := commit + " " + p.Subject
+		if p.Branch != "" {
+			msg = p.Branch + ": " + msg
+		}
+		return withConversation("Commit created", p.ConversationTitle), msg
+	case BashFinishedPayload:
+		status := "finished"
+		if p.Failed {
+			status = "failed"
+		}
+		return withConversation("Command "+status, p.ConversationTitle),
+			fmt.Sprintf("%s (%s)", p.Command, time.Duration(p.DurationSeconds*float64(time.Second)).Round(time.Second))
+	case ConversationIdlePayload:
+		return withConversation("Waiting for you", p.ConversationTitle),
+			fmt.Sprintf("The agent finished %d minutes ago and is waiting for a reply.", p.IdleMinutes)
+	case UpgradeAvailablePayload:
+		return "Shelley upgrade available", fmt.Sprintf("%s is available (running %s).", p.LatestVersion, p.CurrentVersion)
+	}
+	switch e.Type {
+	case EventAgentDone:
+		return "Agent finished", ""
+	case EventAgentError:
+		return "Agent error", ""
+	}
+	return string(e.Type), ""
 }
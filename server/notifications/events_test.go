@@ -0,0 +1,34 @@
+package notifications
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEventJSONRoundTrip(t *testing.T) {
+	events := []Event{
+		{Type: EventAgentDone, ConversationID: "c1", Cwd: "/src", Tags: []string{"prod"}, Payload: AgentDonePayload{FinalResponse: "done"}},
+		{Type: EventBashFinished, ConversationID: "c1", Payload: BashFinishedPayload{Command: "make", DurationSeconds: 400, Failed: true}},
+		{Type: EventUpgradeAvailable, Payload: UpgradeAvailablePayload{LatestVersion: "v1.2.0"}},
+		{Type: EventConversationIdle, ConversationID: "c2"},
+	}
+	for _, want := range events {
+		want.Timestamp = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+		data, err := json.Marshal(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Event
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("%s: %v", want.Type, err)
+		}
+		again, _ := json.Marshal(got)
+		if string(again) != string(data) {
+			t.Errorf("%s: round trip\n got %s\nwant %s", want.Type, again, data)
+		}
+		if want.Payload != nil && got.Payload != want.Payload {
+			t.Errorf("%s: payload = %#v, want %#v", want.Type, got.Payload, want.Payload)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package notifications
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Filter selects which events a channel receives. It is read from the
+// channel config keys "events", "cwd_globs", "tags" and "allow_replies".
+type Filter struct {
+	// Events lists the accepted event types. Empty means DefaultEventTypes.
+	Events []EventType
+	// CwdGlobs restricts conversation events to conversations whose working
+	// directory, or one of its parents, matches one of the globs.
+	CwdGlobs []string
+	// Tags restricts conversation events to conversations with at least one of the tags.
+	Tags []string
+	// Replies lets the channel's events carry a ReplyURL. Anyone who can
+	// read the channel can use one to message the agent, so it is off
+	// unless the config opts in.
+	Replies bool
+}
+
+// ParseFilter reads a Filter from a channel config. Each key may be a JSON
+// array of strings (from the config file) or a comma-separated string (from
+// the settings UI). "events" may also be "all".
+func ParseFilter(config map[string]any) (Filter, error) {
+	var f Filter
+	events, err := configList(config, "events")
+	if err != nil {
+		return f, err
+	}
+	for _, name := range events {
+		if name == "all" || name == "*" {
+			f.Events = slices.Clone(AllEventTypes)
+			break
+		}
+		if !slices.Contains(AllEventTypes, EventType(name)) {
+			return f, fmt.Errorf("unknown event type %q", name)
+		}
+		f.Events = append(f.Events, EventType(name))
+	}
+
+	if f.CwdGlobs, err = configList(config, "cwd_globs"); err != nil {
+		return f, err
+	}
+	for _, glob := range f.CwdGlobs {
+		if _, err := filepath.Match(glob, ""); err != nil {
+			return f, fmt.Errorf("invalid cwd glob %q: %w", glob, err)
+		}
+	}
+	if f.Tags, err = configList(config, "tags"); err != nil {
+		return f, err
+	}
+	if f.Replies, err = configBool(config, "allow_replies"); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// configBool reads a boolean that may be a JSON bool (from the config file)
+// or "yes"/"no" (from the settings UI).
+func configBool(config map[string]any, key string) (bool, error) {
+	switch v := config[key].(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "", "no", "false":
+			return false, nil
+		case "yes", "true":
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("%q must be yes or no", key)
+}
+
+func configList(config map[string]any, key string) ([]string, error) {
+	var items []string
+	switch v := config[key].(type) {
+	case nil:
+	case string:
+		items = strings.Split(v, ",")
+	case []any:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%q entries must be strings", key)
+			}
+			items = append(items, s)
+		}
+	default:
+		return nil, fmt.Errorf("%q must be a list or a comma-separated string", key)
+	}
+	var result []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// Match reports whether the filter accepts the event. Server-wide events
+// (with no ConversationID) are matched on type alone.
+func (f Filter) Match(event Event) bool {
+	events := f.Events
+	if len(events) == 0 {
+		events = DefaultEventTypes
+	}
+	if !slices.Contains(events, event.Type) {
+		return false
+	}
+	if event.ConversationID == "" {
+		return true
+	}
+	if len(f.Tags) > 0 && !slices.ContainsFunc(f.Tags, func(tag string) bool { return slices.Contains(event.Tags, tag) }) {
+		return false
+	}
+	if len(f.CwdGlobs) > 0 && !slices.ContainsFunc(f.CwdGlobs, func(glob string) bool { return cwdMatches(glob, event.Cwd) }) {
+		return false
+	}
+	return true
+}
+
+// cwdMatches reports whether glob matches cwd or one of its parent
+// directories, so "/home/me/src/*" also covers "/home/me/src/app/cmd".
+func cwdMatches(glob, cwd string) bool {
+	if cwd == "" {
+		return false
+	}
+	glob = filepath.Clean(glob)
+	for dir := filepath.Clean(cwd); ; dir = filepath.Dir(dir) {
+		if ok, _ := filepath.Match(glob, dir); ok {
+			return true
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return false
+		}
+	}
+}
+
+// WithFilter wraps a channel so the Dispatcher only sends it events the
+// filter accepts. Calling Send directly bypasses the filter, which is what
+// "send test" wants.
+func WithFilter(ch Channel, f Filter) Channel {
+	return Configured("", ch, f)
+}
+
+// Configured is like WithFilter for a stored channel: id identifies it so the
+// Dispatcher can queue its deliveries in the Outbox.
+func Configured(id string, ch Channel, f Filter) Channel {
+	return &configuredChannel{Channel: ch, id: id, filter: f}
+}
+
+type configuredChannel struct {
+	Channel
+	id     string
+	filter Filter
+}
+
+func (c *configuredChannel) ID() string { return c.id }
+
+func (c *configuredChannel) Accepts(event Event) bool {
+	return c.filter.Match(event)
+}
+
+func (c *configuredChannel) AllowsReplies() bool {
+	return c.filter.Replies
+}
@@ -0,0 +1,70 @@
+package notifications
+
+import "testing"
+
+func TestFilterMatch(t *testing.T) {
+	event := func(typ EventType, cwd string, tags ...string) Event {
+		return Event{Type: typ, ConversationID: "c1", Cwd: cwd, Tags: tags}
+	}
+	upgrade := Event{Type: EventUpgradeAvailable}
+
+	tests := []struct {
+		name   string
+		config map[string]any
+		event  Event
+		want   bool
+	}{
+		{"default events", nil, event(EventAgentDone, "/src"), true},
+		{"default excludes new events", nil, event(EventGitCommit, "/src"), false},
+		{"listed event", map[string]any{"events": "git_commit, bash_finished"}, event(EventBashFinished, "/src"), true},
+		{"unlisted event", map[string]any{"events": []any{"git_commit"}}, event(EventAgentDone, "/src"), false},
+		{"all", map[string]any{"events": "all"}, event(EventConversationIdle, "/src"), true},
+		{"cwd glob", map[string]any{"cwd_globs": "/home/*/src"}, event(EventAgentDone, "/home/me/src"), true},
+		{"cwd glob covers subdirectories", map[string]any{"cwd_globs": "/home/*/src"}, event(EventAgentDone, "/home/me/src/app/cmd"), true},
+		{"cwd glob mismatch", map[string]any{"cwd_globs": "/home/*/src"}, event(EventAgentDone, "/tmp/src"), false},
+		{"cwd glob without cwd", map[string]any{"cwd_globs": "/home/*/src"}, event(EventAgentDone, ""), false},
+		{"tag", map[string]any{"tags": "prod, nightly"}, event(EventAgentDone, "/src", "nightly"), true},
+		{"tag mismatch", map[string]any{"tags": "prod"}, event(EventAgentDone, "/src", "dev"), false},
+		{"server events skip conversation filters", map[string]any{"events": "all", "tags": "prod", "cwd_globs": "/x"}, upgrade, true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.config)
+		if err != nil {
+			t.Fatalf("%s: ParseFilter: %v", tt.name, err)
+		}
+		if got := f.Match(tt.event); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, config := range []map[string]any{
+		{"events": "agent_done, nope"},
+		{"events": 3.0},
+		{"tags": []any{1.0}},
+		{"cwd_globs": "/src/["},
+		{"allow_replies": "maybe"},
+	} {
+		if _, err := ParseFilter(config); err == nil {
+			t.Errorf("ParseFilter(%v) succeeded, want error", config)
+		}
+	}
+}
+
+func TestParseFilterReplies(t *testing.T) {
+	for config, want := range map[string]map[string]any{
+		"unset": {},
+		"no":    {"allow_replies": "no"},
+		"yes":   {"allow_replies": "yes"},
+		"bool":  {"allow_replies": true},
+	} {
+		f, err := ParseFilter(want)
+		if err != nil {
+			t.Fatalf("%s: %v", config, err)
+		}
+		if got := f.Replies; got != (config == "yes" || config == "bool") {
+			t.Errorf("%s: Replies = %v", config, got)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"shelley.exe.dev/claudetool/onstart"
+	"shelley.exe.dev/gitstate"
+	"tailscale.com/util/singleflight"
+)
+
+const (
+	// repoMapBudget is the most tokens the repository map takes up in
+	// the system prompt.
+	repoMapBudget = 1500
+	// repoMapTimeout bounds how long building a repository map may take.
+	repoMapTimeout = time.Minute
+)
+
+// repoMapBuilds makes conversations in the same repository share one
+// build of its map.
+var repoMapBuilds singleflight.Group[string, struct{}]
+
+// repoMap returns the cached map of the repository in state. Reading a
+// large repository takes a while, so if the map of its commit isn't cached
+// yet, repoMap starts building it for the conversations that come after
+// and returns "", as it does outside a repository.
+func (cm *ConversationManager) repoMap(ctx context.Context, state *gitstate.GitState) string {
+	if state == nil || !state.IsRepo || state.Commit == "" {
+		return ""
+	}
+	content, ok, err := cm.db.GetRepoMap(ctx, state.Worktree, state.Commit)
+	if err != nil {
+		cm.logger.Warn("Failed to read cached repository map", "error", err)
+		return ""
+	}
+	if !ok {
+		go cm.refreshRepoMap(state)
+	}
+	return content
+}
+
+// refreshRepoMap builds and caches the map of the repository in state,
+// unless it is already cached.
+func (cm *ConversationManager) refreshRepoMap(state *gitstate.GitState) {
+	if state == nil || !state.IsRepo || state.Commit == "" {
+		return
+	}
+	repoMapBuilds.Do(state.Worktree+"@"+state.Commit, func() (struct{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), repoMapTimeout)
+		defer cancel()
+		if _, ok, err := cm.db.GetRepoMap(ctx, state.Worktree, state.Commit); err != nil || ok {
+			return struct{}{}, nil
+		}
+		start := time.Now()
+		content, err := onstart.RepoMap(ctx, state.Worktree, repoMapBudget)
+		if err != nil {
+			cm.logger.Warn("Failed to build repository map", "worktree", state.Worktree, "error", err)
+			return struct{}{}, nil
+		}
+		if err := cm.db.SaveRepoMap(ctx, state.Worktree, state.Commit, content); err != nil {
+			cm.logger.Warn("Failed to cache repository map", "error", err)
+			return struct{}{}, nil
+		}
+		cm.logger.Info("Built repository map", "worktree", state.Worktree, "commit", state.Commit, "length", len(content), "elapsed", time.Since(start))
+		return struct{}{}, nil
+	})
+}
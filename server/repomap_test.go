@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/claudetool"
+	"shelley.exe.dev/gitstate"
+)
+
+func TestRepoMapCachedPerCommit(t *testing.T) {
+	database, cleanup := setupTestDB(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	dir := setupTestGitRepo(t)
+	commit := func(name, content string) *gitstate.GitState {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cmd := exec.Command("sh", "-c", "git add -A && git commit -q -m update")
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git commit: %v\n%s", err, out)
+		}
+		return gitstate.GetGitState(dir)
+	}
+
+	cm := NewConversationManager("conv", database, nil, claudetool.ToolSetConfig{}, nil, nil)
+	// The first conversation at a commit starts building its map, and
+	// the ones after get it.
+	mapAt := func(state *gitstate.GitState) string {
+		t.Helper()
+		cm.repoMap(ctx, state)
+		var got string
+		waitFor(t, 10*time.Second, func() bool {
+			got = cm.repoMap(ctx, state)
+			return got != ""
+		})
+		return got
+	}
+	first := commit("main.go", "package main\n\nfunc main() {}\n")
+	if got := mapAt(first); !strings.Contains(got, "main.go: func main") {
+		t.Fatalf("repo map of the first commit:\n%s", got)
+	}
+
+	// A new HEAD gets a new map.
+	second := commit("tool.py", "if __name__ == \"__main__\":\n    pass\n")
+	if got := cm.repoMap(ctx, second); got != "" {
+		t.Errorf("repo map of a new commit = %q before it was built", got)
+	}
+	cm.refreshRepoMap(second)
+	if got := cm.repoMap(ctx, second); !strings.Contains(got, "tool.py") {
+		t.Fatalf("repo map of the second commit:\n%s", got)
+	}
+	if got := cm.repoMap(ctx, first); !strings.Contains(got, "main.go") || strings.Contains(got, "tool.py") {
+		t.Errorf("repo map of the first commit changed:\n%s", got)
+	}
+
+	if got := cm.repoMap(ctx, gitstate.GetGitState(t.TempDir())); got != "" {
+		t.Errorf("repo map outside a repository = %q", got)
+	}
+}
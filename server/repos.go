@@ -0,0 +1,115 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// RepoConversation is a conversation entry within a RepoSummary.
+type RepoConversation struct {
+	ConversationID string    `json:"conversation_id"`
+	Slug           *string   `json:"slug"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Worktree       string    `json:"worktree,omitempty"`
+	Branches       []string  `json:"branches,omitempty"`
+	Working        bool      `json:"working"`
+}
+
+// RepoSummary groups the conversations that worked in one git repository,
+// including all of its worktrees.
+type RepoSummary struct {
+	RepoRoot          string             `json:"repo_root"`
+	ConversationCount int                `json:"conversation_count"`
+	LastUpdatedAt     time.Time          `json:"last_updated_at"`
+	Worktrees         []string           `json:"worktrees"`
+	Branches          []string           `json:"branches"`
+	Conversations     []RepoConversation `json:"conversations"`
+}
+
+// handleRepos handles GET /api/repos. It lists repositories with their
+// non-archived conversations, most recently active first.
+//
+//	repo=DIR     only the repository rooted at DIR
+//	branch=NAME  only conversations that have been on branch NAME
+func (s *Server) handleRepos(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	ctx := r.Context()
+	branch := r.URL.Query().Get("branch")
+
+	conversations, err := s.db.ListRepoConversations(ctx, r.URL.Query().Get("repo"))
+	if err != nil {
+		s.logger.Error("Failed to list repo conversations", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	branches, err := s.db.GetAllConversationBranches(ctx)
+	if err != nil {
+		s.logger.Error("Failed to get conversation branches", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	workingStates := s.getWorkingConversations()
+
+	// Conversations arrive ordered by updated_at DESC, so repos are created
+	// in order of their most recent activity.
+	result := []*RepoSummary{}
+	byRoot := make(map[string]*RepoSummary)
+	visible := s.conversationVisibility(ctx, s.viewer(r))
+	for _, conv := range conversations {
+		if !visible(conv.ConversationID, &conv) {
+			continue
+		}
+		convBranches := branches[conv.ConversationID]
+		if branch != "" && !slices.Contains(convBranches, branch) {
+			continue
+		}
+		root := *conv.RepoRoot
+		repo, ok := byRoot[root]
+		if !ok {
+			repo = &RepoSummary{
+				RepoRoot:      root,
+				LastUpdatedAt: conv.UpdatedAt,
+				Worktrees:     []string{},
+				Branches:      []string{},
+			}
+			byRoot[root] = repo
+			result = append(result, repo)
+		}
+
+		rc := RepoConversation{
+			ConversationID: conv.ConversationID,
+			Slug:           conv.Slug,
+			UpdatedAt:      conv.UpdatedAt,
+			Branches:       convBranches,
+			Working:        workingStates[conv.ConversationID],
+		}
+		if conv.RepoWorktree != nil {
+			rc.Worktree = *conv.RepoWorktree
+			if !slices.Contains(repo.Worktrees, rc.Worktree) {
+				repo.Worktrees = append(repo.Worktrees, rc.Worktree)
+			}
+		}
+		for _, b := range convBranches {
+			if !slices.Contains(repo.Branches, b) {
+				repo.Branches = append(repo.Branches, b)
+			}
+		}
+		repo.Conversations = append(repo.Conversations, rc)
+		repo.ConversationCount++
+	}
+
+	for _, repo := range result {
+		slices.Sort(repo.Worktrees)
+		slices.Sort(repo.Branches)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
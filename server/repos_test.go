@@ -0,0 +1,87 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleRepos(t *testing.T) {
+	h := NewTestHarness(t)
+	ctx := t.Context()
+
+	a, err := h.db.CreateConversation(ctx, nil, true, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	b, err := h.db.CreateConversation(ctx, nil, true, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	other, err := h.db.CreateConversation(ctx, nil, true, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create conversation: %v", err)
+	}
+	for _, rec := range []struct{ id, root, worktree, branch string }{
+		{a.ConversationID, "/src/app", "/src/app", "main"},
+		{b.ConversationID, "/src/app", "/src/app-login", "feature/login"},
+		{other.ConversationID, "/src/lib", "/src/lib", "main"},
+	} {
+		if err := h.db.RecordConversationGitState(ctx, rec.id, rec.root, rec.worktree, rec.branch); err != nil {
+			t.Fatalf("RecordConversationGitState() error = %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+
+	get := func(path string) []RepoSummary {
+		t.Helper()
+		req := httptest.NewRequest("GET", path, nil)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var repos []RepoSummary
+		if err := json.NewDecoder(rec.Body).Decode(&repos); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return repos
+	}
+
+	repos := get("/api/repos")
+	if len(repos) != 2 {
+		t.Fatalf("Expected 2 repos, got %+v", repos)
+	}
+	var app *RepoSummary
+	for i := range repos {
+		if repos[i].RepoRoot == "/src/app" {
+			app = &repos[i]
+		}
+	}
+	if app == nil {
+		t.Fatalf("Expected /src/app in %+v", repos)
+	}
+	if app.ConversationCount != 2 {
+		t.Errorf("Expected 2 conversations in /src/app, got %d", app.ConversationCount)
+	}
+	if got := strings.Join(app.Worktrees, ","); got != "/src/app,/src/app-login" {
+		t.Errorf("Expected both worktrees, got %q", got)
+	}
+	if got := strings.Join(app.Branches, ","); got != "feature/login,main" {
+		t.Errorf("Expected both branches, got %q", got)
+	}
+
+	repos = get("/api/repos?branch=feature/login")
+	if len(repos) != 1 || repos[0].ConversationCount != 1 || repos[0].Conversations[0].ConversationID != b.ConversationID {
+		t.Errorf("Expected only the feature branch conversation, got %+v", repos)
+	}
+
+	repos = get("/api/repos?repo=/src/lib")
+	if len(repos) != 1 || repos[0].RepoRoot != "/src/lib" {
+		t.Errorf("Expected only /src/lib, got %+v", repos)
+	}
+}
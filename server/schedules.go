@@ -0,0 +1,409 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/llm"
+	"shelley.exe.dev/slug"
+)
+
+// Overlap policies for a schedule whose previous run is still working.
+const (
+	scheduleOverlapSkip  = "skip"  // drop the new run
+	scheduleOverlapQueue = "queue" // start it once the previous run finishes
+)
+
+// maxQueuedScheduleRuns bounds how many runs a "queue" schedule can accumulate.
+const maxQueuedScheduleRuns = 5
+
+// scheduleTickInterval is how often the scheduler looks for due schedules.
+const scheduleTickInterval = 30 * time.Second
+
+// scheduleRunsLimit bounds the runs returned by GET /api/schedules/<id>/runs.
+const scheduleRunsLimit = 50
+
+// ScheduleRequest is the body of POST /api/schedules and PUT /api/schedules/<id>.
+type ScheduleRequest struct {
+	Name          string `json:"name"`
+	Cron          string `json:"cron"`
+	Timezone      string `json:"timezone,omitempty"` // IANA name; empty means server local time
+	Prompt        string `json:"prompt"`
+	Cwd           string `json:"cwd,omitempty"`
+	Model         string `json:"model,omitempty"`
+	Enabled       *bool  `json:"enabled,omitempty"`        // defaults to true
+	OverlapPolicy string `json:"overlap_policy,omitempty"` // "skip" (default) or "queue"
+}
+
+// validate checks the request and fills in defaults.
+func (req *ScheduleRequest) validate() error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		return errors.New("prompt is required")
+	}
+	if _, err := parseCron(req.Cron); err != nil {
+		return fmt.Errorf("invalid cron: %w", err)
+	}
+	if _, err := time.LoadLocation(req.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone: %w", err)
+	}
+	switch req.OverlapPolicy {
+	case "":
+		req.OverlapPolicy = scheduleOverlapSkip
+	case scheduleOverlapSkip, scheduleOverlapQueue:
+	default:
+		return fmt.Errorf("invalid overlap_policy %q (want %q or %q)", req.OverlapPolicy, scheduleOverlapSkip, scheduleOverlapQueue)
+	}
+	if req.Enabled == nil {
+		enabled := true
+		req.Enabled = &enabled
+	}
+	return nil
+}
+
+// nextScheduleRun returns when a schedule fires next after t, or nil if it never does.
+func nextScheduleRun(cronExpr, timezone string, t time.Time) (*time.Time, error) {
+	c, err := parseCron(cronExpr)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.Local
+	if timezone != "" {
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, err
+		}
+	}
+	next := c.Next(t.In(loc))
+	if next.IsZero() {
+		return nil, nil
+	}
+	next = next.UTC()
+	return &next, nil
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// handleSchedules handles GET and POST /api/schedules
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		schedules, err := s.db.ListSchedules(r.Context())
+		if err != nil {
+			s.logger.Error("Failed to list schedules", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(schedules)
+	case http.MethodPost:
+		s.handleCreateSchedule(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	nextRunAt, err := nextScheduleRun(req.Cron, req.Timezone, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := s.db.CreateSchedule(r.Context(), generated.CreateScheduleParams{
+		ScheduleID:    "sched-" + uuid.New().String()[:8],
+		Name:          req.Name,
+		Cron:          req.Cron,
+		Timezone:      req.Timezone,
+		Prompt:        req.Prompt,
+		Cwd:           optionalString(req.Cwd),
+		Model:         optionalString(req.Model),
+		Enabled:       *req.Enabled,
+		OverlapPolicy: req.OverlapPolicy,
+		NextRunAt:     nextRunAt,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create schedule", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// handleSchedule handles /api/schedules/<id>, /api/schedules/<id>/run and
+// /api/schedules/<id>/runs
+func (s *Server) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/schedules/")
+	scheduleID, action, _ := strings.Cut(path, "/")
+	if scheduleID == "" || strings.Contains(action, "/") {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := s.db.GetSchedule(r.Context(), scheduleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get schedule", "scheduleID", scheduleID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(schedule)
+	case action == "" && r.Method == http.MethodPut:
+		s.handleUpdateSchedule(w, r, schedule)
+	case action == "" && r.Method == http.MethodDelete:
+		if err := s.db.DeleteSchedule(r.Context(), scheduleID); err != nil {
+			s.logger.Error("Failed to delete schedule", "scheduleID", scheduleID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case action == "run" && r.Method == http.MethodPost:
+		s.handleRunScheduleNow(w, r, schedule)
+	case action == "runs" && r.Method == http.MethodGet:
+		runs, err := s.db.ListScheduleConversations(r.Context(), scheduleID, scheduleRunsLimit)
+		if err != nil {
+			s.logger.Error("Failed to list schedule runs", "scheduleID", scheduleID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		working := s.getWorkingConversations()
+		result := make([]ConversationWithState, len(runs))
+		for i, conv := range runs {
+			result[i] = ConversationWithState{Conversation: conv, Working: working[conv.ConversationID]}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	case action == "" || action == "run" || action == "runs":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+func (s *Server) handleUpdateSchedule(w http.ResponseWriter, r *http.Request, existing *generated.Schedule) {
+	var req ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	nextRunAt, err := nextScheduleRun(req.Cron, req.Timezone, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pendingRuns := existing.PendingRuns
+	if !*req.Enabled || req.OverlapPolicy != scheduleOverlapQueue {
+		pendingRuns = 0
+	}
+
+	schedule, err := s.db.UpdateSchedule(r.Context(), generated.UpdateScheduleParams{
+		Name:          req.Name,
+		Cron:          req.Cron,
+		Timezone:      req.Timezone,
+		Prompt:        req.Prompt,
+		Cwd:           optionalString(req.Cwd),
+		Model:         optionalString(req.Model),
+		Enabled:       *req.Enabled,
+		OverlapPolicy: req.OverlapPolicy,
+		NextRunAt:     nextRunAt,
+		PendingRuns:   pendingRuns,
+		ScheduleID:    existing.ScheduleID,
+	})
+	if err != nil {
+		s.logger.Error("Failed to update schedule", "scheduleID", existing.ScheduleID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(schedule)
+}
+
+// handleRunScheduleNow starts a run immediately without changing when the
+// schedule fires next. It fails with 409 if the previous run is still working.
+func (s *Server) handleRunScheduleNow(w http.ResponseWriter, r *http.Request, schedule *generated.Schedule) {
+	if schedule.LastConversationID != nil && s.IsAgentWorking(*schedule.LastConversationID) {
+		http.Error(w, "Previous run is still working", http.StatusConflict)
+		return
+	}
+
+	conversation, err := s.startScheduleRun(r.Context(), schedule, time.Now(), schedule.NextRunAt, schedule.PendingRuns)
+	if err != nil {
+		s.logger.Error("Failed to start schedule run", "scheduleID", schedule.ScheduleID, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":          "accepted",
+		"conversation_id": conversation.ConversationID,
+	})
+}
+
+// scheduleRoutine starts due schedule runs until the server shuts down.
+func (s *Server) scheduleRoutine() {
+	ticker := time.NewTicker(scheduleTickInterval)
+	defer ticker.Stop()
+	for {
+		s.runDueSchedules(context.Background(), time.Now())
+		select {
+		case <-s.shutdownCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDueSchedules starts a run for every enabled schedule that is due at now
+// or has a queued run waiting. Occurrences missed while the server was down
+// collapse into a single run.
+func (s *Server) runDueSchedules(ctx context.Context, now time.Time) {
+	schedules, err := s.db.ListEnabledSchedules(ctx)
+	if err != nil {
+		s.logger.Error("Failed to list schedules", "error", err)
+		return
+	}
+
+	for i := range schedules {
+		schedule := &schedules[i]
+		due := schedule.NextRunAt != nil && !schedule.NextRunAt.After(now)
+		if !due && schedule.PendingRuns == 0 {
+			continue
+		}
+
+		nextRunAt := schedule.NextRunAt
+		pendingRuns := schedule.PendingRuns
+		if due {
+			if nextRunAt, err = nextScheduleRun(schedule.Cron, schedule.Timezone, now); err != nil {
+				s.logger.Error("Invalid schedule", "scheduleID", schedule.ScheduleID, "error", err)
+				nextRunAt = nil
+			}
+		}
+
+		if schedule.LastConversationID != nil && s.IsAgentWorking(*schedule.LastConversationID) {
+			if !due {
+				continue
+			}
+			if schedule.OverlapPolicy == scheduleOverlapQueue && pendingRuns < maxQueuedScheduleRuns {
+				pendingRuns++
+				s.logger.Info("Previous schedule run still working; queueing", "scheduleID", schedule.ScheduleID, "pending", pendingRuns)
+			} else {
+				s.logger.Info("Previous schedule run still working; skipping", "scheduleID", schedule.ScheduleID)
+			}
+			if err := s.db.UpdateScheduleNextRun(ctx, schedule.ScheduleID, nextRunAt, pendingRuns); err != nil {
+				s.logger.Error("Failed to update schedule", "scheduleID", schedule.ScheduleID, "error", err)
+			}
+			continue
+		}
+
+		if !due {
+			pendingRuns--
+		}
+		if _, err := s.startScheduleRun(ctx, schedule, now, nextRunAt, pendingRuns); err != nil {
+			s.logger.Error("Failed to start schedule run", "scheduleID", schedule.ScheduleID, "error", err)
+			// Move on so a broken schedule doesn't retry on every tick.
+			if err := s.db.UpdateScheduleNextRun(ctx, schedule.ScheduleID, nextRunAt, pendingRuns); err != nil {
+				s.logger.Error("Failed to update schedule", "scheduleID", schedule.ScheduleID, "error", err)
+			}
+		}
+	}
+}
+
+// startScheduleRun creates a conversation for the schedule, sends its prompt,
+// and records the run. nextRunAt and pendingRuns are stored on the schedule.
+// The result is delivered through the notification dispatcher when the agent
+// finishes, like any other conversation.
+func (s *Server) startScheduleRun(ctx context.Context, schedule *generated.Schedule, now time.Time, nextRunAt *time.Time, pendingRuns int64) (*generated.Conversation, error) {
+	modelID := s.defaultModel
+	if schedule.Model != nil && *schedule.Model != "" {
+		modelID = *schedule.Model
+	}
+	if modelID == "" && s.predictableOnly {
+		modelID = "predictable"
+	}
+	llmService, err := s.llmManager.GetService(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported model %s: %w", modelID, err)
+	}
+
+	now = now.UTC()
+	conversation, err := s.db.CreateScheduleRun(ctx, schedule.ScheduleID, schedule.Cwd, modelID, now, nextRunAt, pendingRuns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create conversation: %w", err)
+	}
+	conversationID := conversation.ConversationID
+	s.logger.Info("Starting schedule run", "scheduleID", schedule.ScheduleID, "conversationID", conversationID)
+
+	go s.publishConversationListUpdate(ConversationListUpdate{
+		Type:         "update",
+		Conversation: conversation,
+	})
+
+	manager, err := s.getOrCreateConversationManager(ctx, conversationID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation manager: %w", err)
+	}
+
+	userMessage := llm.Message{
+		Role:    llm.MessageRoleUser,
+		Content: []llm.Content{{Type: llm.ContentTypeText, Text: schedule.Prompt}},
+	}
+	if _, err := manager.AcceptUserMessage(ctx, llmService, modelID, userMessage); err != nil {
+		return nil, fmt.Errorf("failed to accept user message: %w", err)
+	}
+
+	ctxNoCancel := context.WithoutCancel(ctx)
+	go func() {
+		slugCtx, cancel := context.WithTimeout(ctxNoCancel, 15*time.Second)
+		defer cancel()
+		if _, err := slug.GenerateSlug(slugCtx, s.llmManager, s.db, s.logger, conversationID, schedule.Name, modelID); err != nil {
+			s.logger.Warn("Failed to generate slug for schedule run", "conversationID", conversationID, "error", err)
+		} else {
+			go s.notifySubscribers(ctxNoCancel, conversationID)
+		}
+	}()
+
+	return conversation, nil
+}
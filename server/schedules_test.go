@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/db/generated"
+)
+
+func TestSchedulesAPI(t *testing.T) {
+	h := NewTestHarness(t)
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/api/schedules", `{"name":"triage","cron":"0 9 * * 1-5","timezone":"UTC","prompt":"echo: hello"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	var schedule generated.Schedule
+	if err := json.Unmarshal(w.Body.Bytes(), &schedule); err != nil {
+		t.Fatal(err)
+	}
+	if !schedule.Enabled || schedule.OverlapPolicy != "skip" {
+		t.Errorf("defaults: enabled=%v overlap_policy=%q", schedule.Enabled, schedule.OverlapPolicy)
+	}
+	if schedule.NextRunAt == nil || schedule.NextRunAt.Hour() != 9 || schedule.NextRunAt.Minute() != 0 {
+		t.Errorf("next_run_at = %v, want 09:00 UTC", schedule.NextRunAt)
+	}
+
+	for _, body := range []string{
+		`{"cron":"0 9 * * *","prompt":"x"}`,
+		`{"name":"x","cron":"0 9 * *","prompt":"x"}`,
+		`{"name":"x","cron":"0 9 * * *"}`,
+		`{"name":"x","cron":"0 9 * * *","prompt":"x","timezone":"Mars/Base"}`,
+		`{"name":"x","cron":"0 9 * * *","prompt":"x","overlap_policy":"replace"}`,
+	} {
+		if w := do("POST", "/api/schedules", body); w.Code != http.StatusBadRequest {
+			t.Errorf("create %s: got %d, want 400", body, w.Code)
+		}
+	}
+
+	w = do("PUT", "/api/schedules/"+schedule.ScheduleID, `{"name":"triage","cron":"@hourly","prompt":"echo: hello","enabled":false,"overlap_policy":"queue"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+	var updated generated.Schedule
+	json.Unmarshal(w.Body.Bytes(), &updated)
+	if updated.Enabled || updated.OverlapPolicy != "queue" || updated.Cron != "@hourly" {
+		t.Errorf("update not applied: %+v", updated)
+	}
+
+	w = do("POST", "/api/schedules/"+schedule.ScheduleID+"/run", "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("run: %d %s", w.Code, w.Body.String())
+	}
+	var run struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &run)
+	waitForIdle(t, h.server, run.ConversationID)
+
+	w = do("GET", "/api/schedules/"+schedule.ScheduleID+"/runs", "")
+	var runs []ConversationWithState
+	json.Unmarshal(w.Body.Bytes(), &runs)
+	if len(runs) != 1 || runs[0].ConversationID != run.ConversationID {
+		t.Fatalf("runs = %+v, want the manual run", runs)
+	}
+	if runs[0].ScheduleID == nil || *runs[0].ScheduleID != schedule.ScheduleID {
+		t.Errorf("run schedule_id = %v", runs[0].ScheduleID)
+	}
+
+	w = do("GET", "/api/schedules", "")
+	var list []generated.Schedule
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list) != 1 || list[0].LastConversationID == nil || *list[0].LastConversationID != run.ConversationID {
+		t.Errorf("list = %+v", list)
+	}
+
+	if w := do("DELETE", "/api/schedules/"+schedule.ScheduleID, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d", w.Code)
+	}
+	if w := do("GET", "/api/schedules/"+schedule.ScheduleID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("get after delete: %d, want 404", w.Code)
+	}
+	conv, err := h.db.GetConversationByID(context.Background(), run.ConversationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conv.ScheduleID != nil {
+		t.Errorf("run still attached to deleted schedule")
+	}
+}
+
+func TestRunDueSchedules(t *testing.T) {
+	for _, policy := range []string{"skip", "queue"} {
+		t.Run(policy, func(t *testing.T) {
+			h := NewTestHarness(t)
+			ctx := context.Background()
+
+			now := time.Now().UTC()
+			due := now.Add(-time.Minute)
+			schedule, err := h.db.CreateSchedule(ctx, generated.CreateScheduleParams{
+				ScheduleID:    "sched-test",
+				Name:          "slow",
+				Cron:          "* * * * *",
+				Prompt:        "bash: sleep 2",
+				Enabled:       true,
+				OverlapPolicy: policy,
+				NextRunAt:     &due,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// First tick starts a run.
+			h.server.runDueSchedules(ctx, now)
+			schedule, _ = h.db.GetSchedule(ctx, schedule.ScheduleID)
+			if schedule.LastConversationID == nil {
+				t.Fatal("expected a run to start")
+			}
+			first := *schedule.LastConversationID
+			if !schedule.NextRunAt.After(now) {
+				t.Errorf("next_run_at %v not advanced past %v", schedule.NextRunAt, now)
+			}
+
+			// Second tick while the first run is working.
+			later := schedule.NextRunAt.Add(time.Second)
+			h.server.runDueSchedules(ctx, later)
+			schedule, _ = h.db.GetSchedule(ctx, schedule.ScheduleID)
+			if *schedule.LastConversationID != first {
+				t.Fatal("started a second run while the first was working")
+			}
+			wantPending := int64(0)
+			if policy == "queue" {
+				wantPending = 1
+			}
+			if schedule.PendingRuns != wantPending {
+				t.Errorf("pending_runs = %d, want %d", schedule.PendingRuns, wantPending)
+			}
+
+			// Once the first run finishes, a queued run starts on the next tick.
+			waitForIdle(t, h.server, first)
+			h.server.runDueSchedules(ctx, later)
+			schedule, _ = h.db.GetSchedule(ctx, schedule.ScheduleID)
+			started := *schedule.LastConversationID != first
+			if started != (policy == "queue") {
+				t.Errorf("queued run started = %v", started)
+			}
+			if schedule.PendingRuns != 0 {
+				t.Errorf("pending_runs = %d after draining", schedule.PendingRuns)
+			}
+			waitForIdle(t, h.server, *schedule.LastConversationID)
+		})
+	}
+}
+
+func waitForIdle(t *testing.T, s *Server, conversationID string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for s.IsAgentWorking(conversationID) {
+		if time.Now().After(deadline) {
+			t.Fatalf("conversation %s still working", conversationID)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
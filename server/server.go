@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -23,8 +24,10 @@ import (
 	"shelley.exe.dev/claudetool"
 	"shelley.exe.dev/db"
 	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/gitstate"
 	"shelley.exe.dev/llm"
 	"shelley.exe.dev/models"
+	"shelley.exe.dev/redact"
 	"shelley.exe.dev/server/notifications"
 	"shelley.exe.dev/ui"
 )
@@ -55,12 +58,14 @@ type ConversationState struct {
 // ConversationWithState combines a conversation with its working state.
 type ConversationWithState struct {
 	generated.Conversation
-	Working         bool   `json:"working"`
-	GitRepoRoot     string `json:"git_repo_root,omitempty"`
-	GitWorktreeRoot string `json:"git_worktree_root,omitempty"`
-	GitCommit       string `json:"git_commit,omitempty"`
-	GitSubject      string `json:"git_subject,omitempty"`
-	SubagentCount   int64  `json:"subagent_count"`
+	Working         bool     `json:"working"`
+	GitRepoRoot     string   `json:"git_repo_root,omitempty"`
+	GitWorktreeRoot string   `json:"git_worktree_root,omitempty"`
+	GitCommit       string   `json:"git_commit,omitempty"`
+	GitSubject      string   `json:"git_subject,omitempty"`
+	SubagentCount   int64    `json:"subagent_count"`
+	Tags            []string `json:"tags,omitempty"`
+	Branches        []string `json:"branches,omitempty"`
 }
 
 // StreamResponse represents the response format for conversation streaming
@@ -75,6 +80,8 @@ type StreamResponse struct {
 	Heartbeat bool `json:"heartbeat,omitempty"`
 	// NotificationEvent is set when a notification-worthy event occurs (e.g. agent finished).
 	NotificationEvent *notifications.Event `json:"notification_event,omitempty"`
+	// ToolProgress is set when a running tool call has produced output.
+	ToolProgress *ToolProgress `json:"tool_progress,omitempty"`
 }
 
 // LLMProvider is an interface for getting LLM services
@@ -213,6 +220,7 @@ type ConversationListUpdate struct {
 	ConversationID  string                  `json:"conversation_id,omitempty"` // For deletes
 	GitRepoRoot     string                  `json:"git_repo_root,omitempty"`
 	GitWorktreeRoot string                  `json:"git_worktree_root,omitempty"`
+	Tags            []string                `json:"tags,omitempty"`
 }
 
 // Server manages the HTTP API and active conversations
@@ -233,6 +241,24 @@ type Server struct {
 	notifDispatcher     *notifications.Dispatcher
 	shutdownCh          chan struct{} // Signals background routines to stop
 	listenPort          int           // TCP port the server is listening on
+
+	idleMu       sync.Mutex
+	idleNotified map[string]time.Time // conversation ID -> updated_at when conversation_idle was sent
+
+	deliveries *deliveryWorker
+
+	triggerCallbacksMu sync.Mutex
+	triggerCallbacks   map[string]string // conversation ID -> trigger to call back when the turn ends
+
+	auth *AuthConfig // nil unless built-in authentication is enabled
+	oidc oidcDiscovery
+
+	multiUser bool            // conversations are limited to their owners and shares
+	admins    map[string]bool // emails that may change server-wide settings and budgets
+
+	redactor *redact.Redactor // nil unless secret redaction is enabled
+
+	jobs *claudetool.JobManager // background bash jobs of all conversations
 }
 
 // NewServer creates a new server instance
@@ -251,16 +277,70 @@ func NewServer(database *db.DB, llmManager LLMProvider, toolSetConfig claudetool
 		versionChecker:      NewVersionChecker(),
 		notifDispatcher:     notifications.NewDispatcher(logger),
 		shutdownCh:          make(chan struct{}),
+		idleNotified:        make(map[string]time.Time),
+		deliveries:          newDeliveryWorker(),
+		triggerCallbacks:    make(map[string]string),
+		jobs:                claudetool.NewJobManager(),
 	}
+	s.notifDispatcher.SetOutbox(notificationOutbox{s})
+
+	// Background jobs outlive conversation loops, which are stopped when
+	// idle; they are stopped when the conversation is archived or deleted.
+	s.toolSetConfig.Jobs = s.jobs
 
 	// Set up subagent support
 	s.toolSetConfig.SubagentRunner = NewSubagentRunner(s)
 	s.toolSetConfig.SubagentDB = &db.SubagentDBAdapter{DB: database}
 	s.toolSetConfig.MaxSubagentDepth = 1 // Only top-level conversations can spawn subagents
+	s.toolSetConfig.Audit = func(rec claudetool.AuditRecord) { s.recordAudit(rec, "") }
 
 	return s
 }
 
+// redactionKeySetting is the setting holding the hex-encoded key that
+// redaction placeholders are derived from. It is kept so that placeholders
+// stay the same across restarts, and is never returned by the settings API.
+const redactionKeySetting = "redaction_key"
+
+// EnableRedaction makes conversations redact secrets from tool results
+// before they are recorded or sent to the LLM. Unless cfg has a key, the
+// one stored in the database is used, and created if there is none.
+func (s *Server) EnableRedaction(cfg redact.Config) error {
+	if !cfg.Disabled && len(cfg.Key) == 0 {
+		key, err := s.redactionKey(context.Background())
+		if err != nil {
+			return err
+		}
+		cfg.Key = key
+	}
+	r, err := redact.New(cfg)
+	if err != nil {
+		return err
+	}
+	s.redactor = r
+	return nil
+}
+
+// redactionKey returns the stored redaction key, storing a new one if there
+// is none.
+func (s *Server) redactionKey(ctx context.Context) ([]byte, error) {
+	value, err := s.db.GetSetting(ctx, redactionKeySetting)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redaction key: %w", err)
+	}
+	if key, err := hex.DecodeString(value); err == nil && len(key) > 0 {
+		return key, nil
+	}
+	if value != "" {
+		s.logger.Warn("Replacing invalid redaction key")
+	}
+	key := redact.NewKey()
+	if err := s.db.SetSetting(ctx, redactionKeySetting, hex.EncodeToString(key)); err != nil {
+		return nil, fmt.Errorf("failed to store redaction key: %w", err)
+	}
+	return key, nil
+}
+
 // RegisterNotificationChannel adds a backend notification channel to the dispatcher.
 func (s *Server) RegisterNotificationChannel(ch notifications.Channel) {
 	s.notifDispatcher.Register(ch)
@@ -276,6 +356,8 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.Handle("/api/conversations/distill", http.HandlerFunc(s.handleDistillConversation)) // Small response
 	mux.Handle("/api/conversation/", http.StripPrefix("/api/conversation", s.conversationMux()))
 	mux.Handle("/api/conversation-by-slug/", gzipHandler(http.HandlerFunc(s.handleConversationBySlug)))
+	mux.Handle("/api/tags", http.HandlerFunc(s.handleTags))
+	mux.Handle("/api/repos", gzipHandler(http.HandlerFunc(s.handleRepos)))
 	mux.Handle("/api/validate-cwd", http.HandlerFunc(s.handleValidateCwd)) // Small response
 	mux.Handle("/api/list-directory", gzipHandler(http.HandlerFunc(s.handleListDirectory)))
 	mux.Handle("/api/create-directory", http.HandlerFunc(s.handleCreateDirectory))
@@ -294,10 +376,37 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.Handle("/api/custom-models-test", http.HandlerFunc(s.handleTestModel))
 
 	// Notification channels API
-	mux.Handle("/api/notification-channels", http.HandlerFunc(s.handleNotificationChannels))
-	mux.Handle("/api/notification-channels/", http.HandlerFunc(s.handleNotificationChannel))
+	mux.Handle("/api/notification-channels", s.adminOnly(s.handleNotificationChannels))
+	mux.Handle("/api/notification-channels/", s.adminOnly(s.handleNotificationChannel))
+	mux.Handle("/api/notification-deliveries", s.adminOnly(s.handleNotificationDeliveries))
+	mux.Handle("/api/notification-deliveries/", s.adminOnly(s.handleNotificationDelivery))
+	mux.Handle("/api/notification-replies/", http.HandlerFunc(s.handleNotificationReply))
 	mux.Handle("/api/notification-channel-types", http.HandlerFunc(s.handleNotificationChannelTypes))
 
+	// Schedules API
+	mux.Handle("/api/schedules", s.adminOnly(s.handleSchedules))
+	mux.Handle("/api/schedules/", s.adminOnly(s.handleSchedule))
+
+	// Incoming webhook triggers API
+	mux.Handle("/api/triggers", s.adminOnly(s.handleTriggers))
+	mux.Handle("/api/triggers/", http.HandlerFunc(s.handleTrigger))
+
+	// Authentication (only active after EnableAuth)
+	mux.Handle("/auth/login", http.HandlerFunc(s.handleLogin))
+	mux.Handle("/auth/logout", http.HandlerFunc(s.handleLogout))
+	mux.Handle("/auth/oidc/login", http.HandlerFunc(s.handleOIDCLogin))
+	mux.Handle("/auth/oidc/callback", http.HandlerFunc(s.handleOIDCCallback))
+	mux.Handle("/api/auth/me", http.HandlerFunc(s.handleAuthMe))
+	mux.Handle("/api/auth/tokens", http.HandlerFunc(s.handleAPITokens))
+	mux.Handle("/api/auth/tokens/", http.HandlerFunc(s.handleAPIToken))
+
+	// Audit log of tool executions and file modifications
+	mux.Handle("/api/audit", http.HandlerFunc(s.handleAudit))
+
+	// Multi-user budgets (only active after EnableMultiUser)
+	mux.Handle("/api/budgets", http.HandlerFunc(s.handleBudgets))
+	mux.Handle("/api/budgets/", http.HandlerFunc(s.handleBudget))
+
 	// Models API (dynamic list refresh)
 	mux.Handle("/api/models", http.HandlerFunc(s.handleModels))
 
@@ -305,25 +414,26 @@ func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.Handle("GET /version", http.HandlerFunc(s.handleVersion))
 	mux.Handle("GET /version-check", http.HandlerFunc(s.handleVersionCheck))
 	mux.Handle("GET /version-changelog", http.HandlerFunc(s.handleVersionChangelog))
-	mux.Handle("POST /upgrade", http.HandlerFunc(s.handleUpgrade))
-	mux.Handle("POST /exit", http.HandlerFunc(s.handleExit))
+	mux.Handle("POST /upgrade", s.adminOnly(s.handleUpgrade))
+	mux.Handle("POST /exit", s.adminOnly(s.handleExit))
 	mux.Handle("GET /settings", http.HandlerFunc(s.handleGetSettings))
 	mux.Handle("POST /settings", http.HandlerFunc(s.handleSetSetting))
 
-	// Debug endpoints
-	mux.Handle("GET /debug/conversations", http.HandlerFunc(s.handleDebugConversationsPage))
-	mux.Handle("GET /debug/llm_requests", http.HandlerFunc(s.handleDebugLLMRequests))
-	mux.Handle("GET /debug/llm_requests/api", http.HandlerFunc(s.handleDebugLLMRequestsAPI))
-	mux.Handle("GET /debug/llm_requests/{id}/request", http.HandlerFunc(s.handleDebugLLMRequestBody))
-	mux.Handle("GET /debug/llm_requests/{id}/request_full", http.HandlerFunc(s.handleDebugLLMRequestBodyFull))
-	mux.Handle("GET /debug/llm_requests/{id}/response", http.HandlerFunc(s.handleDebugLLMResponseBody))
+	// Debug endpoints show every conversation's LLM traffic, so in
+	// multi-user mode only admins may use them
+	mux.Handle("GET /debug/conversations", s.adminOnly(s.handleDebugConversationsPage))
+	mux.Handle("GET /debug/llm_requests", s.adminOnly(s.handleDebugLLMRequests))
+	mux.Handle("GET /debug/llm_requests/api", s.adminOnly(s.handleDebugLLMRequestsAPI))
+	mux.Handle("GET /debug/llm_requests/{id}/request", s.adminOnly(s.handleDebugLLMRequestBody))
+	mux.Handle("GET /debug/llm_requests/{id}/request_full", s.adminOnly(s.handleDebugLLMRequestBodyFull))
+	mux.Handle("GET /debug/llm_requests/{id}/response", s.adminOnly(s.handleDebugLLMResponseBody))
 
 	// pprof endpoints
-	mux.Handle("GET /debug/pprof/", http.HandlerFunc(pprof.Index))
-	mux.Handle("GET /debug/pprof/cmdline", http.HandlerFunc(pprof.Cmdline))
-	mux.Handle("GET /debug/pprof/profile", http.HandlerFunc(pprof.Profile))
-	mux.Handle("GET /debug/pprof/symbol", http.HandlerFunc(pprof.Symbol))
-	mux.Handle("GET /debug/pprof/trace", http.HandlerFunc(pprof.Trace))
+	mux.Handle("GET /debug/pprof/", s.adminOnly(pprof.Index))
+	mux.Handle("GET /debug/pprof/cmdline", s.adminOnly(pprof.Cmdline))
+	mux.Handle("GET /debug/pprof/profile", s.adminOnly(pprof.Profile))
+	mux.Handle("GET /debug/pprof/symbol", s.adminOnly(pprof.Symbol))
+	mux.Handle("GET /debug/pprof/trace", s.adminOnly(pprof.Trace))
 
 	// Serve embedded UI assets
 	mux.Handle("/", s.staticHandler(ui.Assets()))
@@ -686,6 +796,12 @@ func (s *Server) getOrCreateConversationManager(ctx context.Context, conversatio
 
 		manager := NewConversationManager(conversationID, s.db, s.logger, s.toolSetConfig, recordMessage, onStateChange)
 		manager.userEmail = userEmail
+		if s.redactor != nil {
+			manager.redact = s.redactor.Redact
+		}
+		manager.onGitCommit = func(ctx context.Context, prev, cur *gitstate.GitState) {
+			s.notifyGitCommit(ctx, conversationID, prev, cur)
+		}
 		if err := manager.Hydrate(ctx); err != nil {
 			return nil, err
 		}
@@ -724,6 +840,9 @@ func (s *Server) getOrCreateSubagentConversationManager(ctx context.Context, con
 		subagentConfig.SubagentDepth = s.toolSetConfig.SubagentDepth + 1
 
 		manager := NewConversationManager(conversationID, s.db, s.logger, subagentConfig, recordMessage, onStateChange)
+		if s.redactor != nil {
+			manager.redact = s.redactor.Redact
+		}
 		if err := manager.Hydrate(ctx); err != nil {
 			return nil, err
 		}
@@ -784,6 +903,10 @@ func (s *Server) recordMessage(ctx context.Context, conversationID string, messa
 	// Extract display data from content items
 	displayDataToStore := ExtractDisplayData(message)
 
+	if message.Role == llm.MessageRoleUser {
+		s.notifyLongBashCommands(ctx, conversationID, message)
+	}
+
 	// Create message
 	var ud interface{}
 	if len(userData) > 0 {
@@ -809,6 +932,10 @@ func (s *Server) recordMessage(ctx context.Context, conversationID string, messa
 		s.logger.Warn("Failed to update conversation timestamp", "conversationID", conversationID, "error", err)
 	}
 
+	if usage.CostUSD > 0 {
+		s.checkBudgetThreshold(ctx, conversationID, usage.CostUSD)
+	}
+
 	// Touch active manager activity time if present
 	s.mu.Lock()
 	mgr, ok := s.activeConversations[conversationID]
@@ -997,6 +1124,13 @@ func (s *Server) publishConversationListUpdate(update ConversationListUpdate) {
 	if update.Conversation != nil && update.Conversation.Cwd != nil {
 		update.GitRepoRoot, update.GitWorktreeRoot = gitInfoForCwd(*update.Conversation.Cwd)
 	}
+	if update.Conversation != nil && update.Tags == nil {
+		tags, err := s.db.GetConversationTags(context.Background(), update.Conversation.ConversationID)
+		if err != nil {
+			s.logger.Warn("Failed to get conversation tags", "conversationID", update.Conversation.ConversationID, "error", err)
+		}
+		update.Tags = tags
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -1013,45 +1147,15 @@ func (s *Server) publishConversationListUpdate(update ConversationListUpdate) {
 // publishConversationState broadcasts a conversation state update to ALL active
 // conversation streams. This allows clients to see the working state of other conversations.
 func (s *Server) publishConversationState(state ConversationState) {
-	// When the agent finishes working, emit a notification event.
-	// Skip notifications for subagent conversations — they're internal
-	// and would just be noise for the user.
+	// When the agent finishes working, emit a notification event:
+	// agent_done or agent_error, or subagent_done for subagent
+	// conversations, which channels only receive if they ask for it.
 	var notifEvent *notifications.Event
 	if !state.Working {
-		conv, convErr := s.db.GetConversationByID(context.Background(), state.ConversationID)
-		isSubagent := convErr == nil && conv.ParentConversationID != nil
-
-		payload := notifications.AgentDonePayload{
-			Model: state.Model,
-		}
-		if convErr == nil && conv.Slug != nil {
-			payload.ConversationTitle = *conv.Slug
-		}
-		if msg, err := s.db.GetLatestMessage(context.Background(), state.ConversationID); err == nil && msg.Type == string(db.MessageTypeAgent) && msg.LlmData != nil {
-			var llmMsg llm.Message
-			if json.Unmarshal([]byte(*msg.LlmData), &llmMsg) == nil {
-				var text string
-				for _, c := range llmMsg.Content {
-					if c.Type == llm.ContentTypeText && c.Text != "" {
-						text = c.Text
-					}
-				}
-				if len(text) > 255 {
-					text = text[:255] + "..."
-				}
-				payload.FinalResponse = text
-			}
-		}
-		event := notifications.Event{
-			Type:           notifications.EventAgentDone,
-			ConversationID: state.ConversationID,
-			Timestamp:      time.Now(),
-			Payload:        payload,
-		}
-		if !isSubagent {
-			s.notifDispatcher.Dispatch(context.Background(), event)
-		}
-		// Still set notifEvent so the SSE stream broadcasts it to the UI.
+		event := s.turnEndEvent(context.Background(), state)
+		s.dispatchNotification(context.Background(), event)
+		s.sendTriggerCallback(event)
+		// Also set notifEvent so the SSE stream broadcasts it to the UI.
 		notifEvent = &event
 	}
 
@@ -1130,16 +1234,23 @@ func (s *Server) StartWithListener(listener net.Listener) error {
 }
 
 // StartWithListeners starts the HTTP server on the given TCP listener and optionally
-// also on a Unix socket. The TCP listener gets full middleware (CSRF, requireHeader, logger).
-// The Unix socket listener gets only the logger middleware (no CSRF, no requireHeader)
-// since it is local and trusted.
+// also on a Unix socket. The TCP listener gets full middleware (CSRF, requireHeader,
+// authentication if enabled, logger). The Unix socket listener gets only the logger
+// middleware (no CSRF, no requireHeader, no authentication) since it is local and trusted.
 func (s *Server) StartWithListeners(tcpListener net.Listener, socketPath string) error {
 	// Set up shared mux with routes
 	mux := http.NewServeMux()
 	s.RegisterRoutes(mux)
 
 	// TCP handler: full middleware (applied in reverse order: last added = first executed)
-	tcpHandler := LoggerMiddleware(s.logger)(mux)
+	var tcpHandler http.Handler = mux
+	if s.multiUser {
+		tcpHandler = s.RequireIdentityMiddleware(tcpHandler)
+	}
+	if s.auth != nil {
+		tcpHandler = s.AuthMiddleware(tcpHandler)
+	}
+	tcpHandler = LoggerMiddleware(s.logger)(tcpHandler)
 	cop := http.NewCrossOriginProtection()
 	tcpHandler = cop.Handler(tcpHandler)
 	if s.requireHeader != "" {
@@ -1156,12 +1267,24 @@ func (s *Server) StartWithListeners(tcpListener net.Listener, socketPath string)
 		defer ticker.Stop()
 		for range ticker.C {
 			s.Cleanup()
+			if s.auth != nil {
+				if err := s.db.PruneUserSessions(context.Background(), time.Now()); err != nil {
+					s.logger.Warn("Failed to prune sessions", "error", err)
+				}
+			}
 		}
 	}()
 
 	// Start auto-upgrade routine
 	go s.autoUpgradeRoutine()
 
+	// Start scheduled conversations
+	go s.scheduleRoutine()
+
+	// Start idle conversation notifications and queued notification delivery
+	go s.notificationRoutine()
+	go s.deliveryRoutine()
+
 	// Get actual port from listener
 	actualPort := tcpListener.Addr().(*net.TCPAddr).Port
 	s.listenPort = actualPort
@@ -1250,11 +1373,14 @@ func (s *Server) StartWithListeners(tcpListener net.Listener, socketPath string)
 		os.Remove(actualSocketPath)
 	}
 
+	s.jobs.StopAll()
+
 	s.logger.Info("Server exited")
 	return nil
 }
 
-// autoUpgradeRoutine checks for upgrades every 24 hours if auto-upgrade is enabled
+// autoUpgradeRoutine checks for upgrades every 24 hours, sends upgrade_available
+// notifications, and upgrades if auto-upgrade is enabled
 func (s *Server) autoUpgradeRoutine() {
 	// Wait a bit before starting to let the server fully initialize
 	timer := time.NewTimer(1 * time.Minute)
@@ -1268,6 +1394,7 @@ func (s *Server) autoUpgradeRoutine() {
 	}
 
 	// Do initial check after startup delay
+	s.notifyUpgradeAvailable(context.Background())
 	s.tryAutoUpgrade()
 
 	ticker := time.NewTicker(24 * time.Hour)
@@ -1276,6 +1403,7 @@ func (s *Server) autoUpgradeRoutine() {
 	for {
 		select {
 		case <-ticker.C:
+			s.notifyUpgradeAvailable(context.Background())
 			s.tryAutoUpgrade()
 		case <-s.shutdownCh:
 			return
@@ -32,6 +32,7 @@ type SystemPromptData struct {
 	ShelleyDBPath    string // Path to the shelley database
 	SkillsXML        string // XML block for available skills
 	UserEmail        string // The exe.dev auth email of the user, if known
+	RepoMap          string // Map of the repository's code, if any
 }
 
 // DBPath is the path to the shelley database, set at startup
@@ -79,6 +80,13 @@ func WithUserEmail(email string) SystemPromptOption {
 	}
 }
 
+// WithRepoMap includes a map of the repository's code in the system prompt.
+func WithRepoMap(repoMap string) SystemPromptOption {
+	return func(d *SystemPromptData) {
+		d.RepoMap = repoMap
+	}
+}
+
 // GenerateSystemPrompt generates the system prompt using the embedded template.
 // If workingDir is empty, it uses the current working directory.
 func GenerateSystemPrompt(workingDir string, opts ...SystemPromptOption) (string, error) {
@@ -168,3 +168,24 @@ func TestSystemPromptIncludesUserEmail(t *testing.T) {
 		t.Error("system prompt should contain the user email when provided")
 	}
 }
+
+func TestSystemPromptIncludesRepoMap(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	prompt, err := GenerateSystemPrompt(tmpDir)
+	if err != nil {
+		t.Fatalf("GenerateSystemPrompt failed: %v", err)
+	}
+	if strings.Contains(prompt, "<repo_map>") {
+		t.Error("system prompt should not have a repo map when none is provided")
+	}
+
+	repoMap := "Entry points:\n  cmd/app/main.go: func main\n"
+	prompt, err = GenerateSystemPrompt(tmpDir, WithRepoMap(repoMap))
+	if err != nil {
+		t.Fatalf("GenerateSystemPrompt with repo map failed: %v", err)
+	}
+	if !strings.Contains(prompt, "<repo_map>\n") || !strings.Contains(prompt, repoMap+"</repo_map>") {
+		t.Errorf("system prompt should contain the repo map, got:\n%s", prompt)
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"shelley.exe.dev/llm"
+)
+
+// ToolProgress carries output a running tool call has produced, such as a
+// chunk of a bash command's output. Offset is where Output starts in all of
+// the call's output, in bytes, so clients can tell a chunk that follows on
+// from what they have from a catch-up snapshot of the latest output.
+type ToolProgress struct {
+	ToolUseID string `json:"tool_use_id"`
+	ToolName  string `json:"tool_name"`
+	Output    string `json:"output"`
+	Offset    int64  `json:"offset"`
+}
+
+// toolOutputTail is how much of a running tool call's latest output is kept
+// for clients that subscribe while it runs.
+const toolOutputTail = 64 * 1024
+
+// maxPendingLine is how much of an unfinished line of tool output is held
+// back, so that a secret in it is redacted whole, before it is sent anyway.
+const maxPendingLine = 4 * 1024
+
+// runningToolOutput is the output of a tool call so far.
+type runningToolOutput struct {
+	name    string
+	tail    string // the latest output, starting at a line where possible
+	total   int64  // how much output there has been
+	pending string // an unfinished line, not yet redacted or sent
+}
+
+// reportToolProgress broadcasts a chunk of a running tool call's output to
+// subscribers and remembers the latest output for later ones. Output is
+// passed on a line at a time, with secrets redacted.
+func (cm *ConversationManager) reportToolProgress(toolUseID, toolName, chunk string) {
+	cm.mu.Lock()
+	if cm.toolOutput == nil {
+		cm.toolOutput = make(map[string]*runningToolOutput)
+	}
+	out := cm.toolOutput[toolUseID]
+	if out == nil {
+		out = &runningToolOutput{name: toolName}
+		cm.toolOutput[toolUseID] = out
+	}
+	out.pending += chunk
+	n := strings.LastIndexAny(out.pending, "\n\r") + 1
+	if n == 0 && len(out.pending) >= maxPendingLine {
+		n = len(out.pending)
+	}
+	if n == 0 {
+		cm.mu.Unlock()
+		return
+	}
+	chunk, out.pending = out.pending[:n], out.pending[n:]
+	if cm.redact != nil {
+		chunk, _ = cm.redact(chunk)
+	}
+	// Offsets must agree with the JSON the clients see.
+	chunk = strings.ToValidUTF8(chunk, "�")
+	offset := out.total
+	out.total += int64(len(chunk))
+	out.tail += chunk
+	if len(out.tail) > 2*toolOutputTail {
+		tail := out.tail[len(out.tail)-toolOutputTail:]
+		if i := strings.IndexByte(tail, '\n'); i >= 0 {
+			tail = tail[i+1:]
+		}
+		out.tail = strings.ToValidUTF8(tail, "")
+	}
+	cm.mu.Unlock()
+
+	cm.subpub.Broadcast(StreamResponse{
+		ToolProgress: &ToolProgress{ToolUseID: toolUseID, ToolName: toolName, Output: chunk, Offset: offset},
+	})
+}
+
+// forgetToolOutput drops the output kept for the tool calls msg has results
+// for.
+func (cm *ConversationManager) forgetToolOutput(msg llm.Message) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	for _, c := range msg.Content {
+		if c.Type == llm.ContentTypeToolResult {
+			delete(cm.toolOutput, c.ToolUseID)
+		}
+	}
+}
+
+// RunningToolOutput returns the latest output of each running tool call.
+func (cm *ConversationManager) RunningToolOutput() []ToolProgress {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	var progress []ToolProgress
+	for id, out := range cm.toolOutput {
+		progress = append(progress, ToolProgress{
+			ToolUseID: id,
+			ToolName:  out.name,
+			Output:    out.tail,
+			Offset:    out.total - int64(len(out.tail)),
+		})
+	}
+	return progress
+}
+
+// CancelToolCall cancels one running tool call, leaving the rest of the
+// turn alone. It reports whether such a call was running.
+func (cm *ConversationManager) CancelToolCall(toolUseID string) bool {
+	cm.mu.Lock()
+	loopInstance := cm.loop
+	cm.mu.Unlock()
+	if loopInstance == nil {
+		return false
+	}
+	return loopInstance.CancelToolCall(toolUseID)
+}
+
+// handleCancelToolCall handles POST /api/conversation/<id>/tools/<tool>/cancel,
+// cancelling a single running tool call. The agent carries on with a result
+// saying that the call was cancelled.
+func (s *Server) handleCancelToolCall(w http.ResponseWriter, r *http.Request, conversationID, toolUseID string) {
+	s.mu.Lock()
+	manager, exists := s.activeConversations[conversationID]
+	s.mu.Unlock()
+	if !exists || !manager.CancelToolCall(toolUseID) {
+		http.Error(w, "Tool call not running", http.StatusNotFound)
+		return
+	}
+	s.logger.Info("Tool call cancelled", "conversationID", conversationID, "toolUseID", toolUseID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "cancelled"})
+}
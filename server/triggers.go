@@ -0,0 +1,489 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"shelley.exe.dev/db/generated"
+	"shelley.exe.dev/server/notifications"
+)
+
+// Trigger modes: what an invocation does with the trigger's conversations.
+const (
+	triggerModeNew      = "new"      // start a new conversation every time
+	triggerModeContinue = "continue" // continue the trigger's last conversation
+)
+
+// defaultTriggerTemplate uses the request body as the prompt.
+const defaultTriggerTemplate = "{{.Body}}"
+
+// maxTriggerPayload bounds the body of a trigger invocation.
+const maxTriggerPayload = 1 << 20
+
+// triggerRunsLimit bounds the conversations returned by GET /api/triggers/<id>/runs.
+const triggerRunsLimit = 50
+
+// triggerCallbackTimeout bounds a callback request.
+const triggerCallbackTimeout = 30 * time.Second
+
+// triggerSignatureHeaders carry an HMAC-SHA256 of the body keyed with the
+// trigger secret, as "sha256=<hex>". The first is what GitHub sends; the
+// second matches the signature on Shelley's own webhooks and callbacks.
+var triggerSignatureHeaders = []string{"X-Hub-Signature-256", "X-Shelley-Signature-256"}
+
+// TriggerRequest is the body of POST /api/triggers and PUT /api/triggers/<id>.
+type TriggerRequest struct {
+	Name string `json:"name"`
+	// PromptTemplate is a text/template rendered with triggerTemplateData;
+	// empty means the request body is the prompt.
+	PromptTemplate string `json:"prompt_template,omitempty"`
+	Cwd            string `json:"cwd,omitempty"`
+	Model          string `json:"model,omitempty"`
+	Mode           string `json:"mode,omitempty"`         // "new" (default) or "continue"
+	CallbackURL    string `json:"callback_url,omitempty"` // POSTed the turn-end event when the agent finishes
+	Enabled        *bool  `json:"enabled,omitempty"`      // defaults to true
+}
+
+// validate checks the request and fills in defaults.
+func (req *TriggerRequest) validate() error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	if strings.TrimSpace(req.PromptTemplate) == "" {
+		req.PromptTemplate = defaultTriggerTemplate
+	}
+	if _, err := parseTriggerTemplate(req.PromptTemplate); err != nil {
+		return fmt.Errorf("invalid prompt_template: %w", err)
+	}
+	switch req.Mode {
+	case "":
+		req.Mode = triggerModeNew
+	case triggerModeNew, triggerModeContinue:
+	default:
+		return fmt.Errorf("invalid mode %q (want %q or %q)", req.Mode, triggerModeNew, triggerModeContinue)
+	}
+	if req.CallbackURL != "" {
+		u, err := url.Parse(req.CallbackURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("callback_url must be an http or https URL")
+		}
+	}
+	if req.Enabled == nil {
+		enabled := true
+		req.Enabled = &enabled
+	}
+	return nil
+}
+
+// triggerTemplateData is what a trigger's prompt template is rendered with.
+type triggerTemplateData struct {
+	Trigger string      // trigger name
+	Body    string      // raw request body
+	JSON    any         // request body decoded as JSON, or nil if it isn't JSON
+	Header  http.Header // request headers, e.g. {{.Header.Get "X-GitHub-Event"}}
+	Query   url.Values  // query parameters, e.g. {{.Query.Get "ref"}}
+}
+
+func parseTriggerTemplate(text string) (*template.Template, error) {
+	return template.New("prompt").Option("missingkey=zero").Parse(text)
+}
+
+// renderTriggerPrompt renders a trigger's prompt template for a request body.
+func renderTriggerPrompt(trigger *generated.Trigger, r *http.Request, body []byte) (string, error) {
+	tmpl, err := parseTriggerTemplate(trigger.PromptTemplate)
+	if err != nil {
+		return "", err
+	}
+	data := triggerTemplateData{
+		Trigger: trigger.Name,
+		Body:    string(body),
+		Header:  r.Header,
+		Query:   r.URL.Query(),
+	}
+	var decoded any
+	if json.Unmarshal(body, &decoded) == nil {
+		data.JSON = decoded
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+func newTriggerSecret() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// triggerAuthorized reports whether a request carries the trigger's secret,
+// either as a bearer token or as an HMAC signature of the body. The secret
+// is never accepted in the query string, which ends up in request logs.
+func triggerAuthorized(r *http.Request, body []byte, secret string) bool {
+	equal := func(a, b string) bool {
+		return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && equal(token, secret) {
+		return true
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	for _, h := range triggerSignatureHeaders {
+		if sig := r.Header.Get(h); sig != "" && equal(sig, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// TriggerAPI is a trigger as listed by GET /api/triggers. The secret is
+// left out; it is only returned when a trigger is created, fetched on its
+// own or has its secret rotated.
+type TriggerAPI struct {
+	generated.Trigger
+	Secret string `json:"secret,omitempty"` // hides Trigger.Secret
+}
+
+// handleTriggers handles GET and POST /api/triggers
+func (s *Server) handleTriggers(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		triggers, err := s.db.ListTriggers(r.Context())
+		if err != nil {
+			s.logger.Error("Failed to list triggers", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		result := make([]TriggerAPI, len(triggers))
+		for i, trigger := range triggers {
+			result[i] = TriggerAPI{Trigger: trigger}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	case http.MethodPost:
+		s.handleCreateTrigger(w, r)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleCreateTrigger(w http.ResponseWriter, r *http.Request) {
+	var req TriggerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	trigger, err := s.db.CreateTrigger(r.Context(), generated.CreateTriggerParams{
+		TriggerID:      "trigger-" + uuid.New().String()[:8],
+		Name:           req.Name,
+		Secret:         newTriggerSecret(),
+		PromptTemplate: req.PromptTemplate,
+		Cwd:            optionalString(req.Cwd),
+		Model:          optionalString(req.Model),
+		Mode:           req.Mode,
+		CallbackUrl:    optionalString(req.CallbackURL),
+		Enabled:        *req.Enabled,
+	})
+	if err != nil {
+		s.logger.Error("Failed to create trigger", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(trigger)
+}
+
+// handleTrigger handles /api/triggers/<id>, /api/triggers/<id>/runs and
+// /api/triggers/<id>/rotate-secret. POST /api/triggers/<id> invokes the
+// trigger and is authenticated with the trigger's secret.
+func (s *Server) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/triggers/")
+	triggerID, action, _ := strings.Cut(path, "/")
+	if triggerID == "" || strings.Contains(action, "/") {
+		http.Error(w, "Invalid trigger ID", http.StatusBadRequest)
+		return
+	}
+	invoke := action == "" && r.Method == http.MethodPost
+	if !invoke && !s.isAdmin(r) {
+		http.Error(w, "Only admins can do that", http.StatusForbidden)
+		return
+	}
+
+	trigger, err := s.db.GetTrigger(r.Context(), triggerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "Trigger not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to get trigger", "triggerID", triggerID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case invoke:
+		s.handleInvokeTrigger(w, r, trigger)
+	case action == "" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(trigger)
+	case action == "" && r.Method == http.MethodPut:
+		s.handleUpdateTrigger(w, r, trigger)
+	case action == "" && r.Method == http.MethodDelete:
+		if err := s.db.DeleteTrigger(r.Context(), triggerID); err != nil {
+			s.logger.Error("Failed to delete trigger", "triggerID", triggerID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case action == "rotate-secret" && r.Method == http.MethodPost:
+		trigger, err := s.db.SetTriggerSecret(r.Context(), triggerID, newTriggerSecret())
+		if err != nil {
+			s.logger.Error("Failed to rotate trigger secret", "triggerID", triggerID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(trigger)
+	case action == "runs" && r.Method == http.MethodGet:
+		runs, err := s.db.ListTriggerConversations(r.Context(), triggerID, triggerRunsLimit)
+		if err != nil {
+			s.logger.Error("Failed to list trigger runs", "triggerID", triggerID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		working := s.getWorkingConversations()
+		result := make([]ConversationWithState, len(runs))
+		for i, conv := range runs {
+			result[i] = ConversationWithState{Conversation: conv, Working: working[conv.ConversationID]}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	case action == "" || action == "runs" || action == "rotate-secret":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+func (s *Server) handleUpdateTrigger(w http.ResponseWriter, r *http.Request, existing *generated.Trigger) {
+	var req TriggerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := req.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	trigger, err := s.db.UpdateTrigger(r.Context(), generated.UpdateTriggerParams{
+		Name:           req.Name,
+		PromptTemplate: req.PromptTemplate,
+		Cwd:            optionalString(req.Cwd),
+		Model:          optionalString(req.Model),
+		Mode:           req.Mode,
+		CallbackUrl:    optionalString(req.CallbackURL),
+		Enabled:        *req.Enabled,
+		TriggerID:      existing.TriggerID,
+	})
+	if err != nil {
+		s.logger.Error("Failed to update trigger", "triggerID", existing.TriggerID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(trigger)
+}
+
+// handleInvokeTrigger handles POST /api/triggers/<id>. The rendered prompt
+// starts a new conversation, or continues one of the trigger's conversations:
+// the one named by the conversation_id query parameter, or in "continue"
+// mode the trigger's last one.
+func (s *Server) handleInvokeTrigger(w http.ResponseWriter, r *http.Request, trigger *generated.Trigger) {
+	ctx := r.Context()
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTriggerPayload))
+	if err != nil {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !triggerAuthorized(r, body, trigger.Secret) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !trigger.Enabled {
+		http.Error(w, "Trigger is disabled", http.StatusForbidden)
+		return
+	}
+
+	prompt, err := renderTriggerPrompt(trigger, r, body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to render prompt: %v", err), http.StatusBadRequest)
+		return
+	}
+	if prompt == "" {
+		http.Error(w, "Rendered prompt is empty", http.StatusBadRequest)
+		return
+	}
+
+	// Find the conversation to continue, if any.
+	var conversation *generated.Conversation
+	conversationID := r.URL.Query().Get("conversation_id")
+	if conversationID == "" && trigger.Mode == triggerModeContinue && trigger.LastConversationID != nil {
+		conversationID = *trigger.LastConversationID
+	}
+	if conversationID != "" {
+		conv, err := s.db.GetConversationByID(ctx, conversationID)
+		switch {
+		case err == nil && conv.TriggerID != nil && *conv.TriggerID == trigger.TriggerID:
+			conversation = conv
+		case r.URL.Query().Has("conversation_id"):
+			http.Error(w, "Conversation not found", http.StatusNotFound)
+			return
+		}
+		// In continue mode, a deleted last conversation means starting over.
+	}
+
+	modelID := s.defaultModel
+	switch {
+	case conversation != nil && conversation.Model != nil:
+		modelID = *conversation.Model
+	case trigger.Model != nil && *trigger.Model != "":
+		modelID = *trigger.Model
+	}
+	if modelID == "" && s.predictableOnly {
+		modelID = "predictable"
+	}
+	llmService, err := s.llmManager.GetService(modelID)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Unsupported model: %s", modelID), http.StatusBadRequest)
+		return
+	}
+
+	status := http.StatusAccepted
+	now := time.Now().UTC()
+	if conversation == nil {
+		conversation, err = s.db.CreateTriggerConversation(ctx, trigger.TriggerID, trigger.Cwd, modelID, now)
+		if err != nil {
+			s.logger.Error("Failed to create trigger conversation", "triggerID", trigger.TriggerID, "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		status = http.StatusCreated
+		go s.publishConversationListUpdate(ConversationListUpdate{
+			Type:         "update",
+			Conversation: conversation,
+		})
+	} else if err := s.db.RecordTriggerRun(ctx, trigger.TriggerID, conversation.ConversationID, now); err != nil {
+		s.logger.Warn("Failed to record trigger run", "triggerID", trigger.TriggerID, "error", err)
+	}
+	conversationID = conversation.ConversationID
+	s.logger.Info("Trigger invoked", "triggerID", trigger.TriggerID, "conversationID", conversationID)
+
+	// Register the callback first so a quick turn can't end before it is set.
+	if trigger.CallbackUrl != nil {
+		s.triggerCallbacksMu.Lock()
+		s.triggerCallbacks[conversationID] = trigger.TriggerID
+		s.triggerCallbacksMu.Unlock()
+	}
+	err = s.sendUserMessage(ctx, conversationID, "", llmService, modelID, prompt)
+	if err != nil {
+		s.triggerCallbacksMu.Lock()
+		delete(s.triggerCallbacks, conversationID)
+		s.triggerCallbacksMu.Unlock()
+	}
+	if errors.Is(err, errConversationModelMismatch) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, errBudgetExceeded) {
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to send trigger prompt", "triggerID", trigger.TriggerID, "conversationID", conversationID, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":          "accepted",
+		"conversation_id": conversationID,
+	})
+}
+
+// sendTriggerCallback POSTs the turn-end event to the callback URL of the
+// trigger that started the turn, if it has one. The body is the event as
+// JSON, signed with the trigger secret like an incoming signed request.
+// Callbacks are kept in memory, so one pending across a restart is lost.
+func (s *Server) sendTriggerCallback(event notifications.Event) {
+	s.triggerCallbacksMu.Lock()
+	triggerID, ok := s.triggerCallbacks[event.ConversationID]
+	delete(s.triggerCallbacks, event.ConversationID)
+	s.triggerCallbacksMu.Unlock()
+	if !ok {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), triggerCallbackTimeout)
+		defer cancel()
+		trigger, err := s.db.GetTrigger(ctx, triggerID)
+		if err != nil || trigger.CallbackUrl == nil {
+			return // deleted, or the callback was removed since
+		}
+		body, err := json.Marshal(event)
+		if err != nil {
+			s.logger.Error("Failed to marshal trigger callback", "triggerID", triggerID, "error", err)
+			return
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, *trigger.CallbackUrl, bytes.NewReader(body))
+		if err != nil {
+			s.logger.Warn("Invalid trigger callback URL", "triggerID", triggerID, "error", err)
+			return
+		}
+		mac := hmac.New(sha256.New, []byte(trigger.Secret))
+		mac.Write(body)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "Shelley")
+		req.Header.Set("X-Shelley-Trigger", triggerID)
+		req.Header.Set("X-Shelley-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			s.logger.Warn("Trigger callback failed", "triggerID", triggerID, "conversationID", event.ConversationID, "error", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			s.logger.Warn("Trigger callback failed", "triggerID", triggerID, "conversationID", event.ConversationID, "status", resp.Status)
+		}
+	}()
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley.exe.dev/db/generated"
+)
+
+func TestTriggersAPI(t *testing.T) {
+	h := NewTestHarness(t)
+	mux := http.NewServeMux()
+	h.server.RegisterRoutes(mux)
+
+	do := func(method, path, body string, header ...string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	callbacks := make(chan *http.Request, 4)
+	callbackBodies := make(chan []byte, 4)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		callbacks <- r
+		callbackBodies <- body
+	}))
+	defer hook.Close()
+
+	for _, body := range []string{
+		`{"prompt_template":"x"}`,
+		`{"name":"x","prompt_template":"{{.Body"}`,
+		`{"name":"x","mode":"append"}`,
+		`{"name":"x","callback_url":"ftp://example.com"}`,
+	} {
+		if w := do("POST", "/api/triggers", body); w.Code != http.StatusBadRequest {
+			t.Errorf("create %s: got %d, want 400", body, w.Code)
+		}
+	}
+
+	w := do("POST", "/api/triggers", `{"name":"ci","prompt_template":"echo: {{.JSON.job}} failed on {{.Query.Get \"ref\"}}","mode":"continue","callback_url":"`+hook.URL+`"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	var trigger generated.Trigger
+	if err := json.Unmarshal(w.Body.Bytes(), &trigger); err != nil {
+		t.Fatal(err)
+	}
+	if trigger.Secret == "" || !trigger.Enabled {
+		t.Fatalf("create: secret=%q enabled=%v", trigger.Secret, trigger.Enabled)
+	}
+	path := "/api/triggers/" + trigger.TriggerID
+	payload := `{"job":"lint"}`
+
+	// The list leaves out secrets.
+	w = do("GET", "/api/triggers", "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), trigger.Secret) || !strings.Contains(w.Body.String(), trigger.TriggerID) {
+		t.Errorf("list: %d %s", w.Code, w.Body.String())
+	}
+
+	// Invocations need the secret.
+	if w := do("POST", path+"?ref=main", payload); w.Code != http.StatusUnauthorized {
+		t.Errorf("no secret: got %d, want 401", w.Code)
+	}
+	if w := do("POST", path+"?ref=main", payload, "Authorization", "Bearer wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong secret: got %d, want 401", w.Code)
+	}
+	if w := do("POST", path+"?token="+trigger.Secret, payload); w.Code != http.StatusUnauthorized {
+		t.Errorf("secret in the query string: got %d, want 401", w.Code)
+	}
+
+	w = do("POST", path+"?ref=main", payload, "Authorization", "Bearer "+trigger.Secret)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("invoke: %d %s", w.Code, w.Body.String())
+	}
+	var run struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &run)
+	waitForIdle(t, h.server, run.ConversationID)
+
+	messages, err := h.db.ListMessages(t.Context(), run.ConversationID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sawPrompt bool
+	for _, m := range messages {
+		if m.UserData != nil && strings.Contains(*m.UserData, "echo: lint failed on main") {
+			sawPrompt = true
+		}
+		if m.LlmData != nil && strings.Contains(*m.LlmData, "echo: lint failed on main") {
+			sawPrompt = true
+		}
+	}
+	if !sawPrompt {
+		t.Error("rendered prompt not found in conversation")
+	}
+
+	select {
+	case r := <-callbacks:
+		body := <-callbackBodies
+		mac := hmac.New(sha256.New, []byte(trigger.Secret))
+		mac.Write(body)
+		if got, want := r.Header.Get("X-Shelley-Signature-256"), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+			t.Errorf("callback signature = %q, want %q", got, want)
+		}
+		var event struct {
+			Type           string `json:"type"`
+			ConversationID string `json:"conversation_id"`
+		}
+		json.Unmarshal(body, &event)
+		if event.Type != "agent_done" || event.ConversationID != run.ConversationID {
+			t.Errorf("callback event = %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no callback")
+	}
+
+	// In continue mode the next invocation, here signed GitHub-style,
+	// continues the same conversation.
+	mac := hmac.New(sha256.New, []byte(trigger.Secret))
+	mac.Write([]byte(payload))
+	w = do("POST", path+"?ref=dev", payload, "X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("continue: %d %s", w.Code, w.Body.String())
+	}
+	var again struct {
+		ConversationID string `json:"conversation_id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &again)
+	if again.ConversationID != run.ConversationID {
+		t.Errorf("continue started %s, want %s", again.ConversationID, run.ConversationID)
+	}
+	waitForIdle(t, h.server, run.ConversationID)
+
+	// Only the trigger's own conversations can be continued.
+	h.NewConversation("echo: other", "")
+	if w := do("POST", path+"?conversation_id="+h.ConversationID(), payload, "Authorization", "Bearer "+trigger.Secret); w.Code != http.StatusNotFound {
+		t.Errorf("foreign conversation: got %d, want 404", w.Code)
+	}
+
+	w = do("GET", path+"/runs", "")
+	var runs []ConversationWithState
+	json.Unmarshal(w.Body.Bytes(), &runs)
+	if len(runs) != 1 || runs[0].ConversationID != run.ConversationID {
+		t.Errorf("runs = %+v, want the one conversation", runs)
+	}
+
+	// Rotating the secret invalidates the old one.
+	w = do("POST", path+"/rotate-secret", "")
+	var rotated generated.Trigger
+	json.Unmarshal(w.Body.Bytes(), &rotated)
+	if rotated.Secret == "" || rotated.Secret == trigger.Secret {
+		t.Fatalf("rotate: secret=%q", rotated.Secret)
+	}
+	if w := do("POST", path, payload, "Authorization", "Bearer "+trigger.Secret); w.Code != http.StatusUnauthorized {
+		t.Errorf("old secret after rotation: got %d, want 401", w.Code)
+	}
+
+	// Disabled triggers refuse invocations.
+	if w := do("PUT", path, `{"name":"ci","enabled":false}`); w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+	if w := do("POST", path, payload, "Authorization", "Bearer "+rotated.Secret); w.Code != http.StatusForbidden {
+		t.Errorf("disabled: got %d, want 403", w.Code)
+	}
+
+	if w := do("DELETE", path, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d", w.Code)
+	}
+	conv, err := h.db.GetConversationByID(t.Context(), run.ConversationID)
+	if err != nil || conv.TriggerID != nil {
+		t.Errorf("after delete: conversation %+v, %v; want kept without trigger", conv, err)
+	}
+}
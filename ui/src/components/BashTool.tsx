@@ -1,9 +1,10 @@
-import React, { useState } from "react";
+import React, { useEffect, useRef, useState } from "react";
 import { LLMContent } from "../types";
 
 // Display data from the bash tool backend
 interface BashDisplayData {
   workingDir: string;
+  jobId?: string; // set for background commands
 }
 
 interface BashToolProps {
@@ -16,6 +17,10 @@ interface BashToolProps {
   hasError?: boolean;
   executionTime?: string;
   display?: unknown;
+
+  // While running: the output so far, and a way to stop the command
+  liveOutput?: string;
+  onCancel?: () => void;
 }
 
 function BashTool({
@@ -25,8 +30,18 @@ function BashTool({
   hasError,
   executionTime,
   display,
+  liveOutput,
+  onCancel,
 }: BashToolProps) {
   const [isExpanded, setIsExpanded] = useState(false);
+  const [stopping, setStopping] = useState(false);
+  const liveOutputRef = useRef<HTMLPreElement>(null);
+
+  // Follow the output as it arrives
+  useEffect(() => {
+    const el = liveOutputRef.current;
+    if (el) el.scrollTop = el.scrollHeight;
+  }, [liveOutput]);
 
   // Extract working directory from display data
   const displayData: BashDisplayData | null =
@@ -78,10 +93,29 @@ function BashTool({
               in {displayData.workingDir}
             </span>
           )}
+          {displayData?.jobId && (
+            <span className="bash-tool-job" title="Running as a background job">
+              {displayData.jobId}
+            </span>
+          )}
           {isComplete && isCancelled && <span className="bash-tool-cancelled">✗ cancelled</span>}
           {isComplete && hasError && !isCancelled && <span className="bash-tool-error">✗</span>}
           {isComplete && !hasError && <span className="bash-tool-success">✓</span>}
         </div>
+        {isRunning && onCancel && (
+          <button
+            className="bash-tool-stop"
+            onClick={(e) => {
+              e.stopPropagation();
+              setStopping(true);
+              onCancel();
+            }}
+            disabled={stopping}
+            title="Stop this command; the agent carries on"
+          >
+            {stopping ? "Stopping…" : "Stop"}
+          </button>
+        )}
         <button
           className="bash-tool-toggle"
           aria-label={isExpanded ? "Collapse" : "Expand"}
@@ -109,6 +143,12 @@ function BashTool({
         </button>
       </div>
 
+      {isRunning && liveOutput && (
+        <pre className="bash-tool-code bash-tool-live" ref={liveOutputRef}>
+          {liveOutput}
+        </pre>
+      )}
+
       {isExpanded && (
         <div className="bash-tool-details">
           {displayData?.workingDir && (
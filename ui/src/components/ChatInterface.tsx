@@ -5,6 +5,7 @@ import {
   StreamResponse,
   LLMContent,
   ConversationListUpdate,
+  ToolProgress,
   isDistillStatusMessage,
 } from "../types";
 import { api } from "../services/api";
@@ -37,6 +38,7 @@ import ChangeDirTool from "./ChangeDirTool";
 import SubagentTool from "./SubagentTool";
 import LLMOneShotTool from "./LLMOneShotTool";
 import OutputIframeTool from "./OutputIframeTool";
+import RunTestsTool from "./RunTestsTool";
 import BrowserEmulateTool from "./BrowserEmulateTool";
 import BrowserNetworkTool from "./BrowserNetworkTool";
 import BrowserAccessibilityTool from "./BrowserAccessibilityTool";
@@ -44,6 +46,7 @@ import BrowserProfileTool from "./BrowserProfileTool";
 import DirectoryPickerModal from "./DirectoryPickerModal";
 import { useVersionChecker } from "./VersionChecker";
 import TerminalPanel, { EphemeralTerminal } from "./TerminalPanel";
+import JobsPanel from "./JobsPanel";
 import ModelPicker from "./ModelPicker";
 import SystemPromptView from "./SystemPromptView";
 
@@ -238,9 +241,48 @@ interface CoalescedItem {
   toolEndTime?: string | null;
   hasResult?: boolean;
   display?: unknown;
+  redactions?: number;
+}
+
+// LiveToolOutput is what has been seen of the running tool call's output.
+interface LiveToolOutput {
+  toolUseId: string;
+  text: string;
+  end: number; // offset just past text, in UTF-8 bytes
+}
+
+// How much of a running tool call's output to keep, in characters.
+const LIVE_OUTPUT_LIMIT = 64 * 1024;
+
+const utf8Encoder = new TextEncoder();
+const utf8Decoder = new TextDecoder();
+
+// mergeToolProgress adds a chunk of output to what has been seen of the
+// running tool call. After the stream reconnects, a chunk can repeat output
+// already seen, or skip some that was missed.
+function mergeToolProgress(prev: LiveToolOutput | null, p: ToolProgress): LiveToolOutput {
+  const bytes = utf8Encoder.encode(p.output);
+  const end = p.offset + bytes.length;
+  let text: string;
+  if (prev && prev.toolUseId === p.tool_use_id) {
+    if (end <= prev.end) return prev;
+    if (p.offset <= prev.end) {
+      text = prev.text + utf8Decoder.decode(bytes.subarray(prev.end - p.offset));
+    } else {
+      text = prev.text + "\n…\n" + p.output;
+    }
+  } else {
+    text = p.offset > 0 ? "…\n" + p.output : p.output;
+  }
+  if (text.length > LIVE_OUTPUT_LIMIT) {
+    const cut = text.indexOf("\n", text.length - LIVE_OUTPUT_LIMIT);
+    text = "…\n" + text.slice(cut >= 0 ? cut + 1 : text.length - LIVE_OUTPUT_LIMIT);
+  }
+  return { toolUseId: p.tool_use_id, text, end };
 }
 
 interface CoalescedToolCallProps {
+  toolUseId?: string;
   toolName: string;
   toolInput?: unknown;
   toolResult?: LLMContent[];
@@ -249,6 +291,9 @@ interface CoalescedToolCallProps {
   toolEndTime?: string | null;
   hasResult?: boolean;
   display?: unknown;
+  redactions?: number;
+  liveOutput?: string;
+  onCancelToolCall?: (toolUseId: string) => void;
   onCommentTextChange?: (text: string) => void;
 }
 
@@ -267,6 +312,7 @@ const TOOL_COMPONENTS: Record<string, React.ComponentType<any>> = {
   subagent: SubagentTool,
   output_iframe: OutputIframeTool,
   llm_one_shot: LLMOneShotTool,
+  run_tests: RunTestsTool,
   browser_emulate: BrowserEmulateTool,
   browser_network: BrowserNetworkTool,
   browser_accessibility: BrowserAccessibilityTool,
@@ -281,6 +327,7 @@ const TOOL_COMPONENTS: Record<string, React.ComponentType<any>> = {
 };
 
 const CoalescedToolCall = React.memo(function CoalescedToolCall({
+  toolUseId,
   toolName,
   toolInput,
   toolResult,
@@ -289,6 +336,9 @@ const CoalescedToolCall = React.memo(function CoalescedToolCall({
   toolEndTime,
   hasResult,
   display,
+  redactions,
+  liveOutput,
+  onCancelToolCall,
   onCommentTextChange,
 }: CoalescedToolCallProps) {
   // Calculate execution time if available
@@ -304,6 +354,13 @@ const CoalescedToolCall = React.memo(function CoalescedToolCall({
     }
   }
 
+  // Secrets redacted from the output before it reached the LLM
+  const redactionNotice = redactions ? (
+    <div className="tool-redactions" data-testid="tool-redactions">
+      {redactions} secret{redactions > 1 ? "s" : ""} redacted
+    </div>
+  ) : null;
+
   // Look up the specialized component for this tool
   const ToolComponent = TOOL_COMPONENTS[toolName];
   if (ToolComponent) {
@@ -315,8 +372,23 @@ const CoalescedToolCall = React.memo(function CoalescedToolCall({
       executionTime,
       display,
       ...(toolName === "patch" && onCommentTextChange ? { onCommentTextChange } : {}),
+      ...(toolName === "bash" && !hasResult
+        ? {
+            liveOutput,
+            onCancel:
+              onCancelToolCall && toolUseId ? () => onCancelToolCall(toolUseId) : undefined,
+          }
+        : {}),
+      ...(toolName === "run_tests" && !hasResult && onCancelToolCall && toolUseId
+        ? { onCancel: () => onCancelToolCall(toolUseId) }
+        : {}),
     };
-    return <ToolComponent {...props} />;
+    return (
+      <>
+        <ToolComponent {...props} />
+        {redactionNotice}
+      </>
+    );
   }
 
   const getToolResultSummary = (results: LLMContent[]) => {
@@ -414,6 +486,7 @@ const CoalescedToolCall = React.memo(function CoalescedToolCall({
                 </span>
               </div>
               <div className="tool-result-time">
+                {redactionNotice}
                 {executionTime && <span>{executionTime}</span>}
               </div>
             </div>
@@ -634,6 +707,7 @@ function ChatInterface({
   onConversationUnarchived,
 }: ChatInterfaceProps) {
   const [messages, setMessages] = useState<Message[]>([]);
+  const [liveToolOutput, setLiveToolOutput] = useState<LiveToolOutput | null>(null);
   const [loading, setLoading] = useState(true);
   const [showLoadingProgressUI, setShowLoadingProgressUI] = useState(false);
   const [loadingProgress, setLoadingProgress] = useState<{
@@ -902,6 +976,7 @@ function ChatInterface({
 
   // Load messages and set up streaming
   useEffect(() => {
+    setLiveToolOutput(null);
     if (conversationId) {
       setAgentWorking(false);
       loadMessages();
@@ -1298,6 +1373,12 @@ function ChatInterface({
           }
         }
 
+        // Output of the running tool call
+        const progress = streamResponse.tool_progress;
+        if (progress) {
+          setLiveToolOutput((prev) => mergeToolProgress(prev, progress));
+        }
+
         // Dispatch notification events to registered handlers
         if (streamResponse.notification_event) {
           handleNotificationEvent(streamResponse.notification_event);
@@ -1567,6 +1648,21 @@ function ChatInterface({
     }
   };
 
+  // Cancels one running tool call; the agent carries on. Stable so that
+  // tool calls don't re-render when it is passed down.
+  const handleCancelToolCall = useCallback(
+    async (toolUseId: string) => {
+      if (!conversationId) return;
+      try {
+        await api.cancelToolCall(conversationId, toolUseId);
+      } catch (err) {
+        console.error("Failed to cancel tool call:", err);
+        setError("Failed to stop the command. Please try again.");
+      }
+    },
+    [conversationId],
+  );
+
   // Handler to distill and continue conversation
   const handleDistillConversation = async () => {
     if (!conversationId || !onDistillConversation) return;
@@ -1615,6 +1711,7 @@ function ChatInterface({
         error: boolean;
         startTime: string | null;
         endTime: string | null;
+        redactions: number;
       }
     > = {};
     const displayDataMap: Record<string, unknown> = {};
@@ -1634,6 +1731,7 @@ function ChatInterface({
                   error: content.ToolError || false,
                   startTime: content.ToolUseStartTime || null,
                   endTime: content.ToolUseEndTime || null,
+                  redactions: content.Redactions || 0,
                 };
                 if (content.Display) {
                   displayDataMap[content.ToolUseID] = content.Display;
@@ -1736,6 +1834,7 @@ function ChatInterface({
                 // Mark as complete if truncated (tool was lost, not running)
                 hasResult: !!resultData || wasTruncated,
                 display: displayData,
+                redactions: resultData?.redactions,
               });
             });
           }
@@ -1819,6 +1918,7 @@ function ChatInterface({
         return (
           <CoalescedToolCall
             key={item.toolUseId || `tool-${index}`}
+            toolUseId={item.toolUseId}
             toolName={item.toolName || "Unknown Tool"}
             toolInput={item.toolInput}
             toolResult={item.toolResult}
@@ -1827,6 +1927,13 @@ function ChatInterface({
             toolEndTime={item.toolEndTime}
             hasResult={item.hasResult}
             display={item.display}
+            redactions={item.redactions}
+            liveOutput={
+              liveToolOutput && liveToolOutput.toolUseId === item.toolUseId
+                ? liveToolOutput.text
+                : undefined
+            }
+            onCancelToolCall={handleCancelToolCall}
             onCommentTextChange={setDiffCommentText}
           />
         );
@@ -2440,6 +2547,9 @@ function ChatInterface({
         }}
       />
 
+      {/* Background bash jobs, if any */}
+      <JobsPanel conversationId={conversationId} refreshKey={messages.length} />
+
       {/* Status bar — always visible on desktop; hidden on mobile for active convos
           (CSS hides it, and content is suppressed to avoid duplicate DOM elements). */}
       <div
@@ -376,6 +376,33 @@ function CommandPalette({
       keywords: ["markdown", "render", "format", "rich", "text", "plain"],
     });
 
+    // Sign out (only with built-in authentication)
+    const authEmail = window.__SHELLEY_INIT__?.auth_email;
+    if (authEmail) {
+      items.push({
+        id: "sign-out",
+        type: "action",
+        title: t("signOut"),
+        subtitle: authEmail,
+        icon: (
+          <svg fill="none" stroke="currentColor" viewBox="0 0 24 24" width="16" height="16">
+            <path
+              strokeLinecap="round"
+              strokeLinejoin="round"
+              strokeWidth={2}
+              d="M17 16l4-4m0 0l-4-4m4 4H7m6 4v1a3 3 0 01-3 3H6a3 3 0 01-3-3V7a3 3 0 013-3h4a3 3 0 013 3v1"
+            />
+          </svg>
+        ),
+        action: async () => {
+          onClose();
+          await api.logout();
+          window.location.href = "/auth/login";
+        },
+        keywords: ["sign out", "logout", "log out", "account", "user"],
+      });
+    }
+
     // Archive current conversation
     if (currentConversation) {
       items.push({
@@ -41,6 +41,16 @@ export default function ConfigFieldInput({ field, value, onChange }: ConfigField
             </option>
           ))}
         </select>
+      ) : field.type === "text" ? (
+        <textarea
+          id={inputId}
+          className="form-input"
+          rows={4}
+          value={value}
+          onChange={(e) => onChange(e.target.value)}
+          placeholder={field.placeholder}
+          aria-describedby={field.description ? descId : undefined}
+        />
       ) : (
         <input
           id={inputId}
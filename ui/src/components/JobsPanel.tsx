@@ -0,0 +1,108 @@
+import React, { useCallback, useEffect, useState } from "react";
+import { api } from "../services/api";
+import { JobInfo } from "../types";
+
+interface JobsPanelProps {
+  conversationId: string | null;
+  // Changes whenever the jobs may have changed, e.g. the message count.
+  refreshKey: unknown;
+}
+
+// How often to refresh while some job is running.
+const POLL_INTERVAL_MS = 5000;
+
+function jobStatus(job: JobInfo): string {
+  if (job.running) return "running";
+  if (job.stopped) return "stopped";
+  if (job.exit_code === undefined) return "killed";
+  return `exited ${job.exit_code}`;
+}
+
+// JobsPanel lists the conversation's background bash jobs, with a button to
+// stop the running ones. It renders nothing when there are no jobs.
+function JobsPanel({ conversationId, refreshKey }: JobsPanelProps) {
+  const [jobs, setJobs] = useState<JobInfo[]>([]);
+  const [stopping, setStopping] = useState<Record<string, boolean>>({});
+  const [expanded, setExpanded] = useState(false);
+
+  const refresh = useCallback(async () => {
+    if (!conversationId) {
+      setJobs([]);
+      return;
+    }
+    try {
+      setJobs(await api.getJobs(conversationId));
+    } catch (err) {
+      console.error("Failed to load jobs:", err);
+    }
+  }, [conversationId]);
+
+  useEffect(() => {
+    refresh();
+  }, [refresh, refreshKey]);
+
+  const anyRunning = jobs.some((j) => j.running);
+  useEffect(() => {
+    if (!anyRunning) return;
+    const timer = setInterval(refresh, POLL_INTERVAL_MS);
+    return () => clearInterval(timer);
+  }, [anyRunning, refresh]);
+
+  const stop = async (jobId: string) => {
+    if (!conversationId) return;
+    setStopping((prev) => ({ ...prev, [jobId]: true }));
+    try {
+      await api.stopJob(conversationId, jobId);
+    } catch (err) {
+      console.error("Failed to stop job:", err);
+    } finally {
+      setStopping((prev) => ({ ...prev, [jobId]: false }));
+      refresh();
+    }
+  };
+
+  if (jobs.length === 0) return null;
+
+  const running = jobs.filter((j) => j.running).length;
+  return (
+    <div className="jobs-panel">
+      <button
+        className="jobs-panel-header"
+        onClick={() => setExpanded(!expanded)}
+        aria-expanded={expanded}
+      >
+        <span className={`jobs-panel-dot${running > 0 ? " running" : ""}`} />
+        {running > 0
+          ? `${running} background job${running === 1 ? "" : "s"} running`
+          : "Background jobs"}
+        <span className="jobs-panel-count">{jobs.length} total</span>
+      </button>
+      {expanded && (
+        <ul className="jobs-panel-list">
+          {jobs.map((job) => (
+            <li key={job.id} className="jobs-panel-job">
+              <span className="jobs-panel-id">{job.id}</span>
+              <span className="jobs-panel-command" title={`${job.command}\nin ${job.dir}`}>
+                {job.command}
+              </span>
+              <span className={`jobs-panel-status${job.running ? " running" : ""}`}>
+                {jobStatus(job)}
+              </span>
+              {job.running && (
+                <button
+                  className="jobs-panel-stop"
+                  onClick={() => stop(job.id)}
+                  disabled={stopping[job.id]}
+                >
+                  {stopping[job.id] ? "Stopping…" : "Stop"}
+                </button>
+              )}
+            </li>
+          ))}
+        </ul>
+      )}
+    </div>
+  );
+}
+
+export default JobsPanel;
@@ -24,6 +24,7 @@ import ChangeDirTool from "./ChangeDirTool";
 import SubagentTool from "./SubagentTool";
 import LLMOneShotTool from "./LLMOneShotTool";
 import OutputIframeTool from "./OutputIframeTool";
+import RunTestsTool from "./RunTestsTool";
 import BrowserEmulateTool from "./BrowserEmulateTool";
 import BrowserNetworkTool from "./BrowserNetworkTool";
 import BrowserAccessibilityTool from "./BrowserAccessibilityTool";
@@ -599,6 +600,9 @@ const Message = React.memo(function Message({
         if (content.ToolName === "output_iframe") {
           return <OutputIframeTool toolInput={content.ToolInput} isRunning={true} />;
         }
+        if (content.ToolName === "run_tests") {
+          return <RunTestsTool toolInput={content.ToolInput} isRunning={true} />;
+        }
         if (content.ToolName === "browser_emulate") {
           return <BrowserEmulateTool toolInput={content.ToolInput} isRunning={true} />;
         }
@@ -818,6 +822,19 @@ const Message = React.memo(function Message({
           );
         }
 
+        if (toolName === "run_tests") {
+          return (
+            <RunTestsTool
+              toolInput={toolInput}
+              isRunning={false}
+              toolResult={content.ToolResult}
+              hasError={hasError}
+              executionTime={executionTime}
+              display={content.Display}
+            />
+          );
+        }
+
         if (toolName === "browser_emulate") {
           return (
             <BrowserEmulateTool
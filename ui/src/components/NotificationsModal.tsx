@@ -2,7 +2,12 @@ import React, { useState, useEffect, useCallback } from "react";
 import Modal from "./Modal";
 import { useI18n } from "../i18n";
 import ConfigFieldInput from "./ConfigFieldInput";
-import { notificationChannelsApi, NotificationChannelAPI, ChannelTypeInfo } from "../services/api";
+import {
+  notificationChannelsApi,
+  NotificationChannelAPI,
+  NotificationDeliveryAPI,
+  ChannelTypeInfo,
+} from "../services/api";
 import {
   getBrowserNotificationState,
   requestBrowserNotificationPermission,
@@ -34,6 +39,7 @@ const emptyForm: FormData = {
 function NotificationsModal({ isOpen, onClose }: NotificationsModalProps) {
   const { t } = useI18n();
   const [channels, setChannels] = useState<NotificationChannelAPI[]>([]);
+  const [deliveries, setDeliveries] = useState<NotificationDeliveryAPI[]>([]);
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
 
@@ -57,8 +63,12 @@ function NotificationsModal({ isOpen, onClose }: NotificationsModalProps) {
     try {
       setLoading(true);
       setError(null);
-      const result = await notificationChannelsApi.getChannels();
+      const [result, recent] = await Promise.all([
+        notificationChannelsApi.getChannels(),
+        notificationChannelsApi.getDeliveries({ limit: 20 }),
+      ]);
       setChannels(result);
+      setDeliveries(recent);
     } catch (err) {
       setError(err instanceof Error ? err.message : "Failed to load channels");
     } finally {
@@ -170,11 +180,27 @@ function NotificationsModal({ isOpen, onClose }: NotificationsModalProps) {
     }
   };
 
-  const handleTest = async (channelId: string) => {
+  const handleRetryDelivery = async (deliveryId: string) => {
+    try {
+      setError(null);
+      await notificationChannelsApi.retryDelivery(deliveryId);
+      await loadChannels();
+    } catch (err) {
+      setError(err instanceof Error ? err.message : "Failed to retry delivery");
+    }
+  };
+
+  // Test with the form values so unsaved edits can be checked before saving.
+  const handleTest = async () => {
     try {
       setTesting(true);
       setTestResult(null);
-      const result = await notificationChannelsApi.testChannel(channelId);
+      const result = await notificationChannelsApi.testChannelConfig({
+        channel_type: form.channel_type,
+        display_name: form.display_name,
+        enabled: true,
+        config: form.config,
+      });
       setTestResult(result);
     } catch (err) {
       setTestResult({
@@ -261,15 +287,13 @@ function NotificationsModal({ isOpen, onClose }: NotificationsModalProps) {
           <button className="btn btn-secondary" onClick={handleCancel}>
             {t("cancel")}
           </button>
-          {editingChannelId && (
-            <button
-              className="btn btn-secondary"
-              onClick={() => handleTest(editingChannelId)}
-              disabled={testing}
-            >
-              {testing ? t("testingButton") : t("testButton")}
-            </button>
-          )}
+          <button
+            className="btn btn-secondary"
+            onClick={handleTest}
+            disabled={testing || form.channel_type === ""}
+          >
+            {testing ? t("testingButton") : t("testButton")}
+          </button>
           <button className="btn btn-primary" onClick={handleSave} disabled={!canSave}>
             {editingChannelId ? t("save") : t("addChannel")}
           </button>
@@ -501,6 +525,79 @@ function NotificationsModal({ isOpen, onClose }: NotificationsModalProps) {
           </div>
         ))}
       </div>
+
+      {/* Delivery log */}
+      {deliveries.length > 0 && (
+        <div style={{ marginTop: "1rem" }}>
+          <div
+            className="overflow-menu-label"
+            style={{
+              marginBottom: "0.5rem",
+              fontSize: "0.75rem",
+              textTransform: "uppercase",
+              letterSpacing: "0.05em",
+              color: "var(--text-secondary)",
+            }}
+          >
+            Recent deliveries
+          </div>
+          {deliveries.map((d) => (
+            <div
+              key={d.delivery_id}
+              style={{
+                display: "flex",
+                alignItems: "center",
+                justifyContent: "space-between",
+                gap: "0.5rem",
+                padding: "0.25rem 0",
+                fontSize: "0.75rem",
+              }}
+            >
+              <div style={{ flex: 1, minWidth: 0 }}>
+                <span
+                  style={{
+                    color:
+                      d.status === "failed"
+                        ? "var(--error, #dc2626)"
+                        : d.status === "pending"
+                          ? "var(--text-secondary)"
+                          : undefined,
+                  }}
+                >
+                  {d.status}
+                </span>{" "}
+                {d.event_type} → {d.channel_name || d.channel_id}
+                <span style={{ color: "var(--text-secondary)" }}>
+                  {" "}
+                  · {new Date(d.created_at).toLocaleString()}
+                  {d.attempts > 1 && ` · ${d.attempts} attempts`}
+                </span>
+                {d.last_error && d.status !== "delivered" && (
+                  <div
+                    style={{
+                      color: "var(--text-secondary)",
+                      overflow: "hidden",
+                      textOverflow: "ellipsis",
+                      whiteSpace: "nowrap",
+                    }}
+                    title={d.last_error}
+                  >
+                    {d.last_error}
+                  </div>
+                )}
+              </div>
+              {d.status === "failed" && (
+                <button
+                  className="btn btn-secondary btn-sm"
+                  onClick={() => handleRetryDelivery(d.delivery_id)}
+                >
+                  Retry
+                </button>
+              )}
+            </div>
+          ))}
+        </div>
+      )}
     </Modal>
   );
 }
@@ -30,11 +30,13 @@ function setSideBySidePreference(value: boolean): void {
 }
 
 // Display data — new payloads have {path, diff}; legacy ones also include oldContent/newContent.
+// Multi-file patches have an empty path, the changed files, and a "diff --git" header per file.
 interface PatchDisplayData {
   path: string;
   diff?: string;
   oldContent?: string;
   newContent?: string;
+  files?: string[];
 }
 
 interface PatchToolProps {
@@ -222,6 +224,29 @@ function SnapshotDiffView({
   );
 }
 
+// Render a multi-file diff as one PatchDiffView per file.
+function MultiFilePatchView({ diff, sideBySide }: { diff: string; sideBySide: boolean }) {
+  // Hunk lines start with " ", "+", "-" or "@", so a "diff --git" line always starts a file.
+  const parts = diff.split(/^(?=diff --git )/m).filter((part) => part.trim() !== "");
+  return (
+    <>
+      {parts.map((part, i) => {
+        const header = part.slice(0, part.indexOf("\n"));
+        const name = header.replace(/^diff --git a(\S+) b(\S+)$/, (_, from, to) =>
+          from === to ? from : `${from} → ${to}`,
+        );
+        const body = part.slice(part.indexOf("\n") + 1);
+        return (
+          <div key={i} className="patch-tool-file">
+            <div className="patch-tool-file-name">{name}</div>
+            {body.includes("@@") && <PatchDiffView patch={body} sideBySide={sideBySide} />}
+          </div>
+        );
+      })}
+    </>
+  );
+}
+
 // Picks the right diff renderer based on available data.
 function DiffView({
   displayData,
@@ -237,6 +262,10 @@ function DiffView({
     return <SnapshotDiffView displayData={displayData} sideBySide={sideBySide} />;
   }
 
+  if (displayData.files && displayData.diff) {
+    return <MultiFilePatchView diff={displayData.diff} sideBySide={sideBySide} />;
+  }
+
   // New payloads with only the unified diff string.
   if (displayData.diff) {
     return <PatchDiffView patch={displayData.diff} sideBySide={sideBySide} />;
@@ -337,7 +366,14 @@ function PatchTool({ toolInput, isRunning, toolResult, hasError, display }: Patc
     setSideBySidePreference(newValue);
   }, [sideBySide]);
 
-  // Extract path from toolInput
+  // Extract path from toolInput, or the number of files for a multi-file patch
+  const inputFiles =
+    typeof toolInput === "object" &&
+    toolInput !== null &&
+    "files" in toolInput &&
+    Array.isArray(toolInput.files)
+      ? toolInput.files
+      : null;
   const path =
     typeof toolInput === "object" &&
     toolInput !== null &&
@@ -346,7 +382,9 @@ function PatchTool({ toolInput, isRunning, toolResult, hasError, display }: Patc
       ? toolInput.path
       : typeof toolInput === "string"
         ? toolInput
-        : "";
+        : inputFiles
+          ? `${inputFiles.length} files`
+          : "";
 
   // Accept both {path,diff} (new) and {path,oldContent,newContent,diff} (legacy) payloads.
   const displayData: PatchDisplayData | null =
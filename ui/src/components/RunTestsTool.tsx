@@ -0,0 +1,197 @@
+import React, { useState } from "react";
+import { LLMContent } from "../types";
+
+// A failed test, as reported by the run_tests tool
+interface TestFailure {
+  suite?: string;
+  name?: string;
+  file?: string;
+  line?: number;
+  message: string;
+}
+
+// RunTestsDisplayData from the Go tool
+interface RunTestsDisplayData {
+  framework: string;
+  command: string;
+  dir: string;
+  passed: number;
+  failed: number;
+  skipped: number;
+  failures?: TestFailure[];
+}
+
+interface RunTestsToolProps {
+  // For tool_use (pending state)
+  toolInput?: unknown; // { framework?: string, args?: string[], rerun_failed?: boolean }
+  isRunning?: boolean;
+  onCancel?: () => void;
+
+  // For tool_result (completed state)
+  toolResult?: LLMContent[];
+  hasError?: boolean;
+  executionTime?: string;
+  display?: unknown;
+}
+
+function failureLocation(f: TestFailure): string {
+  if (!f.file) return "";
+  return f.line ? `${f.file}:${f.line}` : f.file;
+}
+
+function RunTestsTool({
+  toolInput,
+  isRunning,
+  onCancel,
+  toolResult,
+  hasError,
+  executionTime,
+  display,
+}: RunTestsToolProps) {
+  const displayData: RunTestsDisplayData | null =
+    display &&
+    typeof display === "object" &&
+    "framework" in display &&
+    typeof display.framework === "string"
+      ? (display as RunTestsDisplayData)
+      : null;
+  const failures = displayData?.failures ?? [];
+
+  // Failures are what the reader wants to see, so show them until the
+  // reader collapses them
+  const [expanded, setExpanded] = useState<boolean | null>(null);
+  const isExpanded = expanded ?? failures.length > 0;
+  const [stopping, setStopping] = useState(false);
+
+  const input =
+    typeof toolInput === "object" && toolInput !== null
+      ? (toolInput as { framework?: string; args?: string[]; rerun_failed?: boolean })
+      : {};
+  const requested = [
+    input.framework || "tests",
+    ...(Array.isArray(input.args) ? input.args : []),
+    ...(input.rerun_failed ? ["(rerun failed)"] : []),
+  ].join(" ");
+
+  const output =
+    toolResult && toolResult.length > 0 && toolResult[0].Text ? toolResult[0].Text : "";
+  const isComplete = !isRunning && toolResult !== undefined;
+
+  return (
+    <div className="tool" data-testid={isComplete ? "tool-call-completed" : "tool-call-running"}>
+      <div className="tool-header" onClick={() => setExpanded(!isExpanded)}>
+        <div className="tool-summary">
+          <span className={`tool-emoji ${isRunning ? "running" : ""}`}>🧪</span>
+          <span className="tool-command">{displayData?.command || requested}</span>
+          {displayData && (
+            <span className="run-tests-counts">
+              <span className="run-tests-count passed" title="Passed">✓ {displayData.passed}</span>
+              {displayData.failed > 0 && (
+                <span className="run-tests-count failed" title="Failed">
+                  ✗ {displayData.failed}
+                </span>
+              )}
+              {displayData.skipped > 0 && (
+                <span className="run-tests-count skipped" title="Skipped">
+                  ⊘ {displayData.skipped}
+                </span>
+              )}
+            </span>
+          )}
+          {isComplete && hasError && <span className="tool-error">✗</span>}
+          {isComplete && !hasError && failures.length === 0 && (
+            <span className="tool-success">✓</span>
+          )}
+        </div>
+        {isRunning && onCancel && (
+          <button
+            className="bash-tool-stop"
+            onClick={(e) => {
+              e.stopPropagation();
+              setStopping(true);
+              onCancel();
+            }}
+            disabled={stopping}
+            title="Stop the tests; the agent carries on"
+          >
+            {stopping ? "Stopping…" : "Stop"}
+          </button>
+        )}
+        <button
+          className="tool-toggle"
+          aria-label={isExpanded ? "Collapse" : "Expand"}
+          aria-expanded={isExpanded}
+        >
+          <svg
+            width="12"
+            height="12"
+            viewBox="0 0 12 12"
+            fill="none"
+            xmlns="http://www.w3.org/2000/svg"
+            style={{
+              transform: isExpanded ? "rotate(90deg)" : "rotate(0deg)",
+              transition: "transform 0.2s",
+            }}
+          >
+            <path
+              d="M4.5 3L7.5 6L4.5 9"
+              stroke="currentColor"
+              strokeWidth="1.5"
+              strokeLinecap="round"
+              strokeLinejoin="round"
+            />
+          </svg>
+        </button>
+      </div>
+
+      {isExpanded && (
+        <div className="tool-details">
+          {displayData?.dir && (
+            <div className="tool-section">
+              <div className="tool-label">Directory:</div>
+              <pre className="tool-code">{displayData.dir}</pre>
+            </div>
+          )}
+
+          {failures.length > 0 && (
+            <div className="tool-section">
+              <div className="tool-label">
+                Failures:
+                {executionTime && <span className="tool-time">{executionTime}</span>}
+              </div>
+              <ul className="run-tests-failures">
+                {failures.map((f, i) => (
+                  <li key={i} className="run-tests-failure">
+                    <div className="run-tests-failure-header">
+                      <span className="run-tests-failure-name">
+                        {f.name || f.suite || "failure"}
+                      </span>
+                      {failureLocation(f) && (
+                        <span className="run-tests-failure-location">{failureLocation(f)}</span>
+                      )}
+                    </div>
+                    {f.message && <pre className="tool-code error">{f.message}</pre>}
+                  </li>
+                ))}
+              </ul>
+            </div>
+          )}
+
+          {isComplete && (!displayData || hasError) && (
+            <div className="tool-section">
+              <div className="tool-label">
+                Output{hasError ? " (Error)" : ""}:
+                {executionTime && <span className="tool-time">{executionTime}</span>}
+              </div>
+              <pre className={`tool-code ${hasError ? "error" : ""}`}>
+                {output || "(no output)"}
+              </pre>
+            </div>
+          )}
+        </div>
+      )}
+    </div>
+  );
+}
+
+export default RunTestsTool;
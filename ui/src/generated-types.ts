@@ -13,6 +13,12 @@ export interface Conversation {
   archived: boolean;
   parent_conversation_id: string | null;
   model: string | null;
+  pinned: boolean;
+  repo_root: string | null;
+  repo_worktree: string | null;
+  schedule_id: string | null;
+  trigger_id: string | null;
+  owner_email: string | null;
 }
 
 export interface Usage {
@@ -71,12 +77,17 @@ export interface ConversationWithStateForTS {
   archived: boolean;
   parent_conversation_id: string | null;
   model: string | null;
+  pinned: boolean;
+  repo_root: string | null;
+  repo_worktree: string | null;
   working: boolean;
   git_repo_root?: string;
   git_worktree_root?: string;
   git_commit?: string;
   git_subject?: string;
   subagent_count: number;
+  tags?: string[] | null;
+  branches?: string[] | null;
 }
 
 export type MessageType = "user" | "agent" | "tool" | "error" | "system" | "gitinfo";
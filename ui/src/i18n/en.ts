@@ -68,6 +68,7 @@ export const en: TranslationKeys = {
   showPlainText: "Show plain text for all messages",
   archiveConversationAction: "Archive Conversation",
   archiveCurrentConversation: "Archive the current conversation",
+  signOut: "Sign Out",
   newConversationInMainRepo: "New Conversation in Main Repo",
   newConversationInNewWorktree: "New Conversation in New Worktree",
   createNewWorktree: "Create a new git worktree for this conversation",
@@ -68,6 +68,7 @@ export const es: TranslationKeys = {
   showPlainText: "Mostrar texto sin formato en todos los mensajes",
   archiveConversationAction: "Archivar conversación",
   archiveCurrentConversation: "Archivar la conversación actual",
+  signOut: "Cerrar sesión",
   newConversationInMainRepo: "Nueva conversación en el repositorio principal",
   newConversationInNewWorktree: "Nueva conversación en nuevo worktree",
   createNewWorktree: "Crear un nuevo worktree de Git para esta conversación",
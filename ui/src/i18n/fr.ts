@@ -69,6 +69,7 @@ export const fr: TranslationKeys = {
   showPlainText: "Afficher le texte brut pour tous les messages",
   archiveConversationAction: "Archiver la conversation",
   archiveCurrentConversation: "Archiver la conversation en cours",
+  signOut: "Se déconnecter",
   newConversationInMainRepo: "Nouvelle conversation dans le dépôt principal",
   newConversationInNewWorktree: "Nouvelle conversation dans un nouveau Worktree",
   createNewWorktree: "Créer un nouveau worktree Git pour cette conversation",
@@ -68,6 +68,7 @@ export const ja: TranslationKeys = {
   showPlainText: "すべてのメッセージをプレーンテキストで表示",
   archiveConversationAction: "会話をアーカイブ",
   archiveCurrentConversation: "現在の会話をアーカイブする",
+  signOut: "サインアウト",
   newConversationInMainRepo: "メインリポジトリで新しい会話",
   newConversationInNewWorktree: "新しいWorktreeで新しい会話",
   createNewWorktree: "この会話用に新しいGit Worktreeを作成する",
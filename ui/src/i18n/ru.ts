@@ -68,6 +68,7 @@ export const ru: TranslationKeys = {
   showPlainText: "Показывать простой текст для всех сообщений",
   archiveConversationAction: "Архивировать диалог",
   archiveCurrentConversation: "Архивировать текущий диалог",
+  signOut: "Выйти",
   newConversationInMainRepo: "Новый диалог в основном репозитории",
   newConversationInNewWorktree: "Новый диалог в новом worktree",
   createNewWorktree: "Создать новый Git worktree для этого диалога",
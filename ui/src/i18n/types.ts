@@ -68,6 +68,7 @@ export interface TranslationKeys {
   showPlainText: string;
   archiveConversationAction: string;
   archiveCurrentConversation: string;
+  signOut: string;
   newConversationInMainRepo: string;
   newConversationInNewWorktree: string;
   createNewWorktree: string;
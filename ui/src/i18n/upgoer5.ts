@@ -68,6 +68,7 @@ export const upgoer5: TranslationKeys = {
   showPlainText: "Show just the words for everything",
   archiveConversationAction: "Put Away Talk",
   archiveCurrentConversation: "Put away the talk you are in",
+  signOut: "Leave",
   newConversationInMainRepo: "New Talk in Home Place",
   newConversationInNewWorktree: "New Talk in New Work Place",
   createNewWorktree: "Make a new work place for this talk",
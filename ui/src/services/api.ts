@@ -8,6 +8,7 @@ import {
   GitFileDiff,
   VersionInfo,
   CommitInfo,
+  JobInfo,
 } from "../types";
 
 class ApiService {
@@ -321,6 +322,35 @@ class ApiService {
     return response.json();
   }
 
+  async getJobs(conversationId: string): Promise<JobInfo[]> {
+    const response = await fetch(`${this.baseUrl}/conversation/${conversationId}/jobs`);
+    if (!response.ok) {
+      throw new Error(`Failed to get jobs: ${response.statusText}`);
+    }
+    return response.json();
+  }
+
+  async stopJob(conversationId: string, jobId: string): Promise<JobInfo> {
+    const response = await fetch(
+      `${this.baseUrl}/conversation/${conversationId}/jobs/${encodeURIComponent(jobId)}/stop`,
+      { method: "POST" },
+    );
+    if (!response.ok) {
+      throw new Error(`Failed to stop job: ${response.statusText}`);
+    }
+    return response.json();
+  }
+
+  async cancelToolCall(conversationId: string, toolUseId: string): Promise<void> {
+    const response = await fetch(
+      `${this.baseUrl}/conversation/${conversationId}/tools/${encodeURIComponent(toolUseId)}/cancel`,
+      { method: "POST" },
+    );
+    if (!response.ok) {
+      throw new Error(`Failed to cancel tool call: ${response.statusText}`);
+    }
+  }
+
   // Version check APIs
   async checkVersion(forceRefresh = false): Promise<VersionInfo> {
     const url = forceRefresh ? "/version-check?refresh=true" : "/version-check";
@@ -386,6 +416,17 @@ class ApiService {
     }
     return response.json();
   }
+
+  // Ends the current session when built-in authentication is enabled.
+  async logout(): Promise<void> {
+    const response = await fetch("/auth/logout", {
+      method: "POST",
+      headers: { "X-Shelley-Request": "1" },
+    });
+    if (!response.ok) {
+      throw new Error(`Failed to sign out: ${response.statusText}`);
+    }
+  }
 }
 
 export const api = new ApiService();
@@ -537,6 +578,20 @@ export interface ChannelTypeInfo {
   }[];
 }
 
+export interface NotificationDeliveryAPI {
+  delivery_id: string;
+  channel_id: string;
+  channel_name?: string;
+  event_type: string;
+  conversation_id: string | null;
+  status: "pending" | "delivered" | "failed";
+  attempts: number;
+  last_error: string | null;
+  next_attempt_at: string;
+  delivered_at: string | null;
+  created_at: string;
+}
+
 class NotificationChannelsApi {
   private baseUrl = "/api";
 
@@ -594,6 +649,40 @@ class NotificationChannelsApi {
     await this.throwIfNotOk(response, "Failed to test notification channel");
     return response.json();
   }
+
+  async testChannelConfig(
+    request: CreateNotificationChannelRequest,
+  ): Promise<{ success: boolean; message: string }> {
+    const response = await fetch(`${this.baseUrl}/notification-channels/test`, {
+      method: "POST",
+      headers: this.postHeaders,
+      body: JSON.stringify(request),
+    });
+    await this.throwIfNotOk(response, "Failed to test notification channel");
+    return response.json();
+  }
+
+  async getDeliveries(
+    params: { status?: string; channel_id?: string; limit?: number } = {},
+  ): Promise<NotificationDeliveryAPI[]> {
+    const query = new URLSearchParams();
+    if (params.status) query.set("status", params.status);
+    if (params.channel_id) query.set("channel_id", params.channel_id);
+    if (params.limit) query.set("limit", String(params.limit));
+    const qs = query.toString();
+    const response = await fetch(`${this.baseUrl}/notification-deliveries${qs ? `?${qs}` : ""}`);
+    await this.throwIfNotOk(response, "Failed to get notification deliveries");
+    return response.json();
+  }
+
+  async retryDelivery(deliveryId: string): Promise<NotificationDeliveryAPI> {
+    const response = await fetch(`${this.baseUrl}/notification-deliveries/${deliveryId}/retry`, {
+      method: "POST",
+      headers: this.postHeaders,
+    });
+    await this.throwIfNotOk(response, "Failed to retry notification delivery");
+    return response.json();
+  }
 }
 
 export const notificationChannelsApi = new NotificationChannelsApi();
@@ -45,6 +45,7 @@ export interface LLMContent {
   ToolUseEndTime?: string | null;
   Display?: unknown;
   Cache?: boolean;
+  Redactions?: number;
 }
 
 // API types
@@ -62,7 +63,16 @@ export interface ChatRequest {
   cwd?: string;
 }
 // Notification event types
-export type NotificationEventType = "agent_done" | "agent_error";
+export type NotificationEventType =
+  | "agent_done"
+  | "agent_error"
+  | "tool_approval_needed"
+  | "budget_threshold"
+  | "subagent_done"
+  | "git_commit"
+  | "bash_finished"
+  | "conversation_idle"
+  | "upgrade_available";
 
 export interface NotificationEvent extends Omit<NotificationEventForTS, "type"> {
   type: NotificationEventType;
@@ -75,6 +85,16 @@ export interface StreamResponse extends Omit<StreamResponseForTS, "messages"> {
   conversation_list_update?: ConversationListUpdate;
   heartbeat?: boolean;
   notification_event?: NotificationEvent;
+  tool_progress?: ToolProgress;
+}
+
+// ToolProgress carries output a running tool call has produced. offset is
+// where output starts in all of the call's output, in UTF-8 bytes.
+export interface ToolProgress {
+  tool_use_id: string;
+  tool_name: string;
+  output: string;
+  offset: number;
 }
 
 // Link represents a custom link that can be added to the UI
@@ -93,6 +113,7 @@ export interface InitData {
   hostname?: string;
   terminal_url?: string;
   links?: Link[];
+  auth_email?: string; // set when signed in with built-in authentication
   notification_channel_types?: import("./services/api").ChannelTypeInfo[];
 }
 
@@ -148,6 +169,21 @@ export interface ConversationListUpdate {
   conversation_id?: string; // For deletes
   git_repo_root?: string;
   git_worktree_root?: string;
+  tags?: string[];
+}
+
+// Background bash job, from GET /api/conversation/<id>/jobs
+export interface JobInfo {
+  id: string;
+  command: string;
+  dir: string;
+  pid: number;
+  started_at: string;
+  ended_at?: string;
+  running: boolean;
+  exit_code?: number;
+  stopped?: boolean;
+  output_bytes: number;
 }
 
 // Version check types